@@ -2,64 +2,125 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"load-balancer/internal/access"
+	"load-balancer/internal/accesslog"
+	"load-balancer/internal/admission"
+	"load-balancer/internal/alerting"
 	"load-balancer/internal/api"
 	"load-balancer/internal/config"
+	"load-balancer/internal/conformance"
+	"load-balancer/internal/connlimit"
+	"load-balancer/internal/forwardproxy"
+	"load-balancer/internal/hooks"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
 
 	"load-balancer/internal/balancer"
+	"load-balancer/internal/bench"
+	"load-balancer/internal/buildinfo"
+	"load-balancer/internal/capture"
 
 	"load-balancer/internal/ratelimiter"
+	"load-balancer/internal/requestid"
+	"load-balancer/internal/simulate"
+	"load-balancer/internal/sni"
+	"load-balancer/internal/tracing"
 
 	"load-balancer/internal/storage"
+	"load-balancer/internal/udpproxy"
 
 	_ "modernc.org/sqlite"
 )
 
 func main() {
-	log.Println("Запуск балансировщика...")
+	// Подкоманда simulate проверяет конфигурацию на синтетическом трафике, не запуская балансировщик
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		os.Exit(runSimulate(os.Args[2:]))
+	}
+	// Подкоманда ratelimit-test проверяет, что Rate Limiter с хранилищем из конфигурации соблюдает лимиты
+	if len(os.Args) > 1 && os.Args[1] == "ratelimit-test" {
+		os.Exit(runRateLimitTest(os.Args[2:]))
+	}
+	// Подкоманда storage migrate переносит данные хранилища между драйверами
+	if len(os.Args) > 1 && os.Args[1] == "storage" {
+		os.Exit(runStorage(os.Args[2:]))
+	}
+	// Подкоманда bench нагружает работающий балансировщик синтетическим трафиком
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(runBench(os.Args[2:]))
+	}
+
+	i18n.Logf(i18n.MainStarting)
+	build := buildinfo.Get()
+	i18n.Logf(i18n.MainVersion, build.Version, build.Commit, build.BuildDate, build.GoVersion)
 	configPath := "config.yaml"
 
 	// Загрузка конфигурации
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
-		log.Fatalf("[Error] Не удалось загрузить конфигурацию: %v", err)
+		i18n.Fatalf(i18n.MainConfigLoadFailed, err)
 	}
+	// Формат проверен при загрузке конфигурации
+	_ = i18n.SetFormat(cfg.Logging.Format)
+	i18n.SetLevel(cfg.LogLevel)
 
 	// Проверяем базовые параметры конфигурации.
 	if len(cfg.BackendServers) == 0 {
-		log.Fatal("Список бэкенд-серверов (backend_servers) в конфигурации пуст.")
+		i18n.Fatalf(i18n.MainNoBackends)
 	}
 	if cfg.Port == "" {
-		log.Fatal("Порт (port) не указан в конфигурации.")
+		i18n.Fatalf(i18n.MainNoPort)
 	}
 
-	// Инициализация хранилища (если Rate Limiter включен и использует БД)
+	// Инициализация хранилища: SQLite, если Rate Limiter включен и использует БД, иначе память процесса.
+	// store - база SQLite (nil без нее) для возможностей, которых нет у хранилища в памяти:
+	// обслуживания базы и статуса бэкендов между перезапусками
 	var store *storage.DB
+	var limits storage.Store
 	if cfg.RateLimiter.Enabled && cfg.RateLimiter.DatabasePath != "" {
-		log.Printf("[Storage] Инициализация SQLite из '%s'...", cfg.RateLimiter.DatabasePath)
-		store, err = storage.NewSQLiteDB(cfg.RateLimiter.DatabasePath)
+		i18n.Logf(i18n.MainSQLiteInit, cfg.RateLimiter.DatabasePath)
+		store, err = storage.OpenSQLiteDB(cfg.RateLimiter.DatabasePath, cfg.SQLite)
 		if err != nil {
-			log.Fatalf("[Error] Не удалось подключиться к БД SQLite: %v", err)
+			i18n.Fatalf(i18n.MainSQLiteFailed, err)
 		}
 		defer store.Close() // Закрываем БД при выходе
+		limits = store
 	} else {
-		log.Println("[Storage] Используется хранилище в памяти или Rate Limiter выключен (API управления лимитами будет недоступно).")
-		store = nil // APIHandler будет знать, что store недоступен
+		i18n.Logf(i18n.MainNoStore)
+		limits = storage.NewMemoryStore()
+	}
+	// Синхронизируем лимиты, объявленные в config.yaml, с хранилищем
+	if _, _, err := ratelimiter.ImportClientLimits(limits, cfg.RateLimiter.Clients); err != nil {
+		i18n.Fatalf(i18n.MainImportFailed, err)
 	}
 
 	// Инициализация Rate Limiter
 	// Передаем указатель на секцию RateLimiter из конфига и store (может быть nil)
 	// ratelimiter.New ожидает *config.RateLimiterConfig
-	rateLimiter, err := ratelimiter.New(&cfg.RateLimiter, store)
+	rateLimiter, err := ratelimiter.New(&cfg.RateLimiter, limits)
 	if err != nil {
-		// Обрабатываем ошибку от New, если она есть (хотя пока New ее не возвращает)
-		log.Fatalf("[Error] Не удалось инициализировать Rate Limiter: %v", err)
+		// New возвращает ошибку, если не удалось открыть сокет rate_limiter.gossip.listen
+		i18n.Fatalf(i18n.MainRateLimiterFailed, err)
+	}
+
+	// Списки доступа: записи из конфигурации и из хранилища (добавленные через /access)
+	accessList, err := access.New(cfg.AccessList, limits)
+	if err != nil {
+		i18n.Fatalf(i18n.MainAccessListFailed, err)
 	}
 
 	// Инициализация балансировщика
@@ -71,52 +132,321 @@ func main() {
 		cfg.LoadBalancingAlgorithm,
 	)
 	if err != nil {
-		log.Fatalf("[Error] Не удалось создать балансировщик: %v", err)
+		i18n.Fatalf(i18n.MainBalancerFailed, err)
+	}
+	lb.SetRoutes(cfg.Headers, cfg.Routes)
+	lb.SetBackendLabels(cfg.BackendLabels)
+	if cfg.GRPC.Enabled {
+		lb.EnableGRPC()
+	}
+
+	// Встроенный алертинг (доля 429, недоступность всех бэкендов)
+	var alertMonitor *alerting.Monitor
+	if cfg.Alerts.Enabled {
+		alertMonitor = alerting.NewMonitor(cfg.Alerts, lb.HealthyBackends)
+		lb.AddObserver(alertMonitor)
+		alertMonitor.Start()
 	}
 
 	// Инициализация API обработчика (передаем store)
-	apiHandler := api.NewAPIHandler(store)
+	apiHandler := api.NewAPIHandler(limits)
+	apiHandler.Plans = rateLimiter   // Планы для downgrade_to пробных периодов
+	apiHandler.Limiter = rateLimiter // Корзины клиентов (/clients/{id}/reset и /state)
+
+	// Аутентификация API управления (admin_auth); nil - API открыт
+	adminAuth := api.NewAuthenticator(cfg.AdminAuth)
 
 	// Создаем основной маршрутизатор
 	smux := http.NewServeMux()
-	smux.Handle("/clients", http.StripPrefix("/clients", apiHandler))
-	smux.Handle("/clients/", http.StripPrefix("/clients", apiHandler))
+	smux.Handle("/clients", api.RequireAuth(adminAuth, http.StripPrefix("/clients", apiHandler)))
+	smux.Handle("/clients/", api.RequireAuth(adminAuth, http.StripPrefix("/clients", apiHandler)))
+	accessHandler := api.NewAccessHandler(accessList)
+	smux.Handle("/access", api.RequireAuth(adminAuth, http.StripPrefix("/access", accessHandler)))
+	smux.Handle("/access/", api.RequireAuth(adminAuth, http.StripPrefix("/access", accessHandler)))
+	// API-ключи клиентов (rate_limiter.api_keys)
+	apiKeyHandler := api.NewAPIKeyHandler(limits)
+	apiKeyHandler.Plans = rateLimiter
+	apiKeyHandler.Cache = rateLimiter
+	smux.Handle("/api-keys", api.RequireAuth(adminAuth, http.StripPrefix("/api-keys", apiKeyHandler)))
+	smux.Handle("/api-keys/", api.RequireAuth(adminAuth, http.StripPrefix("/api-keys", apiKeyHandler)))
+	// Запись запросов для отладки включается через /admin/capture
+	recorder := capture.New(cfg.Capture)
+	adminHandler := api.NewAdminHandler(lb)
+	adminHandler.Capture = recorder
+	adminHandler.Limiter = rateLimiter
+	adminHandler.ConfigHash = cfg.Hash
+	reloads := &config.ReloadLog{}
+	adminHandler.Reloads = reloads
+	adminHandler.Features = cfg.Features()
+	// Обслуживание базы SQLite: плановое (storage_maintenance.enabled) и по POST /admin/storage/maintenance
+	var maintainer *storage.Maintainer
+	if store != nil {
+		maintainer = storage.NewMaintainer(store, cfg.StorageMaintenance)
+		adminHandler.Maintenance = maintainer
+		if cfg.StorageMaintenance.Enabled {
+			maintainer.Start()
+		}
+	}
+	smux.Handle("/admin/", api.RequireAuth(adminAuth, adminHandler))
+	// Готовность к приему трафика для внешнего балансировщика и Kubernetes
+	readiness := &api.ReadinessHandler{
+		Pools:         []*balancer.Balancer{lb},
+		InFlightRatio: cfg.Readiness.InFlightRatio,
+		StoreTimeout:  cfg.Readiness.StoreTimeout,
+		Store:         limits,
+	}
+	smux.Handle("/readyz", readiness)
+	// Метрики в текстовом формате Prometheus (metrics.path)
+	if cfg.Metrics.Enabled {
+		smux.Handle(cfg.Metrics.Path, metrics.Handler())
+		i18n.Logf(i18n.MainMetricsEnabled, cfg.Metrics.Path)
+	}
+	rateLimiter.RegisterMetrics()
 	smux.Handle("/", lb)
 
 	// 7. Настраиваем и запускаем HTTP-сервер.
-	addr := ":" + cfg.Port
+	addr := config.ListenAddr(cfg.BindAddress, cfg.Port)
 	server := &http.Server{
 		Addr:    addr,
-		Handler: smux,
+		Handler: metrics.InFlight(requestid.Middleware(smux)), // Каждый запрос получает X-Request-ID
+		// Ограничения времени на соединения клиентов (секция timeouts; 0 - без ограничения)
+		ReadTimeout:       cfg.Timeouts.Read,
+		ReadHeaderTimeout: cfg.Timeouts.ReadHeader,
+		WriteTimeout:      cfg.Timeouts.Write,
+		IdleTimeout:       cfg.Timeouts.Idle,
+	}
+	if cfg.GRPC.Enabled {
+		// Клиенты gRPC подключаются по HTTP/2 без TLS (h2c)
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	// HTTPS-листенер: сертификат и пул бэкендов выбираются по SNI
+	var tlsServer *http.Server
+	var sitePools []*balancer.Balancer
+	if cfg.TLS.Enabled {
+		tlsServer, sitePools = newTLSServer(cfg, smux, rateLimiter, alertMonitor)
+	}
+	balancers := append([]*balancer.Balancer{lb}, sitePools...)
+
+	// Пулы для GET /admin/state: пулы сайтов TLS называются по первому имени сайта
+	adminHandler.Pools = map[string]*balancer.Balancer{"primary": lb}
+	poolIndex := 0
+	for _, site := range cfg.TLS.Sites {
+		if cfg.TLS.Enabled && len(site.BackendServers) > 0 {
+			adminHandler.Pools["tls:"+site.ServerNames[0]] = sitePools[poolIndex]
+			poolIndex++
+		}
+	}
+
+	// Общий для всех пулов лимит одновременных запросов с приоритетной очередью
+	if cfg.Concurrency.Enabled {
+		guard := admission.New(cfg.Concurrency)
+		readiness.Admission = guard
+		for _, b := range balancers {
+			b.SetAdmission(guard)
+		}
+	}
+	for _, b := range balancers {
+		b.SetCapture(recorder)
+		b.SetAccessList(accessList)
+		b.SetPassiveHealth(cfg.PassiveHealth)
+		b.SetBackendPassiveHealth(cfg.BackendPassiveHealth)
+		b.SetBackendWeights(cfg.BackendWeights)
+		b.SetBackendMaxConnections(cfg.BackendMaxConnections)
+		b.SetSaturationThreshold(cfg.SaturationThreshold)
+		b.SetStickySessions(cfg.StickySessions)
+		b.SetStatsHistory(cfg.StatsHistory.Window)
+		b.SetMaxRequestAge(cfg.RequestAge.MaxAge)
+		b.SetTrace(cfg.Trace)
+	}
+	if cfg.Trace.Enabled && cfg.Trace.Token == "" {
+		i18n.Logf(i18n.MainTraceWithoutToken)
+	}
+
+	// Журнал доступа отправляется напрямую в удаленный приемник (syslog, HTTP, Kafka)
+	var accessLog *accesslog.Shipper
+	if cfg.AccessLog.Enabled {
+		accessLog, err = accesslog.New(cfg.AccessLog)
+		if err != nil {
+			i18n.Fatalf(i18n.MainAccessLogFailed, err)
+		}
+		accessLog.Start()
+		for _, b := range balancers {
+			b.SetAccessLog(accessLog)
+		}
+	}
+
+	// Резервный пул принимает запросы основного, когда тот исчерпал бюджет
+	if cfg.Spillover.Enabled {
+		overflow, err := balancer.New(cfg.Spillover.BackendServers, rateLimiter, cfg.HealthCheck, cfg.LoadBalancingAlgorithm)
+		if err != nil {
+			i18n.Fatalf(i18n.MainSpilloverFailed, err)
+		}
+		overflow.SetRoutes(cfg.Headers, cfg.Routes)
+		overflow.SetBackendLabels(cfg.BackendLabels)
+		overflow.SetPassiveHealth(cfg.PassiveHealth)
+		overflow.SetBackendPassiveHealth(cfg.BackendPassiveHealth)
+		overflow.SetBackendWeights(cfg.BackendWeights)
+		overflow.SetBackendMaxConnections(cfg.BackendMaxConnections)
+		overflow.SetSaturationThreshold(cfg.SaturationThreshold)
+		overflow.SetStatsHistory(cfg.StatsHistory.Window)
+		overflow.SetMaxRequestAge(cfg.RequestAge.MaxAge)
+		if cfg.GRPC.Enabled {
+			overflow.EnableGRPC()
+		}
+		lb.SetSpillover(overflow, cfg.Spillover.PrimaryMaxInFlight, cfg.Spillover.PrimaryMaxRPS)
+		balancers = append(balancers, overflow)
+		adminHandler.Pools["spillover"] = overflow
+		readiness.Pools = append(readiness.Pools, overflow)
+	}
+
+	// Реакция на ответы бэкендов 429 и 503, учетные данные для бэкендов, разрешение их имен, TLS и канареечное
+	// разделение задаются по имени пула, как в GET /admin/state; под этим же именем пул экспортирует метрики
+	for name, pool := range adminHandler.Pools {
+		pool.SetBackendTimeout(cfg.Timeouts.BackendResponse)
+		pool.SetUpstreamThrottling(cfg.UpstreamThrottling.PolicyFor(name), cfg.UpstreamThrottling.Penalty, cfg.UpstreamThrottling.WeightPercent)
+		pool.SetUpstreamAuth(cfg.UpstreamAuth[name])
+		pool.SetDNS(cfg.DNS[name])
+		if err := pool.SetBackendTLS(cfg.BackendTLS[name]); err != nil {
+			i18n.Fatalf(i18n.MainBackendTLSFailed, name, err)
+		}
+		pool.SetCanary(cfg.Canary[name])
+		pool.RegisterMetrics(name)
+	}
+
+	// Распределенная трассировка: span запросов всех пулов отправляются в коллектор OTLP
+	var tracer *tracing.Tracer
+	if cfg.Tracing.Enabled {
+		tracer = tracing.New(cfg.Tracing)
+		tracer.Start()
+		for _, pool := range adminHandler.Pools {
+			pool.SetTracer(tracer)
+		}
+	}
+
+	// CN клиентского сертификата (tls.client_auth) передается бэкендам всех пулов; пулы, принимающие
+	// запросы и по HTTP, удаляют присланный клиентом заголовок
+	if cfg.TLS.Enabled && cfg.TLS.ClientAuth.SubjectHeader != "" {
+		for _, pool := range adminHandler.Pools {
+			pool.SetClientCertHeader(cfg.TLS.ClientAuth.SubjectHeader)
+		}
+	}
+
+	// Сценарий допуска выполняется пулом, принявшим запрос, и может направить запрос в любой из пулов
+	if cfg.AdmissionHook.Enabled {
+		hook, err := hooks.New(cfg.AdmissionHook)
+		if err != nil {
+			i18n.Fatalf(i18n.MainHookFailed, err)
+		}
+		for _, pool := range adminHandler.Pools {
+			pool.SetHook(hook, adminHandler.Pools)
+		}
+	}
+
+	// UDP-балансировка (DNS, syslog и т.п.) работает независимо от HTTP-листенеров
+	var udpProxy *udpproxy.Proxy
+	if cfg.UDP.Enabled {
+		udpProxy, err = udpproxy.New(cfg.UDP)
+		if err != nil {
+			i18n.Fatalf(i18n.MainUDPFailed, err)
+		}
+		if err := udpProxy.Start(); err != nil {
+			i18n.Fatalf(i18n.MainUDPFailed, err)
+		}
+	}
+
+	// Прямой прокси: исходящие запросы внутренних клиентов распределяются по egress-шлюзам
+	var fpServer *http.Server
+	if cfg.ForwardProxy.Enabled {
+		gateways, err := balancer.New(cfg.ForwardProxy.Gateways, rateLimiter, cfg.HealthCheck, cfg.LoadBalancingAlgorithm)
+		if err != nil {
+			i18n.Fatalf(i18n.MainFPFailed, err)
+		}
+		balancers = append(balancers, gateways)
+		fpServer = &http.Server{
+			Addr:    config.ListenAddr(cfg.ForwardProxy.BindAddress, cfg.ForwardProxy.Port),
+			Handler: forwardproxy.New(gateways, rateLimiter, cfg.ForwardProxy.DialTimeout),
+		}
+	}
+
+	// Бэкенды, недоступные перед прошлой остановкой, не получают запросов до первой успешной проверки
+	if store != nil && cfg.HealthCheck.Enabled {
+		if saved, err := store.LoadBackendHealth(); err != nil {
+			i18n.Logf(i18n.MainLoadHealthFailed, err)
+		} else {
+			for _, b := range balancers {
+				b.RestoreHealth(saved)
+			}
+		}
 	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	// SIGHUP перечитывает config.yaml и применяет параметры, которые можно менять на лету
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		current := cfg
+		for range reload {
+			current = reloadConfig(configPath, current, balancers, rateLimiter, limits, accessList, reloads)
+		}
+	}()
+
 	go func() {
-		log.Printf("Балансировщик запущен на %s", addr)
-		log.Printf("API доступно по префиксу /clients/")
-		log.Printf("Зарегистрированные бэкенды: %v", cfg.BackendServers)
+		i18n.Logf(i18n.MainListening, addr)
+		i18n.Logf(i18n.MainAPIPrefix)
+		i18n.Logf(i18n.MainBackends, cfg.BackendServers)
 		if cfg.RateLimiter.Enabled {
-			log.Printf("Rate Limiter включен (Store: %T, Header: '%s')", store, cfg.RateLimiter.IdentifierHeader)
+			i18n.Logf(i18n.MainRateLimiterOn, limits, cfg.RateLimiter.IdentifierHeader)
 		} else {
-			log.Printf("Rate Limiter выключен.")
+			i18n.Logf(i18n.MainRateLimiterOff)
 		}
 		if cfg.HealthCheck.Enabled {
-			log.Printf("[Main] Health Checks включены (Interval: %v, Timeout: %v, Path: %s)",
+			i18n.Logf(i18n.MainHealthChecksOn,
 				cfg.HealthCheck.Interval, cfg.HealthCheck.Timeout, cfg.HealthCheck.Path)
 		} else {
-			log.Println("[Main] Health Checks выключены.")
+			i18n.Logf(i18n.MainHealthChecksOff)
 		}
 
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Ошибка запуска сервера: %v", err)
+		// Соединения сверх connection_limit.max_per_ip закрываются листенером до чтения запросов
+		ln, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			i18n.Fatalf(i18n.MainServeFailed, err)
+		}
+		if err := server.Serve(connlimit.Wrap(ln, cfg.ConnectionLimit.MaxPerIP)); err != nil && err != http.ErrServerClosed {
+			i18n.Fatalf(i18n.MainServeFailed, err)
 		}
 	}()
 
+	if tlsServer != nil {
+		go func() {
+			i18n.Logf(i18n.MainTLSListening, tlsServer.Addr, len(cfg.TLS.Sites))
+			ln, err := net.Listen("tcp", tlsServer.Addr)
+			if err != nil {
+				i18n.Fatalf(i18n.MainTLSServeFailed, err)
+			}
+			// Сертификаты берутся из TLSConfig, поэтому пути к файлам не передаются
+			if err := tlsServer.ServeTLS(connlimit.Wrap(ln, cfg.ConnectionLimit.MaxPerIP), "", ""); err != nil && err != http.ErrServerClosed {
+				i18n.Fatalf(i18n.MainTLSServeFailed, err)
+			}
+		}()
+	}
+
+	if fpServer != nil {
+		go func() {
+			i18n.Logf(i18n.MainFPListening, fpServer.Addr, cfg.ForwardProxy.Gateways)
+			if err := fpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				i18n.Fatalf(i18n.MainFPServeFailed, err)
+			}
+		}()
+	}
+
 	// Блокируем main горутину до получения сигнала.
 	<-quit
-	log.Println("Получен сигнал завершения, начинаем Graceful Shutdown...")
+	i18n.Logf(i18n.MainShutdownSignal)
 
 	// Создаем контекст с таймаутом для Shutdown.
 	// Даем серверу 10 секунд на завершение обработки текущих запросов.
@@ -127,32 +457,447 @@ func main() {
 	// Сначала останавливаем Health Checks балансировщика.
 	// Метод StopHealthChecks вызывается всегда, даже если HealthCheck был nil/disabled,
 	// внутри Balancer есть проверка healthCheckStopChan != nil
-	lb.StopHealthChecks()
+	for _, b := range balancers {
+		b.StopHealthChecks()
+	}
+	for _, b := range balancers {
+		if err := b.Wait(shutdownCtx); err != nil {
+			i18n.Logf(i18n.MainBackgroundWaitFailed, "health checks", err)
+		}
+	}
+	// Статус бэкендов сохраняется после последней проверки, чтобы восстановить его при следующем запуске
+	if store != nil && cfg.HealthCheck.Enabled {
+		health := make(map[string]bool)
+		for _, b := range balancers {
+			for url, alive := range b.HealthSnapshot() {
+				health[url] = alive
+			}
+		}
+		if err := store.SaveBackendHealth(health, time.Now()); err != nil {
+			i18n.Logf(i18n.MainSaveHealthFailed, err)
+		}
+	}
+	if udpProxy != nil {
+		udpProxy.Stop()
+		if err := udpProxy.Wait(shutdownCtx); err != nil {
+			i18n.Logf(i18n.MainBackgroundWaitFailed, "udp proxy", err)
+		}
+	}
+	if alertMonitor != nil {
+		alertMonitor.Stop()
+		if err := alertMonitor.Wait(shutdownCtx); err != nil {
+			i18n.Logf(i18n.MainBackgroundWaitFailed, "alerts", err)
+		}
+	}
+
+	if maintainer != nil {
+		maintainer.Stop()
+		if err := maintainer.Wait(shutdownCtx); err != nil {
+			i18n.Logf(i18n.MainBackgroundWaitFailed, "storage maintenance", err)
+		}
+	}
 
-	// Затем останавливаем Ticker в Rate Limiter.
+	// Затем останавливаем Ticker в Rate Limiter и дожидаемся последнего тика пополнения,
+	// чтобы сохранение состояния не пересекалось с ним.
 	if rateLimiter != nil {
 		rateLimiter.Stop()
+		if err := rateLimiter.Wait(shutdownCtx); err != nil {
+			i18n.Logf(i18n.MainBackgroundWaitFailed, "rate limiter refill", err)
+		}
 
 		// Сохраняем текущее состояние корзин Rate Limiter в БД.
 		if err := rateLimiter.SaveState(); err != nil {
 			// Логируем ошибку, но не прерываем Shutdown
-			log.Printf("[Error] Ошибка сохранения состояния Rate Limiter: %v", err)
+			i18n.Logf(i18n.MainSaveStateFailed, err)
 		}
 	}
 
-	// Выполняем Graceful Shutdown сервера.
+	// Выполняем Graceful Shutdown серверов.
+	for _, extra := range []*http.Server{tlsServer, fpServer} {
+		if extra == nil {
+			continue
+		}
+		if err := extra.Shutdown(shutdownCtx); err != nil {
+			i18n.Fatalf(i18n.MainShutdownFailed, err)
+		}
+	}
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("Ошибка при Graceful Shutdown сервера: %v", err)
+		i18n.Fatalf(i18n.MainShutdownFailed, err)
 	} else {
-		log.Println("HTTP-сервер корректно остановлен.")
+		i18n.Logf(i18n.MainServerStopped)
+	}
+
+	// Журнал доступа останавливается после серверов, чтобы попали записи о последних запросах
+	if accessLog != nil {
+		accessLog.Stop()
+		if err := accessLog.Wait(shutdownCtx); err != nil {
+			i18n.Logf(i18n.MainBackgroundWaitFailed, "access log", err)
+		}
+	}
+	// Span последних запросов отправляются до выхода
+	if tracer != nil {
+		tracer.Stop()
+		if err := tracer.Wait(shutdownCtx); err != nil {
+			i18n.Logf(i18n.MainBackgroundWaitFailed, "tracing", err)
+		}
 	}
 
 	// Закрываем соединение с базой данных.
-	if err := store.Close(); err != nil {
-		log.Printf("[Error] Ошибка закрытия БД: %v", err)
+	if err := limits.Close(); err != nil {
+		i18n.Logf(i18n.MainDBCloseFailed, err)
+	} else {
+		i18n.Logf(i18n.MainDBClosed)
+	}
+
+	i18n.Logf(i18n.MainStopped)
+}
+
+// newTLSServer создает HTTPS-сервер, выбирающий сертификат и пул бэкендов по SNI.
+// Сайты без собственного backend_servers обслуживаются общим обработчиком fallback.
+// Возвращает также созданные пулы, чтобы их health checks можно было остановить.
+func newTLSServer(cfg *config.Config, fallback http.Handler, rl *ratelimiter.RateLimiter, monitor *alerting.Monitor) (*http.Server, []*balancer.Balancer) {
+	router := sni.NewRouter(fallback)
+	if cfg.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			i18n.Fatalf(i18n.MainTLSDefaultCertFailed, err)
+		}
+		router.SetDefault(cert)
+	}
+	var pools []*balancer.Balancer
+	for i, site := range cfg.TLS.Sites {
+		cert, err := tls.LoadX509KeyPair(site.CertFile, site.KeyFile)
+		if err != nil {
+			i18n.Fatalf(i18n.MainTLSCertFailed, i, err)
+		}
+
+		var handler http.Handler
+		if len(site.BackendServers) > 0 {
+			pool, err := balancer.New(site.BackendServers, rl, cfg.HealthCheck, cfg.LoadBalancingAlgorithm)
+			if err != nil {
+				i18n.Fatalf(i18n.MainTLSPoolFailed, i, err)
+			}
+			pool.SetRoutes(cfg.Headers, cfg.Routes)
+			pool.SetBackendLabels(cfg.BackendLabels)
+			if cfg.GRPC.Enabled {
+				pool.EnableGRPC()
+			}
+			if monitor != nil {
+				pool.AddObserver(monitor)
+			}
+			pools = append(pools, pool)
+			handler = pool
+		}
+		router.Add(site.ServerNames, cert, handler)
+	}
+
+	tlsConfig := router.TLSConfig()
+	tlsConfig.MinVersion = cfg.TLS.MinVersion
+	tlsConfig.CipherSuites = cfg.TLS.CipherSuiteIDs
+	// Взаимная аутентификация: клиентские сертификаты проверяются по УЦ из tls.client_auth.ca_file
+	if cfg.TLS.ClientAuth.Type != tls.NoClientCert {
+		clientCAs, err := sni.LoadCertPool(cfg.TLS.ClientAuth.CAFile)
+		if err != nil {
+			i18n.Fatalf(i18n.MainTLSClientCAFailed, err)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = cfg.TLS.ClientAuth.Type
+	}
+	return &http.Server{
+		Addr:      config.ListenAddr(cfg.TLS.BindAddress, cfg.TLS.Port),
+		Handler:   metrics.InFlight(requestid.Middleware(router)),
+		TLSConfig: tlsConfig,
+		// Отпечаток JA3 соединения доступен обработчикам (rate_limiter.tls_fingerprint_identity, {tls_fingerprint})
+		ConnContext: router.ConnContext,
+		ConnState:   router.ConnState,
+
+		ReadTimeout:       cfg.Timeouts.Read,
+		ReadHeaderTimeout: cfg.Timeouts.ReadHeader,
+		WriteTimeout:      cfg.Timeouts.Write,
+		IdleTimeout:       cfg.Timeouts.Idle,
+	}, pools
+}
+
+// reloadConfig перечитывает конфигурацию и применяет изменения без перезапуска.
+// Сейчас на лету применяются параметры health_check, лимиты rate_limiter.clients (с учетом шаблонов),
+// планы пробных периодов (rate_limiter.templates), access_list и log_level; остальные секции требуют перезапуска. Отличия от current пишутся в лог и сохраняются в reloads
+// (GET /admin/config/last-reload). Возвращает конфигурацию, действующую после перечитывания.
+func reloadConfig(configPath string, current *config.Config, balancers []*balancer.Balancer, rateLimiter *ratelimiter.RateLimiter, store storage.Store, accessList *access.List, reloads *config.ReloadLog) *config.Config {
+	i18n.Logf(i18n.MainReloading, configPath)
+	report := config.ReloadReport{Time: time.Now(), OldHash: current.Hash}
+	fail := func(err error) *config.Config {
+		i18n.Logf(i18n.MainReloadFailed, err)
+		report.Error = err.Error()
+		reloads.Record(report)
+		return current
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fail(err)
+	}
+	report.NewHash = cfg.Hash
+	report.Changes = config.Diff(current, cfg)
+	logConfigChanges(report.Changes)
+
+	if cfg.HealthCheck.Enabled {
+		for _, lb := range balancers {
+			if err := lb.UpdateHealthCheckConfig(cfg.HealthCheck); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	// Лимиты клиентов пересчитываются с учетом изменений шаблонов (rate_limiter.templates)
+	if _, _, err := ratelimiter.ImportClientLimits(store, cfg.RateLimiter.Clients); err != nil {
+		return fail(err)
+	}
+	rateLimiter.SetPlans(cfg.RateLimiter.Templates)
+	accessList.SetStatic(cfg.AccessList)
+	// Уровень, выставленный через PUT /admin/loglevel, сохраняется, пока logging.level (log_level) в файле не изменится
+	if cfg.LogLevel != current.LogLevel {
+		i18n.SetLevel(cfg.LogLevel)
+	}
+	reloads.Record(report)
+	i18n.Logf(i18n.MainReloaded)
+	return cfg
+}
+
+// logConfigChanges пишет в лог отличия перечитанной конфигурации и изменения, требующие перезапуска.
+func logConfigChanges(changes []config.Change) {
+	if len(changes) == 0 {
+		i18n.Logf(i18n.MainReloadNoChanges)
+		return
+	}
+	applied := make([]string, 0, len(changes))
+	var restart []string
+	for _, change := range changes {
+		if change.RestartRequired {
+			restart = append(restart, change.String())
+		} else {
+			applied = append(applied, change.String())
+		}
+	}
+	if len(applied) > 0 {
+		i18n.Logf(i18n.MainReloadChanges, len(applied), strings.Join(applied, "; "))
+	}
+	if len(restart) > 0 {
+		i18n.Logf(i18n.MainReloadRestartRequired, len(restart), strings.Join(restart, "; "))
+	}
+}
+
+// runSimulate выполняет подкоманду "simulate": моделирует трафик из сценария на конфигурации
+// без запуска листенеров и выводит ожидаемое распределение, отказы 429 и насыщение бэкендов.
+// Возвращает код завершения процесса.
+func runSimulate(args []string) int {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), i18n.T(i18n.SimUsage)) }
+	configPath := flags.String("config", "config.yaml", "")
+	scenarioPath := flags.String("scenario", "", "")
+	asJSON := flags.Bool("json", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *scenarioPath == "" {
+		flags.Usage()
+		return 2
+	}
+
+	// Сообщения загрузки конфигурации не должны смешиваться с отчетом
+	log.SetOutput(io.Discard)
+	cfg, err := config.LoadConfig(*configPath)
+	log.SetOutput(os.Stderr)
+	if err != nil {
+		i18n.Logf(i18n.MainConfigLoadFailed, err)
+		return 1
+	}
+	scenario, err := simulate.LoadScenario(*scenarioPath)
+	if err != nil {
+		i18n.Logf(i18n.SimFailed, err)
+		return 1
+	}
+
+	report := simulate.Run(cfg, scenario)
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		i18n.Logf(i18n.SimFailed, err)
+		return 1
+	}
+	return 0
+}
+
+// runRateLimitTest выполняет подкоманду "ratelimit-test": нагружает Rate Limiter с хранилищем из конфигурации
+// и лимитами из флагов и сравнивает пропущенные запросы с ожидаемыми. Возвращает код завершения процесса:
+// 0 - лимиты соблюдаются, 1 - обнаружены проблемы, 2 - неверные аргументы.
+func runRateLimitTest(args []string) int {
+	flags := flag.NewFlagSet("ratelimit-test", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), i18n.T(i18n.RLTestUsage)) }
+	configPath := flags.String("config", "config.yaml", "")
+	var opts conformance.Options
+	flags.Float64Var(&opts.Rate, "rate", 10, "")
+	flags.Float64Var(&opts.Capacity, "capacity", 20, "")
+	flags.DurationVar(&opts.Duration, "duration", 30*time.Second, "")
+	flags.IntVar(&opts.Concurrency, "concurrency", 4, "")
+	flags.Float64Var(&opts.Tolerance, "tolerance", conformance.DefaultTolerance, "")
+	asJSON := flags.Bool("json", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		flags.Usage()
+		return 2
+	}
+
+	// Сообщения загрузки конфигурации и Rate Limiter не должны смешиваться с отчетом
+	log.SetOutput(io.Discard)
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.SetOutput(os.Stderr)
+		i18n.Logf(i18n.MainConfigLoadFailed, err)
+		return 1
+	}
+	var store ratelimiter.StoreConfigInterface
+	if cfg.RateLimiter.DatabasePath != "" {
+		db, err := storage.OpenSQLiteDB(cfg.RateLimiter.DatabasePath, cfg.SQLite)
+		if err != nil {
+			log.SetOutput(os.Stderr)
+			i18n.Logf(i18n.MainSQLiteFailed, err)
+			return 1
+		}
+		defer db.Close()
+		store = db
+	}
+
+	report, err := conformance.Run(cfg.RateLimiter, store, opts)
+	log.SetOutput(os.Stderr)
+	if err != nil {
+		i18n.Logf(i18n.RLTestFailed, err)
+		return 1
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		i18n.Logf(i18n.RLTestFailed, err)
+		return 1
+	}
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
+
+// runStorage выполняет подкоманду "storage migrate": переносит клиентов, списки доступа и статус бэкендов
+// из хранилища -from в пустое хранилище -to и сверяет перенесенные данные. Адрес хранилища -
+// "<драйвер>:<строка подключения>" (см. storage.OpenStore). Возвращает код завершения процесса.
+func runStorage(args []string) int {
+	flags := flag.NewFlagSet("storage migrate", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), i18n.T(i18n.StorageMigrateUsage)) }
+	if len(args) == 0 || args[0] != "migrate" {
+		flags.Usage()
+		return 2
+	}
+	from := flags.String("from", "", "")
+	to := flags.String("to", "", "")
+	asJSON := flags.Bool("json", false, "")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *from == "" || *to == "" {
+		flags.Usage()
+		return 2
+	}
+
+	// Сообщения открытия хранилищ не должны смешиваться с отчетом
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	source, err := storage.OpenStore(*from)
+	if err != nil {
+		log.SetOutput(os.Stderr)
+		i18n.Logf(i18n.StorageMigrateOpenFailed, *from, err)
+		return 1
+	}
+	defer source.Close()
+	destination, err := storage.OpenStore(*to)
+	if err != nil {
+		log.SetOutput(os.Stderr)
+		i18n.Logf(i18n.StorageMigrateOpenFailed, *to, err)
+		return 1
+	}
+	defer destination.Close()
+
+	report, err := storage.Migrate(source, destination)
+	log.SetOutput(os.Stderr)
+	if err != nil {
+		i18n.Logf(i18n.StorageMigrateFailed, err)
+		return 1
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
 	} else {
-		log.Println("Соединение с БД закрыто.")
+		_, err = fmt.Println(i18n.T(i18n.StorageMigrateReport, report.Clients, report.AccessEntries, report.BackendHealth, report.APIKeys))
+	}
+	if err != nil {
+		i18n.Logf(i18n.StorageMigrateFailed, err)
+		return 1
+	}
+	return 0
+}
+
+// runBench выполняет подкоманду "bench": отправляет на -target запросы с частотой -rps от -clients клиентов
+// с разными идентификаторами и выводит время ответа, долю отказов и распределение по бэкендам.
+// Прерывание (Ctrl+C) завершает нагрузку досрочно с отчетом. Возвращает код завершения процесса.
+func runBench(args []string) int {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), i18n.T(i18n.BenchUsage)) }
+	var opts bench.Options
+	flags.StringVar(&opts.Target, "target", "", "")
+	flags.Float64Var(&opts.RPS, "rps", 100, "")
+	flags.IntVar(&opts.Clients, "clients", 10, "")
+	flags.DurationVar(&opts.Duration, "duration", 30*time.Second, "")
+	flags.IntVar(&opts.Concurrency, "concurrency", 64, "")
+	flags.DurationVar(&opts.Timeout, "timeout", 5*time.Second, "")
+	flags.StringVar(&opts.ClientHeader, "client-header", bench.DefaultClientHeader, "")
+	flags.StringVar(&opts.TraceToken, "trace-token", "", "")
+	asJSON := flags.Bool("json", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		flags.Usage()
+		return 2
 	}
 
-	log.Println("Балансировщик успешно завершил работу.")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report, err := bench.Run(ctx, opts)
+	if err != nil {
+		i18n.Logf(i18n.BenchFailed, err)
+		return 1
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		i18n.Logf(i18n.BenchFailed, err)
+		return 1
+	}
+	return 0
 }
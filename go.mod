@@ -4,6 +4,7 @@ go 1.24.2
 
 require (
 	github.com/stretchr/testify v1.10.0
+	go.starlark.net v0.0.0-20231121155337-90ade8b19d09
 	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/sqlite v1.37.0
 )
@@ -0,0 +1,337 @@
+package access
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+	"load-balancer/internal/storage"
+)
+
+var (
+	deniedTotal = metrics.NewCounter("access_list_denied_total",
+		"Количество запросов, отклоненных списком запрета.")
+	allowedTotal = metrics.NewCounter("access_list_allowed_total",
+		"Количество запросов клиентов из списка разрешения, пропущенных без проверки Rate Limiter.")
+)
+
+// ErrStoreUnavailable возвращается при изменении списков, если хранилище не подключено.
+var ErrStoreUnavailable = i18n.NewError(i18n.AccessStoreUnavailable)
+
+// Decision - результат проверки клиента по спискам доступа.
+type Decision int
+
+const (
+	// NoMatch - клиента нет в списках, запрос обрабатывается как обычно.
+	NoMatch Decision = iota
+	// Allowed - клиент в списке разрешения и не ограничивается Rate Limiter.
+	Allowed
+	// Denied - клиент в списке запрета, запрос отклоняется.
+	Denied
+)
+
+// Store - хранилище записей, добавленных через API (реализуется *storage.DB).
+type Store interface {
+	PutAccessEntry(entry storage.AccessEntry) error
+	DeleteAccessEntry(value string) error
+	ListAccessEntries() ([]storage.AccessEntry, error)
+	DeleteExpiredAccessEntries(now time.Time) (int64, error)
+}
+
+// Entry - запись списка доступа с указанием источника.
+type Entry struct {
+	storage.AccessEntry
+	Static bool // Запись из config.yaml: через API не удаляется.
+}
+
+// rule - скомпилированная запись для проверки запросов.
+type rule struct {
+	list      string
+	expiresAt time.Time
+}
+
+// table - неизменяемый набор правил; заменяется целиком при каждом изменении списков.
+type table struct {
+	ids      map[string][]rule // Записи-идентификаторы клиентов.
+	prefixes []prefixRule      // Записи-адреса и подсети.
+}
+
+type prefixRule struct {
+	prefix netip.Prefix
+	rule
+}
+
+// List - списки запрета и разрешения. Постоянные записи берутся из конфигурации,
+// записи с причиной и сроком действия добавляются через API и сохраняются в хранилище,
+// поэтому переживают перезапуск. Проверка запросов не берет блокировок.
+type List struct {
+	store Store // nil - только записи из конфигурации.
+
+	mu      sync.Mutex
+	static  []storage.AccessEntry
+	dynamic map[string]storage.AccessEntry
+	rules   atomic.Pointer[table]
+}
+
+// New создает списки из секции access_list конфигурации и загружает записи из хранилища
+// (истекшие записи при этом удаляются). store может быть nil.
+func New(cfg config.AccessListConfig, store Store) (*List, error) {
+	l := &List{store: store, dynamic: make(map[string]storage.AccessEntry)}
+	l.static = staticEntries(cfg)
+	if store != nil {
+		now := time.Now()
+		if _, err := store.DeleteExpiredAccessEntries(now); err != nil {
+			return nil, err
+		}
+		entries, err := store.ListAccessEntries()
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.Expired(now) {
+				l.dynamic[entry.Value] = entry
+			}
+		}
+	}
+	l.compile()
+	i18n.Logf(i18n.AccessLoaded, len(l.static), len(l.dynamic))
+	return l, nil
+}
+
+// SetStatic заменяет записи из конфигурации (при перечитывании config.yaml).
+func (l *List) SetStatic(cfg config.AccessListConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.static = staticEntries(cfg)
+	l.compileLocked()
+}
+
+// Persistent сообщает, подключено ли хранилище, т.е. можно ли менять списки через API.
+func (l *List) Persistent() bool {
+	return l.store != nil
+}
+
+// Decide проверяет клиента по спискам. clientID - идентификатор из Rate Limiter: IP-адрес,
+// IPv6-подсеть (при rate_limiter.ipv6_prefix_length) или значение заголовка identifier_header.
+// Если клиент есть в обоих списках, действует запрет.
+func (l *List) Decide(clientID string) Decision {
+	t := l.rules.Load()
+	var matched []rule
+	matched = append(matched, t.ids[clientID]...)
+	if len(t.prefixes) > 0 {
+		if client, ok := clientPrefix(clientID); ok {
+			for _, pr := range t.prefixes {
+				// Подсеть клиента совпадает, если целиком входит в подсеть записи
+				if pr.prefix.Bits() <= client.Bits() && pr.prefix.Contains(client.Addr()) {
+					matched = append(matched, pr.rule)
+				}
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return NoMatch
+	}
+
+	now := time.Now()
+	decision := NoMatch
+	for _, r := range matched {
+		if !r.expiresAt.IsZero() && !now.Before(r.expiresAt) {
+			continue
+		}
+		if r.list == config.AccessListDeny {
+			deniedTotal.Inc()
+			return Denied
+		}
+		decision = Allowed
+	}
+	if decision == Allowed {
+		allowedTotal.Inc()
+	}
+	return decision
+}
+
+// Put добавляет запись в список (или заменяет запись с тем же значением) и сохраняет ее в хранилище.
+// Значение должно пройти config.ValidateAccessEntry; ttl <= 0 - запись бессрочная.
+func (l *List) Put(value, list, reason string, ttl time.Duration) (storage.AccessEntry, error) {
+	if l.store == nil {
+		return storage.AccessEntry{}, ErrStoreUnavailable
+	}
+	entry := storage.AccessEntry{
+		Value:     Normalize(value),
+		List:      list,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	if ttl > 0 {
+		entry.ExpiresAt = entry.CreatedAt.Add(ttl)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.store.PutAccessEntry(entry); err != nil {
+		return storage.AccessEntry{}, err
+	}
+	l.dynamic[entry.Value] = entry
+	l.purgeLocked(entry.CreatedAt)
+	l.compileLocked()
+	return entry, nil
+}
+
+// Delete удаляет запись, добавленную через API. Записи из конфигурации не удаляются:
+// для них, как и для отсутствующих записей, возвращается storage.ErrAccessEntryNotFound.
+func (l *List) Delete(value string) error {
+	if l.store == nil {
+		return ErrStoreUnavailable
+	}
+	value = Normalize(value)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.store.DeleteAccessEntry(value); err != nil {
+		return err
+	}
+	delete(l.dynamic, value)
+	l.compileLocked()
+	return nil
+}
+
+// Get возвращает действующую запись с указанным значением; запись из хранилища имеет приоритет.
+func (l *List) Get(value string) (Entry, bool) {
+	value = Normalize(value)
+	for _, entry := range l.Entries() {
+		if entry.Value == value {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Entries возвращает действующие записи обоих списков, отсортированные по значению:
+// сначала записи из хранилища, затем из конфигурации. Истекшие записи при этом удаляются.
+func (l *List) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.purgeLocked(time.Now()) {
+		l.compileLocked()
+	}
+
+	entries := make([]Entry, 0, len(l.dynamic)+len(l.static))
+	for _, entry := range l.dynamic {
+		entries = append(entries, Entry{AccessEntry: entry})
+	}
+	for _, entry := range l.static {
+		entries = append(entries, Entry{AccessEntry: entry, Static: true})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Value != entries[j].Value {
+			return entries[i].Value < entries[j].Value
+		}
+		return !entries[i].Static && entries[j].Static
+	})
+	return entries
+}
+
+// purgeLocked удаляет истекшие записи из памяти и хранилища. Сообщает, были ли удалены записи в памяти.
+func (l *List) purgeLocked(now time.Time) bool {
+	purged := false
+	for value, entry := range l.dynamic {
+		if entry.Expired(now) {
+			delete(l.dynamic, value)
+			purged = true
+		}
+	}
+	if purged {
+		// Ошибка не мешает работе: истекшие записи игнорируются и будут удалены при следующей попытке
+		if _, err := l.store.DeleteExpiredAccessEntries(now); err != nil {
+			i18n.Logf(i18n.AccessPurgeFailed, err)
+		}
+	}
+	return purged
+}
+
+func (l *List) compile() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.compileLocked()
+}
+
+// compileLocked пересобирает таблицу правил из текущих записей.
+func (l *List) compileLocked() {
+	t := &table{ids: make(map[string][]rule)}
+	add := func(entry storage.AccessEntry) {
+		r := rule{list: entry.List, expiresAt: entry.ExpiresAt}
+		if prefix, ok := entryPrefix(entry.Value); ok {
+			t.prefixes = append(t.prefixes, prefixRule{prefix: prefix, rule: r})
+			return
+		}
+		t.ids[entry.Value] = append(t.ids[entry.Value], r)
+	}
+	for _, entry := range l.static {
+		add(entry)
+	}
+	for _, entry := range l.dynamic {
+		add(entry)
+	}
+	l.rules.Store(t)
+}
+
+func staticEntries(cfg config.AccessListConfig) []storage.AccessEntry {
+	entries := make([]storage.AccessEntry, 0, len(cfg.Deny)+len(cfg.Allow))
+	for _, value := range cfg.Deny {
+		entries = append(entries, storage.AccessEntry{Value: Normalize(value), List: config.AccessListDeny})
+	}
+	for _, value := range cfg.Allow {
+		entries = append(entries, storage.AccessEntry{Value: Normalize(value), List: config.AccessListAllow})
+	}
+	return entries
+}
+
+// Normalize приводит адрес или подсеть к каноническому виду ("10.1.2.3/8" -> "10.0.0.0/8",
+// "::ffff:10.0.0.1" -> "10.0.0.1"), чтобы одна и та же запись не хранилась под разными значениями.
+// Идентификаторы клиентов не меняются.
+func Normalize(value string) string {
+	if strings.Contains(value, "/") {
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+				prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+			}
+			return prefix.Masked().String()
+		}
+		return value
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return addr.Unmap().WithZone("").String()
+	}
+	return value
+}
+
+// entryPrefix возвращает подсеть записи-адреса или записи-подсети (адрес - подсеть /32 или /128).
+func entryPrefix(value string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		return prefix, true
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	return netip.Prefix{}, false
+}
+
+// clientPrefix разбирает идентификатор клиента Rate Limiter как адрес или IPv6-подсеть.
+func clientPrefix(clientID string) (netip.Prefix, bool) {
+	if !strings.Contains(clientID, "/") {
+		addr, err := netip.ParseAddr(clientID)
+		if err != nil {
+			return netip.Prefix{}, false
+		}
+		addr = addr.Unmap().WithZone("")
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	prefix, err := netip.ParsePrefix(clientID)
+	return prefix, err == nil
+}
@@ -0,0 +1,88 @@
+package access_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/access"
+	"load-balancer/internal/config"
+	"load-balancer/internal/storage"
+)
+
+// TestList_Decide проверяет сопоставление адресов, подсетей и ID клиентов с записями из конфигурации.
+func TestList_Decide(t *testing.T) {
+	list, err := access.New(config.AccessListConfig{
+		Deny:  []string{"203.0.113.7", "2001:db8:bad::/48", "scraper", "10.1.2.3"},
+		Allow: []string{"10.0.0.0/8", "partner"},
+	}, nil)
+	require.NoError(t, err)
+
+	cases := map[string]access.Decision{
+		"203.0.113.7":         access.Denied,
+		"203.0.113.8":         access.NoMatch,
+		"::ffff:203.0.113.7":  access.Denied, // IPv4-mapped адрес
+		"2001:db8:bad::1":     access.Denied,
+		"2001:db8:bad:1::/64": access.Denied, // IPv6-подсеть клиента внутри запрещенной
+		"2001:db8::/32":       access.NoMatch,
+		"scraper":             access.Denied,
+		"10.20.30.40":         access.Allowed,
+		"10.1.2.3":            access.Denied, // Запрет важнее разрешения
+		"partner":             access.Allowed,
+		"unknown":             access.NoMatch,
+	}
+	for clientID, want := range cases {
+		assert.Equal(t, want, list.Decide(clientID), clientID)
+	}
+
+	list.SetStatic(config.AccessListConfig{})
+	assert.Equal(t, access.NoMatch, list.Decide("scraper"), "Записи из конфигурации заменяются при перечитывании")
+
+	_, err = list.Put("scraper", config.AccessListDeny, "", 0)
+	require.ErrorIs(t, err, access.ErrStoreUnavailable, "Без хранилища списки меняются только через конфигурацию")
+}
+
+// TestList_Persistence проверяет, что записи из API переживают перезапуск, а истекшие перестают действовать.
+func TestList_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.db")
+	db, err := storage.NewSQLiteDB(path)
+	require.NoError(t, err)
+
+	list, err := access.New(config.AccessListConfig{Allow: []string{"partner"}}, db)
+	require.NoError(t, err)
+	entry, err := list.Put("10.1.2.3/16", config.AccessListDeny, "abuse", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.0.0/16", entry.Value, "Подсеть приводится к каноническому виду")
+	_, err = list.Put("flaky", config.AccessListDeny, "", 50*time.Millisecond)
+	require.NoError(t, err)
+
+	assert.Equal(t, access.Denied, list.Decide("10.1.200.1"))
+	assert.Equal(t, access.Denied, list.Decide("flaky"))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, access.NoMatch, list.Decide("flaky"), "Истекшая запись не действует")
+
+	require.ErrorIs(t, list.Delete("partner"), storage.ErrAccessEntryNotFound, "Записи из конфигурации через API не удаляются")
+	require.NoError(t, db.Close())
+
+	// Перезапуск: записи загружаются из хранилища
+	db, err = storage.NewSQLiteDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+	restarted, err := access.New(config.AccessListConfig{}, db)
+	require.NoError(t, err)
+	assert.Equal(t, access.Denied, restarted.Decide("10.1.200.1"))
+
+	entries := restarted.Entries()
+	require.Len(t, entries, 1, "Истекшая запись удалена")
+	assert.Equal(t, "abuse", entries[0].Reason)
+	assert.False(t, entries[0].Static)
+
+	require.NoError(t, restarted.Delete("10.1.0.0/16"))
+	assert.Equal(t, access.NoMatch, restarted.Decide("10.1.200.1"))
+	stored, err := db.ListAccessEntries()
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+}
@@ -0,0 +1,154 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+)
+
+// stdoutAddress - адрес приемника file, означающий стандартный вывод процесса.
+const stdoutAddress = "stdout"
+
+// clfTime - формат времени Common Log Format.
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// fileSink пишет записи в файл или в стандартный вывод в формате JSON (запись на строку) или Common Log Format.
+// Файл ротируется по размеру: access.log переименовывается в access.log.1, прежний access.log.1 - в access.log.2
+// и так далее до maxBackups; более старые файлы удаляются. Пачки пишет одна горутина (Shipper.run),
+// поэтому блокировки не нужны.
+type fileSink struct {
+	path       string // Пусто - стандартный вывод.
+	format     string
+	maxSize    int64 // 0 - без ротации.
+	maxBackups int
+
+	out  io.Writer
+	file *os.File
+	size int64
+}
+
+func newFileSink(cfg config.AccessLogConfig) (*fileSink, error) {
+	s := &fileSink{format: cfg.Format, maxSize: int64(cfg.MaxSizeMB) << 20, maxBackups: cfg.MaxBackups}
+	if cfg.Address == stdoutAddress {
+		s.out = os.Stdout
+		s.maxSize = 0
+		return s, nil
+	}
+	s.path = cfg.Address
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open открывает файл для дописывания и запоминает его текущий размер.
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file, s.out, s.size = file, file, info.Size()
+	return nil
+}
+
+func (s *fileSink) send(_ context.Context, batch []Entry) error {
+	var buf bytes.Buffer
+	for _, entry := range batch {
+		if err := s.encode(&buf, entry); err != nil {
+			return err
+		}
+	}
+	if s.path != "" && s.file == nil {
+		// Новый файл не открылся при прошлой ротации: пробуем снова при повторе
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(buf.Len()) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.out.Write(buf.Bytes())
+	s.size += int64(n)
+	return err
+}
+
+// encode добавляет запись в buf строкой в формате приемника.
+func (s *fileSink) encode(buf *bytes.Buffer, entry Entry) error {
+	if s.format != config.AccessLogFormatCommon {
+		return json.NewEncoder(buf).Encode(entry)
+	}
+	buf.WriteString(formatCommon(entry))
+	buf.WriteByte('\n')
+	return nil
+}
+
+// formatCommon форматирует запись в Common Log Format: вместо адреса клиента - ID клиента, за размером ответа
+// следуют бэкенд, время обработки в миллисекундах и ID запроса ("-", если значения нет):
+//
+//	10.0.0.7 - - [02/Jan/2006:15:04:05 +0000] "GET /api HTTP/1.1" 200 512 "http://b1:8080" 12.345 req-1
+func formatCommon(entry Entry) string {
+	bytesSent := "-"
+	if entry.Bytes > 0 {
+		bytesSent = strconv.FormatInt(entry.Bytes, 10)
+	}
+	request := entry.Method + " " + entry.Path
+	if entry.Protocol != "" {
+		request += " " + entry.Protocol
+	}
+	var b strings.Builder
+	b.WriteString(orDash(entry.ClientID) + " - - [" + entry.Time.Format(clfTime) + "] ")
+	b.WriteString(strconv.Quote(request) + " " + strconv.Itoa(entry.Status) + " " + bytesSent + " ")
+	b.WriteString(strconv.Quote(orDash(entry.Backend)) + " ")
+	b.WriteString(strconv.FormatFloat(entry.DurationMS, 'f', 3, 64) + " " + orDash(entry.RequestID))
+	return b.String()
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// rotate сдвигает ротированные файлы (самый старый удаляется) и начинает новый файл.
+func (s *fileSink) rotate() error {
+	err := s.file.Close()
+	s.file, s.out = nil, nil
+	if err != nil {
+		return err
+	}
+	backup := func(n int) string { return s.path + "." + strconv.Itoa(n) }
+	_ = os.Remove(backup(s.maxBackups))
+	for n := s.maxBackups - 1; n >= 1; n-- {
+		_ = os.Rename(backup(n), backup(n+1)) // Файла может не быть: ротаций было меньше maxBackups
+	}
+	if err := os.Rename(s.path, backup(1)); err != nil {
+		return err
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+	i18n.Logf(i18n.AccessLogRotated, s.path, s.maxBackups)
+	return nil
+}
+
+func (s *fileSink) close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,272 @@
+package accesslog
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+// maxRetryBackoff - наибольшая пауза между повторами отправки пачки.
+const maxRetryBackoff = 5 * time.Second
+
+var (
+	shippedTotal = metrics.NewCounter("accesslog_shipped_total",
+		"Количество записей журнала доступа, доставленных в приемник.")
+	droppedTotal = metrics.NewCounter("accesslog_dropped_total",
+		"Количество записей журнала доступа, отброшенных из-за переполнения очереди.")
+	failedTotal = metrics.NewCounter("accesslog_failed_total",
+		"Количество записей журнала доступа, потерянных после исчерпания повторов отправки.")
+)
+
+// Entry - запись журнала доступа об одном запросе.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id,omitempty"`
+	ClientID   string    `json:"client_id"`
+	Method     string    `json:"method"`
+	Protocol   string    `json:"protocol,omitempty"`
+	Host       string    `json:"host"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMS float64   `json:"duration_ms"`
+	Backend    string    `json:"backend,omitempty"` // Пусто, если запрос не дошел до бэкенда.
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// sink - приемник журнала доступа.
+type sink interface {
+	// send доставляет пачку целиком; при ошибке пачка отправляется повторно (возможны дубликаты).
+	send(ctx context.Context, batch []Entry) error
+	close() error
+}
+
+// Shipper отправляет журнал доступа в приемник пачками из фоновой горутины.
+// Log никогда не блокирует обработку запроса: при переполнении очереди записи отбрасываются и учитываются в метриках.
+type Shipper struct {
+	cfg   config.AccessLogConfig
+	sink  sink
+	queue chan Entry
+
+	dropped  atomic.Uint64 // Отброшено с момента последнего предупреждения в лог.
+	quit     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup // Горутина отправки (см. Wait)
+}
+
+// New создает Shipper по секции access_log конфигурации. Отправка начинается после Start.
+func New(cfg config.AccessLogConfig) (*Shipper, error) {
+	var s sink
+	switch cfg.Sink {
+	case config.AccessLogSinkSyslog:
+		var err error
+		if s, err = newSyslogSink(cfg.Address); err != nil {
+			return nil, err
+		}
+	case config.AccessLogSinkKafka:
+		s = newKafkaSink(cfg.Address, cfg.Topic, cfg.Headers)
+	case config.AccessLogSinkFile:
+		var err error
+		if s, err = newFileSink(cfg); err != nil {
+			return nil, err
+		}
+	default:
+		s = newHTTPSink(cfg.Address, cfg.Headers)
+	}
+	return &Shipper{
+		cfg:   cfg,
+		sink:  s,
+		queue: make(chan Entry, cfg.QueueSize),
+		quit:  make(chan struct{}),
+	}, nil
+}
+
+// Start запускает фоновую отправку.
+func (s *Shipper) Start() {
+	i18n.Logf(i18n.AccessLogStarted, s.cfg.Sink, s.cfg.Address, s.cfg.BatchSize, s.cfg.FlushInterval, s.cfg.QueueSize)
+	s.wg.Add(1)
+	go s.run()
+}
+
+// Log ставит запись в очередь на отправку.
+func (s *Shipper) Log(entry Entry) {
+	select {
+	case s.queue <- entry:
+	default:
+		droppedTotal.Inc()
+		s.dropped.Add(1)
+	}
+}
+
+// Stop останавливает отправку: записи, уже стоящие в очереди, отправляются по одному разу без повторов.
+func (s *Shipper) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.quit)
+	})
+}
+
+// Wait дожидается отправки оставшихся записей после Stop или истечения ctx.
+func (s *Shipper) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Shipper) run() {
+	defer s.wg.Done()
+	defer s.sink.close()
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, s.cfg.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			s.ship(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) == s.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			if dropped := s.dropped.Swap(0); dropped > 0 {
+				i18n.Logf(i18n.AccessLogDropped, dropped, s.cfg.QueueSize)
+			}
+		case <-s.quit:
+			for {
+				select {
+				case entry := <-s.queue:
+					batch = append(batch, entry)
+					if len(batch) == s.cfg.BatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// ship отправляет пачку, повторяя попытки с экспоненциальной паузой. Пока идут повторы,
+// новые записи накапливаются в очереди; после исчерпания повторов пачка отбрасывается.
+func (s *Shipper) ship(batch []Entry) {
+	backoff := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+		err := s.sink.send(ctx, batch)
+		cancel()
+		if err == nil {
+			shippedTotal.Add(uint64(len(batch)))
+			return
+		}
+
+		if attempt >= s.cfg.MaxRetries || s.stopping() {
+			failedTotal.Add(uint64(len(batch)))
+			i18n.Logf(i18n.AccessLogBatchLost, len(batch), err)
+			return
+		}
+		i18n.Logf(i18n.AccessLogSendFailed, attempt+1, s.cfg.MaxRetries, err, backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-s.quit:
+			timer.Stop()
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+func (s *Shipper) stopping() bool {
+	select {
+	case <-s.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// ResponseWriter запоминает статус и размер ответа для записи журнала доступа.
+// Flush доступен и через Unwrap (http.ResponseController); Hijack при смене протокола запоминает статус 101.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// NewResponseWriter оборачивает w.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+func (lw *ResponseWriter) WriteHeader(code int) {
+	if lw.status == 0 && code >= http.StatusOK {
+		lw.status = code
+	}
+	lw.ResponseWriter.WriteHeader(code)
+}
+
+func (lw *ResponseWriter) Write(p []byte) (int, error) {
+	if lw.status == 0 {
+		lw.status = http.StatusOK
+	}
+	n, err := lw.ResponseWriter.Write(p)
+	lw.bytes += int64(n)
+	return n, err
+}
+
+// Flush нужен потоковым ответам (gRPC, SSE), которые проверяют http.Flusher напрямую.
+func (lw *ResponseWriter) Flush() {
+	_ = http.NewResponseController(lw.ResponseWriter).Flush()
+}
+
+// Hijack вызывается прокси после ответа бэкенда 101 (WebSocket): данные сессии в размер ответа не входят.
+func (lw *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(lw.ResponseWriter).Hijack()
+	if err == nil && lw.status == 0 {
+		lw.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+func (lw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
+// Status возвращает код ответа (200, если обработчик ничего не записал явно).
+func (lw *ResponseWriter) Status() int {
+	if lw.status == 0 {
+		return http.StatusOK
+	}
+	return lw.status
+}
+
+// BytesWritten возвращает размер тела ответа.
+func (lw *ResponseWriter) BytesWritten() int64 {
+	return lw.bytes
+}
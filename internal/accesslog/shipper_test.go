@@ -0,0 +1,277 @@
+package accesslog_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/accesslog"
+	"load-balancer/internal/config"
+)
+
+func newShipper(t *testing.T, cfg config.AccessLogConfig) *accesslog.Shipper {
+	t.Helper()
+	cfg.Enabled = true
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 10
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 100
+	}
+	cfg.FlushInterval = 20 * time.Millisecond
+	cfg.Timeout = time.Second
+	shipper, err := accesslog.New(cfg)
+	require.NoError(t, err)
+	return shipper
+}
+
+func stop(t *testing.T, shipper *accesslog.Shipper) {
+	t.Helper()
+	shipper.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, shipper.Wait(ctx))
+}
+
+// collector - HTTP-приемник, запоминающий тела запросов.
+type collector struct {
+	mu       sync.Mutex
+	bodies   []string
+	headers  []http.Header
+	failures int // Сколько первых запросов завершить ошибкой 503.
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failures > 0 {
+		c.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	c.bodies = append(c.bodies, string(body))
+	c.headers = append(c.headers, r.Header.Clone())
+}
+
+func (c *collector) received() ([]string, []http.Header) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.bodies...), append([]http.Header(nil), c.headers...)
+}
+
+// TestShipper_HTTP проверяет отправку пачек NDJSON, дополнительные заголовки и повтор после ошибки приемника.
+func TestShipper_HTTP(t *testing.T) {
+	sink := &collector{failures: 1}
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	shipper := newShipper(t, config.AccessLogConfig{
+		Sink: config.AccessLogSinkHTTP, Address: server.URL, BatchSize: 2, MaxRetries: 2,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	})
+	shipper.Start()
+	for i := 0; i < 3; i++ {
+		shipper.Log(accesslog.Entry{Method: http.MethodGet, Path: "/" + strconv.Itoa(i), Status: 200})
+	}
+	// Первая пачка доставляется со второй попытки, остаток - по flush_interval
+	require.Eventually(t, func() bool {
+		bodies, _ := sink.received()
+		return len(bodies) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	stop(t, shipper)
+
+	bodies, headers := sink.received()
+	require.Len(t, bodies, 2)
+	var paths []string
+	for i, body := range bodies {
+		assert.Equal(t, "application/x-ndjson", headers[i].Get("Content-Type"))
+		assert.Equal(t, "Bearer token", headers[i].Get("Authorization"))
+		for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+			var entry accesslog.Entry
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			paths = append(paths, entry.Path)
+		}
+	}
+	assert.Equal(t, []string{"/0", "/1", "/2"}, paths)
+}
+
+// TestShipper_Kafka проверяет формат запроса к Kafka REST Proxy.
+func TestShipper_Kafka(t *testing.T) {
+	sink := &collector{}
+	mux := http.NewServeMux()
+	mux.Handle("/topics/access-log", sink)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	shipper := newShipper(t, config.AccessLogConfig{Sink: config.AccessLogSinkKafka, Address: server.URL + "/", Topic: "access-log"})
+	shipper.Start()
+	shipper.Log(accesslog.Entry{ClientID: "user1", Status: 429})
+	stop(t, shipper)
+
+	bodies, headers := sink.received()
+	require.Len(t, bodies, 1)
+	assert.Equal(t, "application/vnd.kafka.json.v2+json", headers[0].Get("Content-Type"))
+	var payload struct {
+		Records []struct {
+			Value accesslog.Entry `json:"value"`
+		} `json:"records"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(bodies[0]), &payload))
+	require.Len(t, payload.Records, 1)
+	assert.Equal(t, "user1", payload.Records[0].Value.ClientID)
+	assert.Equal(t, 429, payload.Records[0].Value.Status)
+}
+
+// TestShipper_Syslog проверяет сообщения RFC 5424 по UDP и TCP (octet counting).
+func TestShipper_Syslog(t *testing.T) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer udp.Close()
+
+	shipper := newShipper(t, config.AccessLogConfig{Sink: config.AccessLogSinkSyslog, Address: "udp://" + udp.LocalAddr().String()})
+	shipper.Start()
+	shipper.Log(accesslog.Entry{Path: "/udp", Status: 200})
+
+	buf := make([]byte, 4096)
+	udp.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := udp.ReadFrom(buf)
+	require.NoError(t, err)
+	message := string(buf[:n])
+	assert.True(t, strings.HasPrefix(message, "<134>1 "), message)
+	assert.Contains(t, message, " load-balancer - access - {")
+	assert.Contains(t, message, `"path":"/udp"`)
+	stop(t, shipper)
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer tcp.Close()
+	lines := make(chan string, 2)
+	go func() {
+		conn, err := tcp.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			size, _ := strconv.Atoi(strings.TrimSpace(length))
+			frame := make([]byte, size)
+			if _, err := io.ReadFull(reader, frame); err != nil {
+				return
+			}
+			lines <- string(frame)
+		}
+	}()
+
+	shipper = newShipper(t, config.AccessLogConfig{Sink: config.AccessLogSinkSyslog, Address: "tcp://" + tcp.Addr().String()})
+	shipper.Start()
+	shipper.Log(accesslog.Entry{Path: "/a"})
+	shipper.Log(accesslog.Entry{Path: "/b"})
+	stop(t, shipper)
+	assert.Contains(t, <-lines, `"path":"/a"`)
+	assert.Contains(t, <-lines, `"path":"/b"`)
+}
+
+// TestShipper_File проверяет запись в файл в Common Log Format и ротацию по размеру.
+func TestShipper_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	shipper := newShipper(t, config.AccessLogConfig{Sink: config.AccessLogSinkFile, Address: path,
+		Format: config.AccessLogFormatCommon, MaxSizeMB: 1, MaxBackups: 1, BatchSize: 1})
+	shipper.Start()
+
+	at := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	shipper.Log(accesslog.Entry{Time: at, RequestID: "req-1", ClientID: "c1", Method: "GET", Protocol: "HTTP/1.1",
+		Path: "/api", Status: 200, Bytes: 512, DurationMS: 12.3456, Backend: "http://b1:8080"})
+	// Запись больше мегабайта не помещается в файл и вызывает ротацию
+	long := strings.Repeat("x", 1<<20)
+	shipper.Log(accesslog.Entry{Time: at, ClientID: "c2", Method: "GET", Path: "/" + long, Status: 429})
+	stop(t, shipper)
+
+	rotated, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, `c1 - - [05/Mar/2024:10:00:00 +0000] "GET /api HTTP/1.1" 200 512 "http://b1:8080" 12.346 req-1`+"\n",
+		string(rotated))
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(current), `c2 - - [05/Mar/2024:10:00:00 +0000] "GET /x`))
+	assert.True(t, strings.HasSuffix(string(current), `" 429 - "-" 0.000 -`+"\n"))
+}
+
+// TestShipper_FileJSON проверяет запись в файл по записи JSON на строку (формат по умолчанию).
+func TestShipper_FileJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	shipper := newShipper(t, config.AccessLogConfig{Sink: config.AccessLogSinkFile, Address: path})
+	shipper.Start()
+	shipper.Log(accesslog.Entry{ClientID: "c1", Method: "POST", Path: "/orders", Status: 201})
+	shipper.Log(accesslog.Entry{ClientID: "c2", Method: "GET", Path: "/orders", Status: 200})
+	stop(t, shipper)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var entry accesslog.Entry
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "c2", entry.ClientID)
+	assert.Equal(t, 200, entry.Status)
+}
+
+// TestShipper_Backpressure проверяет, что Log не блокируется при недоступном приемнике, а лишние записи отбрасываются.
+func TestShipper_Backpressure(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	shipper := newShipper(t, config.AccessLogConfig{Sink: config.AccessLogSinkHTTP, Address: server.URL, BatchSize: 1, QueueSize: 5})
+	shipper.Start()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			shipper.Log(accesslog.Entry{Path: "/"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Log не должен ждать приемник")
+	}
+	shipper.Stop()
+}
+
+// TestResponseWriter проверяет учет статуса и размера ответа.
+func TestResponseWriter(t *testing.T) {
+	rr := httptest.NewRecorder()
+	lw := accesslog.NewResponseWriter(rr)
+	assert.Equal(t, http.StatusOK, lw.Status())
+
+	lw.WriteHeader(http.StatusNotFound)
+	_, _ = lw.Write([]byte("not found"))
+	lw.Flush()
+	assert.Equal(t, http.StatusNotFound, lw.Status())
+	assert.Equal(t, int64(9), lw.BytesWritten())
+	assert.True(t, rr.Flushed)
+}
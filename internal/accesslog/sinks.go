@@ -0,0 +1,161 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"load-balancer/internal/i18n"
+)
+
+// syslogPriority - facility local0 (16) и severity informational (6): 16*8 + 6.
+const syslogPriority = 134
+
+// syslogSink отправляет записи в syslog по RFC 5424 (тело сообщения - JSON записи).
+// По UDP каждая запись уходит отдельной датаграммой, по TCP используется octet counting (RFC 6587).
+type syslogSink struct {
+	network  string
+	addr     string
+	hostname string
+	conn     net.Conn // Устанавливается при первой отправке и после ошибки.
+}
+
+func newSyslogSink(address string) (*syslogSink, error) {
+	network, addr, _ := strings.Cut(address, "://")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, i18n.Errorf(i18n.ConfigAccessLogBadAddress, "syslog", address)
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &syslogSink{network: network, addr: addr, hostname: hostname}, nil
+}
+
+func (s *syslogSink) send(ctx context.Context, batch []Entry) error {
+	if s.conn == nil {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, s.network, s.addr)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	}
+
+	for _, entry := range batch {
+		msg, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		line := fmt.Sprintf("<%d>1 %s %s load-balancer - access - %s",
+			syslogPriority, entry.Time.UTC().Format(time.RFC3339Nano), s.hostname, msg)
+		if s.network == "tcp" {
+			line = fmt.Sprintf("%d %s", len(line), line)
+		}
+		if _, err := io.WriteString(s.conn, line); err != nil {
+			// Соединение, скорее всего, разорвано: при повторе устанавливаем новое
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syslogSink) close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// httpSink отправляет пачку одним POST-запросом; тело формирует encode.
+type httpSink struct {
+	client      *http.Client
+	url         string
+	headers     map[string]string
+	contentType string
+	encode      func(batch []Entry) ([]byte, error)
+}
+
+// newHTTPSink создает приемник, принимающий пачку в формате NDJSON (по записи JSON на строку).
+func newHTTPSink(address string, headers map[string]string) *httpSink {
+	return &httpSink{
+		client:      &http.Client{},
+		url:         address,
+		headers:     headers,
+		contentType: "application/x-ndjson",
+		encode: func(batch []Entry) ([]byte, error) {
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			for _, entry := range batch {
+				if err := encoder.Encode(entry); err != nil {
+					return nil, err
+				}
+			}
+			return buf.Bytes(), nil
+		},
+	}
+}
+
+// newKafkaSink создает приемник, публикующий записи в топик через Kafka REST Proxy (API v2).
+func newKafkaSink(address, topic string, headers map[string]string) *httpSink {
+	type record struct {
+		Value Entry `json:"value"`
+	}
+	return &httpSink{
+		client:      &http.Client{},
+		url:         strings.TrimRight(address, "/") + "/topics/" + url.PathEscape(topic),
+		headers:     headers,
+		contentType: "application/vnd.kafka.json.v2+json",
+		encode: func(batch []Entry) ([]byte, error) {
+			records := make([]record, len(batch))
+			for i, entry := range batch {
+				records[i].Value = entry
+			}
+			return json.Marshal(map[string][]record{"records": records})
+		},
+	}
+}
+
+func (s *httpSink) send(ctx context.Context, batch []Entry) error {
+	body, err := s.encode(batch)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", s.contentType)
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return i18n.Errorf(i18n.AccessLogBadStatus, resp.Status)
+	}
+	return nil
+}
+
+func (s *httpSink) close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
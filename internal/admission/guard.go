@@ -0,0 +1,214 @@
+package admission
+
+import (
+	"container/heap"
+	"context"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+// MaxPriority - наибольший приоритет запроса; приоритеты из заголовка ограничиваются диапазоном [0, MaxPriority].
+const MaxPriority = 9
+
+var (
+	// ErrQueueFull возвращается Acquire, если очередь ожидания заполнена.
+	ErrQueueFull = i18n.NewError(i18n.AdmissionQueueFull)
+	// ErrQueueTimeout возвращается Acquire, если слот не освободился за queue_timeout.
+	ErrQueueTimeout = i18n.NewError(i18n.AdmissionQueueTimeout)
+)
+
+var (
+	queuedTotal = metrics.NewCounter("admission_queued_total",
+		"Количество запросов, ожидавших свободного слота в очереди.")
+	rejectedTotal = metrics.NewCounter("admission_rejected_total",
+		"Количество запросов, отклоненных из-за переполнения очереди или таймаута ожидания.")
+)
+
+// waiter - запрос, ожидающий свободного слота.
+type waiter struct {
+	priority int
+	seq      uint64        // Порядок поступления: при равном приоритете первым идет более ранний.
+	ready    chan struct{} // Закрывается, когда слот передан ожидающему.
+	index    int           // Позиция в куче; -1 - уже извлечен из очереди.
+}
+
+// waitQueue - очередь с приоритетом (max-heap по priority, затем FIFO по seq).
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *waitQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+func (q *waitQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*q = old[:len(old)-1]
+	return w
+}
+
+// Guard ограничивает число одновременно обрабатываемых запросов. Когда все слоты заняты,
+// запросы ждут в очереди, и освободившийся слот получает запрос с наибольшим приоритетом.
+type Guard struct {
+	maxInFlight     int
+	maxQueue        int
+	queueTimeout    time.Duration
+	priorityHeader  string
+	defaultPriority int
+	priorityTrusted []netip.Prefix // Клиенты, которым приоритет из заголовка разрешено повышать.
+
+	mu       sync.Mutex
+	inFlight int
+	queue    waitQueue
+	seq      uint64
+}
+
+// New создает Guard по секции concurrency конфигурации.
+func New(cfg config.ConcurrencyConfig) *Guard {
+	i18n.Logf(i18n.AdmissionEnabled, cfg.MaxInFlight, cfg.MaxQueue, cfg.QueueTimeout, cfg.PriorityHeader)
+	return &Guard{
+		maxInFlight:     cfg.MaxInFlight,
+		maxQueue:        cfg.MaxQueue,
+		queueTimeout:    cfg.QueueTimeout,
+		priorityHeader:  cfg.PriorityHeader,
+		defaultPriority: cfg.DefaultPriority,
+		priorityTrusted: cfg.PriorityTrustedPrefixes,
+	}
+}
+
+// Priority возвращает приоритет запроса из заголовка priority_header
+// или default_priority, если заголовок не задан или некорректен. Клиент не из priority_trusted
+// не может получить приоритет выше default_priority.
+func (g *Guard) Priority(r *http.Request) int {
+	raw := strings.TrimSpace(r.Header.Get(g.priorityHeader))
+	if raw == "" {
+		return g.defaultPriority
+	}
+	priority, err := strconv.Atoi(raw)
+	if err != nil {
+		return g.defaultPriority
+	}
+	priority = min(max(priority, 0), MaxPriority)
+	if !g.trusted(r) {
+		priority = min(priority, g.defaultPriority)
+	}
+	return priority
+}
+
+// trusted сообщает, что запрос пришел с адреса из priority_trusted.
+func (g *Guard) trusted(r *http.Request) bool {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	addr := addrPort.Addr().Unmap()
+	for _, prefix := range g.priorityTrusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Acquire занимает слот для запроса с указанным приоритетом. Если свободных слотов нет,
+// ожидает в очереди не дольше queue_timeout. При успехе возвращает функцию освобождения слота,
+// которую нужно вызвать ровно один раз по окончании обработки запроса.
+func (g *Guard) Acquire(ctx context.Context, priority int) (func(), error) {
+	g.mu.Lock()
+	if g.inFlight < g.maxInFlight && g.queue.Len() == 0 {
+		g.inFlight++
+		g.mu.Unlock()
+		return g.release, nil
+	}
+	if g.queue.Len() >= g.maxQueue {
+		g.mu.Unlock()
+		rejectedTotal.Inc()
+		return nil, ErrQueueFull
+	}
+	g.seq++
+	w := &waiter{priority: priority, seq: g.seq, ready: make(chan struct{})}
+	heap.Push(&g.queue, w)
+	g.mu.Unlock()
+	queuedTotal.Inc()
+
+	timer := time.NewTimer(g.queueTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-w.ready:
+		return g.release, nil
+	case <-timer.C:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	g.mu.Lock()
+	if w.index >= 0 {
+		heap.Remove(&g.queue, w.index)
+		g.mu.Unlock()
+		rejectedTotal.Inc()
+		return nil, err
+	}
+	g.mu.Unlock()
+	// Слот был передан одновременно с отменой: возвращаем его следующему в очереди
+	g.release()
+	rejectedTotal.Inc()
+	return nil, err
+}
+
+// release освобождает слот: он передается ожидающему с наибольшим приоритетом или возвращается в пул.
+func (g *Guard) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.queue.Len() > 0 {
+		w := heap.Pop(&g.queue).(*waiter)
+		close(w.ready)
+		return
+	}
+	g.inFlight--
+}
+
+// Stats возвращает число занятых слотов и длину очереди.
+func (g *Guard) Stats() (inFlight, queued int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.inFlight, g.queue.Len()
+}
+
+// Load возвращает загрузку: долю занятых слотов от max_in_flight. Непустая очередь означает загрузку не меньше 1.
+func (g *Guard) Load() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.maxInFlight <= 0 {
+		return 0
+	}
+	load := float64(g.inFlight) / float64(g.maxInFlight)
+	if g.queue.Len() > 0 {
+		load = max(load, 1)
+	}
+	return load
+}
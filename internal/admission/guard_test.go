@@ -0,0 +1,146 @@
+package admission_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/admission"
+	"load-balancer/internal/config"
+)
+
+func newGuard(maxInFlight, maxQueue int, queueTimeout time.Duration) *admission.Guard {
+	return admission.New(config.ConcurrencyConfig{
+		Enabled:                 true,
+		MaxInFlight:             maxInFlight,
+		MaxQueue:                maxQueue,
+		QueueTimeout:            queueTimeout,
+		PriorityHeader:          "X-Priority",
+		DefaultPriority:         5,
+		PriorityTrustedPrefixes: []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")},
+	})
+}
+
+// waitQueued ждет, пока в очереди окажется n запросов.
+func waitQueued(t *testing.T, g *admission.Guard, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		_, queued := g.Stats()
+		return queued == n
+	}, time.Second, time.Millisecond)
+}
+
+// TestGuard_Priority проверяет разбор заголовка приоритета от клиента из priority_trusted.
+func TestGuard_Priority(t *testing.T) {
+	g := newGuard(1, 1, time.Second)
+	tests := map[string]int{
+		"":     5,
+		"7":    7,
+		" 0 ":  0,
+		"42":   admission.MaxPriority,
+		"-3":   0,
+		"high": 5,
+	}
+	for header, want := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if header != "" {
+			req.Header.Set("X-Priority", header)
+		}
+		assert.Equal(t, want, g.Priority(req), "X-Priority: %q", header)
+	}
+}
+
+// TestGuard_PriorityUntrusted проверяет, что клиент не из priority_trusted может только понизить приоритет.
+func TestGuard_PriorityUntrusted(t *testing.T) {
+	g := newGuard(1, 1, time.Second)
+	tests := map[string]int{
+		"":  5,
+		"9": 5,
+		"7": 5,
+		"2": 2,
+	}
+	for header, want := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "198.51.100.7:4321"
+		if header != "" {
+			req.Header.Set("X-Priority", header)
+		}
+		assert.Equal(t, want, g.Priority(req), "X-Priority: %q", header)
+	}
+}
+
+// TestGuard_PriorityOrder проверяет, что освободившийся слот получает запрос с наибольшим приоритетом.
+func TestGuard_PriorityOrder(t *testing.T) {
+	g := newGuard(1, 10, time.Second)
+	holder, err := g.Acquire(context.Background(), 0)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i, priority := range []int{1, 9, 5, 9} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := g.Acquire(context.Background(), priority)
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+			release()
+		}()
+		waitQueued(t, g, i+1)
+	}
+
+	holder()
+	wg.Wait()
+	assert.Equal(t, []int{9, 9, 5, 1}, order)
+
+	inFlight, queued := g.Stats()
+	assert.Equal(t, 0, inFlight)
+	assert.Equal(t, 0, queued)
+}
+
+// TestGuard_Rejections проверяет отказы при заполненной очереди, таймауте и отмене контекста.
+func TestGuard_Rejections(t *testing.T) {
+	// Очередь не предусмотрена
+	noQueue := newGuard(1, 0, time.Second)
+	release, err := noQueue.Acquire(context.Background(), 5)
+	require.NoError(t, err)
+	_, err = noQueue.Acquire(context.Background(), 9)
+	assert.ErrorIs(t, err, admission.ErrQueueFull)
+	release()
+	release, err = noQueue.Acquire(context.Background(), 5)
+	require.NoError(t, err, "После освобождения слот снова доступен")
+	release()
+
+	// Слот не освободился за queue_timeout
+	g := newGuard(1, 5, 20*time.Millisecond)
+	release, err = g.Acquire(context.Background(), 5)
+	require.NoError(t, err)
+	_, err = g.Acquire(context.Background(), 5)
+	assert.ErrorIs(t, err, admission.ErrQueueTimeout)
+
+	// Клиент ушел, не дождавшись слота
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		waitQueued(t, g, 1)
+		cancel()
+	}()
+	_, err = g.Acquire(ctx, 5)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, queued := g.Stats()
+	assert.Equal(t, 0, queued, "Отказавшиеся запросы должны покидать очередь")
+	release()
+	inFlight, _ := g.Stats()
+	assert.Equal(t, 0, inFlight)
+}
@@ -0,0 +1,235 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+// Имена алертов.
+const (
+	AlertHighRejectionRate = "high_rejection_rate"
+	AlertAllBackendsDown   = "all_backends_down"
+)
+
+// Состояния алерта в событии.
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+var alertsFiredTotal = metrics.NewCounter("alerts_fired_total",
+	"Количество сработавших алертов (переходов в состояние firing).")
+
+// Event - уведомление об изменении состояния алерта (отправляется в лог и на webhook).
+type Event struct {
+	Alert     string    `json:"alert"`
+	Status    string    `json:"status"`
+	Message   string    `json:"message"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Time      time.Time `json:"time"`
+}
+
+// BackendsFunc возвращает количество работоспособных и общее количество бэкендов.
+type BackendsFunc func() (healthy, total int)
+
+// secondBucket - счетчики запросов за одну секунду.
+type secondBucket struct {
+	second   int64
+	total    uint64
+	rejected uint64
+}
+
+// Monitor отслеживает долю отклоненных запросов и доступность бэкендов
+// и сообщает о пересечении порогов. Алерт срабатывает один раз при переходе
+// в состояние firing и один раз при возврате в норму.
+type Monitor struct {
+	cfg      config.AlertsConfig
+	backends BackendsFunc
+	client   *http.Client
+
+	mu      sync.Mutex
+	buckets []secondBucket // Кольцевой буфер посекундных счетчиков на окно rejection_window.
+
+	firing       map[string]bool
+	allDownSince time.Time // Момент, с которого все бэкенды недоступны (нулевое - есть живые).
+	quit         chan struct{}
+	stopOnce     sync.Once
+	wg           sync.WaitGroup // Горутина периодической проверки (см. Wait)
+}
+
+// NewMonitor создает монитор алертов. backends может быть nil, тогда алерт недоступности бэкендов не проверяется.
+func NewMonitor(cfg config.AlertsConfig, backends BackendsFunc) *Monitor {
+	windowSeconds := int(cfg.RejectionWindow / time.Second)
+	if windowSeconds < 1 {
+		windowSeconds = 1
+	}
+	return &Monitor{
+		cfg:      cfg,
+		backends: backends,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		buckets:  make([]secondBucket, windowSeconds),
+		firing:   make(map[string]bool),
+		quit:     make(chan struct{}),
+	}
+}
+
+// ObserveRequest учитывает обработанный запрос; rateLimited - запрос отклонен Rate Limiter.
+func (m *Monitor) ObserveRequest(rateLimited bool) {
+	sec := time.Now().Unix()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bucket := &m.buckets[sec%int64(len(m.buckets))]
+	if bucket.second != sec {
+		*bucket = secondBucket{second: sec}
+	}
+	bucket.total++
+	if rateLimited {
+		bucket.rejected++
+	}
+}
+
+// Start запускает периодическую проверку порогов.
+func (m *Monitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.cfg.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Evaluate()
+			case <-m.quit:
+				return
+			}
+		}
+	}()
+	i18n.Logf(i18n.AlertsStarted,
+		m.cfg.RejectionRateThreshold*100, m.cfg.RejectionWindow, m.cfg.MinRequests, m.cfg.AllBackendsDownFor, m.cfg.WebhookURL)
+}
+
+// Stop останавливает периодическую проверку.
+func (m *Monitor) Stop() {
+	m.stopOnce.Do(func() { close(m.quit) })
+}
+
+// Wait ожидает завершения периодической проверки после Stop, включая отправку webhook.
+// Возвращает ошибку контекста, если проверка не завершилась до его отмены.
+func (m *Monitor) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rejectionRate возвращает долю отклоненных запросов и общее число запросов в окне.
+func (m *Monitor) rejectionRate(now time.Time) (float64, uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldest := now.Unix() - int64(len(m.buckets)) + 1
+	var total, rejected uint64
+	for _, bucket := range m.buckets {
+		if bucket.second >= oldest {
+			total += bucket.total
+			rejected += bucket.rejected
+		}
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	return float64(rejected) / float64(total), total
+}
+
+// Evaluate проверяет пороги и отправляет события при изменении состояния алертов.
+// Вызывается периодически из Start; не предназначен для конкурентного вызова.
+func (m *Monitor) Evaluate() {
+	now := time.Now()
+
+	rate, total := m.rejectionRate(now)
+	rateExceeded := total >= uint64(m.cfg.MinRequests) && rate > m.cfg.RejectionRateThreshold
+	m.transition(AlertHighRejectionRate, rateExceeded, Event{
+		Message: i18n.T(i18n.AlertsRejectionRate,
+			rate*100, m.cfg.RejectionWindow, total),
+		Value:     rate,
+		Threshold: m.cfg.RejectionRateThreshold,
+		Time:      now,
+	})
+
+	if m.backends == nil {
+		return
+	}
+	healthy, backendsTotal := m.backends()
+	var downFor time.Duration
+	if backendsTotal > 0 && healthy == 0 {
+		if m.allDownSince.IsZero() {
+			m.allDownSince = now
+		}
+		downFor = now.Sub(m.allDownSince)
+	} else {
+		m.allDownSince = time.Time{}
+	}
+	m.transition(AlertAllBackendsDown, downFor >= m.cfg.AllBackendsDownFor, Event{
+		Message:   i18n.T(i18n.AlertsAllBackendsDown, backendsTotal, downFor.Round(time.Second)),
+		Value:     downFor.Seconds(),
+		Threshold: m.cfg.AllBackendsDownFor.Seconds(),
+		Time:      now,
+	})
+}
+
+// transition отправляет событие, если состояние алерта изменилось.
+func (m *Monitor) transition(alert string, active bool, event Event) {
+	if m.firing[alert] == active {
+		return
+	}
+	m.firing[alert] = active
+
+	event.Alert = alert
+	event.Status = StatusResolved
+	if active {
+		event.Status = StatusFiring
+		alertsFiredTotal.Inc()
+	}
+	m.emit(event)
+}
+
+// emit логирует событие и отправляет его на webhook, если он настроен.
+func (m *Monitor) emit(event Event) {
+	i18n.Logf(i18n.AlertsEvent, event.Alert, event.Status, event.Message, event.Value, event.Threshold)
+	if m.cfg.WebhookURL == "" {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		i18n.Logf(i18n.AlertsMarshalFailed, err)
+		return
+	}
+	resp, err := m.client.Post(m.cfg.WebhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		i18n.Logf(i18n.AlertsWebhookFailed, m.cfg.WebhookURL, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		i18n.Logf(i18n.AlertsWebhookStatus, m.cfg.WebhookURL, resp.StatusCode)
+	}
+}
@@ -0,0 +1,104 @@
+package alerting_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/alerting"
+	"load-balancer/internal/config"
+)
+
+// webhookRecorder - тестовый webhook, сохраняющий полученные события.
+type webhookRecorder struct {
+	mu     sync.Mutex
+	events []alerting.Event
+}
+
+func (wr *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var event alerting.Event
+	if err := json.NewDecoder(r.Body).Decode(&event); err == nil {
+		wr.mu.Lock()
+		wr.events = append(wr.events, event)
+		wr.mu.Unlock()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (wr *webhookRecorder) received() []alerting.Event {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return append([]alerting.Event(nil), wr.events...)
+}
+
+// TestMonitor_HighRejectionRate проверяет срабатывание и снятие алерта по доле 429.
+func TestMonitor_HighRejectionRate(t *testing.T) {
+	recorder := &webhookRecorder{}
+	webhook := httptest.NewServer(recorder)
+	defer webhook.Close()
+
+	monitor := alerting.NewMonitor(config.AlertsConfig{
+		RejectionRateThreshold: 0.05,
+		RejectionWindow:        2 * time.Second,
+		MinRequests:            10,
+		AllBackendsDownFor:     time.Minute,
+		WebhookURL:             webhook.URL,
+	}, nil)
+
+	// 2 отклоненных из 20 = 10% > 5%
+	for i := 0; i < 20; i++ {
+		monitor.ObserveRequest(i < 2)
+	}
+	monitor.Evaluate()
+	monitor.Evaluate() // Повторная проверка не должна дублировать событие
+
+	events := recorder.received()
+	require.Len(t, events, 1)
+	assert.Equal(t, alerting.AlertHighRejectionRate, events[0].Alert)
+	assert.Equal(t, alerting.StatusFiring, events[0].Status)
+	assert.InDelta(t, 0.1, events[0].Value, 0.001)
+
+	// После выхода запросов из окна алерт снимается
+	time.Sleep(2100 * time.Millisecond)
+	monitor.Evaluate()
+	events = recorder.received()
+	require.Len(t, events, 2)
+	assert.Equal(t, alerting.StatusResolved, events[1].Status)
+}
+
+// TestMonitor_AllBackendsDown проверяет алерт недоступности всех бэкендов.
+func TestMonitor_AllBackendsDown(t *testing.T) {
+	recorder := &webhookRecorder{}
+	webhook := httptest.NewServer(recorder)
+	defer webhook.Close()
+
+	healthy := 0
+	monitor := alerting.NewMonitor(config.AlertsConfig{
+		RejectionRateThreshold: 0.05,
+		RejectionWindow:        time.Second,
+		AllBackendsDownFor:     30 * time.Millisecond,
+		WebhookURL:             webhook.URL,
+	}, func() (int, int) { return healthy, 2 })
+
+	monitor.Evaluate()
+	assert.Empty(t, recorder.received(), "Алерт не должен срабатывать до истечения all_backends_down_for")
+
+	time.Sleep(40 * time.Millisecond)
+	monitor.Evaluate()
+	events := recorder.received()
+	require.Len(t, events, 1)
+	assert.Equal(t, alerting.AlertAllBackendsDown, events[0].Alert)
+	assert.Equal(t, alerting.StatusFiring, events[0].Status)
+
+	healthy = 1
+	monitor.Evaluate()
+	events = recorder.received()
+	require.Len(t, events, 2)
+	assert.Equal(t, alerting.StatusResolved, events[1].Status)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"load-balancer/internal/access"
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/response"
+	"load-balancer/internal/storage"
+)
+
+// Источники записей списков доступа.
+const (
+	AccessSourceAPI    = "api"
+	AccessSourceConfig = "config"
+)
+
+// AccessEntryRequest - тело запроса POST /access.
+type AccessEntryRequest struct {
+	Value  string `json:"value"` // IP-адрес, подсеть CIDR или ID клиента.
+	List   string `json:"list"`  // "deny" или "allow".
+	Reason string `json:"reason,omitempty"`
+	// TTL - срок действия записи (например, "1h"), пусто - запись бессрочная.
+	TTL string `json:"ttl,omitempty"`
+}
+
+// AccessEntryResponse - запись списка доступа в ответах /access.
+type AccessEntryResponse struct {
+	Value     string     `json:"value"`
+	List      string     `json:"list"`
+	Reason    string     `json:"reason,omitempty"`
+	Source    string     `json:"source"` // "api" или "config".
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// AccessHandler обрабатывает запросы к спискам запрета и разрешения (/access).
+// Записи, добавленные через API, сохраняются в хранилище и переживают перезапуск.
+type AccessHandler struct {
+	List *access.List
+}
+
+func NewAccessHandler(list *access.List) *AccessHandler {
+	return &AccessHandler{List: list}
+}
+
+func (h *AccessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Путь после StripPrefix("/access", ...): "" или "/" для коллекции, "/{value}" для записи.
+	// Значение может содержать "/" (подсеть CIDR), поэтому убирается только ведущий слэш.
+	value := strings.TrimPrefix(r.URL.Path, "/")
+
+	if value == "" {
+		switch r.Method {
+		case http.MethodGet:
+			h.listEntries(w)
+		case http.MethodPost:
+			h.putEntry(w, r)
+		default:
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, "/access"))
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		entry, ok := h.List.Get(value)
+		if !ok {
+			response.RespondWithError(w, http.StatusNotFound, response.CodeEntryNotFound, i18n.T(i18n.APIAccessEntryNotFound, value))
+			return
+		}
+		response.RespondWithJSON(w, http.StatusOK, newAccessEntryResponse(entry))
+	case http.MethodDelete:
+		h.deleteEntry(w, value)
+	default:
+		response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, "/access/{value}"))
+	}
+}
+
+// listEntries обрабатывает GET /access
+func (h *AccessHandler) listEntries(w http.ResponseWriter) {
+	entries := h.List.Entries()
+	resp := make([]AccessEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		resp = append(resp, newAccessEntryResponse(entry))
+	}
+	response.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// putEntry обрабатывает POST /access: добавляет запись или заменяет запись с тем же значением.
+func (h *AccessHandler) putEntry(w http.ResponseWriter, r *http.Request) {
+	if !h.List.Persistent() {
+		response.RespondWithError(w, http.StatusServiceUnavailable, response.CodeStoreUnavailable, i18n.T(i18n.APIAccessStoreUnavailable))
+		return
+	}
+
+	var req AccessEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeInvalidJSON, i18n.T(i18n.APIInvalidJSON, err))
+		return
+	}
+	if err := config.ValidateAccessEntry(req.Value); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIBadAccessValue, req.Value, err))
+		return
+	}
+	if req.List != config.AccessListDeny && req.List != config.AccessListAllow {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIBadAccessList, req.List))
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		parsed, err := time.ParseDuration(req.TTL)
+		if err != nil || parsed <= 0 {
+			response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIBadAccessTTL, req.TTL))
+			return
+		}
+		ttl = parsed
+	}
+
+	entry, err := h.List.Put(req.Value, req.List, req.Reason, ttl)
+	if err != nil {
+		i18n.Logf(i18n.APIAccessPutFailed, req.Value, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIAccessInternal))
+		return
+	}
+	response.RespondWithJSON(w, http.StatusCreated, newAccessEntryResponse(access.Entry{AccessEntry: entry}))
+}
+
+// deleteEntry обрабатывает DELETE /access/{value}
+func (h *AccessHandler) deleteEntry(w http.ResponseWriter, value string) {
+	err := h.List.Delete(value)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, access.ErrStoreUnavailable):
+		response.RespondWithError(w, http.StatusServiceUnavailable, response.CodeStoreUnavailable, i18n.T(i18n.APIAccessStoreUnavailable))
+	case errors.Is(err, storage.ErrAccessEntryNotFound):
+		response.RespondWithError(w, http.StatusNotFound, response.CodeEntryNotFound, i18n.T(i18n.APIAccessEntryNotFound, value))
+	default:
+		i18n.Logf(i18n.APIAccessDeleteFailed, value, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIAccessInternal))
+	}
+}
+
+func newAccessEntryResponse(entry access.Entry) AccessEntryResponse {
+	resp := AccessEntryResponse{
+		Value:  entry.Value,
+		List:   entry.List,
+		Reason: entry.Reason,
+		Source: AccessSourceAPI,
+	}
+	if entry.Static {
+		resp.Source = AccessSourceConfig
+	}
+	if !entry.CreatedAt.IsZero() {
+		resp.CreatedAt = &entry.CreatedAt
+	}
+	if !entry.ExpiresAt.IsZero() {
+		resp.ExpiresAt = &entry.ExpiresAt
+	}
+	return resp
+}
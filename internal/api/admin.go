@@ -0,0 +1,709 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"load-balancer/internal/balancer"
+	"load-balancer/internal/buildinfo"
+	"load-balancer/internal/capture"
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/ratelimiter"
+	"load-balancer/internal/response"
+	"load-balancer/internal/storage"
+)
+
+// HealthChecker выполняет внеочередные проверки состояния бэкендов и управляет их параметрами.
+type HealthChecker interface {
+	CheckNow(backend string) ([]balancer.HealthCheckResult, error)
+	HealthCheckConfig() config.HealthCheckConfig
+	UpdateHealthCheckConfig(cfg config.HealthCheckConfig) error
+}
+
+// StorageMaintainer выполняет обслуживание базы по POST /admin/storage/maintenance (реализуется *storage.Maintainer).
+type StorageMaintainer interface {
+	Run() (storage.MaintenanceReport, error)
+}
+
+// RuntimeLimiter предоставляет состояние Rate Limiter для GET /admin/state и POST /admin/state/save.
+type RuntimeLimiter interface {
+	Summary() ratelimiter.BucketSummary
+	SaveState() error
+}
+
+// HealthCheckConfigRequest - тело PUT /admin/health/config. Незаданные поля не меняются.
+type HealthCheckConfigRequest struct {
+	Interval    *string `json:"interval"`
+	Timeout     *string `json:"timeout"`
+	Path        *string `json:"path"`
+	Type        *string `json:"type"`
+	GRPCService *string `json:"grpc_service"`
+	MaxBackoff  *string `json:"max_backoff"`
+	Jitter      *string `json:"jitter"`
+}
+
+// HealthCheckConfigResponse - действующие параметры проверок состояния.
+type HealthCheckConfigResponse struct {
+	Enabled     bool   `json:"enabled"`
+	Interval    string `json:"interval"`
+	Timeout     string `json:"timeout"`
+	Path        string `json:"path"`
+	Type        string `json:"type"`
+	GRPCService string `json:"grpc_service,omitempty"`
+	Workers     int    `json:"workers"`
+	MaxBackoff  string `json:"max_backoff"`
+	Jitter      string `json:"jitter"`
+}
+
+// HealthCheckResponse - ответ на принудительную проверку состояния.
+type HealthCheckResponse struct {
+	Healthy int                          `json:"healthy"`
+	Total   int                          `json:"total"`
+	Results []balancer.HealthCheckResult `json:"results"`
+}
+
+// CaptureResponse - состояние записи запросов и записанные запросы (GET /admin/capture).
+type CaptureResponse struct {
+	Active  bool            `json:"active"`
+	Filter  capture.Filter  `json:"filter"`
+	Entries []capture.Entry `json:"entries"`
+}
+
+// StateResponse - снимок состояния балансировщика (GET /admin/state).
+type StateResponse struct {
+	ConfigHash  string                        `json:"config_hash"`
+	Pools       map[string]balancer.PoolState `json:"pools"`
+	RateLimiter *ratelimiter.BucketSummary    `json:"rate_limiter,omitempty"`
+}
+
+// BackendStatus - состояние бэкенда в ответе GET /admin/backends.
+type BackendStatus struct {
+	Pool  string `json:"pool"`
+	Index int    `json:"index"` // ID бэкенда в пуле (см. balancer.Backend.ID)
+	URL   string `json:"url"`
+	Alive bool   `json:"alive"`
+	// Drained - бэкенд выведен из балансировки вручную (POST /admin/backends/{id}/drain).
+	Drained bool `json:"drained"`
+	// LastCheck - время последней активной проверки (null, если проверок еще не было), LastError - ее ошибка.
+	LastCheck *time.Time `json:"last_check"`
+	LastError string     `json:"last_error,omitempty"`
+	// ActiveConnections - запросы, которые проксируются на бэкенд в данный момент.
+	ActiveConnections int64 `json:"active_connections"`
+}
+
+// AddBackendRequest - тело POST /admin/backends: бэкенд, добавляемый в пул во время работы.
+type AddBackendRequest struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	// Labels - метки для backend_selector маршрутов и селектора canary.
+	Labels         map[string]string `json:"labels"`
+	MaxConnections int               `json:"max_connections"`
+}
+
+// CanaryStatus - сравнение стабильных и канареечных бэкендов пула в ответе GET /admin/canary.
+type CanaryStatus struct {
+	Pool string `json:"pool"`
+	balancer.CanaryReport
+}
+
+// VersionResponse - версия, сборка и время работы экземпляра (GET /admin/version).
+type VersionResponse struct {
+	buildinfo.Info
+	// Features - включенные в конфигурации возможности (rate_limiter, tls, spillover и т.д.).
+	Features map[string]bool `json:"features"`
+}
+
+// TimeSeriesResponse - история запросов и ошибок бэкендов по секундам (GET /admin/stats/timeseries).
+// Серии упорядочены от старых секунд к новым; последняя секунда (End) еще не завершена.
+type TimeSeriesResponse struct {
+	End           time.Time                           `json:"end"`
+	BucketSeconds int                                 `json:"bucket_seconds"`
+	Pools         map[string][]balancer.BackendSeries `json:"pools"`
+}
+
+// LogLevelRequest - тело PUT /admin/loglevel; LogLevelResponse - текущий уровень логов.
+type LogLevelRequest struct {
+	Level string `json:"level"` // "debug", "info", "warn" или "error".
+}
+
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// AdminHandler обрабатывает служебные запросы по префиксу /admin/.
+type AdminHandler struct {
+	Health HealthChecker
+	// Capture - запись запросов для отладки; nil, если недоступна.
+	Capture *capture.Recorder
+	// Pools - пулы бэкендов по имени (например, "primary", "spillover") для GET /admin/state.
+	Pools map[string]*balancer.Balancer
+	// Limiter - Rate Limiter для GET /admin/state и POST /admin/state/save; nil, если недоступен.
+	Limiter RuntimeLimiter
+	// ConfigHash - хэш файла конфигурации, с которым запущен процесс (см. config.Config.Hash).
+	ConfigHash string
+	// Reloads - результат последнего перечитывания конфигурации по SIGHUP; nil, если недоступен.
+	Reloads *config.ReloadLog
+	// Features - включенные возможности для GET /admin/version (см. config.Config.Features).
+	Features map[string]bool
+	// Maintenance - обслуживание базы SQLite; nil, если хранилище не подключено.
+	Maintenance StorageMaintainer
+}
+
+func NewAdminHandler(health HealthChecker) *AdminHandler {
+	return &AdminHandler{Health: health}
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/admin/health/check":
+		if r.Method != http.MethodPost {
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+			return
+		}
+		h.forceHealthCheck(w, r)
+	case "/admin/health/config":
+		switch r.Method {
+		case http.MethodGet:
+			response.RespondWithJSON(w, http.StatusOK, newHealthCheckConfigResponse(h.Health.HealthCheckConfig()))
+		case http.MethodPut:
+			h.updateHealthCheckConfig(w, r)
+		default:
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+		}
+	case "/admin/capture":
+		if h.Capture == nil {
+			response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APICaptureDisabled))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			h.getCapture(w)
+		case http.MethodPut:
+			h.startCapture(w, r)
+		case http.MethodDelete:
+			h.Capture.Stop()
+			h.getCapture(w)
+		default:
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+		}
+	case "/admin/state":
+		if r.Method != http.MethodGet {
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+			return
+		}
+		h.getState(w)
+	case "/admin/backends":
+		switch r.Method {
+		case http.MethodGet:
+			h.getBackends(w, r)
+		case http.MethodPost:
+			h.addBackend(w, r)
+		default:
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+		}
+	case "/admin/canary":
+		if r.Method != http.MethodGet {
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+			return
+		}
+		h.getCanary(w, r)
+	case "/admin/canary/resume":
+		if r.Method != http.MethodPost {
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+			return
+		}
+		h.resumeCanary(w, r)
+	case "/admin/stats/timeseries":
+		if r.Method != http.MethodGet {
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+			return
+		}
+		h.getTimeSeries(w, r)
+	case "/admin/config/last-reload":
+		if r.Method != http.MethodGet {
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+			return
+		}
+		h.getLastReload(w)
+	case "/admin/version":
+		if r.Method != http.MethodGet {
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+			return
+		}
+		response.RespondWithJSON(w, http.StatusOK, VersionResponse{Info: buildinfo.Get(), Features: h.Features})
+	case "/admin/loglevel":
+		switch r.Method {
+		case http.MethodGet:
+			response.RespondWithJSON(w, http.StatusOK, LogLevelResponse{Level: i18n.CurrentLevel().String()})
+		case http.MethodPut:
+			h.setLogLevel(w, r)
+		default:
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+		}
+	case "/admin/state/save":
+		if r.Method != http.MethodPost {
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+			return
+		}
+		h.saveState(w)
+	case "/admin/storage/maintenance":
+		if r.Method != http.MethodPost {
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+			return
+		}
+		h.runMaintenance(w)
+	default:
+		if strings.HasPrefix(r.URL.Path, "/admin/backends/") {
+			h.serveBackend(w, r)
+			return
+		}
+		response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APIAdminNotFound, r.URL.Path))
+	}
+}
+
+// getState обрабатывает GET /admin/state: бэкенды и их проверки, алгоритм, сводка по корзинам и хэш конфигурации.
+func (h *AdminHandler) getState(w http.ResponseWriter) {
+	resp := StateResponse{
+		ConfigHash: h.configHash(),
+		Pools:      make(map[string]balancer.PoolState, len(h.Pools)),
+	}
+	for name, pool := range h.Pools {
+		resp.Pools[name] = pool.Snapshot()
+	}
+	if h.Limiter != nil {
+		summary := h.Limiter.Summary()
+		resp.RateLimiter = &summary
+	}
+	response.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// getBackends обрабатывает GET /admin/backends[?pool=<имя>]: доступность, последняя проверка и активные
+// соединения каждого бэкенда, упорядоченные по пулу и ID бэкенда.
+func (h *AdminHandler) getBackends(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(h.Pools))
+	if name := r.URL.Query().Get("pool"); name != "" {
+		if _, ok := h.Pools[name]; !ok {
+			response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APIPoolNotFound, name))
+			return
+		}
+		names = append(names, name)
+	} else {
+		for name := range h.Pools {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	backends := []BackendStatus{}
+	for _, name := range names {
+		for _, backend := range h.Pools[name].Snapshot().Backends {
+			backends = append(backends, newBackendStatus(name, backend))
+		}
+	}
+	response.RespondWithJSON(w, http.StatusOK, backends)
+}
+
+// newBackendStatus формирует элемент ответа GET /admin/backends по снимку бэкенда.
+func newBackendStatus(pool string, backend balancer.BackendState) BackendStatus {
+	return BackendStatus{
+		Pool:              pool,
+		Index:             backend.Index,
+		URL:               backend.URL,
+		Alive:             backend.Alive,
+		Drained:           backend.Drained,
+		LastCheck:         backend.LastCheck,
+		LastError:         backend.LastError,
+		ActiveConnections: backend.InFlight,
+	}
+}
+
+// serveBackend обрабатывает запросы к отдельному бэкенду: DELETE /admin/backends/{ID}
+// и POST /admin/backends/{ID}/drain и /enable.
+func (h *AdminHandler) serveBackend(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/admin/backends/"), "/")
+	method := http.MethodPost
+	switch {
+	case id == "":
+		response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APIAdminNotFound, r.URL.Path))
+		return
+	case action == "":
+		method = http.MethodDelete
+	case action != "drain" && action != "enable":
+		response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APIAdminNotFound, r.URL.Path))
+		return
+	}
+	if r.Method != method {
+		response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+		return
+	}
+	name, pool, ok := h.targetPool(w, r)
+	if !ok {
+		return
+	}
+	if action == "" {
+		h.removeBackend(w, pool, id)
+		return
+	}
+	h.drainBackend(w, name, pool, id, action == "drain")
+}
+
+// targetPool возвращает пул из ?pool= (по умолчанию primary); если пула нет, отвечает 404 и возвращает ok = false.
+func (h *AdminHandler) targetPool(w http.ResponseWriter, r *http.Request) (name string, pool *balancer.Balancer, ok bool) {
+	name = r.URL.Query().Get("pool")
+	if name == "" {
+		name = "primary"
+	}
+	pool, ok = h.Pools[name]
+	if !ok {
+		response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APIPoolNotFound, name))
+	}
+	return name, pool, ok
+}
+
+// drainBackend обрабатывает POST /admin/backends/{ID}/drain и /enable[?pool=<имя>]: выводит бэкенд
+// из балансировки на время выкладки или возвращает его. Ответ - состояние бэкенда; active_connections
+// показывает, сколько запросов еще завершается.
+func (h *AdminHandler) drainBackend(w http.ResponseWriter, name string, pool *balancer.Balancer, id string, drained bool) {
+	idx, err := pool.SetDrained(id, drained)
+	if err != nil {
+		response.RespondWithError(w, http.StatusNotFound, response.CodeBackendNotFound, err.Error())
+		return
+	}
+	h.respondWithBackend(w, http.StatusOK, name, pool, idx)
+}
+
+// addBackend обрабатывает POST /admin/backends[?pool=<имя>]: добавляет бэкенд в работающий пул.
+// Если проверки состояния включены, бэкенд сразу проверяется и ответ показывает ее результат.
+func (h *AdminHandler) addBackend(w http.ResponseWriter, r *http.Request) {
+	var req AddBackendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeInvalidJSON, i18n.T(i18n.APIInvalidJSON, err))
+		return
+	}
+	if req.Weight < 0 || req.MaxConnections < 0 {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIBackendBadLimits, req.Weight, req.MaxConnections))
+		return
+	}
+	name, pool, ok := h.targetPool(w, r)
+	if !ok {
+		return
+	}
+	id, err := pool.AddBackend(balancer.BackendSpec{
+		URL:            req.URL,
+		Weight:         req.Weight,
+		Labels:         req.Labels,
+		MaxConnections: req.MaxConnections,
+	})
+	if err != nil {
+		if errors.Is(err, balancer.ErrBackendExists) {
+			response.RespondWithError(w, http.StatusConflict, response.CodeBackendExists, err.Error())
+			return
+		}
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+		return
+	}
+	h.respondWithBackend(w, http.StatusCreated, name, pool, id)
+}
+
+// removeBackend обрабатывает DELETE /admin/backends/{ID или URL}[?pool=<имя>]: удаляет бэкенд из пула.
+// Запросы в обработке завершаются; ID удаленного бэкенда больше не используется.
+func (h *AdminHandler) removeBackend(w http.ResponseWriter, pool *balancer.Balancer, id string) {
+	if _, err := pool.RemoveBackend(id); err != nil {
+		if errors.Is(err, balancer.ErrLastBackend) {
+			response.RespondWithError(w, http.StatusConflict, response.CodeLastBackend, err.Error())
+			return
+		}
+		response.RespondWithError(w, http.StatusNotFound, response.CodeBackendNotFound, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithBackend отвечает состоянием бэкенда пула с ID id.
+func (h *AdminHandler) respondWithBackend(w http.ResponseWriter, status int, name string, pool *balancer.Balancer, id int) {
+	for _, backend := range pool.Snapshot().Backends {
+		if backend.Index == id {
+			response.RespondWithJSON(w, status, newBackendStatus(name, backend))
+			return
+		}
+	}
+	// Бэкенд удален параллельным запросом
+	response.RespondWithError(w, http.StatusNotFound, response.CodeBackendNotFound, i18n.Errorf(i18n.HealthCheckBackendNotFoundWrap, balancer.ErrBackendNotFound, strconv.Itoa(id)).Error())
+}
+
+// canaryPools возвращает упорядоченные имена пулов с канареечным разделением или один пул из ?pool=.
+// Если пул не найден или разделение в нем не настроено, отвечает 404 и возвращает ok = false.
+func (h *AdminHandler) canaryPools(w http.ResponseWriter, r *http.Request) (names []string, ok bool) {
+	if name := r.URL.Query().Get("pool"); name != "" {
+		pool, found := h.Pools[name]
+		if !found {
+			response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APIPoolNotFound, name))
+			return nil, false
+		}
+		if _, configured := pool.Canary(time.Now()); !configured {
+			response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APICanaryNotConfigured, name))
+			return nil, false
+		}
+		return []string{name}, true
+	}
+	for name, pool := range h.Pools {
+		if _, configured := pool.Canary(time.Now()); configured {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+// getCanary обрабатывает GET /admin/canary[?pool=<имя>]: ошибки и время ответа стабильных и канареечных
+// бэкендов за canary.window и состояние автоматического отката.
+func (h *AdminHandler) getCanary(w http.ResponseWriter, r *http.Request) {
+	names, ok := h.canaryPools(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	statuses := []CanaryStatus{}
+	for _, name := range names {
+		report, _ := h.Pools[name].Canary(now)
+		statuses = append(statuses, CanaryStatus{Pool: name, CanaryReport: report})
+	}
+	response.RespondWithJSON(w, http.StatusOK, statuses)
+}
+
+// resumeCanary обрабатывает POST /admin/canary/resume[?pool=<имя>]: возобновляет откаченное разделение
+// (без ?pool= - во всех пулах) и возвращает состояние, как GET /admin/canary.
+func (h *AdminHandler) resumeCanary(w http.ResponseWriter, r *http.Request) {
+	names, ok := h.canaryPools(w, r)
+	if !ok {
+		return
+	}
+	for _, name := range names {
+		h.Pools[name].ResumeCanary()
+	}
+	h.getCanary(w, r)
+}
+
+// getTimeSeries обрабатывает GET /admin/stats/timeseries[?pool=<имя>][&seconds=<N>]: запросы и ошибки
+// каждого бэкенда по секундам за последние N секунд (по умолчанию - за stats_history.window).
+func (h *AdminHandler) getTimeSeries(w http.ResponseWriter, r *http.Request) {
+	seconds := 0
+	if raw := r.URL.Query().Get("seconds"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIBadTimeSeriesSeconds, raw))
+			return
+		}
+		seconds = parsed
+	}
+
+	pools := h.Pools
+	if name := r.URL.Query().Get("pool"); name != "" {
+		pool, ok := h.Pools[name]
+		if !ok {
+			response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APIPoolNotFound, name))
+			return
+		}
+		pools = map[string]*balancer.Balancer{name: pool}
+	}
+
+	end := time.Now().Truncate(time.Second)
+	resp := TimeSeriesResponse{End: end, BucketSeconds: 1, Pools: make(map[string][]balancer.BackendSeries, len(pools))}
+	for name, pool := range pools {
+		if series := pool.TimeSeries(end, seconds); series != nil {
+			resp.Pools[name] = series
+		}
+	}
+	response.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// configHash возвращает хэш последней успешно перечитанной конфигурации или конфигурации при запуске.
+func (h *AdminHandler) configHash() string {
+	if h.Reloads != nil {
+		if hash := h.Reloads.AppliedHash(); hash != "" {
+			return hash
+		}
+	}
+	return h.ConfigHash
+}
+
+// getLastReload обрабатывает GET /admin/config/last-reload: время, хэши и отличия последнего перечитывания
+// конфигурации, а также изменения, для применения которых нужен перезапуск.
+func (h *AdminHandler) getLastReload(w http.ResponseWriter) {
+	if h.Reloads == nil {
+		response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APIReloadUnavailable))
+		return
+	}
+	last, ok := h.Reloads.Last()
+	if !ok {
+		response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APINoReloadYet))
+		return
+	}
+	if last.Changes == nil {
+		last.Changes = []config.Change{}
+	}
+	response.RespondWithJSON(w, http.StatusOK, last)
+}
+
+// saveState обрабатывает POST /admin/state/save: внеочередное сохранение корзин токенов в хранилище
+// (например, перед обслуживанием). Отвечает 204 после завершения сохранения.
+func (h *AdminHandler) saveState(w http.ResponseWriter) {
+	if h.Limiter == nil {
+		response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APIStateSaveUnavailable))
+		return
+	}
+	if err := h.Limiter.SaveState(); err != nil {
+		i18n.Logf(i18n.APIStateSaveFailed, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIStateSaveInternal))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runMaintenance обрабатывает POST /admin/storage/maintenance: синхронно удаляет устаревшие записи базы
+// и возвращает освободившиеся страницы файловой системе (см. storage_maintenance).
+func (h *AdminHandler) runMaintenance(w http.ResponseWriter) {
+	if h.Maintenance == nil {
+		response.RespondWithError(w, http.StatusNotFound, response.CodeNotFound, i18n.T(i18n.APIMaintenanceUnavailable))
+		return
+	}
+	report, err := h.Maintenance.Run()
+	if err != nil {
+		i18n.Logf(i18n.APIMaintenanceFailed, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIMaintenanceInternal))
+		return
+	}
+	response.RespondWithJSON(w, http.StatusOK, report)
+}
+
+// getCapture обрабатывает GET /admin/capture: состояние записи и записанные запросы.
+func (h *AdminHandler) getCapture(w http.ResponseWriter) {
+	active, filter := h.Capture.Status()
+	response.RespondWithJSON(w, http.StatusOK, CaptureResponse{Active: active, Filter: filter, Entries: h.Capture.Entries()})
+}
+
+// startCapture обрабатывает PUT /admin/capture: включает запись для клиента и/или маршрута.
+// Записи предыдущего сеанса удаляются; DELETE /admin/capture выключает запись, сохраняя записи.
+func (h *AdminHandler) startCapture(w http.ResponseWriter, r *http.Request) {
+	var filter capture.Filter
+	if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeInvalidJSON, i18n.T(i18n.APIInvalidJSON, err))
+		return
+	}
+	if filter.Empty() {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APICaptureEmptyFilter))
+		return
+	}
+	h.Capture.Start(filter)
+	h.getCapture(w)
+}
+
+// forceHealthCheck обрабатывает POST /admin/health/check[?backend=<ID или URL>]
+// и синхронно возвращает результаты проверки.
+func (h *AdminHandler) forceHealthCheck(w http.ResponseWriter, r *http.Request) {
+	results, err := h.Health.CheckNow(r.URL.Query().Get("backend"))
+	if err != nil {
+		switch {
+		case errors.Is(err, balancer.ErrBackendNotFound):
+			response.RespondWithError(w, http.StatusNotFound, response.CodeBackendNotFound, err.Error())
+		case errors.Is(err, balancer.ErrHealthChecksDisabled):
+			response.RespondWithError(w, http.StatusConflict, response.CodeHealthChecksDisabled, err.Error())
+		default:
+			response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIHealthCheckFailed, err))
+		}
+		return
+	}
+
+	resp := HealthCheckResponse{Total: len(results), Results: results}
+	for _, result := range results {
+		if result.Healthy {
+			resp.Healthy++
+		}
+	}
+	response.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// setLogLevel обрабатывает PUT /admin/loglevel: меняет уровень логов без перезапуска
+// (например, включает debug на время разбора инцидента).
+func (h *AdminHandler) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeInvalidJSON, i18n.T(i18n.APIInvalidJSON, err))
+		return
+	}
+	level, err := i18n.ParseLevel(req.Level)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+		return
+	}
+	previous := i18n.CurrentLevel()
+	i18n.SetLevel(level)
+	i18n.Logf(i18n.APILogLevelChanged, previous, level)
+	response.RespondWithJSON(w, http.StatusOK, LogLevelResponse{Level: level.String()})
+}
+
+// updateHealthCheckConfig обрабатывает PUT /admin/health/config: меняет параметры проверок на лету.
+func (h *AdminHandler) updateHealthCheckConfig(w http.ResponseWriter, r *http.Request) {
+	var req HealthCheckConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeInvalidJSON, i18n.T(i18n.APIInvalidJSON, err))
+		return
+	}
+
+	cfg := h.Health.HealthCheckConfig()
+	if req.Interval != nil {
+		cfg.IntervalStr = *req.Interval
+	}
+	if req.Timeout != nil {
+		cfg.TimeoutStr = *req.Timeout
+	}
+	if req.Path != nil {
+		cfg.Path = *req.Path
+	}
+	if req.Type != nil {
+		cfg.Type = *req.Type
+	}
+	if req.GRPCService != nil {
+		cfg.GRPCService = *req.GRPCService
+	}
+	if req.MaxBackoff != nil {
+		cfg.MaxBackoffStr = *req.MaxBackoff
+	}
+	if req.Jitter != nil {
+		cfg.JitterStr = *req.Jitter
+	}
+	if err := cfg.Parse(); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+		return
+	}
+
+	if err := h.Health.UpdateHealthCheckConfig(cfg); err != nil {
+		if errors.Is(err, balancer.ErrHealthChecksDisabled) {
+			response.RespondWithError(w, http.StatusConflict, response.CodeHealthChecksDisabled, err.Error())
+			return
+		}
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+		return
+	}
+	response.RespondWithJSON(w, http.StatusOK, newHealthCheckConfigResponse(h.Health.HealthCheckConfig()))
+}
+
+func newHealthCheckConfigResponse(cfg config.HealthCheckConfig) HealthCheckConfigResponse {
+	return HealthCheckConfigResponse{
+		Enabled:     cfg.Enabled,
+		Interval:    cfg.Interval.String(),
+		Timeout:     cfg.Timeout.String(),
+		Path:        cfg.Path,
+		Type:        cfg.Type,
+		GRPCService: cfg.GRPCService,
+		Workers:     cfg.Workers,
+		MaxBackoff:  cfg.MaxBackoff.String(),
+		Jitter:      cfg.Jitter.String(),
+	}
+}
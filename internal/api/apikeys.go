@@ -0,0 +1,190 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/response"
+	"load-balancer/internal/storage"
+)
+
+// apiKeyBytes - длина генерируемого API-ключа в байтах до кодирования.
+const apiKeyBytes = 32
+
+// APIKeyStore - хранилище API-ключей (реализуется *storage.DB).
+type APIKeyStore interface {
+	PutAPIKey(key storage.APIKey) error
+	DeleteAPIKey(hash string) error
+	ListAPIKeys() ([]storage.APIKey, error)
+}
+
+// APIKeyCache - кэш найденных ключей, из которого удаляется отозванный ключ (реализуется *ratelimiter.RateLimiter).
+type APIKeyCache interface {
+	ForgetAPIKey(hash string)
+}
+
+// APIKeyRequest - тело запроса POST /api-keys.
+type APIKeyRequest struct {
+	ClientID string `json:"client_id"`
+	// Plan - шаблон rate_limiter.templates, лимиты которого назначаются клиенту. Пусто - лимиты клиента
+	// не меняются (задаются через /clients или действуют лимиты по умолчанию).
+	Plan string `json:"plan,omitempty"`
+}
+
+// APIKeyResponse - API-ключ в ответах /api-keys. Сам ключ возвращается только при создании:
+// хранится лишь его хэш, по которому ключ отзывается (DELETE /api-keys/{id}).
+type APIKeyResponse struct {
+	ID        string    `json:"id"`
+	Key       string    `json:"key,omitempty"`
+	ClientID  string    `json:"client_id"`
+	Plan      string    `json:"plan,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIKeyHandler выдает, перечисляет и отзывает API-ключи клиентов (/api-keys, см. rate_limiter.api_keys).
+type APIKeyHandler struct {
+	Store APIKeyStore
+	// Plans - планы, которые можно назначить при выдаче ключа; nil - ключи выдаются только без плана.
+	Plans PlanLookup
+	// Cache - nil, если кэша нет: отозванный ключ перестает действовать сразу.
+	Cache APIKeyCache
+}
+
+func NewAPIKeyHandler(store APIKeyStore) *APIKeyHandler {
+	return &APIKeyHandler{Store: store}
+}
+
+func (h *APIKeyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		response.RespondWithError(w, http.StatusServiceUnavailable, response.CodeStoreUnavailable, i18n.T(i18n.APIStoreUnavailable))
+		return
+	}
+
+	// Путь после StripPrefix("/api-keys", ...): "" или "/" для коллекции, "/{id}" для ключа.
+	id := strings.Trim(r.URL.Path, "/")
+	if id == "" {
+		switch r.Method {
+		case http.MethodGet:
+			h.listKeys(w)
+		case http.MethodPost:
+			h.createKey(w, r)
+		default:
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, "/api-keys"))
+		}
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, "/api-keys/{id}"))
+		return
+	}
+	h.deleteKey(w, id)
+}
+
+// listKeys обрабатывает GET /api-keys
+func (h *APIKeyHandler) listKeys(w http.ResponseWriter) {
+	keys, err := h.Store.ListAPIKeys()
+	if err != nil {
+		i18n.Logf(i18n.APIAPIKeyListFailed, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIAPIKeyInternal))
+		return
+	}
+	resp := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		resp = append(resp, APIKeyResponse{ID: key.Hash, ClientID: key.ClientID, Plan: key.Plan, CreatedAt: key.CreatedAt})
+	}
+	response.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// createKey обрабатывает POST /api-keys: генерирует ключ и, если задан план, назначает его лимиты клиенту.
+func (h *APIKeyHandler) createKey(w http.ResponseWriter, r *http.Request) {
+	var req APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeInvalidJSON, i18n.T(i18n.APIInvalidJSON, err))
+		return
+	}
+	if req.ClientID == "" {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIClientIDRequired))
+		return
+	}
+	if req.Plan != "" {
+		if !h.applyPlan(w, req) {
+			return
+		}
+	}
+
+	raw := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(raw); err != nil {
+		i18n.Logf(i18n.APIAPIKeyCreateFailed, req.ClientID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIAPIKeyInternal))
+		return
+	}
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+	key := storage.APIKey{
+		Hash:      storage.HashAPIKey(secret),
+		ClientID:  req.ClientID,
+		Plan:      req.Plan,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := h.Store.PutAPIKey(key); err != nil {
+		i18n.Logf(i18n.APIAPIKeyCreateFailed, req.ClientID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIAPIKeyInternal))
+		return
+	}
+	response.RespondWithJSON(w, http.StatusCreated, APIKeyResponse{
+		ID: key.Hash, Key: secret, ClientID: key.ClientID, Plan: key.Plan, CreatedAt: key.CreatedAt,
+	})
+}
+
+// applyPlan записывает клиенту лимиты плана из запроса (create-or-update, как rate_limiter.clients)
+// и отвечает клиенту при ошибке. Возвращает false, если запрос отклонен.
+func (h *APIKeyHandler) applyPlan(w http.ResponseWriter, req APIKeyRequest) bool {
+	var limit config.ClientRateConfig
+	ok := false
+	if h.Plans != nil {
+		limit, ok = h.Plans.Plan(req.Plan)
+	}
+	if !ok {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIUnknownPlan, req.Plan))
+		return false
+	}
+	limits, ok := h.Store.(ClientLimitStore)
+	if !ok {
+		response.RespondWithError(w, http.StatusNotImplemented, response.CodeNotImplemented, i18n.T(i18n.APIAPIKeyPlansNotSupported))
+		return false
+	}
+	err := limits.CreateClientLimit(req.ClientID, limit)
+	if errors.Is(err, storage.ErrClientAlreadyExists) {
+		err = limits.UpdateClientLimit(req.ClientID, limit)
+	}
+	if err != nil {
+		i18n.Logf(i18n.APIAPIKeyCreateFailed, req.ClientID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIAPIKeyInternal))
+		return false
+	}
+	return true
+}
+
+// deleteKey обрабатывает DELETE /api-keys/{id}
+func (h *APIKeyHandler) deleteKey(w http.ResponseWriter, id string) {
+	err := h.Store.DeleteAPIKey(id)
+	switch {
+	case err == nil:
+		if h.Cache != nil {
+			h.Cache.ForgetAPIKey(id)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, storage.ErrAPIKeyNotFound):
+		response.RespondWithError(w, http.StatusNotFound, response.CodeAPIKeyNotFound, i18n.T(i18n.APIAPIKeyNotFound, id))
+	default:
+		i18n.Logf(i18n.APIAPIKeyDeleteFailed, id, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIAPIKeyInternal))
+	}
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/response"
+)
+
+// authRealm - область защиты в заголовке WWW-Authenticate.
+const authRealm = `realm="load-balancer"`
+
+// Principal - пользователь API управления, прошедший аутентификацию.
+type Principal struct {
+	Name string
+	Role string // config.AdminRoleAdmin или config.AdminRoleViewer.
+}
+
+// Authenticator проверяет учетные данные запроса к API управления (см. RequireAuth).
+type Authenticator interface {
+	// Authenticate возвращает пользователя запроса; false - учетных данных нет или они неверны.
+	Authenticate(r *http.Request) (Principal, bool)
+	// Challenge возвращает значение WWW-Authenticate для ответа 401, например `Bearer realm="load-balancer"`.
+	Challenge() string
+}
+
+// Authenticators проверяет запрос по очереди каждым Authenticator; первый успешный определяет пользователя.
+type Authenticators []Authenticator
+
+func (a Authenticators) Authenticate(r *http.Request) (Principal, bool) {
+	for _, auth := range a {
+		if principal, ok := auth.Authenticate(r); ok {
+			return principal, true
+		}
+	}
+	return Principal{}, false
+}
+
+func (a Authenticators) Challenge() string {
+	challenges := make([]string, 0, len(a))
+	for _, auth := range a {
+		challenges = append(challenges, auth.Challenge())
+	}
+	return strings.Join(challenges, ", ")
+}
+
+// BearerAuthenticator проверяет статические токены из заголовка Authorization: Bearer <токен>.
+type BearerAuthenticator struct {
+	tokens []bearerToken
+}
+
+type bearerToken struct {
+	digest    [sha256.Size]byte // Токен сравнивается по хэшу: время сравнения не зависит от длины совпадения.
+	principal Principal
+}
+
+// NewBearerAuthenticator создает проверку токенов admin_auth.tokens.
+func NewBearerAuthenticator(tokens []config.AdminTokenConfig) *BearerAuthenticator {
+	auth := &BearerAuthenticator{}
+	for _, token := range tokens {
+		auth.tokens = append(auth.tokens, bearerToken{
+			digest:    sha256.Sum256([]byte(token.Token)),
+			principal: Principal{Name: token.Name, Role: token.Role},
+		})
+	}
+	return auth
+}
+
+func (a *BearerAuthenticator) Authenticate(r *http.Request) (Principal, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return Principal{}, false
+	}
+	digest := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	// Проверяются все токены, чтобы время ответа не выдавало, какой из них совпал
+	var found Principal
+	ok = false
+	for _, candidate := range a.tokens {
+		if subtle.ConstantTimeCompare(digest[:], candidate.digest[:]) == 1 {
+			found, ok = candidate.principal, true
+		}
+	}
+	return found, ok
+}
+
+func (a *BearerAuthenticator) Challenge() string {
+	return "Bearer " + authRealm
+}
+
+// BasicAuthenticator проверяет пользователей и пароли из заголовка Authorization: Basic (RFC 7617).
+type BasicAuthenticator struct {
+	users map[string]basicUser
+}
+
+type basicUser struct {
+	digest    [sha256.Size]byte // Хэш пароля.
+	principal Principal
+}
+
+// NewBasicAuthenticator создает проверку пользователей admin_auth.users.
+func NewBasicAuthenticator(users []config.AdminUserConfig) *BasicAuthenticator {
+	auth := &BasicAuthenticator{users: make(map[string]basicUser, len(users))}
+	for _, user := range users {
+		auth.users[user.Username] = basicUser{
+			digest:    sha256.Sum256([]byte(user.Password)),
+			principal: Principal{Name: user.Username, Role: user.Role},
+		}
+	}
+	return auth
+}
+
+func (a *BasicAuthenticator) Authenticate(r *http.Request) (Principal, bool) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return Principal{}, false
+	}
+	user, known := a.users[username]
+	digest := sha256.Sum256([]byte(password))
+	if subtle.ConstantTimeCompare(digest[:], user.digest[:]) != 1 || !known {
+		return Principal{}, false
+	}
+	return user.principal, true
+}
+
+func (a *BasicAuthenticator) Challenge() string {
+	return `Basic ` + authRealm + `, charset="UTF-8"`
+}
+
+// NewAuthenticator создает проверку учетных данных из admin_auth. nil - аутентификация выключена.
+func NewAuthenticator(cfg config.AdminAuthConfig) Authenticator {
+	var auth Authenticators
+	if len(cfg.Tokens) > 0 {
+		auth = append(auth, NewBearerAuthenticator(cfg.Tokens))
+	}
+	if len(cfg.Users) > 0 {
+		auth = append(auth, NewBasicAuthenticator(cfg.Users))
+	}
+	if len(auth) == 0 {
+		return nil
+	}
+	return auth
+}
+
+// RequireAuth пропускает к next только запросы, прошедшие auth: без учетных данных или с неверными
+// ответ 401 с WWW-Authenticate, пользователю с ролью viewer на изменяющие методы - 403.
+// С auth = nil запросы пропускаются без проверки.
+func RequireAuth(auth Authenticator, next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.Authenticate(r)
+		if !ok {
+			i18n.Logf(i18n.APIAuthFailed, r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", auth.Challenge())
+			response.RespondWithError(w, http.StatusUnauthorized, response.CodeUnauthorized, i18n.T(i18n.APIUnauthorized))
+			return
+		}
+		if principal.Role != config.AdminRoleAdmin && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			i18n.Logf(i18n.APIAuthForbidden, principal.Name, r.Method, r.URL.Path)
+			response.RespondWithError(w, http.StatusForbidden, response.CodeForbidden, i18n.T(i18n.APIForbidden, principal.Name, r.Method))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
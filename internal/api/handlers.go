@@ -3,12 +3,14 @@ package api
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
-	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/ratelimiter"
 	"load-balancer/internal/response"
 	"load-balancer/internal/storage"
 )
@@ -20,55 +22,134 @@ type ClientLimitStore interface {
 	DeleteClientLimit(clientID string) error
 }
 
+// ClientTrialStore - хранилище пробных периодов клиентов (реализуется *storage.DB).
+// Если Store его не реализует, запросы с trial_until отклоняются.
+type ClientTrialStore interface {
+	SetClientTrial(trial storage.ClientTrial) error
+	GetClientTrial(clientID string) (trial storage.ClientTrial, found bool, err error)
+}
+
+// ClientListStore - хранилище, перечисляющее клиентов (реализуется *storage.DB).
+// Если Store его не реализует, GET /clients отвечает 501.
+type ClientListStore interface {
+	ListClientLimits(prefix string, limit, offset int) (clients []storage.ClientLimit, total int, err error)
+}
+
+// Размер страницы GET /clients (параметр limit).
+const (
+	DefaultClientListLimit = 100
+	MaxClientListLimit     = 1000
+)
+
+// PlanLookup находит план (шаблон rate_limiter.templates) по имени (реализуется *ratelimiter.RateLimiter).
+type PlanLookup interface {
+	Plan(name string) (config.ClientRateConfig, bool)
+}
+
+// LiveLimiter - работающий Rate Limiter с корзинами клиентов в памяти (реализуется *ratelimiter.RateLimiter).
+type LiveLimiter interface {
+	// ResetBucket заполняет корзину клиента до емкости (POST /clients/{id}/reset).
+	ResetBucket(key string) (capacity float64, err error)
+	// BucketState возвращает состояние корзины клиента (GET /clients/{id}/state).
+	BucketState(key string) (ratelimiter.BucketState, error)
+}
+
 // ClientLimitRequest структура для тела запроса при создании/обновлении лимита.
 type ClientLimitRequest struct {
 	ClientID string  `json:"client_id"`
 	Rate     float64 `json:"rate_per_sec"`
 	Capacity float64 `json:"capacity"`
+	// TrialUntil - окончание пробного периода: после него клиент переводится на план DowngradeTo.
+	// Не задано - лимит постоянный (PUT снимает пробный период).
+	TrialUntil  *time.Time `json:"trial_until,omitempty"`
+	DowngradeTo string     `json:"downgrade_to,omitempty"`
+}
+
+// ClientLimitPatch - тело запроса PATCH /clients/{id}: незаданные поля сохраняют текущие значения.
+// Пробный период клиента не меняется.
+type ClientLimitPatch struct {
+	ClientID string   `json:"client_id,omitempty"`
+	Rate     *float64 `json:"rate_per_sec"`
+	Capacity *float64 `json:"capacity"`
 }
 
 // ClientLimitResponse структура для ответа при получении/создании/обновлении лимита.
 type ClientLimitResponse struct {
+	ClientID    string     `json:"client_id"`
+	Rate        float64    `json:"rate_per_sec"`
+	Capacity    float64    `json:"capacity"`
+	TrialUntil  *time.Time `json:"trial_until,omitempty"`
+	DowngradeTo string     `json:"downgrade_to,omitempty"`
+}
+
+// ClientListResponse - ответ GET /clients: страница клиентов в порядке ID.
+type ClientListResponse struct {
+	Clients []ClientLimitResponse `json:"clients"`
+	Total   int                   `json:"total"` // Сколько всего клиентов с префиксом prefix.
+	Limit   int                   `json:"limit"`
+	Offset  int                   `json:"offset"`
+}
+
+// ClientResetResponse - ответ POST /clients/{id}/reset: корзина клиента после сброса.
+type ClientResetResponse struct {
 	ClientID string  `json:"client_id"`
-	Rate     float64 `json:"rate_per_sec"`
-	Capacity float64 `json:"capacity"`
+	Tokens   float64 `json:"tokens"` // Равно емкости корзины.
 }
 
 // APIHandler обрабатывает HTTP-запросы к API.
 type APIHandler struct {
 	Store ClientLimitStore
+	// Plans - планы для downgrade_to; nil - пробные периоды не принимаются.
+	Plans PlanLookup
+	// Limiter - Rate Limiter для /clients/{id}/reset и /clients/{id}/state; nil - корзины недоступны.
+	Limiter LiveLimiter
 }
 
 func NewAPIHandler(store ClientLimitStore) *APIHandler {
 	if store == nil {
-		log.Println("[API] Warning: Хранилище (Store) не предоставлено APIHandler. CRUD операции не будут работать.")
+		i18n.Logf(i18n.APINoStore)
 	}
 	return &APIHandler{Store: store}
 }
 
 func (h *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if h.Store == nil {
-		response.RespondWithError(w, http.StatusServiceUnavailable, "Хранилище лимитов недоступно")
-		return
-	}
-
 	// r.URL.Path здесь уже *после* применения StripPrefix("/clients", ...)
 	// Если исходный путь был /clients или /clients/, то r.URL.Path будет "" или "/"
 	// Если исходный путь был /clients/{id} или /clients/{id}/, то r.URL.Path будет "/{id}" или "/{id}/"
 	pathPart := strings.TrimPrefix(r.URL.Path, "/") // Убираем ведущий слэш, если есть
 	pathPart = strings.TrimSuffix(pathPart, "/")    // Убираем завершающий слэш, если есть
 
-	log.Printf("[API] Debug: Path after StripPrefix and Trim: '%s' (Original r.URL.Path: '%s')", pathPart, r.URL.Path)
+	i18n.Logf(i18n.APIDebugPath, pathPart, r.URL.Path)
+
+	// Документация API не зависит от хранилища; клиент с ID "docs" через GET недоступен
+	if r.Method == http.MethodGet && (pathPart == "docs" || pathPart == "docs/openapi.json") {
+		serveDocs(w)
+		return
+	}
+
+	// Корзины живут в памяти Rate Limiter: сброс и состояние доступны и без хранилища
+	if clientID, ok := strings.CutSuffix(pathPart, "/reset"); ok && clientID != "" && r.Method == http.MethodPost {
+		h.resetClient(w, clientID)
+		return
+	}
+	if clientID, ok := strings.CutSuffix(pathPart, "/state"); ok && clientID != "" && r.Method == http.MethodGet {
+		h.clientState(w, clientID)
+		return
+	}
+
+	if h.Store == nil {
+		response.RespondWithError(w, http.StatusServiceUnavailable, response.CodeStoreUnavailable, i18n.T(i18n.APIStoreUnavailable))
+		return
+	}
 
 	if pathPart == "" { // Обработка запросов к коллекции (/clients или /clients/)
 		switch r.Method {
 		case http.MethodPost:
 			h.createClient(w, r)
 		case http.MethodGet:
-			// TODO: Реализовать GET /clients для получения списка всех клиентов?
-			response.RespondWithError(w, http.StatusNotImplemented, "Получение списка всех клиентов не реализовано")
+			h.listClients(w, r)
 		default:
-			response.RespondWithError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Метод %s не поддерживается для /clients", r.Method))
+			response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIMethodNotAllowedCollection, r.Method))
 		}
 		return // Завершаем обработку
 	}
@@ -80,10 +161,12 @@ func (h *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.getClient(w, r, clientID)
 	case http.MethodPut:
 		h.updateClient(w, r, clientID)
+	case http.MethodPatch:
+		h.patchClient(w, r, clientID)
 	case http.MethodDelete:
 		h.deleteClient(w, r, clientID)
 	default:
-		response.RespondWithError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Метод %s не поддерживается для /clients/{id}", r.Method))
+		response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIMethodNotAllowedItem, r.Method))
 	}
 }
 
@@ -91,17 +174,21 @@ func (h *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (h *APIHandler) createClient(w http.ResponseWriter, r *http.Request) {
 	var req ClientLimitRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Ошибка парсинга JSON: %v", err))
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeInvalidJSON, i18n.T(i18n.APIInvalidJSON, err))
 		return
 	}
 
 	if req.ClientID == "" {
-		response.RespondWithError(w, http.StatusBadRequest, "Поле client_id обязательно")
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIClientIDRequired))
 		return
 	}
 	// Используем req.Rate и req.Capacity напрямую
 	if req.Rate <= 0 || req.Capacity <= 0 {
-		response.RespondWithError(w, http.StatusBadRequest, "Значения rate и capacity должны быть положительными")
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APINonPositiveLimit))
+		return
+	}
+	trialStore, ok := h.validateTrial(w, req)
+	if !ok {
 		return
 	}
 
@@ -116,34 +203,88 @@ func (h *APIHandler) createClient(w http.ResponseWriter, r *http.Request) {
 		// Используем errors.Is для проверки конкретной ошибки из хранилища
 		if errors.Is(err, storage.ErrClientAlreadyExists) {
 			// Возвращаем осмысленный HTTP статус и сообщение
-			response.RespondWithError(w, http.StatusConflict, fmt.Sprintf("Клиент с ID '%s' уже существует", req.ClientID))
+			response.RespondWithError(w, http.StatusConflict, response.CodeClientExists, i18n.T(i18n.APIClientExists, req.ClientID))
 		} else {
 			// Логируем оригинальную ошибку для отладки
-			log.Printf("[API] Ошибка при создании клиента '%s': %v", req.ClientID, err)
-			response.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера при создании клиента")
+			i18n.Logf(i18n.APICreateFailed, req.ClientID, err)
+			response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APICreateInternal))
 		}
 		return
 	}
+	if req.TrialUntil != nil {
+		if err := trialStore.SetClientTrial(requestTrial(req.ClientID, req)); err != nil {
+			i18n.Logf(i18n.APICreateFailed, req.ClientID, err)
+			response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APICreateInternal))
+			return
+		}
+	}
 
 	// Возвращаем созданный объект (используем ClientLimitResponse для ответа)
 	resp := ClientLimitResponse{
-		ClientID: req.ClientID,
-		Rate:     req.Rate,
-		Capacity: req.Capacity,
+		ClientID:    req.ClientID,
+		Rate:        req.Rate,
+		Capacity:    req.Capacity,
+		TrialUntil:  req.TrialUntil,
+		DowngradeTo: req.DowngradeTo,
 	}
 	response.RespondWithJSON(w, http.StatusCreated, resp)
 }
 
+// listClients обрабатывает GET /clients?limit=&offset=&prefix=
+func (h *APIHandler) listClients(w http.ResponseWriter, r *http.Request) {
+	listStore, ok := h.Store.(ClientListStore)
+	if !ok {
+		response.RespondWithError(w, http.StatusNotImplemented, response.CodeNotImplemented, i18n.T(i18n.APIListNotImplemented))
+		return
+	}
+
+	query := r.URL.Query()
+	limit := DefaultClientListLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > MaxClientListLimit {
+			response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIBadListLimit, value, MaxClientListLimit))
+			return
+		}
+		limit = parsed
+	}
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIBadListOffset, value))
+			return
+		}
+		offset = parsed
+	}
+
+	clients, total, err := listStore.ListClientLimits(query.Get("prefix"), limit, offset)
+	if err != nil {
+		i18n.Logf(i18n.APIListFailed, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIListInternal))
+		return
+	}
+	resp := ClientListResponse{Clients: make([]ClientLimitResponse, 0, len(clients)), Total: total, Limit: limit, Offset: offset}
+	for _, client := range clients {
+		item := ClientLimitResponse{ClientID: client.ClientID, Rate: client.Rate, Capacity: client.Capacity}
+		if !client.Trial.Until.IsZero() {
+			item.TrialUntil, item.DowngradeTo = &client.Trial.Until, client.Trial.Plan
+		}
+		resp.Clients = append(resp.Clients, item)
+	}
+	response.RespondWithJSON(w, http.StatusOK, resp)
+}
+
 // getClient обрабатывает GET /clients/{clientID}
 func (h *APIHandler) getClient(w http.ResponseWriter, r *http.Request, clientID string) {
 	// Используем новый GetClientLimitConfig, т.к. нам нужны только rate и capacity для ответа
 	rate, capacity, found, err := h.Store.GetClientLimitConfig(clientID)
 	if err != nil {
-		response.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка получения лимита из БД: %v", err))
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIGetFailed, err))
 		return
 	}
 	if !found {
-		response.RespondWithError(w, http.StatusNotFound, fmt.Sprintf("Клиент с ID '%s' не найден", clientID))
+		response.RespondWithError(w, http.StatusNotFound, response.CodeClientNotFound, i18n.T(i18n.APIClientNotFound, clientID))
 		return
 	}
 
@@ -152,25 +293,49 @@ func (h *APIHandler) getClient(w http.ResponseWriter, r *http.Request, clientID
 		Rate:     rate,
 		Capacity: capacity,
 	}
+	if err := h.addTrial(&resp); err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIGetFailed, err))
+		return
+	}
 	response.RespondWithJSON(w, http.StatusOK, resp)
 }
 
+// addTrial дополняет ответ пробным периодом клиента, если хранилище их поддерживает.
+func (h *APIHandler) addTrial(resp *ClientLimitResponse) error {
+	trialStore, ok := h.Store.(ClientTrialStore)
+	if !ok {
+		return nil
+	}
+	trial, found, err := trialStore.GetClientTrial(resp.ClientID)
+	if err != nil {
+		return err
+	}
+	if found {
+		resp.TrialUntil, resp.DowngradeTo = &trial.Until, trial.Plan
+	}
+	return nil
+}
+
 // updateClient обрабатывает PUT /clients/{clientID}
 func (h *APIHandler) updateClient(w http.ResponseWriter, r *http.Request, clientID string) {
 	var req ClientLimitRequest // Ожидаем плоскую структуру
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Ошибка парсинга JSON: %v", err))
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeInvalidJSON, i18n.T(i18n.APIInvalidJSON, err))
 		return
 	}
 
 	// Проверяем, что client_id в теле совпадает с путем (или отсутствует в теле)
 	if req.ClientID != "" && req.ClientID != clientID {
-		response.RespondWithError(w, http.StatusBadRequest, "client_id в теле запроса не совпадает с ID в пути")
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIClientIDMismatch))
 		return
 	}
 	// Используем req.Rate и req.Capacity напрямую
 	if req.Rate <= 0 || req.Capacity <= 0 {
-		response.RespondWithError(w, http.StatusBadRequest, "Значения rate и capacity должны быть положительными")
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APINonPositiveLimit))
+		return
+	}
+	trialStore, ok := h.validateTrial(w, req)
+	if !ok {
 		return
 	}
 
@@ -183,18 +348,89 @@ func (h *APIHandler) updateClient(w http.ResponseWriter, r *http.Request, client
 	if err != nil {
 		// Используем errors.Is для проверки
 		if errors.Is(err, storage.ErrClientNotFound) {
-			response.RespondWithError(w, http.StatusNotFound, fmt.Sprintf("Клиент с ID '%s' не найден для обновления", clientID))
+			response.RespondWithError(w, http.StatusNotFound, response.CodeClientNotFound, i18n.T(i18n.APIUpdateNotFound, clientID))
+		} else {
+			i18n.Logf(i18n.APIUpdateFailed, clientID, err)
+			response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIUpdateInternal))
+		}
+		return
+	}
+	// Лимит без trial_until становится постоянным
+	if trialStore != nil {
+		if err := trialStore.SetClientTrial(requestTrial(clientID, req)); err != nil {
+			i18n.Logf(i18n.APIUpdateFailed, clientID, err)
+			response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIUpdateInternal))
+			return
+		}
+	}
+
+	resp := ClientLimitResponse{
+		ClientID:    clientID,
+		Rate:        req.Rate,
+		Capacity:    req.Capacity,
+		TrialUntil:  req.TrialUntil,
+		DowngradeTo: req.DowngradeTo,
+	}
+	response.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// patchClient обрабатывает PATCH /clients/{clientID}: меняет только заданные поля, остальные берутся
+// из хранилища.
+func (h *APIHandler) patchClient(w http.ResponseWriter, r *http.Request, clientID string) {
+	var patch ClientLimitPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeInvalidJSON, i18n.T(i18n.APIInvalidJSON, err))
+		return
+	}
+	if patch.ClientID != "" && patch.ClientID != clientID {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIClientIDMismatch))
+		return
+	}
+	if patch.Rate == nil && patch.Capacity == nil {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIPatchEmpty))
+		return
+	}
+
+	rate, capacity, found, err := h.Store.GetClientLimitConfig(clientID)
+	if err != nil {
+		i18n.Logf(i18n.APIUpdateFailed, clientID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIUpdateInternal))
+		return
+	}
+	if !found {
+		response.RespondWithError(w, http.StatusNotFound, response.CodeClientNotFound, i18n.T(i18n.APIUpdateNotFound, clientID))
+		return
+	}
+	limitConfig := config.ClientRateConfig{Rate: rate, Capacity: capacity}
+	if patch.Rate != nil {
+		limitConfig.Rate = *patch.Rate
+	}
+	if patch.Capacity != nil {
+		limitConfig.Capacity = *patch.Capacity
+	}
+	if limitConfig.Rate <= 0 || limitConfig.Capacity <= 0 {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APINonPositiveLimit))
+		return
+	}
+
+	if err := h.Store.UpdateClientLimit(clientID, limitConfig); err != nil {
+		if errors.Is(err, storage.ErrClientNotFound) {
+			response.RespondWithError(w, http.StatusNotFound, response.CodeClientNotFound, i18n.T(i18n.APIUpdateNotFound, clientID))
 		} else {
-			log.Printf("[API] Ошибка при обновлении клиента '%s': %v", clientID, err)
-			response.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера при обновлении клиента")
+			i18n.Logf(i18n.APIUpdateFailed, clientID, err)
+			response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIUpdateInternal))
 		}
 		return
 	}
 
 	resp := ClientLimitResponse{
 		ClientID: clientID,
-		Rate:     req.Rate,
-		Capacity: req.Capacity,
+		Rate:     limitConfig.Rate,
+		Capacity: limitConfig.Capacity,
+	}
+	if err := h.addTrial(&resp); err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIGetFailed, err))
+		return
 	}
 	response.RespondWithJSON(w, http.StatusOK, resp)
 }
@@ -205,13 +441,87 @@ func (h *APIHandler) deleteClient(w http.ResponseWriter, r *http.Request, client
 	if err != nil {
 		// Используем errors.Is для проверки
 		if errors.Is(err, storage.ErrClientNotFound) {
-			response.RespondWithError(w, http.StatusNotFound, fmt.Sprintf("Клиент с ID '%s' не найден для удаления", clientID))
+			response.RespondWithError(w, http.StatusNotFound, response.CodeClientNotFound, i18n.T(i18n.APIDeleteNotFound, clientID))
 		} else {
-			log.Printf("[API] Ошибка при удалении клиента '%s': %v", clientID, err)
-			response.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера при удалении клиента")
+			i18n.Logf(i18n.APIDeleteFailed, clientID, err)
+			response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIDeleteInternal))
 		}
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// resetClient обрабатывает POST /clients/{clientID}/reset: заполняет корзину клиента до емкости
+func (h *APIHandler) resetClient(w http.ResponseWriter, clientID string) {
+	if h.Limiter == nil {
+		response.RespondWithError(w, http.StatusNotImplemented, response.CodeNotImplemented, i18n.T(i18n.APIResetNotSupported))
+		return
+	}
+	capacity, err := h.Limiter.ResetBucket(clientID)
+	switch {
+	case err == nil:
+		response.RespondWithJSON(w, http.StatusOK, ClientResetResponse{ClientID: clientID, Tokens: capacity})
+	case errors.Is(err, ratelimiter.ErrDisabled):
+		response.RespondWithError(w, http.StatusConflict, response.CodeRateLimiterDisabled, i18n.T(i18n.APIResetDisabled))
+	case errors.Is(err, ratelimiter.ErrStoreUnavailable):
+		response.RespondWithError(w, http.StatusServiceUnavailable, response.CodeStoreUnavailable, i18n.T(i18n.APIStoreUnavailable))
+	default:
+		i18n.Logf(i18n.APIResetFailed, clientID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.APIResetInternal))
+	}
+}
+
+// clientState обрабатывает GET /clients/{clientID}/state: текущее состояние корзины клиента
+func (h *APIHandler) clientState(w http.ResponseWriter, clientID string) {
+	if h.Limiter == nil {
+		response.RespondWithError(w, http.StatusNotImplemented, response.CodeNotImplemented, i18n.T(i18n.APIStateNotSupported))
+		return
+	}
+	state, err := h.Limiter.BucketState(clientID)
+	switch {
+	case err == nil:
+		response.RespondWithJSON(w, http.StatusOK, state)
+	case errors.Is(err, ratelimiter.ErrDisabled):
+		response.RespondWithError(w, http.StatusConflict, response.CodeRateLimiterDisabled, i18n.T(i18n.APIStateDisabled))
+	default:
+		// Корзины нет в памяти, а хранилище не ответило
+		i18n.Logf(i18n.APIStateFailed, clientID, err)
+		response.RespondWithError(w, http.StatusServiceUnavailable, response.CodeStoreUnavailable, i18n.T(i18n.APIStoreUnavailable))
+	}
+}
+
+// validateTrial проверяет пробный период из запроса и отвечает клиенту при ошибке.
+// Возвращает хранилище пробных периодов (nil, если Store их не поддерживает) и false, если запрос отклонен.
+func (h *APIHandler) validateTrial(w http.ResponseWriter, req ClientLimitRequest) (ClientTrialStore, bool) {
+	trialStore, _ := h.Store.(ClientTrialStore)
+	if req.TrialUntil == nil && req.DowngradeTo == "" {
+		return trialStore, true
+	}
+
+	switch {
+	case req.TrialUntil == nil || req.DowngradeTo == "":
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APITrialIncomplete))
+	case !req.TrialUntil.After(time.Now()):
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APITrialInPast))
+	case trialStore == nil:
+		response.RespondWithError(w, http.StatusNotImplemented, response.CodeNotImplemented, i18n.T(i18n.APITrialsNotSupported))
+	default:
+		if h.Plans != nil {
+			if _, ok := h.Plans.Plan(req.DowngradeTo); ok {
+				return trialStore, true
+			}
+		}
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeValidationFailed, i18n.T(i18n.APIUnknownPlan, req.DowngradeTo))
+	}
+	return nil, false
+}
+
+// requestTrial возвращает пробный период клиента из запроса (нулевой Until - пробного периода нет).
+func requestTrial(clientID string, req ClientLimitRequest) storage.ClientTrial {
+	trial := storage.ClientTrial{ClientID: clientID, Plan: req.DowngradeTo}
+	if req.TrialUntil != nil {
+		trial.Until = *req.TrialUntil
+	}
+	return trial
+}
@@ -2,6 +2,7 @@ package api_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,14 +10,22 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"load-balancer/internal/access"
 	"load-balancer/internal/api"
+	"load-balancer/internal/balancer"
+	"load-balancer/internal/capture"
 	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/ratelimiter"
+	"load-balancer/internal/response"
 	"load-balancer/internal/storage"
 
 	_ "modernc.org/sqlite"
@@ -77,6 +86,7 @@ func TestAPI_CRUD_Operations(t *testing.T) {
 	require.NoError(t, err)
 	resp.Body.Close() // Не забываем закрыть тело
 	require.Contains(t, string(bodyBytes), fmt.Sprintf("Клиент с ID '%s' уже существует", clientID), "Create duplicate error message")
+	require.Contains(t, string(bodyBytes), `"error_code":"CLIENT_EXISTS"`, "Create duplicate error code")
 
 	// 3. Get Client
 	resp, err = http.Get(server.URL + "/clients/" + clientID)
@@ -208,7 +218,7 @@ func TestAPI_BadRequest(t *testing.T) {
 	resp.Body.Close()
 
 	// 5. Неподдерживаемый метод
-	req, _ = http.NewRequest(http.MethodPatch, server.URL+"/clients/some-id", nil)
+	req, _ = http.NewRequest(http.MethodOptions, server.URL+"/clients/some-id", nil)
 	resp, err = http.DefaultClient.Do(req)
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
@@ -225,8 +235,260 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.ResponseWriter.WriteHeader(code)
 }
 
+// TestAPI_ClientTrial проверяет пробный лимит: trial_until и downgrade_to сохраняются, возвращаются в GET,
+// проверяются при создании и снимаются PUT без trial_until.
+func TestAPI_ClientTrial(t *testing.T) {
+	apiHandler, cleanup := setupTestAPI(t)
+	defer cleanup()
+	apiHandler.Plans = planLookup{"free": {Rate: 1, Capacity: 5}}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		apiHandler.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	rr := do(http.MethodPost, "/", fmt.Sprintf(
+		`{"client_id":"trial","rate_per_sec":50,"capacity":500,"trial_until":%q,"downgrade_to":"free"}`, until.Format(time.RFC3339)))
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	rr = do(http.MethodGet, "/trial", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var got api.ClientLimitResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	require.NotNil(t, got.TrialUntil)
+	assert.True(t, until.Equal(*got.TrialUntil))
+	assert.Equal(t, "free", got.DowngradeTo)
+
+	invalid := map[string]string{
+		"нет плана":        `{"client_id":"a","rate_per_sec":1,"capacity":1,"trial_until":"2099-01-01T00:00:00Z"}`,
+		"нет окончания":    `{"client_id":"a","rate_per_sec":1,"capacity":1,"downgrade_to":"free"}`,
+		"в прошлом":        `{"client_id":"a","rate_per_sec":1,"capacity":1,"trial_until":"2000-01-01T00:00:00Z","downgrade_to":"free"}`,
+		"неизвестный план": `{"client_id":"a","rate_per_sec":1,"capacity":1,"trial_until":"2099-01-01T00:00:00Z","downgrade_to":"gold"}`,
+	}
+	for name, body := range invalid {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/", body).Code, name)
+	}
+
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/trial", `{"rate_per_sec":50,"capacity":500}`).Code)
+	var permanent api.ClientLimitResponse
+	require.NoError(t, json.Unmarshal(do(http.MethodGet, "/trial", "").Body.Bytes(), &permanent))
+	assert.Nil(t, permanent.TrialUntil, "PUT без trial_until делает лимит постоянным")
+	assert.Empty(t, permanent.DowngradeTo)
+
+	// Хранилище без пробных периодов
+	rr = httptest.NewRecorder()
+	handler := &api.APIHandler{Store: &mockStore{}, Plans: planLookup{"free": {Rate: 1, Capacity: 5}}}
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(
+		`{"client_id":"a","rate_per_sec":1,"capacity":1,"trial_until":"2099-01-01T00:00:00Z","downgrade_to":"free"}`)))
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+}
+
+// TestAPI_PatchClient проверяет частичное изменение лимита: незаданные поля и пробный период сохраняются.
+func TestAPI_PatchClient(t *testing.T) {
+	apiHandler, cleanup := setupTestAPI(t)
+	defer cleanup()
+	apiHandler.Plans = planLookup{"free": {Rate: 1, Capacity: 5}}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		apiHandler.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/", fmt.Sprintf(
+		`{"client_id":"p","rate_per_sec":10,"capacity":100,"trial_until":%q,"downgrade_to":"free"}`, until.Format(time.RFC3339))).Code)
+
+	rr := do(http.MethodPatch, "/p", `{"capacity":200}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var got api.ClientLimitResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, 10.0, got.Rate, "Незаданный rate_per_sec сохраняется")
+	assert.Equal(t, 200.0, got.Capacity)
+	require.NotNil(t, got.TrialUntil, "PATCH не снимает пробный период")
+	assert.Equal(t, "free", got.DowngradeTo)
+
+	require.Equal(t, http.StatusOK, do(http.MethodPatch, "/p", `{"rate_per_sec":20}`).Code)
+	require.NoError(t, json.Unmarshal(do(http.MethodGet, "/p", "").Body.Bytes(), &got))
+	assert.Equal(t, 20.0, got.Rate)
+	assert.Equal(t, 200.0, got.Capacity)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPatch, "/missing", `{"rate_per_sec":1}`).Code)
+	for _, body := range []string{`{}`, `{"capacity":0}`, `{"client_id":"other","capacity":1}`, `{"capacity":`} {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPatch, "/p", body).Code, body)
+	}
+}
+
+// TestAPI_ResetClient проверяет POST /clients/{id}/reset: корзина клиента заполняется в работающем Rate Limiter.
+func TestAPI_ResetClient(t *testing.T) {
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 1}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	// Сброс не требует хранилища лимитов
+	apiHandler := api.NewAPIHandler(nil)
+	do := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		apiHandler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/203.0.113.7/reset", nil))
+		return rr
+	}
+
+	assert.Equal(t, http.StatusNotImplemented, do().Code, "Rate Limiter не подключен")
+
+	apiHandler.Limiter = rl
+	require.True(t, rl.Allow("203.0.113.7"))
+	require.False(t, rl.Allow("203.0.113.7"))
+	rr := do()
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var got api.ClientResetResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, api.ClientResetResponse{ClientID: "203.0.113.7", Tokens: 1}, got)
+	assert.True(t, rl.Allow("203.0.113.7"), "Клиент разблокирован сразу")
+
+	apiHandler.Limiter = ratelimiter.NewDisabled()
+	rr = do()
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), string(response.CodeRateLimiterDisabled))
+}
+
+// TestAPI_ClientState проверяет GET /clients/{id}/state: состояние корзины из работающего Rate Limiter.
+func TestAPI_ClientState(t *testing.T) {
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 3}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	apiHandler := api.NewAPIHandler(nil)
+	do := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		apiHandler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/throttled/state", nil))
+		return rr
+	}
+
+	assert.Equal(t, http.StatusNotImplemented, do().Code, "Rate Limiter не подключен")
+
+	apiHandler.Limiter = rl
+	require.True(t, rl.Allow("throttled"))
+	rr := do()
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var got ratelimiter.BucketState
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, "throttled", got.Key)
+	assert.Equal(t, ratelimiter.BucketSourceMemory, got.Source)
+	assert.InDelta(t, 2.0, got.Tokens, 0.01)
+	assert.Equal(t, 3.0, got.Capacity)
+	assert.NotNil(t, got.LastRefill)
+
+	apiHandler.Limiter = ratelimiter.NewDisabled()
+	assert.Equal(t, http.StatusConflict, do().Code)
+}
+
+// TestAPI_OpenAPI проверяет документ OpenAPI в /clients/docs: схемы строятся по структурам API.
+func TestAPI_OpenAPI(t *testing.T) {
+	apiHandler := api.NewAPIHandler(nil) // Документация доступна и без хранилища
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		apiHandler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	rr := get("/docs")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "application/json")
+	assert.Equal(t, rr.Body.String(), get("/docs/openapi.json").Body.String())
+	var doc struct {
+		OpenAPI    string                               `json:"openapi"`
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any `json:"properties"`
+				Required   []string                  `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	for path, methods := range map[string][]string{
+		"/clients":            {"get", "post"},
+		"/clients/{id}":       {"get", "put", "patch", "delete"},
+		"/clients/{id}/reset": {"post"},
+		"/clients/{id}/state": {"get"},
+		"/access/{value}":     {"get", "delete"},
+		"/api-keys":           {"get", "post"},
+	} {
+		for _, method := range methods {
+			op, ok := doc.Paths[path][method]
+			require.True(t, ok, "%s %s", method, path)
+			assert.NotEmpty(t, op["summary"], "%s %s", method, path)
+			assert.Contains(t, op["responses"], "401", "%s %s", method, path)
+		}
+	}
+
+	request := doc.Components.Schemas["ClientLimitRequest"]
+	assert.Equal(t, map[string]any{"type": "number"}, request.Properties["rate_per_sec"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, request.Properties["trial_until"])
+	assert.ElementsMatch(t, []string{"client_id", "rate_per_sec", "capacity"}, request.Required)
+	assert.Empty(t, doc.Components.Schemas["ClientLimitPatch"].Required, "Поля PATCH необязательны")
+	list := doc.Components.Schemas["ClientListResponse"]
+	assert.Equal(t, "#/components/schemas/ClientLimitResponse", list.Properties["clients"]["items"].(map[string]any)["$ref"])
+	for name, schema := range doc.Components.Schemas {
+		assert.NotEmpty(t, schema.Properties, name)
+	}
+}
+
+// TestAPI_ListClients проверяет GET /clients: страницы, фильтр по префиксу и проверку параметров.
+func TestAPI_ListClients(t *testing.T) {
+	apiHandler, cleanup := setupTestAPI(t)
+	defer cleanup()
+	mux := http.NewServeMux()
+	mux.Handle("/clients", http.StripPrefix("/clients", apiHandler))
+	mux.Handle("/clients/", http.StripPrefix("/clients", apiHandler))
+	list := func(query string) (*httptest.ResponseRecorder, api.ClientListResponse) {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/clients"+query, nil))
+		var resp api.ClientListResponse
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		}
+		return rr, resp
+	}
+
+	rr, resp := list("")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, api.ClientListResponse{Clients: []api.ClientLimitResponse{}, Limit: api.DefaultClientListLimit}, resp,
+		"Пустой список - [], а не null")
+
+	for _, clientID := range []string{"org1-a", "org1-b", "org2-a"} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/clients",
+			strings.NewReader(fmt.Sprintf(`{"client_id":%q,"rate_per_sec":1,"capacity":2}`, clientID))))
+		require.Equal(t, http.StatusCreated, rr.Code)
+	}
+
+	rr, resp = list("/?limit=2&offset=1")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 3, resp.Total)
+	require.Len(t, resp.Clients, 2)
+	assert.Equal(t, "org1-b", resp.Clients[0].ClientID)
+	assert.Equal(t, 2.0, resp.Clients[0].Capacity)
+
+	_, resp = list("?prefix=org1-")
+	assert.Equal(t, 2, resp.Total)
+	assert.Len(t, resp.Clients, 2)
+
+	for _, query := range []string{"?limit=0", "?limit=abc", "?limit=1001", "?offset=-1"} {
+		rr, _ := list(query)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
 // --- Mocks and Helpers ---
 
+// planLookup реализует api.PlanLookup для тестов.
+type planLookup map[string]config.ClientRateConfig
+
+func (p planLookup) Plan(name string) (config.ClientRateConfig, bool) {
+	plan, ok := p[name]
+	return plan, ok
+}
+
 // mockStore реализует ClientLimitStore для тестов
 type mockStore struct {
 	getClientLimitConfigFunc func(clientID string) (rate, capacity float64, found bool, err error)
@@ -268,6 +530,69 @@ func (m *mockStore) DeleteClientLimit(clientID string) error {
 }
 
 // TestAPIHandler_NewNilStore проверяет создание хендлера с nil store
+// TestRequireAuth проверяет ответы 401/403 и пропуск запросов по токену и паролю admin_auth.
+func TestRequireAuth(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	assert.Nil(t, api.NewAuthenticator(config.AdminAuthConfig{}))
+	// Без admin_auth обработчик не оборачивается
+	handler := api.RequireAuth(nil, next)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/clients/c1", nil))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	auth := api.NewAuthenticator(config.AdminAuthConfig{
+		Tokens: []config.AdminTokenConfig{
+			{Name: "deploy", Token: "admin-token", Role: config.AdminRoleAdmin},
+			{Name: "dashboard", Token: "viewer-token", Role: config.AdminRoleViewer},
+		},
+		Users: []config.AdminUserConfig{{Username: "ops", Password: "pass", Role: config.AdminRoleAdmin}},
+	})
+	require.NotNil(t, auth)
+	handler = api.RequireAuth(auth, next)
+
+	do := func(method string, setAuth func(r *http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/clients/c1", nil)
+		if setAuth != nil {
+			setAuth(req)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	bearer := func(token string) func(r *http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+	basic := func(username, password string) func(r *http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(username, password) }
+	}
+
+	for name, setAuth := range map[string]func(r *http.Request){
+		"no credentials": nil,
+		"wrong token":    bearer("admin-token-x"),
+		"wrong password": basic("ops", "wrong"),
+		"unknown user":   basic("root", "pass"),
+	} {
+		rr := do(http.MethodGet, setAuth)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, name)
+		assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Bearer", name)
+		assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Basic", name)
+		var errResp response.ErrorResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp), name)
+		assert.Equal(t, response.CodeUnauthorized, errResp.ErrorCode, name)
+	}
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, bearer("admin-token")).Code)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, basic("ops", "pass")).Code)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodGet, bearer("viewer-token")).Code)
+
+	// Роль viewer - только чтение
+	rr = do(http.MethodDelete, bearer("viewer-token"))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	var errResp response.ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+	assert.Equal(t, response.CodeForbidden, errResp.ErrorCode)
+}
+
 func TestAPIHandler_NewNilStore(t *testing.T) {
 	// Ожидаем, что не будет паники и вернется хендлер
 	// Лог предупреждения мы проверить не можем стандартными средствами,
@@ -475,3 +800,753 @@ func assertErrorResponseContains(t *testing.T, rr *httptest.ResponseRecorder, ex
 			respBody.Message, expectedSubstring)
 	}
 }
+
+// fakeHealthChecker - подмена балансировщика для тестов AdminHandler.
+type fakeHealthChecker struct {
+	results []balancer.HealthCheckResult
+	err     error
+	backend string // Последний запрошенный бэкенд
+	cfg     config.HealthCheckConfig
+}
+
+func (f *fakeHealthChecker) CheckNow(backend string) ([]balancer.HealthCheckResult, error) {
+	f.backend = backend
+	return f.results, f.err
+}
+
+func (f *fakeHealthChecker) HealthCheckConfig() config.HealthCheckConfig {
+	return f.cfg
+}
+
+func (f *fakeHealthChecker) UpdateHealthCheckConfig(cfg config.HealthCheckConfig) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.cfg = cfg
+	return nil
+}
+
+// TestAdminHandler_ForceHealthCheck проверяет POST /admin/health/check.
+func TestAdminHandler_ForceHealthCheck(t *testing.T) {
+	checker := &fakeHealthChecker{results: []balancer.HealthCheckResult{
+		{Index: 0, URL: "http://b0", Healthy: true},
+		{Index: 1, URL: "http://b1", Healthy: false, Error: "timeout"},
+	}}
+	handler := api.NewAdminHandler(checker)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/health/check", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp api.HealthCheckResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Healthy)
+	assert.Equal(t, 2, resp.Total)
+	assert.Equal(t, "timeout", resp.Results[1].Error)
+	assert.Equal(t, "", checker.backend)
+
+	// Один бэкенд
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/health/check?backend=1", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "1", checker.backend)
+
+	// Ошибки
+	checker.err = balancer.ErrBackendNotFound
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/health/check?backend=9", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"error_code":"BACKEND_NOT_FOUND"`)
+
+	checker.err = balancer.ErrHealthChecksDisabled
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/health/check", nil))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/health/check", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+// TestAdminHandler_HealthCheckConfig проверяет чтение и изменение параметров проверок через /admin/health/config.
+func TestAdminHandler_HealthCheckConfig(t *testing.T) {
+	current := config.HealthCheckConfig{Enabled: true, IntervalStr: "10s", TimeoutStr: "2s", Path: "/healthz"}
+	require.NoError(t, current.Parse())
+	checker := &fakeHealthChecker{cfg: current}
+	handler := api.NewAdminHandler(checker)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/health/config", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp api.HealthCheckConfigResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "10s", resp.Interval)
+	assert.Equal(t, "1m20s", resp.MaxBackoff)
+
+	// Частичное обновление: меняются только переданные поля, max_backoff пересчитывается от нового интервала
+	body := strings.NewReader(`{"interval": "5s", "path": "ready"}`)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/admin/health/config", body))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "5s", resp.Interval)
+	assert.Equal(t, "2s", resp.Timeout)
+	assert.Equal(t, "/ready", resp.Path)
+	assert.Equal(t, "40s", resp.MaxBackoff)
+
+	// Невалидное значение
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/admin/health/config", strings.NewReader(`{"timeout": "abc"}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"error_code":"VALIDATION_FAILED"`)
+	assert.Equal(t, 5*time.Second, checker.cfg.Interval, "Конфиг не должен меняться при ошибке")
+}
+
+// TestAdminHandler_Capture проверяет включение, чтение и выключение записи через /admin/capture.
+func TestAdminHandler_Capture(t *testing.T) {
+	handler := api.NewAdminHandler(&fakeHealthChecker{})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/capture", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Без Recorder запись недоступна")
+
+	handler.Capture = capture.New(config.CaptureConfig{BufferSize: 10, MaxBodyBytes: 100})
+
+	// Пустой фильтр запрещен
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/admin/capture", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"error_code":"VALIDATION_FAILED"`)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/admin/capture", strings.NewReader(`{"client_id": "user1"}`)))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp api.CaptureResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.True(t, resp.Active)
+	assert.Equal(t, "user1", resp.Filter.ClientID)
+	assert.True(t, handler.Capture.Matches("user1", "/"))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/admin/capture", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.False(t, resp.Active)
+	assert.False(t, handler.Capture.Matches("user1", "/"))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/capture", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+// fakeLimiter - Rate Limiter с заданной сводкой и результатом сохранения.
+type fakeLimiter struct {
+	summary ratelimiter.BucketSummary
+	saveErr error
+	saved   int
+}
+
+func (f *fakeLimiter) Summary() ratelimiter.BucketSummary { return f.summary }
+
+func (f *fakeLimiter) SaveState() error {
+	f.saved++
+	return f.saveErr
+}
+
+// TestAdminHandler_State проверяет снимок состояния и внеочередное сохранение корзин.
+func TestAdminHandler_State(t *testing.T) {
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	pool, err := balancer.New([]string{"http://backend1:80", "http://backend2:80"}, rl, config.HealthCheckConfig{}, "random")
+	require.NoError(t, err)
+	pool.SetBackendLabels(map[string]map[string]string{"http://backend2:80": {"version": "v2"}})
+	pool.Backend(0).SetAlive(false)
+
+	limiter := &fakeLimiter{summary: ratelimiter.BucketSummary{Enabled: true, Buckets: 3, Exhausted: 1}}
+	handler := api.NewAdminHandler(&fakeHealthChecker{})
+	handler.Pools = map[string]*balancer.Balancer{"primary": pool}
+	handler.Limiter = limiter
+	handler.ConfigHash = "abc123"
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/state", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp api.StateResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "abc123", resp.ConfigHash)
+	primary := resp.Pools["primary"]
+	assert.Equal(t, "random", primary.Algorithm)
+	assert.Equal(t, 1, primary.Healthy)
+	assert.Equal(t, 2, primary.Total)
+	require.Len(t, primary.Backends, 2)
+	assert.False(t, primary.Backends[0].Alive)
+	assert.Equal(t, map[string]string{"version": "v2"}, primary.Backends[1].Labels)
+	require.NotNil(t, resp.RateLimiter)
+	assert.Equal(t, 3, resp.RateLimiter.Buckets)
+	assert.Equal(t, 1, resp.RateLimiter.Exhausted)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/state/save", nil))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, 1, limiter.saved)
+
+	limiter.saveErr = errors.New("disk full")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/state/save", nil))
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/state/save", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+// TestAdminHandler_Backends проверяет GET /admin/backends: доступность, последняя проверка и ее ошибка.
+func TestAdminHandler_Backends(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	hc := config.HealthCheckConfig{Enabled: true, Interval: time.Hour, Timeout: time.Second, Path: "/"}
+	primary, err := balancer.New([]string{healthy.URL, down.URL}, ratelimiter.NewDisabled(), hc, "round_robin")
+	require.NoError(t, err)
+	defer primary.StopHealthChecks()
+	_, err = primary.CheckNow("")
+	require.NoError(t, err)
+	spillover, err := balancer.New([]string{"http://spare:80"}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	handler := api.NewAdminHandler(&fakeHealthChecker{})
+	handler.Pools = map[string]*balancer.Balancer{"primary": primary, "spillover": spillover}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/backends", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var backends []api.BackendStatus
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &backends))
+	require.Len(t, backends, 3)
+
+	assert.Equal(t, "primary", backends[0].Pool)
+	assert.True(t, backends[0].Alive)
+	require.NotNil(t, backends[0].LastCheck)
+	assert.WithinDuration(t, time.Now(), *backends[0].LastCheck, time.Minute)
+	assert.Empty(t, backends[0].LastError)
+	assert.Zero(t, backends[0].ActiveConnections)
+
+	assert.Equal(t, 1, backends[1].Index)
+	assert.False(t, backends[1].Alive)
+	assert.Contains(t, backends[1].LastError, strings.TrimPrefix(down.URL, "http://"))
+
+	assert.Equal(t, "spillover", backends[2].Pool)
+	assert.Nil(t, backends[2].LastCheck, "Бэкенд пула без проверок не проверялся")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/backends?pool=spillover", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &backends))
+	assert.Len(t, backends, 1)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/backends?pool=canary", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/admin/backends", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+// TestAdminHandler_DrainBackend проверяет POST /admin/backends/{id}/drain и /enable.
+func TestAdminHandler_DrainBackend(t *testing.T) {
+	primary, err := balancer.New([]string{"http://a:80", "http://b:80"}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	spillover, err := balancer.New([]string{"http://spare:80"}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	handler := api.NewAdminHandler(&fakeHealthChecker{})
+	handler.Pools = map[string]*balancer.Balancer{"primary": primary, "spillover": spillover}
+
+	post := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, nil))
+		return rr
+	}
+
+	rr := post("/admin/backends/1/drain")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var status api.BackendStatus
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &status))
+	assert.Equal(t, "primary", status.Pool)
+	assert.Equal(t, 1, status.Index)
+	assert.Equal(t, "http://b:80", status.URL)
+	assert.True(t, status.Drained)
+	assert.True(t, primary.Snapshot().Backends[1].Drained)
+
+	rr = post("/admin/backends/0/drain?pool=spillover")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.True(t, spillover.Snapshot().Backends[0].Drained)
+
+	rr = post("/admin/backends/1/enable")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var enabled api.BackendStatus
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &enabled))
+	assert.False(t, enabled.Drained)
+
+	for path, code := range map[string]int{
+		"/admin/backends/7/drain":              http.StatusNotFound,
+		"/admin/backends//drain":               http.StatusNotFound,
+		"/admin/backends/0/restart":            http.StatusNotFound,
+		"/admin/backends/0/drain?pool=unknown": http.StatusNotFound,
+	} {
+		assert.Equal(t, code, post(path).Code, path)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/backends/0/drain", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+// TestAdminHandler_AddRemoveBackend проверяет POST /admin/backends и DELETE /admin/backends/{id}.
+func TestAdminHandler_AddRemoveBackend(t *testing.T) {
+	primary, err := balancer.New([]string{"http://a:80"}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	handler := api.NewAdminHandler(&fakeHealthChecker{})
+	handler.Pools = map[string]*balancer.Balancer{"primary": primary}
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := serve(http.MethodPost, "/admin/backends", `{"url": "http://b:80", "weight": 3, "labels": {"zone": "b"}, "max_connections": 10}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var status api.BackendStatus
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &status))
+	assert.Equal(t, "primary", status.Pool)
+	assert.Equal(t, 1, status.Index)
+	assert.Equal(t, "http://b:80", status.URL)
+	assert.True(t, status.Alive)
+	state := primary.Snapshot().Backends[1]
+	assert.Equal(t, 3, state.Weight)
+	assert.Equal(t, int64(10), state.MaxConnections)
+	assert.Equal(t, map[string]string{"zone": "b"}, state.Labels)
+
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPost, "/admin/backends", `{"url": "http://b:80"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/admin/backends", `{"url": "b"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/admin/backends", `{"url": "http://c:80", "weight": -1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/admin/backends", `{`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/admin/backends?pool=unknown", `{"url": "http://c:80"}`).Code)
+
+	rr = serve(http.MethodDelete, "/admin/backends/0", "")
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	backends := primary.Snapshot().Backends
+	require.Len(t, backends, 1)
+	assert.Equal(t, "http://b:80", backends[0].URL)
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/admin/backends/0", "").Code)
+	assert.Equal(t, http.StatusConflict, serve(http.MethodDelete, "/admin/backends/1", "").Code, "Последний бэкенд не удаляется")
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPost, "/admin/backends/1", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodDelete, "/admin/backends/1/drain", "").Code)
+}
+
+// TestAdminHandler_Canary проверяет GET /admin/canary и POST /admin/canary/resume.
+func TestAdminHandler_Canary(t *testing.T) {
+	primary, err := balancer.New([]string{"http://stable:80", "http://canary:80"}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	primary.SetBackendLabels(map[string]map[string]string{"http://canary:80": {"track": "canary"}})
+	primary.SetCanary(config.CanaryConfig{Selector: map[string]string{"track": "canary"}, Percent: 10, Window: time.Minute, MinRequests: 100})
+	spillover, err := balancer.New([]string{"http://spare:80"}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	handler := api.NewAdminHandler(&fakeHealthChecker{})
+	handler.Pools = map[string]*balancer.Balancer{"primary": primary, "spillover": spillover}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/canary", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var statuses []api.CanaryStatus
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 1, "Пулы без разделения не выводятся")
+	assert.Equal(t, "primary", statuses[0].Pool)
+	assert.Equal(t, 10, statuses[0].Percent)
+	assert.Equal(t, "1m0s", statuses[0].Window)
+	assert.Equal(t, 1, statuses[0].Stable.Backends)
+	assert.Equal(t, 1, statuses[0].Canary.Backends)
+	assert.False(t, statuses[0].RolledBack)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/canary/resume?pool=primary", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	notFound := []struct{ method, path string }{
+		{http.MethodGet, "/admin/canary?pool=spillover"},
+		{http.MethodGet, "/admin/canary?pool=unknown"},
+		{http.MethodPost, "/admin/canary/resume?pool=spillover"},
+	}
+	for _, tt := range notFound {
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code, tt.path)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/canary", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+// TestAdminHandler_TimeSeries проверяет GET /admin/stats/timeseries.
+func TestAdminHandler_TimeSeries(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	pool, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	pool.SetStatsHistory(time.Minute)
+	pool.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	noHistory, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	handler := api.NewAdminHandler(&fakeHealthChecker{})
+	handler.Pools = map[string]*balancer.Balancer{"primary": pool, "other": noHistory}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/stats/timeseries?seconds=5", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp api.TimeSeriesResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.BucketSeconds)
+	require.Contains(t, resp.Pools, "primary")
+	assert.NotContains(t, resp.Pools, "other", "Пулы без истории не выводятся")
+	series := resp.Pools["primary"]
+	require.Len(t, series, 1)
+	require.Len(t, series[0].Requests, 5)
+	assert.Equal(t, int64(1), series[0].Requests[3]+series[0].Requests[4], "Запрос попадает в последние секунды")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/stats/timeseries?pool=missing", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/stats/timeseries?seconds=-1", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/stats/timeseries", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+// TestAdminHandler_LastReload проверяет GET /admin/config/last-reload и хэш конфигурации после перечитывания.
+func TestAdminHandler_LastReload(t *testing.T) {
+	handler := api.NewAdminHandler(&fakeHealthChecker{})
+	handler.ConfigHash = "old"
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/config/last-reload", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Без журнала перечитываний")
+
+	handler.Reloads = &config.ReloadLog{}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/config/last-reload", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Конфигурация еще не перечитывалась")
+
+	handler.Reloads.Record(config.ReloadReport{
+		Time: time.Now(), OldHash: "old", NewHash: "new",
+		Changes: []config.Change{{Field: "backend_servers", Kind: config.ChangeAdded, New: "http://backend3:80", RestartRequired: true}},
+	})
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/config/last-reload", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var report config.ReloadReport
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, "new", report.NewHash)
+	require.Len(t, report.Changes, 1)
+	assert.True(t, report.Changes[0].RestartRequired)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/state", nil))
+	var state api.StateResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &state))
+	assert.Equal(t, "new", state.ConfigHash)
+
+	// Неудачное перечитывание не меняет действующий хэш
+	handler.Reloads.Record(config.ReloadReport{Time: time.Now(), OldHash: "new", Error: "bad yaml"})
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/state", nil))
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &state))
+	assert.Equal(t, "new", state.ConfigHash)
+}
+
+// TestAdminHandler_LogLevel проверяет смену уровня логов через /admin/loglevel.
+func TestAdminHandler_LogLevel(t *testing.T) {
+	t.Cleanup(func() { i18n.SetLevel(i18n.DefaultLevel) })
+	handler := api.NewAdminHandler(&fakeHealthChecker{})
+	do := func(method, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, "/admin/loglevel", strings.NewReader(body)))
+		return rr
+	}
+
+	rr := do(http.MethodPut, `{"level":"DEBUG"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, i18n.LevelDebug, i18n.CurrentLevel())
+
+	rr = do(http.MethodGet, "")
+	var resp api.LogLevelResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "debug", resp.Level)
+
+	rr = do(http.MethodPut, `{"level":"verbose"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, i18n.LevelDebug, i18n.CurrentLevel(), "Неверный уровень не применяется")
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPost, "").Code)
+}
+
+// TestAdminHandler_Version проверяет GET /admin/version.
+func TestAdminHandler_Version(t *testing.T) {
+	handler := api.NewAdminHandler(&fakeHealthChecker{})
+	handler.Features = map[string]bool{"rate_limiter": true, "tls": false}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/version", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp api.VersionResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "dev", resp.Version, "Без -ldflags")
+	assert.Equal(t, runtime.Version(), resp.GoVersion)
+	assert.False(t, resp.StartedAt.IsZero())
+	assert.GreaterOrEqual(t, resp.UptimeSeconds, 0.0)
+	assert.Equal(t, handler.Features, resp.Features)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/version", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+// fakeLoad - загрузка ограничения одновременных запросов для GET /readyz.
+type fakeLoad float64
+
+func (l fakeLoad) Load() float64 { return float64(l) }
+
+// fakePinger - хранилище для GET /readyz, отвечающее заданной ошибкой.
+type fakePinger struct{ err error }
+
+func (p *fakePinger) Ping(context.Context) error { return p.err }
+
+// TestReadinessHandler проверяет причины, по которым /readyz отвечает 503.
+func TestReadinessHandler(t *testing.T) {
+	pool, err := balancer.New([]string{"http://backend1:80", "http://backend2:80"}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	store := &fakePinger{}
+	handler := &api.ReadinessHandler{
+		Pools:         []*balancer.Balancer{pool},
+		Admission:     fakeLoad(0.5),
+		InFlightRatio: 0.9,
+		Store:         store,
+	}
+
+	check := func(wantCode int) api.ReadinessResponse {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		require.Equal(t, wantCode, rr.Code, rr.Body.String())
+		var resp api.ReadinessResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := check(http.StatusOK)
+	assert.True(t, resp.Ready)
+	require.NotNil(t, resp.InFlightLoad)
+	assert.Equal(t, 0.5, *resp.InFlightLoad)
+
+	handler.Admission = fakeLoad(0.95)
+	store.err = errors.New("database is locked")
+	resp = check(http.StatusServiceUnavailable)
+	assert.Equal(t, []string{api.ReadinessInFlightNearCap, api.ReadinessStoreUnavailable}, resp.Reasons)
+
+	handler.Admission = fakeLoad(0.1)
+	store.err = nil
+	pool.SetBackendMaxConnections(map[string]int{"http://backend2:80": 1})
+	pool.Backend(0).SetAlive(false)
+	assert.True(t, check(http.StatusOK).Ready, "Один доступный бэкенд - экземпляр готов")
+	pool.Backend(1).SetAlive(false)
+	assert.Equal(t, []string{api.ReadinessNoHealthyBackends}, check(http.StatusServiceUnavailable).Reasons)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/readyz", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+// TestAccessHandler_CRUD проверяет добавление, получение и удаление записей списков доступа через /access.
+func TestAccessHandler_CRUD(t *testing.T) {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "access.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	list, err := access.New(config.AccessListConfig{Allow: []string{"partner"}}, db)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	mux.Handle("/access", http.StripPrefix("/access", api.NewAccessHandler(list)))
+	mux.Handle("/access/", http.StripPrefix("/access", api.NewAccessHandler(list)))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := do(http.MethodPost, "/access", `{"value":"198.51.100.0/24","list":"deny","reason":"scan","ttl":"1h"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created api.AccessEntryResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, api.AccessSourceAPI, created.Source)
+	require.NotNil(t, created.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *created.ExpiresAt, time.Minute)
+	assert.Equal(t, access.Denied, list.Decide("198.51.100.9"), "Запись действует сразу")
+
+	rr = do(http.MethodGet, "/access/198.51.100.0/24", "")
+	require.Equal(t, http.StatusOK, rr.Code, "Значение с подсетью передается в пути")
+
+	rr = do(http.MethodGet, "/access", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var entries []api.AccessEntryResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "198.51.100.0/24", entries[0].Value)
+	assert.Equal(t, api.AccessSourceConfig, entries[1].Source)
+	assert.Nil(t, entries[1].CreatedAt)
+
+	invalid := []string{
+		`{"value":"","list":"deny"}`,
+		`{"value":"10.0.0.0/33","list":"deny"}`,
+		`{"value":"bot","list":"block"}`,
+		`{"value":"bot","list":"deny","ttl":"-1h"}`,
+		`{"value":`,
+	}
+	for _, body := range invalid {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/access", body).Code, body)
+	}
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/access/198.51.100.0/24", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/access/198.51.100.0/24", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/access/partner", "").Code, "Записи из конфигурации не удаляются")
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/access/unknown", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPut, "/access", "").Code)
+
+	// Без хранилища записи можно только читать
+	readOnly, err := access.New(config.AccessListConfig{}, nil)
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	api.NewAccessHandler(readOnly).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"value":"bot","list":"deny"}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+// forgottenKeys реализует api.APIKeyCache для тестов.
+type forgottenKeys []string
+
+func (f *forgottenKeys) ForgetAPIKey(hash string) { *f = append(*f, hash) }
+
+// TestAPIKeyHandler проверяет выдачу API-ключа с планом, список ключей без самих ключей и отзыв.
+func TestAPIKeyHandler(t *testing.T) {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "keys.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	var forgotten forgottenKeys
+	handler := api.NewAPIKeyHandler(db)
+	handler.Plans = planLookup{"pro": {Rate: 50, Capacity: 500}}
+	handler.Cache = &forgotten
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := do(http.MethodPost, "/", `{"client_id":"acme","plan":"pro"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created api.APIKeyResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Key)
+	assert.Equal(t, storage.HashAPIKey(created.Key), created.ID)
+	key, found, err := db.LookupAPIKey(created.ID)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "acme", key.ClientID)
+	rate, capacity, found, err := db.GetClientLimitConfig("acme")
+	require.NoError(t, err)
+	require.True(t, found, "Лимиты плана назначаются клиенту")
+	assert.Equal(t, 50.0, rate)
+	assert.Equal(t, 500.0, capacity)
+
+	// Второй ключ того же клиента обновляет лимиты, а не конфликтует с ними
+	require.NoError(t, db.UpdateClientLimit("acme", config.ClientRateConfig{Rate: 1, Capacity: 1}))
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/", `{"client_id":"acme","plan":"pro"}`).Code)
+	rate, _, _, err = db.GetClientLimitConfig("acme")
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, rate)
+
+	rr = do(http.MethodGet, "/", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var keys []api.APIKeyResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &keys))
+	require.Len(t, keys, 2)
+	for _, listed := range keys {
+		assert.Empty(t, listed.Key, "Ключ возвращается только при выдаче")
+	}
+
+	for _, body := range []string{`{"plan":"pro"}`, `{"client_id":"acme","plan":"gold"}`, `{"client_id":`} {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/", body).Code, body)
+	}
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/"+created.ID, "").Code)
+	assert.Equal(t, forgottenKeys{created.ID}, forgotten)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodGet, "/"+created.ID, "").Code)
+
+	rr = httptest.NewRecorder()
+	api.NewAPIKeyHandler(nil).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+// fakeMaintainer - обслуживание базы для тестов POST /admin/storage/maintenance.
+type fakeMaintainer struct {
+	report storage.MaintenanceReport
+	err    error
+	runs   int
+}
+
+func (f *fakeMaintainer) Run() (storage.MaintenanceReport, error) {
+	f.runs++
+	return f.report, f.err
+}
+
+// TestAdminHandler_StorageMaintenance проверяет POST /admin/storage/maintenance.
+func TestAdminHandler_StorageMaintenance(t *testing.T) {
+	handler := api.NewAdminHandler(&fakeHealthChecker{})
+	post := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/storage/maintenance", nil))
+		return rr
+	}
+
+	assert.Equal(t, http.StatusNotFound, post().Code, "Без хранилища обслуживание недоступно")
+
+	maintainer := &fakeMaintainer{report: storage.MaintenanceReport{ExpiredAccessEntries: 3, FreedPages: 120, SizeBefore: 819200, SizeAfter: 327680}}
+	handler.Maintenance = maintainer
+	rr := post()
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var report storage.MaintenanceReport
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, maintainer.report, report)
+	assert.Equal(t, 1, maintainer.runs)
+
+	maintainer.err = errors.New("database is locked")
+	assert.Equal(t, http.StatusInternalServerError, post().Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/storage/maintenance", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
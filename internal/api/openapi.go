@@ -0,0 +1,192 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"load-balancer/internal/buildinfo"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/ratelimiter"
+	"load-balancer/internal/response"
+)
+
+// OpenAPISpecPath - путь документа OpenAPI 3; тот же документ отдается по /clients/docs (см. serveDocs).
+const OpenAPISpecPath = "/clients/docs/openapi.json"
+
+// apiParam - параметр операции в пути или строке запроса.
+type apiParam struct {
+	Name string
+	In   string // "path" или "query".
+	Type string // Тип JSON Schema: "string" или "integer".
+}
+
+// apiOperation - операция API управления в документе OpenAPI. Схемы тел запроса и ответа строятся
+// по структурам Go (см. openAPISchema), поэтому документ не расходится с обработчиками.
+type apiOperation struct {
+	Method  string
+	Path    string
+	Summary i18n.ID
+	Params  []apiParam
+	Request any // Образец тела запроса, nil - без тела.
+	Status  int // Код успешного ответа.
+	// Response - образец тела успешного ответа, nil - без тела.
+	Response any
+	Errors   []int // Коды ответов с ошибкой (тело - response.ErrorResponse).
+}
+
+var (
+	clientIDParam = apiParam{Name: "id", In: "path", Type: "string"}
+	listParams    = []apiParam{{"limit", "query", "integer"}, {"offset", "query", "integer"}, {"prefix", "query", "string"}}
+)
+
+// apiOperations - операции API управления. Новая операция добавляется сюда вместе с обработчиком.
+var apiOperations = []apiOperation{
+	{http.MethodGet, "/clients", i18n.OpenAPIListClients, listParams, nil, http.StatusOK, ClientListResponse{}, []int{400, 500, 501, 503}},
+	{http.MethodPost, "/clients", i18n.OpenAPICreateClient, nil, ClientLimitRequest{}, http.StatusCreated, ClientLimitResponse{}, []int{400, 409, 500, 503}},
+	{http.MethodGet, "/clients/{id}", i18n.OpenAPIGetClient, []apiParam{clientIDParam}, nil, http.StatusOK, ClientLimitResponse{}, []int{404, 500, 503}},
+	{http.MethodPut, "/clients/{id}", i18n.OpenAPIUpdateClient, []apiParam{clientIDParam}, ClientLimitRequest{}, http.StatusOK, ClientLimitResponse{}, []int{400, 404, 500, 503}},
+	{http.MethodPatch, "/clients/{id}", i18n.OpenAPIPatchClient, []apiParam{clientIDParam}, ClientLimitPatch{}, http.StatusOK, ClientLimitResponse{}, []int{400, 404, 500, 503}},
+	{http.MethodDelete, "/clients/{id}", i18n.OpenAPIDeleteClient, []apiParam{clientIDParam}, nil, http.StatusNoContent, nil, []int{404, 500, 503}},
+	{http.MethodPost, "/clients/{id}/reset", i18n.OpenAPIResetClient, []apiParam{clientIDParam}, nil, http.StatusOK, ClientResetResponse{}, []int{409, 500, 501, 503}},
+	{http.MethodGet, "/clients/{id}/state", i18n.OpenAPIClientState, []apiParam{clientIDParam}, nil, http.StatusOK, ratelimiter.BucketState{}, []int{409, 501, 503}},
+	{http.MethodGet, "/access", i18n.OpenAPIListAccess, nil, nil, http.StatusOK, []AccessEntryResponse{}, nil},
+	{http.MethodPost, "/access", i18n.OpenAPIPutAccess, nil, AccessEntryRequest{}, http.StatusCreated, AccessEntryResponse{}, []int{400, 500, 503}},
+	{http.MethodGet, "/access/{value}", i18n.OpenAPIGetAccess, []apiParam{{"value", "path", "string"}}, nil, http.StatusOK, AccessEntryResponse{}, []int{404}},
+	{http.MethodDelete, "/access/{value}", i18n.OpenAPIDeleteAccess, []apiParam{{"value", "path", "string"}}, nil, http.StatusNoContent, nil, []int{404, 500, 503}},
+	{http.MethodGet, "/api-keys", i18n.OpenAPIListAPIKeys, nil, nil, http.StatusOK, []APIKeyResponse{}, []int{500, 503}},
+	{http.MethodPost, "/api-keys", i18n.OpenAPICreateAPIKey, nil, APIKeyRequest{}, http.StatusCreated, APIKeyResponse{}, []int{400, 500, 501, 503}},
+	{http.MethodDelete, "/api-keys/{id}", i18n.OpenAPIDeleteAPIKey, []apiParam{{"id", "path", "string"}}, nil, http.StatusNoContent, nil, []int{404, 500, 503}},
+}
+
+// OpenAPIDocument возвращает документ OpenAPI 3 с операциями API управления. Запросы принимают
+// учетные данные admin_auth (Bearer или Basic); без admin_auth API открыто, поэтому они необязательны.
+func OpenAPIDocument() map[string]any {
+	schemas := map[string]any{}
+	errorRef := openAPISchema(schemas, reflect.TypeOf(response.ErrorResponse{}))
+	paths := map[string]any{}
+	for _, op := range apiOperations {
+		item, ok := paths[op.Path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[op.Path] = item
+		}
+
+		responses := map[string]any{}
+		success := map[string]any{"description": http.StatusText(op.Status)}
+		if op.Response != nil {
+			success["content"] = jsonContent(openAPISchema(schemas, reflect.TypeOf(op.Response)))
+		}
+		responses[strconv.Itoa(op.Status)] = success
+		for _, status := range append([]int{http.StatusUnauthorized, http.StatusForbidden}, op.Errors...) {
+			responses[strconv.Itoa(status)] = map[string]any{"description": http.StatusText(status), "content": jsonContent(errorRef)}
+		}
+
+		operation := map[string]any{"summary": i18n.T(op.Summary), "responses": responses}
+		if len(op.Params) > 0 {
+			params := make([]any, 0, len(op.Params))
+			for _, p := range op.Params {
+				params = append(params, map[string]any{
+					"name": p.Name, "in": p.In, "required": p.In == "path", "schema": map[string]any{"type": p.Type},
+				})
+			}
+			operation["parameters"] = params
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]any{"required": true, "content": jsonContent(openAPISchema(schemas, reflect.TypeOf(op.Request)))}
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": i18n.T(i18n.OpenAPITitle), "version": buildinfo.Version},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+				"basicAuth":  map[string]any{"type": "http", "scheme": "basic"},
+			},
+		},
+		"security": []any{map[string]any{"bearerAuth": []any{}}, map[string]any{"basicAuth": []any{}}, map[string]any{}},
+	}
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// openAPISchema возвращает схему JSON для типа t. Структуры добавляются в schemas под своим именем
+// и возвращаются ссылкой; поля описываются по тегам json, поля без omitempty и не указатели обязательны
+// (поэтому необязательные поля тел запросов помечены omitempty, хотя структуры только декодируются).
+func openAPISchema(schemas map[string]any, t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := schemas[t.Name()]; ok {
+			return ref
+		}
+		schemas[t.Name()] = nil // Защита от рекурсии до заполнения схемы
+		properties := map[string]any{}
+		var required []string
+		openAPIFields(schemas, t, properties, &required)
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		schemas[t.Name()] = schema
+		return ref
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]any{"type": "array", "items": openAPISchema(schemas, t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": openAPISchema(schemas, t.Elem())}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	}
+	return map[string]any{}
+}
+
+// openAPIFields добавляет поля структуры t в properties; поля встроенных структур поднимаются, как в encoding/json.
+func openAPIFields(schemas map[string]any, t reflect.Type, properties map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			openAPIFields(schemas, field.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = openAPISchema(schemas, field.Type)
+		if !strings.Contains(","+opts+",", ",omitempty,") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// serveDocs обрабатывает GET /clients/docs и GET /clients/docs/openapi.json: документ OpenAPI 3 API управления.
+// Страница Swagger UI не встроена в балансировщик: документ открывается в любом просмотрщике OpenAPI.
+func serveDocs(w http.ResponseWriter) {
+	response.RespondWithJSON(w, http.StatusOK, OpenAPIDocument())
+}
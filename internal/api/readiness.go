@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"load-balancer/internal/balancer"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+	"load-balancer/internal/response"
+)
+
+// Причины неготовности экземпляра в ответе GET /readyz.
+const (
+	ReadinessNoHealthyBackends = "no_healthy_backends" // Во всех пулах нет доступных бэкендов.
+	ReadinessBackendsSaturated = "backends_saturated"  // Доступные бэкенды достигли backend_max_connections.
+	ReadinessInFlightNearCap   = "in_flight_near_cap"  // Занято не меньше readiness.in_flight_ratio слотов concurrency.
+	ReadinessStoreUnavailable  = "store_unavailable"   // Хранилище лимитов не ответило за readiness.store_timeout.
+)
+
+var notReadyTotal = metrics.NewCounter("api_readiness_not_ready_total",
+	"Количество ответов 503 на GET /readyz.")
+
+// AdmissionLoad сообщает загрузку ограничения одновременных запросов (см. admission.Guard.Load).
+type AdmissionLoad interface {
+	Load() float64
+}
+
+// StorePinger проверяет доступность хранилища лимитов.
+type StorePinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ReadinessResponse - ответ GET /readyz.
+type ReadinessResponse struct {
+	Ready   bool     `json:"ready"`
+	Reasons []string `json:"reasons,omitempty"` // Readiness* - почему экземпляр не готов.
+	// InFlightLoad - доля занятых слотов concurrency.max_in_flight; отсутствует без concurrency.enabled.
+	InFlightLoad *float64 `json:"in_flight_load,omitempty"`
+}
+
+// ReadinessHandler обрабатывает GET /readyz: 200, если экземпляр может обработать запрос, иначе 503,
+// чтобы внешний балансировщик или Kubernetes временно уводили с него трафик.
+type ReadinessHandler struct {
+	// Pools - пулы основного листенера (основной и резервный): экземпляр готов, пока хотя бы в одном
+	// есть доступный бэкенд.
+	Pools []*balancer.Balancer
+	// Admission - ограничение одновременных запросов; nil, если concurrency выключен.
+	Admission     AdmissionLoad
+	InFlightRatio float64
+	// Store - хранилище лимитов; nil, если хранилище не используется.
+	Store        StorePinger
+	StoreTimeout time.Duration
+
+	notReady atomic.Bool // Последний ответ был 503: смена состояния пишется в лог.
+}
+
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		response.RespondWithError(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, i18n.T(i18n.APIAdminMethodNotAllowed, r.Method, r.URL.Path))
+		return
+	}
+
+	resp := h.check(r.Context())
+	if !resp.Ready {
+		notReadyTotal.Inc()
+		if !h.notReady.Swap(true) {
+			i18n.Logf(i18n.APINotReady, resp.Reasons)
+		}
+		response.RespondWithJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	if h.notReady.Swap(false) {
+		i18n.Logf(i18n.APIReadyAgain)
+	}
+	response.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// check проверяет условия готовности.
+func (h *ReadinessHandler) check(ctx context.Context) ReadinessResponse {
+	resp := ReadinessResponse{Ready: true}
+	notReady := func(reason string) {
+		resp.Ready = false
+		resp.Reasons = append(resp.Reasons, reason)
+	}
+
+	if len(h.Pools) > 0 {
+		healthy, available := 0, 0
+		for _, pool := range h.Pools {
+			state := pool.Snapshot()
+			healthy += state.Healthy
+			available += state.Available
+		}
+		switch {
+		case healthy == 0:
+			notReady(ReadinessNoHealthyBackends)
+		case available == 0:
+			notReady(ReadinessBackendsSaturated)
+		}
+	}
+
+	if h.Admission != nil {
+		load := h.Admission.Load()
+		resp.InFlightLoad = &load
+		if load >= h.InFlightRatio {
+			notReady(ReadinessInFlightNearCap)
+		}
+	}
+
+	if h.Store != nil {
+		timeout := h.StoreTimeout
+		if timeout <= 0 {
+			timeout = time.Second
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if err := h.Store.Ping(pingCtx); err != nil {
+			notReady(ReadinessStoreUnavailable)
+		}
+	}
+	return resp
+}
@@ -0,0 +1,59 @@
+package balancer
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+	"load-balancer/internal/response"
+)
+
+var requestsExpiredTotal = metrics.NewCounter("balancer_requests_expired_total",
+	"Количество запросов, отброшенных с 503: провели в балансировщике дольше request_age.max_age.")
+
+// receivedKey - ключ контекста с моментом поступления запроса в балансировщик.
+type receivedKey struct{}
+
+// SetMaxRequestAge задает, сколько запрос может провести в балансировщике до отправки бэкенду
+// (очередь concurrency, повторы после 429/503); 0 - без ограничения.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetMaxRequestAge(maxAge time.Duration) {
+	b.maxRequestAge = maxAge
+}
+
+// withReceived запоминает в контексте момент поступления запроса. Момент, уже запомненный
+// другим пулом (перелив в резервный пул), не меняется.
+func withReceived(r *http.Request, now time.Time) *http.Request {
+	if _, ok := r.Context().Value(receivedKey{}).(time.Time); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), receivedKey{}, now))
+}
+
+// ageContext возвращает контекст ожидания в очереди, который отменяется, когда запрос достигает max_age.
+func (b *Balancer) ageContext(r *http.Request) (context.Context, context.CancelFunc) {
+	received, ok := r.Context().Value(receivedKey{}).(time.Time)
+	if b.maxRequestAge <= 0 || !ok {
+		return r.Context(), func() {}
+	}
+	return context.WithDeadline(r.Context(), received.Add(b.maxRequestAge))
+}
+
+// shedExpired отвечает 503, если запрос провел в балансировщике не меньше max_age.
+// Возвращает true, если запрос отброшен.
+func (b *Balancer) shedExpired(w http.ResponseWriter, r *http.Request, clientID string) bool {
+	received, ok := r.Context().Value(receivedKey{}).(time.Time)
+	if b.maxRequestAge <= 0 || !ok {
+		return false
+	}
+	age := time.Since(received)
+	if age < b.maxRequestAge {
+		return false
+	}
+	requestsExpiredTotal.Inc()
+	i18n.Logf(i18n.BalancerRequestExpired, clientID, age.Round(time.Millisecond), b.maxRequestAge)
+	b.respondWithError(w, r, http.StatusServiceUnavailable, response.CodeRequestExpired, i18n.T(i18n.BalancerRequestTooOld))
+	return true
+}
@@ -0,0 +1,55 @@
+package balancer
+
+import (
+	"crypto/tls"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/sni"
+)
+
+// SetBackendTLS настраивает TLS соединений пула с https:// бэкендами: свои УЦ вместо системных, клиентский
+// сертификат балансировщика или отключение проверки сертификатов. Применяется к проксированию (включая gRPC)
+// и активным проверкам; транспорты уже созданных бэкендов пересоздаются. Проверки запускаются уже в New,
+// поэтому до вызова первая из них может использовать системные настройки.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetBackendTLS(cfg config.BackendTLSConfig) error {
+	if cfg == (config.BackendTLSConfig{}) {
+		return nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CAFile != "" {
+		roots, err := sni.LoadCertPool(cfg.CAFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.RootCAs = roots
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	b.backendTLS.Store(tlsConfig)
+	if cfg.InsecureSkipVerify {
+		i18n.Logf(i18n.BalancerBackendTLSInsecure)
+	}
+
+	for _, backend := range b.backendList() {
+		backend.transport.rebuild()
+		if backend.grpcTransport != nil {
+			backend.grpcTransport.rebuild()
+		}
+	}
+	return nil
+}
+
+// tlsClientConfig возвращает копию настроек TLS пула для нового транспорта; nil - системные настройки.
+func (b *Balancer) tlsClientConfig() *tls.Config {
+	if tlsConfig := b.backendTLS.Load(); tlsConfig != nil {
+		return tlsConfig.Clone()
+	}
+	return nil
+}
@@ -2,10 +2,10 @@ package balancer
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
-	"fmt"
-	"log"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -14,336 +14,871 @@ import (
 	"sync/atomic"
 	"time"
 
+	"load-balancer/internal/access"
+	"load-balancer/internal/accesslog"
+	"load-balancer/internal/admission"
+	"load-balancer/internal/capture"
 	"load-balancer/internal/config"
+	"load-balancer/internal/headers"
+	"load-balancer/internal/hooks"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+	"load-balancer/internal/ratelimiter"
+	"load-balancer/internal/requestid"
 	"load-balancer/internal/response"
+	"load-balancer/internal/tracing"
 )
 
 type Limiter interface {
-	Allow(clientID string) bool
+	// Check проверяет лимит клиента. Ошибка означает, что решение принять не удалось
+	// (например, хранилище недоступно при политике fail_closed).
+	Check(clientID string) (bool, error)
 	GetClientID(r *http.Request) string
 }
 
+// SoftLimiter реализуется Limiter с мягким порогом: запрос еще проходит, но клиента нужно предупредить.
+type SoftLimiter interface {
+	// CheckSoft работает как Check и дополнительно возвращает значение заголовка X-RateLimit-Warning
+	// (пустая строка, если мягкий порог не превышен).
+	CheckSoft(clientID string) (bool, string, error)
+}
+
+// KeyLimiter реализуется Limiter, ключ корзины которого строится из атрибутов запроса (rate_limiter.key_template).
+type KeyLimiter interface {
+	// LimitKey возвращает ключ корзины для запроса; pathPrefix - префикс совпавшего маршрута ("" - маршрут по умолчанию).
+	LimitKey(r *http.Request, clientID, pathPrefix string) string
+}
+
+// CostLimiter реализуется Limiter, который может списать за запрос несколько токенов (стоимость из admission_hook).
+type CostLimiter interface {
+	// CheckCost работает как CheckSoft для запроса стоимостью cost токенов.
+	CheckCost(clientID string, cost float64) (bool, string, error)
+}
+
+// BucketReader реализуется Limiter, чье состояние корзины клиента доступно сценарию admission_hook.
+type BucketReader interface {
+	// Bucket возвращает состояние корзины по ключу без расхода токенов; found = false, если корзины нет.
+	Bucket(key string) (tokens, capacity, rate float64, found bool)
+}
+
+// InFlightLimiter реализуется Limiter, ограничивающим число одновременных запросов клиента.
+type InFlightLimiter interface {
+	// AcquireInFlight занимает слот запроса клиента; ok = false - лимит исчерпан. release вызывается после ответа.
+	AcquireInFlight(clientID string) (release func(), ok bool)
+}
+
+// RequestObserver получает уведомления о результатах обработки запросов (например, для алертинга).
+type RequestObserver interface {
+	// ObserveRequest вызывается для каждого запроса; rateLimited - запрос отклонен Rate Limiter (429).
+	ObserveRequest(rateLimited bool)
+}
+
+// RateLimitWarningHeader - заголовок ответа для клиентов, превысивших мягкий порог Rate Limiter.
+const RateLimitWarningHeader = "X-RateLimit-Warning"
+
 // ErrNoHealthyBackends возвращается, когда нет доступных для запроса бэкендов.
-var ErrNoHealthyBackends = errors.New("нет доступных бэкендов")
+var ErrNoHealthyBackends = i18n.NewError(i18n.BalancerNoHealthyBackends)
 
 // Backend представляет один бэкенд-сервер.
 type Backend struct {
 	URL   *url.URL
 	Alive bool         // Флаг, указывающий, доступен ли бэкенд.
-	mux   sync.RWMutex // Мьютекс для безопасного доступа к полю Alive.
+	mux   sync.RWMutex // Мьютекс для безопасного доступа к полям Alive и checked.
+	// checked - статус бэкенда хотя бы раз определен активной проверкой (см. RestoreHealth).
+	checked bool
 	// ReverseProxy используется для перенаправления запросов на этот бэкенд.
 	ReverseProxy *httputil.ReverseProxy
+	grpcProxy    *httputil.ReverseProxy // Прокси по HTTP/2 для вызовов gRPC (см. EnableGRPC).
+	// Транспорты прокси пересоздаются, если после неудачной проверки хост бэкенда разрешился в другие адреса.
+	transport     *backendTransport
+	grpcTransport *backendTransport
+	resolved      resolvedAddrs
+	// Labels - метки бэкенда для выбора подмножества пула маршрутом (см. SetBackendLabels).
+	Labels map[string]string
+
+	health healthState // Состояние активных проверок (backoff, выполняющаяся проверка).
+	// heldUntil - до этого момента (UnixNano) бэкенд исключен после ошибки (см. SetHoldDown), 0 - не исключен.
+	heldUntil atomic.Int64
+	failures  failureBudget // Недавние ошибки при обработке запросов (см. SetFailureBudget).
+	// passive - параметры пассивной проверки бэкенда (см. SetBackendPassiveHealth); nil - параметры пула.
+	passive *passivePolicy
+	// admittedAt - момент (UnixNano) возврата бэкенда в балансировку, от него отсчитывается passive_health.slow_start.
+	admittedAt atomic.Int64
+	// inFlight - запросы, которые проксируются на бэкенд в данный момент; maxConnections - их максимум
+	// (см. SetBackendMaxConnections), 0 - без ограничения.
+	inFlight       atomic.Int64
+	maxConnections int64
+	weight         int  // Вес во взвешенном выборе (см. SetBackendWeights), по умолчанию 1.
+	canary         bool // Бэкенд подходит под селектор канареечного разделения пула (см. SetCanary).
+	// drained - бэкенд выведен из балансировки вручную (см. SetDrained), независимо от Alive.
+	drained atomic.Bool
+	// removed - бэкенд удален из пула (см. RemoveBackend): он остается в списке, пока не завершатся
+	// запросы в обработке.
+	removed  atomic.Bool
+	id       int    // ID бэкенда в пуле (см. ID).
+	stickyID string // ID бэкенда в cookie привязки (см. SetStickySessions).
+	// throttledUntil - до этого момента (UnixNano) вес бэкенда снижен после ответа 429/503 (см. SetUpstreamThrottling).
+	throttledUntil atomic.Int64
+	// history - запросы и ошибки по секундам (см. SetStatsHistory); nil - история выключена.
+	history *requestHistory
+	// requests и failed - счетчики запросов к бэкенду и завершившихся ошибкой (см. BackendState).
+	requests atomic.Uint64
+	failed   atomic.Uint64
+	// latency - время ответа бэкенда в секундах (см. Metrics).
+	latency *metrics.Histogram
 }
 
 // SetAlive безопасно устанавливает статус работоспособности бэкенда.
 func (b *Backend) SetAlive(alive bool) {
 	b.mux.Lock()
 	defer b.mux.Unlock()
+	b.setAliveLocked(alive)
+}
+
+// setCheckedAlive устанавливает статус по результату активной проверки.
+func (b *Backend) setCheckedAlive(alive bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.checked = true
+	b.setAliveLocked(alive)
+}
 
+// setAliveLocked меняет статус и пишет смену в лог. Вызывается под b.mux.
+func (b *Backend) setAliveLocked(alive bool) {
 	if b.Alive != alive {
 		b.Alive = alive
-		status := "недоступен"
 		if alive {
-			status = "доступен"
+			b.admittedAt.Store(time.Now().UnixNano())
 		}
-		log.Printf("[HealthCheck] Бэкенд %s теперь %s", b.URL.String(), status)
+		status := i18n.T(i18n.BalancerBackendDown)
+		if alive {
+			status = i18n.T(i18n.BalancerBackendUp)
+		}
+		i18n.Logf(i18n.BalancerBackendStatus, b.URL.String(), status)
 	}
 }
 
 // IsAlive безопасно проверяет статус работоспособности бэкенда.
+// Бэкенд, исключенный после ошибки на время passive_health.hold_down, считается недоступным.
 func (b *Backend) IsAlive() bool {
+	if _, held := b.HeldUntil(); held {
+		return false
+	}
 	b.mux.RLock()         // Блокируем на чтение.
 	defer b.mux.RUnlock() // Гарантируем разблокировку.
 	return b.Alive
 }
 
+// Matches проверяет, что у бэкенда есть все метки селектора с теми же значениями.
+// Пустой селектор совпадает с любым бэкендом.
+func (b *Backend) Matches(selector map[string]string) bool {
+	for key, value := range selector {
+		if b.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
 // Balancer является HTTP обработчиком, реализующим балансировку нагрузки.
 type Balancer struct {
-	backends            []*Backend
-	current             atomic.Uint64 // Используется только для Round Robin
-	algorithm           string        // Алгоритм балансировки ("round_robin" или "random")
-	rng                 *rand.Rand    // Генератор случайных чисел (для Random)
-	rateLimiter         Limiter       // Используем интерфейс вместо конкретного типа
-	healthCheckConfig   config.HealthCheckConfig
+	backends            atomic.Pointer[[]*Backend]               // Бэкенды пула (см. backendList)
+	membership          sync.Mutex                               // Упорядочивает AddBackend и RemoveBackend
+	nextID              int                                      // ID следующего добавленного бэкенда (под membership)
+	current             atomic.Uint64                            // Используется только для Round Robin
+	algorithm           string                                   // Алгоритм балансировки ("round_robin", "random" или "consistent_hash")
+	rng                 *rand.Rand                               // Генератор случайных чисел (для Random)
+	rateLimiter         Limiter                                  // Используем интерфейс вместо конкретного типа
+	healthCheckConfig   atomic.Pointer[config.HealthCheckConfig] // Текущие параметры проверок (см. UpdateHealthCheckConfig)
+	healthCheckReload   chan struct{}                            // Сигнал циклу проверок о смене параметров
 	healthCheckStopChan chan struct{}
+	background          sync.WaitGroup             // Фоновые горутины балансировщика (см. Wait)
+	routes              atomic.Pointer[routeTable] // Правила маршрутов (см. SetRoutes)
+	observers           []RequestObserver
+	grpc                bool                         // Режим gRPC (см. EnableGRPC)
+	admission           *admission.Guard             // Ограничение одновременных запросов (см. SetAdmission)
+	spillover           *spillover                   // Резервный пул на случай исчерпания бюджета (см. SetSpillover)
+	capture             *capture.Recorder            // Запись запросов для отладки (см. SetCapture)
+	accessLog           *accesslog.Shipper           // Отправка журнала доступа (см. SetAccessLog)
+	resolver            atomic.Pointer[Resolver]     // Повторное разрешение имен бэкендов (см. SetResolver)
+	dns                 atomic.Pointer[poolResolver] // Разрешение имен бэкендов пула (см. SetDNS); nil - системное.
+	backendTLS          atomic.Pointer[tls.Config]   // TLS с https:// бэкендами (см. SetBackendTLS); nil - системные настройки.
+	maxRequestAge       time.Duration                // Предельный возраст запроса (см. SetMaxRequestAge); 0 - без ограничения
+	trace               config.TraceConfig           // Трассировка решений в заголовке ответа (см. SetTrace)
+	tracer              *tracing.Tracer              // Распределенная трассировка OpenTelemetry (см. SetTracer)
+	backendTimeout      time.Duration                // Ожидание начала ответа бэкенда (см. SetBackendTimeout); 0 - без ограничения
+	clientCertHeader    string                       // Заголовок с CN клиентского сертификата (см. SetClientCertHeader)
+	hook                *hooks.Hook                  // Сценарий допуска (см. SetHook); nil - сценария нет
+	hookPools           map[string]*Balancer         // Пулы, которые может выбрать сценарий допуска
+	accessList          *access.List                 // Списки запрета и разрешения (см. SetAccessList)
+	passive             passivePolicy                // Пассивная проверка бэкендов пула (см. SetPassiveHealth)
+	saturationThreshold float64                      // Порог почти заполненного бэкенда (см. SetSaturationThreshold)
+	weighted            atomic.Bool                  // У бэкендов разные веса (см. SetBackendWeights)
+	weights             weightedQueue                // Очередь взвешенного Round Robin по всему пулу
+	ring                atomic.Pointer[backendRing]  // Кольцо для consistent_hash (см. buildRing)
+	sticky              *stickySessions              // Привязка клиентов к бэкендам по cookie (см. SetStickySessions)
+	historySize         int                          // Длина истории запросов в секундах (см. SetStatsHistory)
+	throttling          *upstreamThrottling          // Реакция на ответы 429 и 503 (см. SetUpstreamThrottling)
+	upstreamAuth        atomic.Pointer[upstreamAuth] // Учетные данные для бэкендов (см. SetUpstreamAuth)
+	canary              *canarySplit                 // Канареечное разделение запросов (см. SetCanary)
+}
+
+// backendList возвращает текущий список бэкендов пула. Опубликованный список не меняется: AddBackend
+// и RemoveBackend заменяют его копией. Позиция бэкенда в списке может измениться после удаления
+// другого бэкенда, поэтому вне выбора бэкенд определяется по ID (см. Backend.ID).
+func (b *Balancer) backendList() []*Backend {
+	return *b.backends.Load()
 }
 
 // New создает новый экземпляр Balancer.
 func New(backendUrls []string, rl Limiter, hcConfig config.HealthCheckConfig, algorithm string) (*Balancer, error) {
 	if len(backendUrls) == 0 {
-		return nil, fmt.Errorf("не указаны бэкенд-серверы")
+		return nil, i18n.Errorf(i18n.BalancerNoBackends)
 	}
 
 	parsedAlgorithm := strings.ToLower(algorithm)
-	if parsedAlgorithm != "round_robin" && parsedAlgorithm != "random" {
-		log.Printf("[Warning] Неизвестный алгоритм балансировки '%s', используется 'round_robin'", algorithm)
+	if parsedAlgorithm != "round_robin" && parsedAlgorithm != "random" && parsedAlgorithm != "consistent_hash" {
+		i18n.Logf(i18n.BalancerUnknownAlgorithm, algorithm)
 		parsedAlgorithm = "round_robin"
 	}
 
 	b := &Balancer{
-		rateLimiter:       rl,
-		healthCheckConfig: hcConfig,
-		algorithm:         parsedAlgorithm,
+		rateLimiter:         rl,
+		algorithm:           parsedAlgorithm,
+		saturationThreshold: config.DefaultSaturationThreshold,
 	}
+	b.routes.Store(newRouteTable(config.HeaderPolicyConfig{}, nil))
+	b.SetResolver(net.DefaultResolver)
+	b.healthCheckConfig.Store(&hcConfig)
 
 	// Инициализируем RNG, если выбран Random
 	if b.algorithm == "random" {
 		source := rand.NewSource(time.Now().UnixNano())
 		b.rng = rand.New(source)
-		log.Println("[Balancer] Инициализирован генератор случайных чисел для Random алгоритма.")
+		i18n.Logf(i18n.BalancerRNGInit)
 	}
 
 	backends := make([]*Backend, 0, len(backendUrls))
 
 	for i, rawURL := range backendUrls {
-		parsedURL, err := url.Parse(rawURL)
+		backend, err := b.newBackend(i, rawURL)
 		if err != nil {
-			return nil, fmt.Errorf("ошибка парсинга URL бэкенда #%d ('%s'): %w", i, rawURL, err)
-		}
-
-		// Добавляем проверку: URL должен быть абсолютным (иметь схему и хост)
-		if parsedURL.Scheme == "" || parsedURL.Host == "" {
-			return nil, fmt.Errorf("URL бэкенда #%d ('%s') должен быть абсолютным (например, 'http://host:port')", i, rawURL)
+			return nil, err
 		}
+		backends = append(backends, backend)
+		i18n.Logf(i18n.BalancerBackendAdded, i, backend.URL)
+	}
 
-		proxy := httputil.NewSingleHostReverseProxy(parsedURL)
+	// Только после успешного парсинга всех URL присваиваем слайс балансировщику
+	b.backends.Store(&backends)
+	b.nextID = len(backends)
+	if b.algorithm == "consistent_hash" {
+		b.buildRing()
+	}
 
-		// Создаем копию индекса для замыкания ErrorHandler
-		backendIndex := i
+	if hcConfig.Enabled {
+		b.healthCheckStopChan = make(chan struct{})
+		b.healthCheckReload = make(chan struct{}, 1)
+		b.background.Add(1)
+		go func() {
+			defer b.background.Done()
+			b.startHealthChecks()
+		}()
+		i18n.Logf(i18n.BalancerHealthChecksStarted)
+	}
 
-		proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
-			log.Printf("--- Custom ErrorHandler ENTERED for %s ---", req.URL.Path) // Добавим лог входа
+	return b, nil
+}
 
-			clientID := rl.GetClientID(req)
-			log.Printf("[Balancer] Ошибка проксирования на Бэкенд #%d (%s) для запроса от '%s': %v. Помечаем как нерабочий.",
-				backendIndex, parsedURL.String(), clientID, err)
+// newBackend создает бэкенд с ID i: разбирает URL и настраивает прокси с транспортом.
+func (b *Balancer) newBackend(i int, rawURL string) (*Backend, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, i18n.Errorf(i18n.BalancerBadBackendURL, i, rawURL, err)
+	}
 
-			// Находим нужный бэкенд по индексу (теперь он есть в замыкании)
-			// Нужна проверка на выход за границы на случай гонки состояний, хотя маловероятно
-			if backendIndex < len(b.backends) {
-				b.backends[backendIndex].SetAlive(false)
-			} else {
-				log.Printf("[Warning] ErrorHandler: Не удалось найти бэкенд с индексом %d для установки Alive=false", backendIndex)
-			}
+	// Добавляем проверку: URL должен быть абсолютным (иметь схему и хост)
+	if parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return nil, i18n.Errorf(i18n.BalancerRelativeBackendURL, i, rawURL)
+	}
 
-			response.RespondWithError(rw, http.StatusBadGateway, "Bad Gateway from Custom Handler")
-			log.Printf("--- Custom ErrorHandler EXITED for %s ---", req.URL.Path) // Добавим лог выхода
+	proxy := httputil.NewSingleHostReverseProxy(parsedURL)
+	transport := newBackendTransport(b.withDialer(newHTTPTransport))
+	proxy.Transport = transport
+	proxy.Director = b.director(i, parsedURL)
+	// Бэкенд для замыканий ModifyResponse и ErrorHandler; создается ниже, до первого запроса
+	var backend *Backend
+
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		// Заголовки соединения бэкенда не передаются клиенту
+		headers.RemoveHopByHop(resp.Header)
+		if resp.StatusCode >= http.StatusInternalServerError {
+			backend.failed.Add(1)
+		}
+		if err := b.checkThrottled(backend, resp); err != nil {
+			return err
 		}
+		if err := b.validateResponse(resp); err != nil {
+			return err
+		}
+		b.observeResponse(backend, resp.StatusCode)
+		return nil
+	}
 
-		backend := &Backend{
-			URL:          parsedURL,
-			Alive:        true,
-			ReverseProxy: proxy,
+	proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
+		if errors.Is(err, errUpstreamThrottled) {
+			// Ответ 429/503 отброшен, запрос будет повторен на другом бэкенде (см. forwardWithRetry)
+			return
+		}
+		if ClientDisconnected(rw, req) {
+			// Запрос отменен клиентом, а не сорван бэкендом: бэкенд не исключается
+			return
 		}
 
-		backends = append(backends, backend)
-		log.Printf("[Config] Бэкенд #%d добавлен: %s", i, backend.URL)
-	}
+		traceFrom(req.Context()).add("proxy_error=%d", i)
+		clientID := b.rateLimiter.GetClientID(req)
+		i18n.Logf(i18n.BalancerProxyFailed,
+			i, parsedURL.String(), clientID, requestid.FromContext(req.Context()), err)
+		i18n.Logf(i18n.BalancerRequestHeaders, b.matchRoute(req.URL.Path).headers.Redact(req.Header))
 
-	// Только после успешного парсинга всех URL присваиваем слайс балансировщику
-	b.backends = backends
+		backend.failed.Add(1)
+		b.passiveFailure(backend)
 
-	if b.healthCheckConfig.Enabled {
-		b.healthCheckStopChan = make(chan struct{})
-		go b.startHealthChecks()
-		log.Println("[Balancer] Health Checks запущены.")
+		if backendTimedOut(req) {
+			backendTimeoutsTotal.Inc()
+			b.respondWithError(rw, req, http.StatusGatewayTimeout, response.CodeGatewayTimeout, i18n.T(i18n.BalancerGatewayTimeout))
+		} else {
+			b.respondWithError(rw, req, http.StatusBadGateway, response.CodeBadGateway, i18n.T(i18n.BalancerBadGateway))
+		}
 	}
 
-	return b, nil
+	backend = &Backend{
+		id:           i,
+		URL:          parsedURL,
+		Alive:        true,
+		ReverseProxy: proxy,
+		transport:    transport,
+		weight:       1,
+		stickyID:     stickyID(parsedURL.String()),
+		latency:      metrics.NewUnregisteredHistogram(latencyBuckets),
+	}
+
+	return backend, nil
 }
 
 // StopHealthChecks останавливает фоновые проверки состояния.
 func (b *Balancer) StopHealthChecks() {
 	if b.healthCheckStopChan != nil {
 		close(b.healthCheckStopChan)
-		log.Println("[Balancer] Остановка Health Checks...")
-		// Можно добавить ожидание завершения, если это необходимо
+		i18n.Logf(i18n.BalancerHealthChecksStopping)
 	}
 }
 
-// GetBackends возвращает слайс бэкендов (для использования в тестах).
-func (b *Balancer) GetBackends() []*Backend {
-	return b.backends
+// Wait ожидает завершения фоновых горутин балансировщика после StopHealthChecks.
+// Возвращает ошибку контекста, если горутины не завершились до его отмены.
+func (b *Balancer) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// getRoundRobinHealthyBackend выбирает следующий работоспособный бэкенд по Round Robin.
-func (b *Balancer) getRoundRobinHealthyBackend() (*Backend, int, error) {
-	numBackends := len(b.backends)
-	if numBackends == 0 {
-		return nil, -1, ErrNoHealthyBackends
-	}
+// SetAdmission подключает ограничение одновременных запросов. Один Guard может разделяться
+// несколькими балансировщиками. Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetAdmission(g *admission.Guard) {
+	b.admission = g
+}
+
+// SetCapture подключает запись запросов и ответов, управляемую через служебный API.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetCapture(rec *capture.Recorder) {
+	b.capture = rec
+}
 
-	start := b.current.Add(1)
+// SetAccessLog подключает отправку журнала доступа: по записи на каждый обработанный запрос.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetAccessLog(shipper *accesslog.Shipper) {
+	b.accessLog = shipper
+}
 
-	for i := 0; i < numBackends; i++ {
-		idx := int((start + uint64(i) - 1) % uint64(numBackends))
-		backend := b.backends[idx]
+// SetAccessList подключает списки запрета и разрешения клиентов. Один List может разделяться
+// несколькими балансировщиками. Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetAccessList(list *access.List) {
+	b.accessList = list
+}
+
+// AddObserver регистрирует наблюдателя за запросами. Должен вызываться до начала обработки запросов.
+func (b *Balancer) AddObserver(o RequestObserver) {
+	b.observers = append(b.observers, o)
+}
+
+// notifyObservers сообщает наблюдателям о результате обработки запроса.
+func (b *Balancer) notifyObservers(rateLimited bool) {
+	for _, o := range b.observers {
+		o.ObserveRequest(rateLimited)
+	}
+}
+
+// HealthyBackends возвращает количество работоспособных и общее количество бэкендов (без удаленных).
+func (b *Balancer) HealthyBackends() (healthy, total int) {
+	for _, backend := range b.backendList() {
+		if backend.Removed() {
+			continue
+		}
+		total++
 		if backend.IsAlive() {
-			return backend, idx, nil
+			healthy++
 		}
 	}
-	return nil, -1, ErrNoHealthyBackends
+	return healthy, total
 }
 
-// getRandomHealthyBackend выбирает случайный работоспособный бэкенд.
-func (b *Balancer) getRandomHealthyBackend() (*Backend, int, error) {
-	// Создаем срез с индексами живых бэкендов
-	healthyIndices := make([]int, 0, len(b.backends))
-	for i, backend := range b.backends {
-		if backend.IsAlive() {
-			healthyIndices = append(healthyIndices, i)
+// Backend возвращает бэкенд пула по ID (поле index в Snapshot), например чтобы вывести его из балансировки
+// через SetAlive; nil, если бэкенда с таким ID нет. Состояние бэкендов читается через Snapshot.
+func (b *Balancer) Backend(id int) *Backend {
+	for _, backend := range b.backendList() {
+		if backend.id == id && !backend.Removed() {
+			return backend
 		}
 	}
+	return nil
+}
 
-	numHealthy := len(healthyIndices)
-	if numHealthy == 0 {
+// ID возвращает ID бэкенда в пуле. ID бэкендов из backend_servers совпадают с их порядковыми номерами,
+// бэкенды, добавленные через AddBackend, получают следующие. ID не меняется и не используется повторно
+// после удаления бэкенда, поэтому по нему бэкенд находится в логах, Snapshot и API администратора.
+func (b *Backend) ID() int {
+	return b.id
+}
+
+// getRoundRobinHealthyBackend выбирает следующий работоспособный бэкенд по Round Robin и возвращает его с ID.
+// candidates - бэкенды, из которых идет выбор; counter - счетчик очереди.
+func (b *Balancer) getRoundRobinHealthyBackend(candidates []*Backend, counter *atomic.Uint64) (*Backend, int, error) {
+	numBackends := len(candidates)
+	if numBackends == 0 {
 		return nil, -1, ErrNoHealthyBackends
 	}
 
-	// Выбираем случайный индекс из среза *живых* индексов
-	randomIndexInHealthySlice := b.rng.Intn(numHealthy)
-	// Получаем оригинальный индекс бэкенда из среза healthyIndices
-	originalIndex := healthyIndices[randomIndexInHealthySlice]
+	start := counter.Add(1)
 
-	return b.backends[originalIndex], originalIndex, nil
+	// Почти заполненные бэкенды пропускаются, пока в очереди есть менее загруженные
+	pick := saturationPick{threshold: b.saturationThreshold}
+	for i := 0; i < numBackends; i++ {
+		backend := candidates[(start+uint64(i)-1)%uint64(numBackends)]
+		if backend.selectable() && pick.consider(backend) {
+			return backend, backend.id, nil
+		}
+	}
+	return pick.result()
 }
 
-// ServeHTTP обрабатывает входящие запросы.
-func (b *Balancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Логируем входящий запрос
-	clientID := b.rateLimiter.GetClientID(r)
-	log.Printf("[Request] Получен запрос: Метод=%s Путь=%s От=%s (%s)", r.Method, r.URL.Path, r.RemoteAddr, clientID)
-
-	// 1. Rate Limiting (если включен)
-	// Интерфейс будет nil, если rate limiter выключен или не передан
-	if b.rateLimiter != nil {
-		if !b.rateLimiter.Allow(clientID) {
-			// Используем новую функцию для ответа
-			response.RespondWithError(w, http.StatusTooManyRequests, "Rate limit exceeded")
-			return
+// getRandomHealthyBackend выбирает случайный работоспособный бэкенд среди candidates и возвращает его с ID.
+// Почти заполненные бэкенды участвуют в выборе, только если менее загруженных нет.
+func (b *Balancer) getRandomHealthyBackend(candidates []*Backend) (*Backend, int, error) {
+	// Создаем срез живых и не почти заполненных бэкендов
+	pick := saturationPick{threshold: b.saturationThreshold}
+	healthy := make([]*Backend, 0, len(candidates))
+	for _, backend := range candidates {
+		if backend.selectable() && pick.consider(backend) {
+			healthy = append(healthy, backend)
 		}
 	}
 
-	// 2. Выбор бэкенда
-	var targetBackend *Backend
-	var backendIndex int
-	var err error
+	numHealthy := len(healthy)
+	if numHealthy == 0 {
+		return pick.result()
+	}
+
+	if b.weighted.Load() {
+		backend := b.weightedRandom(healthy)
+		return backend, backend.id, nil
+	}
 
+	// Выбираем случайный бэкенд из среза *живых* бэкендов
+	backend := healthy[b.rng.Intn(numHealthy)]
+	return backend, backend.id, nil
+}
+
+// NextBackend выбирает работоспособный бэкенд по настроенному алгоритму и возвращает его с ID.
+// Возвращает ErrNoHealthyBackends, если доступных бэкендов нет. Без ключа клиента
+// consistent_hash выбирает бэкенд по Round Robin.
+func (b *Balancer) NextBackend() (*Backend, int, error) {
+	backends := b.backendList()
 	switch b.algorithm {
 	case "random":
-		targetBackend, backendIndex, err = b.getRandomHealthyBackend()
+		return b.getRandomHealthyBackend(backends)
 	case "round_robin":
 		fallthrough
 	default:
-		targetBackend, backendIndex, err = b.getRoundRobinHealthyBackend()
+		if b.weighted.Load() {
+			return b.getWeightedHealthyBackend(backends, &b.weights)
+		}
+		return b.getRoundRobinHealthyBackend(backends, &b.current)
 	}
+}
 
-	if err != nil {
-		log.Printf("[Balancer] Ошибка выбора бэкенда (%s): %v. Невозможно обработать запрос %s %s от '%s'.", b.algorithm, err, r.Method, r.URL.Path, clientID)
-		response.RespondWithError(w, http.StatusServiceUnavailable, "All backend servers are unavailable")
-		return
+// nextBackendForRoute выбирает бэкенд среди подходящих под селектор маршрута.
+// Для подмножества пула Round Robin ведет отдельную очередь маршрута, чтобы запросы распределялись равномерно.
+// clientID - ключ для consistent_hash: запросы клиента попадают на один и тот же бэкенд.
+func (b *Balancer) nextBackendForRoute(rt *route, clientID string) (*Backend, int, error) {
+	if b.algorithm == "consistent_hash" {
+		return b.getHashedHealthyBackend(clientID, rt.selector)
 	}
-
-	// Настраиваем и выполняем проксирование
-	targetUrl := targetBackend.URL
-	log.Printf("[Balancer] Перенаправление запроса (%s) от '%s' -> Бэкенд #%d (%s)", b.algorithm, clientID, backendIndex, targetUrl)
-
-	targetBackend.ReverseProxy.Director = func(r *http.Request) {
-		// Устанавливаем целевой URL и хост
-		r.URL.Scheme = targetUrl.Scheme
-		r.URL.Host = targetUrl.Host
-		r.URL.Path, r.URL.RawPath = r.URL.Path, r.URL.RawPath
-		if _, ok := r.Header["User-Agent"]; !ok {
-			r.Header.Set("User-Agent", "")
-		}
-		// Устанавливаем Host и X-Forwarded-*
-		r.Host = targetUrl.Host
-		if originalHost := r.Header.Get("Host"); originalHost != "" {
-			r.Header.Set("X-Forwarded-Host", originalHost)
-		} else {
-			r.Header.Set("X-Forwarded-Host", r.Host)
+	if len(rt.selector) == 0 {
+		if b.canary != nil {
+			return b.nextCanaryBackend()
 		}
+		return b.NextBackend()
+	}
 
-		r.Header.Del("X-Forwarded-For")
-		log.Printf("[Balancer] Перенаправление запроса от '%s' -> Бэкенд #%d (%s)", clientID, backendIndex, targetUrl)
+	backends := b.backendList()
+	candidates := make([]*Backend, 0, len(backends))
+	for _, backend := range backends {
+		if !backend.Removed() && backend.Matches(rt.selector) {
+			candidates = append(candidates, backend)
+		}
 	}
+	return b.pickBackend(candidates, &rt.current, &rt.weights)
+}
 
-	targetBackend.ReverseProxy.ServeHTTP(w, r)
+// pickBackend выбирает бэкенд среди candidates по алгоритму пула (кроме consistent_hash);
+// counter и queue - очереди Round Robin и взвешенного Round Robin по этому подмножеству.
+func (b *Balancer) pickBackend(candidates []*Backend, counter *atomic.Uint64, queue *weightedQueue) (*Backend, int, error) {
+	if b.algorithm == "random" {
+		return b.getRandomHealthyBackend(candidates)
+	}
+	if b.weighted.Load() {
+		return b.getWeightedHealthyBackend(candidates, queue)
+	}
+	return b.getRoundRobinHealthyBackend(candidates, counter)
 }
 
-// --- Health Check Logic ---
+// ServeHTTP обрабатывает входящие запросы.
+func (b *Balancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if b.maxRequestAge > 0 {
+		r = withReceived(r, time.Now())
+	}
+
+	// Логируем входящий запрос
+	clientID, idSource := ClientIdentity(b.rateLimiter, r)
+	i18n.Logf(i18n.BalancerRequestReceived, r.Method, r.URL.Path, r.RemoteAddr, clientID, requestid.FromContext(r.Context()))
+
+	// Журнал доступа: запись формируется после ответа, отправка идет в фоне
+	var upstream string // Бэкенд, на который ушел запрос
+	if b.accessLog != nil {
+		started := time.Now()
+		lw := accesslog.NewResponseWriter(w)
+		w = lw
+		defer func() {
+			b.accessLog.Log(accesslog.Entry{
+				Time:       started,
+				RequestID:  requestid.FromContext(r.Context()),
+				ClientID:   clientID,
+				Method:     r.Method,
+				Protocol:   r.Proto,
+				Host:       r.Host,
+				Path:       r.URL.Path,
+				Status:     lw.Status(),
+				Bytes:      lw.BytesWritten(),
+				DurationMS: float64(time.Since(started).Microseconds()) / 1000,
+				Backend:    upstream,
+				UserAgent:  r.UserAgent(),
+			})
+		}()
+	}
+	// Span распределенной трассировки: бэкенд и статус записываются после ответа
+	w, r, endServerSpan := b.startServerSpan(w, r, clientID)
+	defer func() { endServerSpan(upstream) }()
+	// Запись запроса и ответа, если она включена для этого клиента или маршрута
+	captured := b.capture != nil && b.capture.Matches(clientID, r.URL.Path)
+	// Трассировка решений по запросу: в заголовке ответа X-Balancer-Trace и в записи запроса
+	w, r, trace := b.startTrace(w, r, captured)
+	if captured {
+		var finish func(trace string)
+		w, r, finish = b.capture.Begin(w, r, clientID, b.matchRoute(r.URL.Path).headers)
+		defer func() { finish(trace.String()) }()
+	}
+	trace.add("route=%s", traceRoute(b.matchRoute(r.URL.Path)))
+
+	// 1. Списки доступа: запрещенные клиенты отклоняются, разрешенные не ограничиваются Rate Limiter
+	exempt := false
+	if b.accessList != nil {
+		switch b.accessList.Decide(clientID) {
+		case access.Denied:
+			trace.add("access=deny")
+			i18n.Logf(i18n.BalancerAccessDenied, clientID)
+			b.respondWithError(w, r, http.StatusForbidden, response.CodeAccessDenied, i18n.T(i18n.BalancerForbidden))
+			return
+		case access.Allowed:
+			trace.add("access=allow")
+			exempt = true
+		}
+	}
 
-// startHealthChecks запускает периодические проверки состояния для всех бэкендов.
-func (b *Balancer) startHealthChecks() {
-	log.Printf("[HealthCheck] Запуск проверок состояния: Интервал=%v, Таймаут=%v, Путь=%s",
-		b.healthCheckConfig.Interval, b.healthCheckConfig.Timeout, b.healthCheckConfig.Path)
+	// 1a. Сценарий допуска (admission_hook): может отклонить запрос, задать его стоимость в токенах
+	// или направить его в другой пул
+	decision := b.runHook(r, clientID)
+	if decision.RejectStatus != 0 {
+		trace.add("hook=reject %d", decision.RejectStatus)
+		b.rejectByHook(w, r, decision, clientID)
+		return
+	}
+	if decision.Cost != 1 {
+		trace.add("hook=cost %g", decision.Cost)
+	}
+	hookPool := b.hookPool(decision, clientID)
+
+	// 1b. Одновременные запросы клиента: слот занят до ответа, в том числе пока запрос ждет в очереди concurrency.
+	// Проверяется до Rate Limiter: отказ из-за лимита одновременных запросов не расходует токены клиента
+	if inFlight, ok := b.rateLimiter.(InFlightLimiter); ok && !exempt {
+		release, ok := inFlight.AcquireInFlight(clientID)
+		if !ok {
+			trace.add("inflight=rejected")
+			b.notifyObservers(true)
+			b.respondWithError(w, r, http.StatusTooManyRequests, response.CodeTooManyInFlight, i18n.T(i18n.BalancerTooManyInFlight))
+			return
+		}
+		defer release()
+	}
+
+	// 2. Rate Limiting (если включен)
+	// Интерфейс будет nil, если rate limiter выключен или не передан
+	if b.rateLimiter != nil && !exempt && decision.Cost > 0 {
+		limitKey := b.limitKey(r, clientID)
+		var allowed, global bool
+		var warning string
+		var err error
+		if quota, ok := b.rateLimiter.(QuotaLimiter); ok {
+			var state ratelimiter.Decision
+			state, err = quota.Decide(limitKey, idSource, decision.Cost)
+			allowed, warning, global = state.Allowed, state.Warning, state.Global
+			setQuotaHeaders(w.Header(), state)
+		} else if costed, ok := b.rateLimiter.(CostLimiter); ok && decision.Cost != 1 {
+			allowed, warning, err = costed.CheckCost(limitKey, decision.Cost)
+		} else if soft, ok := b.rateLimiter.(SoftLimiter); ok {
+			allowed, warning, err = soft.CheckSoft(limitKey)
+		} else {
+			allowed, err = b.rateLimiter.Check(limitKey)
+		}
+		if global {
+			trace.add("ratelimit=global")
+		} else {
+			b.traceLimit(trace, limitKey, allowed, err)
+		}
+		if err != nil {
+			// Хранилище лимитов недоступно и выбрана политика fail_closed
+			b.respondWithError(w, r, http.StatusServiceUnavailable, response.CodeRateLimitStoreDown, i18n.T(i18n.BalancerStoreUnavailable))
+			return
+		}
+		if !allowed {
+			b.notifyObservers(true)
+			if global {
+				// Превышен общий лимит сервиса: клиент не виноват, поэтому 503, а не 429
+				b.respondWithError(w, r, http.StatusServiceUnavailable, response.CodeOverloaded, i18n.T(i18n.BalancerOverloaded))
+				return
+			}
+			// Используем новую функцию для ответа
+			b.respondWithError(w, r, http.StatusTooManyRequests, response.CodeRateLimited, i18n.T(i18n.BalancerRateLimited))
+			return
+		}
+		if warning != "" {
+			// Заголовок сохраняется и в ответе бэкенда, и в ответе балансировщика об ошибке
+			w.Header().Set(RateLimitWarningHeader, warning)
+		}
+	}
 
-	client := &http.Client{
-		Timeout: b.healthCheckConfig.Timeout,
-		Transport: &http.Transport{
-			MaxIdleConnsPerHost: 5,
-			IdleConnTimeout:     30 * time.Second,
-		},
+	if exempt {
+		trace.add("ratelimit=exempt")
+	}
+	b.notifyObservers(false)
+
+	// 3. Ограничение одновременных запросов: при перегрузке первыми проходят запросы с большим приоритетом
+	if b.admission != nil {
+		// Ожидание в очереди ограничено request_age.max_age: устаревший запрос не занимает место
+		waitCtx, cancel := b.ageContext(r)
+		release, err := b.admission.Acquire(waitCtx, b.admission.Priority(r))
+		cancel()
+		if err != nil {
+			if ClientDisconnected(w, r) || b.shedExpired(w, r, clientID) {
+				return
+			}
+			trace.add("admission=rejected")
+			i18n.Logf(i18n.BalancerAdmissionRejected, clientID, err)
+			b.respondWithError(w, r, http.StatusServiceUnavailable, response.CodeOverloaded, i18n.T(i18n.BalancerOverloaded))
+			return
+		}
+		defer release()
+	}
+	if b.shedExpired(w, r, clientID) {
+		return
 	}
 
-	ticker := time.NewTicker(b.healthCheckConfig.Interval)
-	defer ticker.Stop()
+	// Пул, выбранный сценарием допуска, обрабатывает запрос вместо этого пула (без перелива)
+	if hookPool != nil {
+		trace.add("hook=pool %s", decision.Pool)
+		upstream = b.forwardToHookPool(w, r, hookPool, decision.Pool, clientID)
+		return
+	}
 
-	b.performChecks(client)
+	// 4. Перелив в резервный пул, если бюджет основного пула исчерпан
+	if b.spillover != nil {
+		if !b.spillover.admitPrimary() {
+			overflowRoute := b.spillover.pool.matchRoute(r.URL.Path)
+			if overflowBackend, overflowIndex, err := b.spillover.pool.nextBackendForRoute(overflowRoute, clientID); err == nil {
+				spilloverRequestsTotal.Inc()
+				trace.add("spillover=%d", overflowIndex)
+				i18n.Logf(i18n.BalancerSpillover, clientID, overflowIndex, overflowBackend.URL)
+				upstream = overflowBackend.URL.String()
+				b.spillover.pool.forward(w, r, overflowBackend, overflowIndex, clientID)
+				return
+			}
+			// В резервном пуле нет доступных бэкендов: обрабатываем запрос основным пулом сверх бюджета
+			spilloverUnavailableTotal.Inc()
+			b.spillover.enterPrimary()
+		}
+		defer b.spillover.leavePrimary()
+		primaryRequestsTotal.Inc()
+	}
 
-	// Запускаем цикл проверок
-	for {
-		select {
-		case <-ticker.C:
-			b.performChecks(client)
-		case <-b.healthCheckStopChan:
-			log.Println("[HealthCheck] Получен сигнал остановки проверок.")
+	// 5. Выбор бэкенда: бэкенд из cookie привязки, если он доступен, иначе по алгоритму
+	// (маршрут может ограничить выбор подмножеством пула по меткам)
+	rt := b.matchRoute(r.URL.Path)
+	if trace != nil {
+		trace.add("%s", b.traceCandidates(rt))
+	}
+	targetBackend, backendIndex, stuck := b.stickyBackend(r, rt)
+	if stuck {
+		trace.add("sticky=%d", backendIndex)
+	} else {
+		var err error
+		targetBackend, backendIndex, err = b.nextBackendForRoute(rt, clientID)
+		if err != nil {
+			trace.add("backend=none")
+			i18n.Logf(i18n.BalancerSelectFailed, b.algorithm, err, r.Method, r.URL.Path, clientID)
+			if errors.Is(err, ErrBackendsSaturated) {
+				b.respondWithError(w, r, http.StatusServiceUnavailable, response.CodeOverloaded, i18n.T(i18n.BalancerOverloaded))
+				return
+			}
+			b.respondWithError(w, r, http.StatusServiceUnavailable, response.CodeNoHealthyBackends, i18n.T(i18n.BalancerAllBackendsDown))
 			return
 		}
+		b.stick(w, r, targetBackend)
 	}
-}
 
-// performChecks запускает проверку для каждого бэкенда в отдельной горутине.
-func (b *Balancer) performChecks(client *http.Client) {
-	log.Println("[HealthCheck] Выполнение цикла проверок...")
+	upstream = targetBackend.URL.String()
+	trace.add("backend=%d", backendIndex)
+	b.forward(w, r, targetBackend, backendIndex, clientID)
+}
 
-	for _, backend := range b.backends {
-		go func(be *Backend) {
-			b.checkBackendHealth(be, client)
-		}(backend)
+// forward проксирует запрос на выбранный бэкенд этого балансировщика.
+func (b *Balancer) forward(w http.ResponseWriter, r *http.Request, targetBackend *Backend, backendIndex int, clientID string) {
+	if b.throttling.retries(r) {
+		b.forwardWithRetry(w, r, targetBackend, backendIndex, clientID)
+		return
 	}
+	b.proxyTo(w, r, targetBackend, backendIndex, clientID, nil)
 }
 
-// checkBackendHealth выполняет проверку состояния одного бэкенда.
-func (b *Balancer) checkBackendHealth(backend *Backend, client *http.Client) {
-	checkURL := backend.URL.JoinPath(b.healthCheckConfig.Path).String()
+// director возвращает Director прокси бэкенда. Он задается один раз при создании прокси и общий
+// для всех запросов бэкенда: все, что зависит от запроса (маршрут, его политика заголовков),
+// определяется по самому запросу, а не по замыканию.
+func (b *Balancer) director(backendIndex int, target *url.URL) func(*http.Request) {
+	return func(r *http.Request) {
+		// Устанавливаем целевой URL и хост
+		r.URL.Scheme = target.Scheme
+		r.URL.Host = target.Host
+		// Устанавливаем Host и X-Forwarded-*
+		r.Host = target.Host
+		forwardedHost := r.Header.Get("Host")
+		if forwardedHost == "" {
+			forwardedHost = r.Host
+		}
 
-	ctx, cancel := context.WithTimeout(context.Background(), b.healthCheckConfig.Timeout)
-	defer cancel()
+		r.Header.Del("X-Forwarded-For")
+		r.Header.Del(TraceHeader) // Токен трассировки не передается бэкенду
+		// Заголовки соединения клиента не пересылаются бэкенду (кроме смены протокола и "TE: trailers")
+		headers.RemoveHopByHop(r.Header)
+		// Удаляем заголовки клиента, которые не должны дойти до бэкенда по политике маршрута. Заголовки
+		// балансировщика добавляются после этого: список разрешенных заголовков маршрута их не удаляет
+		rt := b.matchRoute(r.URL.Path)
+		rt.headers.Scrub(r.Header)
+		if _, ok := r.Header["User-Agent"]; !ok {
+			r.Header.Set("User-Agent", "")
+		}
+		r.Header.Set("X-Forwarded-Host", forwardedHost)
+		if id := requestid.FromContext(r.Context()); id != "" {
+			r.Header.Set(requestid.Header, id)
+		}
+		tracing.Inject(r.Context(), r.Header)
+		b.setClientCertHeader(r)
+		if auth := b.upstreamAuth.Load(); auth != nil {
+			auth.apply(r, rt.untrusted, time.Now())
+		}
+		i18n.Logf(i18n.BalancerDirector, requestid.FromContext(r.Context()), backendIndex, target)
+	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checkURL, nil)
-	if err != nil {
-		log.Printf("[HealthCheck] Ошибка создания запроса для %s: %v", checkURL, err)
-		backend.SetAlive(false) // Считаем нерабочим при ошибке создания запроса
-		return
+// proxyTo проксирует запрос на бэкенд. throttled - ответ 429/503, отброшенный для повтора
+// (см. forwardWithRetry): его статус учитывается в истории бэкенда вместо ответа клиенту.
+func (b *Balancer) proxyTo(w http.ResponseWriter, r *http.Request, targetBackend *Backend, backendIndex int, clientID string, throttled *throttledResponse) {
+	rt := b.matchRoute(r.URL.Path)
+	i18n.Logf(i18n.BalancerForwarding, b.algorithm, clientID, backendIndex, targetBackend.URL)
+	targetBackend.requests.Add(1)
+	targetBackend.inFlight.Add(1)
+	defer b.release(targetBackend)
+
+	// Вызовы gRPC идут через отдельный прокси с HTTP/2 до бэкенда
+	proxy := targetBackend.ReverseProxy
+	if b.grpc && response.IsGRPC(r) {
+		proxy = targetBackend.grpcProxy
+	}
+	// Маршрут может отключить буферизацию ответа (SSE, потоковые API): используем копию прокси,
+	// чтобы не менять поведение остальных маршрутов этого бэкенда
+	if rt.flushInterval != nil && *rt.flushInterval != proxy.FlushInterval {
+		routeProxy := *proxy
+		routeProxy.FlushInterval = *rt.flushInterval
+		proxy = &routeProxy
 	}
 
-	// Отправляем GET-запрос
-	resp, err := client.Do(req)
-	if err != nil {
-		// Ошибка может быть связана с сетью, таймаутом или другими проблемами
-		log.Printf("[HealthCheck] Ошибка проверки бэкенда %s: %v", checkURL, err)
-		backend.SetAlive(false)
-		return
+	// Смена протокола (WebSocket): сессия занимает бэкенд (inFlight) до закрытия соединения,
+	// а во время ответа бэкенда учитывается только ожидание ответа 101
+	var uw *upgradeWriter
+	if headers.UpgradeRequested(r.Header) {
+		uw = &upgradeWriter{ResponseWriter: w}
+		w = uw
 	}
-	defer resp.Body.Close()
 
-	// Проверяем статус код (ожидаем 2xx)
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		// Бэкенд считается живым
-		backend.SetAlive(true)
-	} else {
-		log.Printf("[HealthCheck] Бэкенд %s вернул не-2xx статус: %d", checkURL, resp.StatusCode)
-		backend.SetAlive(false)
+	r, span := b.startBackendSpan(r, targetBackend, backendIndex)
+	r, stopTimeout := b.withBackendTimeout(r, rt)
+	defer stopTimeout()
+	started := time.Now()
+	if targetBackend.history == nil && b.canary == nil && span == nil {
+		proxy.ServeHTTP(w, r)
+		targetBackend.latency.Observe(uw.responseTime(started, time.Now()).Seconds())
+		return
+	}
+	sw := &statusWriter{ResponseWriter: w}
+	proxy.ServeHTTP(sw, r)
+	status := sw.status
+	if throttled != nil && throttled.status != 0 {
+		status = throttled.status
+	}
+	now := time.Now()
+	elapsed := uw.responseTime(started, now)
+	targetBackend.latency.Observe(elapsed.Seconds())
+	endSpan(span, status)
+	failed := status >= http.StatusInternalServerError
+	if targetBackend.history != nil {
+		targetBackend.history.record(now, failed)
+	}
+	if b.canary != nil {
+		b.canary.observe(targetBackend, now, failed, elapsed)
 	}
 }
@@ -135,6 +135,39 @@ func BenchmarkServeHTTP(b *testing.B) {
 	})
 }
 
+// BenchmarkServeHTTP_Routes измеряет обработку параллельных запросов к одному бэкенду по маршрутам
+// с разными политиками заголовков: Director прокси общий для всех запросов бэкенда и не пересоздается.
+func BenchmarkServeHTTP_Routes(b *testing.B) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	b.Cleanup(backendServer.Close)
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(b, err)
+	lb, err := balancer.New([]string{backendServer.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(b, err)
+	lb.SetRoutes(config.HeaderPolicyConfig{}, []config.RouteConfig{
+		{PathPrefix: "/public", Headers: config.HeaderPolicyConfig{Deny: []string{"X-Secret"}}},
+	})
+
+	paths := []string{"/public/item", "/private/item"}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			req := httptest.NewRequest(http.MethodGet, paths[i%len(paths)], nil)
+			req.Header.Set("X-Secret", "s3cret")
+			w := httptest.NewRecorder()
+			lb.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				b.Errorf("Ожидался статус 200 OK, получено %d", w.Code)
+			}
+			i++
+		}
+	})
+}
+
 // --- Unit тесты ---
 
 func TestNewBalancer_InvalidAlgorithm(t *testing.T) {
@@ -186,3 +219,78 @@ func TestNewBalancer_InvalidBackendURL(t *testing.T) {
 		t.Errorf("Ожидалась ошибка парсинга URL, но получено nil")
 	}
 }
+
+// TestNextBackend_Weighted проверяет плавный взвешенный Round Robin: бэкенд с весом 3
+// получает втрое больше запросов и не подряд, а вперемешку с остальными.
+func TestNextBackend_Weighted(t *testing.T) {
+	urls := []string{"http://heavy:80", "http://light:80"}
+	lb, err := balancer.New(urls, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetBackendWeights(map[string]int{"http://heavy:80": 3})
+
+	var sequence []int
+	for i := 0; i < 8; i++ {
+		_, idx, err := lb.NextBackend()
+		require.NoError(t, err)
+		sequence = append(sequence, idx)
+	}
+	assert.Equal(t, []int{0, 0, 1, 0, 0, 0, 1, 0}, sequence)
+	assert.Equal(t, 3, lb.Snapshot().Backends[0].Weight)
+
+	// Недоступный бэкенд не участвует в выборе независимо от веса
+	lb.Backend(0).SetAlive(false)
+	for i := 0; i < 3; i++ {
+		_, idx, err := lb.NextBackend()
+		require.NoError(t, err)
+		assert.Equal(t, 1, idx)
+	}
+
+	random, err := balancer.New(urls, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "random")
+	require.NoError(t, err)
+	random.SetBackendWeights(map[string]int{"http://heavy:80": 3})
+	counts := make([]int, 2)
+	for i := 0; i < 4000; i++ {
+		_, idx, err := random.NextBackend()
+		require.NoError(t, err)
+		counts[idx]++
+	}
+	assert.InDelta(t, 3.0, float64(counts[0])/float64(counts[1]), 0.5)
+}
+
+// TestBalancer_Snapshot проверяет снимок пула: счетчики запросов и ошибок и независимость от пула.
+func TestBalancer_Snapshot(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	lb, err := balancer.New([]string{ok.URL, failing.URL}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetBackendLabels(map[string]map[string]string{ok.URL: {"zone": "a"}})
+	for i := 0; i < 4; i++ {
+		lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	snapshot := lb.Snapshot()
+	assert.Equal(t, "round_robin", snapshot.Algorithm)
+	assert.Equal(t, 2, snapshot.Total)
+	assert.Equal(t, 2, snapshot.Healthy)
+	require.Len(t, snapshot.Backends, 2)
+	assert.Equal(t, uint64(2), snapshot.Backends[0].Requests)
+	assert.Equal(t, uint64(0), snapshot.Backends[0].Failures)
+	assert.Equal(t, uint64(2), snapshot.Backends[1].Requests)
+	assert.Equal(t, uint64(2), snapshot.Backends[1].Failures)
+
+	// Снимок не меняется вместе с пулом, а изменение снимка не затрагивает пул
+	lb.Backend(1).SetAlive(false)
+	snapshot.Backends[0].Labels["zone"] = "b"
+	assert.True(t, snapshot.Backends[1].Alive)
+	next := lb.Snapshot()
+	assert.False(t, next.Backends[1].Alive)
+	assert.Equal(t, "a", next.Backends[0].Labels["zone"])
+
+	assert.Nil(t, lb.Backend(2))
+	assert.Nil(t, lb.Backend(-1))
+}
@@ -0,0 +1,288 @@
+package balancer
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+// Группы бэкендов пула с канареечным разделением.
+const (
+	stableGroup = iota
+	canaryGroup
+)
+
+var canaryRollbacksTotal = metrics.NewCounter("balancer_canary_rollbacks_total",
+	"Количество автоматических откатов канареечного разделения из-за превышения порогов.")
+
+// canaryWindow - кольцевой буфер запросов, ошибок и суммарного времени ответа группы по секундам.
+type canaryWindow struct {
+	mu       sync.Mutex
+	seconds  []int64 // Unix-время корзины в секундах: корзина с другим временем устарела и считается пустой.
+	requests []int64
+	errors   []int64
+	latency  []time.Duration
+}
+
+func newCanaryWindow(size int) *canaryWindow {
+	return &canaryWindow{
+		seconds:  make([]int64, size),
+		requests: make([]int64, size),
+		errors:   make([]int64, size),
+		latency:  make([]time.Duration, size),
+	}
+}
+
+// record учитывает запрос, завершившийся в момент now за время latency.
+func (w *canaryWindow) record(now time.Time, failed bool, latency time.Duration) {
+	second := now.Unix()
+	i := int(second % int64(len(w.seconds)))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.seconds[i] != second {
+		w.seconds[i], w.requests[i], w.errors[i], w.latency[i] = second, 0, 0, 0
+	}
+	w.requests[i]++
+	if failed {
+		w.errors[i]++
+	}
+	w.latency[i] += latency
+}
+
+// reset очищает буфер.
+func (w *canaryWindow) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	clear(w.seconds)
+}
+
+// stats возвращает статистику группы за окно, заканчивающееся секундой end.
+func (w *canaryWindow) stats(end int64) CanaryGroupStats {
+	var stats CanaryGroupStats
+	var latency time.Duration
+
+	w.mu.Lock()
+	for k := range w.seconds {
+		second := end - int64(k)
+		i := int(second % int64(len(w.seconds)))
+		if w.seconds[i] == second {
+			stats.Requests += w.requests[i]
+			stats.Errors += w.errors[i]
+			latency += w.latency[i]
+		}
+	}
+	w.mu.Unlock()
+
+	if stats.Requests > 0 {
+		stats.ErrorRate = float64(stats.Errors) / float64(stats.Requests)
+		stats.AvgLatencyMs = float64(latency.Microseconds()) / 1000 / float64(stats.Requests)
+	}
+	return stats
+}
+
+// canarySplit - разделение запросов пула между стабильными и канареечными бэкендами (см. SetCanary).
+type canarySplit struct {
+	cfg config.CanaryConfig
+	// candidates - бэкенды групп stableGroup и canaryGroup; меняются при AddBackend и RemoveBackend.
+	candidates atomic.Pointer[[2][]*Backend]
+	current    [2]atomic.Uint64
+	weights    [2]weightedQueue
+	windows    [2]*canaryWindow
+	// evaluated - секунда последнего сравнения групп: пороги проверяются не чаще раза в секунду.
+	evaluated  atomic.Int64
+	rolledBack atomic.Bool
+	mu         sync.Mutex // Защищает rollbackAt и rollbackReason.
+	rollbackAt time.Time
+	// rollbackReason - какой порог превысила канарейка при откате.
+	rollbackReason string
+}
+
+// CanaryGroupStats - запросы группы бэкендов за окно canary.window.
+type CanaryGroupStats struct {
+	Backends int   `json:"backends"`
+	Requests int64 `json:"requests"`
+	// Errors - ответы 5xx и ошибки проксирования (клиент получил 502).
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	// AvgLatencyMs - среднее время ответа бэкендов группы в миллисекундах.
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// CanaryReport - сравнение стабильных и канареечных бэкендов пула (GET /admin/canary).
+type CanaryReport struct {
+	Selector map[string]string `json:"selector"`
+	Percent  int               `json:"percent"`
+	Window   string            `json:"window"`
+	Stable   CanaryGroupStats  `json:"stable"`
+	Canary   CanaryGroupStats  `json:"canary"`
+	// Degraded - канарейка превышает пороги; сравнение выполняется, только когда у обеих групп
+	// в окне не меньше canary.min_requests запросов. Reason - какой порог превышен.
+	Degraded bool   `json:"degraded"`
+	Reason   string `json:"reason,omitempty"`
+	// RolledBack - разделение откачено (auto_rollback): все запросы получают стабильные бэкенды.
+	RolledBack     bool       `json:"rolled_back"`
+	RolledBackAt   *time.Time `json:"rolled_back_at,omitempty"`
+	RollbackReason string     `json:"rollback_reason,omitempty"`
+}
+
+// SetCanary включает канареечное разделение пула: бэкенды с метками cfg.Selector получают cfg.Percent
+// процентов запросов, остальные - стабильные. Маршруты с backend_selector выбирают бэкенды по своему
+// селектору без разделения. Должен вызываться после SetBackendLabels и до начала обработки запросов.
+func (b *Balancer) SetCanary(cfg config.CanaryConfig) {
+	if cfg.Percent == 0 {
+		return
+	}
+	c := &canarySplit{cfg: cfg}
+	candidates := c.group(b.backendList())
+	if len(candidates[canaryGroup]) == 0 {
+		i18n.Logf(i18n.BalancerCanaryNoBackends, cfg.Selector)
+		return
+	}
+	for _, backend := range candidates[canaryGroup] {
+		backend.canary = true
+	}
+	c.candidates.Store(&candidates)
+	size := int(cfg.Window / time.Second)
+	c.windows[stableGroup], c.windows[canaryGroup] = newCanaryWindow(size), newCanaryWindow(size)
+	b.canary = c
+	i18n.Logf(i18n.BalancerCanary, cfg.Percent, cfg.Selector, len(candidates[canaryGroup]), len(candidates[stableGroup]))
+}
+
+// group делит бэкенды пула (кроме удаленных) на стабильные и канареечные по селектору.
+func (c *canarySplit) group(backends []*Backend) [2][]*Backend {
+	var candidates [2][]*Backend
+	for _, backend := range backends {
+		if backend.Removed() {
+			continue
+		}
+		group := stableGroup
+		if backend.Matches(c.cfg.Selector) {
+			group = canaryGroup
+		}
+		candidates[group] = append(candidates[group], backend)
+	}
+	return candidates
+}
+
+// nextCanaryBackend выбирает группу с вероятностью canary.percent и бэкенд в ней по алгоритму пула;
+// возвращает бэкенд с его ID.
+// Если в выбранной группе нет доступных бэкендов, запрос получает другая группа; после отката
+// канареечные бэкенды не выбираются.
+func (b *Balancer) nextCanaryBackend() (*Backend, int, error) {
+	c := b.canary
+	rolledBack := c.rolledBack.Load()
+	group := stableGroup
+	if !rolledBack && rand.Intn(100) < c.cfg.Percent {
+		group = canaryGroup
+	}
+	candidates := c.candidates.Load()
+	backend, idx, err := b.pickBackend(candidates[group], &c.current[group], &c.weights[group])
+	if err == nil || rolledBack {
+		return backend, idx, err
+	}
+	other := 1 - group
+	if otherBackend, otherIdx, otherErr := b.pickBackend(candidates[other], &c.current[other], &c.weights[other]); otherErr == nil {
+		return otherBackend, otherIdx, nil
+	}
+	return backend, idx, err
+}
+
+// observe учитывает ответ бэкенда в статистике его группы и раз в секунду сравнивает группы.
+func (c *canarySplit) observe(backend *Backend, now time.Time, failed bool, latency time.Duration) {
+	group := stableGroup
+	if backend.canary {
+		group = canaryGroup
+	}
+	c.windows[group].record(now, failed, latency)
+	if second := now.Unix(); c.evaluated.Swap(second) != second {
+		c.evaluate(now)
+	}
+}
+
+// evaluate сравнивает группы за окно и при превышении порогов откатывает разделение (auto_rollback).
+func (c *canarySplit) evaluate(now time.Time) {
+	stable, canary := c.windows[stableGroup].stats(now.Unix()), c.windows[canaryGroup].stats(now.Unix())
+	reason := c.degradation(stable, canary)
+	if reason == "" || !c.cfg.AutoRollback {
+		return
+	}
+	c.mu.Lock()
+	rolledBack := c.rolledBack.CompareAndSwap(false, true)
+	if rolledBack {
+		c.rollbackAt, c.rollbackReason = now, reason
+	}
+	c.mu.Unlock()
+	if !rolledBack {
+		return
+	}
+	canaryRollbacksTotal.Inc()
+	i18n.Logf(i18n.BalancerCanaryRolledBack, reason)
+}
+
+// degradation возвращает превышенный канарейкой порог или пустую строку. Пока у одной из групп
+// меньше canary.min_requests запросов, группы не сравниваются.
+func (c *canarySplit) degradation(stable, canary CanaryGroupStats) string {
+	if stable.Requests < c.cfg.MinRequests || canary.Requests < c.cfg.MinRequests {
+		return ""
+	}
+	if c.cfg.MaxErrorRateDelta > 0 && canary.ErrorRate-stable.ErrorRate > c.cfg.MaxErrorRateDelta {
+		return i18n.T(i18n.BalancerCanaryErrorRate, canary.ErrorRate, stable.ErrorRate, c.cfg.MaxErrorRateDelta)
+	}
+	if c.cfg.MaxLatencyRatio > 0 && canary.AvgLatencyMs > stable.AvgLatencyMs*c.cfg.MaxLatencyRatio {
+		return i18n.T(i18n.BalancerCanaryLatency, canary.AvgLatencyMs, stable.AvgLatencyMs, c.cfg.MaxLatencyRatio)
+	}
+	return ""
+}
+
+// Canary возвращает сравнение стабильных и канареечных бэкендов за окно, заканчивающееся в момент now;
+// ok = false, если разделение в пуле не настроено.
+func (b *Balancer) Canary(now time.Time) (report CanaryReport, ok bool) {
+	c := b.canary
+	if c == nil {
+		return CanaryReport{}, false
+	}
+	report = CanaryReport{
+		Selector: c.cfg.Selector,
+		Percent:  c.cfg.Percent,
+		Window:   c.cfg.Window.String(),
+		Stable:   c.windows[stableGroup].stats(now.Unix()),
+		Canary:   c.windows[canaryGroup].stats(now.Unix()),
+	}
+	candidates := c.candidates.Load()
+	report.Stable.Backends = len(candidates[stableGroup])
+	report.Canary.Backends = len(candidates[canaryGroup])
+	report.Reason = c.degradation(report.Stable, report.Canary)
+	report.Degraded = report.Reason != ""
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rolledBack.Load() {
+		at := c.rollbackAt
+		report.RolledBack, report.RolledBackAt, report.RollbackReason = true, &at, c.rollbackReason
+	}
+	return report, true
+}
+
+// ResumeCanary возобновляет откаченное разделение; false - разделение в пуле не настроено.
+// Статистика групп сбрасывается: сравнение начинается заново, без ответов до отката.
+func (b *Balancer) ResumeCanary() bool {
+	c := b.canary
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rolledBack.CompareAndSwap(true, false) {
+		c.rollbackAt, c.rollbackReason = time.Time{}, ""
+		c.windows[stableGroup].reset()
+		c.windows[canaryGroup].reset()
+		i18n.Logf(i18n.BalancerCanaryResumed, c.cfg.Percent, c.cfg.Selector)
+	}
+	return true
+}
@@ -0,0 +1,25 @@
+package balancer
+
+import "net/http"
+
+// SetClientCertHeader задает заголовок, в котором бэкенды получают CN проверенного клиентского сертификата
+// (tls.client_auth.subject_header). Значение, присланное клиентом в этом заголовке, удаляется всегда, в том
+// числе для запросов без TLS, чтобы бэкенд мог ему доверять. Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetClientCertHeader(name string) {
+	b.clientCertHeader = http.CanonicalHeaderKey(name)
+}
+
+// setClientCertHeader - часть Director: заменяет заголовок субъекта клиентского сертификата.
+// Сертификаты в PeerCertificates уже проверены HTTPS-листенером (tls.client_auth).
+func (b *Balancer) setClientCertHeader(r *http.Request) {
+	if b.clientCertHeader == "" {
+		return
+	}
+	r.Header.Del(b.clientCertHeader)
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return
+	}
+	if cn := r.TLS.PeerCertificates[0].Subject.CommonName; cn != "" {
+		r.Header.Set(b.clientCertHeader, cn)
+	}
+}
@@ -0,0 +1,46 @@
+package balancer
+
+import (
+	"load-balancer/internal/i18n"
+)
+
+// HealthSnapshot возвращает статус бэкендов пула по результатам проверок: URL -> доступен.
+// Исключение после ошибки (passive_health.hold_down) не учитывается - оно не переживает перезапуск.
+func (b *Balancer) HealthSnapshot() map[string]bool {
+	snapshot := make(map[string]bool, len(b.backendList()))
+	for _, backend := range b.backendList() {
+		if backend.Removed() {
+			continue
+		}
+		backend.mux.RLock()
+		snapshot[backend.URL.String()] = backend.Alive
+		backend.mux.RUnlock()
+	}
+	return snapshot
+}
+
+// RestoreHealth восстанавливает статус бэкендов, сохраненный перед остановкой (см. HealthSnapshot):
+// бэкенды, которые были недоступны, исключаются до первой успешной активной проверки, чтобы
+// перезапуск не направлял запросы на заведомо нерабочие бэкенды. Бэкенды, которые уже проверены
+// после запуска, и бэкенды, отсутствующие в saved, не меняются. Без health_check.enabled
+// ничего не делает: исключенный бэкенд некому вернуть. Возвращает число исключенных бэкендов.
+func (b *Balancer) RestoreHealth(saved map[string]bool) int {
+	if !b.healthCheckConfig.Load().Enabled {
+		return 0
+	}
+	restored := 0
+	for _, backend := range b.backendList() {
+		alive, ok := saved[backend.URL.String()]
+		if !ok || alive || backend.Removed() {
+			continue
+		}
+		backend.mux.Lock()
+		if !backend.checked {
+			backend.setAliveLocked(false)
+			restored++
+			i18n.Logf(i18n.BalancerHealthRestored, backend.id, backend.URL)
+		}
+		backend.mux.Unlock()
+	}
+	return restored
+}
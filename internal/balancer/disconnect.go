@@ -0,0 +1,32 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+	"load-balancer/internal/requestid"
+)
+
+// StatusClientClosedRequest - статус для журнала доступа, если клиент закрыл соединение до ответа
+// (как в nginx). Клиенту он не доходит.
+const StatusClientClosedRequest = 499
+
+var clientDisconnectsTotal = metrics.NewCounter("balancer_client_disconnects_total",
+	"Количество запросов, прерванных клиентом до получения ответа (не считаются ошибками бэкендов).")
+
+// ClientDisconnected проверяет, что запрос прерван клиентом: контекст запроса отменяется сервером,
+// когда клиент закрывает соединение, и вместе с ним отменяется запрос к бэкенду. Такой запрос
+// учитывается отдельно и не считается ошибкой бэкенда (пассивная проверка его не учитывает).
+// Если запрос прерван, записывает StatusClientClosedRequest и возвращает true.
+func ClientDisconnected(w http.ResponseWriter, r *http.Request) bool {
+	if !errors.Is(r.Context().Err(), context.Canceled) || backendTimedOut(r) {
+		return false
+	}
+	clientDisconnectsTotal.Inc()
+	i18n.Logf(i18n.BalancerClientDisconnected, r.Method, r.URL.Path, requestid.FromContext(r.Context()))
+	w.WriteHeader(StatusClientClosedRequest)
+	return true
+}
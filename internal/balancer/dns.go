@@ -0,0 +1,143 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var dnsLookupErrorsTotal = metrics.NewCounter("balancer_dns_lookup_errors_total",
+	"Количество неудачных разрешений имен бэкендов через резолвер пула (dns.<пул>).")
+
+// backendDialer устанавливает соединения с бэкендами с настройками http.DefaultTransport.
+var backendDialer = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+// poolResolver разрешает имена бэкендов пула через DNS-серверы из dns.<пул> и хранит адреса cache_ttl
+// (см. config.DNSConfig).
+type poolResolver struct {
+	resolver *net.Resolver
+	servers  []string
+	next     atomic.Uint32 // Сервер для следующего запроса: повторы распределяются по серверам.
+	timeout  time.Duration
+	ttl      time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedAddrs // Хост -> адреса.
+}
+
+type cachedAddrs struct {
+	addrs   []string
+	expires time.Time
+}
+
+// SetDNS задает разрешение имен бэкендов пула: DNS-серверы, таймаут и кэш адресов. Им пользуются
+// проксирование, активные проверки и повторное разрешение после неудачной проверки.
+// Проверки запускаются уже в New, поэтому до вызова первая из них может использовать системный резолвер.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetDNS(cfg config.DNSConfig) {
+	if len(cfg.Servers) == 0 && cfg.Timeout <= 0 && cfg.CacheTTL <= 0 {
+		return
+	}
+	r := &poolResolver{
+		servers: cfg.Servers,
+		timeout: cfg.Timeout,
+		ttl:     cfg.CacheTTL,
+		cache:   make(map[string]cachedAddrs),
+	}
+	if r.timeout <= 0 {
+		r.timeout = config.DefaultDNSTimeout
+	}
+	r.resolver = net.DefaultResolver
+	source := i18n.T(i18n.BalancerDNSSystem)
+	if len(r.servers) > 0 {
+		r.resolver = &net.Resolver{PreferGo: true, Dial: r.dialServer}
+		source = strings.Join(r.servers, ", ")
+	}
+	b.dns.Store(r)
+	b.SetResolver(r)
+	i18n.Logf(i18n.BalancerDNS, source, r.timeout, r.ttl)
+}
+
+// dialServer - net.Resolver.Dial: вместо системных серверов опрашиваются серверы пула по очереди.
+func (r *poolResolver) dialServer(ctx context.Context, network, _ string) (net.Conn, error) {
+	server := r.servers[int(r.next.Add(1)-1)%len(r.servers)]
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, server)
+}
+
+// LookupHost возвращает адреса хоста из кэша или разрешает имя за timeout.
+func (r *poolResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if r.ttl > 0 {
+		r.mu.Lock()
+		cached, ok := r.cache[host]
+		r.mu.Unlock()
+		if ok && time.Now().Before(cached.expires) {
+			return cached.addrs, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	addrs, err := r.resolver.LookupHost(ctx, host)
+	if err != nil {
+		dnsLookupErrorsTotal.Inc()
+		return nil, err
+	}
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.cache[host] = cachedAddrs{addrs: addrs, expires: time.Now().Add(r.ttl)}
+		r.mu.Unlock()
+	}
+	return addrs, nil
+}
+
+// forget удаляет адреса хоста из кэша: после неудачной проверки имя разрешается заново.
+func (r *poolResolver) forget(host string) {
+	r.mu.Lock()
+	delete(r.cache, host)
+	r.mu.Unlock()
+}
+
+// dial разрешает имя хоста резолвером пула и соединяется с его адресами по очереди до первого успешного.
+func (r *poolResolver) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return backendDialer.DialContext(ctx, network, addr)
+	}
+	addrs, err := r.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	var errs []error
+	for _, ip := range addrs {
+		conn, err := backendDialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// dialContext соединяется с бэкендом: через резолвер пула, если задан dns.<пул>, иначе как http.DefaultTransport.
+func (b *Balancer) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if r := b.dns.Load(); r != nil {
+		return r.dial(ctx, network, addr)
+	}
+	return backendDialer.DialContext(ctx, network, addr)
+}
@@ -0,0 +1,48 @@
+package balancer
+
+import (
+	"time"
+
+	"load-balancer/internal/i18n"
+)
+
+// Drained сообщает, что бэкенд выведен из балансировки вручную (см. SetDrained).
+func (b *Backend) Drained() bool {
+	return b.drained.Load()
+}
+
+// Removed сообщает, что бэкенд удален из пула (см. RemoveBackend).
+func (b *Backend) Removed() bool {
+	return b.removed.Load()
+}
+
+// selectable проверяет, что бэкенд может получить новый запрос: он доступен, не выведен вручную
+// и не удален из пула.
+func (b *Backend) selectable() bool {
+	return !b.drained.Load() && !b.removed.Load() && b.IsAlive()
+}
+
+// SetDrained выводит бэкенд (ID или URL) из балансировки или возвращает его. Выведенный бэкенд
+// не получает новых запросов, но запросы в обработке завершаются, а активные проверки продолжаются:
+// статус Alive от вывода не зависит. Вернувшийся бэкенд проходит passive_health.slow_start.
+// Возвращает ID бэкенда или ErrBackendNotFound.
+func (b *Balancer) SetDrained(backend string, drained bool) (int, error) {
+	if backend == "" {
+		return -1, i18n.Errorf(i18n.HealthCheckBackendNotFoundWrap, ErrBackendNotFound, backend)
+	}
+	targets, err := b.findBackends(backend)
+	if err != nil {
+		return -1, err
+	}
+	target := targets[0]
+	if target.drained.Swap(drained) == drained {
+		return target.id, nil
+	}
+	if drained {
+		i18n.Logf(i18n.BalancerBackendDrained, target.id, target.URL, target.InFlight())
+	} else {
+		target.admittedAt.Store(time.Now().UnixNano())
+		i18n.Logf(i18n.BalancerBackendEnabled, target.id, target.URL)
+	}
+	return target.id, nil
+}
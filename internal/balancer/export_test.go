@@ -0,0 +1,6 @@
+package balancer
+
+// PoolSize возвращает длину списка бэкендов пула вместе с удаленными, которые еще не убраны из него.
+func (b *Balancer) PoolSize() int {
+	return len(b.backendList())
+}
@@ -0,0 +1,90 @@
+package balancer
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+
+	"load-balancer/internal/headers"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/response"
+)
+
+// EnableGRPC включает режим gRPC: запросы с Content-Type application/grpc проксируются
+// по HTTP/2 (h2c для http:// бэкендов), а ошибки возвращаются статусом в grpc-status
+// вместо JSON-ответа. Каждый вызов (поток HTTP/2) балансируется отдельно, поэтому
+// одно клиентское соединение распределяется по всем бэкендам.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) EnableGRPC() {
+	for _, backend := range b.backendList() {
+		b.setGRPCProxy(backend)
+	}
+	b.grpc = true
+	i18n.Logf(i18n.BalancerGRPCEnabled, len(b.backendList()))
+}
+
+// setGRPCProxy создает прокси по HTTP/2 для бэкенда пула.
+func (b *Balancer) setGRPCProxy(backend *Backend) {
+	proxy := httputil.NewSingleHostReverseProxy(backend.URL)
+	backend.grpcTransport = newBackendTransport(b.withDialer(newGRPCTransport))
+	proxy.Transport = backend.grpcTransport
+	proxy.Director = b.director(backend.id, backend.URL)
+	proxy.FlushInterval = -1 // Потоковые вызовы: сообщения отправляются клиенту без буферизации
+	proxy.ErrorHandler = backend.ReverseProxy.ErrorHandler
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		headers.RemoveHopByHop(resp.Header)
+		b.observeResponse(backend, resp.StatusCode)
+		return grpcModifyResponse(resp)
+	}
+	backend.grpcProxy = proxy
+}
+
+// newGRPCTransport создает транспорт, использующий только HTTP/2: gRPC не работает поверх HTTP/1.1.
+// Для http:// бэкендов используется HTTP/2 без TLS (h2c, prior knowledge).
+func newGRPCTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Protocols = new(http.Protocols)
+	transport.Protocols.SetHTTP2(true)
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	return transport
+}
+
+// grpcModifyResponse превращает не-gRPC ответ бэкенда (например, HTML-страницу 502 от промежуточного прокси)
+// в ответ "Trailers-Only" с кодом, полученным по HTTP-статусу.
+func grpcModifyResponse(resp *http.Response) error {
+	if resp.Header.Get("Grpc-Status") != "" ||
+		(resp.StatusCode == http.StatusOK && response.IsGRPCContentType(resp.Header.Get("Content-Type"))) {
+		return nil
+	}
+
+	status := response.GRPCStatusFromHTTP(resp.StatusCode)
+	message := i18n.T(i18n.BalancerGRPCUpstreamStatus, resp.StatusCode)
+	i18n.Logf(i18n.BalancerGRPCUpstreamMapped, resp.Request.URL.Path, resp.StatusCode, status)
+
+	resp.Body.Close()
+	resp.Body = http.NoBody
+	resp.ContentLength = 0
+	resp.StatusCode = http.StatusOK
+	resp.Status = http.StatusText(http.StatusOK)
+	resp.Header = http.Header{
+		"Content-Type": {response.GRPCContentType},
+		"Grpc-Status":  {strconv.Itoa(int(status))},
+		"Grpc-Message": {response.EncodeGRPCMessage(message)},
+	}
+	return nil
+}
+
+// respondWithError отвечает ошибкой в формате, понятном клиенту: статусом gRPC для вызовов gRPC
+// в режиме gRPC и JSON-ответом для остальных запросов.
+func (b *Balancer) respondWithError(w http.ResponseWriter, r *http.Request, statusCode int, errorCode response.ErrorCode, message string) {
+	if !b.grpc || !response.IsGRPC(r) {
+		response.RespondWithError(w, statusCode, errorCode, message)
+		return
+	}
+
+	status := response.GRPCStatusFromHTTP(statusCode)
+	if errorCode == response.CodeRateLimited {
+		status = response.GRPCResourceExhausted
+	}
+	response.RespondWithGRPCError(w, status, errorCode, message)
+}
@@ -0,0 +1,174 @@
+package balancer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/response"
+)
+
+// grpcHealthCheckPath - метод стандартного протокола проверки состояния gRPC.
+const grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+
+// grpcServing - статус SERVING в grpc.health.v1.HealthCheckResponse.
+const grpcServing = 1
+
+// grpcServingStatuses - имена статусов HealthCheckResponse.ServingStatus для сообщений об ошибках.
+var grpcServingStatuses = []string{"UNKNOWN", "SERVING", "NOT_SERVING", "SERVICE_UNKNOWN"}
+
+var (
+	errGRPCNoStatus   = i18n.NewError(i18n.HealthCheckGRPCNoStatus)
+	errGRPCBadFrame   = i18n.NewError(i18n.HealthCheckGRPCBadFrame)
+	errGRPCBadMessage = i18n.NewError(i18n.HealthCheckGRPCBadMessage)
+)
+
+// healthCheckClients - клиенты проверок состояния: HTTP/1.1 для проверок http и HTTP/2 (h2c для http://)
+// для проверок grpc.
+type healthCheckClients struct {
+	http *http.Client
+	grpc *http.Client
+}
+
+// closeIdleConnections закрывает простаивающие соединения обоих клиентов.
+func (c healthCheckClients) closeIdleConnections() {
+	c.http.CloseIdleConnections()
+	c.grpc.CloseIdleConnections()
+}
+
+// newGRPCHealthCheckClient создает клиент HTTP/2 для проверок grpc.
+func (b *Balancer) newGRPCHealthCheckClient() *http.Client {
+	transport := newGRPCTransport()
+	transport.DialContext = b.dialContext
+	transport.TLSClientConfig = b.tlsClientConfig()
+	transport.IdleConnTimeout = 30 * time.Second
+	return &http.Client{Transport: transport}
+}
+
+// checkGRPC вызывает grpc.health.v1.Health/Check на бэкенде: бэкенд исправен, если вызов завершился
+// с grpc-status 0 и сервис health_check.grpc_service в статусе SERVING.
+func (b *Balancer) checkGRPC(ctx context.Context, backend *Backend, client *http.Client, cfg *config.HealthCheckConfig) error {
+	checkURL := (&url.URL{Scheme: backend.URL.Scheme, Host: backend.URL.Host, Path: grpcHealthCheckPath}).String()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, checkURL, bytes.NewReader(grpcHealthRequest(cfg.GRPCService)))
+	if err != nil {
+		return i18n.Errorf(i18n.HealthCheckRequestFailed, checkURL, err)
+	}
+	req.Header.Set("Content-Type", response.GRPCContentType)
+	req.Header.Set("Te", "trailers")
+	if auth := b.upstreamAuth.Load(); auth != nil {
+		auth.apply(req, false, time.Now())
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return i18n.Errorf(i18n.HealthCheckUnreachable, checkURL, err)
+	}
+	defer resp.Body.Close()
+	// Трейлеры доступны только после чтения тела до конца
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return i18n.Errorf(i18n.HealthCheckUnreachable, checkURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return i18n.Errorf(i18n.HealthCheckBadStatus, checkURL, resp.StatusCode)
+	}
+
+	// Ответ без сообщения ("Trailers-Only") передает статус в заголовках
+	status := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	switch status {
+	case "":
+		return i18n.Errorf(i18n.HealthCheckGRPCBadResponse, checkURL, errGRPCNoStatus)
+	case "0":
+	default:
+		return i18n.Errorf(i18n.HealthCheckGRPCStatus, checkURL, status, message)
+	}
+
+	serving, err := parseGRPCHealthResponse(body)
+	if err != nil {
+		return i18n.Errorf(i18n.HealthCheckGRPCBadResponse, checkURL, err)
+	}
+	if serving != grpcServing {
+		name := grpcServingStatuses[0]
+		if serving < uint64(len(grpcServingStatuses)) {
+			name = grpcServingStatuses[serving]
+		}
+		return i18n.Errorf(i18n.HealthCheckGRPCNotServing, checkURL, name, cfg.GRPCService)
+	}
+	return nil
+}
+
+// grpcHealthRequest кодирует HealthCheckRequest{service} в кадр сообщения gRPC без сжатия.
+func grpcHealthRequest(service string) []byte {
+	var message []byte
+	if service != "" {
+		message = append(message, 0x0a) // Поле 1 (service), тип length-delimited
+		message = binary.AppendUvarint(message, uint64(len(service)))
+		message = append(message, service...)
+	}
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// parseGRPCHealthResponse извлекает поле status (1) из кадра с HealthCheckResponse.
+// Отсутствующее поле - значение по умолчанию UNKNOWN (0).
+func parseGRPCHealthResponse(body []byte) (uint64, error) {
+	if len(body) < 5 || body[0] != 0 {
+		return 0, errGRPCBadFrame
+	}
+	length := binary.BigEndian.Uint32(body[1:5])
+	if uint64(len(body)-5) < uint64(length) {
+		return 0, errGRPCBadFrame
+	}
+	message := body[5 : 5+length]
+
+	var status uint64
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return 0, errGRPCBadMessage
+		}
+		message = message[n:]
+		switch key & 7 {
+		case 0: // varint
+			value, n := binary.Uvarint(message)
+			if n <= 0 {
+				return 0, errGRPCBadMessage
+			}
+			if key>>3 == 1 {
+				status = value
+			}
+			message = message[n:]
+		case 1: // fixed64
+			if len(message) < 8 {
+				return 0, errGRPCBadMessage
+			}
+			message = message[8:]
+		case 2: // length-delimited
+			size, n := binary.Uvarint(message)
+			if n <= 0 || uint64(len(message)-n) < size {
+				return 0, errGRPCBadMessage
+			}
+			message = message[n+int(size):]
+		case 5: // fixed32
+			if len(message) < 4 {
+				return 0, errGRPCBadMessage
+			}
+			message = message[4:]
+		default:
+			return 0, errGRPCBadMessage
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,47 @@
+package balancer
+
+import "load-balancer/internal/hashring"
+
+// backendRing - кольцо согласованного хеширования и бэкенды, по которым оно построено:
+// узел кольца с номером i - бэкенд backends[i].
+type backendRing struct {
+	ring     *hashring.Ring
+	backends []*Backend
+}
+
+// buildRing строит кольцо согласованного хеширования по бэкендам пула с учетом их весов.
+// Удаленные бэкенды (RemoveBackend) остаются в кольце, пока не завершатся их запросы, и пропускаются
+// при обходе, как недоступные: их клиенты переходят к следующим по кольцу. Позиция бэкенда на кольце
+// зависит только от его URL, поэтому клиенты остальных бэкендов не перемещаются.
+func (b *Balancer) buildRing() {
+	backends := b.backendList()
+	names := make([]string, len(backends))
+	weights := make([]int, len(backends))
+	for i, backend := range backends {
+		names[i] = backend.URL.String()
+		weights[i] = backend.weight
+	}
+	b.ring.Store(&backendRing{ring: hashring.New(names, weights, hashring.DefaultReplicas), backends: backends})
+}
+
+// getHashedHealthyBackend выбирает бэкенд для ключа клиента по кольцу согласованного хеширования
+// среди подходящих под селектор и возвращает его с ID. Если бэкенд клиента недоступен, запрос получает
+// следующий по кольцу, а после восстановления клиент возвращается на свой бэкенд. Почти заполненные
+// бэкенды пропускаются так же.
+func (b *Balancer) getHashedHealthyBackend(key string, selector map[string]string) (*Backend, int, error) {
+	pick := saturationPick{threshold: b.saturationThreshold}
+	ring := b.ring.Load()
+	var chosen *Backend
+	ring.ring.Walk(key, func(idx int) bool {
+		backend := ring.backends[idx]
+		if backend.Matches(selector) && backend.selectable() && pick.consider(backend) {
+			chosen = backend
+			return false
+		}
+		return true
+	})
+	if chosen == nil {
+		return pick.result()
+	}
+	return chosen, chosen.id, nil
+}
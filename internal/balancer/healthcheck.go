@@ -0,0 +1,476 @@
+package balancer
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+)
+
+// defaultHealthCheckWorkers - максимальное число воркеров проверок, если health_check.workers не задан.
+const defaultHealthCheckWorkers = 8
+
+// healthCheckSpreadTicks - на сколько тиков делится интервал, когда проверки бэкендов распределяются
+// внутри него (health_check.jitter, health_check.stagger).
+const healthCheckSpreadTicks = 10
+
+var (
+	// ErrHealthChecksDisabled возвращается CheckNow, если health_check.enabled выключен.
+	ErrHealthChecksDisabled = i18n.NewError(i18n.HealthCheckDisabled)
+	// ErrBackendNotFound возвращается CheckNow для неизвестного бэкенда.
+	ErrBackendNotFound = i18n.NewError(i18n.HealthCheckBackendNotFound)
+)
+
+// HealthCheckResult - результат принудительной проверки одного бэкенда.
+type HealthCheckResult struct {
+	Index      int    `json:"index"` // ID бэкенда (см. Backend.ID)
+	URL        string `json:"url"`
+	Healthy    bool   `json:"healthy"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// healthState хранит состояние активных проверок одного бэкенда.
+type healthState struct {
+	mu                  sync.Mutex
+	consecutiveFailures int       // Количество неудачных проверок подряд.
+	nextCheck           time.Time // Не проверять бэкенд раньше этого момента (backoff).
+	inFlight            bool      // Проверка уже поставлена в очередь или выполняется.
+	lastCheck           time.Time // Время завершения последней проверки (плановой или внеочередной).
+	checkError          string    // Ошибка последней проверки; пусто, если бэкенд исправен.
+
+	// Подавление повторов в логе: одинаковые ошибки подряд выводятся раз в log_repeat_every проверок.
+	lastError    string // Текст последней выведенной в лог ошибки.
+	suppressed   int    // Сколько таких же ошибок не выведено с момента последней записи.
+	failedChecks int    // Неудачных плановых проверок подряд (для сообщения о восстановлении).
+}
+
+// tryBegin помечает проверку как начатую, если бэкенд пора проверять и проверка еще не выполняется.
+func (hs *healthState) tryBegin(now time.Time) bool {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.inFlight || now.Before(hs.nextCheck) {
+		return false
+	}
+	hs.inFlight = true
+	return true
+}
+
+// cancel снимает отметку о начатой проверке, если ее не удалось поставить в очередь.
+func (hs *healthState) cancel() {
+	hs.mu.Lock()
+	hs.inFlight = false
+	hs.mu.Unlock()
+}
+
+// finish фиксирует результат проверки и вычисляет время следующей проверки.
+// После n неудач подряд следующая проверка откладывается на interval*2^(n-1), но не более max_backoff,
+// плюс случайная добавка до jitter.
+// Возвращает количество неудач подряд и задержку до следующей проверки.
+func (hs *healthState) finish(checkErr error, cfg *config.HealthCheckConfig) (int, time.Duration) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	hs.inFlight = false
+	return hs.recordLocked(checkErr, cfg)
+}
+
+// record фиксирует результат внеочередной проверки, не трогая отметку о проверке из очереди.
+func (hs *healthState) record(checkErr error, cfg *config.HealthCheckConfig) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	hs.recordLocked(checkErr, cfg)
+}
+
+// recordLocked обновляет счетчик неудач и время следующей проверки. Вызывается под hs.mu.
+func (hs *healthState) recordLocked(checkErr error, cfg *config.HealthCheckConfig) (int, time.Duration) {
+	hs.lastCheck = time.Now()
+	if checkErr == nil {
+		hs.consecutiveFailures = 0
+		hs.checkError = ""
+	} else {
+		hs.consecutiveFailures++
+		hs.checkError = checkErr.Error()
+	}
+
+	interval, maxBackoff := cfg.Interval, cfg.MaxBackoff
+	delay := interval
+	if hs.consecutiveFailures > 1 && maxBackoff > interval {
+		for i := 1; i < hs.consecutiveFailures && delay < maxBackoff; i++ {
+			delay *= 2
+		}
+		delay = min(delay, maxBackoff)
+	}
+	if cfg.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(cfg.Jitter)))
+	}
+
+	// Вычитаем половину тика, чтобы проверка попадала на ближайший тик после задержки.
+	hs.nextCheck = time.Now().Add(delay - healthCheckTick(cfg)/2)
+	return hs.consecutiveFailures, delay
+}
+
+// delayUntil откладывает следующую плановую проверку до момента at.
+func (hs *healthState) delayUntil(at time.Time) {
+	hs.mu.Lock()
+	hs.nextCheck = at
+	hs.mu.Unlock()
+}
+
+// healthCheckTick возвращает период цикла проверок: interval или его доля, если проверки бэкендов
+// распределяются внутри интервала.
+func healthCheckTick(cfg *config.HealthCheckConfig) time.Duration {
+	if cfg.Spread() {
+		return cfg.Interval / healthCheckSpreadTicks
+	}
+	return cfg.Interval
+}
+
+// logResult решает, писать ли результат плановой проверки в лог.
+// Первая ошибка и ошибка с новым текстом выводятся сразу; одинаковые повторы - раз в repeatEvery проверок
+// вместе с числом повторов с прошлой записи (repeats). Если часть ошибок не попала в лог,
+// при восстановлении возвращается общее число неудач подряд (recoveredAfter).
+func (hs *healthState) logResult(checkErr error, repeatEvery int) (logFailure bool, repeats, recoveredAfter int) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if checkErr == nil {
+		if hs.suppressed > 0 {
+			recoveredAfter = hs.failedChecks
+		}
+		hs.lastError, hs.suppressed, hs.failedChecks = "", 0, 0
+		return false, 0, recoveredAfter
+	}
+
+	hs.failedChecks++
+	msg := checkErr.Error()
+	switch {
+	case msg != hs.lastError:
+		hs.lastError, hs.suppressed = msg, 0
+		return true, 0, 0
+	case repeatEvery <= 1 || hs.suppressed+1 >= repeatEvery:
+		repeats = hs.suppressed + 1
+		hs.suppressed = 0
+		return true, repeats, 0
+	default:
+		hs.suppressed++
+		return false, 0, 0
+	}
+}
+
+// startHealthChecks запускает периодические проверки состояния для всех бэкендов.
+// Проверки выполняет ограниченный пул воркеров, а не отдельная горутина на каждый бэкенд.
+// Параметры проверок читаются при каждом использовании, поэтому их можно менять без перезапуска цикла.
+func (b *Balancer) startHealthChecks() {
+	workers := b.healthCheckWorkers()
+	cfg := b.healthCheckConfig.Load()
+
+	i18n.Logf(i18n.HealthCheckStarting, cfg.Interval, cfg.Timeout, cfg.Path, workers, cfg.MaxBackoff)
+
+	clients := b.newHealthCheckClients()
+	defer clients.closeIdleConnections()
+
+	// Очередь не длиннее числа бэкендов: каждый бэкенд находится в ней не более одного раза.
+	jobs := make(chan *Backend, len(b.backendList()))
+	var workersWG sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		workersWG.Add(1)
+		go func() {
+			defer workersWG.Done()
+			b.healthCheckWorker(clients, jobs)
+		}()
+	}
+
+	ticker := time.NewTicker(healthCheckTick(cfg))
+	defer ticker.Stop()
+
+	if cfg.Stagger {
+		b.staggerHealthChecks(cfg.Interval)
+	}
+	b.performChecks(jobs)
+
+	// Запускаем цикл проверок
+	for {
+		select {
+		case <-ticker.C:
+			b.performChecks(jobs)
+		case <-b.healthCheckReload:
+			// Новый интервал начинает действовать сразу, не дожидаясь старого тика
+			ticker.Reset(healthCheckTick(b.healthCheckConfig.Load()))
+		case <-b.healthCheckStopChan:
+			i18n.Logf(i18n.HealthCheckStopSignal)
+			close(jobs)
+			workersWG.Wait()
+			return
+		}
+	}
+}
+
+// staggerHealthChecks распределяет первые проверки бэкендов равномерно по interval (health_check.stagger),
+// чтобы при старте бэкенды не проверялись все одновременно.
+func (b *Balancer) staggerHealthChecks(interval time.Duration) {
+	now := time.Now()
+	for i, backend := range b.backendList() {
+		backend.health.delayUntil(now.Add(interval * time.Duration(i) / time.Duration(len(b.backendList()))))
+	}
+	i18n.Logf(i18n.HealthCheckStaggered, len(b.backendList()), interval)
+}
+
+// HealthCheckConfig возвращает текущие параметры проверок состояния.
+func (b *Balancer) HealthCheckConfig() config.HealthCheckConfig {
+	return *b.healthCheckConfig.Load()
+}
+
+// UpdateHealthCheckConfig меняет интервал, таймаут, путь и backoff проверок без перезапуска цикла проверок.
+// cfg должен быть разобран (config.HealthCheckConfig.Parse). Включение и выключение проверок,
+// а также размер пула воркеров во время работы не меняются.
+func (b *Balancer) UpdateHealthCheckConfig(cfg config.HealthCheckConfig) error {
+	current := b.healthCheckConfig.Load()
+	if !current.Enabled {
+		return ErrHealthChecksDisabled
+	}
+	if cfg.Interval <= 0 || cfg.Timeout <= 0 {
+		return i18n.Errorf(i18n.HealthCheckInvalidConfig, cfg.Interval, cfg.Timeout)
+	}
+
+	cfg.Enabled = true
+	if cfg.Workers != current.Workers {
+		i18n.Logf(i18n.HealthCheckWorkersNotReloaded, current.Workers, cfg.Workers)
+		cfg.Workers = current.Workers
+	}
+	b.healthCheckConfig.Store(&cfg)
+
+	// Будим цикл проверок, чтобы он перезапустил тикер с новым интервалом
+	select {
+	case b.healthCheckReload <- struct{}{}:
+	default:
+	}
+	i18n.Logf(i18n.HealthCheckReloaded, cfg.Interval, cfg.Timeout, cfg.Path, cfg.MaxBackoff)
+	return nil
+}
+
+// healthCheckWorkers возвращает размер пула воркеров проверок.
+func (b *Balancer) healthCheckWorkers() int {
+	if workers := b.healthCheckConfig.Load().Workers; workers > 0 {
+		return workers
+	}
+	return min(len(b.backendList()), defaultHealthCheckWorkers)
+}
+
+// newHealthCheckClients создает HTTP-клиенты для проверок состояния.
+// Таймаут задается контекстом каждого запроса, чтобы его можно было менять во время работы.
+func (b *Balancer) newHealthCheckClients() healthCheckClients {
+	return healthCheckClients{
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext:         b.dialContext,
+				TLSClientConfig:     b.tlsClientConfig(),
+				MaxIdleConnsPerHost: 5,
+				IdleConnTimeout:     30 * time.Second,
+			},
+		},
+		grpc: b.newGRPCHealthCheckClient(),
+	}
+}
+
+// CheckNow немедленно проверяет бэкенды, не дожидаясь следующего тика и игнорируя backoff,
+// и возвращает результаты. backend - ID или URL бэкенда; пустая строка - все бэкенды.
+// Результат проверки применяется так же, как в плановой проверке (статус и backoff).
+func (b *Balancer) CheckNow(backend string) ([]HealthCheckResult, error) {
+	cfg := b.healthCheckConfig.Load()
+	if !cfg.Enabled {
+		return nil, ErrHealthChecksDisabled
+	}
+
+	targets, err := b.findBackends(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	clients := b.newHealthCheckClients()
+	defer clients.closeIdleConnections()
+
+	results := make([]HealthCheckResult, len(targets))
+	sem := make(chan struct{}, b.healthCheckWorkers())
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			checkErr := b.checkBackendHealth(target, clients)
+			target.health.record(checkErr, cfg)
+
+			results[i] = HealthCheckResult{
+				Index:      target.id,
+				URL:        target.URL.String(),
+				Healthy:    checkErr == nil,
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+			if checkErr != nil {
+				results[i].Error = checkErr.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	healthy := 0
+	for _, result := range results {
+		if result.Healthy {
+			healthy++
+		}
+	}
+	i18n.Logf(i18n.HealthCheckForced, healthy, len(results))
+	return results, nil
+}
+
+// findBackends возвращает бэкенды по ID или URL; пустая строка - все бэкенды.
+// Удаленные бэкенды (RemoveBackend) не находятся.
+func (b *Balancer) findBackends(backend string) ([]*Backend, error) {
+	backends := b.backendList()
+	if backend == "" {
+		found := make([]*Backend, 0, len(backends))
+		for _, candidate := range backends {
+			if !candidate.Removed() {
+				found = append(found, candidate)
+			}
+		}
+		return found, nil
+	}
+
+	if id, err := strconv.Atoi(backend); err == nil {
+		if candidate := b.Backend(id); candidate != nil {
+			return []*Backend{candidate}, nil
+		}
+	} else {
+		wanted := strings.TrimSuffix(backend, "/")
+		for _, candidate := range backends {
+			if !candidate.Removed() && strings.TrimSuffix(candidate.URL.String(), "/") == wanted {
+				return []*Backend{candidate}, nil
+			}
+		}
+	}
+	return nil, i18n.Errorf(i18n.HealthCheckBackendNotFoundWrap, ErrBackendNotFound, backend)
+}
+
+// healthCheckWorker выполняет проверки из очереди, пока она не будет закрыта.
+func (b *Balancer) healthCheckWorker(clients healthCheckClients, jobs <-chan *Backend) {
+	for backend := range jobs {
+		err := b.checkBackendHealth(backend, clients)
+		cfg := b.healthCheckConfig.Load()
+		failures, delay := backend.health.finish(err, cfg)
+		if err != nil {
+			b.reresolve(backend, cfg.Timeout)
+		}
+		logFailure, repeats, recoveredAfter := backend.health.logResult(err, cfg.LogRepeatEvery)
+		switch {
+		case logFailure && repeats > 0:
+			i18n.Logf(i18n.HealthCheckFailedRepeated, err, repeats, failures, delay)
+		case logFailure:
+			i18n.Logf(i18n.HealthCheckFailed, err, failures, delay)
+		case recoveredAfter > 0:
+			i18n.Logf(i18n.HealthCheckRecovered, backend.URL, recoveredAfter)
+		}
+	}
+}
+
+// performChecks ставит в очередь проверки бэкендов, для которых подошло время.
+// Бэкенды в backoff и бэкенды, проверка которых еще не завершилась, пропускаются.
+func (b *Balancer) performChecks(jobs chan<- *Backend) {
+	now := time.Now()
+	queued := 0
+	for _, backend := range b.backendList() {
+		if backend.Removed() || !backend.health.tryBegin(now) {
+			continue
+		}
+		select {
+		case jobs <- backend:
+			queued++
+		default:
+			// Очередь заполнена - проверим на следующем тике
+			backend.health.cancel()
+		}
+	}
+	if queued > 0 {
+		i18n.Logf(i18n.HealthCheckCycle, queued)
+	}
+}
+
+// checkBackendHealth выполняет проверку состояния одного бэкенда способом из health_check.type
+// и обновляет его статус. Возвращает ошибку, если бэкенд признан нерабочим.
+func (b *Balancer) checkBackendHealth(backend *Backend, clients healthCheckClients) error {
+	cfg := b.healthCheckConfig.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	var err error
+	switch cfg.Type {
+	case config.HealthCheckTCP:
+		err = b.checkTCP(ctx, backend)
+	case config.HealthCheckGRPC:
+		err = b.checkGRPC(ctx, backend, clients.grpc, cfg)
+	default:
+		err = b.checkHTTP(ctx, backend, clients.http, cfg)
+	}
+	backend.setCheckedAlive(err == nil)
+	return err
+}
+
+// checkTCP проверяет, что бэкенд принимает TCP-соединения: для бэкендов без HTTP-эндпоинта проверки.
+// Порт по умолчанию определяется схемой URL бэкенда.
+func (b *Balancer) checkTCP(ctx context.Context, backend *Backend) error {
+	port := backend.URL.Port()
+	if port == "" {
+		port = "80"
+		if backend.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	addr := net.JoinHostPort(backend.URL.Hostname(), port)
+
+	conn, err := b.dialContext(ctx, "tcp", addr)
+	if err != nil {
+		return i18n.Errorf(i18n.HealthCheckDialFailed, addr, err)
+	}
+	conn.Close()
+	return nil
+}
+
+// checkHTTP проверяет бэкенд запросом GET path и сверяет ответ с ожидаемым (см. checkResponse).
+func (b *Balancer) checkHTTP(ctx context.Context, backend *Backend, client *http.Client, cfg *config.HealthCheckConfig) error {
+	checkURL := backend.URL.JoinPath(cfg.Path).String()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checkURL, nil)
+	if err != nil {
+		return i18n.Errorf(i18n.HealthCheckRequestFailed, checkURL, err)
+	}
+	// Бэкенд, проверяющий учетные данные балансировщика, не должен отклонять его проверки
+	if auth := b.upstreamAuth.Load(); auth != nil {
+		auth.apply(req, false, time.Now())
+	}
+
+	// Отправляем GET-запрос
+	resp, err := client.Do(req)
+	if err != nil {
+		// Ошибка может быть связана с сетью, таймаутом или другими проблемами
+		return i18n.Errorf(i18n.HealthCheckUnreachable, checkURL, err)
+	}
+	defer resp.Body.Close()
+	// Тело читается и без expected_body/expected_json, чтобы соединение вернулось в пул и переиспользовалось
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return checkResponse(cfg, checkURL, resp.StatusCode, body)
+}
@@ -0,0 +1,75 @@
+package balancer
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+)
+
+// checkResponse проверяет ответ HTTP-проверки: код из expected_status (без него - любой 2xx),
+// подстроку expected_body и поля expected_json. Бэкенд, отвечающий 200 с признаком неисправности
+// в теле, признается нерабочим.
+func checkResponse(cfg *config.HealthCheckConfig, checkURL string, status int, body []byte) error {
+	if len(cfg.ExpectedStatus) > 0 {
+		if !slices.Contains(cfg.ExpectedStatus, status) {
+			return i18n.Errorf(i18n.HealthCheckUnexpectedStatus, checkURL, status, cfg.ExpectedStatus)
+		}
+	} else if status < 200 || status >= 300 {
+		return i18n.Errorf(i18n.HealthCheckBadStatus, checkURL, status)
+	}
+
+	if cfg.ExpectedBody != "" && !bytes.Contains(body, []byte(cfg.ExpectedBody)) {
+		return i18n.Errorf(i18n.HealthCheckBodyMismatch, checkURL, cfg.ExpectedBody)
+	}
+
+	if len(cfg.ExpectedJSON) == 0 {
+		return nil
+	}
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return i18n.Errorf(i18n.HealthCheckBadJSON, checkURL, err)
+	}
+	// Поля проверяются в порядке имен, чтобы при нескольких расхождениях ошибка была одной и той же
+	// и повторы подавлялись в логе (log_repeat_every)
+	fields := make([]string, 0, len(cfg.ExpectedJSON))
+	for field := range cfg.ExpectedJSON {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	for _, field := range fields {
+		value, ok := lookupJSON(doc, field)
+		if !ok {
+			return i18n.Errorf(i18n.HealthCheckJSONMissing, checkURL, field)
+		}
+		if expected := cfg.ExpectedJSON[field]; value != expected {
+			return i18n.Errorf(i18n.HealthCheckJSONMismatch, checkURL, field, value, expected)
+		}
+	}
+	return nil
+}
+
+// lookupJSON возвращает значение поля по пути через точку: строку как есть, остальные значения
+// в записи JSON. false - поля нет.
+func lookupJSON(doc any, path string) (string, bool) {
+	for _, key := range strings.Split(path, ".") {
+		object, ok := doc.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if doc, ok = object[key]; !ok {
+			return "", false
+		}
+	}
+	if s, ok := doc.(string); ok {
+		return s, true
+	}
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
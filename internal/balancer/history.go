@@ -0,0 +1,145 @@
+package balancer
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// requestHistory - кольцевой буфер запросов и ошибок бэкенда по секундам (см. SetStatsHistory).
+type requestHistory struct {
+	mu       sync.Mutex
+	seconds  []int64 // Unix-время корзины в секундах: корзина с другим временем устарела и считается пустой.
+	requests []int64
+	errors   []int64
+}
+
+func newRequestHistory(size int) *requestHistory {
+	return &requestHistory{
+		seconds:  make([]int64, size),
+		requests: make([]int64, size),
+		errors:   make([]int64, size),
+	}
+}
+
+// record учитывает запрос, завершившийся в момент now; failed - бэкенд ответил 5xx или не ответил.
+func (h *requestHistory) record(now time.Time, failed bool) {
+	second := now.Unix()
+	i := int(second % int64(len(h.seconds)))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.seconds[i] != second {
+		h.seconds[i], h.requests[i], h.errors[i] = second, 0, 0
+	}
+	h.requests[i]++
+	if failed {
+		h.errors[i]++
+	}
+}
+
+// series возвращает запросы и ошибки за n секунд, заканчивая секундой end (от старых к новым).
+func (h *requestHistory) series(end int64, n int) (requests, errors []int64) {
+	requests, errors = make([]int64, n), make([]int64, n)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for k := range n {
+		second := end - int64(n-1-k)
+		i := int(second % int64(len(h.seconds)))
+		if h.seconds[i] == second {
+			requests[k], errors[k] = h.requests[i], h.errors[i]
+		}
+	}
+	return requests, errors
+}
+
+// BackendSeries - история запросов одного бэкенда по секундам.
+type BackendSeries struct {
+	Index    int     `json:"index"` // ID бэкенда (см. Backend.ID)
+	URL      string  `json:"url"`
+	Requests []int64 `json:"requests"`
+	// Errors - ответы 5xx и ошибки проксирования (клиент получил 502).
+	Errors []int64 `json:"errors"`
+}
+
+// SetStatsHistory включает историю запросов и ошибок бэкендов по секундам за последние window
+// (stats_history.window). Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetStatsHistory(window time.Duration) {
+	size := int(window / time.Second)
+	if size <= 0 {
+		return
+	}
+	b.historySize = size
+	for _, backend := range b.backendList() {
+		backend.history = newRequestHistory(size)
+	}
+}
+
+// HistorySize возвращает, за сколько секунд хранится история запросов; 0 - история выключена.
+func (b *Balancer) HistorySize() int {
+	return b.historySize
+}
+
+// TimeSeries возвращает историю запросов бэкендов за последние seconds секунд, заканчивая секундой end.
+// seconds <= 0 или больше HistorySize - вся история. Без SetStatsHistory возвращает nil.
+func (b *Balancer) TimeSeries(end time.Time, seconds int) []BackendSeries {
+	if b.historySize == 0 {
+		return nil
+	}
+	if seconds <= 0 || seconds > b.historySize {
+		seconds = b.historySize
+	}
+	backends := b.backendList()
+	series := make([]BackendSeries, 0, len(backends))
+	for _, backend := range backends {
+		if backend.Removed() {
+			continue
+		}
+		backendSeries := BackendSeries{Index: backend.id, URL: backend.URL.String()}
+		backendSeries.Requests, backendSeries.Errors = backend.history.series(end.Unix(), seconds)
+		series = append(series, backendSeries)
+	}
+	return series
+}
+
+// statusWriter запоминает статус ответа клиенту, чтобы учесть запрос в истории бэкенда.
+// Flush доступен и через Unwrap (http.ResponseController); Hijack при смене протокола запоминает статус 101.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if sw.status == 0 && code >= http.StatusOK {
+		sw.status = code
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(p)
+}
+
+// Flush нужен потоковым ответам (gRPC, SSE), которые проверяют http.Flusher напрямую.
+func (sw *statusWriter) Flush() {
+	_ = http.NewResponseController(sw.ResponseWriter).Flush()
+}
+
+// Hijack вызывается прокси после ответа бэкенда 101 (WebSocket): сам ответ пишется уже в соединение.
+func (sw *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(sw.ResponseWriter).Hijack()
+	if err == nil && sw.status == 0 {
+		sw.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
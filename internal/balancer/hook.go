@@ -0,0 +1,113 @@
+package balancer
+
+import (
+	"net/http"
+	"strings"
+
+	"load-balancer/internal/hooks"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+	"load-balancer/internal/response"
+)
+
+var (
+	hookRejectedTotal = metrics.NewCounter("balancer_hook_rejected_total",
+		"Количество запросов, отклоненных сценарием admission_hook.")
+	hookPoolTotal = metrics.NewCounter("balancer_hook_pool_overrides_total",
+		"Количество запросов, направленных сценарием admission_hook в другой пул.")
+)
+
+// SetHook задает сценарий admission_hook; pools - пулы по имени (как в GET /admin/state),
+// в которые сценарий может направить запрос. Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetHook(hook *hooks.Hook, pools map[string]*Balancer) {
+	b.hook = hook
+	b.hookPools = pools
+}
+
+// limitKey возвращает ключ корзины Rate Limiter для запроса.
+func (b *Balancer) limitKey(r *http.Request, clientID string) string {
+	if keyed, ok := b.rateLimiter.(KeyLimiter); ok {
+		return keyed.LimitKey(r, clientID, b.matchRoute(r.URL.Path).prefix)
+	}
+	return clientID
+}
+
+// runHook выполняет сценарий допуска для запроса. Ошибка сценария не мешает обработке:
+// запрос обрабатывается так, как если бы сценария не было.
+func (b *Balancer) runHook(r *http.Request, clientID string) hooks.Decision {
+	if b.hook == nil {
+		return hooks.DefaultDecision
+	}
+
+	req := hooks.Request{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Host:       r.Host,
+		RemoteAddr: r.RemoteAddr,
+		ClientID:   clientID,
+		Headers:    make(map[string]string, len(r.Header)),
+		Query:      make(map[string]string),
+	}
+	for name, values := range r.Header {
+		req.Headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+	for name, values := range r.URL.Query() {
+		req.Query[name] = values[0]
+	}
+	if reader, ok := b.rateLimiter.(BucketReader); ok {
+		if tokens, capacity, rate, found := reader.Bucket(b.limitKey(r, clientID)); found {
+			req.Limiter = &hooks.LimiterState{Tokens: tokens, Capacity: capacity, Rate: rate}
+		}
+	}
+
+	decision, err := b.hook.Decide(req)
+	if err != nil {
+		i18n.Logf(i18n.BalancerHookFailed, clientID, err)
+	}
+	return decision
+}
+
+// rejectByHook отвечает клиенту статусом, выбранным сценарием допуска.
+func (b *Balancer) rejectByHook(w http.ResponseWriter, r *http.Request, decision hooks.Decision, clientID string) {
+	message := decision.Message
+	if message == "" {
+		message = i18n.T(i18n.BalancerHookDenied)
+	}
+	hookRejectedTotal.Inc()
+	i18n.Logf(i18n.BalancerHookRejected, clientID, decision.RejectStatus, message)
+	b.respondWithError(w, r, decision.RejectStatus, response.CodeHookRejected, message)
+}
+
+// hookPool возвращает пул, выбранный сценарием допуска, или nil, если запрос остается в этом пуле.
+// Неизвестный пул не прерывает обработку: запрос обрабатывается этим пулом.
+func (b *Balancer) hookPool(decision hooks.Decision, clientID string) *Balancer {
+	if decision.Pool == "" {
+		return nil
+	}
+	pool, ok := b.hookPools[decision.Pool]
+	if !ok {
+		i18n.Logf(i18n.BalancerHookUnknownPool, decision.Pool, clientID)
+		return nil
+	}
+	if pool == b {
+		return nil
+	}
+	return pool
+}
+
+// forwardToHookPool проксирует запрос на бэкенд пула, выбранного сценарием допуска,
+// и возвращает URL бэкенда для журнала доступа.
+func (b *Balancer) forwardToHookPool(w http.ResponseWriter, r *http.Request, pool *Balancer, name, clientID string) string {
+	rt := pool.matchRoute(r.URL.Path)
+	backend, index, err := pool.nextBackendForRoute(rt, clientID)
+	if err != nil {
+		i18n.Logf(i18n.BalancerSelectFailed, pool.algorithm, err, r.Method, r.URL.Path, clientID)
+		b.respondWithError(w, r, http.StatusServiceUnavailable, response.CodeNoHealthyBackends, i18n.T(i18n.BalancerAllBackendsDown))
+		return ""
+	}
+	hookPoolTotal.Inc()
+	traceFrom(r.Context()).add("backend=%s/%d", name, index)
+	i18n.Logf(i18n.BalancerHookPool, clientID, name, index, backend.URL)
+	pool.forward(w, r, backend, index, clientID)
+	return backend.URL.String()
+}
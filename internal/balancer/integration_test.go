@@ -1,13 +1,35 @@
 package balancer_test
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io"
+	"log"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -15,10 +37,18 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"load-balancer/internal/access"
+	"load-balancer/internal/accesslog"
+	"load-balancer/internal/admission"
 	"load-balancer/internal/balancer"
+	"load-balancer/internal/capture"
 	"load-balancer/internal/config"
+	"load-balancer/internal/hooks"
+	"load-balancer/internal/metrics"
 	"load-balancer/internal/ratelimiter"
+	"load-balancer/internal/requestid"
 	"load-balancer/internal/response"
+	"load-balancer/internal/tracing"
 )
 
 // --- Управляемый обработчик для Health Checks ---
@@ -250,6 +280,7 @@ func TestIntegration_RateLimiting(t *testing.T) {
 	require.NoError(t, err, "Не удалось распарсить JSON ошибки 429: %s", body)
 	assert.Equal(t, http.StatusTooManyRequests, errResp3.Code, "Incorrect error code in body 3")
 	assert.Contains(t, errResp3.Message, "Rate limit exceeded", "Incorrect error message in body 3")
+	assert.Equal(t, response.CodeRateLimited, errResp3.ErrorCode, "Incorrect error_code in body 3")
 	// --------------------
 
 	// 3. Ждем > 1 сек, чтобы токены пополнились (тикер работает)
@@ -295,6 +326,7 @@ func TestIntegration_UnhealthyBackend(t *testing.T) {
 			require.NoError(t, err, "Не удалось распарсить JSON ошибки 502: %s", body)
 			assert.Equal(t, http.StatusBadGateway, errResp.Code, "Incorrect code in Bad Gateway body")
 			assert.Contains(t, errResp.Message, "Bad Gateway from Custom Handler", "Incorrect message in Bad Gateway body")
+			assert.Equal(t, response.CodeBadGateway, errResp.ErrorCode, "Incorrect error_code in Bad Gateway body")
 			firstResponse502 = true
 			continue // Пропускаем проверку тела
 		}
@@ -341,6 +373,7 @@ func TestIntegration_AllBackendsUnhealthy(t *testing.T) {
 	require.NoError(t, err, "Не удалось распарсить JSON ошибки 503: %s", body)
 	assert.Equal(t, http.StatusServiceUnavailable, errResp.Code, "Incorrect code in 503 error body")
 	assert.Contains(t, errResp.Message, "All backend servers are unavailable", "Incorrect message in 503 error body")
+	assert.Equal(t, response.CodeNoHealthyBackends, errResp.ErrorCode, "Incorrect error_code in 503 error body")
 }
 
 // TestIntegration_HealthChecks проверяет работу Health Checks.
@@ -359,8 +392,8 @@ func TestIntegration_HealthChecks(t *testing.T) {
 	time.Sleep(hcInterval * 2)
 
 	// 1. Проверяем начальное состояние: все бэкенды должны быть живы
-	for i, backend := range ts.balancer.GetBackends() { // Предполагаем наличие метода GetBackends()
-		assert.True(t, backend.IsAlive(), "Бэкенд #%d должен быть жив изначально", i)
+	for i, backend := range ts.balancer.Snapshot().Backends {
+		assert.True(t, backend.Alive, "Бэкенд #%d должен быть жив изначально", i)
 	}
 
 	// 2. Симулируем падение бэкенда #0
@@ -372,11 +405,11 @@ func TestIntegration_HealthChecks(t *testing.T) {
 	// Используем assert.Eventually для ожидания изменения статуса
 	require.Eventually(t, func() bool {
 		// Получаем актуальный статус бэкенда из балансировщика
-		backends := ts.balancer.GetBackends()
+		backends := ts.balancer.Snapshot().Backends
 		if len(backends) <= backendToFailIndex {
 			return false // На случай, если бэкенды еще не полностью инициализированы
 		}
-		return !backends[backendToFailIndex].IsAlive()
+		return !backends[backendToFailIndex].Alive
 	}, hcInterval*4, hcInterval/2, "Health Check не пометил бэкенд #%d как нерабочий", backendToFailIndex)
 
 	t.Logf("Бэкенд #%d помечен как нерабочий Health Check'ом", backendToFailIndex)
@@ -396,11 +429,11 @@ func TestIntegration_HealthChecks(t *testing.T) {
 
 	// 6. Ждем, пока Health Check обнаружит восстановление
 	require.Eventually(t, func() bool {
-		backends := ts.balancer.GetBackends()
+		backends := ts.balancer.Snapshot().Backends
 		if len(backends) <= backendToFailIndex {
 			return false
 		}
-		return backends[backendToFailIndex].IsAlive()
+		return backends[backendToFailIndex].Alive
 	}, hcInterval*4, hcInterval/2, "Health Check не пометил бэкенд #%d как рабочий после восстановления", backendToFailIndex)
 
 	t.Logf("Бэкенд #%d помечен как рабочий Health Check'ом", backendToFailIndex)
@@ -509,8 +542,8 @@ func TestIntegration_Random_AllBackendsUnhealthy(t *testing.T) {
 
 	// Проверяем, что все бэкенды действительно помечены как нерабочие
 	require.Eventually(t, func() bool {
-		for _, be := range ts.balancer.GetBackends() {
-			if be.IsAlive() {
+		for _, be := range ts.balancer.Snapshot().Backends {
+			if be.Alive {
 				return false // Хотя бы один еще жив
 			}
 		}
@@ -527,5 +560,3094 @@ func TestIntegration_Random_AllBackendsUnhealthy(t *testing.T) {
 	require.NoError(t, err, "Не удалось распарсить JSON ошибки 503 (random): %s", body)
 	assert.Equal(t, http.StatusServiceUnavailable, errResp.Code, "Incorrect code in 503 error body")
 	assert.Contains(t, errResp.Message, "All backend servers are unavailable", "Incorrect message in 503 error body")
+	assert.Equal(t, response.CodeNoHealthyBackends, errResp.ErrorCode, "Incorrect error_code in 503 error body")
 	// --------------------
 }
+
+// TestIntegration_HeaderScrubbing проверяет удаление чувствительных заголовков для недоверенных маршрутов.
+func TestIntegration_HeaderScrubbing(t *testing.T) {
+	// Бэкенд возвращает значения заголовков, которые до него дошли
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "auth=%s;cookie=%s;debug=%s", r.Header.Get("Authorization"), r.Header.Get("Cookie"), r.Header.Get("X-Debug"))
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	lb.SetRoutes(config.HeaderPolicyConfig{
+		Sensitive: []string{"Authorization", "Cookie"},
+		Deny:      []string{"X-Debug"},
+	}, []config.RouteConfig{
+		{PathPrefix: "/partner", Untrusted: true},
+	})
+
+	send := func(path string) string {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Cookie", "session=abc")
+		req.Header.Set("X-Debug", "1")
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	// Доверенный маршрут по умолчанию: удаляется только заголовок из deny
+	assert.Equal(t, "auth=Bearer secret;cookie=session=abc;debug=", send("/internal"))
+	// Недоверенный маршрут: чувствительные заголовки не пересылаются
+	assert.Equal(t, "auth=;cookie=;debug=", send("/partner/orders"))
+}
+
+// TestIntegration_HealthCheckBackoff проверяет, что постоянно падающий бэкенд проверяется реже благодаря backoff.
+func TestIntegration_HealthCheckBackoff(t *testing.T) {
+	var mu sync.Mutex
+	probes := 0
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		probes++
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+
+	interval := 40 * time.Millisecond
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{
+		Enabled:    true,
+		Interval:   interval,
+		Timeout:    20 * time.Millisecond,
+		Path:       "/healthz",
+		Workers:    1,
+		MaxBackoff: 8 * interval,
+	}, "round_robin")
+	require.NoError(t, err)
+
+	time.Sleep(25 * interval)
+	lb.StopHealthChecks()
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Без backoff было бы ~25 проверок; с backoff (1, 2, 4, 8, 8... интервалов) - заметно меньше
+	assert.Greater(t, probes, 2, "Бэкенд должен проверяться")
+	assert.Less(t, probes, 12, "Backoff должен сокращать количество проверок падающего бэкенда")
+	assert.False(t, lb.Snapshot().Backends[0].Alive)
+}
+
+// TestIntegration_HealthCheckStagger проверяет, что при health_check.stagger первые проверки бэкендов
+// распределяются по интервалу, а health_check.jitter добавляет к следующей проверке случайную задержку.
+func TestIntegration_HealthCheckStagger(t *testing.T) {
+	const backends = 4
+	interval := 400 * time.Millisecond
+	var mu sync.Mutex
+	firstProbe := make(map[int]time.Time)
+	urls := make([]string, backends)
+	for i := range urls {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			if _, ok := firstProbe[i]; !ok {
+				firstProbe[i] = time.Now()
+			}
+			mu.Unlock()
+		}))
+		defer server.Close()
+		urls[i] = server.URL
+	}
+
+	started := time.Now()
+	lb, err := balancer.New(urls, ratelimiter.NewDisabled(), config.HealthCheckConfig{
+		Enabled: true, Interval: interval, Timeout: 100 * time.Millisecond, Path: "/healthz",
+		Stagger: true, Jitter: interval / 2, MaxBackoff: interval,
+	}, "round_robin")
+	require.NoError(t, err)
+	defer lb.StopHealthChecks()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(firstProbe) == backends
+	}, 2*interval, 10*time.Millisecond)
+	mu.Lock()
+	for i := 1; i < backends; i++ {
+		assert.True(t, firstProbe[i].After(firstProbe[i-1]), "Бэкенд %d проверен раньше предыдущего", i)
+	}
+	assert.GreaterOrEqual(t, firstProbe[backends-1].Sub(started), interval*(backends-1)/backends-interval/10,
+		"Последний бэкенд проверяется в конце интервала")
+	mu.Unlock()
+
+	// Следующая проверка назначается через interval плюс добавка до jitter (минус половина тика)
+	for _, backend := range lb.Snapshot().Backends {
+		if backend.NextCheck == nil {
+			continue
+		}
+		wait := time.Until(*backend.NextCheck)
+		assert.Less(t, wait, interval+interval/2)
+	}
+}
+
+// TestIntegration_CheckNow проверяет принудительную проверку состояния вне расписания.
+func TestIntegration_CheckNow(t *testing.T) {
+	handler0 := newHealthAwareHandler(0, "/healthz")
+	handler1 := newHealthAwareHandler(1, "/healthz")
+	backend0 := httptest.NewServer(handler0)
+	defer backend0.Close()
+	backend1 := httptest.NewServer(handler1)
+	defer backend1.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+
+	// Интервал намеренно большой: изменения видны только через CheckNow
+	lb, err := balancer.New([]string{backend0.URL, backend1.URL}, rl, config.HealthCheckConfig{
+		Enabled:  true,
+		Interval: time.Hour,
+		Timeout:  time.Second,
+		Path:     "/healthz",
+	}, "round_robin")
+	require.NoError(t, err)
+	defer lb.StopHealthChecks()
+
+	handler1.setHealth(false)
+	results, err := lb.CheckNow("")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Healthy)
+	assert.False(t, results[1].Healthy)
+	assert.NotEmpty(t, results[1].Error)
+	assert.False(t, lb.Snapshot().Backends[1].Alive, "Результат проверки должен применяться к бэкенду")
+
+	// Проверка одного бэкенда по индексу и по URL
+	handler1.setHealth(true)
+	results, err = lb.CheckNow("1")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, 1, results[0].Index)
+	assert.True(t, results[0].Healthy)
+	assert.True(t, lb.Snapshot().Backends[1].Alive)
+
+	results, err = lb.CheckNow(backend0.URL + "/")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, 0, results[0].Index)
+
+	_, err = lb.CheckNow("5")
+	assert.ErrorIs(t, err, balancer.ErrBackendNotFound)
+}
+
+// TestIntegration_RestoreHealth проверяет восстановление статуса бэкендов, сохраненного перед перезапуском.
+func TestIntegration_RestoreHealth(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // Первая проверка не завершается, пока статус не восстановлен
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer fast.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+
+	lb, err := balancer.New([]string{slow.URL, fast.URL}, rl, config.HealthCheckConfig{
+		Enabled:  true,
+		Interval: time.Hour,
+		Timeout:  5 * time.Second,
+		Path:     "/healthz",
+	}, "round_robin")
+	require.NoError(t, err)
+	defer lb.StopHealthChecks()
+
+	_, err = lb.CheckNow("1")
+	require.NoError(t, err)
+
+	restored := lb.RestoreHealth(map[string]bool{slow.URL: false, fast.URL: false, "http://removed:1": false})
+	assert.Equal(t, 1, restored, "Уже проверенный бэкенд не должен исключаться")
+	assert.False(t, lb.Snapshot().Backends[0].Alive, "Бэкенд, недоступный до перезапуска, ждет первой проверки")
+	assert.True(t, lb.Snapshot().Backends[1].Alive)
+
+	close(release)
+	require.Eventually(t, func() bool { return lb.Snapshot().Backends[0].Alive }, 5*time.Second, 10*time.Millisecond,
+		"Успешная проверка должна вернуть бэкенд")
+	assert.Equal(t, map[string]bool{slow.URL: true, fast.URL: true}, lb.HealthSnapshot())
+}
+
+// TestIntegration_UpdateHealthCheckConfig проверяет смену параметров проверок без перезапуска цикла.
+func TestIntegration_UpdateHealthCheckConfig(t *testing.T) {
+	var mu sync.Mutex
+	probes := map[string]int{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		probes[r.URL.Path]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{
+		Enabled:    true,
+		Interval:   time.Hour,
+		Timeout:    time.Second,
+		Path:       "/old",
+		MaxBackoff: time.Hour,
+	}, "round_robin")
+	require.NoError(t, err)
+	defer lb.StopHealthChecks()
+
+	newCfg := config.HealthCheckConfig{Enabled: true, IntervalStr: "20ms", TimeoutStr: "10ms", Path: "/new"}
+	require.NoError(t, newCfg.Parse())
+	require.NoError(t, lb.UpdateHealthCheckConfig(newCfg))
+	assert.Equal(t, "/new", lb.HealthCheckConfig().Path)
+
+	// При часовом интервале новых проверок не было бы; после обновления они идут каждые 20ms по новому пути
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.LessOrEqual(t, probes["/old"], 1, "Старый путь может проверяться только при старте")
+	assert.GreaterOrEqual(t, probes["/new"], 3)
+
+	// Выключенные проверки нельзя перенастроить на лету
+	lbDisabled, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	assert.ErrorIs(t, lbDisabled.UpdateHealthCheckConfig(newCfg), balancer.ErrHealthChecksDisabled)
+}
+
+// TestIntegration_StopHealthChecksWait проверяет, что Wait дожидается остановки проверок
+// и после него новые пробы не отправляются.
+func TestIntegration_StopHealthChecksWait(t *testing.T) {
+	var probes atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		time.Sleep(30 * time.Millisecond) // Проба еще выполняется в момент остановки
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{
+		Enabled:    true,
+		Interval:   10 * time.Millisecond,
+		Timeout:    time.Second,
+		Path:       "/health",
+		MaxBackoff: time.Second,
+	}, "round_robin")
+	require.NoError(t, err)
+
+	time.Sleep(50 * time.Millisecond)
+	lb.StopHealthChecks()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, lb.Wait(ctx))
+
+	stopped := probes.Load()
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, stopped, probes.Load(), "После Wait проверки не должны выполняться")
+
+	// Без проверок ждать нечего
+	lbDisabled, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	assert.NoError(t, lbDisabled.Wait(ctx))
+}
+
+// newH2CServer запускает тестовый сервер, принимающий HTTP/2 без TLS.
+func newH2CServer(handler http.Handler) *httptest.Server {
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	return server
+}
+
+// TestIntegration_GRPC проверяет режим gRPC: HTTP/2 до бэкенда, балансировку отдельных вызовов
+// в одном соединении и отображение ошибок в grpc-status.
+func TestIntegration_GRPC(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	grpcBackend := func(id string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			calls[id]++
+			mu.Unlock()
+			assert.Equal(t, 2, r.ProtoMajor, "До бэкенда вызов gRPC должен идти по HTTP/2")
+			assert.Equal(t, "trailers", r.Header.Get("Te"))
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Trailer", "Grpc-Status")
+			_, _ = w.Write([]byte{0, 0, 0, 0, 0})
+			w.Header().Set("Grpc-Status", "0")
+		})
+	}
+	backend1 := newH2CServer(grpcBackend("b1"))
+	defer backend1.Close()
+	backend2 := newH2CServer(grpcBackend("b2"))
+	defer backend2.Close()
+	htmlBackend := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "<html>maintenance</html>")
+	}))
+	defer htmlBackend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+
+	newFrontend := func(backends ...string) *httptest.Server {
+		lb, err := balancer.New(backends, rl, config.HealthCheckConfig{}, "round_robin")
+		require.NoError(t, err)
+		lb.EnableGRPC()
+		return newH2CServer(lb)
+	}
+
+	// Клиент gRPC: только HTTP/2 без TLS, все вызовы в одном соединении
+	clientTransport := &http.Transport{Protocols: new(http.Protocols)}
+	clientTransport.Protocols.SetUnencryptedHTTP2(true)
+	defer clientTransport.CloseIdleConnections()
+	client := &http.Client{Transport: clientTransport}
+
+	call := func(url string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, url+"/pkg.Service/Method", strings.NewReader("\x00\x00\x00\x00\x00"))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("Te", "trailers")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp
+	}
+
+	frontend := newFrontend(backend1.URL, backend2.URL)
+	defer frontend.Close()
+	for i := 0; i < 4; i++ {
+		resp := call(frontend.URL)
+		assert.Equal(t, 2, resp.ProtoMajor)
+		assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"), "Трейлеры бэкенда должны доходить до клиента")
+	}
+	mu.Lock()
+	assert.Equal(t, map[string]int{"b1": 2, "b2": 2}, calls, "Вызовы одного соединения должны распределяться по бэкендам")
+	mu.Unlock()
+
+	// HTML-ответ 503 бэкенда превращается в UNAVAILABLE
+	htmlFrontend := newFrontend(htmlBackend.URL)
+	defer htmlFrontend.Close()
+	resp := call(htmlFrontend.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/grpc", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "14", resp.Header.Get("Grpc-Status"))
+
+	// Недоступный бэкенд: вместо JSON 502 клиент получает grpc-status UNAVAILABLE
+	deadFrontend := newFrontend("http://127.0.0.1:1")
+	defer deadFrontend.Close()
+	resp = call(deadFrontend.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "14", resp.Header.Get("Grpc-Status"))
+	assert.NotEmpty(t, resp.Header.Get("Grpc-Message"))
+
+	// Обычные HTTP-запросы по-прежнему получают JSON-ошибки
+	plainResp, err := http.Get(deadFrontend.URL + "/")
+	require.NoError(t, err)
+	defer plainResp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, plainResp.StatusCode, "После ошибки прокси бэкенд помечен недоступным")
+	assert.Equal(t, "application/json", plainResp.Header.Get("Content-Type"))
+}
+
+// TestIntegration_Admission проверяет ответ 503 OVERLOADED, когда все слоты заняты и очереди нет.
+func TestIntegration_Admission(t *testing.T) {
+	unblock := make(chan struct{})
+	started := make(chan struct{}, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-unblock
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetAdmission(admission.New(config.ConcurrencyConfig{
+		Enabled: true, MaxInFlight: 1, MaxQueue: 0, QueueTimeout: time.Second, PriorityHeader: "X-Priority",
+	}))
+
+	firstDone := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		firstDone <- w.Code
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var errResp response.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, response.CodeOverloaded, errResp.ErrorCode)
+
+	close(unblock)
+	assert.Equal(t, http.StatusOK, <-firstDone)
+}
+
+// TestIntegration_RequestAge проверяет, что запрос, дождавшийся в очереди request_age.max_age,
+// отбрасывается с REQUEST_EXPIRED и не доходит до бэкенда.
+func TestIntegration_RequestAge(t *testing.T) {
+	unblock := make(chan struct{})
+	started := make(chan struct{}, 2)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-unblock
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetAdmission(admission.New(config.ConcurrencyConfig{
+		Enabled: true, MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 5 * time.Second, PriorityHeader: "X-Priority",
+	}))
+	lb.SetMaxRequestAge(100 * time.Millisecond)
+	expired := metrics.NewCounter("balancer_requests_expired_total", "")
+	before := expired.Value()
+
+	firstDone := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		firstDone <- w.Code
+	}()
+	<-started
+
+	// Слот занят: запрос ждет в очереди и отбрасывается по max_age, а не по queue_timeout
+	begin := time.Now()
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Less(t, time.Since(begin), 2*time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var errResp response.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, response.CodeRequestExpired, errResp.ErrorCode)
+	assert.Equal(t, before+1, expired.Value())
+	assert.Empty(t, started, "устаревший запрос не должен дойти до бэкенда")
+
+	close(unblock)
+	assert.Equal(t, http.StatusOK, <-firstDone)
+}
+
+// TestIntegration_AdmissionHook проверяет решения сценария допуска: отклонение со своим статусом,
+// стоимость запроса в токенах и выбор другого пула.
+func TestIntegration_AdmissionHook(t *testing.T) {
+	primaryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "primary")
+	}))
+	defer primaryServer.Close()
+	overflowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "overflow")
+	}))
+	defer overflowServer.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 0.001, DefaultCapacity: 3, IdentifierHeader: "X-Client-ID",
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{primaryServer.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	overflow, err := balancer.New([]string{overflowServer.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	hook, err := hooks.New(config.AdmissionHookConfig{Source: `
+def admit(request):
+    if request.path == "/blocked":
+        return {"reject": 451, "message": "blocked for " + request.client_id}
+    if request.limiter != None and request.limiter.tokens < 1:
+        return {"pool": "spillover", "cost": 0}
+    if request.path == "/heavy":
+        return {"cost": 3}
+    pool = request.headers.get("x-pool")
+    if pool:
+        return {"pool": pool}
+    return None
+`})
+	require.NoError(t, err)
+	pools := map[string]*balancer.Balancer{"primary": lb, "spillover": overflow}
+	lb.SetHook(hook, pools)
+	overflow.SetHook(hook, pools)
+
+	send := func(client, path, pool string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Client-ID", client)
+		if pool != "" {
+			req.Header.Set("X-Pool", pool)
+		}
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("alice", "/blocked", "")
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, w.Code)
+	var errResp response.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, response.CodeHookRejected, errResp.ErrorCode)
+	assert.Equal(t, "blocked for alice", errResp.Message)
+
+	// Запрос стоимостью 3 токена расходует всю корзину; следующие запросы клиента по состоянию
+	// корзины уходят в резервный пул без проверки лимита
+	w = send("alice", "/heavy", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "primary", w.Body.String())
+	w = send("alice", "/heavy", "")
+	assert.Equal(t, "overflow", w.Body.String())
+	w = send("alice", "/", "")
+	assert.Equal(t, "overflow", w.Body.String())
+
+	// Пул из заголовка; неизвестный пул не прерывает обработку
+	assert.Equal(t, "overflow", send("bob", "/", "spillover").Body.String())
+	assert.Equal(t, "primary", send("bob", "/", "tls:unknown").Body.String())
+	assert.Equal(t, "primary", send("bob", "/", "primary").Body.String())
+}
+
+// TestIntegration_Spillover проверяет перелив в резервный пул при исчерпании бюджета основного.
+func TestIntegration_Spillover(t *testing.T) {
+	unblock := make(chan struct{})
+	started := make(chan struct{}, 1)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-unblock
+		_, _ = io.WriteString(w, "primary")
+	}))
+	defer primary.Close()
+	overflowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "overflow")
+	}))
+	defer overflowServer.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{primary.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	overflow, err := balancer.New([]string{overflowServer.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetSpillover(overflow, 1, 0)
+
+	primaryTotal := metrics.NewCounter("balancer_primary_requests_total", "")
+	spilloverTotal := metrics.NewCounter("balancer_spillover_requests_total", "")
+	unavailableTotal := metrics.NewCounter("balancer_spillover_unavailable_total", "")
+	primaryBefore, spilloverBefore, unavailableBefore := primaryTotal.Value(), spilloverTotal.Value(), unavailableTotal.Value()
+
+	firstDone := make(chan string)
+	go func() {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		firstDone <- w.Body.String()
+	}()
+	<-started
+
+	// Основной пул занят: запрос уходит в резервный
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "overflow", w.Body.String())
+
+	// Резервный пул недоступен: запрос остается в основном сверх бюджета
+	overflow.Backend(0).SetAlive(false)
+	secondDone := make(chan string)
+	go func() {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		secondDone <- w.Body.String()
+	}()
+	<-started
+
+	close(unblock)
+	assert.Equal(t, "primary", <-firstDone)
+	assert.Equal(t, "primary", <-secondDone)
+	assert.Equal(t, uint64(2), primaryTotal.Value()-primaryBefore)
+	assert.Equal(t, uint64(1), spilloverTotal.Value()-spilloverBefore)
+	assert.Equal(t, uint64(1), unavailableTotal.Value()-unavailableBefore)
+
+	// Бюджет освободился: следующий запрос снова идет в основной пул
+	w = httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "primary", w.Body.String())
+}
+
+// TestIntegration_Capture проверяет запись запросов выбранного клиента, проходящих через балансировщик.
+func TestIntegration_Capture(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, "echo:"+string(body))
+	}))
+	defer backend.Close()
+
+	// Идентификатор клиента из заголовка используется только включенным Rate Limiter
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 100, DefaultCapacity: 100, IdentifierHeader: "X-Client-ID",
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetRoutes(config.HeaderPolicyConfig{Sensitive: []string{"Authorization"}}, nil)
+	rec := capture.New(config.CaptureConfig{BufferSize: 10, MaxBodyBytes: 1024})
+	lb.SetCapture(rec)
+	rec.Start(capture.Filter{ClientID: "user1"})
+
+	for _, clientID := range []string{"user1", "user2"} {
+		req := httptest.NewRequest(http.MethodPost, "/api", strings.NewReader("ping"))
+		req.Header.Set("X-Client-ID", clientID)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "echo:ping", w.Body.String())
+	}
+
+	entries := rec.Entries()
+	require.Len(t, entries, 1, "Записываются только запросы выбранного клиента")
+	assert.Equal(t, "user1", entries[0].ClientID)
+	assert.Equal(t, "ping", entries[0].Request.Body)
+	assert.Equal(t, "echo:ping", entries[0].Response.Body)
+	assert.Equal(t, "[REDACTED]", entries[0].Request.Headers.Get("Authorization"))
+}
+
+// TestIntegration_AcceptEncoding проверяет, что бэкенд получает Accept-Encoding согласно режиму маршрута,
+// а в режиме strip клиент получает распакованный ответ.
+func TestIntegration_AcceptEncoding(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Accept-Encoding", r.Header.Get("Accept-Encoding"))
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			_, _ = io.WriteString(w, "plain body")
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = io.WriteString(gz, "plain body")
+		_ = gz.Close()
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetRoutes(config.HeaderPolicyConfig{AcceptEncoding: config.AcceptEncodingPass}, []config.RouteConfig{
+		{PathPrefix: "/strip", Headers: config.HeaderPolicyConfig{AcceptEncoding: config.AcceptEncodingStrip}},
+		{PathPrefix: "/identity", Headers: config.HeaderPolicyConfig{AcceptEncoding: config.AcceptEncodingIdentity}},
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "br, zstd, gzip")
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+
+	w := get("/pass")
+	assert.Equal(t, "br, zstd, gzip", w.Header().Get("X-Seen-Accept-Encoding"))
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"), "В режиме pass ответ пересылается сжатым")
+
+	w = get("/identity")
+	assert.Equal(t, "identity", w.Header().Get("X-Seen-Accept-Encoding"))
+	assert.Equal(t, "plain body", w.Body.String())
+
+	w = get("/strip")
+	assert.Equal(t, "gzip", w.Header().Get("X-Seen-Accept-Encoding"), "Транспорт сам запрашивает gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "plain body", w.Body.String())
+}
+
+// TestIntegration_AccessLog проверяет записи журнала доступа, формируемые балансировщиком.
+func TestIntegration_AccessLog(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, "created")
+	}))
+	defer backend.Close()
+
+	var mu sync.Mutex
+	var entries []accesslog.Entry
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		decoder := json.NewDecoder(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		for decoder.More() {
+			var entry accesslog.Entry
+			require.NoError(t, decoder.Decode(&entry))
+			entries = append(entries, entry)
+		}
+	}))
+	defer sink.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	shipper, err := accesslog.New(config.AccessLogConfig{
+		Enabled: true, Sink: config.AccessLogSinkHTTP, Address: sink.URL,
+		BatchSize: 10, QueueSize: 10, FlushInterval: time.Hour, Timeout: time.Second,
+	})
+	require.NoError(t, err)
+	shipper.Start()
+	lb.SetAccessLog(shipper)
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	lb.ServeHTTP(httptest.NewRecorder(), req)
+
+	backend.Close()
+	lb.Backend(0).SetAlive(false)
+	lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/down", nil))
+
+	// Остановка отправляет накопленные записи
+	shipper.Stop()
+	require.NoError(t, shipper.Wait(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "/orders", entries[0].Path)
+	assert.Equal(t, http.StatusCreated, entries[0].Status)
+	assert.Equal(t, int64(len("created")), entries[0].Bytes)
+	assert.Equal(t, backend.URL, entries[0].Backend)
+	assert.Equal(t, "test-agent", entries[0].UserAgent)
+	assert.Equal(t, http.StatusServiceUnavailable, entries[1].Status)
+	assert.Empty(t, entries[1].Backend, "Запрос не дошел до бэкенда")
+}
+
+// TestIntegration_BackendSelector проверяет выбор подмножества пула по меткам бэкендов.
+func TestIntegration_BackendSelector(t *testing.T) {
+	var servers []*httptest.Server
+	for _, name := range []string{"v1", "v2-a", "v2-b"} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name)
+		}))
+		defer server.Close()
+		servers = append(servers, server)
+	}
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{servers[0].URL, servers[1].URL, servers[2].URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetRoutes(config.HeaderPolicyConfig{}, []config.RouteConfig{
+		{PathPrefix: "/v2", BackendSelector: map[string]string{"version": "v2"}},
+		{PathPrefix: "/gpu", BackendSelector: map[string]string{"gpu": "true"}},
+	})
+	lb.SetBackendLabels(map[string]map[string]string{
+		servers[0].URL: {"version": "v1"},
+		servers[1].URL: {"version": "v2"},
+		servers[2].URL: {"version": "v2"},
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// Запросы маршрута распределяются поровну между бэкендами подмножества
+	counts := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		w := get("/v2/models")
+		require.Equal(t, http.StatusOK, w.Code)
+		counts[w.Body.String()]++
+	}
+	assert.Equal(t, map[string]int{"v2-a": 3, "v2-b": 3}, counts)
+
+	// Маршрут без селектора использует весь пул
+	counts = make(map[string]int)
+	for i := 0; i < 3; i++ {
+		counts[get("/other").Body.String()]++
+	}
+	assert.Equal(t, map[string]int{"v1": 1, "v2-a": 1, "v2-b": 1}, counts)
+
+	// Недоступное подмножество не подменяется остальными бэкендами пула
+	lb.Backend(1).SetAlive(false)
+	assert.Equal(t, "v2-b", get("/v2").Body.String())
+	lb.Backend(2).SetAlive(false)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/v2").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/gpu").Code, "Ни у одного бэкенда нет метки gpu")
+}
+
+// TestIntegration_SoftRateLimit проверяет заголовок X-RateLimit-Warning после мягкого порога.
+func TestIntegration_SoftRateLimit(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 0.001, DefaultCapacity: 4, SoftLimitRatio: 0.6, IdentifierHeader: "X-Client-ID",
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	var warnings []string
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Client-ID", "soft-client")
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		warnings = append(warnings, w.Header().Get(balancer.RateLimitWarningHeader))
+	}
+	// Емкость 4, порог 60%: предупреждение, когда осталось не больше 1.6 токена
+	assert.Empty(t, warnings[0])
+	assert.Empty(t, warnings[1])
+	assert.Equal(t, "soft limit exceeded; remaining=1; capacity=4", warnings[2])
+	assert.Equal(t, "soft limit exceeded; remaining=0; capacity=4", warnings[3])
+}
+
+// TestIntegration_RateLimitHeaders проверяет заголовки X-RateLimit-* в ответах бэкенда и 429.
+func TestIntegration_RateLimitHeaders(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 0.5, DefaultCapacity: 2, DefaultCapacityIP: 1, IdentifierHeader: "X-Client-ID",
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Client-ID", "quota-client")
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(balancer.RateLimitLimitHeader))
+	assert.Equal(t, "1", w.Header().Get(balancer.RateLimitRemainingHeader))
+	assert.Equal(t, "2", w.Header().Get(balancer.RateLimitResetHeader))
+	assert.Empty(t, w.Header().Get(balancer.RetryAfterHeader), "Retry-After только в ответе 429")
+
+	require.Equal(t, http.StatusOK, get().Code)
+	w = get()
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get(balancer.RateLimitLimitHeader))
+	assert.Equal(t, "0", w.Header().Get(balancer.RateLimitRemainingHeader))
+	assert.Equal(t, "4", w.Header().Get(balancer.RateLimitResetHeader))
+	assert.Equal(t, "2", w.Header().Get(balancer.RetryAfterHeader))
+
+	// Клиент из заголовка получает лимит для заголовка, даже если его ID выглядит как IP-адрес
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Client-ID", "192.0.2.50")
+	w = httptest.NewRecorder()
+	lb.ServeHTTP(w, req)
+	assert.Equal(t, "2", w.Header().Get(balancer.RateLimitLimitHeader))
+	w = httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "1", w.Header().Get(balancer.RateLimitLimitHeader), "Клиент без заголовка получает лимит для IP")
+}
+
+// TestIntegration_GlobalRateLimit проверяет отказ 503 сверх общего лимита независимо от клиента.
+func TestIntegration_GlobalRateLimit(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 100, DefaultCapacity: 100, IdentifierHeader: "X-Client-ID",
+		Global: config.GlobalLimitConfig{Rate: 0.5, Capacity: 2},
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	var codes []int
+	var w *httptest.ResponseRecorder
+	for _, client := range []string{"client-a", "client-b", "client-c"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Client-ID", client)
+		w = httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusServiceUnavailable}, codes)
+	assert.Contains(t, w.Body.String(), string(response.CodeOverloaded))
+	assert.Equal(t, "2", w.Header().Get(balancer.RetryAfterHeader))
+	assert.Empty(t, w.Header().Get(balancer.RateLimitLimitHeader), "Заголовки корзины клиента не относятся к общему лимиту")
+}
+
+// TestIntegration_MaxInFlightPerClient проверяет отказ 429 клиенту, у которого уже max_in_flight_per_client
+// запросов в обработке, освобождение слота после ответа и то, что такой отказ не расходует токены клиента.
+func TestIntegration_MaxInFlightPerClient(t *testing.T) {
+	started := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-unblock
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 0.001, DefaultCapacity: 10, IdentifierHeader: "X-Client-ID", MaxInFlightPerClient: 1,
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	get := func(path, client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Client-ID", client)
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		return w
+	}
+
+	slow := make(chan int)
+	go func() { slow <- get("/slow", "slow-client").Code }()
+	<-started
+	before, err := rl.BucketState("slow-client")
+	require.NoError(t, err)
+
+	for range 3 {
+		w := get("/", "slow-client")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Contains(t, w.Body.String(), string(response.CodeTooManyInFlight))
+	}
+	after, err := rl.BucketState("slow-client")
+	require.NoError(t, err)
+	assert.InDelta(t, before.Tokens, after.Tokens, 0.01, "Отказ из-за одновременных запросов не расходует токены")
+	assert.Equal(t, http.StatusOK, get("/", "other-client").Code)
+
+	close(unblock)
+	assert.Equal(t, http.StatusOK, <-slow)
+	assert.Equal(t, http.StatusOK, get("/", "slow-client").Code, "Слот освобождается после ответа")
+}
+
+// lockedBuffer - буфер для перехвата лога, безопасный для конкурентной записи и чтения.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestIntegration_HealthCheckLogDedup проверяет, что одинаковые ошибки проверок не пишутся в лог на каждой проверке.
+func TestIntegration_HealthCheckLogDedup(t *testing.T) {
+	var probes atomic.Int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+
+	// Лог могут писать и фоновые горутины других тестов, поэтому буфер защищен мьютексом
+	logs := &lockedBuffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	interval := 20 * time.Millisecond
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{
+		Enabled:        true,
+		Interval:       interval,
+		Timeout:        10 * time.Millisecond,
+		Path:           "/healthz",
+		Workers:        1,
+		MaxBackoff:     interval, // Без backoff: бэкенд проверяется на каждом тике
+		LogRepeatEvery: 5,
+	}, "round_robin")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return probes.Load() >= 12 }, 5*time.Second, interval)
+	lb.StopHealthChecks()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, lb.Wait(ctx))
+
+	failureLines := 0
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(line, backend.URL+"/healthz") {
+			failureLines++
+		}
+	}
+	// Первая ошибка и по строке на каждые 5 повторов вместо строки на каждую проверку
+	assert.GreaterOrEqual(t, failureLines, 2)
+	assert.LessOrEqual(t, failureLines, int(probes.Load()/5)+1)
+}
+
+// TestIntegration_RouteFlushInterval проверяет отключение буферизации ответа для маршрута (flush_interval: -1).
+func TestIntegration_RouteFlushInterval(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Ответ известной длины: без flush_interval прокси отдает его клиенту только целиком
+		w.Header().Set("Content-Length", "10")
+		_, _ = io.WriteString(w, "hello")
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = io.WriteString(w, "world")
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetRoutes(config.HeaderPolicyConfig{}, []config.RouteConfig{
+		{PathPrefix: "/stream", FlushIntervalStr: "-1", FlushInterval: -1},
+	})
+	server := httptest.NewServer(lb)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	defer close(release) // Бэкенд завершает ответ до закрытия серверов
+
+	first := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 5)
+		n, _ := io.ReadFull(resp.Body, buf)
+		first <- string(buf[:n])
+	}()
+	select {
+	case got := <-first:
+		assert.Equal(t, "hello", got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Первая часть ответа должна прийти до завершения ответа бэкендом")
+	}
+}
+
+// fakeResolver возвращает заданные адреса для любого хоста.
+type fakeResolver struct {
+	mu      sync.Mutex
+	addrs   []string
+	lookups int
+}
+
+func (r *fakeResolver) lookupCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookups
+}
+
+func (r *fakeResolver) set(addrs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addrs = addrs
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	return append([]string(nil), r.addrs...), nil
+}
+
+// TestIntegration_DNSReresolution проверяет, что после неудачных проверок имя хоста бэкенда разрешается заново,
+// транспорт пересоздается только при смене адресов, а запросы после пересоздания проходят.
+func TestIntegration_DNSReresolution(t *testing.T) {
+	var healthy atomic.Bool
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" && !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+	// Бэкенд задан именем хоста: адреса, заданные IP, повторно не разрешаются
+	backendURL := strings.Replace(backend.URL, "127.0.0.1", "localhost", 1)
+
+	resolver := &fakeResolver{}
+	resolver.set("10.0.0.1")
+	reresolved := metrics.NewCounter("balancer_dns_reresolutions_total", "")
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	interval := 20 * time.Millisecond
+	lb, err := balancer.New([]string{backendURL}, rl, config.HealthCheckConfig{
+		Enabled:        true,
+		Interval:       interval,
+		Timeout:        time.Second,
+		Path:           "/healthz",
+		Workers:        1,
+		MaxBackoff:     interval,
+		LogRepeatEvery: 10,
+	}, "round_robin")
+	require.NoError(t, err)
+	lb.SetResolver(resolver)
+	defer func() {
+		lb.StopHealthChecks()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, lb.Wait(ctx))
+	}()
+
+	// Первые проверки могли пройти до SetResolver, поэтому отсчет ведется после второго обращения к резолверу
+	require.Eventually(t, func() bool { return resolver.lookupCount() >= 2 }, 5*time.Second, interval)
+	before := reresolved.Value()
+	// Те же адреса при следующих неудачах транспорт не пересоздают
+	require.Eventually(t, func() bool { return resolver.lookupCount() >= 5 }, 5*time.Second, interval)
+	assert.Equal(t, before, reresolved.Value())
+
+	// Контейнер пересоздан с новым адресом
+	resolver.set("10.0.0.2")
+	require.Eventually(t, func() bool { return reresolved.Value() == before+1 }, 5*time.Second, interval)
+
+	healthy.Store(true)
+	require.Eventually(t, func() bool { return lb.Snapshot().Healthy == 1 }, 5*time.Second, interval)
+	rr := httptest.NewRecorder()
+	lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "ok", rr.Body.String())
+}
+
+// TestIntegration_AccessList проверяет, что клиенты из списка запрета получают 403,
+// а клиенты из списка разрешения не ограничиваются Rate Limiter.
+func TestIntegration_AccessList(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 0.001, DefaultCapacity: 1, IdentifierHeader: "X-Client-ID",
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	list, err := access.New(config.AccessListConfig{Deny: []string{"banned"}, Allow: []string{"partner"}}, nil)
+	require.NoError(t, err)
+	lb.SetAccessList(list)
+
+	body, code := sendRequest(t, lb, "banned", "X-Client-ID")
+	require.Equal(t, http.StatusForbidden, code)
+	var errResp response.ErrorResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &errResp))
+	assert.Equal(t, response.CodeAccessDenied, errResp.ErrorCode)
+
+	for i := 0; i < 3; i++ {
+		_, code = sendRequest(t, lb, "partner", "X-Client-ID")
+		assert.Equal(t, http.StatusOK, code, "Клиент из списка разрешения не ограничивается")
+	}
+
+	_, code = sendRequest(t, lb, "regular", "X-Client-ID")
+	assert.Equal(t, http.StatusOK, code)
+	_, code = sendRequest(t, lb, "regular", "X-Client-ID")
+	assert.Equal(t, http.StatusTooManyRequests, code, "Остальные клиенты ограничиваются как обычно")
+}
+
+// TestIntegration_CompositeLimitKey проверяет лимит клиента на каждый маршрут (key_template "{client_id}:{path_prefix}").
+func TestIntegration_CompositeLimitKey(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	parts, err := config.ParseKeyTemplate("{client_id}:{path_prefix}")
+	require.NoError(t, err)
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 0.001, DefaultCapacity: 1, IdentifierHeader: "X-Client-ID", KeyParts: parts,
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetRoutes(config.HeaderPolicyConfig{}, []config.RouteConfig{{PathPrefix: "/api"}, {PathPrefix: "/static"}})
+
+	send := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Client-ID", "tenant")
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, send("/api/orders"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/api/users"), "Один маршрут - одна корзина")
+	assert.Equal(t, http.StatusOK, send("/static/app.js"), "Другой маршрут - отдельная корзина")
+	assert.Equal(t, http.StatusOK, send("/"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/other"), "Пути вне маршрутов делят корзину маршрута по умолчанию")
+}
+
+// TestIntegration_ResponseValidation проверяет, что ответ 200 с неверным Content-Type или пустым телом
+// считается ошибкой бэкенда: клиент получает 502, а бэкенд исключается на время hold_down.
+func TestIntegration_ResponseValidation(t *testing.T) {
+	var brokenMode atomic.Value
+	brokenMode.Store("html")
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch brokenMode.Load() {
+		case "html":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = io.WriteString(w, "<html>maintenance</html>")
+		case "empty":
+			// Пустой потоковый ответ: Content-Length неизвестен
+			w.Header().Set("Content-Type", "application/json")
+			w.(http.Flusher).Flush()
+		default:
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_, _ = io.WriteString(w, `{"backend":"broken"}`)
+		}
+	}))
+	defer broken.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"backend":"healthy"}`)
+	}))
+	defer healthy.Close()
+
+	lb, err := balancer.New([]string{broken.URL, healthy.URL}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetRoutes(config.HeaderPolicyConfig{}, []config.RouteConfig{{
+		PathPrefix:         "/api",
+		ResponseValidation: &config.ResponseValidationConfig{ContentTypes: []string{"application/json"}, NonEmptyBody: true},
+	}})
+	holdDown := 200 * time.Millisecond
+	lb.SetHoldDown(holdDown)
+	invalid := metrics.NewCounter("balancer_upstream_invalid_responses_total", "")
+	before := invalid.Value()
+
+	send := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	// Маршрут без проверки: HTML от бэкенда передается клиенту
+	code, body := send("/static")
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "maintenance")
+	send("/static") // Следующий запрос - второму бэкенду
+
+	code, _ = send("/api/orders")
+	require.Equal(t, http.StatusBadGateway, code, "HTML на маршруте /api - ошибка бэкенда")
+	assert.Equal(t, before+1, invalid.Value())
+	heldUntil := lb.Snapshot().Backends[0].HeldUntil
+	require.NotNil(t, heldUntil, "Бэкенд исключен")
+	assert.WithinDuration(t, time.Now().Add(holdDown), *heldUntil, holdDown)
+
+	for i := 0; i < 3; i++ {
+		code, body = send("/api/orders")
+		require.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "healthy", "Пока бэкенд исключен, запросы идут на второй")
+	}
+
+	// После hold_down бэкенд возвращается; пустое потоковое тело тоже считается ошибкой
+	brokenMode.Store("empty")
+	require.Eventually(t, func() bool { return lb.Snapshot().Backends[0].Alive }, time.Second, 10*time.Millisecond)
+	statuses := map[int]int{}
+	for i := 0; i < 2; i++ {
+		code, _ = send("/api/orders")
+		statuses[code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusOK: 1, http.StatusBadGateway: 1}, statuses)
+
+	brokenMode.Store("json")
+	require.Eventually(t, func() bool { return lb.Snapshot().Backends[0].Alive }, time.Second, 10*time.Millisecond)
+	bodies := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		code, body = send("/api/orders")
+		require.Equal(t, http.StatusOK, code)
+		bodies[body] = true
+	}
+	assert.Len(t, bodies, 2, "Исправный ответ с параметрами Content-Type проходит проверку")
+}
+
+// TestIntegration_BackendSaturation проверяет, что почти заполненный бэкенд обходится,
+// пока есть менее загруженные, а заполненный не выбирается совсем.
+func TestIntegration_BackendSaturation(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_, _ = io.WriteString(w, "slow")
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "fast")
+	}))
+	defer fast.Close()
+
+	lb, err := balancer.New([]string{slow.URL, fast.URL}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetBackendMaxConnections(map[string]int{slow.URL: 2, fast.URL: 10})
+	lb.SetSaturationThreshold(0.5)
+	slowBackend := lb.Backend(0)
+
+	send := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+
+	// Первый запрос по Round Robin уходит на медленный бэкенд и занимает половину его соединений
+	go send()
+	require.Eventually(t, func() bool { return slowBackend.InFlight() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 0.5, slowBackend.Saturation())
+
+	for i := 0; i < 4; i++ {
+		w := send()
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "fast", w.Body.String(), "Почти заполненный бэкенд обходится")
+	}
+
+	state := lb.Snapshot().Backends[0]
+	assert.Equal(t, int64(1), state.InFlight)
+	assert.Equal(t, int64(2), state.MaxConnections)
+	assert.Equal(t, 0.5, state.Saturation)
+
+	// Пул из одного бэкенда: почти заполненный выбирается, заполненный - нет
+	single, err := balancer.New([]string{slow.URL}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "random")
+	require.NoError(t, err)
+	single.SetBackendMaxConnections(map[string]int{slow.URL: 2})
+	single.SetSaturationThreshold(0.5)
+	singleBackend := single.Backend(0)
+	for i := 0; i < 2; i++ {
+		go single.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Eventually(t, func() bool { return singleBackend.InFlight() == int64(i+1) }, time.Second, 5*time.Millisecond)
+	}
+
+	w := httptest.NewRecorder()
+	single.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), response.CodeOverloaded)
+}
+
+// TestIntegration_FailureBudget проверяет, что бэкенд исключается только после
+// passive_health.max_failures ошибок за failure_window.
+func TestIntegration_FailureBudget(t *testing.T) {
+	// Бэкенд сбрасывает соединение, не отвечая
+	resetting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		conn.Close()
+	}))
+	defer resetting.Close()
+
+	lb, err := balancer.New([]string{resetting.URL}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	window := 300 * time.Millisecond
+	lb.SetFailureBudget(3, window)
+	backend := lb.Backend(0)
+
+	send := func() int {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusBadGateway, send())
+		assert.True(t, backend.IsAlive(), "Ошибки в пределах бюджета не исключают бэкенд")
+	}
+	assert.Equal(t, 2, lb.Snapshot().Backends[0].RecentFailures)
+
+	// Ошибки за пределами окна не учитываются
+	time.Sleep(window + 50*time.Millisecond)
+	assert.Equal(t, 0, lb.Snapshot().Backends[0].RecentFailures)
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusBadGateway, send())
+	}
+	assert.True(t, backend.IsAlive())
+
+	require.Equal(t, http.StatusBadGateway, send())
+	assert.False(t, backend.IsAlive(), "Третья ошибка за окно исключает бэкенд")
+}
+
+// TestIntegration_PassiveHealth5xx проверяет учет ответов 5xx, обнуление счетчика успешным ответом,
+// параметры отдельного бэкенда и плавный возврат бэкенда после hold_down.
+func TestIntegration_PassiveHealth5xx(t *testing.T) {
+	var failing atomic.Bool
+	var hitsA, hitsB atomic.Int64
+	backendA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hitsA.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer backendA.Close()
+	// Ответы 5xx второго бэкенда не учитываются: count_5xx включен только для первого
+	backendB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hitsB.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer backendB.Close()
+
+	lb, err := balancer.New([]string{backendA.URL, backendB.URL}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	holdDown := 300 * time.Millisecond
+	lb.SetPassiveHealth(config.PassiveHealthConfig{FailureWindow: time.Minute})
+	lb.SetBackendPassiveHealth(map[string]config.PassiveHealthConfig{
+		backendA.URL: {
+			HoldDown: holdDown, MaxFailures: 2, FailureWindow: time.Minute,
+			Count5xx: true, Consecutive: true, SlowStart: 5 * time.Second,
+		},
+	})
+	a := lb.Backend(0)
+
+	send := func(n int) {
+		for i := 0; i < n; i++ {
+			w := httptest.NewRecorder()
+			lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			require.Equal(t, http.StatusInternalServerError, w.Code, "Ответ 5xx передается клиенту как есть")
+		}
+	}
+	failing.Store(true)
+	send(2)
+	assert.Equal(t, 1, lb.Snapshot().Backends[0].RecentFailures)
+	assert.True(t, lb.Snapshot().Backends[1].Alive)
+
+	// Успешный ответ обнуляет счетчик: считаются только ошибки подряд
+	failing.Store(false)
+	lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, 0, lb.Snapshot().Backends[0].RecentFailures)
+	send(1)
+
+	failing.Store(true)
+	send(3)
+	assert.False(t, a.IsAlive(), "Вторая ошибка подряд исключает бэкенд")
+	_, held := a.HeldUntil()
+	assert.True(t, held)
+
+	// После hold_down бэкенд возвращается с долей веса и получает меньше запросов
+	failing.Store(false)
+	time.Sleep(holdDown + 50*time.Millisecond)
+	require.True(t, a.IsAlive())
+	percent := lb.Snapshot().Backends[0].SlowStartPercent
+	assert.GreaterOrEqual(t, percent, config.SlowStartMinPercent)
+	assert.Less(t, percent, 100)
+
+	hitsA.Store(0)
+	hitsB.Store(0)
+	for i := 0; i < 20; i++ {
+		lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Positive(t, hitsA.Load())
+	assert.Less(t, hitsA.Load(), hitsB.Load()/2)
+}
+
+// TestIntegration_SlowStartAfterHealthCheck проверяет, что бэкенд, признанный рабочим активной проверкой
+// после отказа, получает сначала долю трафика (passive_health.slow_start), а не полный слот Round Robin.
+func TestIntegration_SlowStartAfterHealthCheck(t *testing.T) {
+	handler0 := newHealthAwareHandler(0, "/healthz")
+	handler1 := newHealthAwareHandler(1, "/healthz")
+	backend0 := httptest.NewServer(handler0)
+	defer backend0.Close()
+	backend1 := httptest.NewServer(handler1)
+	defer backend1.Close()
+
+	lb, err := balancer.New([]string{backend0.URL, backend1.URL}, ratelimiter.NewDisabled(), config.HealthCheckConfig{
+		Enabled: true, Interval: time.Hour, Timeout: time.Second, Path: "/healthz",
+	}, "round_robin")
+	require.NoError(t, err)
+	defer lb.StopHealthChecks()
+	lb.SetPassiveHealth(config.PassiveHealthConfig{SlowStart: time.Minute})
+
+	handler0.setHealth(false)
+	_, err = lb.CheckNow("0")
+	require.NoError(t, err)
+	require.False(t, lb.Snapshot().Backends[0].Alive)
+
+	handler0.setHealth(true)
+	_, err = lb.CheckNow("0")
+	require.NoError(t, err)
+	recovered := lb.Snapshot().Backends[0]
+	require.True(t, recovered.Alive)
+	assert.Equal(t, config.SlowStartMinPercent, recovered.SlowStartPercent)
+
+	counts := map[string]int{}
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		counts[strings.Fields(w.Body.String())[1]]++
+	}
+	assert.Positive(t, counts["0"], "Вернувшийся бэкенд получает трафик")
+	assert.Less(t, counts["0"], counts["1"]/3, "Но меньше полного слота")
+}
+
+// TestIntegration_ClientDisconnect проверяет, что отключение клиента отменяет запрос к бэкенду
+// и не считается ошибкой бэкенда.
+func TestIntegration_ClientDisconnect(t *testing.T) {
+	started := make(chan struct{})
+	upstreamCanceled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-r.Context().Done():
+			close(upstreamCanceled)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	lb, err := balancer.New([]string{slow.URL}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	disconnects := metrics.NewCounter("balancer_client_disconnects_total", "")
+	before := disconnects.Value()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/download", nil).WithContext(ctx))
+	}()
+
+	<-started
+	cancel() // Клиент закрыл соединение
+	select {
+	case <-upstreamCanceled:
+	case <-time.After(time.Second):
+		t.Fatal("Запрос к бэкенду не отменен")
+	}
+	<-done
+
+	assert.Equal(t, balancer.StatusClientClosedRequest, w.Code)
+	assert.Equal(t, before+1, disconnects.Value())
+	assert.True(t, lb.Snapshot().Backends[0].Alive, "Отключение клиента не исключает бэкенд")
+}
+
+// TestIntegration_ConsistentHash проверяет, что клиент при consistent_hash попадает на один и тот же бэкенд,
+// при его отказе переходит на другой, а после восстановления возвращается.
+func TestIntegration_ConsistentHash(t *testing.T) {
+	var urls []string
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("backend%d", i)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name)
+		}))
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+	lb, err := balancer.New(urls, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "consistent_hash")
+	require.NoError(t, err)
+
+	send := func(clientIP string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = clientIP + ":40000"
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	assigned := make(map[string]string)
+	used := make(map[string]bool)
+	for i := 0; i < 30; i++ {
+		client := fmt.Sprintf("10.0.0.%d", i)
+		assigned[client] = send(client)
+		used[assigned[client]] = true
+		for j := 0; j < 3; j++ {
+			assert.Equal(t, assigned[client], send(client), "Клиент %s остается на своем бэкенде", client)
+		}
+	}
+	assert.Len(t, used, 3, "Клиенты распределяются по всем бэкендам")
+
+	lb.Backend(0).SetAlive(false)
+	for client, backend := range assigned {
+		got := send(client)
+		if backend == "backend0" {
+			assert.NotEqual(t, "backend0", got)
+		} else {
+			assert.Equal(t, backend, got, "Клиенты других бэкендов не перемещаются")
+		}
+	}
+
+	lb.Backend(0).SetAlive(true)
+	for client, backend := range assigned {
+		assert.Equal(t, backend, send(client), "После восстановления клиент возвращается")
+	}
+}
+
+// TestIntegration_ConcurrentRouteHeaders проверяет, что параллельные запросы к одному бэкенду по маршрутам
+// с разными политиками заголовков не влияют друг на друга.
+func TestIntegration_ConcurrentRouteHeaders(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s secret=%t", r.URL.Path, r.Header.Get("X-Secret") != "")
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetRoutes(config.HeaderPolicyConfig{}, []config.RouteConfig{
+		{PathPrefix: "/public", Headers: config.HeaderPolicyConfig{Deny: []string{"X-Secret"}}},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				path, want := "/private", "/private secret=true"
+				if (i+j)%2 == 0 {
+					path, want = "/public", "/public secret=false"
+				}
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				req.Header.Set("X-Secret", "s3cret")
+				rr := httptest.NewRecorder()
+				lb.ServeHTTP(rr, req)
+				assert.Equal(t, want, rr.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestIntegration_HopByHopHeaders проверяет, что заголовки соединения не пересылаются ни бэкенду, ни клиенту.
+func TestIntegration_HopByHopHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.Header().Set("Connection", "X-Backend-Hop")
+		w.Header().Set("X-Backend-Hop", "1")
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.Header().Set("Proxy-Authenticate", "Basic")
+		w.Header().Set("X-Backend", "1")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Connection", "X-Client-Hop")
+	req.Header.Set("X-Client-Hop", "1")
+	req.Header.Set("Keep-Alive", "timeout=5")
+	req.Header.Set("Proxy-Authorization", "Basic c2VjcmV0")
+	req.Header.Set("Te", "trailers")
+	req.Header.Set("X-Client", "1")
+	rr := httptest.NewRecorder()
+	lb.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	got := <-received
+	for _, name := range []string{"X-Client-Hop", "Keep-Alive", "Proxy-Authorization"} {
+		assert.Empty(t, got.Get(name), name)
+	}
+	assert.Equal(t, "trailers", got.Get("Te"), "TE: trailers пересылается бэкенду")
+	assert.Equal(t, "1", got.Get("X-Client"))
+
+	for _, name := range []string{"Connection", "X-Backend-Hop", "Keep-Alive", "Proxy-Authenticate"} {
+		assert.Empty(t, rr.Header().Get(name), name)
+	}
+	assert.Equal(t, "1", rr.Header().Get("X-Backend"))
+}
+
+// TestIntegration_ProtocolUpgrade проверяет, что смена протокола (WebSocket) проходит через балансировщик.
+func TestIntegration_ProtocolUpgrade(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("Connection") != "Upgrade" || r.Header.Get("Keep-Alive") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(buf, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		buf.Flush()
+		line, _ := buf.ReadString('\n')
+		fmt.Fprint(conn, "echo: "+line)
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	server := httptest.NewServer(lb)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /ws HTTP/1.1\r\nHost: example.com\r\nConnection: keep-alive, Upgrade\r\nKeep-Alive: timeout=5\r\nUpgrade: websocket\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "websocket", resp.Header.Get("Upgrade"))
+
+	fmt.Fprint(conn, "ping\n")
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "echo: ping\n", line)
+}
+
+// TestIntegration_StatsHistory проверяет учет запросов и ошибок бэкенда по секундам.
+func TestIntegration_StatsHistory(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	assert.Nil(t, lb.TimeSeries(time.Now(), 0), "Без stats_history история не ведется")
+
+	lb.SetStatsHistory(time.Minute)
+	assert.Equal(t, 60, lb.HistorySize())
+	for _, path := range []string{"/", "/a", "/fail", "/b", "/fail"} {
+		rr := httptest.NewRecorder()
+		lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	series := lb.TimeSeries(time.Now(), 0)
+	require.Len(t, series, 1)
+	assert.Equal(t, backend.URL, series[0].URL)
+	require.Len(t, series[0].Requests, 60)
+	require.Len(t, series[0].Errors, 60)
+	sum := func(values []int64) (total int64) {
+		for _, v := range values {
+			total += v
+		}
+		return total
+	}
+	assert.Equal(t, int64(5), sum(series[0].Requests))
+	assert.Equal(t, int64(2), sum(series[0].Errors), "Ответы 5xx считаются ошибками")
+
+	// Запросы старше окна не попадают в историю
+	series = lb.TimeSeries(time.Now().Add(2*time.Minute), 10)
+	require.Len(t, series[0].Requests, 10)
+	assert.Zero(t, sum(series[0].Requests))
+}
+
+// TestIntegration_UpstreamThrottlingRetry проверяет повтор запроса на другом бэкенде после ответа 429/503.
+func TestIntegration_UpstreamThrottlingRetry(t *testing.T) {
+	var throttledHits atomic.Int64
+	throttled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		throttledHits.Add(1)
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "busy")
+	}))
+	defer throttled.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "ok")
+	}))
+	defer healthy.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{throttled.URL, healthy.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetUpstreamThrottling(config.ThrottleRetry, time.Minute, 25)
+
+	for i := 0; i < 4; i++ {
+		rr := httptest.NewRecorder()
+		lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, rr.Code, "Запрос без тела повторяется на другом бэкенде")
+		assert.Equal(t, "ok", rr.Body.String())
+	}
+	// Повтор занимает очередь Round Robin второго бэкенда, поэтому каждый запрос сначала попадает на первый
+	assert.Equal(t, int64(4), throttledHits.Load())
+	assert.True(t, lb.Snapshot().Backends[0].Alive, "Ответ 503 не исключает бэкенд")
+
+	// Запрос с телом не повторяется: ответ передается как есть
+	lb.Backend(1).SetAlive(false)
+	rr := httptest.NewRecorder()
+	lb.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("payload")))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "busy", rr.Body.String())
+
+	// Повторить негде: клиент получает статус и заголовки ответа бэкенда
+	rr = httptest.NewRecorder()
+	lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "5", rr.Header().Get("Retry-After"))
+}
+
+// TestIntegration_UpstreamAuth проверяет учетные данные, которые балансировщик добавляет к запросам на бэкенды.
+func TestIntegration_UpstreamAuth(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	probes := make(chan *http.Request, 4)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			select {
+			case probes <- r:
+			default:
+			}
+			return
+		}
+		received <- r
+	}))
+	defer backend.Close()
+
+	hc := config.HealthCheckConfig{Enabled: true, Interval: time.Hour, Timeout: time.Second, Path: "/health"}
+	newBalancer := func(auth config.UpstreamAuthConfig) *balancer.Balancer {
+		lb, err := balancer.New([]string{backend.URL}, ratelimiter.NewDisabled(), hc, "round_robin")
+		require.NoError(t, err)
+		t.Cleanup(lb.StopHealthChecks)
+		lb.SetRoutes(config.HeaderPolicyConfig{}, []config.RouteConfig{{PathPrefix: "/partner", Untrusted: true}})
+		lb.SetUpstreamAuth(auth)
+		return lb
+	}
+	send := func(lb *balancer.Balancer, target string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer client")
+		req.Header.Set(balancer.UpstreamSignatureHeader, "forged")
+		rr := httptest.NewRecorder()
+		lb.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		return <-received
+	}
+
+	// bearer заменяет Authorization клиента; недоверенному маршруту токен не отправляется
+	lb := newBalancer(config.UpstreamAuthConfig{Type: config.UpstreamAuthBearer, Header: "Authorization", Token: "t0ken"})
+	assert.Equal(t, "Bearer t0ken", send(lb, "/api").Header.Get("Authorization"))
+	assert.Equal(t, "Bearer client", send(lb, "/partner/orders").Header.Get("Authorization"))
+
+	// basic в отдельном заголовке оставляет Authorization клиента
+	lb = newBalancer(config.UpstreamAuthConfig{Type: config.UpstreamAuthBasic, Header: "X-Upstream-Auth", Username: "lb", Password: "secret"})
+	got := send(lb, "/api")
+	assert.Equal(t, "Basic bGI6c2VjcmV0", got.Header.Get("X-Upstream-Auth"))
+	assert.Equal(t, "Bearer client", got.Header.Get("Authorization"))
+
+	// hmac подписывает метод, путь и время, подпись клиента заменяется
+	secret := []byte("hmac-secret")
+	lb = newBalancer(config.UpstreamAuthConfig{Type: config.UpstreamAuthHMAC, Secret: string(secret)})
+	got = send(lb, "/partner/orders?page=2")
+	timestamp := got.Header.Get(balancer.UpstreamTimestampHeader)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), time.Unix(unix, 0), 5*time.Second)
+	assert.Equal(t, balancer.SignUpstreamRequest(secret, timestamp, http.MethodGet, "/partner/orders?page=2"),
+		got.Header.Get(balancer.UpstreamSignatureHeader))
+
+	// Активные проверки тоже подписываются
+	for len(probes) > 0 {
+		<-probes
+	}
+	results, err := lb.CheckNow("")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	got = <-probes
+	timestamp = got.Header.Get(balancer.UpstreamTimestampHeader)
+	assert.Equal(t, balancer.SignUpstreamRequest(secret, timestamp, http.MethodGet, "/health"),
+		got.Header.Get(balancer.UpstreamSignatureHeader))
+}
+
+// TestIntegration_UpstreamThrottlingReduceWeight проверяет временное снижение веса бэкенда после ответа 429.
+func TestIntegration_UpstreamThrottlingReduceWeight(t *testing.T) {
+	var hits [2]atomic.Int64
+	newBackend := func(i int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[i].Add(1)
+			if r.URL.Path == "/limited" {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+	backend0, backend1 := newBackend(0), newBackend(1)
+	defer backend0.Close()
+	defer backend1.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend0.URL, backend1.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetUpstreamThrottling(config.ThrottleReduceWeight, time.Minute, 25)
+
+	rr := httptest.NewRecorder()
+	lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/limited", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "Ответ передается клиенту")
+	require.NotNil(t, lb.Snapshot().Backends[0].ThrottledUntil)
+
+	hits[0].Store(0)
+	hits[1].Store(0)
+	for i := 0; i < 50; i++ {
+		lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	// Вес 25% против 100%: бэкенд получает около пятой части запросов
+	assert.InDelta(t, 10, hits[0].Load(), 2)
+	assert.Equal(t, int64(50), hits[0].Load()+hits[1].Load())
+}
+
+// TestIntegration_CanaryRollback проверяет процентное разделение запросов между стабильными
+// и канареечными бэкендами и автоматический откат канарейки, отвечающей ошибками.
+func TestIntegration_CanaryRollback(t *testing.T) {
+	var stableHits, canaryHits atomic.Int64
+	stable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stableHits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer stable.Close()
+	canary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		canaryHits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer canary.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{stable.URL, canary.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetBackendLabels(map[string]map[string]string{canary.URL: {"version": "v2"}})
+	lb.SetCanary(config.CanaryConfig{
+		Selector:          map[string]string{"version": "v2"},
+		Percent:           50,
+		Window:            time.Minute,
+		MinRequests:       10,
+		MaxErrorRateDelta: 0.1,
+		AutoRollback:      true,
+	})
+	get := func() {
+		lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	// Группы сравниваются раз в секунду, когда у обеих набралось min_requests запросов
+	require.Eventually(t, func() bool {
+		for range 20 {
+			get()
+		}
+		report, _ := lb.Canary(time.Now())
+		return report.RolledBack
+	}, 5*time.Second, 50*time.Millisecond)
+
+	report, ok := lb.Canary(time.Now())
+	require.True(t, ok)
+	assert.Equal(t, 1, report.Canary.Backends)
+	assert.Equal(t, 1.0, report.Canary.ErrorRate)
+	assert.Zero(t, report.Stable.ErrorRate)
+	assert.True(t, report.Degraded)
+	assert.NotEmpty(t, report.RollbackReason)
+	require.NotNil(t, report.RolledBackAt)
+	assert.Equal(t, stableHits.Load(), report.Stable.Requests)
+	assert.Equal(t, canaryHits.Load(), report.Canary.Requests)
+
+	// После отката все запросы получают стабильные бэкенды
+	before := canaryHits.Load()
+	for range 20 {
+		get()
+	}
+	assert.Equal(t, before, canaryHits.Load())
+
+	// Возобновление сбрасывает статистику и снова направляет часть запросов канарейке
+	require.True(t, lb.ResumeCanary())
+	report, _ = lb.Canary(time.Now())
+	assert.False(t, report.RolledBack)
+	assert.Zero(t, report.Canary.Requests)
+	for range 20 {
+		get()
+	}
+	assert.Greater(t, canaryHits.Load(), before)
+}
+
+// TestIntegration_DrainBackend проверяет ручной вывод бэкенда из балансировки: новые запросы его
+// не получают, а запрос в обработке завершается.
+func TestIntegration_DrainBackend(t *testing.T) {
+	release := make(chan struct{})
+	var hits [2]atomic.Int64
+	newBackend := func(i int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[i].Add(1)
+			if r.URL.Path == "/slow" {
+				<-release
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+	backend0, backend1 := newBackend(0), newBackend(1)
+	defer backend0.Close()
+	defer backend1.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	lb, err := balancer.New([]string{backend0.URL, backend1.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	// Первый запрос Round Robin уходит на бэкенд 0 и ждет, пока его не отпустят
+	slow := make(chan int)
+	go func() {
+		rr := httptest.NewRecorder()
+		lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		slow <- rr.Code
+	}()
+	require.Eventually(t, func() bool { return lb.Snapshot().Backends[0].InFlight == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	idx, err := lb.SetDrained(backend0.URL, true)
+	require.NoError(t, err)
+	assert.Equal(t, 0, idx)
+	state := lb.Snapshot()
+	assert.True(t, state.Backends[0].Drained)
+	assert.True(t, state.Backends[0].Alive, "Вывод не меняет статус проверок")
+	assert.Equal(t, 1, state.Available)
+
+	for range 4 {
+		rr := httptest.NewRecorder()
+		lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+	assert.Equal(t, int64(1), hits[0].Load(), "Выведенный бэкенд не получает новых запросов")
+	assert.Equal(t, int64(4), hits[1].Load())
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-slow, "Запрос в обработке завершается")
+
+	_, err = lb.SetDrained("0", false)
+	require.NoError(t, err)
+	for range 4 {
+		lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Equal(t, int64(3), hits[0].Load())
+
+	_, err = lb.SetDrained("5", true)
+	assert.ErrorIs(t, err, balancer.ErrBackendNotFound)
+	_, err = lb.SetDrained("", true)
+	assert.ErrorIs(t, err, balancer.ErrBackendNotFound)
+}
+
+// TestIntegration_AddRemoveBackend проверяет добавление и удаление бэкендов во время работы:
+// новый бэкенд получает запросы после проверки, удаленный - нет, а ID остальных не меняются.
+func TestIntegration_AddRemoveBackend(t *testing.T) {
+	newServer := func(name string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	backend0, backend1, backend2 := newServer("backend0"), newServer("backend1"), newServer("backend2")
+
+	hc := config.HealthCheckConfig{Enabled: true, Interval: time.Hour, Timeout: time.Second, Path: "/"}
+	lb, err := balancer.New([]string{backend0.URL, backend1.URL}, ratelimiter.NewDisabled(), hc, "round_robin")
+	require.NoError(t, err)
+	t.Cleanup(lb.StopHealthChecks)
+	require.Eventually(t, func() bool { healthy, _ := lb.HealthyBackends(); return healthy == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	send := func() map[string]int {
+		seen := make(map[string]int)
+		for range 6 {
+			rr := httptest.NewRecorder()
+			lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			require.Equal(t, http.StatusOK, rr.Code)
+			seen[rr.Body.String()]++
+		}
+		return seen
+	}
+
+	idx, err := lb.AddBackend(balancer.BackendSpec{URL: backend2.URL, Labels: map[string]string{"zone": "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, idx)
+	state := lb.Snapshot()
+	require.Len(t, state.Backends, 3)
+	assert.True(t, state.Backends[2].Alive, "Новый бэкенд проверяется сразу")
+	assert.Equal(t, map[string]string{"zone": "b"}, state.Backends[2].Labels)
+	assert.Equal(t, map[string]int{"backend0": 2, "backend1": 2, "backend2": 2}, send())
+
+	_, err = lb.AddBackend(balancer.BackendSpec{URL: backend2.URL + "/"})
+	assert.ErrorIs(t, err, balancer.ErrBackendExists)
+	_, err = lb.AddBackend(balancer.BackendSpec{URL: "not-a-url"})
+	assert.Error(t, err)
+
+	idx, err = lb.RemoveBackend(backend0.URL)
+	require.NoError(t, err)
+	assert.Equal(t, 0, idx)
+	state = lb.Snapshot()
+	assert.Equal(t, 2, state.Total)
+	require.Len(t, state.Backends, 2)
+	assert.Equal(t, 1, state.Backends[0].Index, "ID остальных бэкендов не меняются")
+	assert.Equal(t, 2, state.Backends[1].Index)
+	seen := send()
+	assert.NotContains(t, seen, "backend0", "Удаленный бэкенд не получает запросов")
+	assert.Len(t, seen, 2)
+
+	_, err = lb.RemoveBackend("0")
+	assert.ErrorIs(t, err, balancer.ErrBackendNotFound, "Удаленный бэкенд не находится")
+	_, err = lb.CheckNow("0")
+	assert.ErrorIs(t, err, balancer.ErrBackendNotFound)
+
+	// Бэкенд с тем же URL можно добавить снова - он получает новый ID
+	idx, err = lb.AddBackend(balancer.BackendSpec{URL: backend0.URL})
+	require.NoError(t, err)
+	assert.Equal(t, 3, idx)
+
+	_, err = lb.RemoveBackend("1")
+	require.NoError(t, err)
+	_, err = lb.RemoveBackend("2")
+	require.NoError(t, err)
+	_, err = lb.RemoveBackend("3")
+	assert.ErrorIs(t, err, balancer.ErrLastBackend)
+}
+
+// TestIntegration_RemoveBackendCompaction проверяет, что удаленные бэкенды убираются из пула:
+// сразу, если запросов к ним нет, или после завершения последнего запроса. Пул не растет
+// при многократном добавлении и удалении, а ID бэкендов не меняются и не используются повторно.
+func TestIntegration_RemoveBackendCompaction(t *testing.T) {
+	release := make(chan struct{})
+	backend0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		_, _ = io.WriteString(w, "backend0")
+	}))
+	defer backend0.Close()
+	backend1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "backend1")
+	}))
+	defer backend1.Close()
+	backend2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "backend2")
+	}))
+	defer backend2.Close()
+
+	lb, err := balancer.New([]string{backend0.URL, backend1.URL}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+
+	for i := range 50 {
+		id, err := lb.AddBackend(balancer.BackendSpec{URL: backend2.URL})
+		require.NoError(t, err)
+		assert.Equal(t, 2+i, id, "ID удаленных бэкендов не используются повторно")
+		rr := httptest.NewRecorder()
+		lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		_, err = lb.RemoveBackend(strconv.Itoa(id))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, lb.PoolSize(), "Удаленные бэкенды без запросов убираются сразу")
+
+	// Первый запрос Round Robin уходит на бэкенд 0 и ждет, пока его не отпустят
+	slow := make(chan int)
+	go func() {
+		rr := httptest.NewRecorder()
+		lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		slow <- rr.Code
+	}()
+	require.Eventually(t, func() bool { return lb.Snapshot().Backends[0].InFlight == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	_, err = lb.RemoveBackend(backend0.URL)
+	require.NoError(t, err)
+	assert.Nil(t, lb.Backend(0))
+	assert.Equal(t, 2, lb.PoolSize(), "Бэкенд с запросом в обработке остается в пуле")
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-slow, "Запрос в обработке завершается")
+	assert.Equal(t, 1, lb.PoolSize(), "Бэкенд убирается после завершения последнего запроса")
+	state := lb.Snapshot()
+	require.Len(t, state.Backends, 1)
+	assert.Equal(t, 1, state.Backends[0].Index)
+	require.NotNil(t, lb.Backend(1))
+	assert.Equal(t, backend1.URL, lb.Backend(1).URL.String())
+
+	rr := httptest.NewRecorder()
+	lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "backend1", rr.Body.String())
+}
+
+// TestIntegration_RemoveBackendConsistentHash проверяет, что при удалении бэкенда из пула consistent_hash
+// перемещаются только его клиенты, а добавленный бэкенд получает часть клиентов.
+func TestIntegration_RemoveBackendConsistentHash(t *testing.T) {
+	var urls []string
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("backend%d", i)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name)
+		}))
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+	lb, err := balancer.New(urls[:3], ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "consistent_hash")
+	require.NoError(t, err)
+
+	send := func(clientIP string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = clientIP + ":40000"
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	assigned := make(map[string]string)
+	for i := 0; i < 30; i++ {
+		client := fmt.Sprintf("10.0.0.%d", i)
+		assigned[client] = send(client)
+	}
+
+	_, err = lb.RemoveBackend("0")
+	require.NoError(t, err)
+	for client, backend := range assigned {
+		got := send(client)
+		if backend == "backend0" {
+			assert.NotEqual(t, "backend0", got)
+		} else {
+			assert.Equal(t, backend, got, "Клиенты других бэкендов не перемещаются")
+		}
+	}
+
+	_, err = lb.AddBackend(balancer.BackendSpec{URL: urls[3]})
+	require.NoError(t, err)
+	moved := 0
+	for client := range assigned {
+		if send(client) == "backend3" {
+			moved++
+		}
+	}
+	assert.Positive(t, moved, "Новый бэкенд получает часть клиентов")
+}
+
+// TestIntegration_StickySessions проверяет привязку клиента к бэкенду по подписанной cookie.
+func TestIntegration_StickySessions(t *testing.T) {
+	var urls []string
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("backend%d", i)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name)
+		}))
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+	lb, err := balancer.New(urls, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetStickySessions(config.StickySessionConfig{Enabled: true, Secret: "test-secret", TTL: time.Hour})
+
+	send := func(cookie *http.Cookie) (string, *http.Cookie) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var issued *http.Cookie
+		for _, c := range w.Result().Cookies() {
+			if c.Name == config.DefaultStickyCookieName {
+				issued = c
+			}
+		}
+		return w.Body.String(), issued
+	}
+
+	first, cookie := send(nil)
+	require.NotNil(t, cookie, "Первый ответ выдает cookie привязки")
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, 3600, cookie.MaxAge)
+	assert.NotContains(t, cookie.Value, "127.0.0.1", "Адрес бэкенда не раскрывается")
+	for i := 0; i < 5; i++ {
+		got, reissued := send(cookie)
+		assert.Equal(t, first, got, "Запросы с cookie идут на тот же бэкенд")
+		assert.Nil(t, reissued, "Действующая cookie не выдается заново")
+	}
+
+	forged := *cookie
+	forged.Value = cookie.Value[:strings.Index(cookie.Value, ".")] + ".forged"
+	_, reissued := send(&forged)
+	assert.NotNil(t, reissued, "Cookie с неверной подписью заменяется")
+
+	// Бэкенд клиента недоступен: запрос уходит на другой, cookie указывает на новый бэкенд
+	stuck := int(first[len(first)-1] - '0')
+	lb.Backend(stuck).SetAlive(false)
+	fallback, moved := send(cookie)
+	assert.NotEqual(t, first, fallback)
+	require.NotNil(t, moved)
+	lb.Backend(stuck).SetAlive(true)
+	got, _ := send(moved)
+	assert.Equal(t, fallback, got, "Клиент остается на новом бэкенде")
+}
+
+// TestIntegration_TCPHealthCheck проверяет проверку health_check.type: tcp на бэкенде без HTTP:
+// бэкенд исправен, пока принимает соединения, а HTTP-проверка того же бэкенда не проходит.
+func TestIntegration_TCPHealthCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close() // Бэкенд не говорит по HTTP
+		}
+	}()
+	backendURL := "http://" + ln.Addr().String()
+
+	newBalancer := func(checkType string) *balancer.Balancer {
+		hc := config.HealthCheckConfig{Enabled: true, Interval: time.Hour, Timeout: time.Second, Path: "/", Type: checkType}
+		lb, err := balancer.New([]string{backendURL}, ratelimiter.NewDisabled(), hc, "round_robin")
+		require.NoError(t, err)
+		t.Cleanup(lb.StopHealthChecks)
+		return lb
+	}
+
+	lb := newBalancer(config.HealthCheckTCP)
+	results, err := lb.CheckNow("")
+	require.NoError(t, err)
+	assert.True(t, results[0].Healthy, results[0].Error)
+	assert.True(t, lb.Snapshot().Backends[0].Alive)
+
+	results, err = newBalancer(config.HealthCheckHTTP).CheckNow("")
+	require.NoError(t, err)
+	assert.False(t, results[0].Healthy, "Бэкенд не отвечает на HTTP-запрос")
+
+	ln.Close()
+	results, err = lb.CheckNow("")
+	require.NoError(t, err)
+	assert.False(t, results[0].Healthy)
+	assert.Contains(t, results[0].Error, ln.Addr().String())
+	assert.False(t, lb.Snapshot().Backends[0].Alive)
+}
+
+// TestIntegration_GRPCHealthCheck проверяет проверку health_check.type: grpc: бэкенд исправен, только
+// если grpc.health.v1.Health/Check вернул SERVING для health_check.grpc_service.
+func TestIntegration_GRPCHealthCheck(t *testing.T) {
+	var serving atomic.Bool
+	var service atomic.Value
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		service.Store(string(body[min(len(body), 7):])) // Кадр (5 байт), тег и длина поля service
+		if r.URL.Path != "/grpc.health.v1.Health/Check" || r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		status := byte(2) // NOT_SERVING
+		if serving.Load() {
+			status = 1
+		}
+		w.Write([]byte{0, 0, 0, 0, 2, 0x08, status})
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	hc := config.HealthCheckConfig{
+		Enabled: true, Interval: time.Hour, Timeout: time.Second, Type: config.HealthCheckGRPC, GRPCService: "orders.v1.Orders",
+	}
+	lb, err := balancer.New([]string{server.URL}, ratelimiter.NewDisabled(), hc, "round_robin")
+	require.NoError(t, err)
+	defer lb.StopHealthChecks()
+
+	results, err := lb.CheckNow("")
+	require.NoError(t, err)
+	assert.False(t, results[0].Healthy)
+	assert.Contains(t, results[0].Error, "NOT_SERVING")
+	assert.Equal(t, "orders.v1.Orders", service.Load())
+	assert.False(t, lb.Snapshot().Backends[0].Alive)
+
+	serving.Store(true)
+	results, err = lb.CheckNow("")
+	require.NoError(t, err)
+	assert.True(t, results[0].Healthy, results[0].Error)
+	assert.True(t, lb.Snapshot().Backends[0].Alive)
+}
+
+// TestIntegration_HealthCheckResponseMatching проверяет, что бэкенд, отвечающий 200 с признаком
+// неисправности в теле или неожиданным кодом, признается нерабочим.
+func TestIntegration_HealthCheckResponseMatching(t *testing.T) {
+	var status atomic.Int32
+	var body atomic.Value
+	status.Store(http.StatusOK)
+	body.Store(`{"status":"ok","checks":{"db":"up","replicas":2}}`)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+		io.WriteString(w, body.Load().(string))
+	}))
+	defer backend.Close()
+
+	check := func(hc config.HealthCheckConfig) string {
+		hc.Enabled, hc.Interval, hc.Timeout, hc.Path = true, time.Hour, time.Second, "/health"
+		lb, err := balancer.New([]string{backend.URL}, ratelimiter.NewDisabled(), hc, "round_robin")
+		require.NoError(t, err)
+		defer lb.StopHealthChecks()
+		results, err := lb.CheckNow("")
+		require.NoError(t, err)
+		assert.Equal(t, results[0].Healthy, lb.Snapshot().Backends[0].Alive)
+		return results[0].Error
+	}
+
+	expectJSON := map[string]string{"status": "ok", "checks.db": "up", "checks.replicas": "2"}
+	assert.Empty(t, check(config.HealthCheckConfig{ExpectedBody: `"status":"ok"`, ExpectedJSON: expectJSON}))
+
+	body.Store(`{"status":"degraded","checks":{"db":"down","replicas":2}}`)
+	assert.Contains(t, check(config.HealthCheckConfig{ExpectedBody: `"status":"ok"`}), `"status":"ok"`)
+	assert.Contains(t, check(config.HealthCheckConfig{ExpectedJSON: expectJSON}), "checks.db")
+	assert.Contains(t, check(config.HealthCheckConfig{ExpectedJSON: map[string]string{"checks.cache": "up"}}), "checks.cache")
+
+	body.Store("OK")
+	assert.Contains(t, check(config.HealthCheckConfig{ExpectedJSON: expectJSON}), "JSON")
+
+	// Без expected_status годится любой 2xx; со списком - только коды из него
+	status.Store(http.StatusNoContent)
+	assert.Empty(t, check(config.HealthCheckConfig{}))
+	assert.Contains(t, check(config.HealthCheckConfig{ExpectedStatus: []int{http.StatusOK}}), "204")
+	status.Store(http.StatusTooManyRequests)
+	assert.Empty(t, check(config.HealthCheckConfig{ExpectedStatus: []int{http.StatusOK, http.StatusTooManyRequests}}))
+}
+
+// startFakeDNS запускает DNS-сервер, отвечающий записью A 127.0.0.1 на имена из names (остальным - NXDOMAIN)
+// и пустым ответом на другие типы записей. Возвращает адрес сервера и счетчик запросов A.
+func startFakeDNS(t *testing.T, names ...string) (string, *atomic.Int32) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	var queries atomic.Int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			// Заголовок (12 байт), имя из меток, тип и класс
+			end := 12
+			var labels []string
+			for end < n && buf[end] != 0 {
+				labels = append(labels, string(buf[end+1:end+1+int(buf[end])]))
+				end += 1 + int(buf[end])
+			}
+			end += 5
+			if end > n {
+				continue
+			}
+			qtype := int(buf[end-4])<<8 | int(buf[end-3])
+
+			resp := append([]byte(nil), buf[:end]...) // Без дополнительной секции запроса (EDNS)
+			resp[2], resp[3] = 0x81, 0x80             // Ответ, рекурсия доступна
+			resp[6], resp[7], resp[8], resp[9], resp[10], resp[11] = 0, 0, 0, 0, 0, 0
+			switch {
+			case !slices.Contains(names, strings.Join(labels, ".")):
+				resp[3] |= 3 // NXDOMAIN
+			case qtype == 1:
+				queries.Add(1)
+				resp[7] = 1
+				resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 0, 0, 1)
+			}
+			conn.WriteTo(resp, from)
+		}
+	}()
+	return conn.LocalAddr().String(), &queries
+}
+
+// TestIntegration_PoolDNS проверяет разрешение имен бэкендов пула через DNS-сервер из dns.<пул>:
+// имя, неизвестное системному резолверу, разрешается, а адреса хранятся cache_ttl.
+func TestIntegration_PoolDNS(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "close") // Каждый запрос - новое соединение и новое разрешение имени
+	}))
+	defer backend.Close()
+	_, port, err := net.SplitHostPort(backend.Listener.Addr().String())
+	require.NoError(t, err)
+	server, queries := startFakeDNS(t, "backend.lb-test")
+
+	newBalancer := func(host string, dns config.DNSConfig) *balancer.Balancer {
+		lb, err := balancer.New([]string{"http://" + host + ":" + port}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+		require.NoError(t, err)
+		lb.SetDNS(dns)
+		return lb
+	}
+	send := func(lb *balancer.Balancer) int {
+		rr := httptest.NewRecorder()
+		lb.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rr.Code
+	}
+
+	lb := newBalancer("backend.lb-test", config.DNSConfig{Servers: []string{server}, Timeout: time.Second, CacheTTL: time.Minute})
+	assert.Equal(t, http.StatusOK, send(lb))
+	assert.Equal(t, http.StatusOK, send(lb))
+	assert.Equal(t, int32(1), queries.Load(), "Второе соединение использует адрес из кэша")
+
+	// Без cache_ttl имя разрешается при каждом соединении
+	lb = newBalancer("backend.lb-test", config.DNSConfig{Servers: []string{server}, Timeout: time.Second})
+	assert.Equal(t, http.StatusOK, send(lb))
+	assert.Equal(t, http.StatusOK, send(lb))
+	assert.Equal(t, int32(3), queries.Load())
+
+	lb = newBalancer("unknown.lb-test", config.DNSConfig{Servers: []string{server}, Timeout: time.Second})
+	assert.Equal(t, http.StatusBadGateway, send(lb))
+}
+
+// TestIntegration_Metrics проверяет метрики бэкендов пула: запросы, ошибки, время ответа и доступность.
+func TestIntegration_Metrics(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	lb, err := balancer.New([]string{ok.URL, failing.URL}, ratelimiter.NewDisabled(), config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	for range 4 {
+		lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	families := make(map[string]metrics.Family)
+	for _, family := range lb.Metrics("primary") {
+		families[family.Name] = family
+	}
+	requests := families["balancer_backend_requests_total"].Samples
+	require.Len(t, requests, 2)
+	assert.Equal(t, []metrics.Label{{Name: "pool", Value: "primary"}, {Name: "backend", Value: ok.URL}, {Name: "index", Value: "0"}},
+		requests[0].Labels)
+	assert.Equal(t, 2.0, requests[0].Value)
+	assert.Equal(t, 2.0, requests[1].Value)
+	failures := families["balancer_backend_failures_total"].Samples
+	assert.Equal(t, 0.0, failures[0].Value)
+	assert.Equal(t, 2.0, failures[1].Value)
+	latency := families["balancer_backend_response_seconds"].Samples
+	assert.Equal(t, uint64(2), latency[0].Histogram.Count)
+	assert.Equal(t, metrics.TypeHistogram, families["balancer_backend_response_seconds"].Type)
+	assert.Equal(t, 1.0, families["balancer_backend_alive"].Samples[0].Value)
+
+	// Удаленные бэкенды не экспортируются
+	_, err = lb.RemoveBackend("1")
+	require.NoError(t, err)
+	for _, family := range lb.Metrics("primary") {
+		assert.Len(t, family.Samples, 1, family.Name)
+	}
+}
+
+// TestIntegration_Trace проверяет трассировку решений: заголовок ответа только при верном токене,
+// отсутствие токена в запросе к бэкенду и трассировку в записи запроса.
+func TestIntegration_Trace(t *testing.T) {
+	var forwarded atomic.Value
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded.Store(r.Header.Get(balancer.TraceHeader))
+	}))
+	defer backend.Close()
+	drained := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer drained.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 1, DefaultCapacity: 10, IdentifierHeader: "X-Client-ID",
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL, drained.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetRoutes(config.HeaderPolicyConfig{}, []config.RouteConfig{{PathPrefix: "/api"}})
+	lb.SetTrace(config.TraceConfig{Enabled: true, Token: "secret"})
+	_, err = lb.SetDrained("1", true)
+	require.NoError(t, err)
+	rec := capture.New(config.CaptureConfig{BufferSize: 10, MaxBodyBytes: 1024})
+	lb.SetCapture(rec)
+
+	send := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+		req.Header.Set("X-Client-ID", "user1")
+		if token != "" {
+			req.Header.Set(balancer.TraceHeader, token)
+		}
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+
+	assert.Empty(t, send("").Header().Get(balancer.TraceHeader))
+	assert.Empty(t, send("wrong").Header().Get(balancer.TraceHeader), "Трассировка только с верным токеном")
+
+	trace := send("secret").Header().Get(balancer.TraceHeader)
+	assert.Equal(t, "", forwarded.Load(), "Токен трассировки не передается бэкенду")
+	assert.Regexp(t, `^route=/api; ratelimit=allowed key=user1 tokens=\d+\.\d\d/10; candidates=0 skipped=1:drained; backend=0$`, trace)
+
+	// Записанный запрос получает трассировку без заголовка в запросе
+	rec.Start(capture.Filter{ClientID: "user1"})
+	assert.Empty(t, send("").Header().Get(balancer.TraceHeader))
+	entries := rec.Entries()
+	require.Len(t, entries, 1)
+	assert.Contains(t, entries[0].Trace, "backend=0")
+}
+
+// TestIntegration_Tracing проверяет span запроса и обращения к бэкенду: бэкенд получает traceparent
+// с ID трассы клиента и span обращения к нему, span отправляются в коллектор OTLP.
+func TestIntegration_Tracing(t *testing.T) {
+	var traceparent atomic.Value
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent.Store(r.Header.Get("traceparent"))
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer backend.Close()
+	bodies := make(chan []byte, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer collector.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	tracer := tracing.New(config.TracingConfig{Endpoint: collector.URL, ServiceName: "lb", SampleRatio: 1,
+		BatchSize: 10, QueueSize: 10, FlushInterval: time.Hour, Timeout: time.Second})
+	tracer.Start()
+	lb.SetTracer(tracer)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadGateway, w.Code)
+
+	forwarded, ok := tracing.ParseTraceparent(traceparent.Load().(string))
+	require.True(t, ok)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+hex.EncodeToString(forwarded.SpanID[:])+"-01", traceparent.Load())
+	assert.NotEqual(t, "00f067aa0ba902b7", hex.EncodeToString(forwarded.SpanID[:]), "Бэкенд получает span балансировщика")
+
+	tracer.Stop()
+	require.NoError(t, tracer.Wait(context.Background()))
+	var export struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Kind         int    `json:"kind"`
+					Status       struct {
+						Code int `json:"code"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	require.NoError(t, json.Unmarshal(<-bodies, &export))
+	spans := export.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	client, server := spans[0], spans[1] // Span обращения к бэкенду завершается раньше
+	assert.Equal(t, 3, client.Kind)
+	assert.Equal(t, 2, server.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", server.ParentSpanID)
+	assert.Equal(t, server.SpanID, client.ParentSpanID)
+	assert.Equal(t, hex.EncodeToString(forwarded.SpanID[:]), client.SpanID)
+	assert.Equal(t, 2, client.Status.Code, "Ответ 5xx отмечается ошибкой")
+}
+
+// TestIntegration_AllowListKeepsBalancerHeaders проверяет, что список разрешенных заголовков маршрута
+// удаляет только заголовки клиента: traceparent, X-Request-ID и X-Forwarded-Host доходят до бэкенда.
+func TestIntegration_AllowListKeepsBalancerHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer backend.Close()
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetRoutes(config.HeaderPolicyConfig{}, []config.RouteConfig{
+		{PathPrefix: "/api", Headers: config.HeaderPolicyConfig{Allow: []string{"Accept"}}},
+	})
+	tracer := tracing.New(config.TracingConfig{Endpoint: collector.URL, ServiceName: "lb", SampleRatio: 1,
+		BatchSize: 10, QueueSize: 10, FlushInterval: time.Hour, Timeout: time.Second})
+	tracer.Start()
+	defer func() {
+		tracer.Stop()
+		_ = tracer.Wait(context.Background())
+	}()
+	lb.SetTracer(tracer)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Custom", "1")
+	req.Header.Set(requestid.Header, "req-allow-list")
+	w := httptest.NewRecorder()
+	requestid.Middleware(lb).ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	h := <-received
+	assert.Equal(t, "application/json", h.Get("Accept"))
+	assert.Empty(t, h.Get("X-Custom"), "Заголовок клиента вне списка удаляется")
+	assert.Equal(t, "req-allow-list", h.Get(requestid.Header))
+	assert.NotEmpty(t, h.Get(tracing.TraceparentHeader))
+	assert.NotEmpty(t, h.Get("X-Forwarded-Host"))
+}
+
+// TestIntegration_BackendTimeout проверяет ответ 504, если бэкенд не начал отвечать за timeouts.backend_response,
+// переопределение времени для маршрута и то, что потоковое тело ответа таймаутом не прерывается.
+func TestIntegration_BackendTimeout(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stream" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-time.After(300 * time.Millisecond):
+			_, _ = io.WriteString(w, "done")
+		case <-r.Context().Done():
+		}
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetRoutes(config.HeaderPolicyConfig{}, []config.RouteConfig{
+		{PathPrefix: "/reports", BackendTimeoutStr: "0"},
+	})
+	lb.SetBackendTimeout(100 * time.Millisecond)
+
+	// Заголовки получены вовремя: тело читается дольше таймаута
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "done", w.Body.String())
+
+	// Маршрут отключает ограничение
+	w = httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports/yearly", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "done", w.Body.String())
+
+	w = httptest.NewRecorder()
+	started := time.Now()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api", nil))
+	assert.Less(t, time.Since(started), 250*time.Millisecond)
+	require.Equal(t, http.StatusGatewayTimeout, w.Code)
+	var errResp response.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, response.CodeGatewayTimeout, errResp.ErrorCode)
+	assert.False(t, lb.Snapshot().Backends[0].Alive, "Бэкенд, не ответивший вовремя, исключается как ошибочный")
+}
+
+// wsGUID - константа вычисления Sec-WebSocket-Accept (RFC 6455).
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// writeWSFrame записывает текстовый кадр WebSocket с коротким телом (до 125 байт); кадры клиента маскируются.
+func writeWSFrame(w io.Writer, payload []byte, masked bool) error {
+	frame := []byte{0x81, byte(len(payload))}
+	if masked {
+		key := []byte{1, 2, 3, 4}
+		frame[1] |= 0x80
+		frame = append(frame, key...)
+		for i, c := range payload {
+			frame = append(frame, c^key[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// readWSFrame читает кадр WebSocket с коротким телом и снимает маску.
+func readWSFrame(r *bufio.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	var key []byte
+	if header[1]&0x80 != 0 {
+		key = make([]byte, 4)
+		if _, err := io.ReadFull(r, key); err != nil {
+			return nil, err
+		}
+	}
+	payload := make([]byte, header[1]&0x7f)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if key != nil {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return payload, nil
+}
+
+// newWSEchoBackend запускает бэкенд WebSocket, который отвечает на каждое сообщение "<name>:<сообщение>".
+func newWSEchoBackend(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+			!strings.EqualFold(r.Header.Get("Connection"), "Upgrade") {
+			http.Error(w, "upgrade required", http.StatusUpgradeRequired)
+			return
+		}
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + wsGUID))
+		fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(sum[:]))
+		for brw.Flush() == nil {
+			payload, err := readWSFrame(brw.Reader)
+			if err != nil {
+				return
+			}
+			_ = writeWSFrame(brw, append([]byte(name+":"), payload...), false)
+		}
+	}))
+}
+
+// dialWS открывает соединение WebSocket через балансировщик; cookie - cookie привязки или nil.
+func dialWS(t *testing.T, serverURL string, cookie *http.Cookie) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(serverURL, "http://"))
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, serverURL+"/ws", nil)
+	require.NoError(t, err)
+	// Браузеры отправляют upgrade вместе с другими токенами
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	require.NoError(t, req.Write(conn))
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	require.NoError(t, err)
+	return conn, reader, resp
+}
+
+// wsRoundTrip отправляет сообщение и возвращает ответ бэкенда.
+func wsRoundTrip(t *testing.T, conn net.Conn, reader *bufio.Reader, message string) string {
+	require.NoError(t, writeWSFrame(conn, []byte(message), true))
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	payload, err := readWSFrame(reader)
+	require.NoError(t, err)
+	return string(payload)
+}
+
+// TestIntegration_WebSocket проверяет проксирование WebSocket: рукопожатие доходит до бэкенда с "Connection: Upgrade",
+// сообщения передаются без буферизации, а сроки чтения и записи сервера не обрывают долгую сессию.
+func TestIntegration_WebSocket(t *testing.T) {
+	backend := newWSEchoBackend("b0")
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetBackendTimeout(time.Second)
+	front := httptest.NewUnstartedServer(lb)
+	front.Config.ReadTimeout = 200 * time.Millisecond
+	front.Config.WriteTimeout = 200 * time.Millisecond
+	front.Start()
+	defer front.Close()
+
+	conn, reader, resp := dialWS(t, front.URL, nil)
+	defer conn.Close()
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	assert.Equal(t, "websocket", resp.Header.Get("Upgrade"))
+
+	assert.Equal(t, "b0:hello", wsRoundTrip(t, conn, reader, "hello"))
+	// Сессия живет дольше timeouts.read и timeouts.write, и дольше времени ожидания ответа бэкенда
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, "b0:still here", wsRoundTrip(t, conn, reader, "still here"))
+	require.Eventually(t, func() bool { return lb.Snapshot().Backends[0].InFlight == 1 }, time.Second, 10*time.Millisecond,
+		"Сессия WebSocket занимает бэкенд до закрытия")
+
+	conn.Close()
+	require.Eventually(t, func() bool { return lb.Snapshot().Backends[0].InFlight == 0 }, time.Second, 10*time.Millisecond)
+	assert.True(t, lb.Snapshot().Backends[0].Alive)
+}
+
+// TestIntegration_WebSocketSticky проверяет привязку клиентов WebSocket: cookie выдается в ответе 101,
+// и повторные подключения с ней попадают на тот же бэкенд.
+func TestIntegration_WebSocketSticky(t *testing.T) {
+	backends := []*httptest.Server{newWSEchoBackend("b0"), newWSEchoBackend("b1")}
+	for _, backend := range backends {
+		defer backend.Close()
+	}
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backends[0].URL, backends[1].URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetStickySessions(config.StickySessionConfig{Enabled: true, Secret: "test-secret", TTL: time.Hour})
+	front := httptest.NewServer(lb)
+	defer front.Close()
+
+	conn, reader, resp := dialWS(t, front.URL, nil)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	first, _, _ := strings.Cut(wsRoundTrip(t, conn, reader, "hi"), ":")
+	conn.Close()
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == config.DefaultStickyCookieName {
+			cookie = c
+		}
+	}
+	require.NotNil(t, cookie, "Cookie привязки выдается в ответе 101")
+
+	for range 3 {
+		conn, reader, resp := dialWS(t, front.URL, cookie)
+		require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+		backend, _, _ := strings.Cut(wsRoundTrip(t, conn, reader, "again"), ":")
+		conn.Close()
+		assert.Equal(t, first, backend)
+		assert.Empty(t, resp.Cookies(), "Cookie не выдается повторно")
+	}
+}
+
+// TestIntegration_ClientCertHeader проверяет передачу бэкенду CN клиентского сертификата (tls.client_auth.subject_header):
+// значение, присланное клиентом, заменяется или удаляется.
+func TestIntegration_ClientCertHeader(t *testing.T) {
+	var received atomic.Value
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Store(r.Header.Values("X-Client-Cert-CN"))
+	}))
+	defer backend.Close()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	lb.SetClientCertHeader("x-client-cert-cn")
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Client-Cert-CN", "admin")
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "client-1"}}}}
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"client-1"}, received.Load())
+
+	// Запрос без клиентского сертификата (или по HTTP): подделанный заголовок не доходит до бэкенда
+	req = httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Client-Cert-CN", "admin")
+	w = httptest.NewRecorder()
+	lb.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, received.Load())
+}
+
+// writeTestCertificate создает самоподписанный сертификат с ключом и записывает их в файлы PEM.
+func writeTestCertificate(t *testing.T, commonName string) (tls.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	return cert, certFile, keyFile
+}
+
+// TestIntegration_BackendTLS проверяет соединения с https:// бэкендом, требующим клиентский сертификат:
+// сертификат бэкенда проверяется по УЦ из backend_tls.ca_file, балансировщик предъявляет свой сертификат.
+func TestIntegration_BackendTLS(t *testing.T) {
+	clientCert, clientCertFile, clientKeyFile := writeTestCertificate(t, "balancer")
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert.Leaf)
+
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	backend.TLS = &tls.Config{ClientCAs: clientCAs, ClientAuth: tls.RequireAndVerifyClientCert}
+	backend.StartTLS()
+	defer backend.Close()
+	caFile := filepath.Join(t.TempDir(), "backend-ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw}), 0o600))
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	tests := []struct {
+		name     string
+		cfg      config.BackendTLSConfig
+		wantCode int
+	}{
+		{"system roots", config.BackendTLSConfig{}, http.StatusBadGateway},
+		{"no client certificate", config.BackendTLSConfig{CAFile: caFile}, http.StatusBadGateway},
+		{"ca and client certificate", config.BackendTLSConfig{CAFile: caFile, CertFile: clientCertFile, KeyFile: clientKeyFile}, http.StatusOK},
+		{"skip verify", config.BackendTLSConfig{InsecureSkipVerify: true, CertFile: clientCertFile, KeyFile: clientKeyFile}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+			require.NoError(t, err)
+			require.NoError(t, lb.SetBackendTLS(tt.cfg))
+
+			w := httptest.NewRecorder()
+			lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api", nil))
+			require.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode == http.StatusOK {
+				assert.Equal(t, "balancer", w.Body.String())
+			}
+		})
+	}
+
+	lb, err := balancer.New([]string{backend.URL}, rl, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	assert.Error(t, lb.SetBackendTLS(config.BackendTLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}))
+}
@@ -0,0 +1,173 @@
+package balancer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"load-balancer/internal/i18n"
+)
+
+var (
+	// ErrBackendExists возвращается AddBackend, если бэкенд с таким URL уже есть в пуле.
+	ErrBackendExists = i18n.NewError(i18n.BalancerBackendExists)
+	// ErrLastBackend возвращается RemoveBackend при попытке удалить единственный бэкенд пула.
+	ErrLastBackend = i18n.NewError(i18n.BalancerLastBackend)
+)
+
+// BackendSpec - параметры бэкенда, добавляемого во время работы (см. AddBackend).
+type BackendSpec struct {
+	URL    string
+	Weight int // Вес во взвешенном выборе; < 1 - вес 1.
+	// Labels - метки для backend_selector маршрутов и селектора canary.
+	Labels map[string]string
+	// MaxConnections - максимум одновременных запросов к бэкенду; 0 - без ограничения.
+	MaxConnections int
+}
+
+// AddBackend добавляет бэкенд в работающий пул и возвращает его ID (см. Backend.ID). Если проверки
+// состояния включены, бэкенд получает запросы только после успешной проверки, которая выполняется сразу;
+// иначе - сразу, с учетом passive_health.slow_start.
+func (b *Balancer) AddBackend(spec BackendSpec) (int, error) {
+	id, err := b.addBackend(spec)
+	if err != nil {
+		return -1, err
+	}
+	if b.healthCheckConfig.Load().Enabled {
+		// Результат применяется так же, как в плановой проверке; ошибка означает, что бэкенд уже удален
+		_, _ = b.CheckNow(strconv.Itoa(id))
+	}
+	return id, nil
+}
+
+// addBackend создает бэкенд и публикует список пула с ним.
+func (b *Balancer) addBackend(spec BackendSpec) (int, error) {
+	b.membership.Lock()
+	defer b.membership.Unlock()
+
+	current := b.backendList()
+	wanted := strings.TrimSuffix(spec.URL, "/")
+	for _, existing := range current {
+		if !existing.Removed() && strings.TrimSuffix(existing.URL.String(), "/") == wanted {
+			return -1, i18n.Errorf(i18n.BalancerBackendExistsWrap, ErrBackendExists, spec.URL)
+		}
+	}
+
+	id := b.nextID
+	backend, err := b.newBackend(id, spec.URL)
+	if err != nil {
+		return -1, err
+	}
+	b.nextID++
+	backend.Labels = spec.Labels
+	if spec.Weight > 1 {
+		backend.weight = spec.Weight
+		b.weighted.Store(true)
+	}
+	if spec.MaxConnections > 0 {
+		backend.maxConnections = int64(spec.MaxConnections)
+	}
+	if b.historySize > 0 {
+		backend.history = newRequestHistory(b.historySize)
+	}
+	if b.grpc {
+		b.setGRPCProxy(backend)
+	}
+	if b.canary != nil {
+		backend.canary = backend.Matches(b.canary.cfg.Selector)
+	}
+	if b.healthCheckConfig.Load().Enabled {
+		backend.Alive = false // До первой успешной проверки
+	} else {
+		backend.admittedAt.Store(time.Now().UnixNano())
+	}
+
+	backends := make([]*Backend, len(current), len(current)+1)
+	copy(backends, current)
+	backends = append(backends, backend)
+	b.backends.Store(&backends)
+	b.membershipChanged()
+	i18n.Logf(i18n.BalancerBackendRegistered, id, backend.URL, backend.weight, backend.Labels)
+	return id, nil
+}
+
+// RemoveBackend удаляет бэкенд (ID или URL) из пула и возвращает его ID. Бэкенд сразу перестает
+// получать новые запросы и проверяться, запросы в обработке завершаются; после этого бэкенд убирается
+// из списка пула (см. release). ID удаленного бэкенда не используется повторно.
+// Возвращает ErrBackendNotFound или ErrLastBackend.
+func (b *Balancer) RemoveBackend(backend string) (int, error) {
+	if backend == "" {
+		return -1, i18n.Errorf(i18n.HealthCheckBackendNotFoundWrap, ErrBackendNotFound, backend)
+	}
+
+	b.membership.Lock()
+	defer b.membership.Unlock()
+
+	targets, err := b.findBackends(backend)
+	if err != nil {
+		return -1, err
+	}
+	if all, _ := b.findBackends(""); len(all) == 1 {
+		return -1, ErrLastBackend
+	}
+	target := targets[0]
+	target.removed.Store(true)
+	i18n.Logf(i18n.BalancerBackendRemoved, target.id, target.URL, target.InFlight())
+	// Бэкенд без запросов в обработке убирается сразу, иначе - после завершения последнего (см. release)
+	if !b.compactLocked() {
+		b.membershipChanged()
+	}
+	return target.id, nil
+}
+
+// release учитывает завершение запроса к бэкенду. Удаленный бэкенд убирается из списка пула,
+// когда завершается последний запрос к нему.
+func (b *Balancer) release(backend *Backend) {
+	if backend.inFlight.Add(-1) == 0 && backend.Removed() {
+		b.membership.Lock()
+		defer b.membership.Unlock()
+		b.compactLocked()
+	}
+}
+
+// compactLocked публикует список пула без удаленных бэкендов, у которых нет запросов в обработке,
+// и закрывает их простаивающие соединения; false - таких бэкендов нет. Запрос, выбравший бэкенд
+// до удаления, еще может начаться после этого: бэкенд уже не в списке, и release для него ничего не меняет.
+// Вызывается под b.membership.
+func (b *Balancer) compactLocked() bool {
+	current := b.backendList()
+	backends := make([]*Backend, 0, len(current))
+	var dropped []*Backend
+	for _, backend := range current {
+		if backend.Removed() && backend.InFlight() == 0 {
+			dropped = append(dropped, backend)
+			continue
+		}
+		backends = append(backends, backend)
+	}
+	if len(dropped) == 0 {
+		return false
+	}
+	b.backends.Store(&backends)
+	b.membershipChanged()
+	for _, backend := range dropped {
+		// Соединения запросов, начавшихся после удаления, закрываются транспортом после их завершения
+		backend.transport.current.Load().CloseIdleConnections()
+		if backend.grpcTransport != nil {
+			backend.grpcTransport.current.Load().CloseIdleConnections()
+		}
+	}
+	return true
+}
+
+// membershipChanged перестраивает состояние, зависящее от состава пула: кольцо consistent_hash
+// и группы канареечного разделения. Вызывается под b.membership.
+func (b *Balancer) membershipChanged() {
+	if b.ring.Load() != nil {
+		b.buildRing()
+	}
+	if c := b.canary; c != nil {
+		candidates := c.group(b.backendList())
+		c.candidates.Store(&candidates)
+	}
+}
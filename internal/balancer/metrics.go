@@ -0,0 +1,53 @@
+package balancer
+
+import (
+	"strconv"
+
+	"load-balancer/internal/metrics"
+)
+
+// latencyBuckets - границы корзин времени ответа бэкенда в секундах (как в клиентах Prometheus по умолчанию).
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics возвращает метрики бэкендов пула с метками pool (имя пула) и backend (URL): запросы, ошибки,
+// время ответа, доступность и запросы в обработке. Удаленные бэкенды не экспортируются.
+// Используется как metrics.Collector (см. RegisterMetrics).
+func (b *Balancer) Metrics(pool string) []metrics.Family {
+	requests := metrics.Family{Name: "balancer_backend_requests_total", Type: metrics.TypeCounter,
+		Help: "Количество запросов, направленных на бэкенд."}
+	failures := metrics.Family{Name: "balancer_backend_failures_total", Type: metrics.TypeCounter,
+		Help: "Количество запросов к бэкенду, завершившихся ошибкой проксирования, ответом, не прошедшим проверку, или статусом 5xx."}
+	latency := metrics.Family{Name: "balancer_backend_response_seconds", Type: metrics.TypeHistogram,
+		Help: "Время ответа бэкенда в секундах."}
+	alive := metrics.Family{Name: "balancer_backend_alive", Type: metrics.TypeGauge,
+		Help: "Доступность бэкенда по проверкам состояния: 1 - доступен, 0 - нет."}
+	inFlight := metrics.Family{Name: "balancer_backend_in_flight_requests", Type: metrics.TypeGauge,
+		Help: "Количество запросов, которые проксируются на бэкенд в данный момент."}
+
+	for _, backend := range b.backendList() {
+		if backend.Removed() {
+			continue
+		}
+		labels := []metrics.Label{
+			{Name: "pool", Value: pool},
+			{Name: "backend", Value: backend.URL.String()},
+			{Name: "index", Value: strconv.Itoa(backend.id)},
+		}
+		var up float64
+		if backend.IsAlive() {
+			up = 1
+		}
+		requests.Samples = append(requests.Samples, metrics.Sample{Labels: labels, Value: float64(backend.requests.Load())})
+		failures.Samples = append(failures.Samples, metrics.Sample{Labels: labels, Value: float64(backend.failed.Load())})
+		latency.Samples = append(latency.Samples, metrics.Sample{Labels: labels, Histogram: backend.latency.Snapshot()})
+		alive.Samples = append(alive.Samples, metrics.Sample{Labels: labels, Value: up})
+		inFlight.Samples = append(inFlight.Samples, metrics.Sample{Labels: labels, Value: float64(backend.InFlight())})
+	}
+	return []metrics.Family{requests, failures, latency, alive, inFlight}
+}
+
+// RegisterMetrics регистрирует метрики бэкендов пула в реестре metrics под именем пула pool.
+// Повторная регистрация пула с тем же именем заменяет прежнюю.
+func (b *Balancer) RegisterMetrics(pool string) {
+	metrics.RegisterCollector("balancer:"+pool, func() []metrics.Family { return b.Metrics(pool) })
+}
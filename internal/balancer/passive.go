@@ -0,0 +1,202 @@
+package balancer
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var (
+	holdDownsTotal = metrics.NewCounter("balancer_backend_hold_downs_total",
+		"Количество исключений бэкендов на время passive_health.hold_down.")
+	passiveFailuresTotal = metrics.NewCounter("balancer_passive_failures_total",
+		"Количество ошибок проксирования и ответов, не прошедших проверку, учтенных пассивной проверкой.")
+	upstream5xxTotal = metrics.NewCounter("balancer_passive_5xx_total",
+		"Количество ответов бэкендов 5xx, учтенных пассивной проверкой (passive_health.count_5xx).")
+)
+
+// failureBudget - ошибки бэкенда за последние passive_health.failure_window.
+type failureBudget struct {
+	mu       sync.Mutex
+	failures []time.Time
+}
+
+// record учитывает ошибку и возвращает количество ошибок в окне, включая эту.
+func (f *failureBudget) record(now time.Time, window time.Duration) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.dropOlder(now, window), now)
+	return len(f.failures)
+}
+
+// count возвращает количество ошибок в окне.
+func (f *failureBudget) count(now time.Time, window time.Duration) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = f.dropOlder(now, window)
+	return len(f.failures)
+}
+
+func (f *failureBudget) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = nil
+}
+
+// dropOlder отбрасывает ошибки, случившиеся раньше начала окна. Вызывается под f.mu.
+func (f *failureBudget) dropOlder(now time.Time, window time.Duration) []time.Time {
+	start := now.Add(-window)
+	i := 0
+	for i < len(f.failures) && !f.failures[i].After(start) {
+		i++
+	}
+	return f.failures[i:]
+}
+
+// passivePolicy - параметры пассивной проверки (см. config.PassiveHealthConfig): общие для пула
+// или переопределенные для бэкенда (см. SetBackendPassiveHealth).
+type passivePolicy struct {
+	holdDown      time.Duration
+	maxFailures   int
+	failureWindow time.Duration
+	count5xx      bool
+	consecutive   bool
+	slowStart     time.Duration
+}
+
+func newPassivePolicy(cfg config.PassiveHealthConfig) passivePolicy {
+	return passivePolicy{
+		holdDown:      cfg.HoldDown,
+		maxFailures:   cfg.MaxFailures,
+		failureWindow: cfg.FailureWindow,
+		count5xx:      cfg.Count5xx,
+		consecutive:   cfg.Consecutive,
+		slowStart:     cfg.SlowStart,
+	}
+}
+
+// SetPassiveHealth задает параметры пассивной проверки пула (passive_health).
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetPassiveHealth(cfg config.PassiveHealthConfig) {
+	b.passive = newPassivePolicy(cfg)
+	if cfg.SlowStart > 0 {
+		// Плавный возврат учитывается только взвешенным выбором
+		b.weighted.Store(true)
+	}
+}
+
+// SetBackendPassiveHealth задает параметры пассивной проверки отдельных бэкендов по их URL
+// (backend_servers[].passive_health); остальные бэкенды используют параметры пула.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetBackendPassiveHealth(overrides map[string]config.PassiveHealthConfig) {
+	for _, backend := range b.backendList() {
+		cfg, ok := overrides[backend.URL.String()]
+		if !ok {
+			continue
+		}
+		policy := newPassivePolicy(cfg)
+		backend.passive = &policy
+		if cfg.SlowStart > 0 {
+			b.weighted.Store(true)
+		}
+		i18n.Logf(i18n.BalancerBackendPassiveHealth, backend.id, backend.URL, cfg.MaxFailures, cfg.FailureWindow, cfg.HoldDown, cfg.SlowStart)
+	}
+}
+
+// SetHoldDown задает, на сколько бэкенд исключается из балансировки после ошибки проксирования
+// или ответа, не прошедшего проверку (passive_health.hold_down). 0 - бэкенд считается недоступным
+// до следующей успешной активной проверки. Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetHoldDown(holdDown time.Duration) {
+	b.passive.holdDown = holdDown
+}
+
+// SetFailureBudget задает, сколько ошибок за window нужно, чтобы исключить бэкенд
+// (passive_health.max_failures и failure_window). maxFailures <= 1 - бэкенд исключается после первой ошибки.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetFailureBudget(maxFailures int, window time.Duration) {
+	b.passive.maxFailures = maxFailures
+	b.passive.failureWindow = window
+}
+
+// passiveOf возвращает параметры пассивной проверки бэкенда.
+func (b *Balancer) passiveOf(backend *Backend) *passivePolicy {
+	if backend.passive != nil {
+		return backend.passive
+	}
+	return &b.passive
+}
+
+// passiveFailure учитывает ошибку при обработке запроса клиента и исключает бэкенд,
+// если исчерпан бюджет ошибок (см. SetFailureBudget).
+func (b *Balancer) passiveFailure(backend *Backend) {
+	passiveFailuresTotal.Inc()
+	policy := b.passiveOf(backend)
+	if policy.maxFailures > 1 {
+		failures := backend.failures.record(time.Now(), policy.failureWindow)
+		if failures < policy.maxFailures {
+			i18n.Logf(i18n.BalancerBackendFailureCounted, backend.URL, failures, policy.maxFailures, policy.failureWindow)
+			return
+		}
+		backend.failures.reset()
+	}
+
+	if policy.holdDown <= 0 {
+		backend.SetAlive(false)
+		return
+	}
+	until := time.Now().Add(policy.holdDown)
+	backend.heldUntil.Store(until.UnixNano())
+	// После hold_down бэкенд возвращается без активной проверки: плавный возврат отсчитывается от его конца
+	backend.admittedAt.Store(until.UnixNano())
+	holdDownsTotal.Inc()
+	i18n.Logf(i18n.BalancerBackendHeldDown, backend.URL, until.Format(time.RFC3339))
+}
+
+// observeResponse - часть ModifyResponse прокси бэкенда: учитывает статус ответа пассивной проверкой.
+// Ответы 5xx считаются ошибками при passive_health.count_5xx (кроме 503, которые обрабатывает
+// upstream_throttling), остальные при passive_health.consecutive обнуляют счетчик ошибок.
+func (b *Balancer) observeResponse(backend *Backend, status int) {
+	policy := b.passiveOf(backend)
+	if status >= http.StatusInternalServerError {
+		if status == http.StatusServiceUnavailable && b.throttling != nil {
+			return
+		}
+		if policy.count5xx {
+			upstream5xxTotal.Inc()
+			b.passiveFailure(backend)
+			return
+		}
+	}
+	if policy.consecutive && policy.maxFailures > 1 {
+		backend.failures.reset()
+	}
+}
+
+// slowStartPercent возвращает долю полного веса бэкенда (в процентах) при плавном возврате
+// в балансировку (passive_health.slow_start); 100 - бэкенд получает полный вес.
+func (b *Balancer) slowStartPercent(backend *Backend, now time.Time) int {
+	slowStart := b.passiveOf(backend).slowStart
+	admittedAt := backend.admittedAt.Load()
+	if slowStart <= 0 || admittedAt == 0 {
+		return 100
+	}
+	elapsed := now.Sub(time.Unix(0, admittedAt))
+	if elapsed < 0 || elapsed >= slowStart {
+		return 100
+	}
+	return max(config.SlowStartMinPercent, int(elapsed*100/slowStart))
+}
+
+// HeldUntil возвращает момент, до которого бэкенд исключен после ошибки (passive_health.hold_down);
+// false, если бэкенд не исключен.
+func (b *Backend) HeldUntil() (time.Time, bool) {
+	until := b.heldUntil.Load()
+	if until == 0 || time.Now().UnixNano() >= until {
+		return time.Time{}, false
+	}
+	return time.Unix(0, until), true
+}
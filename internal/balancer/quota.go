@@ -0,0 +1,66 @@
+package balancer
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"load-balancer/internal/ratelimiter"
+)
+
+// Заголовки ответа с состоянием корзины клиента (см. QuotaLimiter).
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+	RetryAfterHeader         = "Retry-After"
+)
+
+// QuotaLimiter реализуется Limiter, который сообщает состояние корзины клиента для заголовков X-RateLimit-*.
+type QuotaLimiter interface {
+	// Decide работает как CheckCost и дополнительно возвращает емкость корзины, остаток токенов
+	// и время до пополнения; source - как определен клиент (см. IdentityLimiter).
+	Decide(clientID string, source ratelimiter.IDSource, cost float64) (ratelimiter.Decision, error)
+}
+
+// IdentityLimiter реализуется Limiter, который сообщает, как определен ID клиента: по заголовку, API-ключу,
+// отпечатку TLS или IP-адресу. От способа зависят лимиты по умолчанию, поэтому он передается в QuotaLimiter
+// вместе с ID, а не определяется заново по виду ID: значение заголовка может выглядеть как IP-адрес.
+type IdentityLimiter interface {
+	ClientIdentity(r *http.Request) (clientID string, source ratelimiter.IDSource)
+}
+
+// ClientIdentity возвращает ID клиента запроса и способ его определения. Limiter без IdentityLimiter
+// определяет клиента только по ID: способ считается ratelimiter.SourceHeader.
+func ClientIdentity(limiter Limiter, r *http.Request) (string, ratelimiter.IDSource) {
+	if identity, ok := limiter.(IdentityLimiter); ok {
+		return identity.ClientIdentity(r)
+	}
+	return limiter.GetClientID(r), ratelimiter.SourceHeader
+}
+
+// setQuotaHeaders записывает состояние корзины в заголовки ответа: они сохраняются и в ответе бэкенда,
+// и в ответе 429. Retry-After (RFC 9110) отправляется только при отказе - через сколько секунд повторить
+// запрос. Без решения Rate Limiter (выключен или хранилище недоступно) заголовки не добавляются;
+// отказ по общему лимиту (503) получает только Retry-After.
+func setQuotaHeaders(h http.Header, d ratelimiter.Decision) {
+	if d.Global {
+		h.Set(RetryAfterHeader, strconv.FormatInt(max(ceilSeconds(d.RetryAfter), 1), 10))
+		return
+	}
+	if d.Limit <= 0 {
+		return
+	}
+	h.Set(RateLimitLimitHeader, strconv.FormatInt(int64(d.Limit), 10))
+	h.Set(RateLimitRemainingHeader, strconv.FormatInt(int64(d.Remaining), 10))
+	h.Set(RateLimitResetHeader, strconv.FormatInt(ceilSeconds(d.Reset), 10))
+	if !d.Allowed {
+		h.Set(RetryAfterHeader, strconv.FormatInt(max(ceilSeconds(d.RetryAfter), 1), 10))
+	}
+}
+
+// ceilSeconds округляет длительность вверх до целых секунд.
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
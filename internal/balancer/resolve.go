@@ -0,0 +1,119 @@
+package balancer
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var dnsReresolutionsTotal = metrics.NewCounter("balancer_dns_reresolutions_total",
+	"Количество смен адресов бэкендов, обнаруженных повторным разрешением DNS после неудачной проверки.")
+
+// Resolver разрешает имена хостов бэкендов (по умолчанию net.DefaultResolver).
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// SetResolver задает резолвер, которым имена хостов бэкендов повторно разрешаются после неудачных проверок.
+// Проверки запускаются уже в New, поэтому резолвер можно заменить в любой момент.
+func (b *Balancer) SetResolver(resolver Resolver) {
+	b.resolver.Store(&resolver)
+}
+
+// backendTransport - транспорт бэкенда, который можно пересоздать во время работы.
+// Запросы, уже выполняющиеся через старый транспорт, завершаются на нем, новые идут через новый.
+type backendTransport struct {
+	newTransport func() *http.Transport
+	current      atomic.Pointer[http.Transport]
+}
+
+func newBackendTransport(newTransport func() *http.Transport) *backendTransport {
+	t := &backendTransport{newTransport: newTransport}
+	t.current.Store(newTransport())
+	return t
+}
+
+func (t *backendTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.current.Load().RoundTrip(req)
+}
+
+// rebuild заменяет транспорт новым и закрывает простаивающие соединения старого (к прежним адресам).
+func (t *backendTransport) rebuild() {
+	t.current.Swap(t.newTransport()).CloseIdleConnections()
+}
+
+// withDialer возвращает конструктор транспорта, соединяющегося с бэкендами через резолвер пула (см. SetDNS)
+// с настройками TLS пула (см. SetBackendTLS).
+func (b *Balancer) withDialer(newTransport func() *http.Transport) func() *http.Transport {
+	return func() *http.Transport {
+		transport := newTransport()
+		transport.DialContext = b.dialContext
+		transport.TLSClientConfig = b.tlsClientConfig()
+		return transport
+	}
+}
+
+// newHTTPTransport создает транспорт для проксирования запросов по HTTP/1.1 с настройками http.DefaultTransport.
+func newHTTPTransport() *http.Transport {
+	return http.DefaultTransport.(*http.Transport).Clone()
+}
+
+// resolvedAddrs хранит адреса хоста бэкенда, полученные при последнем повторном разрешении DNS.
+type resolvedAddrs struct {
+	mu    sync.Mutex
+	addrs []string // Отсортированы; nil - хост еще не разрешался.
+}
+
+// update запоминает новые адреса и сообщает прежние и то, изменились ли они.
+func (ra *resolvedAddrs) update(addrs []string) ([]string, bool) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	previous := ra.addrs
+	if previous != nil && slices.Equal(previous, addrs) {
+		return previous, false
+	}
+	ra.addrs = addrs
+	return previous, true
+}
+
+// reresolve повторно разрешает имя хоста бэкенда после неудачной проверки. Если адреса изменились
+// (например, контейнер пересоздан с новым IP), транспорты бэкенда пересоздаются, чтобы запросы
+// не уходили по соединениям к старому адресу. Бэкенды, заданные IP-адресом, пропускаются.
+// Ошибки разрешения не логируются отдельно: их причина уже видна в ошибке проверки.
+func (b *Balancer) reresolve(backend *Backend, timeout time.Duration) {
+	host := backend.URL.Hostname()
+	if _, err := netip.ParseAddr(host); err == nil {
+		return
+	}
+
+	// Адреса из кэша резолвера пула могли устареть - проверка как раз не прошла
+	if r := b.dns.Load(); r != nil {
+		r.forget(host)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	addrs, err := (*b.resolver.Load()).LookupHost(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return
+	}
+	slices.Sort(addrs)
+
+	previous, changed := backend.resolved.update(addrs)
+	if !changed {
+		return
+	}
+	backend.transport.rebuild()
+	if backend.grpcTransport != nil {
+		backend.grpcTransport.rebuild()
+	}
+	dnsReresolutionsTotal.Inc()
+	i18n.Logf(i18n.BalancerBackendReresolved, backend.URL, addrs, previous)
+}
@@ -0,0 +1,101 @@
+package balancer
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/headers"
+	"load-balancer/internal/i18n"
+)
+
+// route - подготовленное к использованию правило маршрута.
+type route struct {
+	prefix  string          // Префикс пути ("" для маршрута по умолчанию).
+	headers *headers.Policy // Политика маскирования и пересылки заголовков.
+	// untrusted - бэкенды маршрута не доверенные: им не отправляются токен и пароль upstream_auth.
+	untrusted bool
+	// selector - метки бэкендов, которым разрешено обслуживать маршрут (пусто - весь пул).
+	selector map[string]string
+	current  atomic.Uint64 // Очередь Round Robin по подмножеству пула.
+	weights  weightedQueue // Очередь взвешенного Round Robin по подмножеству пула.
+	// flushInterval - переопределение ReverseProxy.FlushInterval (nil - как у прокси бэкенда).
+	flushInterval *time.Duration
+	// backendTimeout - переопределение времени ожидания ответа бэкенда (nil - как у пула, см. SetBackendTimeout).
+	backendTimeout *time.Duration
+	validator      *responseValidator // Проверка ответов бэкендов (nil - без проверки).
+}
+
+// routeTable - неизменяемый набор маршрутов, подменяемый целиком через atomic.Pointer.
+type routeTable struct {
+	routes       []*route // Отсортированы по убыванию длины префикса.
+	defaultRoute *route
+}
+
+// newRouteTable компилирует маршруты из конфигурации.
+func newRouteTable(global config.HeaderPolicyConfig, routes []config.RouteConfig) *routeTable {
+	table := &routeTable{
+		routes:       make([]*route, 0, len(routes)),
+		defaultRoute: &route{headers: headers.NewPolicy(global, nil)},
+	}
+
+	for i := range routes {
+		rc := routes[i]
+		rt := &route{
+			prefix:    rc.PathPrefix,
+			headers:   headers.NewPolicy(global, &rc),
+			untrusted: rc.Untrusted,
+			selector:  rc.BackendSelector,
+			validator: newResponseValidator(rc.ResponseValidation),
+		}
+		if rc.HasFlushInterval() {
+			rt.flushInterval = &rc.FlushInterval
+		}
+		if rc.HasBackendTimeout() {
+			rt.backendTimeout = &rc.BackendTimeout
+		}
+		table.routes = append(table.routes, rt)
+	}
+
+	// Самый длинный префикс проверяется первым
+	sort.SliceStable(table.routes, func(i, j int) bool {
+		return len(table.routes[i].prefix) > len(table.routes[j].prefix)
+	})
+
+	return table
+}
+
+// match возвращает маршрут с самым длинным совпавшим префиксом или маршрут по умолчанию.
+func (t *routeTable) match(path string) *route {
+	for _, rt := range t.routes {
+		if strings.HasPrefix(path, rt.prefix) {
+			return rt
+		}
+	}
+	return t.defaultRoute
+}
+
+// SetRoutes задает глобальную политику заголовков и правила маршрутов.
+// Может вызываться во время работы: новые правила применяются к последующим запросам.
+func (b *Balancer) SetRoutes(global config.HeaderPolicyConfig, routes []config.RouteConfig) {
+	b.routes.Store(newRouteTable(global, routes))
+	i18n.Logf(i18n.BalancerRoutesLoaded, len(routes))
+}
+
+// matchRoute подбирает маршрут для пути запроса.
+func (b *Balancer) matchRoute(path string) *route {
+	return b.routes.Load().match(path)
+}
+
+// SetBackendLabels задает метки бэкендов пула по их URL (ключи - URL из backend_servers).
+// Бэкенды, отсутствующие в labels, остаются без меток. Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetBackendLabels(labels map[string]map[string]string) {
+	for _, backend := range b.backendList() {
+		if backendLabels, ok := labels[backend.URL.String()]; ok {
+			backend.Labels = backendLabels
+			i18n.Logf(i18n.BalancerBackendLabels, backend.id, backend.URL, backendLabels)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package balancer
+
+import (
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var (
+	saturatedSelectionsTotal = metrics.NewCounter("balancer_saturated_selections_total",
+		"Количество запросов, направленных на почти заполненный бэкенд, потому что менее загруженных не было.")
+	saturationRejectionsTotal = metrics.NewCounter("balancer_saturation_rejections_total",
+		"Количество запросов, отклоненных из-за того, что все доступные бэкенды достигли backend_max_connections.")
+)
+
+// ErrBackendsSaturated возвращается, когда доступные бэкенды есть, но все достигли backend_max_connections.
+var ErrBackendsSaturated = i18n.NewError(i18n.BalancerBackendsSaturated)
+
+// SetBackendMaxConnections задает максимум одновременных запросов к бэкендам пула по их URL
+// (ключи - URL из backend_servers). Бэкенды, отсутствующие в limits, не ограничиваются.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetBackendMaxConnections(limits map[string]int) {
+	for _, backend := range b.backendList() {
+		if maxConnections, ok := limits[backend.URL.String()]; ok && maxConnections > 0 {
+			backend.maxConnections = int64(maxConnections)
+			i18n.Logf(i18n.BalancerBackendMaxConnections, backend.id, backend.URL, maxConnections)
+		}
+	}
+}
+
+// SetSaturationThreshold задает загрузку (долю backend_max_connections), начиная с которой бэкенд
+// выбирается, только если остальные подходящие бэкенды загружены не меньше.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetSaturationThreshold(ratio float64) {
+	if ratio > 0 {
+		b.saturationThreshold = ratio
+	}
+}
+
+// InFlight возвращает количество запросов, которые проксируются на бэкенд в данный момент.
+func (b *Backend) InFlight() int64 {
+	return b.inFlight.Load()
+}
+
+// Saturation возвращает загрузку бэкенда: отношение запросов в обработке к backend_max_connections.
+// Для бэкенда без ограничения всегда 0.
+func (b *Backend) Saturation() float64 {
+	if b.maxConnections <= 0 {
+		return 0
+	}
+	return float64(b.inFlight.Load()) / float64(b.maxConnections)
+}
+
+// full сообщает, что бэкенд достиг backend_max_connections и новые запросы на него не направляются.
+// Проверка не резервирует место, поэтому при одновременном выборе лимит может быть ненадолго превышен.
+func (b *Backend) full() bool {
+	return b.maxConnections > 0 && b.inFlight.Load() >= b.maxConnections
+}
+
+// saturationPick собирает при выборе бэкенда запасной вариант: наименее загруженный
+// из почти заполненных бэкендов, на случай если ненагруженных не найдется.
+type saturationPick struct {
+	threshold float64
+	backend   *Backend
+	full      bool // Хотя бы один подходящий бэкенд пропущен, потому что достиг backend_max_connections.
+}
+
+// consider сообщает, можно ли сразу выбрать работоспособный бэкенд. Заполненный бэкенд пропускается,
+// почти заполненный запоминается как запасной, если он загружен меньше ранее найденного.
+func (p *saturationPick) consider(backend *Backend) bool {
+	if backend.full() {
+		p.full = true
+		return false
+	}
+	saturation := backend.Saturation()
+	if saturation < p.threshold {
+		return true
+	}
+	if p.backend == nil || saturation < p.backend.Saturation() {
+		p.backend = backend
+	}
+	return false
+}
+
+// result возвращает запасной вариант, если ненагруженных бэкендов не нашлось.
+func (p *saturationPick) result() (*Backend, int, error) {
+	switch {
+	case p.backend != nil:
+		saturatedSelectionsTotal.Inc()
+		return p.backend, p.backend.id, nil
+	case p.full:
+		saturationRejectionsTotal.Inc()
+		return nil, -1, ErrBackendsSaturated
+	default:
+		return nil, -1, ErrNoHealthyBackends
+	}
+}
@@ -0,0 +1,100 @@
+package balancer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var (
+	primaryRequestsTotal = metrics.NewCounter("balancer_primary_requests_total",
+		"Количество запросов, обработанных основным пулом балансировщика с настроенным spillover.")
+	spilloverRequestsTotal = metrics.NewCounter("balancer_spillover_requests_total",
+		"Количество запросов, перелитых в резервный пул из-за исчерпания бюджета основного.")
+	spilloverUnavailableTotal = metrics.NewCounter("balancer_spillover_unavailable_total",
+		"Количество запросов сверх бюджета основного пула, оставшихся в нем из-за недоступности резервного.")
+)
+
+// spillover - резервный пул и бюджет основного пула, при исчерпании которого запросы уходят в резервный.
+type spillover struct {
+	pool        *Balancer
+	maxInFlight int64        // 0 - без ограничения одновременных запросов.
+	inFlight    atomic.Int64 // Запросы, обрабатываемые основным пулом.
+	rate        *rateBudget  // nil - без ограничения частоты.
+}
+
+// SetSpillover задает резервный пул, используемый только когда основной пул исчерпал бюджет:
+// maxInFlight одновременных запросов и/или maxRPS запросов в секунду (0 - без ограничения).
+// Rate Limiter и ограничение одновременных запросов применяются один раз, до выбора пула.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetSpillover(overflow *Balancer, maxInFlight int, maxRPS float64) {
+	s := &spillover{pool: overflow, maxInFlight: int64(maxInFlight)}
+	if maxRPS > 0 {
+		s.rate = newRateBudget(maxRPS)
+	}
+	b.spillover = s
+	i18n.Logf(i18n.BalancerSpilloverEnabled, len(overflow.backendList()), maxInFlight, maxRPS)
+}
+
+// admitPrimary занимает место в бюджете основного пула. false - бюджет исчерпан, запрос нужно перелить.
+func (s *spillover) admitPrimary() bool {
+	if s.maxInFlight > 0 {
+		for {
+			current := s.inFlight.Load()
+			if current >= s.maxInFlight {
+				return false
+			}
+			if s.inFlight.CompareAndSwap(current, current+1) {
+				break
+			}
+		}
+	} else {
+		s.inFlight.Add(1)
+	}
+
+	if s.rate != nil && !s.rate.take() {
+		s.inFlight.Add(-1)
+		return false
+	}
+	return true
+}
+
+// enterPrimary учитывает запрос основного пула без проверки бюджета.
+func (s *spillover) enterPrimary() {
+	s.inFlight.Add(1)
+}
+
+// leavePrimary освобождает место в бюджете основного пула после обработки запроса.
+func (s *spillover) leavePrimary() {
+	s.inFlight.Add(-1)
+}
+
+// rateBudget - корзина токенов на rate запросов в секунду с запасом на одну секунду.
+type rateBudget struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateBudget(rate float64) *rateBudget {
+	return &rateBudget{rate: rate, tokens: max(rate, 1), last: time.Now()}
+}
+
+// take забирает токен, если он есть.
+func (rb *rateBudget) take() bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	now := time.Now()
+	rb.tokens = min(max(rb.rate, 1), rb.tokens+now.Sub(rb.last).Seconds()*rb.rate)
+	rb.last = now
+	if rb.tokens < 1 {
+		return false
+	}
+	rb.tokens--
+	return true
+}
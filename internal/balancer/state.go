@@ -0,0 +1,116 @@
+package balancer
+
+import (
+	"maps"
+	"time"
+)
+
+// BackendState - состояние бэкенда в снимке пула (см. Balancer.Snapshot).
+type BackendState struct {
+	Index int    `json:"index"` // ID бэкенда (см. Backend.ID)
+	URL   string `json:"url"`
+	Alive bool   `json:"alive"`
+	// Drained - бэкенд выведен из балансировки вручную (POST /admin/backends/{id}/drain) и не получает новых запросов.
+	Drained bool              `json:"drained,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Weight  int               `json:"weight"`
+	// ConsecutiveFailures - неудачные активные проверки подряд.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// NextCheck - не раньше этого момента бэкенд будет проверен снова (backoff); пусто, если ограничения нет.
+	NextCheck *time.Time `json:"next_check,omitempty"`
+	// LastCheck - время последней активной проверки (пусто, если проверок еще не было), LastError - ее ошибка.
+	LastCheck *time.Time `json:"last_check,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	// HeldUntil - бэкенд исключен после ошибки до этого момента (passive_health.hold_down).
+	HeldUntil *time.Time `json:"held_until,omitempty"`
+	// ThrottledUntil - вес бэкенда снижен после ответа 429/503 до этого момента (upstream_throttling).
+	ThrottledUntil *time.Time `json:"throttled_until,omitempty"`
+	// RecentFailures - ошибки проксирования за passive_health.failure_window, еще не исключившие бэкенд.
+	RecentFailures int `json:"recent_failures,omitempty"`
+	// SlowStartPercent - бэкенд недавно вернулся в балансировку и получает эту долю своего веса (passive_health.slow_start).
+	SlowStartPercent int `json:"slow_start_percent,omitempty"`
+	// InFlight - запросы, которые проксируются на бэкенд в данный момент.
+	InFlight int64 `json:"in_flight"`
+	// Requests - запросы, направленные на бэкенд с момента запуска; Failures - из них завершившиеся
+	// ошибкой проксирования, ответом, не прошедшим проверку, или статусом 5xx.
+	Requests uint64 `json:"requests"`
+	Failures uint64 `json:"failures"`
+	// MaxConnections - ограничение backend_max_connections (0 - без ограничения), Saturation - доля от него.
+	MaxConnections int64   `json:"max_connections,omitempty"`
+	Saturation     float64 `json:"saturation,omitempty"`
+}
+
+// PoolState - снимок состояния пула бэкендов (см. Balancer.Snapshot).
+type PoolState struct {
+	Algorithm string `json:"algorithm"`
+	Healthy   int    `json:"healthy"`
+	// Available - доступные бэкенды, не достигшие backend_max_connections и не выведенные вручную:
+	// на них можно направить запрос.
+	Available int            `json:"available"`
+	Total     int            `json:"total"`
+	Backends  []BackendState `json:"backends"`
+}
+
+// Snapshot возвращает снимок состояния пула: алгоритм, доступность бэкендов, состояние их проверок
+// и счетчики запросов. Снимок - копия на момент вызова: он не меняется вместе с пулом, и его можно
+// свободно передавать между горутинами. Используется служебным API (GET /admin/state), проверкой
+// готовности и тестами; для встраивания балансировщика это поддерживаемый способ узнать его состояние.
+func (b *Balancer) Snapshot() PoolState {
+	backends := b.backendList()
+	state := PoolState{
+		Algorithm: b.algorithm,
+		Backends:  make([]BackendState, 0, len(backends)),
+	}
+	for _, backend := range backends {
+		if backend.Removed() {
+			continue
+		}
+		state.Total++
+		backendState := BackendState{
+			Index:   backend.id,
+			URL:     backend.URL.String(),
+			Alive:   backend.IsAlive(),
+			Drained: backend.Drained(),
+			Labels:  maps.Clone(backend.Labels),
+			Weight:  backend.weight,
+
+			InFlight:       backend.InFlight(),
+			Requests:       backend.requests.Load(),
+			Failures:       backend.failed.Load(),
+			MaxConnections: backend.maxConnections,
+			Saturation:     backend.Saturation(),
+		}
+		if backendState.Alive {
+			state.Healthy++
+			if !backend.full() && !backendState.Drained {
+				state.Available++
+			}
+		}
+		if heldUntil, held := backend.HeldUntil(); held {
+			backendState.HeldUntil = &heldUntil
+		}
+		if throttledUntil, throttled := backend.ThrottledUntil(); throttled {
+			backendState.ThrottledUntil = &throttledUntil
+		}
+		if policy := b.passiveOf(backend); policy.maxFailures > 1 {
+			backendState.RecentFailures = backend.failures.count(time.Now(), policy.failureWindow)
+		}
+		if percent := b.slowStartPercent(backend, time.Now()); percent < 100 {
+			backendState.SlowStartPercent = percent
+		}
+
+		backend.health.mu.Lock()
+		backendState.ConsecutiveFailures = backend.health.consecutiveFailures
+		if nextCheck := backend.health.nextCheck; nextCheck.After(time.Now()) {
+			backendState.NextCheck = &nextCheck
+		}
+		if lastCheck := backend.health.lastCheck; !lastCheck.IsZero() {
+			backendState.LastCheck = &lastCheck
+			backendState.LastError = backend.health.checkError
+		}
+		backend.health.mu.Unlock()
+
+		state.Backends = append(state.Backends, backendState)
+	}
+	return state
+}
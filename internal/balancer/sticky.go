@@ -0,0 +1,130 @@
+package balancer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/metrics"
+)
+
+var (
+	stickyHitsTotal = metrics.NewCounter("balancer_sticky_hits_total",
+		"Количество запросов, направленных на бэкенд из cookie привязки.")
+	stickyFallbacksTotal = metrics.NewCounter("balancer_sticky_fallbacks_total",
+		"Количество запросов с cookie привязки, для которых бэкенд выбран заново: бэкенд недоступен, неизвестен или подпись неверна.")
+)
+
+// stickySessions выдает и проверяет cookie привязки клиента к бэкенду (см. config.StickySessionConfig).
+// В cookie хранится не ID бэкенда в пуле (Backend.ID), а ID по его URL: привязка переживает перезапуск и изменение
+// порядка backend_servers, а адреса бэкендов не раскрываются клиенту.
+type stickySessions struct {
+	cookieName string
+	secret     []byte
+	ttl        time.Duration
+}
+
+// stickyID возвращает ID бэкенда для cookie привязки: начало SHA-256 его URL.
+func stickyID(backendURL string) string {
+	sum := sha256.Sum256([]byte(backendURL))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SetStickySessions включает привязку клиентов к бэкендам по cookie.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetStickySessions(cfg config.StickySessionConfig) {
+	if !cfg.Enabled {
+		return
+	}
+	secret := []byte(cfg.Secret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+	cookieName := cfg.CookieName
+	if cookieName == "" {
+		cookieName = config.DefaultStickyCookieName
+	}
+	s := &stickySessions{
+		cookieName: cookieName,
+		secret:     secret,
+		ttl:        cfg.TTL,
+	}
+	b.sticky = s
+}
+
+// sign возвращает подпись ID бэкенда.
+func (s *stickySessions) sign(id string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// value возвращает значение cookie для бэкенда: "<ID>.<подпись>".
+func (s *stickySessions) value(backend *Backend) string {
+	return backend.stickyID + "." + s.sign(backend.stickyID)
+}
+
+// lookup возвращает бэкенд пула из cookie запроса; false - cookie нет.
+// nil означает, что cookie подделана или ссылается на бэкенд не из этого пула (или удаленный).
+func (s *stickySessions) lookup(r *http.Request, backends []*Backend) (*Backend, bool) {
+	cookie, err := r.Cookie(s.cookieName)
+	if err != nil {
+		return nil, false
+	}
+	id, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(id))) {
+		return nil, true
+	}
+	for _, backend := range backends {
+		if backend.stickyID == id && !backend.Removed() {
+			return backend, true
+		}
+	}
+	return nil, true
+}
+
+// stickyBackend возвращает бэкенд из cookie привязки, если он доступен, подходит под селектор маршрута
+// и не достиг backend_max_connections, вместе с его ID. false - бэкенд выбирается алгоритмом балансировки.
+func (b *Balancer) stickyBackend(r *http.Request, rt *route) (*Backend, int, bool) {
+	if b.sticky == nil {
+		return nil, -1, false
+	}
+	backend, found := b.sticky.lookup(r, b.backendList())
+	if !found {
+		return nil, -1, false
+	}
+	if backend != nil && backend.Matches(rt.selector) && backend.selectable() && !backend.full() {
+		stickyHitsTotal.Inc()
+		return backend, backend.id, true
+	}
+	stickyFallbacksTotal.Inc()
+	return nil, -1, false
+}
+
+// stick выдает клиенту cookie привязки к выбранному бэкенду, если в запросе ее нет или она указывает
+// на другой бэкенд. Cookie добавляется к ответу бэкенда.
+func (b *Balancer) stick(w http.ResponseWriter, r *http.Request, backend *Backend) {
+	if b.sticky == nil {
+		return
+	}
+	value := b.sticky.value(backend)
+	if cookie, err := r.Cookie(b.sticky.cookieName); err == nil && cookie.Value == value {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     b.sticky.cookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(b.sticky.ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,153 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+// weightScale - множитель весов во взвешенном выборе: целые веса позволяют снизить вес бэкенда
+// с весом 1 до доли upstream_throttling.weight_percent или passive_health.slow_start.
+const weightScale = 100
+
+// errUpstreamThrottled - ModifyResponse прокси отбрасывает ответ 429/503, чтобы повторить запрос
+// на другом бэкенде. ErrorHandler прокси такой ответ клиенту не пишет.
+var errUpstreamThrottled = errors.New("upstream throttled")
+
+var (
+	upstreamThrottledTotal = metrics.NewCounter("balancer_upstream_throttled_total",
+		"Количество ответов бэкендов 429 и 503 в пулах с upstream_throttling, отличным от pass_through.")
+	throttleRetriesTotal = metrics.NewCounter("balancer_upstream_throttle_retries_total",
+		"Количество запросов, повторенных на другом бэкенде после ответа 429 или 503.")
+)
+
+// upstreamThrottling - реакция пула на ответы бэкендов 429 и 503 (см. SetUpstreamThrottling).
+type upstreamThrottling struct {
+	policy        string
+	penalty       time.Duration
+	weightPercent int
+}
+
+// throttledKey - ключ контекста запроса, который при ответе 429/503 повторяется на другом бэкенде.
+type throttledKey struct{}
+
+// throttledResponse - отброшенный ответ 429/503: статус и заголовки передаются клиенту,
+// если повторить запрос негде.
+type throttledResponse struct {
+	status int
+	header http.Header
+}
+
+// SetUpstreamThrottling задает реакцию пула на ответы бэкендов 429 и 503 (upstream_throttling):
+// config.ThrottleRetry - запрос без тела повторяется один раз на другом бэкенде пула;
+// config.ThrottleReduceWeight - вес бэкенда на penalty снижается до weightPercent процентов.
+// config.ThrottlePassThrough или пустая строка - ответ передается клиенту как есть.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetUpstreamThrottling(policy string, penalty time.Duration, weightPercent int) {
+	if policy == "" || policy == config.ThrottlePassThrough {
+		return
+	}
+	b.throttling = &upstreamThrottling{policy: policy, penalty: penalty, weightPercent: weightPercent}
+	if policy == config.ThrottleReduceWeight {
+		// Снижение веса учитывается только взвешенным выбором
+		b.weighted.Store(true)
+	}
+	i18n.Logf(i18n.BalancerThrottlePolicy, policy, len(b.backendList()))
+}
+
+// retries сообщает, что запрос повторяется на другом бэкенде при ответе 429/503.
+// Повторяются только запросы без тела: тело уже прочитано первым бэкендом.
+func (t *upstreamThrottling) retries(r *http.Request) bool {
+	return t != nil && t.policy == config.ThrottleRetry && (r.Body == nil || r.Body == http.NoBody)
+}
+
+// checkThrottled - часть ModifyResponse прокси бэкенда: применяет реакцию пула к ответу 429/503.
+func (b *Balancer) checkThrottled(backend *Backend, resp *http.Response) error {
+	if b.throttling == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return nil
+	}
+	upstreamThrottledTotal.Inc()
+	switch b.throttling.policy {
+	case config.ThrottleReduceWeight:
+		until := time.Now().Add(b.throttling.penalty)
+		backend.throttledUntil.Store(until.UnixNano())
+		i18n.Logf(i18n.BalancerThrottleWeightReduced, backend.URL, resp.StatusCode, b.throttling.weightPercent, until.Format(time.RFC3339))
+	case config.ThrottleRetry:
+		if state, ok := resp.Request.Context().Value(throttledKey{}).(*throttledResponse); ok {
+			state.status = resp.StatusCode
+			state.header = resp.Header.Clone()
+			return errUpstreamThrottled
+		}
+	}
+	return nil
+}
+
+// ThrottledUntil возвращает момент, до которого вес бэкенда снижен после ответа 429/503;
+// false, если вес не снижен.
+func (b *Backend) ThrottledUntil() (time.Time, bool) {
+	until := b.throttledUntil.Load()
+	if until == 0 || time.Now().UnixNano() >= until {
+		return time.Time{}, false
+	}
+	return time.Unix(0, until), true
+}
+
+// weightOf возвращает вес бэкенда во взвешенном выборе с учетом снижения после ответа 429/503
+// и плавного возврата в балансировку (passive_health.slow_start).
+func (b *Balancer) weightOf(backend *Backend) int {
+	weight := backend.weight * weightScale
+	if b.throttling != nil && b.throttling.policy == config.ThrottleReduceWeight {
+		if _, throttled := backend.ThrottledUntil(); throttled {
+			weight = weight * b.throttling.weightPercent / 100
+		}
+	}
+	if percent := b.slowStartPercent(backend, time.Now()); percent < 100 {
+		weight = weight * percent / 100
+	}
+	return max(1, weight)
+}
+
+// forwardWithRetry проксирует запрос и при ответе 429/503 повторяет его один раз на другом бэкенде пула.
+// Если другого доступного бэкенда нет, клиент получает статус и заголовки первого ответа без тела.
+func (b *Balancer) forwardWithRetry(w http.ResponseWriter, r *http.Request, targetBackend *Backend, backendIndex int, clientID string) {
+	state := &throttledResponse{}
+	first := r.WithContext(context.WithValue(r.Context(), throttledKey{}, state))
+	b.proxyTo(w, first, targetBackend, backendIndex, clientID, state)
+	if state.status == 0 {
+		return
+	}
+
+	rt := b.matchRoute(r.URL.Path)
+	for range b.backendList() {
+		next, nextIndex, err := b.nextBackendForRoute(rt, clientID)
+		if err != nil {
+			break
+		}
+		if nextIndex == backendIndex {
+			continue
+		}
+		// Запрос, устаревший за время первой попытки, не повторяется: клиент получает 503
+		if b.shedExpired(w, r, clientID) {
+			return
+		}
+		// Cookie привязки не меняется: бэкенд перегружен временно
+		throttleRetriesTotal.Inc()
+		traceFrom(r.Context()).add("retry=%d->%d status %d", backendIndex, nextIndex, state.status)
+		i18n.Logf(i18n.BalancerThrottleRetry, backendIndex, targetBackend.URL, state.status, nextIndex, next.URL)
+		b.proxyTo(w, r, next, nextIndex, clientID, nil)
+		return
+	}
+
+	traceFrom(r.Context()).add("retry=none status %d", state.status)
+	i18n.Logf(i18n.BalancerThrottleNoRetry, backendIndex, targetBackend.URL, state.status)
+	for key, values := range state.header {
+		w.Header()[key] = values
+	}
+	w.Header().Del("Content-Length") // Тело ответа уже закрыто
+	w.WriteHeader(state.status)
+}
@@ -0,0 +1,51 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var backendTimeoutsTotal = metrics.NewCounter("balancer_backend_timeouts_total",
+	"Количество запросов, на которые бэкенд не начал отвечать за timeouts.backend_response (ответ клиенту 504).")
+
+// errBackendTimeout - причина отмены запроса к бэкенду, не начавшему отвечать вовремя (см. withBackendTimeout).
+var errBackendTimeout = i18n.NewError(i18n.BalancerBackendTimeout)
+
+// SetBackendTimeout задает, сколько ждать начала ответа бэкенда (timeouts.backend_response); 0 - без ограничения.
+// Маршрут может переопределить значение (routes[].backend_timeout). Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetBackendTimeout(timeout time.Duration) {
+	b.backendTimeout = timeout
+}
+
+// withBackendTimeout ограничивает ожидание заголовков ответа бэкенда: если они не получены за время маршрута rt
+// (или пула), запрос к бэкенду отменяется с причиной errBackendTimeout. Тело ответа читается без ограничения,
+// поэтому потоковые ответы (SSE, gRPC) не прерываются. stop нужно вызвать после завершения проксирования.
+func (b *Balancer) withBackendTimeout(r *http.Request, rt *route) (*http.Request, func()) {
+	timeout := b.backendTimeout
+	if rt.backendTimeout != nil {
+		timeout = *rt.backendTimeout
+	}
+	if timeout <= 0 {
+		return r, func() {}
+	}
+	ctx, cancel := context.WithCancelCause(r.Context())
+	timer := time.AfterFunc(timeout, func() { cancel(errBackendTimeout) })
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { timer.Stop() },
+	})
+	return r.WithContext(ctx), func() {
+		timer.Stop()
+		cancel(nil)
+	}
+}
+
+// backendTimedOut сообщает, что запрос к бэкенду отменен по timeouts.backend_response, а не клиентом.
+func backendTimedOut(r *http.Request) bool {
+	return errors.Is(context.Cause(r.Context()), errBackendTimeout)
+}
@@ -0,0 +1,180 @@
+package balancer
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"load-balancer/internal/config"
+)
+
+// TraceHeader - заголовок запроса, включающий трассировку решений (значение - trace.token),
+// и заголовок ответа с трассировкой.
+const TraceHeader = "X-Balancer-Trace"
+
+type traceKey struct{}
+
+// decisionTrace - решения, принятые по запросу: маршрут, решение Rate Limiter, рассмотренные бэкенды,
+// повторы. Методы допускают nil: запрос без трассировки ничего не записывает.
+type decisionTrace struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+// add добавляет шаг вида "ключ=значение".
+func (t *decisionTrace) add(format string, args ...any) {
+	if t == nil {
+		return
+	}
+	step := fmt.Sprintf(format, args...)
+	t.mu.Lock()
+	t.steps = append(t.steps, step)
+	t.mu.Unlock()
+}
+
+// String возвращает шаги через "; ".
+func (t *decisionTrace) String() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.steps, "; ")
+}
+
+// traceFrom возвращает трассировку запроса или nil.
+func traceFrom(ctx context.Context) *decisionTrace {
+	t, _ := ctx.Value(traceKey{}).(*decisionTrace)
+	return t
+}
+
+// SetTrace задает трассировку решений в заголовке ответа X-Balancer-Trace (секция trace).
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetTrace(cfg config.TraceConfig) {
+	b.trace = cfg
+}
+
+// startTrace начинает трассировку запроса, если ее запросил клиент (заголовок X-Balancer-Trace
+// со значением trace.token) или запрос записывается (captured). В ответ трассировка попадает,
+// только если ее запросил клиент; заголовок запроса бэкенду не передается.
+func (b *Balancer) startTrace(w http.ResponseWriter, r *http.Request, captured bool) (http.ResponseWriter, *http.Request, *decisionTrace) {
+	if t := traceFrom(r.Context()); t != nil {
+		return w, r, t // Запрос передан из другого пула (перелив, сценарий допуска)
+	}
+	requested := b.trace.Enabled && (b.trace.Token == "" ||
+		subtle.ConstantTimeCompare([]byte(r.Header.Get(TraceHeader)), []byte(b.trace.Token)) == 1)
+	if !requested && !captured {
+		return w, r, nil
+	}
+	t := &decisionTrace{}
+	r = r.WithContext(context.WithValue(r.Context(), traceKey{}, t))
+	if requested {
+		w = &traceWriter{ResponseWriter: w, trace: t}
+	}
+	return w, r, t
+}
+
+// traceLimit записывает решение Rate Limiter по ключу key и остаток токенов корзины, если Limiter его отдает.
+func (b *Balancer) traceLimit(t *decisionTrace, key string, allowed bool, err error) {
+	if t == nil {
+		return
+	}
+	if err != nil {
+		t.add("ratelimit=store_error")
+		return
+	}
+	outcome := "allowed"
+	if !allowed {
+		outcome = "denied"
+	}
+	if reader, ok := b.rateLimiter.(BucketReader); ok {
+		if tokens, capacity, _, found := reader.Bucket(key); found {
+			t.add("ratelimit=%s key=%s tokens=%.2f/%g", outcome, key, tokens, capacity)
+			return
+		}
+	}
+	t.add("ratelimit=%s key=%s", outcome, key)
+}
+
+// traceCandidates описывает бэкенды, которые маршрут rt рассматривает при выборе: доступные через
+// запятую и недоступные с причиной (down, drained, full).
+func (b *Balancer) traceCandidates(rt *route) string {
+	var available, skipped []string
+	for _, backend := range b.backendList() {
+		if backend.Removed() || !backend.Matches(rt.selector) {
+			continue
+		}
+		switch {
+		case backend.Drained():
+			skipped = append(skipped, strconv.Itoa(backend.id)+":drained")
+		case !backend.IsAlive():
+			skipped = append(skipped, strconv.Itoa(backend.id)+":down")
+		case backend.full():
+			skipped = append(skipped, strconv.Itoa(backend.id)+":full")
+		default:
+			available = append(available, strconv.Itoa(backend.id))
+		}
+	}
+	result := "candidates=" + strings.Join(available, ",")
+	if len(skipped) > 0 {
+		result += " skipped=" + strings.Join(skipped, ",")
+	}
+	return result
+}
+
+// traceRoute - имя маршрута в трассировке: префикс или "default".
+func traceRoute(rt *route) string {
+	if rt.prefix == "" {
+		return "default"
+	}
+	return rt.prefix
+}
+
+// traceWriter добавляет трассировку в заголовки ответа перед их отправкой: в ней есть все решения,
+// принятые до ответа, включая повторы на других бэкендах.
+type traceWriter struct {
+	http.ResponseWriter
+	trace       *decisionTrace
+	wroteHeader bool
+}
+
+func (tw *traceWriter) setHeader() {
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		tw.Header().Set(TraceHeader, tw.trace.String())
+	}
+}
+
+func (tw *traceWriter) WriteHeader(code int) {
+	if code >= http.StatusOK {
+		tw.setHeader()
+	}
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *traceWriter) Write(p []byte) (int, error) {
+	tw.setHeader()
+	return tw.ResponseWriter.Write(p)
+}
+
+// Flush нужен потоковым ответам (gRPC, SSE), которые проверяют http.Flusher напрямую.
+func (tw *traceWriter) Flush() {
+	tw.setHeader()
+	_ = http.NewResponseController(tw.ResponseWriter).Flush()
+}
+
+// Hijack вызывается прокси после ответа бэкенда 101 (WebSocket): заголовок трассировки попадает в ответ 101.
+func (tw *traceWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.setHeader()
+	return http.NewResponseController(tw.ResponseWriter).Hijack()
+}
+
+func (tw *traceWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -0,0 +1,68 @@
+package balancer
+
+import (
+	"net/http"
+
+	"load-balancer/internal/requestid"
+	"load-balancer/internal/tracing"
+)
+
+// SetTracer подключает распределенную трассировку OpenTelemetry: span обработки каждого запроса
+// и span каждого обращения к бэкенду, контекст которого передается бэкенду в заголовке traceparent.
+// Один Tracer может разделяться несколькими балансировщиками. Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetTracer(t *tracing.Tracer) {
+	b.tracer = t
+}
+
+// startServerSpan начинает span обработки запроса с родителем из заголовка traceparent клиента.
+// finish завершает span со статусом ответа и бэкендом upstream. Запрос, переданный из другого пула
+// (перелив, сценарий допуска), уже имеет span: новый не создается.
+func (b *Balancer) startServerSpan(w http.ResponseWriter, r *http.Request, clientID string) (http.ResponseWriter, *http.Request, func(upstream string)) {
+	if b.tracer == nil || tracing.SpanFromContext(r.Context()) != nil {
+		return w, r, func(string) {}
+	}
+	remote, _ := tracing.Extract(r.Header)
+	ctx, span := b.tracer.StartSpan(r.Context(), r.Method, tracing.SpanKindServer, remote)
+	span.SetAttribute("http.request.method", r.Method)
+	span.SetAttribute("url.path", r.URL.Path)
+	span.SetAttribute("balancer.client_id", clientID)
+	if id := requestid.FromContext(ctx); id != "" {
+		span.SetAttribute("balancer.request_id", id)
+	}
+	sw := &statusWriter{ResponseWriter: w}
+	return sw, r.WithContext(ctx), func(upstream string) {
+		if upstream != "" {
+			span.SetAttribute("balancer.backend", upstream)
+		}
+		endSpan(span, sw.status)
+	}
+}
+
+// startBackendSpan начинает span обращения к бэкенду; director передает его контекст бэкенду (см. tracing.Inject).
+func (b *Balancer) startBackendSpan(r *http.Request, backend *Backend, backendIndex int) (*http.Request, *tracing.Span) {
+	if b.tracer == nil {
+		return r, nil
+	}
+	ctx, span := b.tracer.StartSpan(r.Context(), r.Method, tracing.SpanKindClient, tracing.SpanContext{})
+	span.SetAttribute("http.request.method", r.Method)
+	span.SetAttribute("server.address", backend.URL.Host)
+	span.SetAttribute("balancer.backend", backend.URL.String())
+	span.SetAttribute("balancer.backend_index", backendIndex)
+	return r.WithContext(ctx), span
+}
+
+// endSpan завершает span с кодом ответа; 5xx отмечается как ошибка. Статус 0 - ответ не записан
+// (обработчик ничего не вернул) и считается 200.
+func endSpan(span *tracing.Span, status int) {
+	if span == nil {
+		return
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	span.SetAttribute("http.response.status_code", status)
+	if status >= http.StatusInternalServerError {
+		span.SetError(http.StatusText(status))
+	}
+	span.End()
+}
@@ -0,0 +1,67 @@
+package balancer
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"load-balancer/internal/metrics"
+)
+
+var (
+	upgradesTotal = metrics.NewCounter("balancer_upgrades_total",
+		"Количество соединений, переключенных на другой протокол (WebSocket) ответом бэкенда 101.")
+	upgradedConnections = metrics.NewGauge("balancer_upgraded_connections",
+		"Открытые соединения после смены протокола (WebSocket).")
+)
+
+// upgradeWriter обслуживает запрос на смену протокола (Connection: Upgrade, например WebSocket). После ответа
+// бэкенда 101 прокси забирает соединение клиента (Hijack) и копирует данные в обе стороны без буферизации
+// и без FlushInterval; сроки timeouts.read и timeouts.write HTTP-сервер при Hijack снимает сам.
+// upgradeWriter учитывает соединение в метриках до его закрытия и запоминает время ответа 101.
+type upgradeWriter struct {
+	http.ResponseWriter
+	switched time.Time // Время ответа 101; нулевое - протокол не сменился.
+}
+
+func (uw *upgradeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(uw.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	uw.switched = time.Now()
+	upgradesTotal.Inc()
+	upgradedConnections.Inc()
+	return &upgradedConn{Conn: conn}, brw, nil
+}
+
+// Flush нужен потоковым ответам, если бэкенд ответил на запрос без смены протокола.
+func (uw *upgradeWriter) Flush() {
+	_ = http.NewResponseController(uw.ResponseWriter).Flush()
+}
+
+func (uw *upgradeWriter) Unwrap() http.ResponseWriter {
+	return uw.ResponseWriter
+}
+
+// responseTime возвращает время ответа бэкенда, начатого в started: после смены протокола - до ответа 101,
+// а не длительность сессии. Допускает nil (запрос без смены протокола).
+func (uw *upgradeWriter) responseTime(started, finished time.Time) time.Duration {
+	if uw != nil && !uw.switched.IsZero() {
+		return uw.switched.Sub(started)
+	}
+	return finished.Sub(started)
+}
+
+// upgradedConn учитывает закрытие соединения после смены протокола в balancer_upgraded_connections.
+type upgradedConn struct {
+	net.Conn
+	closeOnce sync.Once
+}
+
+func (c *upgradedConn) Close() error {
+	c.closeOnce.Do(upgradedConnections.Dec)
+	return c.Conn.Close()
+}
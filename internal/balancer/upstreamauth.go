@@ -0,0 +1,72 @@
+package balancer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+)
+
+// Заголовки подписи запроса к бэкенду при upstream_auth.type: hmac.
+const (
+	UpstreamTimestampHeader = "X-LB-Timestamp" // Время подписи, Unix-секунды.
+	UpstreamSignatureHeader = "X-LB-Signature" // Подпись в hex (см. SignUpstreamRequest).
+)
+
+// upstreamAuth добавляет учетные данные к запросам на бэкенды пула (см. config.UpstreamAuthConfig).
+type upstreamAuth struct {
+	kind   string
+	header string // Заголовок для bearer и basic.
+	value  string // Значение заголовка для bearer и basic.
+	secret []byte // Ключ подписи для hmac.
+}
+
+// SetUpstreamAuth задает учетные данные, которые добавляются к запросам на бэкенды пула и к их активным
+// проверкам (upstream_auth). Пустой cfg.Type - учетные данные не добавляются.
+// Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetUpstreamAuth(cfg config.UpstreamAuthConfig) {
+	if cfg.Type == "" {
+		return
+	}
+	auth := &upstreamAuth{kind: cfg.Type, header: cfg.Header}
+	switch cfg.Type {
+	case config.UpstreamAuthBearer:
+		auth.value = "Bearer " + cfg.Token
+	case config.UpstreamAuthBasic:
+		auth.value = "Basic " + base64.StdEncoding.EncodeToString([]byte(cfg.Username+":"+cfg.Password))
+	case config.UpstreamAuthHMAC:
+		auth.secret = []byte(cfg.Secret)
+	}
+	b.upstreamAuth.Store(auth)
+	i18n.Logf(i18n.BalancerUpstreamAuth, cfg.Type, len(b.backendList()))
+}
+
+// apply добавляет учетные данные к запросу на бэкенд, заменяя значения, переданные клиентом.
+// Бэкендам недоверенного маршрута токен и пароль не отправляются: подпись hmac не раскрывает ключ
+// и добавляется всегда.
+func (a *upstreamAuth) apply(r *http.Request, untrusted bool, now time.Time) {
+	if a.kind != config.UpstreamAuthHMAC {
+		if !untrusted {
+			r.Header.Set(a.header, a.value)
+		}
+		return
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	r.Header.Set(UpstreamTimestampHeader, timestamp)
+	r.Header.Set(UpstreamSignatureHeader, SignUpstreamRequest(a.secret, timestamp, r.Method, r.URL.RequestURI()))
+}
+
+// SignUpstreamRequest возвращает подпись запроса к бэкенду при upstream_auth.type: hmac -
+// HMAC-SHA256 строки "<timestamp>\n<метод>\n<путь с query>" в hex. Бэкенд вычисляет подпись так же,
+// сравнивает ее с X-LB-Signature и по X-LB-Timestamp отклоняет устаревшие запросы.
+func SignUpstreamRequest(secret []byte, timestamp, method, requestURI string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "\n" + method + "\n" + requestURI))
+	return hex.EncodeToString(mac.Sum(nil))
+}
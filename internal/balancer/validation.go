@@ -0,0 +1,87 @@
+package balancer
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var invalidResponsesTotal = metrics.NewCounter("balancer_upstream_invalid_responses_total",
+	"Количество успешных ответов бэкендов, не прошедших routes[].response_validation.")
+
+// responseValidator проверяет успешные ответы бэкендов на маршруте (см. config.ResponseValidationConfig).
+type responseValidator struct {
+	contentTypes []string
+	nonEmptyBody bool
+}
+
+func newResponseValidator(cfg *config.ResponseValidationConfig) *responseValidator {
+	if cfg == nil || (len(cfg.ContentTypes) == 0 && !cfg.NonEmptyBody) {
+		return nil
+	}
+	return &responseValidator{contentTypes: cfg.ContentTypes, nonEmptyBody: cfg.NonEmptyBody}
+}
+
+// validate возвращает ошибку, если ответ нарушает требования маршрута. Проверяются только ответы 2xx:
+// ошибки бэкенда передаются клиенту как есть. Для проверки тела без Content-Length читается первый байт,
+// он остается доступен для передачи клиенту.
+func (v *responseValidator) validate(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil
+	}
+	if len(v.contentTypes) > 0 {
+		contentType := resp.Header.Get("Content-Type")
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !v.allowedType(mediaType) {
+			return i18n.Errorf(i18n.BalancerBadContentType, contentType)
+		}
+	}
+	if v.nonEmptyBody && resp.StatusCode != http.StatusNoContent && resp.Request.Method != http.MethodHead {
+		if resp.ContentLength == 0 {
+			return i18n.Errorf(i18n.BalancerEmptyBody)
+		}
+		if resp.ContentLength < 0 {
+			body := bufio.NewReader(resp.Body)
+			if _, err := body.Peek(1); err == io.EOF {
+				return i18n.Errorf(i18n.BalancerEmptyBody)
+			}
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{body, resp.Body}
+		}
+	}
+	return nil
+}
+
+func (v *responseValidator) allowedType(mediaType string) bool {
+	for _, allowed := range v.contentTypes {
+		if allowed == mediaType {
+			return true
+		}
+		if group, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, group+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateResponse - ModifyResponse прокси бэкенда: проверяет ответ по правилам маршрута запроса.
+// Ошибка передается в ErrorHandler прокси, который отвечает клиенту 502 и исключает бэкенд.
+func (b *Balancer) validateResponse(resp *http.Response) error {
+	validator := b.matchRoute(resp.Request.URL.Path).validator
+	if validator == nil {
+		return nil
+	}
+	if err := validator.validate(resp); err != nil {
+		invalidResponsesTotal.Inc()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package balancer
+
+import (
+	"sync"
+
+	"load-balancer/internal/i18n"
+)
+
+// weightedQueue - состояние плавного взвешенного Round Robin (как в nginx): текущие веса бэкендов.
+// Бэкенды с большим весом выбираются чаще, но не подряд, а вперемешку с остальными.
+type weightedQueue struct {
+	mu      sync.Mutex
+	current map[*Backend]int
+}
+
+// SetBackendWeights задает веса бэкендов пула по их URL (ключи - URL из backend_servers):
+// бэкенд с весом 3 получает втрое больше запросов, чем бэкенд с весом 1. Бэкенды, отсутствующие
+// в weights, имеют вес 1. Должен вызываться до начала обработки запросов.
+func (b *Balancer) SetBackendWeights(weights map[string]int) {
+	for _, backend := range b.backendList() {
+		if weight, ok := weights[backend.URL.String()]; ok && weight > 1 {
+			backend.weight = weight
+			b.weighted.Store(true)
+			i18n.Logf(i18n.BalancerBackendWeight, backend.id, backend.URL, weight)
+		}
+	}
+	if b.ring.Load() != nil {
+		b.buildRing()
+	}
+}
+
+// getWeightedHealthyBackend выбирает работоспособный бэкенд по плавному взвешенному Round Robin
+// и возвращает его с ID. candidates - бэкенды, из которых идет выбор; queue - состояние очереди.
+// Почти заполненные бэкенды пропускаются, пока есть менее загруженные.
+func (b *Balancer) getWeightedHealthyBackend(candidates []*Backend, queue *weightedQueue) (*Backend, int, error) {
+	pick := saturationPick{threshold: b.saturationThreshold}
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if queue.current == nil {
+		queue.current = make(map[*Backend]int, len(candidates))
+	}
+	if len(queue.current) > len(candidates) {
+		// Удаленные бэкенды (RemoveBackend) больше не выбираются: их текущие веса не нужны
+		for backend := range queue.current {
+			if backend.Removed() {
+				delete(queue.current, backend)
+			}
+		}
+	}
+
+	var best *Backend
+	total := 0
+	for _, backend := range candidates {
+		if !backend.selectable() || !pick.consider(backend) {
+			continue
+		}
+		// Бэкенды, добавленные через AddBackend, начинают с нулевого текущего веса
+		weight := b.weightOf(backend)
+		queue.current[backend] += weight
+		total += weight
+		if best == nil || queue.current[backend] > queue.current[best] {
+			best = backend
+		}
+	}
+	if best == nil {
+		return pick.result()
+	}
+	queue.current[best] -= total
+	return best, best.id, nil
+}
+
+// weightedRandom выбирает один из бэкендов с вероятностью, пропорциональной его весу.
+func (b *Balancer) weightedRandom(backends []*Backend) *Backend {
+	total := 0
+	for _, backend := range backends {
+		total += b.weightOf(backend)
+	}
+	n := b.rng.Intn(total)
+	for _, backend := range backends {
+		n -= b.weightOf(backend)
+		if n < 0 {
+			return backend
+		}
+	}
+	return backends[len(backends)-1]
+}
@@ -0,0 +1,277 @@
+// Package bench нагружает работающий экземпляр балансировщика синтетическим трафиком (подкоманда
+// "balancer bench") и измеряет результат снаружи: время ответа, долю отказов и распределение запросов
+// по бэкендам. В отличие от simulate, через балансировщик проходят настоящие запросы, поэтому регрессии
+// производительности выбора бэкенда, Rate Limiter и проксирования видны от начала до конца.
+package bench
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"load-balancer/internal/i18n"
+)
+
+// DefaultClientHeader - заголовок идентификатора клиента по умолчанию (rate_limiter.identifier_header).
+const DefaultClientHeader = "X-Client-ID"
+
+// traceHeader - заголовок трассировки решений балансировщика (см. balancer.TraceHeader).
+const traceHeader = "X-Balancer-Trace"
+
+// Options - параметры нагрузки.
+type Options struct {
+	Target   string        // URL, на который отправляются запросы, например http://localhost:8080/api.
+	RPS      float64       // Суммарная частота запросов всех клиентов.
+	Clients  int           // Число клиентов с разными идентификаторами; запросы распределяются между ними поровну.
+	Duration time.Duration // Длительность нагрузки.
+	// Concurrency - предел одновременных запросов. Запрос, для которого нет свободного слота, не отправляется
+	// и учитывается в Skipped: нагрузка не подстраивается под замедлившийся балансировщик.
+	Concurrency  int
+	Timeout      time.Duration // Таймаут одного запроса.
+	ClientHeader string        // Заголовок идентификатора клиента; пусто - DefaultClientHeader.
+	// TraceToken - trace.token балансировщика: с ним ответы содержат трассировку, из которой берется
+	// бэкенд запроса. Пусто - распределение по бэкендам не измеряется.
+	TraceToken string
+}
+
+// Validate проверяет параметры.
+func (o *Options) Validate() error {
+	target, err := url.Parse(o.Target)
+	switch {
+	case err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "":
+		return i18n.Errorf(i18n.BenchBadOption, "target", o.Target)
+	case o.RPS <= 0:
+		return i18n.Errorf(i18n.BenchBadOption, "rps", o.RPS)
+	case o.Clients < 1:
+		return i18n.Errorf(i18n.BenchBadOption, "clients", o.Clients)
+	case o.Duration <= 0:
+		return i18n.Errorf(i18n.BenchBadOption, "duration", o.Duration)
+	case o.Concurrency < 1:
+		return i18n.Errorf(i18n.BenchBadOption, "concurrency", o.Concurrency)
+	case o.Timeout <= 0:
+		return i18n.Errorf(i18n.BenchBadOption, "timeout", o.Timeout)
+	}
+	return nil
+}
+
+// Latency - перцентили времени ответа в миллисекундах.
+type Latency struct {
+	Min float64 `json:"min_ms"`
+	P50 float64 `json:"p50_ms"`
+	P90 float64 `json:"p90_ms"`
+	P99 float64 `json:"p99_ms"`
+	Max float64 `json:"max_ms"`
+}
+
+// BackendShare - запросы, обработанные бэкендом (по трассировке балансировщика).
+type BackendShare struct {
+	Backend  string  `json:"backend"` // Индекс бэкенда в пуле (см. traceBackend).
+	Requests int64   `json:"requests"`
+	Share    float64 `json:"share"`
+}
+
+// Report - результат нагрузки.
+type Report struct {
+	Target   string  `json:"target"`
+	RPS      float64 `json:"rps"`
+	Clients  int     `json:"clients"`
+	Duration string  `json:"duration"` // Фактическая длительность нагрузки.
+
+	Requests    int64   `json:"requests"`     // Отправленные запросы.
+	Skipped     int64   `json:"skipped"`      // Не отправлены: все слоты Concurrency заняты.
+	AchievedRPS float64 `json:"achieved_rps"` // Отправлено в секунду.
+	// Statuses - ответы по кодам статуса; Errors - запросы без ответа (таймаут, ошибка соединения).
+	Statuses map[int]int64 `json:"statuses"`
+	Errors   int64         `json:"errors"`
+	// RateLimitedRate - доля ответов 429, ErrorRate - доля ответов 5xx и запросов без ответа.
+	RateLimitedRate float64 `json:"rate_limited_rate"`
+	ErrorRate       float64 `json:"error_rate"`
+	// Latency - время ответов (включая отказы), запросы без ответа не учитываются.
+	Latency Latency `json:"latency"`
+	// Backends - распределение ответов с трассировкой по бэкендам; пусто, если ответы без трассировки.
+	Backends []BackendShare `json:"backends,omitempty"`
+}
+
+// result - итог одного запроса.
+type result struct {
+	status  int // 0 - ответа нет.
+	latency time.Duration
+	backend string
+}
+
+// Run отправляет запросы на opts.Target с частотой opts.RPS от opts.Clients клиентов в течение
+// opts.Duration (или до отмены ctx) и возвращает отчет.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.ClientHeader == "" {
+		opts.ClientHeader = DefaultClientHeader
+	}
+	client := &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: opts.Concurrency,
+			IdleConnTimeout:     30 * time.Second,
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	slots := make(chan struct{}, opts.Concurrency)
+	results := make(chan result, opts.Concurrency)
+	var collected []result
+	collector := make(chan struct{})
+	go func() {
+		for res := range results {
+			collected = append(collected, res)
+		}
+		close(collector)
+	}()
+
+	report := &Report{Target: opts.Target, RPS: opts.RPS, Clients: opts.Clients, Statuses: make(map[int]int64)}
+	interval := time.Duration(float64(time.Second) / opts.RPS)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var wg sync.WaitGroup
+	start := time.Now()
+	for n := 0; ctx.Err() == nil; n++ {
+		select {
+		case slots <- struct{}{}:
+			report.Requests++
+			wg.Add(1)
+			go func(clientID string) {
+				defer wg.Done()
+				results <- send(client, opts, clientID)
+				<-slots
+			}("bench-client-" + strconv.Itoa(n%opts.Clients))
+		default:
+			report.Skipped++
+		}
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+	elapsed := time.Since(start)
+	wg.Wait()
+	close(results)
+	<-collector
+
+	report.Duration = elapsed.Round(time.Millisecond).String()
+	report.AchievedRPS = float64(report.Requests) / elapsed.Seconds()
+	report.summarize(collected)
+	return report, nil
+}
+
+// send отправляет один запрос от клиента clientID.
+func send(client *http.Client, opts Options, clientID string) result {
+	req, err := http.NewRequest(http.MethodGet, opts.Target, nil)
+	if err != nil {
+		return result{}
+	}
+	req.Header.Set(opts.ClientHeader, clientID)
+	if opts.TraceToken != "" {
+		req.Header.Set(traceHeader, opts.TraceToken)
+	}
+	started := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return result{}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return result{
+		status:  resp.StatusCode,
+		latency: time.Since(started),
+		backend: traceBackend(resp.Header.Get(traceHeader)),
+	}
+}
+
+// traceBackend извлекает из трассировки бэкенд, ответивший на запрос: индекс из шага "backend=N"
+// ("пул/N" для сценария допуска), после повтора - индекс из шага "retry=N->M", при переливе -
+// "spillover/N" (индекс в резервном пуле).
+func traceBackend(trace string) string {
+	backend := ""
+	for _, step := range strings.Split(trace, "; ") {
+		key, value, _ := strings.Cut(step, "=")
+		switch key {
+		case "backend":
+			if value != "none" {
+				backend = value
+			}
+		case "spillover":
+			backend = "spillover/" + value
+		case "retry":
+			if _, next, ok := strings.Cut(value, "->"); ok {
+				backend, _, _ = strings.Cut(next, " ")
+			}
+		}
+	}
+	return backend
+}
+
+// summarize подсчитывает статусы, доли отказов, перцентили и распределение по бэкендам.
+func (r *Report) summarize(results []result) {
+	var latencies []time.Duration
+	backends := make(map[string]int64)
+	var rateLimited, failed, traced int64
+	for _, res := range results {
+		if res.status == 0 {
+			r.Errors++
+			failed++
+			continue
+		}
+		r.Statuses[res.status]++
+		latencies = append(latencies, res.latency)
+		switch {
+		case res.status == http.StatusTooManyRequests:
+			rateLimited++
+		case res.status >= http.StatusInternalServerError:
+			failed++
+		}
+		if res.backend != "" {
+			backends[res.backend]++
+			traced++
+		}
+	}
+	if total := int64(len(results)); total > 0 {
+		r.RateLimitedRate = float64(rateLimited) / float64(total)
+		r.ErrorRate = float64(failed) / float64(total)
+	}
+
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		r.Latency = Latency{
+			Min: milliseconds(latencies[0]),
+			P50: milliseconds(percentile(latencies, 0.50)),
+			P90: milliseconds(percentile(latencies, 0.90)),
+			P99: milliseconds(percentile(latencies, 0.99)),
+			Max: milliseconds(latencies[len(latencies)-1]),
+		}
+	}
+
+	for backend, requests := range backends {
+		r.Backends = append(r.Backends, BackendShare{Backend: backend, Requests: requests,
+			Share: float64(requests) / float64(traced)})
+	}
+	sort.Slice(r.Backends, func(i, j int) bool { return r.Backends[i].Backend < r.Backends[j].Backend })
+}
+
+// percentile возвращает перцентиль p (0..1) отсортированных значений (метод ближайшего ранга).
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
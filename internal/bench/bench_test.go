@@ -0,0 +1,98 @@
+package bench_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/bench"
+)
+
+// TestRun проверяет подсчет отказов, перцентилей и распределения по бэкендам по трассировке ответов.
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Balancer-Trace") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.Header.Get("X-Client-ID") {
+		case "bench-client-0":
+			w.Header().Set("X-Balancer-Trace", "route=default; ratelimit=denied key=bench-client-0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "bench-client-1":
+			w.Header().Set("X-Balancer-Trace", "route=default; candidates=0,1; backend=0; retry=0->1 status 503")
+		default:
+			w.Header().Set("X-Balancer-Trace", "route=default; candidates=0,1; backend=0")
+		}
+	}))
+	defer server.Close()
+
+	report, err := bench.Run(context.Background(), bench.Options{
+		Target: server.URL, RPS: 200, Clients: 4, Duration: 300 * time.Millisecond,
+		Concurrency: 16, Timeout: time.Second, TraceToken: "secret",
+	})
+	require.NoError(t, err)
+
+	require.Positive(t, report.Requests)
+	assert.Zero(t, report.Errors)
+	assert.Zero(t, report.Statuses[http.StatusBadRequest])
+	assert.Equal(t, report.Requests, report.Statuses[http.StatusOK]+report.Statuses[http.StatusTooManyRequests])
+	assert.InDelta(t, 0.25, report.RateLimitedRate, 0.1)
+	assert.Zero(t, report.ErrorRate)
+	assert.LessOrEqual(t, report.Latency.Min, report.Latency.P50)
+	assert.LessOrEqual(t, report.Latency.P99, report.Latency.Max)
+
+	// Клиент 0 отклоняется до выбора бэкенда, клиент 1 обслуживается бэкендом 1 после повтора
+	require.Len(t, report.Backends, 2)
+	assert.Equal(t, "0", report.Backends[0].Backend)
+	assert.Equal(t, "1", report.Backends[1].Backend)
+	assert.InDelta(t, 2.0/3, report.Backends[0].Share, 0.1)
+
+	var out bytes.Buffer
+	require.NoError(t, report.WriteText(&out))
+	assert.True(t, strings.Contains(out.String(), "429"))
+}
+
+// TestRun_Unreachable проверяет, что запросы без ответа учитываются как ошибки.
+func TestRun_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	target := server.URL
+	server.Close()
+
+	report, err := bench.Run(context.Background(), bench.Options{
+		Target: target, RPS: 50, Clients: 1, Duration: 100 * time.Millisecond, Concurrency: 4, Timeout: time.Second,
+	})
+	require.NoError(t, err)
+
+	require.Positive(t, report.Requests)
+	assert.Equal(t, report.Requests, report.Errors)
+	assert.Equal(t, 1.0, report.ErrorRate)
+	assert.Empty(t, report.Backends)
+}
+
+// TestOptionsValidate проверяет отклонение неверных параметров.
+func TestOptionsValidate(t *testing.T) {
+	valid := bench.Options{Target: "http://localhost:8080/", RPS: 10, Clients: 1, Duration: time.Second,
+		Concurrency: 1, Timeout: time.Second}
+	require.NoError(t, valid.Validate())
+
+	for name, mutate := range map[string]func(*bench.Options){
+		"target":      func(o *bench.Options) { o.Target = "localhost:8080" },
+		"rps":         func(o *bench.Options) { o.RPS = 0 },
+		"clients":     func(o *bench.Options) { o.Clients = 0 },
+		"duration":    func(o *bench.Options) { o.Duration = 0 },
+		"concurrency": func(o *bench.Options) { o.Concurrency = 0 },
+		"timeout":     func(o *bench.Options) { o.Timeout = 0 },
+	} {
+		opts := valid
+		mutate(&opts)
+		assert.Error(t, opts.Validate(), name)
+	}
+}
@@ -0,0 +1,43 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"load-balancer/internal/i18n"
+)
+
+// WriteText выводит отчет для оператора.
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintln(w, i18n.T(i18n.BenchReportSummary, r.Target, r.Clients, r.RPS, r.Duration))
+	fmt.Fprintln(w, i18n.T(i18n.BenchReportTotals, r.Requests, r.AchievedRPS, r.Skipped, r.Errors))
+	fmt.Fprintln(w, i18n.T(i18n.BenchReportRates, r.RateLimitedRate*100, r.ErrorRate*100))
+	fmt.Fprintln(w, i18n.T(i18n.BenchReportLatency, r.Latency.Min, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max))
+
+	statuses := make([]int, 0, len(r.Statuses))
+	for status := range r.Statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	fmt.Fprintln(w, i18n.T(i18n.BenchReportStatuses))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, status := range statuses {
+		fmt.Fprintf(tw, "  %d\t%d\n", status, r.Statuses[status])
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Backends) == 0 {
+		_, err := fmt.Fprintln(w, i18n.T(i18n.BenchReportNoTrace))
+		return err
+	}
+	fmt.Fprintln(w, i18n.T(i18n.BenchReportBackends))
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, backend := range r.Backends {
+		fmt.Fprintf(tw, "  %s\t%d\t%.1f%%\n", backend.Backend, backend.Requests, backend.Share*100)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,58 @@
+// Package buildinfo хранит сведения о сборке и времени запуска процесса.
+//
+// Version, Commit и Date подставляются при сборке через -ldflags, например:
+//
+//	go build -ldflags "-X load-balancer/internal/buildinfo.Version=v1.4.0 \
+//	  -X load-balancer/internal/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//	  -X load-balancer/internal/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ...
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Значения по умолчанию - для сборки без -ldflags (go run, тесты).
+var (
+	Version = "dev"
+	Commit  = "unknown"
+	Date    = "unknown"
+)
+
+// started - время запуска процесса (инициализации пакета).
+var started = time.Now()
+
+// Info - сведения о сборке и запущенном процессе (GET /admin/version).
+type Info struct {
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit"`
+	BuildDate string    `json:"build_date"`
+	GoVersion string    `json:"go_version"`
+	StartedAt time.Time `json:"started_at"`
+	// UptimeSeconds - сколько секунд процесс работает.
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+// Get возвращает сведения о сборке. Если коммит не передан через -ldflags, он берется
+// из данных системы контроля версий, которые Go встраивает при сборке пакета из репозитория.
+func Get() Info {
+	commit := Commit
+	if commit == "unknown" {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range info.Settings {
+				if setting.Key == "vcs.revision" && setting.Value != "" {
+					commit = setting.Value
+				}
+			}
+		}
+	}
+	return Info{
+		Version:       Version,
+		Commit:        commit,
+		BuildDate:     Date,
+		GoVersion:     runtime.Version(),
+		StartedAt:     started,
+		UptimeSeconds: time.Since(started).Seconds(),
+	}
+}
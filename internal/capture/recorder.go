@@ -0,0 +1,276 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/requestid"
+)
+
+// Filter определяет, какие запросы записываются. Пустое поле совпадает с любым значением.
+type Filter struct {
+	ClientID   string `json:"client_id,omitempty"`
+	PathPrefix string `json:"path_prefix,omitempty"`
+}
+
+// Empty сообщает, что фильтр не ограничивает запись ни клиентом, ни маршрутом.
+func (f Filter) Empty() bool {
+	return f.ClientID == "" && f.PathPrefix == ""
+}
+
+// Message - записанные заголовки и тело запроса или ответа.
+type Message struct {
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body,omitempty"`
+	// BodyEncoding равно "base64", если тело не является текстом UTF-8.
+	BodyEncoding  string `json:"body_encoding,omitempty"`
+	BodyTruncated bool   `json:"body_truncated,omitempty"`
+}
+
+// Entry - запись об одном запросе и ответе на него.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	ClientID  string    `json:"client_id"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Status    int       `json:"status"`
+	Duration  string    `json:"duration"`
+	Request   Message   `json:"request"`
+	Response  Message   `json:"response"`
+	// Trace - решения балансировщика по запросу (маршрут, Rate Limiter, выбор бэкенда, повторы).
+	Trace string `json:"trace,omitempty"`
+}
+
+// Redactor маскирует чувствительные заголовки перед записью (см. headers.Policy).
+type Redactor interface {
+	Redact(h http.Header) http.Header
+}
+
+// Recorder записывает запросы и ответы выбранного клиента или маршрута в кольцевой буфер.
+// Запись включается и выключается через служебный API; выключенный Recorder почти ничего не стоит.
+type Recorder struct {
+	maxBodyBytes int
+	active       atomic.Bool
+
+	mu      sync.Mutex
+	filter  Filter
+	entries []Entry // Кольцевой буфер фиксированной емкости.
+	next    int     // Позиция следующей записи.
+	count   int     // Число записей в буфере.
+}
+
+// New создает выключенный Recorder по секции capture конфигурации.
+func New(cfg config.CaptureConfig) *Recorder {
+	return &Recorder{
+		maxBodyBytes: cfg.MaxBodyBytes,
+		entries:      make([]Entry, cfg.BufferSize),
+	}
+}
+
+// Start включает запись запросов, подходящих под фильтр. Записи предыдущего сеанса удаляются.
+func (rec *Recorder) Start(filter Filter) {
+	rec.mu.Lock()
+	rec.filter = filter
+	rec.clearLocked()
+	rec.mu.Unlock()
+	rec.active.Store(true)
+	i18n.Logf(i18n.CaptureStarted, filter.ClientID, filter.PathPrefix, len(rec.entries), rec.maxBodyBytes)
+}
+
+// Stop выключает запись. Уже записанные запросы остаются доступны до следующего Start.
+func (rec *Recorder) Stop() {
+	if rec.active.Swap(false) {
+		i18n.Logf(i18n.CaptureStopped, len(rec.Entries()))
+	}
+}
+
+// Status возвращает признак включенной записи и действующий фильтр.
+func (rec *Recorder) Status() (bool, Filter) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.active.Load(), rec.filter
+}
+
+// Entries возвращает записи от самой старой к самой новой.
+func (rec *Recorder) Entries() []Entry {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	result := make([]Entry, 0, rec.count)
+	start := rec.next - rec.count
+	if start < 0 {
+		start += len(rec.entries)
+	}
+	for i := 0; i < rec.count; i++ {
+		result = append(result, rec.entries[(start+i)%len(rec.entries)])
+	}
+	return result
+}
+
+// Matches проверяет, нужно ли записывать запрос клиента clientID к пути path.
+func (rec *Recorder) Matches(clientID, path string) bool {
+	if !rec.active.Load() {
+		return false
+	}
+	rec.mu.Lock()
+	filter := rec.filter
+	rec.mu.Unlock()
+	return (filter.ClientID == "" || filter.ClientID == clientID) &&
+		(filter.PathPrefix == "" || strings.HasPrefix(path, filter.PathPrefix))
+}
+
+// Begin начинает запись запроса: возвращает обертки над w и r, через которые запрос нужно обработать,
+// и функцию, которую нужно вызвать после обработки с трассировкой решений по запросу, чтобы сохранить запись.
+// Заголовки маскируются redactor; тела записываются не длиннее max_body_bytes.
+func (rec *Recorder) Begin(w http.ResponseWriter, r *http.Request, clientID string, redactor Redactor) (http.ResponseWriter, *http.Request, func(trace string)) {
+	started := time.Now()
+	entry := Entry{
+		Time:      started,
+		RequestID: requestid.FromContext(r.Context()),
+		ClientID:  clientID,
+		Method:    r.Method,
+		URL:       r.URL.String(),
+		Request:   Message{Headers: redactor.Redact(r.Header)},
+	}
+
+	requestBody := &limitedBuffer{limit: rec.maxBodyBytes}
+	if r.Body != nil && r.Body != http.NoBody {
+		r = r.Clone(r.Context())
+		r.Body = &teeBody{Reader: io.TeeReader(r.Body, requestBody), Closer: r.Body}
+	}
+	recorder := &responseRecorder{ResponseWriter: w, body: &limitedBuffer{limit: rec.maxBodyBytes}}
+
+	return recorder, r, func(trace string) {
+		entry.Duration = time.Since(started).String()
+		entry.Trace = trace
+		entry.Request.Body, entry.Request.BodyEncoding, entry.Request.BodyTruncated = requestBody.contents()
+		entry.Status = recorder.statusCode()
+		entry.Response.Headers = redactor.Redact(w.Header())
+		entry.Response.Body, entry.Response.BodyEncoding, entry.Response.BodyTruncated = recorder.body.contents()
+		rec.add(entry)
+	}
+}
+
+// add сохраняет запись, вытесняя самую старую при заполненном буфере.
+func (rec *Recorder) add(entry Entry) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.entries[rec.next] = entry
+	rec.next = (rec.next + 1) % len(rec.entries)
+	if rec.count < len(rec.entries) {
+		rec.count++
+	}
+}
+
+func (rec *Recorder) clearLocked() {
+	clear(rec.entries)
+	rec.next = 0
+	rec.count = 0
+}
+
+// limitedBuffer хранит первые limit байт записанных данных, остальные только учитывает.
+// Тело запроса может дочитываться транспортом после возврата из обработчика, поэтому нужен мьютекс.
+type limitedBuffer struct {
+	mu        sync.Mutex
+	limit     int
+	data      []byte
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	room := b.limit - len(b.data)
+	if len(p) > room {
+		b.data = append(b.data, p[:max(room, 0)]...)
+		b.truncated = true
+		return len(p), nil
+	}
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+// contents возвращает записанное тело, его кодировку ("" или "base64") и признак обрезки.
+func (b *limitedBuffer) contents() (string, string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if utf8.Valid(b.data) {
+		return string(b.data), "", b.truncated
+	}
+	return base64.StdEncoding.EncodeToString(b.data), "base64", b.truncated
+}
+
+// teeBody копирует прочитанное тело запроса в буфер записи, сохраняя Close исходного тела.
+type teeBody struct {
+	io.Reader
+	io.Closer
+}
+
+// responseRecorder копирует статус и тело ответа, передавая их клиенту без изменений.
+// Flush доступен и через Unwrap (http.ResponseController); Hijack при смене протокола запоминает статус 101.
+type responseRecorder struct {
+	http.ResponseWriter
+	mu     sync.Mutex
+	status int
+	body   *limitedBuffer
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	rr.mu.Lock()
+	if rr.status == 0 && code >= http.StatusOK {
+		rr.status = code
+	}
+	rr.mu.Unlock()
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *responseRecorder) Write(p []byte) (int, error) {
+	rr.mu.Lock()
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	rr.mu.Unlock()
+	_, _ = rr.body.Write(p)
+	return rr.ResponseWriter.Write(p)
+}
+
+// Flush нужен потоковым ответам (gRPC, SSE), которые проверяют http.Flusher напрямую.
+func (rr *responseRecorder) Flush() {
+	_ = http.NewResponseController(rr.ResponseWriter).Flush()
+}
+
+// Hijack вызывается прокси после ответа бэкенда 101 (WebSocket): данные сессии не записываются.
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(rr.ResponseWriter).Hijack()
+	if err == nil {
+		rr.mu.Lock()
+		if rr.status == 0 {
+			rr.status = http.StatusSwitchingProtocols
+		}
+		rr.mu.Unlock()
+	}
+	return conn, brw, err
+}
+
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+func (rr *responseRecorder) statusCode() int {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+	return rr.status
+}
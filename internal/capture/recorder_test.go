@@ -0,0 +1,102 @@
+package capture_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/capture"
+	"load-balancer/internal/config"
+	"load-balancer/internal/headers"
+)
+
+// serve обрабатывает запрос через Begin так же, как это делает балансировщик.
+func serve(rec *capture.Recorder, req *http.Request, clientID string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	policy := headers.NewPolicy(config.HeaderPolicyConfig{Sensitive: []string{"Authorization", "Set-Cookie"}}, nil)
+	w, r, finish := rec.Begin(rr, req, clientID, policy)
+	handler(w, r)
+	finish("")
+	return rr
+}
+
+// TestRecorder_Filter проверяет включение, выключение и фильтр по клиенту и маршруту.
+func TestRecorder_Filter(t *testing.T) {
+	rec := capture.New(config.CaptureConfig{BufferSize: 10, MaxBodyBytes: 100})
+	assert.False(t, rec.Matches("user1", "/api"), "По умолчанию запись выключена")
+
+	rec.Start(capture.Filter{ClientID: "user1"})
+	assert.True(t, rec.Matches("user1", "/anything"))
+	assert.False(t, rec.Matches("user2", "/anything"))
+
+	rec.Start(capture.Filter{ClientID: "user1", PathPrefix: "/api"})
+	assert.True(t, rec.Matches("user1", "/api/orders"))
+	assert.False(t, rec.Matches("user1", "/static"))
+
+	rec.Stop()
+	assert.False(t, rec.Matches("user1", "/api/orders"))
+	active, filter := rec.Status()
+	assert.False(t, active)
+	assert.Equal(t, "/api", filter.PathPrefix)
+}
+
+// TestRecorder_Entry проверяет содержимое записи: заголовки (с маскированием), тела и статус.
+func TestRecorder_Entry(t *testing.T) {
+	rec := capture.New(config.CaptureConfig{BufferSize: 10, MaxBodyBytes: 5})
+	rec.Start(capture.Filter{ClientID: "user1"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/orders?id=1", strings.NewReader("hello world"))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Trace", "abc")
+	rr := serve(rec, req, "user1", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(body), "Запись не должна менять тело для бэкенда")
+		w.Header().Set("Set-Cookie", "session=1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte{0xff, 0xfe})
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	entries := rec.Entries()
+	require.Len(t, entries, 1)
+	entry := entries[0]
+	assert.Equal(t, "user1", entry.ClientID)
+	assert.Equal(t, http.MethodPost, entry.Method)
+	assert.Equal(t, "/api/orders?id=1", entry.URL)
+	assert.Equal(t, http.StatusCreated, entry.Status)
+	assert.Equal(t, headers.RedactedValue, entry.Request.Headers.Get("Authorization"))
+	assert.Equal(t, "abc", entry.Request.Headers.Get("X-Trace"))
+	assert.Equal(t, "hello", entry.Request.Body)
+	assert.True(t, entry.Request.BodyTruncated)
+	assert.Equal(t, headers.RedactedValue, entry.Response.Headers.Get("Set-Cookie"))
+	assert.Equal(t, "//4=", entry.Response.Body)
+	assert.Equal(t, "base64", entry.Response.BodyEncoding)
+	assert.False(t, entry.Response.BodyTruncated)
+}
+
+// TestRecorder_RingBuffer проверяет вытеснение старых записей и очистку при новом сеансе.
+func TestRecorder_RingBuffer(t *testing.T) {
+	rec := capture.New(config.CaptureConfig{BufferSize: 3, MaxBodyBytes: 0})
+	rec.Start(capture.Filter{PathPrefix: "/"})
+
+	for _, path := range []string{"/1", "/2", "/3", "/4", "/5"} {
+		serve(rec, httptest.NewRequest(http.MethodGet, path, nil), "user1", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "ok")
+		})
+	}
+
+	entries := rec.Entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, []string{"/3", "/4", "/5"}, []string{entries[0].URL, entries[1].URL, entries[2].URL})
+	assert.Empty(t, entries[2].Response.Body, "При max_body_bytes: 0 записываются только заголовки")
+	assert.True(t, entries[2].Response.BodyTruncated)
+
+	rec.Start(capture.Filter{ClientID: "user2"})
+	assert.Empty(t, rec.Entries(), "Новый сеанс начинается с пустого буфера")
+}
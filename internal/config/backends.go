@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"load-balancer/internal/i18n"
+)
+
+// BackendServer - элемент backend_servers: URL строкой ('http://backend1:80')
+// или объектом с весом ({url: 'http://backend1:80', weight: 3}).
+type BackendServer struct {
+	URL string `yaml:"url"`
+	// Weight - доля запросов относительно других бэкендов: бэкенд с весом 3 получает втрое больше
+	// запросов, чем с весом 1. 0 или не указан - 1.
+	Weight int `yaml:"weight"`
+	// PassiveHealth - параметры passive_health этого бэкенда; не указанные берутся из общего passive_health.
+	// Хранится как узел YAML, потому что накладывается на общие параметры после их разбора.
+	PassiveHealth yaml.Node `yaml:"passive_health"`
+}
+
+// UnmarshalYAML принимает как строку с URL, так и объект {url, weight}.
+func (s *BackendServer) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&s.URL)
+	}
+	type plain BackendServer
+	return node.Decode((*plain)(s))
+}
+
+// parseBackendServers заполняет BackendServers и BackendWeights из backend_servers файла.
+func (c *Config) parseBackendServers() error {
+	c.BackendServers, c.BackendWeights = nil, nil
+	for i, entry := range c.BackendServerEntries {
+		if entry.URL == "" {
+			return i18n.Errorf(i18n.ConfigEmptyBackendURL, i)
+		}
+		if entry.Weight < 0 {
+			return i18n.Errorf(i18n.ConfigBadBackendWeight, entry.URL, entry.Weight)
+		}
+		c.BackendServers = append(c.BackendServers, entry.URL)
+		if entry.Weight > 1 {
+			if c.BackendWeights == nil {
+				c.BackendWeights = make(map[string]int)
+			}
+			c.BackendWeights[entry.URL] = entry.Weight
+		}
+	}
+	return nil
+}
+
+// parseBackendPassiveHealth заполняет BackendPassiveHealth: passive_health бэкенда накладывается
+// на общий passive_health. Вызывается после разбора PassiveHealth.
+func (c *Config) parseBackendPassiveHealth() error {
+	c.BackendPassiveHealth = nil
+	for i, entry := range c.BackendServerEntries {
+		if entry.PassiveHealth.Kind == 0 {
+			continue
+		}
+		field := fmt.Sprintf("backend_servers[%d].passive_health", i)
+		override := c.PassiveHealth
+		if err := entry.PassiveHealth.Decode(&override); err != nil {
+			return i18n.Errorf(i18n.ConfigBadBackendPassiveHealth, field, err)
+		}
+		if err := override.parse(); err != nil {
+			return i18n.Errorf(i18n.ConfigBadBackendPassiveHealth, field, err)
+		}
+		if override.SlowStart > 0 && c.LoadBalancingAlgorithm == "consistent_hash" {
+			return i18n.Errorf(i18n.ConfigSlowStartWithHash, field)
+		}
+		if c.BackendPassiveHealth == nil {
+			c.BackendPassiveHealth = make(map[string]PassiveHealthConfig)
+		}
+		c.BackendPassiveHealth[entry.URL] = override
+	}
+	return nil
+}
+
+// weightOf возвращает вес бэкенда из backend_servers; false, если бэкенда нет в списке.
+func (c *Config) weightOf(url string) (int, bool) {
+	for _, backend := range c.BackendServers {
+		if backend == url {
+			if weight, ok := c.BackendWeights[url]; ok {
+				return weight, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
@@ -1,19 +1,31 @@
 package config
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
-	"log"
+	"net"
+	"net/http"
+	"net/netip"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"load-balancer/internal/i18n"
 )
 
 // ClientRateConfig содержит индивидуальные настройки скорости и емкости лимита для клиента.
 type ClientRateConfig struct {
 	Rate     float64 `yaml:"rate"`     // скорость пополнения
 	Capacity float64 `yaml:"capacity"` // емкость корзины
+	// Template - имя шаблона из rate_limiter.templates. Незаданные (нулевые) rate и capacity
+	// берутся из шаблона, заданные переопределяют его. Используется только в конфигурации.
+	Template string `yaml:"template,omitempty"`
 }
 
 // RateLimiterConfig содержит настройки для rate limiter'а.
@@ -23,128 +35,2542 @@ type RateLimiterConfig struct {
 	DefaultCapacity  float64 `yaml:"default_capacity"`  // Емкость корзины по умолчанию.
 	DatabasePath     string  `yaml:"database_path"`     // Путь к файлу SQLite.
 	IdentifierHeader string  `yaml:"identifier_header"` // Имя заголовка для ID клиента (опционально).
+	// Algorithm - алгоритм ограничения: "token_bucket" (по умолчанию) или "sliding_window" (счетчик
+	// скользящего окна длиной capacity/rate: не больше capacity запросов за любое такое окно).
+	Algorithm string `yaml:"algorithm"`
+	// Лимиты по умолчанию в зависимости от способа идентификации клиента:
+	// по IP-адресу (анонимные клиенты) или по заголовку identifier_header (API-клиенты).
+	// Нулевое значение означает использование default_rate/default_capacity.
+	DefaultRateIP         float64 `yaml:"default_rate_ip"`
+	DefaultCapacityIP     float64 `yaml:"default_capacity_ip"`
+	DefaultRateHeader     float64 `yaml:"default_rate_header"`
+	DefaultCapacityHeader float64 `yaml:"default_capacity_header"`
+	// StoreFailurePolicy - поведение при ошибке или таймауте хранилища лимитов:
+	// "fail_open" (пропускать запросы с текущими/дефолтными лимитами) или "fail_closed" (отвечать 503).
+	StoreFailurePolicy string `yaml:"store_failure_policy"`
+	StoreTimeoutStr    string `yaml:"store_timeout"` // Таймаут обращения к хранилищу (строка, например "200ms"), пусто - без таймаута.
+	// SoftLimitRatio - доля израсходованной емкости корзины (например, 0.8), после которой запросы еще проходят,
+	// но получают заголовок X-RateLimit-Warning. 0 - мягкий порог выключен.
+	SoftLimitRatio float64 `yaml:"soft_limit_ratio"`
+	// DenialCacheTTLStr - наибольшее время, на которое запоминается отказ клиенту (например, "100ms"):
+	// повторные запросы в этот период отклоняются без блокировки корзины. Пусто - кэш выключен.
+	DenialCacheTTLStr string `yaml:"denial_cache_ttl"`
+	// LimitDecreaseWindowStr - за какое время снижение лимитов клиента (через API или в хранилище) вступает
+	// в силу полностью (например, "5m"): rate и capacity корзины линейно переходят от прежних значений
+	// к новым, и интегрированные клиенты не получают разом поток 429. Пусто - новые лимиты действуют сразу.
+	// Повышение лимитов всегда действует сразу.
+	LimitDecreaseWindowStr string `yaml:"limit_decrease_window"`
+	// IdleBucketTTLStr - через сколько без запросов корзина клиента удаляется из памяти (например, "10m").
+	// Удаляются только полные корзины: новая корзина клиента будет такой же. Пусто - корзины не удаляются.
+	IdleBucketTTLStr string `yaml:"idle_bucket_ttl"`
+	// MaxBuckets - наибольшее число корзин в памяти: сверх него удаляются дольше всех не использовавшиеся,
+	// даже неполные (их клиенты получают новую полную корзину). 0 - без ограничения.
+	MaxBuckets int `yaml:"max_buckets"`
+	// MaxInFlightPerClient - сколько запросов одного клиента могут обрабатываться одновременно (включая
+	// ожидающие в очереди concurrency); лишние получают 429. Защищает бэкенды от медленных клиентов,
+	// удерживающих соединения. Считается по ID клиента, а не по key_template. 0 - без ограничения.
+	MaxInFlightPerClient int `yaml:"max_in_flight_per_client"`
+	// Global - общий лимит всего сервиса, проверяемый до корзины клиента: сверх него запросы получают 503
+	// независимо от числа клиентов. Действует на каждом экземпляре отдельно.
+	Global GlobalLimitConfig `yaml:"global"`
+	// IPv6PrefixLength - длина префикса (например, 64), по которому объединяются клиенты с IPv6-адресами:
+	// все адреса одной подсети делят одну корзину. 0 - каждый адрес считается отдельным клиентом.
+	IPv6PrefixLength int `yaml:"ipv6_prefix_length"`
+	// TrustedProxies - адреса и подсети CIDR прокси перед балансировщиком (например, "10.0.0.0/8").
+	// X-Forwarded-For учитывается, только если запрос пришел от такого прокси, и читается справа налево
+	// до первого адреса не из списка: адреса, дописанные самим клиентом, не меняют его ID.
+	// Пусто - X-Forwarded-For не учитывается, клиент идентифицируется по адресу соединения.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// KeyTemplate - шаблон ключа корзины из атрибутов запроса, например "{client_id}:{path_prefix}"
+	// или "{header.X-Tenant}:{method}" (см. ParseKeyTemplate). Пусто - отдельная корзина на клиента.
+	KeyTemplate string `yaml:"key_template"`
+	// TLSFingerprintIdentity - идентифицировать клиентов HTTPS-листенера без identifier_header по отпечатку
+	// TLS ClientHello (JA3), а не по IP: автоматизированные клиенты, меняющие адреса и заголовки,
+	// попадают в одну корзину. Отпечаток общий у всех клиентов одной программы (например, версии браузера).
+	TLSFingerprintIdentity bool `yaml:"tls_fingerprint_identity"`
+	// APIKeys - идентификация клиентов по API-ключам из хранилища вместо identifier_header, значению которого
+	// приходится доверять.
+	APIKeys APIKeysConfig `yaml:"api_keys"`
+	// Clients - индивидуальные лимиты клиентов, синхронизируемые в хранилище при старте (create-or-update).
+	Clients map[string]ClientRateConfig `yaml:"clients"`
+	// Templates - именованные наборы лимитов, на которые ссылаются клиенты из Clients (поле template).
+	Templates map[string]ClientRateConfig `yaml:"templates"`
+	// Gossip - обмен расходом токенов с другими экземплярами балансировщика без общего хранилища.
+	Gossip GossipConfig `yaml:"gossip"`
+
+	StoreTimeout        time.Duration `yaml:"-"`
+	DenialCacheTTL      time.Duration `yaml:"-"`
+	LimitDecreaseWindow time.Duration `yaml:"-"`
+	IdleBucketTTL       time.Duration `yaml:"-"`
+	KeyParts            []KeyPart     `yaml:"-"` // Разобранный KeyTemplate.
+	// TrustedProxyPrefixes - разобранный TrustedProxies; отдельный адрес - подсеть из одного адреса.
+	TrustedProxyPrefixes []netip.Prefix `yaml:"-"`
+}
+
+// GlobalLimitConfig - общая корзина токенов (rate_limiter.global).
+type GlobalLimitConfig struct {
+	Rate     float64 `yaml:"rate"`     // Запросов в секунду на весь сервис, 0 - общий лимит выключен.
+	Capacity float64 `yaml:"capacity"` // Допустимый всплеск, 0 - равен rate (секунда трафика).
+}
+
+// Enabled сообщает, что общий лимит включен.
+func (gc *GlobalLimitConfig) Enabled() bool {
+	return gc.Rate > 0
+}
+
+// validate проверяет общий лимит и подставляет емкость по умолчанию.
+func (gc *GlobalLimitConfig) validate() error {
+	if gc.Rate < 0 || gc.Capacity < 0 {
+		return i18n.Errorf(i18n.ConfigBadGlobalLimit, gc.Rate, gc.Capacity)
+	}
+	if gc.Enabled() && gc.Capacity == 0 {
+		gc.Capacity = gc.Rate
+	}
+	return nil
+}
+
+// APIKeysConfig - идентификация клиентов по API-ключам (rate_limiter.api_keys). Ключ передается в заголовке
+// Authorization: Bearer <ключ> или в header и ищется в таблице api_keys хранилища: запросы учитываются под ID
+// клиента, которому выдан ключ. Запросы без ключа или с неизвестным ключом идентифицируются по IP-адресу.
+type APIKeysConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Header  string `yaml:"header"` // Заголовок с ключом, пусто - DefaultAPIKeyHeader.
+	// CacheTTLStr - сколько найденный ключ запоминается без обращения к хранилищу (например, "1m"):
+	// отозванный на другом экземпляре ключ действует на этом не дольше cache_ttl. Пусто - DefaultAPIKeyCacheTTL.
+	CacheTTLStr string `yaml:"cache_ttl"`
+
+	CacheTTL time.Duration `yaml:"-"`
+}
+
+// Значения rate_limiter.api_keys по умолчанию.
+const (
+	DefaultAPIKeyHeader   = "X-API-Key"
+	DefaultAPIKeyCacheTTL = time.Minute
+)
+
+// validate проверяет настройки API-ключей и подставляет значения по умолчанию. identifierHeader -
+// rate_limiter.identifier_header: вместе с ключами он не задается, иначе неясно, какому ID доверять.
+func (kc *APIKeysConfig) validate(identifierHeader string) error {
+	if identifierHeader != "" {
+		return i18n.Errorf(i18n.ConfigAPIKeysWithIdentifierHeader)
+	}
+	kc.Header = http.CanonicalHeaderKey(kc.Header)
+	if kc.Header == "" {
+		kc.Header = DefaultAPIKeyHeader
+	}
+	kc.CacheTTL = DefaultAPIKeyCacheTTL
+	if kc.CacheTTLStr != "" {
+		ttl, err := time.ParseDuration(kc.CacheTTLStr)
+		if err != nil || ttl < 0 {
+			return i18n.Errorf(i18n.ConfigBadAPIKeyCacheTTL, kc.CacheTTLStr)
+		}
+		kc.CacheTTL = ttl
+	}
+	return nil
+}
+
+// GossipConfig описывает обмен расходом токенов между экземплярами балансировщика: каждый экземпляр
+// раз в interval рассылает пирам, сколько токенов израсходовали клиенты, и списывает такой же расход
+// из своих корзин. Общий лимит соблюдается приблизительно: в пределах interval каждый экземпляр
+// пропускает запросы по своей корзине. Сообщения принимаются только с адресов peers; с secret они еще и
+// подписываются HMAC-SHA256. Порт не должен быть доступен извне: подделанное сообщение опустошает корзины клиентов.
+type GossipConfig struct {
+	Listen string `yaml:"listen"` // UDP-адрес приема сообщений (например, ":7946"), пусто - обмен выключен.
+	// Peers - UDP-адреса других экземпляров в формате host:port. Сообщения с других адресов отбрасываются.
+	Peers       []string `yaml:"peers"`
+	IntervalStr string   `yaml:"interval"` // Период рассылки (строка, например "1s"), пусто - DefaultGossipInterval.
+	// Secret - общий ключ подписи сообщений, одинаковый на всех экземплярах; пусто - сообщения не подписываются.
+	Secret string `yaml:"secret"`
+
+	Interval time.Duration `yaml:"-"`
+}
+
+// DefaultGossipInterval - период рассылки расхода токенов, если rate_limiter.gossip.interval не задан.
+const DefaultGossipInterval = time.Second
+
+// Enabled сообщает, что обмен расходом токенов включен.
+func (gc *GossipConfig) Enabled() bool {
+	return gc.Listen != ""
 }
 
+// Политики поведения Rate Limiter при недоступности хранилища.
+const (
+	StoreFailOpen   = "fail_open"
+	StoreFailClosed = "fail_closed"
+)
+
+// Алгоритмы Rate Limiter (rate_limiter.algorithm).
+const (
+	RateLimitTokenBucket   = "token_bucket"
+	RateLimitSlidingWindow = "sliding_window"
+)
+
 // HealthCheckConfig содержит настройки для проверок состояния бэкендов.
 type HealthCheckConfig struct {
 	Enabled     bool   `yaml:"enabled"`
 	IntervalStr string `yaml:"interval"` // Интервал проверки (строка, например "10s")
 	TimeoutStr  string `yaml:"timeout"`  // Таймаут проверки (строка, например "2s")
 	Path        string `yaml:"path"`     // Путь для проверки
+	// Type - способ проверки: "http" (по умолчанию) - GET path с ответом 2xx, "tcp" - только установка соединения,
+	// "grpc" - вызов grpc.health.v1.Health/Check.
+	Type string `yaml:"type"`
+	// GRPCService - имя сервиса в запросе проверки gRPC (пусто - состояние сервера в целом).
+	GRPCService string `yaml:"grpc_service"`
+	// ExpectedStatus - коды ответа, при которых бэкенд исправен (пусто - любой 2xx).
+	ExpectedStatus []int `yaml:"expected_status"`
+	// ExpectedBody - подстрока, которая должна быть в теле ответа (проверяются первые 64 КБ).
+	ExpectedBody string `yaml:"expected_body"`
+	// ExpectedJSON - поля JSON-ответа и их значения, например {status: ok}. Вложенные поля - через точку
+	// ("checks.db"), числа и логические значения сравниваются в записи JSON ("1", "true").
+	ExpectedJSON map[string]string `yaml:"expected_json"`
+	// Workers - размер пула воркеров проверок (0 - по числу бэкендов, но не более 8).
+	Workers int `yaml:"workers"`
+	// LogRepeatEvery - одинаковая ошибка проверки бэкенда пишется в лог один раз, а затем раз в N проверок
+	// с числом повторов (0 - по умолчанию 10, 1 - каждая ошибка).
+	LogRepeatEvery int    `yaml:"log_repeat_every"`
+	MaxBackoffStr  string `yaml:"max_backoff"` // Максимальная задержка проверок для постоянно падающего бэкенда.
+	// JitterStr - наибольшая случайная добавка к задержке до следующей проверки бэкенда (например, "2s"),
+	// меньше interval: проверки бэкендов расходятся во времени, а не приходят одновременно. Пусто - без добавки.
+	JitterStr string `yaml:"jitter"`
+	// Stagger - первые проверки бэкендов распределяются равномерно по interval, а не выполняются разом при старте.
+	Stagger bool `yaml:"stagger"`
 
-	Interval time.Duration `yaml:"-"`
-	Timeout  time.Duration `yaml:"-"`
+	Interval   time.Duration `yaml:"-"`
+	Timeout    time.Duration `yaml:"-"`
+	MaxBackoff time.Duration `yaml:"-"`
+	Jitter     time.Duration `yaml:"-"`
 }
 
-// Config определяет структуру конфигурационного файла.
-type Config struct {
-	// Port - порт, на котором будет работать балансировщик.
-	Port string `yaml:"port"`
-	// BackendServers - список URL-адресов бэкенд-серверов.
-	BackendServers []string `yaml:"backend_servers"`
-	// LoadBalancingAlgorithm - алгоритм балансировки
-	LoadBalancingAlgorithm string `yaml:"load_balancing_algorithm"`
-	// RateLimiter - настройки для модуля Rate Limiting.
-	RateLimiter RateLimiterConfig `yaml:"rate_limiter"`
-	HealthCheck HealthCheckConfig `yaml:"health_check"`
+// Spread сообщает, распределяются ли проверки бэкендов внутри интервала (jitter или stagger).
+func (hc *HealthCheckConfig) Spread() bool {
+	return hc.Jitter > 0 || hc.Stagger
 }
 
-// LoadConfig загружает конфигурацию из указанного файла.
-func LoadConfig(configPath string) (*Config, error) {
-	config := &Config{
-		// Устанавливаем значения по умолчанию
-		LoadBalancingAlgorithm: "round_robin",
-		RateLimiter: RateLimiterConfig{
-			Enabled:          false,
-			DefaultRate:      1,
-			DefaultCapacity:  1,
-			DatabasePath:     "./rate_limits.db",
-			IdentifierHeader: "",
-		},
-		HealthCheck: HealthCheckConfig{
-			Enabled: false,
-		},
-	}
+// Способы активной проверки бэкенда (health_check.type).
+const (
+	// HealthCheckHTTP - GET health_check.path; бэкенд исправен при ответе 2xx (или из expected_status)
+	// с телом, подходящим под expected_body и expected_json.
+	HealthCheckHTTP = "http"
+	HealthCheckTCP  = "tcp" // Бэкенд исправен, если к его адресу удалось установить TCP-соединение.
+	// HealthCheckGRPC - стандартный протокол проверки gRPC (grpc.health.v1.Health/Check) по HTTP/2;
+	// бэкенд исправен при статусе SERVING сервиса grpc_service.
+	HealthCheckGRPC = "grpc"
+)
 
-	file, err := os.ReadFile(configPath)
+// Parse применяет значения по умолчанию, разбирает строковые длительности и проверяет настройки.
+// Используется при загрузке конфигурации и при изменении параметров во время работы.
+func (hc *HealthCheckConfig) Parse() error {
+	if hc.IntervalStr == "" {
+		hc.IntervalStr = "10s" // Значение по умолчанию
+		i18n.Logf(i18n.ConfigDefaultHealthInterval, hc.IntervalStr)
+	}
+	interval, err := time.ParseDuration(hc.IntervalStr)
 	if err != nil {
-		return nil, err
+		return i18n.Errorf(i18n.ConfigBadHealthInterval, hc.IntervalStr, err)
+	}
+	if interval <= 0 {
+		return i18n.Errorf(i18n.ConfigNonPositiveHealthInterval, hc.IntervalStr)
 	}
+	hc.Interval = interval
 
-	err = yaml.Unmarshal(file, config)
+	if hc.TimeoutStr == "" {
+		hc.TimeoutStr = "2s" // Значение по умолчанию
+		i18n.Logf(i18n.ConfigDefaultHealthTimeout, hc.TimeoutStr)
+	}
+	timeout, err := time.ParseDuration(hc.TimeoutStr)
 	if err != nil {
-		return nil, err
+		return i18n.Errorf(i18n.ConfigBadHealthTimeout, hc.TimeoutStr, err)
+	}
+	if timeout <= 0 {
+		return i18n.Errorf(i18n.ConfigNonPositiveHealthTimeout, hc.TimeoutStr)
 	}
+	if timeout >= interval {
+		i18n.Logf(i18n.ConfigHealthTimeoutTooLong, hc.TimeoutStr, hc.IntervalStr)
+	}
+	hc.Timeout = timeout
 
-	// Валидация алгоритма балансировки
-	config.LoadBalancingAlgorithm = strings.ToLower(config.LoadBalancingAlgorithm)
-	if config.LoadBalancingAlgorithm != "round_robin" && config.LoadBalancingAlgorithm != "random" {
-		return nil, fmt.Errorf("неподдерживаемый load_balancing_algorithm: '%s'. Допустимые значения: 'round_robin', 'random'", config.LoadBalancingAlgorithm)
+	switch hc.Type {
+	case "":
+		hc.Type = HealthCheckHTTP
+	case HealthCheckHTTP, HealthCheckTCP, HealthCheckGRPC:
+	default:
+		return i18n.Errorf(i18n.ConfigUnknownHealthCheckType, hc.Type)
 	}
-	log.Printf("[Config] Используемый алгоритм балансировки: %s", config.LoadBalancingAlgorithm)
 
-	// Дополнительная валидация
-	if config.RateLimiter.Enabled {
-		if config.RateLimiter.DefaultRate <= 0 {
-			config.RateLimiter.DefaultRate = 1
-			println("[Warning] rate_limiter.default_rate должен быть > 0, установлено значение по умолчанию 1")
-		}
-		if config.RateLimiter.DefaultCapacity <= 0 {
-			config.RateLimiter.DefaultCapacity = 1
-			println("[Warning] rate_limiter.default_capacity должен быть > 0, установлено значение по умолчанию 1")
+	for _, status := range hc.ExpectedStatus {
+		if status < 100 || status > 599 {
+			return i18n.Errorf(i18n.ConfigBadExpectedStatus, status)
 		}
-		if config.RateLimiter.DatabasePath == "" {
-			config.RateLimiter.DatabasePath = "./rate_limits.db" // Устанавливаем дефолт, если не указан
-			println("[Warning] rate_limiter.database_path не указан, используется значение по умолчанию ./rate_limits.db")
+	}
+	for field := range hc.ExpectedJSON {
+		if field == "" || strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") || strings.Contains(field, "..") {
+			return i18n.Errorf(i18n.ConfigBadExpectedJSONField, field)
 		}
+	}
 
+	if hc.Path == "" {
+		hc.Path = "/" // Значение по умолчанию
+		i18n.Logf(i18n.ConfigDefaultHealthPath, hc.Path)
+	}
+	// Добавляем '/' в начало пути, если его нет
+	if len(hc.Path) == 0 || hc.Path[0] != '/' {
+		hc.Path = "/" + hc.Path
 	}
 
-	// Парсим интервал и таймаут HealthCheck, если включено
-	if config.HealthCheck.Enabled {
-		if config.HealthCheck.IntervalStr == "" {
-			config.HealthCheck.IntervalStr = "10s" // Значение по умолчанию
-			fmt.Printf("[Config] Интервал HealthCheck не указан, используется значение по умолчанию: %s\n", config.HealthCheck.IntervalStr)
+	if hc.Workers < 0 {
+		return i18n.Errorf(i18n.ConfigNegativeWorkers, hc.Workers)
+	}
+	if hc.LogRepeatEvery < 0 {
+		return i18n.Errorf(i18n.ConfigNegativeLogRepeatEvery, hc.LogRepeatEvery)
+	}
+	if hc.LogRepeatEvery == 0 {
+		hc.LogRepeatEvery = 10 // Значение по умолчанию
+	}
+
+	if hc.MaxBackoffStr == "" {
+		hc.MaxBackoff = 8 * interval // Значение по умолчанию
+	} else {
+		maxBackoff, err := time.ParseDuration(hc.MaxBackoffStr)
+		if err != nil {
+			return i18n.Errorf(i18n.ConfigBadMaxBackoff, hc.MaxBackoffStr, err)
+		}
+		if maxBackoff < interval {
+			i18n.Logf(i18n.ConfigMaxBackoffTooSmall, hc.MaxBackoffStr)
+			maxBackoff = interval
 		}
-		interval, err := time.ParseDuration(config.HealthCheck.IntervalStr)
+		hc.MaxBackoff = maxBackoff
+	}
+
+	if hc.JitterStr != "" {
+		jitter, err := time.ParseDuration(hc.JitterStr)
+		if err != nil {
+			return i18n.Errorf(i18n.ConfigBadHealthJitter, hc.JitterStr, err)
+		}
+		if jitter < 0 || jitter >= interval {
+			return i18n.Errorf(i18n.ConfigHealthJitterRange, hc.JitterStr, hc.IntervalStr)
+		}
+		hc.Jitter = jitter
+	}
+	return nil
+}
+
+// HeaderPolicyConfig содержит правила обработки входящих заголовков.
+type HeaderPolicyConfig struct {
+	Sensitive []string `yaml:"sensitive"` // Заголовки, значения которых маскируются в логах.
+	Allow     []string `yaml:"allow"`     // Если не пусто - бэкенду пересылаются только эти заголовки клиента.
+	Deny      []string `yaml:"deny"`      // Заголовки, которые никогда не пересылаются бэкенду.
+	// AcceptEncoding - что отправлять бэкенду в Accept-Encoding: "pass" (по умолчанию), "strip" или "identity".
+	// В маршруте пустое значение означает глобальную настройку.
+	AcceptEncoding string `yaml:"accept_encoding"`
+}
+
+// Режимы обработки заголовка Accept-Encoding, отправляемого бэкенду.
+const (
+	// AcceptEncodingPass - заголовок клиента пересылается без изменений.
+	AcceptEncodingPass = "pass"
+	// AcceptEncodingStrip - заголовок клиента удаляется; транспорт сам запрашивает gzip и распаковывает ответ,
+	// поэтому br и zstd бэкенд не пришлет, а клиент получит несжатое тело.
+	AcceptEncodingStrip = "strip"
+	// AcceptEncodingIdentity - бэкенду отправляется "Accept-Encoding: identity" (несжатый ответ).
+	AcceptEncodingIdentity = "identity"
+)
+
+// RouteConfig описывает правила для запросов, путь которых начинается с PathPrefix.
+type RouteConfig struct {
+	PathPrefix string `yaml:"path_prefix"`
+	// Untrusted - бэкенды маршрута не доверенные: чувствительные заголовки им не пересылаются.
+	Untrusted bool               `yaml:"untrusted"`
+	Headers   HeaderPolicyConfig `yaml:"headers"` // Переопределение глобальной политики заголовков.
+	// BackendSelector - маршрут обслуживают только бэкенды, у которых есть все указанные метки (см. backend_labels).
+	BackendSelector map[string]string `yaml:"backend_selector"`
+	// FlushIntervalStr - как часто сбрасывать клиенту буферизованный ответ бэкенда (например, "100ms");
+	// "-1" - сразу после каждой записи (SSE, потоковые API). Пусто - буферизация по умолчанию.
+	FlushIntervalStr string `yaml:"flush_interval"`
+
+	// ResponseValidation - проверка ответов бэкендов на маршруте (nil - без проверки).
+	ResponseValidation *ResponseValidationConfig `yaml:"response_validation"`
+	// BackendTimeoutStr - переопределение timeouts.backend_response для маршрута (например, "5m" для отчетов);
+	// "0" - без ограничения. Пусто - глобальное значение.
+	BackendTimeoutStr string `yaml:"backend_timeout"`
+
+	FlushInterval  time.Duration `yaml:"-"`
+	BackendTimeout time.Duration `yaml:"-"`
+}
+
+// ResponseValidationConfig описывает требования к успешным (2xx) ответам бэкенда на маршруте.
+// Ответ, не прошедший проверку, считается ошибкой бэкенда: клиент получает 502, а бэкенд
+// исключается из балансировки так же, как при ошибке соединения (см. PassiveHealthConfig).
+type ResponseValidationConfig struct {
+	// ContentTypes - допустимые типы содержимого без параметров ("application/json", "text/*").
+	ContentTypes []string `yaml:"content_types"`
+	// NonEmptyBody - ответ должен содержать тело (кроме ответов на HEAD и статуса 204).
+	NonEmptyBody bool `yaml:"non_empty_body"`
+}
+
+// HasBackendTimeout сообщает, что маршрут переопределяет время ожидания ответа бэкенда (backend_timeout).
+func (rc *RouteConfig) HasBackendTimeout() bool {
+	return rc.BackendTimeoutStr != ""
+}
+
+// HasFlushInterval сообщает, что маршрут переопределяет буферизацию ответа (flush_interval).
+func (rc *RouteConfig) HasFlushInterval() bool {
+	return rc.FlushIntervalStr != ""
+}
+
+// TimeoutsConfig задает таймауты HTTP-листенеров (основного и TLS) и ожидания ответа бэкендов.
+// Пустое значение или "0" - без ограничения.
+type TimeoutsConfig struct {
+	// ReadStr - чтение запроса целиком, включая тело (http.Server.ReadTimeout).
+	ReadStr string `yaml:"read"`
+	// ReadHeaderStr - чтение заголовков запроса (http.Server.ReadHeaderTimeout), по умолчанию "10s".
+	ReadHeaderStr string `yaml:"read_header"`
+	// WriteStr - от конца чтения заголовков запроса до конца записи ответа (http.Server.WriteTimeout).
+	// Ограничивает и потоковые ответы (SSE, gRPC), поэтому по умолчанию не задан.
+	WriteStr string `yaml:"write"`
+	// IdleStr - простой соединения keep-alive между запросами (http.Server.IdleTimeout), по умолчанию "120s".
+	IdleStr string `yaml:"idle"`
+	// BackendResponseStr - сколько ждать начала ответа бэкенда (заголовков) после отправки запроса,
+	// по умолчанию "60s". Бэкенд, не ответивший вовремя, считается отказавшим, клиент получает 504.
+	// Маршрут может переопределить значение (routes[].backend_timeout).
+	BackendResponseStr string `yaml:"backend_response"`
+
+	Read            time.Duration `yaml:"-"`
+	ReadHeader      time.Duration `yaml:"-"`
+	Write           time.Duration `yaml:"-"`
+	Idle            time.Duration `yaml:"-"`
+	BackendResponse time.Duration `yaml:"-"`
+}
+
+// validate разбирает таймауты.
+func (tc *TimeoutsConfig) validate() error {
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"timeouts.read", tc.ReadStr, &tc.Read},
+		{"timeouts.read_header", tc.ReadHeaderStr, &tc.ReadHeader},
+		{"timeouts.write", tc.WriteStr, &tc.Write},
+		{"timeouts.idle", tc.IdleStr, &tc.Idle},
+		{"timeouts.backend_response", tc.BackendResponseStr, &tc.BackendResponse},
+	} {
+		parsed, err := parseOptionalTimeout(d.name, d.value)
 		if err != nil {
-			return nil, fmt.Errorf("неверный формат интервала HealthCheck (%s): %w", config.HealthCheck.IntervalStr, err)
+			return err
+		}
+		*d.dest = parsed
+	}
+	return nil
+}
+
+// parseOptionalTimeout разбирает таймаут, который можно отключить: пусто или "0" - без ограничения.
+func parseOptionalTimeout(name, value string) (time.Duration, error) {
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, i18n.Errorf(i18n.ConfigBadDuration, name, value, err)
+	}
+	if parsed < 0 {
+		return 0, i18n.Errorf(i18n.ConfigNonPositiveDuration, name, value)
+	}
+	return parsed, nil
+}
+
+// AlertsConfig содержит пороги встроенного алертинга.
+type AlertsConfig struct {
+	Enabled          bool   `yaml:"enabled"`
+	CheckIntervalStr string `yaml:"check_interval"` // Как часто проверять пороги (например, "10s").
+	// RejectionRateThreshold - доля запросов, отклоненных Rate Limiter (429), при превышении которой срабатывает алерт.
+	RejectionRateThreshold float64 `yaml:"rejection_rate_threshold"`
+	RejectionWindowStr     string  `yaml:"rejection_window"` // Окно расчета доли отклоненных запросов (например, "1m").
+	MinRequests            int     `yaml:"min_requests"`     // Минимум запросов в окне для расчета доли.
+	// AllBackendsDownForStr - сколько все бэкенды должны быть недоступны, чтобы сработал алерт (например, "30s").
+	AllBackendsDownForStr string `yaml:"all_backends_down_for"`
+	WebhookURL            string `yaml:"webhook_url"` // URL для POST-уведомлений (опционально, иначе только лог).
+
+	CheckInterval      time.Duration `yaml:"-"`
+	RejectionWindow    time.Duration `yaml:"-"`
+	AllBackendsDownFor time.Duration `yaml:"-"`
+}
+
+// TLSSiteConfig описывает группу доменов, обслуживаемых HTTPS-листенером.
+type TLSSiteConfig struct {
+	// ServerNames - имена из SNI (или заголовка Host); допускается шаблон вида "*.example.com".
+	ServerNames []string `yaml:"server_names"`
+	CertFile    string   `yaml:"cert_file"` // Сертификат домена (PEM).
+	KeyFile     string   `yaml:"key_file"`  // Приватный ключ сертификата (PEM).
+	// BackendServers - собственный пул бэкендов; если пусто, используется общий backend_servers.
+	BackendServers []string `yaml:"backend_servers"`
+}
+
+// TLSConfig описывает HTTPS-листенер с выбором сертификата и пула бэкендов по SNI.
+type TLSConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Port    string `yaml:"port"`
+	// BindAddress - IP-адрес интерфейса (например, "127.0.0.1" или "::1"), пусто - все интерфейсы IPv4 и IPv6.
+	BindAddress string `yaml:"bind_address"`
+	// CertFile и KeyFile - сертификат листенера (PEM) для клиентов без SNI и имен, не указанных в sites;
+	// их запросы обслуживает основной пул. Достаточно для HTTPS без sites.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// Sites - домены со своими сертификатами. Без cert_file первый сайт отдает сертификат клиентам без SNI.
+	Sites []TLSSiteConfig `yaml:"sites"`
+	// ClientAuth - проверка клиентских сертификатов (mTLS).
+	ClientAuth TLSClientAuthConfig `yaml:"client_auth"`
+	// MinVersionStr - минимальная версия TLS: "1.2" (по умолчанию) или "1.3".
+	MinVersionStr string `yaml:"min_version"`
+	// CipherSuites - разрешенные наборы шифров TLS 1.2 по именам Go (например, "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256").
+	// Пусто - безопасные наборы по умолчанию. Наборы TLS 1.3 не настраиваются.
+	CipherSuites []string `yaml:"cipher_suites"`
+
+	MinVersion     uint16   `yaml:"-"`
+	CipherSuiteIDs []uint16 `yaml:"-"`
+}
+
+// Режимы проверки клиентских сертификатов (tls.client_auth.mode).
+const (
+	TLSClientAuthNone     = "none"     // Сертификат не запрашивается (по умолчанию).
+	TLSClientAuthOptional = "optional" // Сертификат запрашивается и проверяется, если клиент его прислал.
+	TLSClientAuthRequire  = "require"  // Без действительного сертификата соединение отклоняется.
+)
+
+// TLSClientAuthConfig описывает взаимную аутентификацию TLS (mTLS) на HTTPS-листенере.
+type TLSClientAuthConfig struct {
+	Mode   string `yaml:"mode"`    // none (по умолчанию), optional или require.
+	CAFile string `yaml:"ca_file"` // Сертификаты УЦ (PEM), которыми подписаны клиентские сертификаты.
+	// SubjectHeader - заголовок, в котором бэкенды получают CN проверенного клиентского сертификата
+	// (например, "X-Client-Cert-CN"). Значение, присланное клиентом, всегда удаляется. Пусто - не передавать.
+	SubjectHeader string `yaml:"subject_header"`
+
+	Type tls.ClientAuthType `yaml:"-"`
+}
+
+// GRPCConfig описывает режим проксирования gRPC.
+type GRPCConfig struct {
+	// Enabled - проксировать вызовы application/grpc по HTTP/2 и возвращать ошибки в grpc-status.
+	Enabled bool `yaml:"enabled"`
+}
+
+// UDPConfig описывает балансировку UDP-датаграмм (DNS, syslog, игровой трафик).
+type UDPConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Listen   string   `yaml:"listen"`   // Адрес приема датаграмм (например, ":5353").
+	Backends []string `yaml:"backends"` // Адреса бэкендов в формате host:port.
+	// SessionTimeoutStr - время простоя, после которого сессия клиента закрывается (например, "30s").
+	SessionTimeoutStr string `yaml:"session_timeout"`
+	// ResponseTimeoutStr - сколько ждать ответа бэкенда, прежде чем засчитать сбой.
+	// Пусто или "0s" - не ждать ответов (syslog и другие односторонние протоколы).
+	ResponseTimeoutStr string `yaml:"response_timeout"`
+	MaxFails           int    `yaml:"max_fails"` // Сбоев подряд, после которых бэкенд считается недоступным.
+	// FailTimeoutStr - на сколько бэкенд исключается из балансировки после max_fails сбоев.
+	FailTimeoutStr string `yaml:"fail_timeout"`
+
+	SessionTimeout  time.Duration `yaml:"-"`
+	ResponseTimeout time.Duration `yaml:"-"`
+	FailTimeout     time.Duration `yaml:"-"`
+}
+
+// ForwardProxyConfig описывает режим прямого прокси (HTTP CONNECT) для исходящего трафика:
+// запросы внутренних клиентов распределяются по пулу egress-шлюзов.
+// Алгоритм, health checks и Rate Limiter берутся из общих секций конфигурации.
+type ForwardProxyConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Port    string `yaml:"port"`
+	// BindAddress - IP-адрес интерфейса, пусто - все интерфейсы (см. Config.BindAddress).
+	BindAddress string   `yaml:"bind_address"`
+	Gateways    []string `yaml:"gateways"` // URL egress-шлюзов (HTTP-прокси), например "http://egress1:3128".
+	// DialTimeoutStr - таймаут подключения к шлюзу и установки туннеля (например, "10s").
+	DialTimeoutStr string `yaml:"dial_timeout"`
+
+	DialTimeout time.Duration `yaml:"-"`
+}
+
+// ConcurrencyConfig ограничивает число одновременно проксируемых запросов (глобальный concurrency guard).
+// Запросы сверх лимита ждут в очереди; первым слот получает запрос с наибольшим приоритетом.
+// Приоритет действует только в этой очереди: при backend_max_connections запросы не ждут.
+type ConcurrencyConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	MaxInFlight int  `yaml:"max_in_flight"` // Максимум одновременно проксируемых запросов.
+	MaxQueue    int  `yaml:"max_queue"`     // Максимальная длина очереди ожидания (0 - без очереди).
+	// QueueTimeoutStr - сколько запрос может ждать слота, прежде чем получить 503 (например, "5s").
+	QueueTimeoutStr string `yaml:"queue_timeout"`
+	// PriorityHeader - заголовок с приоритетом запроса от 0 до 9 (больше - важнее).
+	PriorityHeader  string `yaml:"priority_header"`
+	DefaultPriority int    `yaml:"default_priority"` // Приоритет запросов без заголовка.
+	// PriorityTrusted - адреса и подсети CIDR клиентов (по адресу соединения), которым доверяется заголовок
+	// приоритета. Остальные клиенты могут заголовком только понизить приоритет ниже default_priority.
+	PriorityTrusted []string `yaml:"priority_trusted"`
+
+	QueueTimeout time.Duration `yaml:"-"`
+	// PriorityTrustedPrefixes - разобранный PriorityTrusted; отдельный адрес - подсеть из одного адреса.
+	PriorityTrustedPrefixes []netip.Prefix `yaml:"-"`
+}
+
+// ConnectionLimitConfig ограничивает число одновременно открытых соединений с одного IP-адреса клиента.
+// Проверка выполняется при принятии соединения, до чтения запросов: в отличие от Rate Limiter, она не дает
+// одному хосту занять все соединения сервера, например множеством простаивающих keep-alive соединений.
+type ConnectionLimitConfig struct {
+	// MaxPerIP - сколько соединений может быть открыто с одного IP-адреса, 0 - без ограничения.
+	// Соединения сверх лимита закрываются сразу после принятия.
+	MaxPerIP int `yaml:"max_per_ip"`
+}
+
+// StorageMaintenanceConfig задает периодическое обслуживание файла SQLite (rate_limiter.database_path):
+// удаление устаревших записей и возврат освободившихся страниц файловой системе, чтобы файл не рос
+// при частом создании и удалении клиентов. Обслуживание можно запустить и вручную (POST /admin/storage/maintenance).
+type StorageMaintenanceConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	IntervalStr string `yaml:"interval"` // Период обслуживания (например, "24h").
+	// HealthRetentionStr - сколько хранится статус бэкендов, сохраненный при остановке (например, "168h"):
+	// после долгого простоя он устарел и при запуске не восстанавливается.
+	HealthRetentionStr string `yaml:"health_retention"`
+	// VacuumPages - сколько свободных страниц возвращается за одно обслуживание, 0 - все.
+	// Ограничение сокращает время, на которое обслуживание блокирует запись в базу.
+	VacuumPages int `yaml:"vacuum_pages"`
+
+	Interval        time.Duration `yaml:"-"`
+	HealthRetention time.Duration `yaml:"-"`
+}
+
+// SQLiteConfig задает параметры соединений с базой SQLite (rate_limiter.database_path). В режиме WAL чтение
+// не ждет записи: пакетное сохранение состояния корзин не блокирует запросы API; одновременные записи
+// выполняются по очереди, а ожидание блокировки ограничено busy_timeout.
+type SQLiteConfig struct {
+	JournalMode    string `yaml:"journal_mode"` // Режим журнала: wal, delete, truncate, persist, memory или off.
+	BusyTimeoutStr string `yaml:"busy_timeout"` // Сколько запрос ждет снятия блокировки базы (например, "5s").
+	Synchronous    string `yaml:"synchronous"`  // Синхронизация с диском: off, normal, full или extra.
+	// MaxOpenConns - сколько соединений с базой открывается одновременно, 0 - без ограничения.
+	MaxOpenConns int `yaml:"max_open_conns"`
+	// Pragmas - дополнительные PRAGMA, выполняемые в каждом соединении (например, cache_size: '-20000').
+	Pragmas map[string]string `yaml:"pragmas"`
+
+	BusyTimeout time.Duration `yaml:"-"`
+}
+
+// DefaultSQLiteConfig возвращает параметры SQLite по умолчанию (секция sqlite не задана).
+func DefaultSQLiteConfig() SQLiteConfig {
+	return SQLiteConfig{
+		JournalMode:    "wal",
+		BusyTimeoutStr: "5s",
+		Synchronous:    "normal",
+		MaxOpenConns:   8,
+		BusyTimeout:    5 * time.Second,
+	}
+}
+
+// SpilloverConfig описывает резервный пул основного балансировщика (например, более дорогой облачный регион).
+// Резервный пул получает запросы только когда основной исчерпал бюджет одновременных запросов или частоты.
+type SpilloverConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	BackendServers []string `yaml:"backend_servers"` // URL бэкендов резервного пула.
+	// PrimaryMaxInFlight - сколько запросов основной пул обрабатывает одновременно (0 - без ограничения).
+	PrimaryMaxInFlight int `yaml:"primary_max_in_flight"`
+	// PrimaryMaxRPS - сколько запросов в секунду принимает основной пул (0 - без ограничения).
+	PrimaryMaxRPS float64 `yaml:"primary_max_rps"`
+}
+
+// CaptureConfig задает размеры буфера записи запросов и ответов для отладки.
+// Сама запись включается через служебный API (/admin/capture) для конкретного клиента или маршрута.
+type CaptureConfig struct {
+	BufferSize   int `yaml:"buffer_size"`    // Сколько последних запросов хранится в кольцевом буфере.
+	MaxBodyBytes int `yaml:"max_body_bytes"` // Сколько байт тела запроса и ответа записывается (0 - только заголовки).
+}
+
+// Типы приемников журнала доступа.
+const (
+	AccessLogSinkSyslog = "syslog"
+	AccessLogSinkHTTP   = "http"
+	AccessLogSinkKafka  = "kafka"
+	AccessLogSinkFile   = "file"
+)
+
+// Форматы записей приемника file журнала доступа.
+const (
+	AccessLogFormatJSON   = "json"
+	AccessLogFormatCommon = "common"
+)
+
+// AccessLogConfig описывает запись журнала доступа (по записи на запрос) пачками в удаленный приемник,
+// без локального агента, или в файл. Запросы никогда не ждут записи: при переполнении очереди записи отбрасываются.
+type AccessLogConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Sink - тип приемника: "syslog" (RFC 5424), "http" (NDJSON POST), "kafka" (через Kafka REST Proxy)
+	// или "file" (файл или стандартный вывод).
+	Sink string `yaml:"sink"`
+	// Address - "udp://host:514" или "tcp://host:514" для syslog, URL для http, URL REST Proxy для kafka,
+	// путь к файлу или "stdout" для file.
+	Address string            `yaml:"address"`
+	Topic   string            `yaml:"topic"`   // Топик Kafka.
+	Headers map[string]string `yaml:"headers"` // Дополнительные заголовки запросов http и kafka (например, авторизация).
+	// BatchSize - максимум записей в одной отправке.
+	BatchSize int `yaml:"batch_size"`
+	// QueueSize - сколько записей может ждать отправки; сверх этого записи отбрасываются.
+	QueueSize int `yaml:"queue_size"`
+	// FlushIntervalStr - как часто отправлять неполную пачку (например, "1s").
+	FlushIntervalStr string `yaml:"flush_interval"`
+	// TimeoutStr - таймаут одной отправки.
+	TimeoutStr string `yaml:"timeout"`
+	// MaxRetries - сколько раз повторять неудачную отправку пачки, прежде чем отбросить ее.
+	MaxRetries int `yaml:"max_retries"`
+	// Format - формат записей приемника file: "json" (по умолчанию, запись JSON на строку) или "common"
+	// (Common Log Format, дополненный бэкендом, временем обработки и ID запроса).
+	Format string `yaml:"format"`
+	// MaxSizeMB - размер файла приемника file в мегабайтах, после которого он ротируется
+	// (access.log -> access.log.1 -> access.log.2 ...). 0 - без ротации.
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// MaxBackups - сколько ротированных файлов хранить; более старые удаляются.
+	MaxBackups int `yaml:"max_backups"`
+
+	FlushInterval time.Duration `yaml:"-"`
+	Timeout       time.Duration `yaml:"-"`
+}
+
+// PassiveHealthConfig задает реакцию на ошибки проксирования и ответы, не прошедшие
+// routes[].response_validation.
+type PassiveHealthConfig struct {
+	// HoldDownStr - на сколько бэкенд исключается из балансировки после ошибки (например, "30s");
+	// в это время успешные активные проверки его не возвращают. Пусто - бэкенд считается
+	// недоступным до следующей успешной активной проверки.
+	HoldDownStr string `yaml:"hold_down"`
+	// MaxFailures - сколько ошибок за FailureWindowStr нужно, чтобы исключить бэкенд
+	// (0 или 1 - бэкенд исключается после первой же ошибки).
+	MaxFailures int `yaml:"max_failures"`
+	// FailureWindowStr - за какой период считаются ошибки (например, "10s"); по умолчанию DefaultFailureWindow.
+	FailureWindowStr string `yaml:"failure_window"`
+	// Count5xx - ответы бэкенда 5xx тоже считаются ошибками; клиент получает их как есть.
+	Count5xx bool `yaml:"count_5xx"`
+	// Consecutive - успешный ответ обнуляет счетчик ошибок: бэкенд исключается после max_failures ошибок подряд.
+	Consecutive bool `yaml:"consecutive"`
+	// SlowStartStr - за какое время вес бэкенда, вернувшегося в балансировку (после hold_down или успешной
+	// активной проверки), растет от SlowStartMinPercent до полного (например, "30s"). Пусто - сразу полный вес.
+	SlowStartStr string `yaml:"slow_start"`
+
+	HoldDown      time.Duration `yaml:"-"`
+	FailureWindow time.Duration `yaml:"-"`
+	SlowStart     time.Duration `yaml:"-"`
+}
+
+// parse разбирает длительности и проверяет значения passive_health.
+func (p *PassiveHealthConfig) parse() error {
+	p.HoldDown = 0
+	if p.HoldDownStr != "" {
+		holdDown, err := time.ParseDuration(p.HoldDownStr)
+		if err != nil || holdDown < 0 {
+			return i18n.Errorf(i18n.ConfigBadHoldDown, p.HoldDownStr)
+		}
+		p.HoldDown = holdDown
+	}
+	if p.MaxFailures < 0 {
+		return i18n.Errorf(i18n.ConfigBadMaxFailures, p.MaxFailures)
+	}
+	p.FailureWindow = DefaultFailureWindow
+	if p.FailureWindowStr != "" {
+		window, err := time.ParseDuration(p.FailureWindowStr)
+		if err != nil || window <= 0 {
+			return i18n.Errorf(i18n.ConfigBadFailureWindow, p.FailureWindowStr)
 		}
-		if interval <= 0 {
-			return nil, fmt.Errorf("интервал HealthCheck должен быть положительным: %s", config.HealthCheck.IntervalStr)
+		p.FailureWindow = window
+	}
+	p.SlowStart = 0
+	if p.SlowStartStr != "" {
+		slowStart, err := time.ParseDuration(p.SlowStartStr)
+		if err != nil || slowStart < 0 {
+			return i18n.Errorf(i18n.ConfigBadSlowStart, p.SlowStartStr)
 		}
-		config.HealthCheck.Interval = interval
+		p.SlowStart = slowStart
+	}
+	return nil
+}
+
+// StickySessionConfig включает привязку клиента к бэкенду по cookie: с первым ответом балансировщик
+// выдает подписанную cookie с ID бэкенда, и следующие запросы клиента идут на тот же бэкенд,
+// пока он доступен. Если бэкенд недоступен, он выбирается настроенным алгоритмом и cookie заменяется.
+type StickySessionConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	CookieName string `yaml:"cookie_name"` // Имя cookie, по умолчанию DefaultStickyCookieName.
+	// Secret - ключ подписи cookie (HMAC-SHA256). Должен совпадать у всех экземпляров; пусто - случайный
+	// ключ на время работы процесса (после перезапуска клиенты один раз перераспределяются).
+	Secret string `yaml:"secret"`
+	TTLStr string `yaml:"ttl"` // Срок жизни cookie (например, "1h"), пусто - до закрытия браузера.
+
+	TTL time.Duration `yaml:"-"`
+}
+
+// ReadinessConfig задает, когда GET /readyz отвечает 503, чтобы внешний балансировщик или Kubernetes
+// уводили трафик с перегруженного экземпляра: все бэкенды недоступны или заполнены, занято больше
+// in_flight_ratio слотов concurrency.max_in_flight, хранилище лимитов не отвечает.
+type ReadinessConfig struct {
+	// InFlightRatio - доля concurrency.max_in_flight (например, 0.9), начиная с которой экземпляр не готов.
+	// 0 - DefaultReadinessInFlightRatio. Без concurrency.enabled не проверяется.
+	InFlightRatio float64 `yaml:"in_flight_ratio"`
+	// StoreTimeoutStr - сколько ждать ответа хранилища при проверке (например, "1s"), пусто - DefaultReadinessStoreTimeout.
+	StoreTimeoutStr string `yaml:"store_timeout"`
+
+	StoreTimeout time.Duration `yaml:"-"`
+}
+
+// MetricsConfig задает эндпоинт метрик в текстовом формате Prometheus на основном листенере.
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Path - путь эндпоинта, по умолчанию "/metrics". Не должен совпадать со служебными путями
+	// (/admin/, /clients, /access, /api-keys, /readyz): запросы на него не проксируются бэкендам.
+	Path string `yaml:"path"`
+}
+
+// Роли пользователей API управления (admin_auth).
+const (
+	AdminRoleAdmin  = "admin"  // Чтение и изменение (по умолчанию).
+	AdminRoleViewer = "viewer" // Только чтение: GET и HEAD, остальные методы получают 403.
+)
+
+// AdminAuthConfig - учетные данные API управления: статические токены (Authorization: Bearer) и пользователи
+// с паролями (Basic). Без токенов и пользователей API управления открыто, как раньше.
+type AdminAuthConfig struct {
+	Tokens []AdminTokenConfig `yaml:"tokens"`
+	Users  []AdminUserConfig  `yaml:"users"`
+}
 
-		if config.HealthCheck.TimeoutStr == "" {
-			config.HealthCheck.TimeoutStr = "2s" // Значение по умолчанию
-			fmt.Printf("[Config] Таймаут HealthCheck не указан, используется значение по умолчанию: %s\n", config.HealthCheck.TimeoutStr)
+// AdminTokenConfig - статический токен API управления.
+type AdminTokenConfig struct {
+	Name  string `yaml:"name"` // Имя владельца токена для журнала, пусто - "token-<номер>".
+	Token string `yaml:"token"`
+	Role  string `yaml:"role"` // AdminRoleAdmin или AdminRoleViewer, пусто - AdminRoleAdmin.
+}
+
+// AdminUserConfig - пользователь API управления с паролем (Basic, RFC 7617).
+type AdminUserConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Role     string `yaml:"role"` // AdminRoleAdmin или AdminRoleViewer, пусто - AdminRoleAdmin.
+}
+
+// Enabled сообщает, что API управления требует аутентификации.
+func (ac *AdminAuthConfig) Enabled() bool {
+	return len(ac.Tokens)+len(ac.Users) > 0
+}
+
+// validate проверяет учетные данные и подставляет роли и имена по умолчанию.
+func (ac *AdminAuthConfig) validate() error {
+	tokens := make(map[string]bool, len(ac.Tokens))
+	for i := range ac.Tokens {
+		token := &ac.Tokens[i]
+		field := fmt.Sprintf("admin_auth.tokens[%d]", i)
+		if token.Token == "" {
+			return i18n.Errorf(i18n.ConfigAdminAuthMissing, field+".token")
+		}
+		if tokens[token.Token] {
+			return i18n.Errorf(i18n.ConfigAdminAuthDuplicate, field+".token")
+		}
+		tokens[token.Token] = true
+		if token.Name == "" {
+			token.Name = fmt.Sprintf("token-%d", i)
 		}
-		timeout, err := time.ParseDuration(config.HealthCheck.TimeoutStr)
+		role, err := adminRole(field, token.Role)
 		if err != nil {
-			return nil, fmt.Errorf("неверный формат таймаута HealthCheck (%s): %w", config.HealthCheck.TimeoutStr, err)
+			return err
 		}
-		if timeout <= 0 {
-			return nil, fmt.Errorf("таймаут HealthCheck должен быть положительным: %s", config.HealthCheck.TimeoutStr)
+		token.Role = role
+	}
+	users := make(map[string]bool, len(ac.Users))
+	for i := range ac.Users {
+		user := &ac.Users[i]
+		field := fmt.Sprintf("admin_auth.users[%d]", i)
+		switch {
+		case user.Username == "":
+			return i18n.Errorf(i18n.ConfigAdminAuthMissing, field+".username")
+		case user.Password == "":
+			return i18n.Errorf(i18n.ConfigAdminAuthMissing, field+".password")
+		case users[user.Username]:
+			return i18n.Errorf(i18n.ConfigAdminAuthDuplicate, field+".username")
+		}
+		users[user.Username] = true
+		role, err := adminRole(field, user.Role)
+		if err != nil {
+			return err
 		}
-		if timeout >= interval {
-			fmt.Printf("[Config] Внимание: Таймаут HealthCheck (%s) больше или равен интервалу (%s). Рекомендуется меньший таймаут.\n", config.HealthCheck.TimeoutStr, config.HealthCheck.IntervalStr)
+		user.Role = role
+	}
+	return nil
+}
+
+// adminRole проверяет роль пользователя API управления; пустая роль - AdminRoleAdmin.
+func adminRole(field, role string) (string, error) {
+	switch role = strings.ToLower(role); role {
+	case "":
+		return AdminRoleAdmin, nil
+	case AdminRoleAdmin, AdminRoleViewer:
+		return role, nil
+	}
+	return "", i18n.Errorf(i18n.ConfigUnknownAdminRole, field+".role", role, AdminRoleAdmin, AdminRoleViewer)
+}
+
+// validate проверяет путь эндпоинта метрик.
+func (mc *MetricsConfig) validate() error {
+	if !mc.Enabled {
+		return nil
+	}
+	if !strings.HasPrefix(mc.Path, "/") || mc.Path == "/" {
+		return i18n.Errorf(i18n.ConfigBadMetricsPath, mc.Path)
+	}
+	for _, reserved := range []string{"/admin", "/clients", "/access", "/api-keys", "/readyz"} {
+		if mc.Path == reserved || strings.HasPrefix(mc.Path, reserved+"/") {
+			return i18n.Errorf(i18n.ConfigBadMetricsPath, mc.Path)
 		}
-		config.HealthCheck.Timeout = timeout
+	}
+	return nil
+}
+
+// LoggingConfig задает вывод лога.
+type LoggingConfig struct {
+	// LevelStr - минимальный уровень сообщений: "debug", "info" (по умолчанию), "warn" или "error".
+	// Пусто - используется log_level.
+	LevelStr string `yaml:"level"`
+	// Format - "text" (строка для человека, по умолчанию) или "json" (одна JSON-запись на строку
+	// с полями time, level, msg, component, message_id). Применяется при запуске.
+	Format string `yaml:"format"`
+}
+
+// validate проверяет формат лога.
+func (lc *LoggingConfig) validate() error {
+	lc.Format = strings.ToLower(strings.TrimSpace(lc.Format))
+	switch lc.Format {
+	case "":
+		lc.Format = i18n.FormatText
+	case i18n.FormatText, i18n.FormatJSON:
+	default:
+		return i18n.Errorf(i18n.LogFormatUnsupported, lc.Format, i18n.FormatText+", "+i18n.FormatJSON)
+	}
+	return nil
+}
+
+// TraceConfig включает трассировку решений балансировщика по запросу в заголовке ответа X-Balancer-Trace:
+// маршрут, решение Rate Limiter с остатком токенов, рассмотренные и пропущенные бэкенды, выбранный бэкенд,
+// повторы. Записи запросов (POST /admin/capture) содержат трассировку независимо от этой секции.
+type TraceConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Token - значение заголовка запроса X-Balancer-Trace, при котором ответ получает трассировку.
+	// Пусто - трассировку получают все ответы: в ней видны бэкенды и ключи клиентов, поэтому
+	// без токена трассировку стоит включать только для отладки.
+	Token string `yaml:"token"`
+}
 
-		if config.HealthCheck.Path == "" {
-			config.HealthCheck.Path = "/" // Значение по умолчанию
-			fmt.Printf("[Config] Путь HealthCheck не указан, используется значение по умолчанию: %s\n", config.HealthCheck.Path)
+// TracingConfig включает распределенную трассировку OpenTelemetry: span на каждый запрос к балансировщику
+// и на каждое обращение к бэкенду, заголовок traceparent (W3C Trace Context) передается бэкендам,
+// span отправляются пачками в коллектор по OTLP/HTTP (JSON).
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Endpoint - URL приема span коллектора, например "http://otel-collector:4318/v1/traces".
+	Endpoint string `yaml:"endpoint"`
+	// ServiceName - атрибут ресурса service.name, по умолчанию "load-balancer".
+	ServiceName string `yaml:"service_name"`
+	// SampleRatio - доля новых трасс, которые записываются (0..1, по умолчанию 1). Для запросов
+	// с traceparent решение о записи берется из заголовка.
+	SampleRatio float64           `yaml:"sample_ratio"`
+	Headers     map[string]string `yaml:"headers"` // Дополнительные заголовки запросов к коллектору (например, авторизация).
+	// BatchSize - максимум span в одной отправке; QueueSize - сколько span может ждать отправки,
+	// сверх этого span отбрасываются.
+	BatchSize int `yaml:"batch_size"`
+	QueueSize int `yaml:"queue_size"`
+	// FlushIntervalStr - как часто отправлять неполную пачку; TimeoutStr - таймаут одной отправки.
+	FlushIntervalStr string `yaml:"flush_interval"`
+	TimeoutStr       string `yaml:"timeout"`
+
+	FlushInterval time.Duration `yaml:"-"`
+	Timeout       time.Duration `yaml:"-"`
+}
+
+// validate проверяет адрес коллектора, долю записываемых трасс, размеры пачки и очереди и интервалы.
+func (tc *TracingConfig) validate() error {
+	if !strings.HasPrefix(tc.Endpoint, "http://") && !strings.HasPrefix(tc.Endpoint, "https://") {
+		return i18n.Errorf(i18n.ConfigTracingBadEndpoint, tc.Endpoint)
+	}
+	if tc.SampleRatio < 0 || tc.SampleRatio > 1 {
+		return i18n.Errorf(i18n.ConfigTracingBadSampleRatio, tc.SampleRatio)
+	}
+	if tc.BatchSize < 1 || tc.QueueSize < tc.BatchSize {
+		return i18n.Errorf(i18n.ConfigTracingBadQueue, tc.BatchSize, tc.QueueSize)
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"tracing.flush_interval", tc.FlushIntervalStr, &tc.FlushInterval},
+		{"tracing.timeout", tc.TimeoutStr, &tc.Timeout},
+	} {
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return i18n.Errorf(i18n.ConfigBadDuration, d.name, d.value, err)
 		}
-		// Добавляем '/' в начало пути, если его нет
-		if len(config.HealthCheck.Path) == 0 || config.HealthCheck.Path[0] != '/' {
-			config.HealthCheck.Path = "/" + config.HealthCheck.Path
+		if parsed <= 0 {
+			return i18n.Errorf(i18n.ConfigNonPositiveDuration, d.name, d.value)
 		}
+		*d.dest = parsed
+	}
+	return nil
+}
 
-		fmt.Printf("[Config] Health Checks включены: Интервал=%v, Таймаут=%v, Путь=%s\n",
-			config.HealthCheck.Interval, config.HealthCheck.Timeout, config.HealthCheck.Path)
-	} else {
-		fmt.Println("[Config] Health Checks выключены.")
+// RequestAgeConfig ограничивает время, которое запрос проводит в балансировщике до отправки бэкенду
+// (очередь concurrency, повторы после 429/503): клиент, скорее всего, уже не ждет ответа.
+type RequestAgeConfig struct {
+	// MaxAgeStr - через сколько после поступления запрос отбрасывается с 503 (например, "10s"); пусто - без ограничения.
+	MaxAgeStr string `yaml:"max_age"`
+
+	MaxAge time.Duration `yaml:"-"`
+}
+
+// DefaultHookMaxSteps - предел шагов выполнения сценария admission_hook на один запрос по умолчанию.
+const DefaultHookMaxSteps = 100000
+
+// AdmissionHookConfig описывает сценарий на Starlark, который вызывается для каждого запроса до Rate Limiter
+// с метаданными запроса и состоянием корзины клиента и может отклонить запрос с собственным статусом,
+// задать его стоимость в токенах или направить его в другой пул.
+type AdmissionHookConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Script - путь к файлу сценария, Source - текст сценария прямо в конфигурации; задается одно из двух.
+	Script string `yaml:"script"`
+	Source string `yaml:"source"`
+	// MaxSteps - предел шагов выполнения сценария на запрос: сценарий, превысивший его, прерывается,
+	// и запрос обрабатывается без его решения. 0 - DefaultHookMaxSteps.
+	MaxSteps uint64 `yaml:"max_steps"`
+}
+
+func (h *AdmissionHookConfig) validate() error {
+	if !h.Enabled {
+		return nil
+	}
+	if (h.Script == "") == (h.Source == "") {
+		return i18n.Errorf(i18n.ConfigHookSource)
+	}
+	if h.MaxSteps == 0 {
+		h.MaxSteps = DefaultHookMaxSteps
+	}
+	return nil
+}
+
+// Значения readiness по умолчанию.
+const (
+	DefaultReadinessInFlightRatio = 0.9
+	DefaultReadinessStoreTimeout  = time.Second
+)
+
+// Реакции на ответы бэкенда 429 и 503 (upstream_throttling.policy).
+const (
+	ThrottlePassThrough  = "pass_through"  // Ответ передается клиенту без изменений.
+	ThrottleRetry        = "retry"         // Запрос повторяется на другом бэкенде пула.
+	ThrottleReduceWeight = "reduce_weight" // Ответ передается клиенту, вес бэкенда временно снижается.
+)
+
+// UpstreamThrottlingConfig задает реакцию на ответы бэкенда 429 (Too Many Requests) и 503 (Service Unavailable),
+// которыми бэкенд сообщает о собственной перегрузке.
+type UpstreamThrottlingConfig struct {
+	// Policy - ThrottlePassThrough (по умолчанию), ThrottleRetry или ThrottleReduceWeight для всех пулов.
+	Policy string `yaml:"policy"`
+	// Pools - реакция для отдельных пулов по имени из GET /admin/state: "primary", "spillover", "tls:<имя сайта>".
+	Pools map[string]string `yaml:"pools"`
+	// PenaltyStr - на сколько снижается вес бэкенда (например, "30s"), пусто - DefaultThrottlePenalty.
+	PenaltyStr string `yaml:"penalty"`
+	// WeightPercent - какая доля веса (в процентах, от 1 до 99) остается у бэкенда на время снижения,
+	// 0 - DefaultThrottleWeightPercent.
+	WeightPercent int `yaml:"weight_percent"`
+
+	Penalty time.Duration `yaml:"-"`
+}
+
+// PolicyFor возвращает реакцию на 429 и 503 для пула с именем pool.
+func (c UpstreamThrottlingConfig) PolicyFor(pool string) string {
+	if policy, ok := c.Pools[pool]; ok {
+		return policy
 	}
+	return c.Policy
+}
 
-	return config, nil
+// Способы подтверждения бэкенду, что запрос прошел через балансировщик (upstream_auth.<пул>.type).
+const (
+	UpstreamAuthBearer = "bearer" // Заголовок "Authorization: Bearer <token>".
+	UpstreamAuthBasic  = "basic"  // Заголовок "Authorization: Basic ..." (RFC 7617).
+	// UpstreamAuthHMAC - подпись HMAC-SHA256 метода, пути и отметки времени в X-LB-Signature и X-LB-Timestamp.
+	UpstreamAuthHMAC = "hmac"
+)
+
+// UpstreamAuthConfig - учетные данные, которые балансировщик добавляет к запросам на бэкенды пула
+// и к их активным проверкам, чтобы бэкенд мог убедиться, что запрос прошел через балансировщик.
+// Значения, переданные клиентом в тех же заголовках, заменяются.
+type UpstreamAuthConfig struct {
+	Type string `yaml:"type"` // UpstreamAuthBearer, UpstreamAuthBasic или UpstreamAuthHMAC.
+	// Header - заголовок для bearer и basic; по умолчанию Authorization.
+	Header   string `yaml:"header"`
+	Token    string `yaml:"token"`    // Токен для bearer.
+	Username string `yaml:"username"` // Пользователь и пароль для basic.
+	Password string `yaml:"password"`
+	Secret   string `yaml:"secret"` // Ключ подписи для hmac; должен быть известен бэкендам.
+}
+
+// DefaultDNSTimeout - таймаут разрешения имени бэкенда, если dns.<пул>.timeout не задан.
+const DefaultDNSTimeout = 2 * time.Second
+
+// DNSConfig - разрешение имен хостов бэкендов пула: свои DNS-серверы вместо системных (split-horizon DNS),
+// таймаут запроса и время хранения полученных адресов.
+type DNSConfig struct {
+	// Servers - DNS-серверы "IP[:порт]" (порт по умолчанию 53), опрашиваются по очереди; пусто - системные.
+	Servers    []string `yaml:"servers"`
+	TimeoutStr string   `yaml:"timeout"` // Таймаут разрешения имени, по умолчанию DefaultDNSTimeout.
+	// CacheTTLStr - сколько хранить адреса хоста; пусто - имя разрешается при каждом новом соединении.
+	CacheTTLStr string `yaml:"cache_ttl"`
+
+	Timeout  time.Duration `yaml:"-"`
+	CacheTTL time.Duration `yaml:"-"`
+}
+
+// BackendTLSConfig - TLS соединений пула с https:// бэкендами (проксирование и активные проверки).
+type BackendTLSConfig struct {
+	CAFile string `yaml:"ca_file"` // УЦ (PEM), которыми подписаны сертификаты бэкендов, вместо системных.
+	// InsecureSkipVerify - не проверять сертификаты бэкендов. Только для тестовых стендов.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	// CertFile и KeyFile - клиентский сертификат балансировщика (PEM) для бэкендов, требующих mTLS.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// CanaryConfig - процентное разделение запросов пула между стабильными бэкендами и канареечными,
+// выбранными по меткам, со сравнением их ошибок и задержек (GET /admin/canary).
+type CanaryConfig struct {
+	// Selector - метки канареечных бэкендов (см. backend_labels); остальные бэкенды пула - стабильные.
+	Selector map[string]string `yaml:"selector"`
+	// Percent - доля запросов пула в процентах (от 1 до 99), которая направляется канареечным бэкендам.
+	Percent int `yaml:"percent"`
+	// WindowStr - за сколько последних секунд сравниваются группы (например, "5m"), пусто - DefaultCanaryWindow.
+	WindowStr string `yaml:"window"`
+	// MinRequests - сколько запросов должно быть у каждой группы в окне, чтобы сравнение учитывалось;
+	// 0 - DefaultCanaryMinRequests.
+	MinRequests int64 `yaml:"min_requests"`
+	// MaxErrorRateDelta - на сколько доля ошибок канарейки может превышать долю ошибок стабильных
+	// бэкендов (0.05 - на 5 процентных пунктов); 0 - не проверяется.
+	MaxErrorRateDelta float64 `yaml:"max_error_rate_delta"`
+	// MaxLatencyRatio - во сколько раз среднее время ответа канарейки может превышать стабильное;
+	// 0 - не проверяется.
+	MaxLatencyRatio float64 `yaml:"max_latency_ratio"`
+	// AutoRollback - при превышении порогов запросы перестают направляться канареечным бэкендам
+	// до POST /admin/canary/resume.
+	AutoRollback bool `yaml:"auto_rollback"`
+
+	Window time.Duration `yaml:"-"`
+}
+
+// Значения canary по умолчанию и ограничения окна: статистика хранится в памяти по секунде на группу.
+const (
+	DefaultCanaryWindow      = 5 * time.Minute
+	MaxCanaryWindow          = time.Hour
+	DefaultCanaryMinRequests = 100
+)
+
+// Значения upstream_throttling по умолчанию.
+const (
+	DefaultThrottlePenalty       = 30 * time.Second
+	DefaultThrottleWeightPercent = 25
+)
+
+// StatsHistoryConfig задает историю запросов и ошибок бэкендов по секундам для GET /admin/stats/timeseries
+// (графики на панели мониторинга).
+type StatsHistoryConfig struct {
+	// WindowStr - за сколько последних секунд хранится история (например, "5m"), пусто - DefaultStatsHistoryWindow.
+	WindowStr string `yaml:"window"`
+
+	Window time.Duration `yaml:"-"`
+}
+
+// Ограничения stats_history.window: история хранится в памяти по секунде на каждый бэкенд.
+const (
+	DefaultStatsHistoryWindow = 5 * time.Minute
+	MaxStatsHistoryWindow     = time.Hour
+)
+
+// DefaultStickyCookieName - имя cookie привязки к бэкенду, если sticky_sessions.cookie_name не задан.
+const DefaultStickyCookieName = "lb_backend"
+
+// DefaultFailureWindow - окно подсчета ошибок passive_health.max_failures по умолчанию.
+const DefaultFailureWindow = 10 * time.Second
+
+// SlowStartMinPercent - доля полного веса (в процентах), с которой бэкенд возвращается в балансировку
+// при passive_health.slow_start.
+const SlowStartMinPercent = 10
+
+// DefaultSaturationThreshold - загрузка бэкенда по умолчанию, начиная с которой он считается почти заполненным.
+const DefaultSaturationThreshold = 0.8
+
+// Списки доступа.
+const (
+	AccessListDeny  = "deny"
+	AccessListAllow = "allow"
+)
+
+// AccessListConfig задает постоянные записи списков доступа. Запись - IP-адрес, подсеть CIDR
+// или ID клиента (значение заголовка identifier_header). Запросы клиентов из deny отклоняются
+// с кодом 403, клиенты из allow не ограничиваются Rate Limiter; если клиент есть в обоих списках,
+// действует запрет. Записи с ограниченным сроком действия добавляются через API (/access)
+// и хранятся в базе Rate Limiter вместе с лимитами клиентов.
+type AccessListConfig struct {
+	Deny  []string `yaml:"deny"`
+	Allow []string `yaml:"allow"`
+}
+
+// Config определяет структуру конфигурационного файла.
+type Config struct {
+	// Port - порт, на котором будет работать балансировщик.
+	Port string `yaml:"port"`
+	// BindAddress - IP-адрес интерфейса для HTTP-листенера (например, "127.0.0.1", "::1" или "::"),
+	// пусто - все интерфейсы IPv4 и IPv6. Адрес IPv6 можно указывать в квадратных скобках.
+	BindAddress string `yaml:"bind_address"`
+	// BackendServerEntries - backend_servers как в файле: URL строкой или объектом {url, weight, passive_health}.
+	BackendServerEntries []BackendServer `yaml:"backend_servers"`
+	// BackendServers - список URL-адресов бэкенд-серверов (из backend_servers).
+	BackendServers []string `yaml:"-"`
+	// BackendWeights - веса бэкендов по URL; бэкенды с весом 1 не указываются.
+	BackendWeights map[string]int `yaml:"-"`
+	// BackendPassiveHealth - passive_health бэкендов по URL для бэкендов, переопределяющих общие параметры.
+	BackendPassiveHealth map[string]PassiveHealthConfig `yaml:"-"`
+	// BackendLabels - метки бэкендов по URL (например, version: v2, gpu: "true") для backend_selector маршрутов.
+	BackendLabels map[string]map[string]string `yaml:"backend_labels"`
+	// BackendMaxConnections - максимум одновременных запросов к бэкенду по URL; бэкенды без значения не ограничиваются.
+	BackendMaxConnections map[string]int `yaml:"backend_max_connections"`
+	// SaturationThreshold - загрузка бэкенда (доля backend_max_connections, например 0.8), начиная с которой
+	// он выбирается, только если все остальные бэкенды загружены не меньше. 0 - DefaultSaturationThreshold.
+	SaturationThreshold float64 `yaml:"saturation_threshold"`
+	// LoadBalancingAlgorithm - алгоритм балансировки
+	LoadBalancingAlgorithm string `yaml:"load_balancing_algorithm"`
+	// RateLimiter - настройки для модуля Rate Limiting.
+	RateLimiter RateLimiterConfig `yaml:"rate_limiter"`
+	HealthCheck HealthCheckConfig `yaml:"health_check"`
+	// Headers - глобальная политика маскирования и пересылки заголовков.
+	Headers HeaderPolicyConfig `yaml:"headers"`
+	// Routes - правила для отдельных префиксов пути (выбирается самый длинный совпавший префикс).
+	Routes []RouteConfig `yaml:"routes"`
+	// Alerts - встроенный алертинг по доле 429 и недоступности бэкендов.
+	Alerts AlertsConfig `yaml:"alerts"`
+	// Locale - язык логов и сообщений об ошибках ("ru" или "en").
+	Locale string `yaml:"locale"`
+	// LogLevelStr - минимальный уровень сообщений лога: "debug", "info" (по умолчанию), "warn" или "error".
+	// Меняется на лету через PUT /admin/loglevel. Устаревший вариант logging.level, который имеет приоритет.
+	LogLevelStr string `yaml:"log_level"`
+	// Logging - уровень и формат лога.
+	Logging LoggingConfig `yaml:"logging"`
+	// TLS - HTTPS-листенер с маршрутизацией доменов по SNI.
+	TLS TLSConfig `yaml:"tls"`
+	// GRPC - балансировка вызовов gRPC поверх HTTP/2.
+	GRPC GRPCConfig `yaml:"grpc"`
+	// UDP - балансировка UDP-трафика.
+	UDP UDPConfig `yaml:"udp"`
+	// ForwardProxy - прямой прокси для исходящих запросов через egress-шлюзы.
+	ForwardProxy ForwardProxyConfig `yaml:"forward_proxy"`
+	// Concurrency - ограничение одновременных запросов с приоритетной очередью.
+	Concurrency ConcurrencyConfig `yaml:"concurrency"`
+	// ConnectionLimit - ограничение одновременных соединений с одного IP-адреса на листенерах HTTP и HTTPS.
+	ConnectionLimit ConnectionLimitConfig `yaml:"connection_limit"`
+	// StorageMaintenance - периодическое обслуживание базы SQLite.
+	StorageMaintenance StorageMaintenanceConfig `yaml:"storage_maintenance"`
+	// SQLite - режим журнала, таймаут блокировки и пул соединений базы SQLite.
+	SQLite SQLiteConfig `yaml:"sqlite"`
+	// Spillover - резервный пул на случай исчерпания бюджета основного.
+	Spillover SpilloverConfig `yaml:"spillover"`
+	// Capture - запись запросов и ответов для отладки ("tcpdump-lite" для HTTP).
+	Capture CaptureConfig `yaml:"capture"`
+	// AccessLog - отправка журнала доступа в удаленный приемник.
+	AccessLog AccessLogConfig `yaml:"access_log"`
+	// AccessList - списки запрета и разрешения клиентов.
+	AccessList AccessListConfig `yaml:"access_list"`
+	// PassiveHealth - исключение бэкендов по ошибкам проксирования и проверкам ответов.
+	PassiveHealth PassiveHealthConfig `yaml:"passive_health"`
+	// StickySessions - привязка клиента к бэкенду по подписанной cookie.
+	StickySessions StickySessionConfig `yaml:"sticky_sessions"`
+	// Readiness - условия готовности экземпляра принимать трафик (GET /readyz).
+	Readiness ReadinessConfig `yaml:"readiness"`
+	// Metrics - эндпоинт метрик Prometheus.
+	Metrics MetricsConfig `yaml:"metrics"`
+	// AdminAuth - аутентификация API управления (/clients, /access, /api-keys, /admin/).
+	AdminAuth AdminAuthConfig `yaml:"admin_auth"`
+	// Trace - трассировка решений по запросу для отладки маршрутизации.
+	Trace TraceConfig `yaml:"trace"`
+	// Tracing - распределенная трассировка OpenTelemetry с отправкой в коллектор OTLP.
+	Tracing TracingConfig `yaml:"tracing"`
+	// Timeouts - таймауты листенеров и ожидания ответа бэкендов.
+	Timeouts TimeoutsConfig `yaml:"timeouts"`
+	// RequestAge - предельное время запроса в балансировщике до отправки бэкенду.
+	RequestAge RequestAgeConfig `yaml:"request_age"`
+	// AdmissionHook - сценарий на Starlark с собственными правилами допуска запросов.
+	AdmissionHook AdmissionHookConfig `yaml:"admission_hook"`
+	// StatsHistory - история запросов и ошибок бэкендов по секундам (GET /admin/stats/timeseries).
+	StatsHistory StatsHistoryConfig `yaml:"stats_history"`
+	// UpstreamThrottling - реакция на ответы бэкендов 429 и 503.
+	UpstreamThrottling UpstreamThrottlingConfig `yaml:"upstream_throttling"`
+	// UpstreamAuth - учетные данные для бэкендов по имени пула из GET /admin/state ("primary", "spillover",
+	// "tls:<имя сайта>").
+	UpstreamAuth map[string]UpstreamAuthConfig `yaml:"upstream_auth"`
+	// DNS - разрешение имен бэкендов по имени пула, как в upstream_auth; пулы без настроек используют
+	// системный резолвер.
+	DNS map[string]DNSConfig `yaml:"dns"`
+	// BackendTLS - настройки TLS для https:// бэкендов по имени пула, как в upstream_auth; пулы без настроек
+	// проверяют сертификаты бэкендов по системным УЦ.
+	BackendTLS map[string]BackendTLSConfig `yaml:"backend_tls"`
+	// Canary - процентное разделение трафика пула между стабильными и канареечными бэкендами
+	// по имени пула, как в upstream_auth.
+	Canary map[string]CanaryConfig `yaml:"canary"`
+
+	LogLevel i18n.Level `yaml:"-"`
+
+	// Hash - SHA-256 содержимого файла конфигурации (hex), позволяет сверить конфигурацию экземпляров.
+	Hash string `yaml:"-"`
+}
+
+// Features возвращает включенные в конфигурации возможности (GET /admin/version): по ним удобно
+// сверять экземпляры при постепенной выкладке.
+func (c *Config) Features() map[string]bool {
+	return map[string]bool{
+		"rate_limiter":        c.RateLimiter.Enabled,
+		"rate_limit_gossip":   c.RateLimiter.Enabled && c.RateLimiter.Gossip.Enabled(),
+		"health_check":        c.HealthCheck.Enabled,
+		"alerts":              c.Alerts.Enabled,
+		"tls":                 c.TLS.Enabled,
+		"grpc":                c.GRPC.Enabled,
+		"udp":                 c.UDP.Enabled,
+		"forward_proxy":       c.ForwardProxy.Enabled,
+		"concurrency":         c.Concurrency.Enabled,
+		"connection_limit":    c.ConnectionLimit.MaxPerIP > 0,
+		"storage_maintenance": c.RateLimiter.Enabled && c.StorageMaintenance.Enabled,
+		"spillover":           c.Spillover.Enabled,
+		"access_log":          c.AccessLog.Enabled,
+		"access_list":         len(c.AccessList.Deny)+len(c.AccessList.Allow) > 0,
+		"passive_hold_down":   c.PassiveHealth.HoldDown > 0,
+		"slow_start":          c.PassiveHealth.SlowStart > 0,
+		"sticky_sessions":     c.StickySessions.Enabled,
+		"upstream_throttling": c.UpstreamThrottling.Policy != ThrottlePassThrough ||
+			len(c.UpstreamThrottling.Pools) > 0,
+		"upstream_auth":  len(c.UpstreamAuth) > 0,
+		"custom_dns":     len(c.DNS) > 0,
+		"backend_tls":    len(c.BackendTLS) > 0,
+		"canary":         len(c.Canary) > 0,
+		"request_age":    c.RequestAge.MaxAge > 0,
+		"admission_hook": c.AdmissionHook.Enabled,
+		"metrics":        c.Metrics.Enabled,
+		"admin_auth":     c.AdminAuth.Enabled(),
+		"trace":          c.Trace.Enabled,
+		"tracing":        c.Tracing.Enabled,
+	}
+}
+
+// LoadConfig загружает конфигурацию из указанного файла.
+func LoadConfig(configPath string) (*Config, error) {
+	config := &Config{
+		// Устанавливаем значения по умолчанию
+		LoadBalancingAlgorithm: "round_robin",
+		Locale:                 i18n.DefaultLocale,
+		RateLimiter: RateLimiterConfig{
+			Enabled:            false,
+			DefaultRate:        1,
+			DefaultCapacity:    1,
+			DatabasePath:       "./rate_limits.db",
+			IdentifierHeader:   "",
+			StoreFailurePolicy: StoreFailOpen,
+			Algorithm:          RateLimitTokenBucket,
+		},
+		HealthCheck: HealthCheckConfig{
+			Enabled: false,
+		},
+		Headers: HeaderPolicyConfig{
+			Sensitive: []string{"Authorization", "Cookie", "Proxy-Authorization"},
+		},
+		Alerts: AlertsConfig{
+			CheckIntervalStr:       "10s",
+			RejectionRateThreshold: 0.05,
+			RejectionWindowStr:     "1m",
+			MinRequests:            20,
+			AllBackendsDownForStr:  "30s",
+		},
+		UDP: UDPConfig{
+			SessionTimeoutStr: "30s",
+			MaxFails:          3,
+			FailTimeoutStr:    "10s",
+		},
+		ForwardProxy: ForwardProxyConfig{
+			DialTimeoutStr: "10s",
+		},
+		Metrics: MetricsConfig{
+			Path: "/metrics",
+		},
+		Timeouts: TimeoutsConfig{
+			ReadHeaderStr:      "10s",
+			IdleStr:            "120s",
+			BackendResponseStr: "60s",
+		},
+		Tracing: TracingConfig{
+			ServiceName:      "load-balancer",
+			SampleRatio:      1,
+			BatchSize:        512,
+			QueueSize:        2048,
+			FlushIntervalStr: "5s",
+			TimeoutStr:       "10s",
+		},
+		StorageMaintenance: StorageMaintenanceConfig{
+			IntervalStr:        "24h",
+			HealthRetentionStr: "168h",
+		},
+		SQLite: DefaultSQLiteConfig(),
+		Concurrency: ConcurrencyConfig{
+			MaxQueue:        100,
+			QueueTimeoutStr: "5s",
+			PriorityHeader:  "X-Priority",
+			DefaultPriority: 5,
+		},
+		Capture: CaptureConfig{
+			BufferSize:   100,
+			MaxBodyBytes: 64 * 1024,
+		},
+		AccessLog: AccessLogConfig{
+			BatchSize:        100,
+			QueueSize:        10000,
+			FlushIntervalStr: "1s",
+			TimeoutStr:       "5s",
+			MaxRetries:       3,
+			MaxBackups:       5,
+		},
+	}
+
+	file, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(file, config)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(file)
+	config.Hash = hex.EncodeToString(sum[:])
+
+	// Локаль применяется сразу, чтобы дальнейшие сообщения валидации выводились на выбранном языке
+	config.Locale = strings.ToLower(strings.TrimSpace(config.Locale))
+	if config.Locale == "" {
+		config.Locale = i18n.DefaultLocale
+	}
+	if err := i18n.SetLocale(config.Locale); err != nil {
+		return nil, err
+	}
+
+	// Уровень логов применяется при запуске и при перечитывании, если он изменился
+	config.LogLevel = i18n.DefaultLevel
+	levelStr := config.LogLevelStr
+	if config.Logging.LevelStr != "" {
+		levelStr = config.Logging.LevelStr
+	}
+	if levelStr != "" {
+		level, err := i18n.ParseLevel(levelStr)
+		if err != nil {
+			return nil, err
+		}
+		config.LogLevel = level
+	}
+	if err := config.Logging.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseBackendServers(); err != nil {
+		return nil, err
+	}
+
+	// Валидация алгоритма балансировки
+	config.LoadBalancingAlgorithm = strings.ToLower(config.LoadBalancingAlgorithm)
+	if config.LoadBalancingAlgorithm != "round_robin" && config.LoadBalancingAlgorithm != "random" &&
+		config.LoadBalancingAlgorithm != "consistent_hash" {
+		return nil, i18n.Errorf(i18n.ConfigUnknownAlgorithm, config.LoadBalancingAlgorithm)
+	}
+	i18n.Logf(i18n.ConfigAlgorithm, config.LoadBalancingAlgorithm)
+
+	// Дополнительная валидация
+	if config.RateLimiter.Enabled {
+		if config.RateLimiter.DefaultRate <= 0 {
+			config.RateLimiter.DefaultRate = 1
+			i18n.Logf(i18n.ConfigDefaultRateFixed)
+		}
+		if config.RateLimiter.DefaultCapacity <= 0 {
+			config.RateLimiter.DefaultCapacity = 1
+			i18n.Logf(i18n.ConfigDefaultCapacityFixed)
+		}
+		if config.RateLimiter.DatabasePath == "" {
+			config.RateLimiter.DatabasePath = "./rate_limits.db" // Устанавливаем дефолт, если не указан
+			i18n.Logf(i18n.ConfigDefaultDatabasePath)
+		}
+
+		perTypeDefaults := map[string]float64{
+			"default_rate_ip":         config.RateLimiter.DefaultRateIP,
+			"default_capacity_ip":     config.RateLimiter.DefaultCapacityIP,
+			"default_rate_header":     config.RateLimiter.DefaultRateHeader,
+			"default_capacity_header": config.RateLimiter.DefaultCapacityHeader,
+		}
+		for name, value := range perTypeDefaults {
+			if value < 0 {
+				return nil, i18n.Errorf(i18n.ConfigNegativeRateLimit, name, value)
+			}
+		}
+
+		config.RateLimiter.StoreFailurePolicy = strings.ToLower(config.RateLimiter.StoreFailurePolicy)
+		if config.RateLimiter.StoreFailurePolicy == "" {
+			config.RateLimiter.StoreFailurePolicy = StoreFailOpen
+		}
+		if config.RateLimiter.StoreFailurePolicy != StoreFailOpen && config.RateLimiter.StoreFailurePolicy != StoreFailClosed {
+			return nil, i18n.Errorf(i18n.ConfigUnknownFailurePolicy,
+				config.RateLimiter.StoreFailurePolicy, StoreFailOpen, StoreFailClosed)
+		}
+
+		config.RateLimiter.Algorithm = strings.ToLower(config.RateLimiter.Algorithm)
+		if config.RateLimiter.Algorithm == "" {
+			config.RateLimiter.Algorithm = RateLimitTokenBucket
+		}
+		if config.RateLimiter.Algorithm != RateLimitTokenBucket && config.RateLimiter.Algorithm != RateLimitSlidingWindow {
+			return nil, i18n.Errorf(i18n.ConfigUnknownRateLimitAlgorithm,
+				config.RateLimiter.Algorithm, RateLimitTokenBucket, RateLimitSlidingWindow)
+		}
+
+		if config.RateLimiter.StoreTimeoutStr != "" {
+			storeTimeout, err := time.ParseDuration(config.RateLimiter.StoreTimeoutStr)
+			if err != nil {
+				return nil, i18n.Errorf(i18n.ConfigBadStoreTimeout, config.RateLimiter.StoreTimeoutStr, err)
+			}
+			if storeTimeout < 0 {
+				return nil, i18n.Errorf(i18n.ConfigNegativeStoreTimeout, config.RateLimiter.StoreTimeoutStr)
+			}
+			config.RateLimiter.StoreTimeout = storeTimeout
+		}
+
+		if config.RateLimiter.DenialCacheTTLStr != "" {
+			ttl, err := time.ParseDuration(config.RateLimiter.DenialCacheTTLStr)
+			if err != nil || ttl < 0 {
+				return nil, i18n.Errorf(i18n.ConfigBadDenialCacheTTL, config.RateLimiter.DenialCacheTTLStr)
+			}
+			config.RateLimiter.DenialCacheTTL = ttl
+		}
+
+		if config.RateLimiter.LimitDecreaseWindowStr != "" {
+			window, err := time.ParseDuration(config.RateLimiter.LimitDecreaseWindowStr)
+			if err != nil || window < 0 {
+				return nil, i18n.Errorf(i18n.ConfigBadLimitDecreaseWindow, config.RateLimiter.LimitDecreaseWindowStr)
+			}
+			config.RateLimiter.LimitDecreaseWindow = window
+		}
+
+		if config.RateLimiter.IdleBucketTTLStr != "" {
+			ttl, err := time.ParseDuration(config.RateLimiter.IdleBucketTTLStr)
+			if err != nil || ttl < 0 {
+				return nil, i18n.Errorf(i18n.ConfigBadIdleBucketTTL, config.RateLimiter.IdleBucketTTLStr)
+			}
+			config.RateLimiter.IdleBucketTTL = ttl
+		}
+		if config.RateLimiter.MaxBuckets < 0 {
+			return nil, i18n.Errorf(i18n.ConfigBadMaxBuckets, config.RateLimiter.MaxBuckets)
+		}
+		if config.RateLimiter.MaxInFlightPerClient < 0 {
+			return nil, i18n.Errorf(i18n.ConfigBadMaxInFlightPerClient, config.RateLimiter.MaxInFlightPerClient)
+		}
+		if err := config.RateLimiter.Global.validate(); err != nil {
+			return nil, err
+		}
+		if config.RateLimiter.APIKeys.Enabled {
+			if err := config.RateLimiter.APIKeys.validate(config.RateLimiter.IdentifierHeader); err != nil {
+				return nil, err
+			}
+		}
+
+		if config.RateLimiter.SoftLimitRatio < 0 || config.RateLimiter.SoftLimitRatio >= 1 {
+			return nil, i18n.Errorf(i18n.ConfigBadSoftLimitRatio, config.RateLimiter.SoftLimitRatio)
+		}
+
+		if config.RateLimiter.IPv6PrefixLength < 0 || config.RateLimiter.IPv6PrefixLength > 128 {
+			return nil, i18n.Errorf(i18n.ConfigBadIPv6PrefixLength, config.RateLimiter.IPv6PrefixLength)
+		}
+
+		prefixes, err := parseAddrPrefixes(config.RateLimiter.TrustedProxies, i18n.ConfigBadTrustedProxy)
+		if err != nil {
+			return nil, err
+		}
+		config.RateLimiter.TrustedProxyPrefixes = prefixes
+
+		if config.RateLimiter.KeyTemplate != "" {
+			parts, err := ParseKeyTemplate(config.RateLimiter.KeyTemplate)
+			if err != nil {
+				return nil, err
+			}
+			config.RateLimiter.KeyParts = parts
+		}
+
+		if config.RateLimiter.Gossip.Enabled() || len(config.RateLimiter.Gossip.Peers) > 0 {
+			if err := config.RateLimiter.Gossip.validate(); err != nil {
+				return nil, err
+			}
+		}
+
+		for name, template := range config.RateLimiter.Templates {
+			if template.Template != "" {
+				return nil, i18n.Errorf(i18n.ConfigNestedLimitTemplate, name)
+			}
+			if template.Rate <= 0 || template.Capacity <= 0 {
+				return nil, i18n.Errorf(i18n.ConfigBadLimitTemplate, name)
+			}
+		}
+
+		for clientID, limit := range config.RateLimiter.Clients {
+			if clientID == "" {
+				return nil, i18n.Errorf(i18n.ConfigEmptyClientID)
+			}
+			if limit.Template != "" {
+				template, ok := config.RateLimiter.Templates[limit.Template]
+				if !ok {
+					return nil, i18n.Errorf(i18n.ConfigUnknownLimitTemplate, clientID, limit.Template)
+				}
+				if limit.Rate == 0 {
+					limit.Rate = template.Rate
+				}
+				if limit.Capacity == 0 {
+					limit.Capacity = template.Capacity
+				}
+				config.RateLimiter.Clients[clientID] = limit
+			}
+			if limit.Rate <= 0 || limit.Capacity <= 0 {
+				return nil, i18n.Errorf(i18n.ConfigBadClientLimit, clientID)
+			}
+		}
+	}
+
+	// Парсим интервал и таймаут HealthCheck, если включено
+	if config.HealthCheck.Enabled {
+		if err := config.HealthCheck.Parse(); err != nil {
+			return nil, err
+		}
+
+		i18n.Logf(i18n.ConfigHealthChecksOn,
+			config.HealthCheck.Interval, config.HealthCheck.Timeout, config.HealthCheck.Path)
+	} else {
+		i18n.Logf(i18n.ConfigHealthChecksOff)
+	}
+
+	// Парсим параметры алертинга, если включен
+	if config.Alerts.Enabled {
+		durations := []struct {
+			name  string
+			value string
+			dest  *time.Duration
+		}{
+			{"alerts.check_interval", config.Alerts.CheckIntervalStr, &config.Alerts.CheckInterval},
+			{"alerts.rejection_window", config.Alerts.RejectionWindowStr, &config.Alerts.RejectionWindow},
+			{"alerts.all_backends_down_for", config.Alerts.AllBackendsDownForStr, &config.Alerts.AllBackendsDownFor},
+		}
+		for _, d := range durations {
+			parsed, err := time.ParseDuration(d.value)
+			if err != nil {
+				return nil, i18n.Errorf(i18n.ConfigBadDuration, d.name, d.value, err)
+			}
+			if parsed <= 0 {
+				return nil, i18n.Errorf(i18n.ConfigNonPositiveDuration, d.name, d.value)
+			}
+			*d.dest = parsed
+		}
+		if config.Alerts.RejectionRateThreshold <= 0 || config.Alerts.RejectionRateThreshold > 1 {
+			return nil, i18n.Errorf(i18n.ConfigBadRejectionThreshold, config.Alerts.RejectionRateThreshold)
+		}
+		if config.Alerts.MinRequests < 0 {
+			return nil, i18n.Errorf(i18n.ConfigNegativeMinRequests, config.Alerts.MinRequests)
+		}
+	}
+
+	config.Headers.AcceptEncoding = strings.ToLower(config.Headers.AcceptEncoding)
+	if config.Headers.AcceptEncoding == "" {
+		config.Headers.AcceptEncoding = AcceptEncodingPass
+	}
+	if !validAcceptEncoding(config.Headers.AcceptEncoding) {
+		return nil, i18n.Errorf(i18n.ConfigBadAcceptEncoding, "headers.accept_encoding", config.Headers.AcceptEncoding)
+	}
+
+	// Валидация маршрутов
+	seenPrefixes := make(map[string]bool, len(config.Routes))
+	for i := range config.Routes {
+		route := &config.Routes[i]
+		if route.PathPrefix == "" || route.PathPrefix[0] != '/' {
+			return nil, i18n.Errorf(i18n.ConfigBadRoutePrefix, i, route.PathPrefix)
+		}
+		if seenPrefixes[route.PathPrefix] {
+			return nil, i18n.Errorf(i18n.ConfigDuplicateRoutePrefix, i, route.PathPrefix)
+		}
+		seenPrefixes[route.PathPrefix] = true
+		route.Headers.AcceptEncoding = strings.ToLower(route.Headers.AcceptEncoding)
+		if route.Headers.AcceptEncoding != "" && !validAcceptEncoding(route.Headers.AcceptEncoding) {
+			return nil, i18n.Errorf(i18n.ConfigBadAcceptEncoding, fmt.Sprintf("routes[%d].headers.accept_encoding", i), route.Headers.AcceptEncoding)
+		}
+		if _, ok := route.BackendSelector[""]; ok {
+			return nil, i18n.Errorf(i18n.ConfigEmptyLabel, fmt.Sprintf("routes[%d].backend_selector", i))
+		}
+		if route.HasFlushInterval() {
+			if route.FlushIntervalStr == "-1" {
+				route.FlushInterval = -1
+			} else {
+				flushInterval, err := time.ParseDuration(route.FlushIntervalStr)
+				if err != nil || flushInterval < 0 {
+					return nil, i18n.Errorf(i18n.ConfigBadFlushInterval, i, route.FlushIntervalStr)
+				}
+				route.FlushInterval = flushInterval
+			}
+		}
+		if route.HasBackendTimeout() {
+			timeout, err := parseOptionalTimeout(fmt.Sprintf("routes[%d].backend_timeout", i), route.BackendTimeoutStr)
+			if err != nil {
+				return nil, err
+			}
+			route.BackendTimeout = timeout
+		}
+		if rv := route.ResponseValidation; rv != nil {
+			for j, contentType := range rv.ContentTypes {
+				contentType = strings.ToLower(strings.TrimSpace(contentType))
+				if contentType == "" || strings.Count(contentType, "/") != 1 {
+					return nil, i18n.Errorf(i18n.ConfigBadContentType, fmt.Sprintf("routes[%d].response_validation.content_types[%d]", i, j), rv.ContentTypes[j])
+				}
+				rv.ContentTypes[j] = contentType
+			}
+		}
+	}
+
+	if err := config.PassiveHealth.parse(); err != nil {
+		return nil, err
+	}
+	// Вес бэкенда не влияет на выбор в consistent_hash, поэтому плавный возврат невозможен
+	if config.PassiveHealth.SlowStart > 0 && config.LoadBalancingAlgorithm == "consistent_hash" {
+		return nil, i18n.Errorf(i18n.ConfigSlowStartWithHash, "passive_health")
+	}
+	if err := config.parseBackendPassiveHealth(); err != nil {
+		return nil, err
+	}
+
+	if config.Readiness.InFlightRatio == 0 {
+		config.Readiness.InFlightRatio = DefaultReadinessInFlightRatio
+	}
+	if config.Readiness.InFlightRatio < 0 || config.Readiness.InFlightRatio > 1 {
+		return nil, i18n.Errorf(i18n.ConfigBadReadinessInFlightRatio, config.Readiness.InFlightRatio)
+	}
+	config.Readiness.StoreTimeout = DefaultReadinessStoreTimeout
+	if config.Readiness.StoreTimeoutStr != "" {
+		timeout, err := time.ParseDuration(config.Readiness.StoreTimeoutStr)
+		if err != nil || timeout <= 0 {
+			return nil, i18n.Errorf(i18n.ConfigBadReadinessStoreTimeout, config.Readiness.StoreTimeoutStr)
+		}
+		config.Readiness.StoreTimeout = timeout
+	}
+
+	if err := config.Metrics.validate(); err != nil {
+		return nil, err
+	}
+	if err := config.AdminAuth.validate(); err != nil {
+		return nil, err
+	}
+	if config.Tracing.Enabled {
+		if err := config.Tracing.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.Timeouts.validate(); err != nil {
+		return nil, err
+	}
+
+	if config.RequestAge.MaxAgeStr != "" {
+		maxAge, err := time.ParseDuration(config.RequestAge.MaxAgeStr)
+		if err != nil || maxAge <= 0 {
+			return nil, i18n.Errorf(i18n.ConfigBadRequestMaxAge, config.RequestAge.MaxAgeStr)
+		}
+		config.RequestAge.MaxAge = maxAge
+	}
+
+	if err := config.AdmissionHook.validate(); err != nil {
+		return nil, err
+	}
+
+	config.StatsHistory.Window = DefaultStatsHistoryWindow
+	if config.StatsHistory.WindowStr != "" {
+		window, err := time.ParseDuration(config.StatsHistory.WindowStr)
+		if err != nil || window < time.Second || window > MaxStatsHistoryWindow {
+			return nil, i18n.Errorf(i18n.ConfigBadStatsHistoryWindow, config.StatsHistory.WindowStr, MaxStatsHistoryWindow)
+		}
+		config.StatsHistory.Window = window
+	}
+
+	if config.StickySessions.Enabled {
+		if config.StickySessions.CookieName == "" {
+			config.StickySessions.CookieName = DefaultStickyCookieName
+		}
+		if err := (&http.Cookie{Name: config.StickySessions.CookieName, Value: "x"}).Valid(); err != nil {
+			return nil, i18n.Errorf(i18n.ConfigBadStickyCookieName, config.StickySessions.CookieName, err)
+		}
+		if config.StickySessions.TTLStr != "" {
+			ttl, err := time.ParseDuration(config.StickySessions.TTLStr)
+			if err != nil || ttl <= 0 {
+				return nil, i18n.Errorf(i18n.ConfigBadStickyTTL, config.StickySessions.TTLStr)
+			}
+			config.StickySessions.TTL = ttl
+		}
+		if config.StickySessions.Secret == "" {
+			i18n.Logf(i18n.ConfigStickyRandomSecret)
+		}
+	}
+
+	if err := config.UpstreamThrottling.validate(config.LoadBalancingAlgorithm, config.TLS.Sites); err != nil {
+		return nil, err
+	}
+	for pool, auth := range config.UpstreamAuth {
+		if !knownPools(config.TLS.Sites)[pool] {
+			return nil, i18n.Errorf(i18n.ConfigUnknownPool, "upstream_auth", pool)
+		}
+		if err := auth.validate("upstream_auth." + pool); err != nil {
+			return nil, err
+		}
+		config.UpstreamAuth[pool] = auth
+	}
+	for pool, dns := range config.DNS {
+		if !knownPools(config.TLS.Sites)[pool] {
+			return nil, i18n.Errorf(i18n.ConfigUnknownPool, "dns", pool)
+		}
+		if err := dns.validate("dns." + pool); err != nil {
+			return nil, err
+		}
+		config.DNS[pool] = dns
+	}
+	for pool, backendTLS := range config.BackendTLS {
+		if !knownPools(config.TLS.Sites)[pool] {
+			return nil, i18n.Errorf(i18n.ConfigUnknownPool, "backend_tls", pool)
+		}
+		if err := backendTLS.validate("backend_tls." + pool); err != nil {
+			return nil, err
+		}
+	}
+	for pool, canary := range config.Canary {
+		if !knownPools(config.TLS.Sites)[pool] {
+			return nil, i18n.Errorf(i18n.ConfigUnknownPool, "canary", pool)
+		}
+		if err := canary.validate("canary."+pool, config.LoadBalancingAlgorithm); err != nil {
+			return nil, err
+		}
+		config.Canary[pool] = canary
+	}
+
+	// Валидация списков доступа
+	accessLists := []struct {
+		name    string
+		entries []string
+	}{
+		{AccessListDeny, config.AccessList.Deny},
+		{AccessListAllow, config.AccessList.Allow},
+	}
+	for _, list := range accessLists {
+		for i, entry := range list.entries {
+			if err := ValidateAccessEntry(entry); err != nil {
+				return nil, i18n.Errorf(i18n.ConfigBadAccessEntry, fmt.Sprintf("access_list.%s[%d]", list.name, i), entry, err)
+			}
+		}
+	}
+
+	// Метки можно задать только бэкендам, которые есть в одном из пулов
+	knownBackends := make(map[string]bool)
+	for _, pool := range [][]string{config.BackendServers, config.Spillover.BackendServers} {
+		for _, backend := range pool {
+			knownBackends[backend] = true
+		}
+	}
+	for _, site := range config.TLS.Sites {
+		for _, backend := range site.BackendServers {
+			knownBackends[backend] = true
+		}
+	}
+	for backend, labels := range config.BackendLabels {
+		if !knownBackends[backend] {
+			return nil, i18n.Errorf(i18n.ConfigUnknownLabeledBackend, backend)
+		}
+		if _, ok := labels[""]; ok {
+			return nil, i18n.Errorf(i18n.ConfigEmptyLabel, "backend_labels."+backend)
+		}
+	}
+	for backend, maxConnections := range config.BackendMaxConnections {
+		if !knownBackends[backend] {
+			return nil, i18n.Errorf(i18n.ConfigUnknownLimitedBackend, backend)
+		}
+		if maxConnections <= 0 {
+			return nil, i18n.Errorf(i18n.ConfigBadMaxConnections, backend, maxConnections)
+		}
+	}
+	if config.SaturationThreshold == 0 {
+		config.SaturationThreshold = DefaultSaturationThreshold
+	}
+	if config.SaturationThreshold < 0 || config.SaturationThreshold > 1 {
+		return nil, i18n.Errorf(i18n.ConfigBadSaturationThreshold, config.SaturationThreshold)
+	}
+
+	if err := normalizeBindAddress("bind_address", &config.BindAddress); err != nil {
+		return nil, err
+	}
+
+	if config.TLS.Enabled {
+		if err := config.TLS.validate(config.BindAddress, config.Port); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.UDP.Enabled {
+		if err := config.UDP.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.ForwardProxy.Enabled {
+		if err := config.ForwardProxy.validate(config.BindAddress, config.Port); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.ConnectionLimit.MaxPerIP < 0 {
+		return nil, i18n.Errorf(i18n.ConfigBadMaxConnectionsPerIP, config.ConnectionLimit.MaxPerIP)
+	}
+	if err := config.StorageMaintenance.validate(); err != nil {
+		return nil, err
+	}
+	if err := config.SQLite.validate(); err != nil {
+		return nil, err
+	}
+	if config.Concurrency.Enabled {
+		if err := config.Concurrency.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Spillover.Enabled {
+		if err := config.Spillover.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Capture.BufferSize < 1 {
+		return nil, i18n.Errorf(i18n.ConfigCaptureBadBufferSize, config.Capture.BufferSize)
+	}
+	if config.Capture.MaxBodyBytes < 0 {
+		return nil, i18n.Errorf(i18n.ConfigCaptureBadMaxBody, config.Capture.MaxBodyBytes)
+	}
+
+	if config.AccessLog.Enabled {
+		if err := config.AccessLog.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// validAcceptEncoding проверяет режим обработки Accept-Encoding.
+func validAcceptEncoding(mode string) bool {
+	return mode == AcceptEncodingPass || mode == AcceptEncodingStrip || mode == AcceptEncodingIdentity
+}
+
+// validate проверяет секцию tls и приводит имена серверов к нижнему регистру.
+func (tc *TLSConfig) validate(httpBind, httpPort string) error {
+	if tc.Port == "" {
+		return i18n.Errorf(i18n.ConfigTLSNoPort)
+	}
+	if err := normalizeBindAddress("tls.bind_address", &tc.BindAddress); err != nil {
+		return err
+	}
+	if listenersConflict(tc.BindAddress, tc.Port, httpBind, httpPort) {
+		return i18n.Errorf(i18n.ConfigTLSSamePort, tc.Port)
+	}
+	if (tc.CertFile == "") != (tc.KeyFile == "") {
+		return i18n.Errorf(i18n.ConfigTLSNoDefaultCertificate)
+	}
+	if len(tc.Sites) == 0 && tc.CertFile == "" {
+		return i18n.Errorf(i18n.ConfigTLSNoSites)
+	}
+	switch tc.MinVersionStr {
+	case "", "1.2":
+		tc.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tc.MinVersion = tls.VersionTLS13
+	default:
+		return i18n.Errorf(i18n.ConfigTLSBadMinVersion, tc.MinVersionStr)
+	}
+	if len(tc.CipherSuites) > 0 && tc.MinVersion == tls.VersionTLS13 {
+		return i18n.Errorf(i18n.ConfigTLSCipherSuitesWithTLS13)
+	}
+	tc.CipherSuiteIDs = nil
+	for _, name := range tc.CipherSuites {
+		id, ok := cipherSuiteID(name)
+		if !ok {
+			return i18n.Errorf(i18n.ConfigTLSBadCipherSuite, name)
+		}
+		tc.CipherSuiteIDs = append(tc.CipherSuiteIDs, id)
+	}
+	if err := tc.ClientAuth.validate(); err != nil {
+		return err
+	}
+
+	seenNames := make(map[string]bool)
+	for i := range tc.Sites {
+		site := &tc.Sites[i]
+		if len(site.ServerNames) == 0 {
+			return i18n.Errorf(i18n.ConfigTLSNoServerNames, i)
+		}
+		if site.CertFile == "" || site.KeyFile == "" {
+			return i18n.Errorf(i18n.ConfigTLSNoCertificate, i)
+		}
+		for j, name := range site.ServerNames {
+			name = strings.ToLower(strings.TrimSpace(name))
+			// Шаблон допускается только целым первым сегментом: "*.example.com"
+			if name == "" || strings.Contains(strings.TrimPrefix(name, "*."), "*") {
+				return i18n.Errorf(i18n.ConfigTLSBadServerName, i, site.ServerNames[j])
+			}
+			if seenNames[name] {
+				return i18n.Errorf(i18n.ConfigTLSDuplicateServerName, i, name)
+			}
+			seenNames[name] = true
+			site.ServerNames[j] = name
+		}
+	}
+	return nil
+}
+
+// validate проверяет секцию tls.client_auth и определяет режим проверки сертификатов.
+func (ca *TLSClientAuthConfig) validate() error {
+	switch ca.Mode {
+	case "", TLSClientAuthNone:
+		ca.Type = tls.NoClientCert
+		if ca.SubjectHeader != "" {
+			return i18n.Errorf(i18n.ConfigTLSSubjectHeaderWithoutAuth)
+		}
+		return nil
+	case TLSClientAuthOptional:
+		ca.Type = tls.VerifyClientCertIfGiven
+	case TLSClientAuthRequire:
+		ca.Type = tls.RequireAndVerifyClientCert
+	default:
+		return i18n.Errorf(i18n.ConfigTLSBadClientAuthMode, ca.Mode)
+	}
+	if ca.CAFile == "" {
+		return i18n.Errorf(i18n.ConfigTLSClientAuthNoCA)
+	}
+	return nil
+}
+
+// cipherSuiteID возвращает ID набора шифров TLS 1.2 по имени; небезопасные наборы (tls.InsecureCipherSuites)
+// и наборы только для TLS 1.3 не допускаются.
+func cipherSuiteID(name string) (uint16, bool) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name && slices.Contains(suite.SupportedVersions, tls.VersionTLS12) {
+			return suite.ID, true
+		}
+	}
+	return 0, false
+}
+
+// validate проверяет секцию udp и разбирает длительности.
+func (gc *GossipConfig) validate() error {
+	if gc.Listen == "" {
+		return i18n.Errorf(i18n.ConfigGossipNoListen)
+	}
+	if _, _, err := net.SplitHostPort(gc.Listen); err != nil {
+		return i18n.Errorf(i18n.ConfigGossipBadListen, gc.Listen, err)
+	}
+	for i, addr := range gc.Peers {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return i18n.Errorf(i18n.ConfigGossipBadPeer, i, addr, err)
+		}
+	}
+	gc.Interval = DefaultGossipInterval
+	if gc.IntervalStr != "" {
+		parsed, err := time.ParseDuration(gc.IntervalStr)
+		if err != nil {
+			return i18n.Errorf(i18n.ConfigBadDuration, "rate_limiter.gossip.interval", gc.IntervalStr, err)
+		}
+		if parsed <= 0 {
+			return i18n.Errorf(i18n.ConfigNonPositiveDuration, "rate_limiter.gossip.interval", gc.IntervalStr)
+		}
+		gc.Interval = parsed
+	}
+	return nil
+}
+
+func (uc *UDPConfig) validate() error {
+	if uc.Listen == "" {
+		return i18n.Errorf(i18n.ConfigUDPNoListen)
+	}
+	if len(uc.Backends) == 0 {
+		return i18n.Errorf(i18n.ConfigUDPNoBackends)
+	}
+	for i, addr := range uc.Backends {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return i18n.Errorf(i18n.ConfigUDPBadBackend, i, addr, err)
+		}
+	}
+	if uc.MaxFails < 1 {
+		return i18n.Errorf(i18n.ConfigUDPBadMaxFails, uc.MaxFails)
+	}
+
+	durations := []struct {
+		name      string
+		value     string
+		dest      *time.Duration
+		allowZero bool
+	}{
+		{"udp.session_timeout", uc.SessionTimeoutStr, &uc.SessionTimeout, false},
+		{"udp.response_timeout", uc.ResponseTimeoutStr, &uc.ResponseTimeout, true},
+		{"udp.fail_timeout", uc.FailTimeoutStr, &uc.FailTimeout, false},
+	}
+	for _, d := range durations {
+		if d.value == "" && d.allowZero {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return i18n.Errorf(i18n.ConfigBadDuration, d.name, d.value, err)
+		}
+		if parsed < 0 || (parsed == 0 && !d.allowZero) {
+			return i18n.Errorf(i18n.ConfigNonPositiveDuration, d.name, d.value)
+		}
+		*d.dest = parsed
+	}
+	return nil
+}
+
+// validate проверяет секцию forward_proxy и разбирает таймаут подключения.
+func (fc *ForwardProxyConfig) validate(httpBind, httpPort string) error {
+	if fc.Port == "" {
+		return i18n.Errorf(i18n.ConfigFPNoPort)
+	}
+	if err := normalizeBindAddress("forward_proxy.bind_address", &fc.BindAddress); err != nil {
+		return err
+	}
+	if listenersConflict(fc.BindAddress, fc.Port, httpBind, httpPort) {
+		return i18n.Errorf(i18n.ConfigFPSamePort, fc.Port)
+	}
+	if len(fc.Gateways) == 0 {
+		return i18n.Errorf(i18n.ConfigFPNoGateways)
+	}
+	dialTimeout, err := time.ParseDuration(fc.DialTimeoutStr)
+	if err != nil {
+		return i18n.Errorf(i18n.ConfigBadDuration, "forward_proxy.dial_timeout", fc.DialTimeoutStr, err)
+	}
+	if dialTimeout <= 0 {
+		return i18n.Errorf(i18n.ConfigNonPositiveDuration, "forward_proxy.dial_timeout", fc.DialTimeoutStr)
+	}
+	fc.DialTimeout = dialTimeout
+	return nil
+}
+
+// normalizeBindAddress проверяет адрес листенера field и убирает квадратные скобки вокруг IPv6 ("[::1]" -> "::1").
+// Допускается зона IPv6 ("fe80::1%eth0"). Пустой адрес означает все интерфейсы.
+func normalizeBindAddress(field string, addr *string) error {
+	if *addr == "" {
+		return nil
+	}
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(*addr), "["), "]")
+	if _, err := netip.ParseAddr(trimmed); err != nil {
+		return i18n.Errorf(i18n.ConfigBadBindAddress, field, *addr)
+	}
+	*addr = trimmed
+	return nil
+}
+
+// listenersConflict сообщает, что два листенера займут один и тот же сокет: порты совпадают,
+// а адреса равны или хотя бы один из них слушает все интерфейсы ("", "0.0.0.0", "::").
+func listenersConflict(bindA, portA, bindB, portB string) bool {
+	if portA != portB {
+		return false
+	}
+	isWildcard := func(bind string) bool {
+		addr, err := netip.ParseAddr(bind)
+		return bind == "" || (err == nil && addr.IsUnspecified())
+	}
+	return bindA == bindB || isWildcard(bindA) || isWildcard(bindB)
+}
+
+// ListenAddr возвращает адрес для net.Listen по адресу интерфейса и порту: ":8080", "127.0.0.1:8080", "[::1]:8080".
+func ListenAddr(bindAddress, port string) string {
+	return net.JoinHostPort(bindAddress, port)
+}
+
+// validate проверяет секцию storage_maintenance и разбирает ее длительности. Длительности разбираются
+// и при выключенном плановом обслуживании: их использует обслуживание, запущенное вручную.
+func (sc *StorageMaintenanceConfig) validate() error {
+	durations := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"storage_maintenance.interval", sc.IntervalStr, &sc.Interval},
+		{"storage_maintenance.health_retention", sc.HealthRetentionStr, &sc.HealthRetention},
+	}
+	for _, d := range durations {
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return i18n.Errorf(i18n.ConfigBadDuration, d.name, d.value, err)
+		}
+		if parsed <= 0 {
+			return i18n.Errorf(i18n.ConfigNonPositiveDuration, d.name, d.value)
+		}
+		*d.dest = parsed
+	}
+	if sc.VacuumPages < 0 {
+		return i18n.Errorf(i18n.ConfigBadVacuumPages, sc.VacuumPages)
+	}
+	return nil
+}
+
+// sqlitePragmaPattern - допустимые имена и значения sqlite.pragmas: они подставляются в текст PRAGMA.
+var sqlitePragmaPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// validate проверяет секцию sqlite и разбирает busy_timeout.
+func (sc *SQLiteConfig) validate() error {
+	sc.JournalMode = strings.ToLower(sc.JournalMode)
+	if !slices.Contains([]string{"wal", "delete", "truncate", "persist", "memory", "off"}, sc.JournalMode) {
+		return i18n.Errorf(i18n.ConfigBadSQLiteJournalMode, sc.JournalMode)
+	}
+	sc.Synchronous = strings.ToLower(sc.Synchronous)
+	if !slices.Contains([]string{"off", "normal", "full", "extra"}, sc.Synchronous) {
+		return i18n.Errorf(i18n.ConfigBadSQLiteSynchronous, sc.Synchronous)
+	}
+	timeout, err := time.ParseDuration(sc.BusyTimeoutStr)
+	if err != nil {
+		return i18n.Errorf(i18n.ConfigBadDuration, "sqlite.busy_timeout", sc.BusyTimeoutStr, err)
+	}
+	if timeout <= 0 {
+		return i18n.Errorf(i18n.ConfigNonPositiveDuration, "sqlite.busy_timeout", sc.BusyTimeoutStr)
+	}
+	sc.BusyTimeout = timeout
+	if sc.MaxOpenConns < 0 {
+		return i18n.Errorf(i18n.ConfigBadSQLiteMaxOpenConns, sc.MaxOpenConns)
+	}
+	for name, value := range sc.Pragmas {
+		switch {
+		case !sqlitePragmaPattern.MatchString(name) || !sqlitePragmaPattern.MatchString(value):
+			return i18n.Errorf(i18n.ConfigBadSQLitePragma, name, value)
+		case slices.Contains([]string{"journal_mode", "busy_timeout", "synchronous"}, strings.ToLower(name)):
+			return i18n.Errorf(i18n.ConfigSQLitePragmaReserved, name)
+		}
+	}
+	return nil
+}
+
+// validate проверяет секцию concurrency и разбирает таймаут очереди.
+func (cc *ConcurrencyConfig) validate() error {
+	if cc.MaxInFlight < 1 {
+		return i18n.Errorf(i18n.ConfigConcurrencyBadMaxInFlight, cc.MaxInFlight)
+	}
+	if cc.MaxQueue < 0 {
+		return i18n.Errorf(i18n.ConfigConcurrencyBadMaxQueue, cc.MaxQueue)
+	}
+	if cc.DefaultPriority < 0 || cc.DefaultPriority > 9 {
+		return i18n.Errorf(i18n.ConfigConcurrencyBadPriority, cc.DefaultPriority)
+	}
+	cc.PriorityHeader = strings.TrimSpace(cc.PriorityHeader)
+	if cc.PriorityHeader == "" {
+		cc.PriorityHeader = "X-Priority"
+	}
+	prefixes, err := parseAddrPrefixes(cc.PriorityTrusted, i18n.ConfigBadPriorityTrusted)
+	if err != nil {
+		return err
+	}
+	cc.PriorityTrustedPrefixes = prefixes
+	queueTimeout, err := time.ParseDuration(cc.QueueTimeoutStr)
+	if err != nil {
+		return i18n.Errorf(i18n.ConfigBadDuration, "concurrency.queue_timeout", cc.QueueTimeoutStr, err)
+	}
+	if queueTimeout <= 0 {
+		return i18n.Errorf(i18n.ConfigNonPositiveDuration, "concurrency.queue_timeout", cc.QueueTimeoutStr)
+	}
+	cc.QueueTimeout = queueTimeout
+	return nil
+}
+
+// validate проверяет секцию spillover.
+func (sc *SpilloverConfig) validate() error {
+	if len(sc.BackendServers) == 0 {
+		return i18n.Errorf(i18n.ConfigSpilloverNoBackends)
+	}
+	if sc.PrimaryMaxInFlight < 0 || sc.PrimaryMaxRPS < 0 {
+		return i18n.Errorf(i18n.ConfigSpilloverNegativeBudget, sc.PrimaryMaxInFlight, sc.PrimaryMaxRPS)
+	}
+	if sc.PrimaryMaxInFlight == 0 && sc.PrimaryMaxRPS == 0 {
+		return i18n.Errorf(i18n.ConfigSpilloverNoBudget)
+	}
+	return nil
+}
+
+// validate проверяет секцию upstream_throttling и разбирает длительность снижения веса.
+// Имена в pools должны совпадать с пулами GET /admin/state; вес не влияет на выбор бэкенда в consistent_hash,
+// поэтому reduce_weight с ним не допускается.
+func (tc *UpstreamThrottlingConfig) validate(algorithm string, sites []TLSSiteConfig) error {
+	known := knownPools(sites)
+	checkPolicy := func(field string, policy *string) error {
+		*policy = strings.ToLower(*policy)
+		switch *policy {
+		case ThrottlePassThrough, ThrottleRetry:
+			return nil
+		case ThrottleReduceWeight:
+			if algorithm == "consistent_hash" {
+				return i18n.Errorf(i18n.ConfigThrottleWeightWithHash, field)
+			}
+			return nil
+		default:
+			return i18n.Errorf(i18n.ConfigUnknownThrottlePolicy, field, *policy, ThrottlePassThrough, ThrottleRetry, ThrottleReduceWeight)
+		}
+	}
+
+	if tc.Policy == "" {
+		tc.Policy = ThrottlePassThrough
+	}
+	if err := checkPolicy("upstream_throttling.policy", &tc.Policy); err != nil {
+		return err
+	}
+	for name, policy := range tc.Pools {
+		if !known[name] {
+			return i18n.Errorf(i18n.ConfigUnknownPool, "upstream_throttling.pools", name)
+		}
+		if err := checkPolicy("upstream_throttling.pools."+name, &policy); err != nil {
+			return err
+		}
+		tc.Pools[name] = policy
+	}
+
+	tc.Penalty = DefaultThrottlePenalty
+	if tc.PenaltyStr != "" {
+		penalty, err := time.ParseDuration(tc.PenaltyStr)
+		if err != nil || penalty <= 0 {
+			return i18n.Errorf(i18n.ConfigBadThrottlePenalty, tc.PenaltyStr)
+		}
+		tc.Penalty = penalty
+	}
+	if tc.WeightPercent == 0 {
+		tc.WeightPercent = DefaultThrottleWeightPercent
+	}
+	if tc.WeightPercent < 1 || tc.WeightPercent > 99 {
+		return i18n.Errorf(i18n.ConfigBadThrottleWeightPercent, tc.WeightPercent)
+	}
+	return nil
+}
+
+// knownPools возвращает имена пулов из GET /admin/state, которые могут быть в конфигурации:
+// основной, резервный и пулы сайтов TLS со своими backend_servers (по первому имени сайта).
+func knownPools(sites []TLSSiteConfig) map[string]bool {
+	known := map[string]bool{"primary": true, "spillover": true}
+	for _, site := range sites {
+		if len(site.BackendServers) > 0 && len(site.ServerNames) > 0 {
+			known["tls:"+site.ServerNames[0]] = true
+		}
+	}
+	return known
+}
+
+// validate проверяет разделение трафика пула canary; field - путь к нему в конфигурации.
+// consistent_hash закрепляет клиентов за бэкендами и не делит запросы в заданной пропорции,
+// поэтому canary с ним не допускается.
+func (cc *CanaryConfig) validate(field, algorithm string) error {
+	if algorithm == "consistent_hash" {
+		return i18n.Errorf(i18n.ConfigCanaryWithHash, field)
+	}
+	if len(cc.Selector) == 0 {
+		return i18n.Errorf(i18n.ConfigCanaryNoSelector, field+".selector")
+	}
+	if _, ok := cc.Selector[""]; ok {
+		return i18n.Errorf(i18n.ConfigEmptyLabel, field+".selector")
+	}
+	if cc.Percent < 1 || cc.Percent > 99 {
+		return i18n.Errorf(i18n.ConfigBadCanaryPercent, field+".percent", cc.Percent)
+	}
+
+	cc.Window = DefaultCanaryWindow
+	if cc.WindowStr != "" {
+		window, err := time.ParseDuration(cc.WindowStr)
+		if err != nil || window < time.Second || window > MaxCanaryWindow {
+			return i18n.Errorf(i18n.ConfigBadCanaryWindow, field+".window", cc.WindowStr, MaxCanaryWindow)
+		}
+		cc.Window = window
+	}
+	if cc.MinRequests < 0 {
+		return i18n.Errorf(i18n.ConfigBadCanaryMinRequests, field+".min_requests", cc.MinRequests)
+	}
+	if cc.MinRequests == 0 {
+		cc.MinRequests = DefaultCanaryMinRequests
+	}
+	if cc.MaxErrorRateDelta < 0 || cc.MaxErrorRateDelta > 1 {
+		return i18n.Errorf(i18n.ConfigBadCanaryErrorRateDelta, field+".max_error_rate_delta", cc.MaxErrorRateDelta)
+	}
+	if cc.MaxLatencyRatio != 0 && cc.MaxLatencyRatio < 1 {
+		return i18n.Errorf(i18n.ConfigBadCanaryLatencyRatio, field+".max_latency_ratio", cc.MaxLatencyRatio)
+	}
+	if cc.AutoRollback && cc.MaxErrorRateDelta == 0 && cc.MaxLatencyRatio == 0 {
+		return i18n.Errorf(i18n.ConfigCanaryNoThresholds, field+".auto_rollback")
+	}
+	return nil
+}
+
+// validate проверяет учетные данные пула upstream_auth; field - путь к ним в конфигурации.
+func (ac *UpstreamAuthConfig) validate(field string) error {
+	ac.Type = strings.ToLower(ac.Type)
+	var name, value string
+	switch ac.Type {
+	case UpstreamAuthBearer:
+		name, value = "token", ac.Token
+	case UpstreamAuthBasic:
+		name, value = "username", ac.Username
+	case UpstreamAuthHMAC:
+		name, value = "secret", ac.Secret
+	default:
+		return i18n.Errorf(i18n.ConfigUnknownUpstreamAuthType, field, ac.Type, UpstreamAuthBearer, UpstreamAuthBasic, UpstreamAuthHMAC)
+	}
+	if value == "" {
+		return i18n.Errorf(i18n.ConfigUpstreamAuthMissing, field+"."+name, ac.Type)
+	}
+	if ac.Header == "" {
+		ac.Header = "Authorization"
+	}
+	ac.Header = http.CanonicalHeaderKey(ac.Header)
+	return nil
+}
+
+// validate проверяет секцию access_log и разбирает интервалы.
+func (ac *AccessLogConfig) validate() error {
+	ac.Sink = strings.ToLower(ac.Sink)
+	switch ac.Sink {
+	case AccessLogSinkSyslog:
+		scheme, _, ok := strings.Cut(ac.Address, "://")
+		if !ok || (scheme != "udp" && scheme != "tcp") {
+			return i18n.Errorf(i18n.ConfigAccessLogBadAddress, ac.Sink, ac.Address)
+		}
+	case AccessLogSinkHTTP, AccessLogSinkKafka:
+		if !strings.HasPrefix(ac.Address, "http://") && !strings.HasPrefix(ac.Address, "https://") {
+			return i18n.Errorf(i18n.ConfigAccessLogBadAddress, ac.Sink, ac.Address)
+		}
+		if ac.Sink == AccessLogSinkKafka && ac.Topic == "" {
+			return i18n.Errorf(i18n.ConfigAccessLogNoTopic)
+		}
+	case AccessLogSinkFile:
+		if ac.Address == "" {
+			return i18n.Errorf(i18n.ConfigAccessLogBadAddress, ac.Sink, ac.Address)
+		}
+		ac.Format = strings.ToLower(ac.Format)
+		switch ac.Format {
+		case "":
+			ac.Format = AccessLogFormatJSON
+		case AccessLogFormatJSON, AccessLogFormatCommon:
+		default:
+			return i18n.Errorf(i18n.ConfigAccessLogBadFormat, ac.Format, AccessLogFormatJSON, AccessLogFormatCommon)
+		}
+		if ac.MaxSizeMB < 0 {
+			return i18n.Errorf(i18n.ConfigAccessLogBadMaxSize, ac.MaxSizeMB)
+		}
+		if ac.MaxSizeMB > 0 && ac.MaxBackups < 1 {
+			return i18n.Errorf(i18n.ConfigAccessLogBadMaxBackups, ac.MaxBackups)
+		}
+	default:
+		return i18n.Errorf(i18n.ConfigAccessLogBadSink, ac.Sink, AccessLogSinkSyslog, AccessLogSinkHTTP, AccessLogSinkKafka, AccessLogSinkFile)
+	}
+	if ac.BatchSize < 1 {
+		return i18n.Errorf(i18n.ConfigAccessLogBadBatchSize, ac.BatchSize)
+	}
+	if ac.QueueSize < ac.BatchSize {
+		return i18n.Errorf(i18n.ConfigAccessLogBadQueueSize, ac.QueueSize, ac.BatchSize)
+	}
+	if ac.MaxRetries < 0 {
+		return i18n.Errorf(i18n.ConfigAccessLogBadRetries, ac.MaxRetries)
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"access_log.flush_interval", ac.FlushIntervalStr, &ac.FlushInterval},
+		{"access_log.timeout", ac.TimeoutStr, &ac.Timeout},
+	} {
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return i18n.Errorf(i18n.ConfigBadDuration, d.name, d.value, err)
+		}
+		if parsed <= 0 {
+			return i18n.Errorf(i18n.ConfigNonPositiveDuration, d.name, d.value)
+		}
+		*d.dest = parsed
+	}
+	return nil
+}
+
+// parseAddrPrefixes разбирает список адресов и подсетей CIDR (например, rate_limiter.trusted_proxies);
+// bad - сообщение об ошибке с номером и значением некорректной записи.
+func parseAddrPrefixes(entries []string, bad i18n.ID) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for i, entry := range entries {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, i18n.Errorf(bad, i, entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// ValidateAccessEntry проверяет запись списка доступа: непустое значение без пробелов;
+// значение с "/" должно быть подсетью CIDR.
+func ValidateAccessEntry(entry string) error {
+	if entry == "" || strings.TrimSpace(entry) != entry {
+		return i18n.Errorf(i18n.ConfigEmptyAccessEntry)
+	}
+	if strings.Contains(entry, "/") {
+		if _, err := netip.ParsePrefix(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate проверяет настройки TLS пула; field - путь к ним в конфигурации.
+func (bc *BackendTLSConfig) validate(field string) error {
+	if (bc.CertFile == "") != (bc.KeyFile == "") {
+		return i18n.Errorf(i18n.ConfigBackendTLSNoKeyPair, field)
+	}
+	if bc.InsecureSkipVerify && bc.CAFile != "" {
+		return i18n.Errorf(i18n.ConfigBackendTLSSkipVerifyWithCA, field)
+	}
+	return nil
+}
+
+// validate проверяет настройки разрешения имен пула и дописывает порт 53 к адресам серверов;
+// field - путь к ним в конфигурации.
+func (dc *DNSConfig) validate(field string) error {
+	for i, server := range dc.Servers {
+		if addr, err := netip.ParseAddr(server); err == nil {
+			dc.Servers[i] = netip.AddrPortFrom(addr, 53).String()
+			continue
+		}
+		if _, err := netip.ParseAddrPort(server); err != nil {
+			return i18n.Errorf(i18n.ConfigBadDNSServer, field+".servers", server)
+		}
+	}
+
+	dc.Timeout = DefaultDNSTimeout
+	if dc.TimeoutStr != "" {
+		timeout, err := time.ParseDuration(dc.TimeoutStr)
+		if err != nil || timeout <= 0 {
+			return i18n.Errorf(i18n.ConfigBadDNSDuration, field+".timeout", dc.TimeoutStr)
+		}
+		dc.Timeout = timeout
+	}
+	if dc.CacheTTLStr != "" {
+		ttl, err := time.ParseDuration(dc.CacheTTLStr)
+		if err != nil || ttl < 0 {
+			return i18n.Errorf(i18n.ConfigBadDNSDuration, field+".cache_ttl", dc.CacheTTLStr)
+		}
+		dc.CacheTTL = ttl
+	}
+	return nil
 }
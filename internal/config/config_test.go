@@ -1,6 +1,8 @@
 package config_test
 
 import (
+	"crypto/tls"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"testing"
@@ -10,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
 )
 
 // TestLoadConfig_Success проверяет успешную загрузку валидного конфига.
@@ -140,6 +143,81 @@ health_check:
 	assert.ErrorContains(t, err, "неверный формат интервала HealthCheck", "Текст ошибки не содержит ожидаемую подстроку")
 }
 
+// TestLoadConfig_HealthCheckLogRepeatEvery проверяет значение по умолчанию и валидацию log_repeat_every.
+func TestLoadConfig_HealthCheckLogRepeatEvery(t *testing.T) {
+	hc := config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s"}
+	require.NoError(t, hc.Parse())
+	assert.Equal(t, 10, hc.LogRepeatEvery)
+
+	hc = config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s", LogRepeatEvery: 1}
+	require.NoError(t, hc.Parse())
+	assert.Equal(t, 1, hc.LogRepeatEvery)
+
+	hc = config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s", LogRepeatEvery: -1}
+	assert.Error(t, hc.Parse())
+}
+
+// TestLoadConfig_HealthCheckType проверяет тип проверки по умолчанию и отклонение неизвестного типа.
+func TestLoadConfig_HealthCheckType(t *testing.T) {
+	hc := config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s"}
+	require.NoError(t, hc.Parse())
+	assert.Equal(t, config.HealthCheckHTTP, hc.Type)
+
+	hc = config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s", Type: config.HealthCheckTCP}
+	require.NoError(t, hc.Parse())
+	assert.Equal(t, config.HealthCheckTCP, hc.Type)
+
+	hc = config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s", Type: config.HealthCheckGRPC, GRPCService: "orders.v1.Orders"}
+	require.NoError(t, hc.Parse())
+	assert.Equal(t, config.HealthCheckGRPC, hc.Type)
+
+	hc = config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s", Type: "udp"}
+	assert.ErrorContains(t, hc.Parse(), "udp")
+}
+
+// TestLoadConfig_HealthCheckJitter проверяет разбор health_check.jitter и его границы.
+func TestLoadConfig_HealthCheckJitter(t *testing.T) {
+	hc := config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s"}
+	require.NoError(t, hc.Parse())
+	assert.Zero(t, hc.Jitter)
+	assert.False(t, hc.Spread())
+
+	hc = config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s", JitterStr: "3s"}
+	require.NoError(t, hc.Parse())
+	assert.Equal(t, 3*time.Second, hc.Jitter)
+	assert.True(t, hc.Spread())
+
+	for _, jitter := range []string{"10s", "-1s", "soon"} {
+		hc = config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s", JitterStr: jitter}
+		assert.Error(t, hc.Parse(), jitter)
+	}
+}
+
+// TestLoadConfig_HealthCheckExpectations проверяет разбор ожидаемого ответа проверки.
+func TestLoadConfig_HealthCheckExpectations(t *testing.T) {
+	yamlContent := `
+port: "8080"
+backend_servers: ["http://b1"]
+health_check:
+  enabled: true
+  expected_status: [200, 204]
+  expected_body: '"status":"ok"'
+  expected_json: {status: ok, checks.db: up}
+`
+	tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(tmpFile, []byte(yamlContent), 0o644))
+	cfg, err := config.LoadConfig(tmpFile)
+	require.NoError(t, err)
+	assert.Equal(t, []int{200, 204}, cfg.HealthCheck.ExpectedStatus)
+	assert.Equal(t, `"status":"ok"`, cfg.HealthCheck.ExpectedBody)
+	assert.Equal(t, map[string]string{"status": "ok", "checks.db": "up"}, cfg.HealthCheck.ExpectedJSON)
+
+	hc := config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s", ExpectedStatus: []int{200, 99}}
+	assert.ErrorContains(t, hc.Parse(), "99")
+	hc = config.HealthCheckConfig{IntervalStr: "10s", TimeoutStr: "2s", ExpectedJSON: map[string]string{"checks..db": "up"}}
+	assert.ErrorContains(t, hc.Parse(), "checks..db")
+}
+
 // TestLoadConfig_InvalidAlgorithm проверяет ошибку при невалидном алгоритме.
 func TestLoadConfig_InvalidAlgorithm(t *testing.T) {
 	yamlContent := `
@@ -156,3 +234,1329 @@ load_balancing_algorithm: "least_latency"
 	require.Error(t, err, "LoadConfig не вернул ошибку для невалидного алгоритма")
 	assert.ErrorContains(t, err, "неподдерживаемый load_balancing_algorithm")
 }
+
+// TestLoadConfig_StoreFailurePolicy проверяет разбор политики и таймаута хранилища Rate Limiter.
+func TestLoadConfig_StoreFailurePolicy(t *testing.T) {
+	yamlContent := `
+port: "8080"
+backend_servers: ["http://b1"]
+rate_limiter:
+  enabled: true
+  store_failure_policy: "FAIL_CLOSED"
+  store_timeout: "250ms"
+`
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "store_policy.yaml")
+	require.NoError(t, os.WriteFile(tmpFile, []byte(yamlContent), 0o644))
+
+	cfg, err := config.LoadConfig(tmpFile)
+	require.NoError(t, err)
+	assert.Equal(t, config.StoreFailClosed, cfg.RateLimiter.StoreFailurePolicy)
+	assert.Equal(t, 250*time.Millisecond, cfg.RateLimiter.StoreTimeout)
+
+	// Невалидная политика
+	invalidContent := `
+port: "8080"
+backend_servers: ["http://b1"]
+rate_limiter:
+  enabled: true
+  store_failure_policy: "ignore"
+`
+	invalidFile := filepath.Join(tmpDir, "store_policy_invalid.yaml")
+	require.NoError(t, os.WriteFile(invalidFile, []byte(invalidContent), 0o644))
+
+	_, err = config.LoadConfig(invalidFile)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "неподдерживаемый rate_limiter.store_failure_policy")
+}
+
+// TestLoadConfig_SoftLimitRatio проверяет валидацию мягкого порога Rate Limiter.
+func TestLoadConfig_SoftLimitRatio(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  soft_limit_ratio: 0.8\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 0.8, cfg.RateLimiter.SoftLimitRatio)
+
+	for _, ratio := range []string{"-0.1", "1", "1.5"} {
+		_, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  soft_limit_ratio: " + ratio + "\n"))
+		assert.Error(t, err, ratio)
+	}
+}
+
+// TestLoadConfig_RateLimitAlgorithm проверяет выбор алгоритма Rate Limiter.
+func TestLoadConfig_RateLimitAlgorithm(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.RateLimitTokenBucket, cfg.RateLimiter.Algorithm)
+
+	cfg, err = config.LoadConfig(write("rate_limiter:\n  enabled: true\n  algorithm: Sliding_Window\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.RateLimitSlidingWindow, cfg.RateLimiter.Algorithm)
+
+	_, err = config.LoadConfig(write("rate_limiter:\n  enabled: true\n  algorithm: leaky_bucket\n"))
+	assert.ErrorContains(t, err, "неподдерживаемый rate_limiter.algorithm")
+}
+
+// TestLoadConfig_BucketEviction проверяет разбор idle_bucket_ttl и max_buckets.
+func TestLoadConfig_BucketEviction(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  idle_bucket_ttl: 10m\n  max_buckets: 100000\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, cfg.RateLimiter.IdleBucketTTL)
+	assert.Equal(t, 100000, cfg.RateLimiter.MaxBuckets)
+
+	for _, invalid := range []string{"idle_bucket_ttl: soon", "idle_bucket_ttl: -1m", "max_buckets: -1"} {
+		_, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  " + invalid + "\n"))
+		assert.Error(t, err, invalid)
+	}
+}
+
+// TestLoadConfig_MaxInFlightPerClient проверяет разбор лимита одновременных запросов клиента.
+func TestLoadConfig_MaxInFlightPerClient(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  max_in_flight_per_client: 8\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 8, cfg.RateLimiter.MaxInFlightPerClient)
+
+	_, err = config.LoadConfig(write("rate_limiter:\n  enabled: true\n  max_in_flight_per_client: -1\n"))
+	assert.Error(t, err)
+}
+
+// TestLoadConfig_GlobalLimit проверяет общий лимит и емкость по умолчанию.
+func TestLoadConfig_GlobalLimit(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  global:\n    rate: 500\n"))
+	require.NoError(t, err)
+	assert.True(t, cfg.RateLimiter.Global.Enabled())
+	assert.Equal(t, 500.0, cfg.RateLimiter.Global.Capacity, "Емкость по умолчанию - секунда трафика")
+
+	cfg, err = config.LoadConfig(write("rate_limiter:\n  enabled: true\n"))
+	require.NoError(t, err)
+	assert.False(t, cfg.RateLimiter.Global.Enabled())
+
+	_, err = config.LoadConfig(write("rate_limiter:\n  enabled: true\n  global:\n    rate: -1\n"))
+	assert.Error(t, err)
+}
+
+// TestLoadConfig_APIKeys проверяет rate_limiter.api_keys: значения по умолчанию и несовместимость
+// с identifier_header.
+func TestLoadConfig_APIKeys(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  api_keys:\n    enabled: true\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.DefaultAPIKeyHeader, cfg.RateLimiter.APIKeys.Header)
+	assert.Equal(t, config.DefaultAPIKeyCacheTTL, cfg.RateLimiter.APIKeys.CacheTTL)
+
+	cfg, err = config.LoadConfig(write("rate_limiter:\n  enabled: true\n  api_keys:\n    enabled: true\n    header: x-key\n    cache_ttl: 10s\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "X-Key", cfg.RateLimiter.APIKeys.Header)
+	assert.Equal(t, 10*time.Second, cfg.RateLimiter.APIKeys.CacheTTL)
+
+	for _, invalid := range []string{
+		"rate_limiter:\n  enabled: true\n  identifier_header: X-Client-ID\n  api_keys:\n    enabled: true\n",
+		"rate_limiter:\n  enabled: true\n  api_keys:\n    enabled: true\n    cache_ttl: -1s\n",
+	} {
+		_, err := config.LoadConfig(write(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+// TestLoadConfig_AdminAuth проверяет учетные данные admin_auth и роли по умолчанию.
+func TestLoadConfig_AdminAuth(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write(""))
+	require.NoError(t, err)
+	assert.False(t, cfg.AdminAuth.Enabled())
+
+	cfg, err = config.LoadConfig(write("admin_auth:\n  tokens:\n    - token: secret\n    - name: ci\n      token: other\n      role: Viewer\n  users:\n    - username: ops\n      password: pass\n"))
+	require.NoError(t, err)
+	assert.True(t, cfg.AdminAuth.Enabled())
+	assert.Equal(t, config.AdminTokenConfig{Name: "token-0", Token: "secret", Role: config.AdminRoleAdmin}, cfg.AdminAuth.Tokens[0])
+	assert.Equal(t, config.AdminTokenConfig{Name: "ci", Token: "other", Role: config.AdminRoleViewer}, cfg.AdminAuth.Tokens[1])
+	assert.Equal(t, config.AdminRoleAdmin, cfg.AdminAuth.Users[0].Role)
+
+	for _, invalid := range []string{
+		"admin_auth:\n  tokens:\n    - name: empty\n",
+		"admin_auth:\n  tokens:\n    - token: same\n    - token: same\n",
+		"admin_auth:\n  tokens:\n    - token: secret\n      role: root\n",
+		"admin_auth:\n  users:\n    - username: ops\n",
+		"admin_auth:\n  users:\n    - password: pass\n",
+		"admin_auth:\n  users:\n    - username: ops\n      password: a\n    - username: ops\n      password: b\n",
+	} {
+		_, err := config.LoadConfig(write(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+// TestLoadConfig_SQLite проверяет значения по умолчанию и валидацию секции sqlite.
+func TestLoadConfig_SQLite(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write(""))
+	require.NoError(t, err)
+	assert.Equal(t, config.DefaultSQLiteConfig(), cfg.SQLite)
+
+	cfg, err = config.LoadConfig(write("sqlite:\n  journal_mode: DELETE\n  busy_timeout: '250ms'\n  synchronous: full\n" +
+		"  max_open_conns: 1\n  pragmas:\n    cache_size: '-20000'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "delete", cfg.SQLite.JournalMode)
+	assert.Equal(t, 250*time.Millisecond, cfg.SQLite.BusyTimeout)
+	assert.Equal(t, "full", cfg.SQLite.Synchronous)
+	assert.Equal(t, 1, cfg.SQLite.MaxOpenConns)
+	assert.Equal(t, map[string]string{"cache_size": "-20000"}, cfg.SQLite.Pragmas)
+
+	for _, invalid := range []string{
+		"sqlite:\n  journal_mode: fast\n",
+		"sqlite:\n  synchronous: always\n",
+		"sqlite:\n  busy_timeout: '0s'\n",
+		"sqlite:\n  max_open_conns: -1\n",
+		"sqlite:\n  pragmas:\n    cache_size: '1); DROP TABLE x'\n",
+		"sqlite:\n  pragmas:\n    journal_mode: wal\n",
+	} {
+		_, err := config.LoadConfig(write(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+// TestLoadConfig_IPv6PrefixLength проверяет диапазон rate_limiter.ipv6_prefix_length.
+func TestLoadConfig_IPv6PrefixLength(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  ipv6_prefix_length: 64\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 64, cfg.RateLimiter.IPv6PrefixLength)
+
+	for _, length := range []string{"-1", "129"} {
+		_, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  ipv6_prefix_length: " + length + "\n"))
+		assert.Error(t, err, length)
+	}
+}
+
+// TestLoadConfig_TrustedProxies проверяет разбор rate_limiter.trusted_proxies: адреса и подсети CIDR.
+func TestLoadConfig_TrustedProxies(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  trusted_proxies: ['10.1.2.3/8', '192.0.2.1', '::ffff:198.51.100.1', '2001:db8::/32']\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+		netip.MustParsePrefix("198.51.100.1/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}, cfg.RateLimiter.TrustedProxyPrefixes)
+
+	_, err = config.LoadConfig(write("rate_limiter:\n  enabled: true\n  trusted_proxies: ['proxy.local']\n"))
+	assert.Error(t, err)
+}
+
+// TestLoadConfig_DenialCacheTTL проверяет разбор rate_limiter.denial_cache_ttl.
+func TestLoadConfig_DenialCacheTTL(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  denial_cache_ttl: '100ms'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 100*time.Millisecond, cfg.RateLimiter.DenialCacheTTL)
+
+	for _, ttl := range []string{"-1s", "fast"} {
+		_, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  denial_cache_ttl: '" + ttl + "'\n"))
+		assert.Error(t, err, ttl)
+	}
+}
+
+// TestLoadConfig_LimitDecreaseWindow проверяет разбор rate_limiter.limit_decrease_window.
+func TestLoadConfig_LimitDecreaseWindow(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  limit_decrease_window: '5m'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.RateLimiter.LimitDecreaseWindow)
+
+	for _, window := range []string{"-1m", "slowly"} {
+		_, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  limit_decrease_window: '" + window + "'\n"))
+		assert.Error(t, err, window)
+	}
+}
+
+// TestLoadConfig_Clients проверяет разбор и валидацию rate_limiter.clients.
+func TestLoadConfig_Clients(t *testing.T) {
+	yamlContent := `
+port: "8080"
+backend_servers: ["http://b1"]
+rate_limiter:
+  enabled: true
+  clients:
+    partner-a:
+      rate: 10
+      capacity: 100
+`
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "clients.yaml")
+	require.NoError(t, os.WriteFile(tmpFile, []byte(yamlContent), 0o644))
+
+	cfg, err := config.LoadConfig(tmpFile)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]config.ClientRateConfig{"partner-a": {Rate: 10, Capacity: 100}}, cfg.RateLimiter.Clients)
+
+	invalidContent := `
+port: "8080"
+backend_servers: ["http://b1"]
+rate_limiter:
+  enabled: true
+  clients:
+    partner-b:
+      rate: 0
+      capacity: 100
+`
+	invalidFile := filepath.Join(tmpDir, "clients_invalid.yaml")
+	require.NoError(t, os.WriteFile(invalidFile, []byte(invalidContent), 0o644))
+
+	_, err = config.LoadConfig(invalidFile)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "partner-b")
+}
+
+// TestLoadConfig_LimitTemplates проверяет наследование лимитов клиентов от шаблонов.
+func TestLoadConfig_LimitTemplates(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write(`
+rate_limiter:
+  enabled: true
+  templates:
+    partner:
+      rate: 5
+      capacity: 50
+  clients:
+    partner-a:
+      template: partner
+    partner-b:
+      template: partner
+      capacity: 500
+`))
+	require.NoError(t, err)
+	assert.Equal(t, config.ClientRateConfig{Rate: 5, Capacity: 50, Template: "partner"}, cfg.RateLimiter.Clients["partner-a"])
+	assert.Equal(t, config.ClientRateConfig{Rate: 5, Capacity: 500, Template: "partner"}, cfg.RateLimiter.Clients["partner-b"])
+
+	invalid := map[string]string{
+		"unknown template": "rate_limiter:\n  enabled: true\n  clients:\n    partner-a:\n      template: missing\n",
+		"bad template":     "rate_limiter:\n  enabled: true\n  templates:\n    partner:\n      rate: 5\n",
+		"nested template":  "rate_limiter:\n  enabled: true\n  templates:\n    base:\n      rate: 5\n      capacity: 50\n    partner:\n      rate: 5\n      capacity: 50\n      template: base\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_Locale проверяет выбор языка сообщений.
+func TestLoadConfig_Locale(t *testing.T) {
+	t.Cleanup(func() { _ = i18n.SetLocale(i18n.DefaultLocale) })
+
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	// По умолчанию - русский
+	cfg, err := config.LoadConfig(write("port: \"8080\"\n"))
+	require.NoError(t, err)
+	assert.Equal(t, i18n.LocaleRU, cfg.Locale)
+
+	// Английский: ошибки валидации выводятся на английском
+	_, err = config.LoadConfig(write("locale: \"EN\"\nload_balancing_algorithm: \"least_conn\"\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported load_balancing_algorithm: 'least_conn'")
+
+	// Неизвестная локаль
+	_, err = config.LoadConfig(write("locale: \"fr\"\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'fr'")
+}
+
+// TestLoadConfig_TLS проверяет разбор и валидацию секции tls.
+func TestLoadConfig_TLS(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write(`
+port: "8080"
+tls:
+  enabled: true
+  port: "8443"
+  sites:
+    - server_names: [" API.example.com "]
+      cert_file: "api.pem"
+      key_file: "api-key.pem"
+      backend_servers: ["http://api:9000"]
+    - server_names: ["*.example.com"]
+      cert_file: "example.pem"
+      key_file: "example-key.pem"
+`))
+	require.NoError(t, err)
+	require.Len(t, cfg.TLS.Sites, 2)
+	assert.Equal(t, []string{"api.example.com"}, cfg.TLS.Sites[0].ServerNames, "Имена должны приводиться к нижнему регистру")
+	assert.Equal(t, []string{"http://api:9000"}, cfg.TLS.Sites[0].BackendServers)
+	assert.Empty(t, cfg.TLS.Sites[1].BackendServers)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.TLS.MinVersion, "По умолчанию TLS 1.2")
+	assert.Empty(t, cfg.TLS.CipherSuiteIDs)
+
+	// Один сертификат листенера без sites
+	cfg, err = config.LoadConfig(write(`
+tls:
+  enabled: true
+  port: "8443"
+  cert_file: "balancer.pem"
+  key_file: "balancer-key.pem"
+  cipher_suites: ["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"]
+`))
+	require.NoError(t, err)
+	assert.Empty(t, cfg.TLS.Sites)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, cfg.TLS.CipherSuiteIDs)
+
+	cfg, err = config.LoadConfig(write("tls:\n  enabled: true\n  port: \"8443\"\n  cert_file: c\n  key_file: k\n  min_version: '1.3'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.TLS.MinVersion)
+	assert.Equal(t, tls.NoClientCert, cfg.TLS.ClientAuth.Type, "По умолчанию клиентские сертификаты не запрашиваются")
+
+	cfg, err = config.LoadConfig(write("tls:\n  enabled: true\n  port: \"8443\"\n  cert_file: c\n  key_file: k\n" +
+		"  client_auth:\n    mode: require\n    ca_file: ca.pem\n    subject_header: X-Client-Cert-CN\n"))
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, cfg.TLS.ClientAuth.Type)
+
+	invalid := map[string]string{
+		"no port":           "tls:\n  enabled: true\n  sites: [{server_names: [a.com], cert_file: c, key_file: k}]\n",
+		"no default key":    "tls:\n  enabled: true\n  port: \"8443\"\n  cert_file: c\n",
+		"min_version":       "tls:\n  enabled: true\n  port: \"8443\"\n  cert_file: c\n  key_file: k\n  min_version: '1.1'\n",
+		"insecure suite":    "tls:\n  enabled: true\n  port: \"8443\"\n  cert_file: c\n  key_file: k\n  cipher_suites: [TLS_RSA_WITH_RC4_128_SHA]\n",
+		"tls13 suite":       "tls:\n  enabled: true\n  port: \"8443\"\n  cert_file: c\n  key_file: k\n  cipher_suites: [TLS_AES_128_GCM_SHA256]\n",
+		"client_auth mode":  "tls:\n  enabled: true\n  port: \"8443\"\n  cert_file: c\n  key_file: k\n  client_auth: {mode: always, ca_file: ca.pem}\n",
+		"client_auth no ca": "tls:\n  enabled: true\n  port: \"8443\"\n  cert_file: c\n  key_file: k\n  client_auth: {mode: optional}\n",
+		"subject no auth":   "tls:\n  enabled: true\n  port: \"8443\"\n  cert_file: c\n  key_file: k\n  client_auth: {subject_header: X-Client-Cert-CN}\n",
+		"suites with 13":    "tls:\n  enabled: true\n  port: \"8443\"\n  cert_file: c\n  key_file: k\n  min_version: '1.3'\n  cipher_suites: [TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256]\n",
+		"same port":         "port: \"8443\"\ntls:\n  enabled: true\n  port: \"8443\"\n  sites: [{server_names: [a.com], cert_file: c, key_file: k}]\n",
+		"no sites":          "tls:\n  enabled: true\n  port: \"8443\"\n",
+		"no names":          "tls:\n  enabled: true\n  port: \"8443\"\n  sites: [{cert_file: c, key_file: k}]\n",
+		"no key":            "tls:\n  enabled: true\n  port: \"8443\"\n  sites: [{server_names: [a.com], cert_file: c}]\n",
+		"bad wildcard":      "tls:\n  enabled: true\n  port: \"8443\"\n  sites: [{server_names: [\"a.*.com\"], cert_file: c, key_file: k}]\n",
+		"duplicate name":    "tls:\n  enabled: true\n  port: \"8443\"\n  sites: [{server_names: [a.com], cert_file: c, key_file: k}, {server_names: [A.com], cert_file: c, key_file: k}]\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+
+	// Выключенная секция не валидируется
+	_, err = config.LoadConfig(write("tls:\n  enabled: false\n"))
+	assert.NoError(t, err)
+}
+
+// TestLoadConfig_UDP проверяет значения по умолчанию и валидацию секции udp.
+func TestLoadConfig_UDP(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("udp:\n  enabled: true\n  listen: ':5353'\n  backends: ['10.0.0.1:53']\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.UDP.SessionTimeout)
+	assert.Equal(t, time.Duration(0), cfg.UDP.ResponseTimeout, "По умолчанию ответы не ожидаются")
+	assert.Equal(t, 10*time.Second, cfg.UDP.FailTimeout)
+	assert.Equal(t, 3, cfg.UDP.MaxFails)
+
+	invalid := map[string]string{
+		"no listen":       "udp:\n  enabled: true\n  backends: ['10.0.0.1:53']\n",
+		"no backends":     "udp:\n  enabled: true\n  listen: ':5353'\n",
+		"backend no port": "udp:\n  enabled: true\n  listen: ':5353'\n  backends: ['10.0.0.1']\n",
+		"max_fails":       "udp:\n  enabled: true\n  listen: ':5353'\n  backends: ['10.0.0.1:53']\n  max_fails: 0\n",
+		"bad duration":    "udp:\n  enabled: true\n  listen: ':5353'\n  backends: ['10.0.0.1:53']\n  session_timeout: 'soon'\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_ForwardProxy проверяет валидацию секции forward_proxy.
+func TestLoadConfig_ForwardProxy(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("forward_proxy:\n  enabled: true\n  port: '3128'\n  gateways: ['http://egress1:3128']\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, cfg.ForwardProxy.DialTimeout)
+
+	invalid := map[string]string{
+		"no port":      "forward_proxy:\n  enabled: true\n  gateways: ['http://egress1:3128']\n",
+		"same port":    "port: '3128'\nforward_proxy:\n  enabled: true\n  port: '3128'\n  gateways: ['http://egress1:3128']\n",
+		"no gateways":  "forward_proxy:\n  enabled: true\n  port: '3128'\n",
+		"bad duration": "forward_proxy:\n  enabled: true\n  port: '3128'\n  gateways: ['http://egress1:3128']\n  dial_timeout: '0s'\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_BindAddress проверяет адреса листенеров и конфликты портов с учетом адресов.
+func TestLoadConfig_BindAddress(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("port: '8080'\nbind_address: '[::1]'\n" +
+		"forward_proxy:\n  enabled: true\n  port: '8080'\n  bind_address: '127.0.0.1'\n  gateways: ['http://egress1:3128']\n"))
+	require.NoError(t, err, "Один порт на разных адресах допустим")
+	assert.Equal(t, "::1", cfg.BindAddress, "Скобки вокруг IPv6 убираются")
+	assert.Equal(t, "[::1]:8080", config.ListenAddr(cfg.BindAddress, cfg.Port))
+	assert.Equal(t, "127.0.0.1:8080", config.ListenAddr(cfg.ForwardProxy.BindAddress, cfg.ForwardProxy.Port))
+	assert.Equal(t, ":8080", config.ListenAddr("", "8080"))
+
+	invalid := map[string]string{
+		"hostname":    "bind_address: 'localhost'\n",
+		"same socket": "port: '8080'\nbind_address: '::1'\nforward_proxy:\n  enabled: true\n  port: '8080'\n  bind_address: '::1'\n  gateways: ['http://egress1:3128']\n",
+		"wildcard":    "port: '8080'\nbind_address: '::'\nforward_proxy:\n  enabled: true\n  port: '8080'\n  bind_address: '127.0.0.1'\n  gateways: ['http://egress1:3128']\n",
+		"tls bad":     "tls:\n  enabled: true\n  port: '8443'\n  bind_address: '::1::2'\n  sites:\n    - server_names: ['a.example.com']\n      cert_file: a.pem\n      key_file: a.key\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_KeyTemplate проверяет разбор rate_limiter.key_template.
+func TestLoadConfig_KeyTemplate(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  key_template: 'tenant-{header.x-tenant}:{method}'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []config.KeyPart{
+		{Literal: "tenant-"},
+		{Attr: config.KeyAttrHeader, Header: "X-Tenant"},
+		{Literal: ":"},
+		{Attr: config.KeyAttrMethod},
+	}, cfg.RateLimiter.KeyParts)
+
+	_, err = config.LoadConfig(write("rate_limiter:\n  enabled: true\n  key_template: '{client_id}:{route}'\n"))
+	assert.Error(t, err, "Неизвестный атрибут")
+}
+
+// TestLoadConfig_ResponseValidation проверяет секции routes[].response_validation и passive_health.
+func TestLoadConfig_ResponseValidation(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("passive_health:\n  hold_down: '30s'\n  max_failures: 3\nroutes:\n  - path_prefix: '/api'\n" +
+		"    response_validation:\n      content_types: [' Application/JSON', 'text/*']\n      non_empty_body: true\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.PassiveHealth.HoldDown)
+	assert.Equal(t, 3, cfg.PassiveHealth.MaxFailures)
+	assert.Equal(t, config.DefaultFailureWindow, cfg.PassiveHealth.FailureWindow)
+	require.NotNil(t, cfg.Routes[0].ResponseValidation)
+	assert.Equal(t, []string{"application/json", "text/*"}, cfg.Routes[0].ResponseValidation.ContentTypes)
+
+	invalid := map[string]string{
+		"hold_down":      "passive_health:\n  hold_down: '-1s'\n",
+		"max_failures":   "passive_health:\n  max_failures: -1\n",
+		"failure_window": "passive_health:\n  failure_window: '0s'\n",
+		"content type":   "routes:\n  - path_prefix: '/api'\n    response_validation:\n      content_types: ['json']\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_BackendPassiveHealth проверяет passive_health отдельных бэкендов поверх общего.
+func TestLoadConfig_BackendPassiveHealth(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("backend_servers:\n  - 'http://a:80'\n  - url: 'http://b:80'\n" +
+		"    passive_health:\n      max_failures: 5\n      slow_start: '1m'\n" +
+		"passive_health:\n  hold_down: '30s'\n  max_failures: 2\n  count_5xx: true\n  consecutive: true\n  slow_start: '10s'\n"))
+	require.NoError(t, err)
+	assert.True(t, cfg.PassiveHealth.Count5xx)
+	assert.True(t, cfg.PassiveHealth.Consecutive)
+	assert.Equal(t, 10*time.Second, cfg.PassiveHealth.SlowStart)
+	assert.True(t, cfg.Features()["slow_start"])
+
+	require.Len(t, cfg.BackendPassiveHealth, 1)
+	override := cfg.BackendPassiveHealth["http://b:80"]
+	assert.Equal(t, 5, override.MaxFailures)
+	assert.Equal(t, time.Minute, override.SlowStart)
+	// Не указанные у бэкенда параметры берутся из общего passive_health
+	assert.Equal(t, 30*time.Second, override.HoldDown)
+	assert.Equal(t, config.DefaultFailureWindow, override.FailureWindow)
+	assert.True(t, override.Count5xx)
+	assert.True(t, override.Consecutive)
+
+	invalid := map[string]string{
+		"slow_start":          "passive_health:\n  slow_start: 'soon'\n",
+		"slow_start hash":     "load_balancing_algorithm: consistent_hash\npassive_health:\n  slow_start: '10s'\n",
+		"backend hold_down":   "backend_servers:\n  - url: 'http://a:80'\n    passive_health:\n      hold_down: '-1s'\n",
+		"backend slow hash":   "load_balancing_algorithm: consistent_hash\nbackend_servers:\n  - url: 'http://a:80'\n    passive_health:\n      slow_start: '10s'\n",
+		"backend not mapping": "backend_servers:\n  - url: 'http://a:80'\n    passive_health: [1]\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_BackendWeights проверяет backend_servers со строками и объектами {url, weight}.
+func TestLoadConfig_BackendWeights(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(tmpFile, []byte("backend_servers:\n  - 'http://a:80'\n"+
+		"  - url: 'http://b:80'\n    weight: 3\n  - url: 'http://c:80'\n"), 0o644))
+	cfg, err := config.LoadConfig(tmpFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://a:80", "http://b:80", "http://c:80"}, cfg.BackendServers)
+	assert.Equal(t, map[string]int{"http://b:80": 3}, cfg.BackendWeights)
+
+	next := *cfg
+	next.BackendWeights = map[string]int{"http://b:80": 2}
+	changes := config.Diff(cfg, &next)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "backend_servers.http://b:80.weight", changes[0].Field)
+	assert.True(t, changes[0].RestartRequired)
+
+	invalid := map[string]string{
+		"no url":          "backend_servers:\n  - weight: 2\n",
+		"negative weight": "backend_servers:\n  - url: 'http://a:80'\n    weight: -1\n",
+	}
+	for name, content := range invalid {
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		_, err := config.LoadConfig(tmpFile)
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_LogLevel проверяет разбор log_level и секции logging.
+func TestLoadConfig_LogLevel(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(tmpFile, []byte("log_level: 'WARN'\n"), 0o644))
+	cfg, err := config.LoadConfig(tmpFile)
+	require.NoError(t, err)
+	assert.Equal(t, i18n.LevelWarn, cfg.LogLevel)
+
+	require.NoError(t, os.WriteFile(tmpFile, []byte("log_level: 'trace'\n"), 0o644))
+	_, err = config.LoadConfig(tmpFile)
+	assert.Error(t, err)
+
+	// logging.level имеет приоритет над log_level
+	require.NoError(t, os.WriteFile(tmpFile, []byte("log_level: 'warn'\nlogging:\n  level: 'debug'\n  format: 'JSON'\n"), 0o644))
+	cfg, err = config.LoadConfig(tmpFile)
+	require.NoError(t, err)
+	assert.Equal(t, i18n.LevelDebug, cfg.LogLevel)
+	assert.Equal(t, i18n.FormatJSON, cfg.Logging.Format)
+
+	require.NoError(t, os.WriteFile(tmpFile, []byte("logging:\n  format: 'xml'\n"), 0o644))
+	_, err = config.LoadConfig(tmpFile)
+	assert.Error(t, err)
+}
+
+// TestLoadConfig_BackendMaxConnections проверяет backend_max_connections и saturation_threshold.
+func TestLoadConfig_BackendMaxConnections(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("backend_servers: ['http://a:80']\nbackend_max_connections:\n  'http://a:80': 50\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 50, cfg.BackendMaxConnections["http://a:80"])
+	assert.Equal(t, config.DefaultSaturationThreshold, cfg.SaturationThreshold)
+
+	invalid := map[string]string{
+		"unknown backend": "backend_servers: ['http://a:80']\nbackend_max_connections:\n  'http://b:80': 50\n",
+		"zero":            "backend_servers: ['http://a:80']\nbackend_max_connections:\n  'http://a:80': 0\n",
+		"threshold":       "saturation_threshold: 1.5\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_Gossip проверяет секцию rate_limiter.gossip.
+func TestLoadConfig_Gossip(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("rate_limiter:\n  enabled: true\n  gossip:\n    listen: ':7946'\n    peers: ['lb-2:7946']\n"))
+	require.NoError(t, err)
+	assert.True(t, cfg.RateLimiter.Gossip.Enabled())
+	assert.Equal(t, config.DefaultGossipInterval, cfg.RateLimiter.Gossip.Interval)
+	assert.True(t, cfg.Features()["rate_limit_gossip"])
+
+	invalid := map[string]string{
+		"peers without listen": "rate_limiter:\n  enabled: true\n  gossip:\n    peers: ['lb-2:7946']\n",
+		"peer without port":    "rate_limiter:\n  enabled: true\n  gossip:\n    listen: ':7946'\n    peers: ['lb-2']\n",
+		"interval":             "rate_limiter:\n  enabled: true\n  gossip:\n    listen: ':7946'\n    interval: '0s'\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_StickySessions проверяет секцию sticky_sessions.
+func TestLoadConfig_StickySessions(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("sticky_sessions:\n  enabled: true\n  secret: 's3cret'\n  ttl: '30m'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.DefaultStickyCookieName, cfg.StickySessions.CookieName)
+	assert.Equal(t, 30*time.Minute, cfg.StickySessions.TTL)
+
+	invalid := map[string]string{
+		"cookie name": "sticky_sessions:\n  enabled: true\n  cookie_name: 'lb backend'\n",
+		"ttl":         "sticky_sessions:\n  enabled: true\n  ttl: '-1h'\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_UpstreamThrottling проверяет значения по умолчанию и валидацию секции upstream_throttling.
+func TestLoadConfig_UpstreamThrottling(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("backend_servers: ['http://a:80']\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.ThrottlePassThrough, cfg.UpstreamThrottling.PolicyFor("primary"))
+	assert.False(t, cfg.Features()["upstream_throttling"])
+
+	cfg, err = config.LoadConfig(write("upstream_throttling:\n  policy: RETRY\n  pools:\n    spillover: reduce_weight\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.ThrottleRetry, cfg.UpstreamThrottling.PolicyFor("primary"))
+	assert.Equal(t, config.ThrottleReduceWeight, cfg.UpstreamThrottling.PolicyFor("spillover"))
+	assert.Equal(t, config.DefaultThrottlePenalty, cfg.UpstreamThrottling.Penalty)
+	assert.Equal(t, config.DefaultThrottleWeightPercent, cfg.UpstreamThrottling.WeightPercent)
+
+	invalid := map[string]string{
+		"policy":         "upstream_throttling:\n  policy: drop\n",
+		"pool":           "upstream_throttling:\n  pools:\n    secondary: retry\n",
+		"penalty":        "upstream_throttling:\n  policy: reduce_weight\n  penalty: '0s'\n",
+		"weight percent": "upstream_throttling:\n  policy: reduce_weight\n  weight_percent: 100\n",
+		"hash":           "load_balancing_algorithm: consistent_hash\nupstream_throttling:\n  policy: reduce_weight\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_UpstreamAuth проверяет секцию upstream_auth.
+func TestLoadConfig_UpstreamAuth(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("upstream_auth:\n  primary:\n    type: Bearer\n    token: 't0ken'\n" +
+		"  spillover:\n    type: basic\n    header: 'x-upstream-auth'\n    username: 'lb'\n    password: 'secret'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.UpstreamAuthConfig{Type: config.UpstreamAuthBearer, Header: "Authorization", Token: "t0ken"}, cfg.UpstreamAuth["primary"])
+	assert.Equal(t, "X-Upstream-Auth", cfg.UpstreamAuth["spillover"].Header)
+	assert.True(t, cfg.Features()["upstream_auth"])
+
+	invalid := map[string]string{
+		"unknown pool":   "upstream_auth:\n  tls:example.com:\n    type: bearer\n    token: 'x'\n",
+		"unknown type":   "upstream_auth:\n  primary:\n    type: digest\n",
+		"no token":       "upstream_auth:\n  primary:\n    type: bearer\n",
+		"no username":    "upstream_auth:\n  primary:\n    type: basic\n    password: 'x'\n",
+		"no hmac secret": "upstream_auth:\n  primary:\n    type: hmac\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_DNS проверяет разбор и валидацию настроек разрешения имен пулов.
+func TestLoadConfig_DNS(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("dns:\n  primary:\n    servers: ['10.0.0.2', '[fd00::53]:5353']\n    timeout: '1s'\n" +
+		"    cache_ttl: '30s'\n  spillover:\n    cache_ttl: '10s'\n"))
+	require.NoError(t, err)
+	primary := cfg.DNS["primary"]
+	assert.Equal(t, []string{"10.0.0.2:53", "[fd00::53]:5353"}, primary.Servers)
+	assert.Equal(t, time.Second, primary.Timeout)
+	assert.Equal(t, 30*time.Second, primary.CacheTTL)
+	assert.Equal(t, config.DefaultDNSTimeout, cfg.DNS["spillover"].Timeout)
+	assert.Empty(t, cfg.DNS["spillover"].Servers)
+	assert.True(t, cfg.Features()["custom_dns"])
+
+	invalid := map[string]string{
+		"unknown pool":  "dns:\n  tls:example.com:\n    cache_ttl: '1s'\n",
+		"server name":   "dns:\n  primary:\n    servers: ['ns1.internal']\n",
+		"bad timeout":   "dns:\n  primary:\n    timeout: '0s'\n",
+		"bad cache_ttl": "dns:\n  primary:\n    cache_ttl: 'often'\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_BackendTLS проверяет валидацию настроек TLS с бэкендами пулов.
+func TestLoadConfig_BackendTLS(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("backend_tls:\n  primary:\n    ca_file: ca.pem\n    cert_file: client.pem\n" +
+		"    key_file: client-key.pem\n  spillover:\n    insecure_skip_verify: true\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "ca.pem", cfg.BackendTLS["primary"].CAFile)
+	assert.True(t, cfg.BackendTLS["spillover"].InsecureSkipVerify)
+	assert.True(t, cfg.Features()["backend_tls"])
+
+	invalid := map[string]string{
+		"unknown pool":     "backend_tls:\n  tls:example.com:\n    ca_file: ca.pem\n",
+		"no key":           "backend_tls:\n  primary:\n    cert_file: client.pem\n",
+		"skip verify + ca": "backend_tls:\n  primary:\n    ca_file: ca.pem\n    insecure_skip_verify: true\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_ConnectionLimit проверяет разбор connection_limit.max_per_ip.
+func TestLoadConfig_ConnectionLimit(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("connection_limit:\n  max_per_ip: 64\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 64, cfg.ConnectionLimit.MaxPerIP)
+	assert.True(t, cfg.Features()["connection_limit"])
+
+	_, err = config.LoadConfig(write("connection_limit:\n  max_per_ip: -1\n"))
+	assert.Error(t, err)
+}
+
+// TestLoadConfig_StorageMaintenance проверяет значения по умолчанию и валидацию storage_maintenance.
+func TestLoadConfig_StorageMaintenance(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("port: '8080'\n"))
+	require.NoError(t, err)
+	assert.False(t, cfg.StorageMaintenance.Enabled)
+	assert.Equal(t, 24*time.Hour, cfg.StorageMaintenance.Interval, "Длительности разбираются для запуска вручную")
+	assert.Equal(t, 168*time.Hour, cfg.StorageMaintenance.HealthRetention)
+
+	cfg, err = config.LoadConfig(write("rate_limiter:\n  enabled: true\nstorage_maintenance:\n  enabled: true\n" +
+		"  interval: '6h'\n  health_retention: '24h'\n  vacuum_pages: 500\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 6*time.Hour, cfg.StorageMaintenance.Interval)
+	assert.Equal(t, 24*time.Hour, cfg.StorageMaintenance.HealthRetention)
+	assert.Equal(t, 500, cfg.StorageMaintenance.VacuumPages)
+	assert.True(t, cfg.Features()["storage_maintenance"])
+
+	for _, content := range []string{
+		"storage_maintenance:\n  interval: 'daily'\n",
+		"storage_maintenance:\n  health_retention: '0s'\n",
+		"storage_maintenance:\n  vacuum_pages: -1\n",
+	} {
+		_, err = config.LoadConfig(write(content))
+		assert.Error(t, err, content)
+	}
+}
+
+// TestLoadConfig_Canary проверяет разбор и валидацию канареечного разделения пулов.
+func TestLoadConfig_Canary(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("canary:\n  primary:\n    selector: {version: v2}\n    percent: 10\n" +
+		"    window: '1m'\n    max_error_rate_delta: 0.05\n    max_latency_ratio: 1.5\n    auto_rollback: true\n"))
+	require.NoError(t, err)
+	canary := cfg.Canary["primary"]
+	assert.Equal(t, map[string]string{"version": "v2"}, canary.Selector)
+	assert.Equal(t, 10, canary.Percent)
+	assert.Equal(t, time.Minute, canary.Window)
+	assert.Equal(t, int64(config.DefaultCanaryMinRequests), canary.MinRequests)
+	assert.True(t, canary.AutoRollback)
+	assert.True(t, cfg.Features()["canary"])
+
+	invalid := map[string]string{
+		"unknown pool":       "canary:\n  tls:example.com:\n    selector: {version: v2}\n    percent: 10\n",
+		"no selector":        "canary:\n  primary:\n    percent: 10\n",
+		"percent":            "canary:\n  primary:\n    selector: {version: v2}\n    percent: 100\n",
+		"window":             "canary:\n  primary:\n    selector: {version: v2}\n    percent: 10\n    window: '2h'\n",
+		"min_requests":       "canary:\n  primary:\n    selector: {version: v2}\n    percent: 10\n    min_requests: -1\n",
+		"error rate delta":   "canary:\n  primary:\n    selector: {version: v2}\n    percent: 10\n    max_error_rate_delta: 2\n",
+		"latency ratio":      "canary:\n  primary:\n    selector: {version: v2}\n    percent: 10\n    max_latency_ratio: 0.5\n",
+		"rollback threshold": "canary:\n  primary:\n    selector: {version: v2}\n    percent: 10\n    auto_rollback: true\n",
+		"consistent_hash": "load_balancing_algorithm: consistent_hash\n" +
+			"canary:\n  primary:\n    selector: {version: v2}\n    percent: 10\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_RequestAge проверяет разбор request_age.max_age.
+func TestLoadConfig_RequestAge(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("request_age:\n  max_age: '10s'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, cfg.RequestAge.MaxAge)
+	assert.True(t, cfg.Features()["request_age"])
+
+	cfg, err = config.LoadConfig(write("{}\n"))
+	require.NoError(t, err)
+	assert.Zero(t, cfg.RequestAge.MaxAge)
+	assert.False(t, cfg.Features()["request_age"])
+
+	for _, value := range []string{"soon", "0s", "-1s"} {
+		_, err := config.LoadConfig(write("request_age:\n  max_age: '" + value + "'\n"))
+		assert.Error(t, err, value)
+	}
+}
+
+// TestLoadConfig_AdmissionHook проверяет валидацию секции admission_hook.
+func TestLoadConfig_AdmissionHook(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("admission_hook:\n  enabled: true\n  source: |\n    def admit(request):\n        return None\n"))
+	require.NoError(t, err)
+	assert.Contains(t, cfg.AdmissionHook.Source, "def admit")
+	assert.Equal(t, uint64(config.DefaultHookMaxSteps), cfg.AdmissionHook.MaxSteps)
+	assert.True(t, cfg.Features()["admission_hook"])
+
+	// Выключенный сценарий не проверяется
+	_, err = config.LoadConfig(write("admission_hook:\n  enabled: false\n"))
+	require.NoError(t, err)
+
+	for name, content := range map[string]string{
+		"нет сценария":  "admission_hook:\n  enabled: true\n",
+		"оба источника": "admission_hook:\n  enabled: true\n  script: 'a.star'\n  source: 'def admit(r): pass'\n",
+	} {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_AccessList проверяет валидацию секции access_list.
+func TestLoadConfig_AccessList(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("access_list:\n  deny: ['203.0.113.7', '2001:db8::/32', 'scraper']\n  allow: ['10.0.0.0/8']\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"203.0.113.7", "2001:db8::/32", "scraper"}, cfg.AccessList.Deny)
+	assert.Equal(t, []string{"10.0.0.0/8"}, cfg.AccessList.Allow)
+
+	invalid := map[string]string{
+		"empty":    "access_list:\n  deny: ['']\n",
+		"spaces":   "access_list:\n  allow: [' 10.0.0.1']\n",
+		"bad cidr": "access_list:\n  deny: ['10.0.0.0/33']\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_Concurrency проверяет значения по умолчанию и валидацию секции concurrency.
+func TestLoadConfig_Concurrency(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("concurrency:\n  enabled: true\n  max_in_flight: 10\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 100, cfg.Concurrency.MaxQueue)
+	assert.Equal(t, 5*time.Second, cfg.Concurrency.QueueTimeout)
+	assert.Equal(t, "X-Priority", cfg.Concurrency.PriorityHeader)
+	assert.Equal(t, 5, cfg.Concurrency.DefaultPriority)
+	assert.Empty(t, cfg.Concurrency.PriorityTrustedPrefixes, "По умолчанию повышать приоритет не может никто")
+
+	cfg, err = config.LoadConfig(write("concurrency:\n  enabled: true\n  max_in_flight: 10\n  priority_trusted: ['10.0.0.0/8', '192.0.2.10']\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.10/32")},
+		cfg.Concurrency.PriorityTrustedPrefixes)
+
+	invalid := map[string]string{
+		"max_in_flight":    "concurrency:\n  enabled: true\n",
+		"max_queue":        "concurrency:\n  enabled: true\n  max_in_flight: 1\n  max_queue: -1\n",
+		"default_priority": "concurrency:\n  enabled: true\n  max_in_flight: 1\n  default_priority: 10\n",
+		"queue_timeout":    "concurrency:\n  enabled: true\n  max_in_flight: 1\n  queue_timeout: 'never'\n",
+		"priority_trusted": "concurrency:\n  enabled: true\n  max_in_flight: 1\n  priority_trusted: ['internal']\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_Spillover проверяет валидацию секции spillover.
+func TestLoadConfig_Spillover(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("spillover:\n  enabled: true\n  backend_servers: ['http://overflow:8080']\n  primary_max_rps: 50\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://overflow:8080"}, cfg.Spillover.BackendServers)
+	assert.Equal(t, 50.0, cfg.Spillover.PrimaryMaxRPS)
+
+	invalid := map[string]string{
+		"backend_servers": "spillover:\n  enabled: true\n  primary_max_in_flight: 10\n",
+		"no budget":       "spillover:\n  enabled: true\n  backend_servers: ['http://overflow:8080']\n",
+		"negative":        "spillover:\n  enabled: true\n  backend_servers: ['http://overflow:8080']\n  primary_max_in_flight: -1\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_AcceptEncoding проверяет режим Accept-Encoding по умолчанию и валидацию значений.
+func TestLoadConfig_AcceptEncoding(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("port: '8080'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.AcceptEncodingPass, cfg.Headers.AcceptEncoding)
+
+	cfg, err = config.LoadConfig(write("headers:\n  accept_encoding: STRIP\nroutes:\n  - path_prefix: '/rewrite'\n    headers:\n      accept_encoding: identity\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.AcceptEncodingStrip, cfg.Headers.AcceptEncoding)
+	assert.Equal(t, config.AcceptEncodingIdentity, cfg.Routes[0].Headers.AcceptEncoding)
+
+	_, err = config.LoadConfig(write("headers:\n  accept_encoding: gzip\n"))
+	assert.Error(t, err)
+	_, err = config.LoadConfig(write("routes:\n  - path_prefix: '/api'\n    headers:\n      accept_encoding: br\n"))
+	assert.Error(t, err)
+}
+
+// TestLoadConfig_AccessLog проверяет значения по умолчанию и валидацию секции access_log.
+func TestLoadConfig_AccessLog(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("access_log:\n  enabled: true\n  sink: Syslog\n  address: 'udp://127.0.0.1:514'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.AccessLogSinkSyslog, cfg.AccessLog.Sink)
+	assert.Equal(t, 100, cfg.AccessLog.BatchSize)
+	assert.Equal(t, time.Second, cfg.AccessLog.FlushInterval)
+	assert.Equal(t, 5*time.Second, cfg.AccessLog.Timeout)
+
+	cfg, err = config.LoadConfig(write("access_log:\n  enabled: true\n  sink: file\n  address: stdout\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.AccessLogFormatJSON, cfg.AccessLog.Format)
+	assert.Equal(t, 5, cfg.AccessLog.MaxBackups)
+
+	invalid := map[string]string{
+		"sink":           "access_log:\n  enabled: true\n  sink: 'elastic'\n  address: 'http://logs.local'\n",
+		"file format":    "access_log:\n  enabled: true\n  sink: file\n  address: 'access.log'\n  format: 'combined'\n",
+		"file address":   "access_log:\n  enabled: true\n  sink: file\n",
+		"max_backups":    "access_log:\n  enabled: true\n  sink: file\n  address: 'access.log'\n  max_size_mb: 10\n  max_backups: 0\n",
+		"syslog address": "access_log:\n  enabled: true\n  sink: syslog\n  address: '127.0.0.1:514'\n",
+		"http address":   "access_log:\n  enabled: true\n  sink: http\n  address: 'logs.local/bulk'\n",
+		"kafka topic":    "access_log:\n  enabled: true\n  sink: kafka\n  address: 'http://kafka-rest:8082'\n",
+		"queue_size":     "access_log:\n  enabled: true\n  sink: http\n  address: 'http://logs.local'\n  batch_size: 500\n  queue_size: 100\n",
+		"flush_interval": "access_log:\n  enabled: true\n  sink: http\n  address: 'http://logs.local'\n  flush_interval: '0s'\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_BackendLabels проверяет метки бэкендов и селекторы маршрутов.
+func TestLoadConfig_BackendLabels(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("backend_servers: ['http://b1:80', 'http://b2:80']\nbackend_labels:\n  'http://b2:80':\n    version: v2\n    gpu: 'true'\nroutes:\n  - path_prefix: '/inference'\n    backend_selector:\n      gpu: 'true'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"version": "v2", "gpu": "true"}, cfg.BackendLabels["http://b2:80"])
+	assert.Equal(t, map[string]string{"gpu": "true"}, cfg.Routes[0].BackendSelector)
+
+	invalid := map[string]string{
+		"unknown backend": "backend_servers: ['http://b1:80']\nbackend_labels:\n  'http://b3:80':\n    version: v2\n",
+		"empty label":     "backend_servers: ['http://b1:80']\nbackend_labels:\n  'http://b1:80':\n    '': v2\n",
+		"empty selector":  "routes:\n  - path_prefix: '/api'\n    backend_selector:\n      '': v2\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_RouteFlushInterval проверяет разбор routes[].flush_interval.
+func TestLoadConfig_RouteFlushInterval(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("routes:\n  - path_prefix: '/events'\n    flush_interval: '-1'\n  - path_prefix: '/export'\n    flush_interval: '100ms'\n  - path_prefix: '/api'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), cfg.Routes[0].FlushInterval)
+	assert.Equal(t, 100*time.Millisecond, cfg.Routes[1].FlushInterval)
+	assert.False(t, cfg.Routes[2].HasFlushInterval())
+
+	for _, value := range []string{"-5s", "fast"} {
+		_, err := config.LoadConfig(write("routes:\n  - path_prefix: '/events'\n    flush_interval: '" + value + "'\n"))
+		assert.Error(t, err, value)
+	}
+}
+
+// TestDiff проверяет отличия между загрузками конфигурации и признак необходимости перезапуска.
+func TestDiff(t *testing.T) {
+	write := func(content string) *config.Config {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		cfg, err := config.LoadConfig(tmpFile)
+		require.NoError(t, err)
+		return cfg
+	}
+
+	prev := write("backend_servers: ['http://a', 'http://b']\nload_balancing_algorithm: round_robin\n" +
+		"rate_limiter:\n  enabled: true\n  clients:\n    user1: {rate: 1, capacity: 10}\n    user2: {rate: 2, capacity: 20}\n")
+	next := write("backend_servers: ['http://b', 'http://c']\nload_balancing_algorithm: random\n" +
+		"rate_limiter:\n  enabled: true\n  clients:\n    user1: {rate: 5, capacity: 10}\n    user3: {rate: 3, capacity: 30}\n")
+
+	assert.Empty(t, config.Diff(prev, prev))
+	assert.Equal(t, []config.Change{
+		{Field: "backend_servers", Kind: config.ChangeRemoved, Old: "http://a", RestartRequired: true},
+		{Field: "backend_servers", Kind: config.ChangeAdded, New: "http://c", RestartRequired: true},
+		{Field: "load_balancing_algorithm", Kind: config.ChangeChanged, Old: "round_robin", New: "random", RestartRequired: true},
+		{Field: "rate_limiter.clients.user1", Kind: config.ChangeChanged, Old: "rate=1 capacity=10", New: "rate=5 capacity=10"},
+		{Field: "rate_limiter.clients.user2", Kind: config.ChangeRemoved, Old: "rate=2 capacity=20"},
+		{Field: "rate_limiter.clients.user3", Kind: config.ChangeAdded, New: "rate=3 capacity=30"},
+	}, config.Diff(prev, next))
+}
+
+// TestLoadConfig_Metrics проверяет путь эндпоинта метрик по умолчанию и отказ для занятых путей.
+func TestLoadConfig_Metrics(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("port: '8080'\nmetrics:\n  enabled: true\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "/metrics", cfg.Metrics.Path)
+	assert.True(t, cfg.Features()["metrics"])
+
+	cfg, err = config.LoadConfig(write("metrics:\n  enabled: true\n  path: /internal/prometheus\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "/internal/prometheus", cfg.Metrics.Path)
+
+	for _, path := range []string{"metrics", "/", "/admin/metrics", "/readyz", "/clients", "/api-keys"} {
+		_, err = config.LoadConfig(write("metrics:\n  enabled: true\n  path: '" + path + "'\n"))
+		assert.Error(t, err, path)
+	}
+}
+
+// TestLoadConfig_Tracing проверяет секцию tracing: значения по умолчанию и проверку параметров.
+func TestLoadConfig_Tracing(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("tracing:\n  enabled: true\n  endpoint: 'http://otel:4318/v1/traces'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "load-balancer", cfg.Tracing.ServiceName)
+	assert.Equal(t, 1.0, cfg.Tracing.SampleRatio)
+	assert.Equal(t, 5*time.Second, cfg.Tracing.FlushInterval)
+	assert.Equal(t, 10*time.Second, cfg.Tracing.Timeout)
+	assert.True(t, cfg.Features()["tracing"])
+
+	invalid := map[string]string{
+		"endpoint":       "tracing:\n  enabled: true\n  endpoint: 'otel:4318'\n",
+		"sample_ratio":   "tracing:\n  enabled: true\n  endpoint: 'http://otel:4318/v1/traces'\n  sample_ratio: 1.5\n",
+		"queue_size":     "tracing:\n  enabled: true\n  endpoint: 'http://otel:4318/v1/traces'\n  batch_size: 100\n  queue_size: 10\n",
+		"flush_interval": "tracing:\n  enabled: true\n  endpoint: 'http://otel:4318/v1/traces'\n  flush_interval: '0s'\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLoadConfig_Timeouts проверяет значения по умолчанию секции timeouts и переопределение для маршрута.
+func TestLoadConfig_Timeouts(t *testing.T) {
+	write := func(content string) string {
+		tmpFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(tmpFile, []byte(content), 0o644))
+		return tmpFile
+	}
+
+	cfg, err := config.LoadConfig(write("routes:\n  - path_prefix: '/reports'\n    backend_timeout: '0'\n  - path_prefix: '/api'\n"))
+	require.NoError(t, err)
+	assert.Zero(t, cfg.Timeouts.Read)
+	assert.Equal(t, 10*time.Second, cfg.Timeouts.ReadHeader)
+	assert.Zero(t, cfg.Timeouts.Write)
+	assert.Equal(t, 120*time.Second, cfg.Timeouts.Idle)
+	assert.Equal(t, 60*time.Second, cfg.Timeouts.BackendResponse)
+	assert.True(t, cfg.Routes[0].HasBackendTimeout())
+	assert.Zero(t, cfg.Routes[0].BackendTimeout)
+	assert.False(t, cfg.Routes[1].HasBackendTimeout())
+
+	cfg, err = config.LoadConfig(write("timeouts:\n  write: '30s'\n  backend_response: '0'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.Timeouts.Write)
+	assert.Zero(t, cfg.Timeouts.BackendResponse)
+
+	invalid := map[string]string{
+		"read":            "timeouts:\n  read: 'soon'\n",
+		"backend":         "timeouts:\n  backend_response: '-1s'\n",
+		"backend_timeout": "routes:\n  - path_prefix: '/api'\n    backend_timeout: '5 minutes'\n",
+	}
+	for name, content := range invalid {
+		_, err := config.LoadConfig(write(content))
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Виды изменений в Change.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Change - одно изменение конфигурации между двумя загрузками.
+type Change struct {
+	Field string `json:"field"` // Путь к параметру, например "rate_limiter.clients.user1".
+	Kind  string `json:"kind"`  // ChangeAdded, ChangeRemoved или ChangeChanged.
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+	// RestartRequired - параметр не применяется при перечитывании конфигурации (SIGHUP), нужен перезапуск.
+	RestartRequired bool `json:"restart_required,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+%s=%s", c.Field, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("-%s=%s", c.Field, c.Old)
+	default:
+		return fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+	}
+}
+
+// hotReloadable - параметры, которые применяются при перечитывании конфигурации без перезапуска.
+var hotReloadable = []string{
+	"health_check.interval",
+	"health_check.timeout",
+	"health_check.path",
+	"health_check.type",
+	"health_check.grpc_service",
+	"health_check.expected_status",
+	"health_check.expected_body",
+	"health_check.expected_json",
+	"health_check.max_backoff",
+	"health_check.log_repeat_every",
+	"health_check.jitter",
+	"rate_limiter.clients.",
+	"rate_limiter.templates.",
+	"access_list.deny",
+	"access_list.allow",
+	"log_level",
+}
+
+// Diff сравнивает две загруженные конфигурации: состав бэкендов, алгоритм балансировки,
+// параметры Rate Limiter и лимиты клиентов, параметры проверок состояния, списки доступа, уровень логов.
+// Изменения отсортированы по Field.
+func Diff(prev, next *Config) []Change {
+	var changes []Change
+	value := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, Change{Field: field, Kind: ChangeChanged, Old: oldValue, New: newValue})
+		}
+	}
+
+	changes = append(changes, diffSet("backend_servers", prev.BackendServers, next.BackendServers)...)
+	for _, url := range next.BackendServers {
+		if prevWeight, ok := prev.weightOf(url); ok {
+			nextWeight, _ := next.weightOf(url)
+			value("backend_servers."+url+".weight", fmt.Sprint(prevWeight), fmt.Sprint(nextWeight))
+		}
+	}
+	changes = append(changes, diffSet("spillover.backend_servers", prev.Spillover.BackendServers, next.Spillover.BackendServers)...)
+	value("load_balancing_algorithm", prev.LoadBalancingAlgorithm, next.LoadBalancingAlgorithm)
+	value("log_level", prev.LogLevel.String(), next.LogLevel.String())
+
+	oldRL, newRL := &prev.RateLimiter, &next.RateLimiter
+	value("rate_limiter.enabled", fmt.Sprint(oldRL.Enabled), fmt.Sprint(newRL.Enabled))
+	value("rate_limiter.default_rate", fmt.Sprint(oldRL.DefaultRate), fmt.Sprint(newRL.DefaultRate))
+	value("rate_limiter.default_capacity", fmt.Sprint(oldRL.DefaultCapacity), fmt.Sprint(newRL.DefaultCapacity))
+	value("rate_limiter.default_rate_ip", fmt.Sprint(oldRL.DefaultRateIP), fmt.Sprint(newRL.DefaultRateIP))
+	value("rate_limiter.default_capacity_ip", fmt.Sprint(oldRL.DefaultCapacityIP), fmt.Sprint(newRL.DefaultCapacityIP))
+	value("rate_limiter.default_rate_header", fmt.Sprint(oldRL.DefaultRateHeader), fmt.Sprint(newRL.DefaultRateHeader))
+	value("rate_limiter.default_capacity_header", fmt.Sprint(oldRL.DefaultCapacityHeader), fmt.Sprint(newRL.DefaultCapacityHeader))
+	value("rate_limiter.identifier_header", oldRL.IdentifierHeader, newRL.IdentifierHeader)
+	value("rate_limiter.api_keys.enabled", fmt.Sprint(oldRL.APIKeys.Enabled), fmt.Sprint(newRL.APIKeys.Enabled))
+	value("rate_limiter.api_keys.header", oldRL.APIKeys.Header, newRL.APIKeys.Header)
+	value("rate_limiter.api_keys.cache_ttl", oldRL.APIKeys.CacheTTL.String(), newRL.APIKeys.CacheTTL.String())
+	value("rate_limiter.algorithm", oldRL.Algorithm, newRL.Algorithm)
+	value("rate_limiter.soft_limit_ratio", fmt.Sprint(oldRL.SoftLimitRatio), fmt.Sprint(newRL.SoftLimitRatio))
+	value("rate_limiter.idle_bucket_ttl", oldRL.IdleBucketTTL.String(), newRL.IdleBucketTTL.String())
+	value("rate_limiter.max_buckets", fmt.Sprint(oldRL.MaxBuckets), fmt.Sprint(newRL.MaxBuckets))
+	value("rate_limiter.global.rate", fmt.Sprint(oldRL.Global.Rate), fmt.Sprint(newRL.Global.Rate))
+	value("rate_limiter.global.capacity", fmt.Sprint(oldRL.Global.Capacity), fmt.Sprint(newRL.Global.Capacity))
+	value("rate_limiter.max_in_flight_per_client", fmt.Sprint(oldRL.MaxInFlightPerClient), fmt.Sprint(newRL.MaxInFlightPerClient))
+	value("rate_limiter.ipv6_prefix_length", fmt.Sprint(oldRL.IPv6PrefixLength), fmt.Sprint(newRL.IPv6PrefixLength))
+	value("rate_limiter.trusted_proxies", strings.Join(oldRL.TrustedProxies, ","), strings.Join(newRL.TrustedProxies, ","))
+	value("rate_limiter.key_template", oldRL.KeyTemplate, newRL.KeyTemplate)
+
+	changes = append(changes, diffLimits("rate_limiter.clients.", oldRL.Clients, newRL.Clients)...)
+	changes = append(changes, diffLimits("rate_limiter.templates.", oldRL.Templates, newRL.Templates)...)
+
+	oldHC, newHC := &prev.HealthCheck, &next.HealthCheck
+	value("health_check.enabled", fmt.Sprint(oldHC.Enabled), fmt.Sprint(newHC.Enabled))
+	value("health_check.interval", oldHC.Interval.String(), newHC.Interval.String())
+	value("health_check.timeout", oldHC.Timeout.String(), newHC.Timeout.String())
+	value("health_check.path", oldHC.Path, newHC.Path)
+	value("health_check.type", oldHC.Type, newHC.Type)
+	value("health_check.grpc_service", oldHC.GRPCService, newHC.GRPCService)
+	value("health_check.expected_status", fmt.Sprint(oldHC.ExpectedStatus), fmt.Sprint(newHC.ExpectedStatus))
+	value("health_check.expected_body", oldHC.ExpectedBody, newHC.ExpectedBody)
+	value("health_check.expected_json", fmt.Sprint(oldHC.ExpectedJSON), fmt.Sprint(newHC.ExpectedJSON))
+	value("health_check.max_backoff", oldHC.MaxBackoff.String(), newHC.MaxBackoff.String())
+	value("health_check.log_repeat_every", fmt.Sprint(oldHC.LogRepeatEvery), fmt.Sprint(newHC.LogRepeatEvery))
+	value("health_check.jitter", oldHC.Jitter.String(), newHC.Jitter.String())
+	value("health_check.stagger", fmt.Sprint(oldHC.Stagger), fmt.Sprint(newHC.Stagger))
+
+	changes = append(changes, diffSet("access_list.deny", prev.AccessList.Deny, next.AccessList.Deny)...)
+	changes = append(changes, diffSet("access_list.allow", prev.AccessList.Allow, next.AccessList.Allow)...)
+
+	for i := range changes {
+		changes[i].RestartRequired = !isHotReloadable(changes[i].Field)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// diffSet сравнивает списки как множества: порядок элементов не учитывается.
+func diffSet(field string, prev, next []string) []Change {
+	inOld := make(map[string]bool, len(prev))
+	for _, item := range prev {
+		inOld[item] = true
+	}
+	inNew := make(map[string]bool, len(next))
+	for _, item := range next {
+		inNew[item] = true
+	}
+
+	var changes []Change
+	for _, item := range prev {
+		if !inNew[item] {
+			changes = append(changes, Change{Field: field, Kind: ChangeRemoved, Old: item})
+		}
+	}
+	for _, item := range next {
+		if !inOld[item] {
+			changes = append(changes, Change{Field: field, Kind: ChangeAdded, New: item})
+		}
+	}
+	return changes
+}
+
+// diffLimits сравнивает наборы лимитов по имени (клиенты, шаблоны). Шаблоны клиентов уже подставлены
+// при загрузке, поэтому сравниваются итоговые лимиты.
+func diffLimits(prefix string, prev, next map[string]ClientRateConfig) []Change {
+	var changes []Change
+	for name, oldLimit := range prev {
+		newLimit, ok := next[name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Field: prefix + name, Kind: ChangeRemoved, Old: formatLimit(oldLimit)})
+		case formatLimit(oldLimit) != formatLimit(newLimit):
+			changes = append(changes, Change{Field: prefix + name, Kind: ChangeChanged, Old: formatLimit(oldLimit), New: formatLimit(newLimit)})
+		}
+	}
+	for name, newLimit := range next {
+		if _, ok := prev[name]; !ok {
+			changes = append(changes, Change{Field: prefix + name, Kind: ChangeAdded, New: formatLimit(newLimit)})
+		}
+	}
+	return changes
+}
+
+func formatLimit(limit ClientRateConfig) string {
+	return fmt.Sprintf("rate=%v capacity=%v", limit.Rate, limit.Capacity)
+}
+
+func isHotReloadable(field string) bool {
+	for _, prefix := range hotReloadable {
+		if field == prefix || (strings.HasSuffix(prefix, ".") && strings.HasPrefix(field, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReloadReport - результат перечитывания конфигурации (GET /admin/config/last-reload).
+type ReloadReport struct {
+	Time    time.Time `json:"time"`
+	OldHash string    `json:"old_hash"`
+	NewHash string    `json:"new_hash,omitempty"` // Пусто, если файл не удалось загрузить.
+	// Error - причина, по которой конфигурация не загружена или применена не полностью.
+	Error   string   `json:"error,omitempty"`
+	Changes []Change `json:"changes"`
+}
+
+// ReloadLog хранит результат последнего перечитывания конфигурации.
+type ReloadLog struct {
+	mu          sync.Mutex
+	last        *ReloadReport
+	appliedHash string // Хэш последней успешно перечитанной конфигурации.
+}
+
+// Record запоминает результат перечитывания.
+func (l *ReloadLog) Record(report ReloadReport) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.last = &report
+	if report.Error == "" {
+		l.appliedHash = report.NewHash
+	}
+}
+
+// AppliedHash возвращает хэш последней успешно перечитанной конфигурации (пусто, если таких не было).
+func (l *ReloadLog) AppliedHash() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.appliedHash
+}
+
+// Last возвращает результат последнего перечитывания; false, если конфигурация еще не перечитывалась.
+func (l *ReloadLog) Last() (ReloadReport, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.last == nil {
+		return ReloadReport{}, false
+	}
+	return *l.last, true
+}
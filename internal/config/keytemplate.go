@@ -0,0 +1,73 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+
+	"load-balancer/internal/i18n"
+)
+
+// Атрибуты запроса, доступные в шаблоне ключа Rate Limiter (rate_limiter.key_template).
+const (
+	KeyAttrClientID   = "client_id"   // ID клиента: значение identifier_header или IP-адрес.
+	KeyAttrIP         = "ip"          // IP-адрес клиента (с учетом ipv6_prefix_length), даже если задан заголовок.
+	KeyAttrMethod     = "method"      // HTTP-метод.
+	KeyAttrHost       = "host"        // Заголовок Host.
+	KeyAttrPathPrefix = "path_prefix" // Префикс совпавшего маршрута (routes), "/" - маршрут по умолчанию.
+	KeyAttrHeader     = "header"      // Значение заголовка: {header.X-Tenant}.
+	// KeyAttrTLSFingerprint - отпечаток JA3 TLS-клиента (MD5), пусто для запросов не через HTTPS-листенер.
+	KeyAttrTLSFingerprint = "tls_fingerprint"
+)
+
+// KeyPart - часть разобранного шаблона ключа: либо текст, либо атрибут запроса.
+type KeyPart struct {
+	Literal string // Текст между атрибутами (если Attr пусто).
+	Attr    string // Один из KeyAttr*.
+	Header  string // Имя заголовка в канонической форме (для KeyAttrHeader).
+}
+
+// ParseKeyTemplate разбирает шаблон ключа вида "{client_id}:{path_prefix}" или "{header.X-Tenant}:{method}".
+// Шаблон без атрибутов допустим: все запросы делят одну корзину (глобальный лимит).
+func ParseKeyTemplate(template string) ([]KeyPart, error) {
+	var parts []KeyPart
+	rest := template
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			parts = append(parts, KeyPart{Literal: rest})
+			break
+		}
+		if rest[open] == '}' {
+			return nil, i18n.Errorf(i18n.ConfigBadKeyTemplate, template, i18n.T(i18n.ConfigKeyTemplateUnbalanced))
+		}
+		if open > 0 {
+			parts = append(parts, KeyPart{Literal: rest[:open]})
+		}
+		closing := strings.IndexByte(rest[open:], '}')
+		if closing < 0 {
+			return nil, i18n.Errorf(i18n.ConfigBadKeyTemplate, template, i18n.T(i18n.ConfigKeyTemplateUnbalanced))
+		}
+		name := rest[open+1 : open+closing]
+		part, ok := keyAttr(name)
+		if !ok {
+			return nil, i18n.Errorf(i18n.ConfigBadKeyTemplate, template, i18n.T(i18n.ConfigKeyTemplateUnknownAttr, name))
+		}
+		parts = append(parts, part)
+		rest = rest[open+closing+1:]
+	}
+	return parts, nil
+}
+
+func keyAttr(name string) (KeyPart, bool) {
+	if header, ok := strings.CutPrefix(name, KeyAttrHeader+"."); ok {
+		if header == "" || strings.ContainsAny(header, " {") {
+			return KeyPart{}, false
+		}
+		return KeyPart{Attr: KeyAttrHeader, Header: http.CanonicalHeaderKey(header)}, true
+	}
+	switch name {
+	case KeyAttrClientID, KeyAttrIP, KeyAttrMethod, KeyAttrHost, KeyAttrPathPrefix, KeyAttrTLSFingerprint:
+		return KeyPart{Attr: name}, true
+	}
+	return KeyPart{}, false
+}
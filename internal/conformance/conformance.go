@@ -0,0 +1,211 @@
+// Package conformance проверяет, что Rate Limiter с настройками из конфигурации пропускает столько запросов,
+// сколько обещают лимиты (подкоманда "balancer ratelimit-test"). Проверка выполняется на той же реализации
+// и с тем же хранилищем, что и в работе, и выявляет ошибки настройки до выхода в production:
+// недоступное или медленное хранилище, кэш отказов, отклоняющий лишние запросы, и т.п.
+package conformance
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	"sync"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/ratelimiter"
+)
+
+// DefaultTolerance - допустимое по умолчанию отклонение числа пропущенных запросов от ожидаемого.
+const DefaultTolerance = 0.05
+
+// clientPrefix - префикс ключа клиента проверки. Ключ случайный, чтобы не совпасть с клиентом из хранилища
+// и не начать с сохраненного состояния корзины.
+const clientPrefix = "ratelimit-test-"
+
+// Options - параметры проверки.
+type Options struct {
+	Rate        float64       // Скорость пополнения корзины клиента проверки, токенов в секунду.
+	Capacity    float64       // Емкость корзины клиента проверки.
+	Duration    time.Duration // Длительность нагрузки.
+	Concurrency int           // Число горутин, одновременно отправляющих проверки.
+	Tolerance   float64       // Допустимое относительное отклонение; 0 - DefaultTolerance.
+}
+
+// Validate проверяет параметры.
+func (o *Options) Validate() error {
+	switch {
+	case o.Rate <= 0:
+		return i18n.Errorf(i18n.RLTestBadOption, "rate", o.Rate)
+	case o.Capacity < 1:
+		return i18n.Errorf(i18n.RLTestBadOption, "capacity", o.Capacity)
+	case o.Duration <= 0:
+		return i18n.Errorf(i18n.RLTestBadOption, "duration", o.Duration)
+	case o.Concurrency < 1:
+		return i18n.Errorf(i18n.RLTestBadOption, "concurrency", o.Concurrency)
+	case o.Tolerance < 0:
+		return i18n.Errorf(i18n.RLTestBadOption, "tolerance", o.Tolerance)
+	}
+	return nil
+}
+
+// Pinger проверяет доступность хранилища (см. storage.DB.Ping).
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Report - результат проверки.
+type Report struct {
+	Rate        float64 `json:"rate"`
+	Capacity    float64 `json:"capacity"`
+	Duration    string  `json:"duration"` // Фактическая длительность нагрузки.
+	Concurrency int     `json:"concurrency"`
+	Store       string  `json:"store,omitempty"` // Путь к базе лимитов; пусто - корзины только в памяти.
+
+	Checks  int64 `json:"checks"`
+	Allowed int64 `json:"allowed"`
+	Denied  int64 `json:"denied"`
+	Errors  int64 `json:"errors"` // Отказы из-за недоступного хранилища (store_failure_policy: fail_closed).
+
+	// Expected - сколько запросов должна пропустить корзина: емкость плюс пополнение со скоростью
+	// EffectiveRate за время нагрузки.
+	Expected  float64 `json:"expected"`
+	Deviation float64 `json:"deviation"` // (Allowed - Expected) / Expected.
+	Tolerance float64 `json:"tolerance"`
+	// Allowance - допустимое расхождение в запросах: Tolerance от Expected, но не меньше одного пополнения.
+	Allowance float64 `json:"allowance"`
+	// EffectiveRate - достижимая скорость пополнения: Rate, но не больше емкости за ratelimiter.RefillInterval.
+	EffectiveRate float64 `json:"effective_rate"`
+	// MeasuredRate - пропущено сверх емкости в секунду, сравнивается с EffectiveRate.
+	MeasuredRate float64 `json:"measured_rate"`
+	AvgLatency   string  `json:"avg_latency"` // Среднее время одной проверки лимита.
+	MaxLatency   string  `json:"max_latency"`
+
+	Passed   bool     `json:"passed"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// worker - счетчики одной горутины нагрузки.
+type worker struct {
+	checks, allowed, denied, errors int64
+	total, slowest                  time.Duration
+}
+
+// Run создает Rate Limiter из cfg с лимитами по умолчанию из opts и хранилищем store (может быть nil),
+// нагружает одного клиента в течение opts.Duration и сравнивает число пропущенных запросов с ожидаемым.
+// Rate Limiter включается, даже если rate_limiter.enabled: false; обмен расходом (gossip) не запускается:
+// его адрес занят работающим экземпляром, а расход случайного клиента проверки пирам не нужен.
+func Run(cfg config.RateLimiterConfig, store ratelimiter.StoreConfigInterface, opts Options) (*Report, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Tolerance == 0 {
+		opts.Tolerance = DefaultTolerance
+	}
+
+	report := &Report{Rate: opts.Rate, Capacity: opts.Capacity, Concurrency: opts.Concurrency, Tolerance: opts.Tolerance}
+	if store != nil {
+		report.Store = cfg.DatabasePath
+		if pinger, ok := store.(Pinger); ok {
+			if err := ping(pinger, cfg.StoreTimeout); err != nil {
+				report.Problems = append(report.Problems, i18n.T(i18n.RLTestStoreUnavailable, err))
+			}
+		}
+	}
+
+	cfg.Enabled = true
+	cfg.Gossip = config.GossipConfig{}
+	cfg.DefaultRate, cfg.DefaultCapacity = opts.Rate, opts.Capacity
+	cfg.DefaultRateIP, cfg.DefaultCapacityIP = opts.Rate, opts.Capacity
+	cfg.DefaultRateHeader, cfg.DefaultCapacityHeader = opts.Rate, opts.Capacity
+	limiter, err := ratelimiter.New(&cfg, store)
+	if err != nil {
+		return nil, err
+	}
+	defer limiter.Stop()
+
+	id := make([]byte, 8)
+	rand.Read(id)
+	clientID := clientPrefix + hex.EncodeToString(id)
+
+	workers := make([]worker, opts.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	deadline := start.Add(opts.Duration)
+	for i := range workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			for {
+				before := time.Now()
+				if !before.Before(deadline) {
+					return
+				}
+				allowed, err := limiter.Check(clientID)
+				took := time.Since(before)
+				w.checks++
+				w.total += took
+				w.slowest = max(w.slowest, took)
+				switch {
+				case err != nil:
+					w.errors++
+				case allowed:
+					w.allowed++
+				default:
+					w.denied++
+				}
+			}
+		}(&workers[i])
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var total, slowest time.Duration
+	for _, w := range workers {
+		report.Checks += w.checks
+		report.Allowed += w.allowed
+		report.Denied += w.denied
+		report.Errors += w.errors
+		total += w.total
+		slowest = max(slowest, w.slowest)
+	}
+	// Корзина пополняется раз в ratelimiter.RefillInterval и не больше чем до емкости: при rate,
+	// превышающем емкость за период, клиент получает не больше емкости за период
+	perRefill := min(opts.Rate*ratelimiter.RefillInterval.Seconds(), opts.Capacity)
+	report.Duration = elapsed.Round(time.Millisecond).String()
+	report.EffectiveRate = perRefill / ratelimiter.RefillInterval.Seconds()
+	report.Expected = opts.Capacity + report.EffectiveRate*elapsed.Seconds()
+	report.Deviation = (float64(report.Allowed) - report.Expected) / report.Expected
+	report.MeasuredRate = (float64(report.Allowed) - opts.Capacity) / elapsed.Seconds()
+	if report.Checks > 0 {
+		report.AvgLatency = (total / time.Duration(report.Checks)).String()
+	}
+	report.MaxLatency = slowest.String()
+
+	if report.Errors > 0 {
+		report.Problems = append(report.Problems, i18n.T(i18n.RLTestStoreErrors, report.Errors))
+	}
+	if cfg.StoreTimeout > 0 && slowest >= cfg.StoreTimeout {
+		report.Problems = append(report.Problems, i18n.T(i18n.RLTestSlowStore, slowest, cfg.StoreTimeout))
+	}
+	// Пополнение, пришедшееся на границу нагрузки, может как попасть в нее, так и нет:
+	// расхождение на одно пополнение допустимо при любой длительности
+	report.Allowance = max(opts.Tolerance*report.Expected, perRefill+1)
+	if math.Abs(float64(report.Allowed)-report.Expected) > report.Allowance {
+		report.Problems = append(report.Problems, i18n.T(i18n.RLTestDeviation,
+			report.Allowed, report.Expected, report.Deviation*100, report.Allowance))
+	}
+	report.Passed = len(report.Problems) == 0
+	return report, nil
+}
+
+// ping проверяет доступность хранилища за store_timeout (по умолчанию за секунду).
+func ping(pinger Pinger, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return pinger.Ping(ctx)
+}
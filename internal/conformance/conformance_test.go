@@ -0,0 +1,83 @@
+package conformance_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/conformance"
+)
+
+// brokenStore - хранилище лимитов, не отвечающее на запросы.
+type brokenStore struct{}
+
+func (brokenStore) GetClientLimitConfig(string) (float64, float64, bool, error) {
+	return 0, 0, false, errors.New("database is locked")
+}
+func (brokenStore) CreateClientLimit(string, config.ClientRateConfig) error { return nil }
+func (brokenStore) UpdateClientLimit(string, config.ClientRateConfig) error { return nil }
+func (brokenStore) DeleteClientLimit(string) error                          { return nil }
+func (brokenStore) SupportsStatePersistence() bool                          { return false }
+
+// TestRun_InMemory проверяет, что корзина в памяти пропускает емкость и пополнение, а отчет выводится.
+func TestRun_InMemory(t *testing.T) {
+	report, err := conformance.Run(config.RateLimiterConfig{DenialCacheTTL: time.Second}, nil, conformance.Options{
+		Rate: 1000, Capacity: 50, Duration: 300 * time.Millisecond, Concurrency: 2,
+	})
+	require.NoError(t, err)
+
+	assert.True(t, report.Passed, report.Problems)
+	assert.Empty(t, report.Store)
+	assert.Zero(t, report.Errors)
+	assert.GreaterOrEqual(t, report.Allowed, int64(50))
+	assert.Positive(t, report.Denied)
+	assert.Equal(t, report.Checks, report.Allowed+report.Denied)
+	// Пополнение не больше емкости за период: достижимая скорость - 50/с, а не 1000/с
+	assert.Equal(t, 50.0, report.EffectiveRate)
+	assert.Equal(t, conformance.DefaultTolerance, report.Tolerance)
+
+	var out bytes.Buffer
+	require.NoError(t, report.WriteText(&out))
+	assert.NotEmpty(t, out.String())
+}
+
+// TestRun_StoreFailClosed проверяет, что недоступное хранилище при fail_closed приводит к провалу проверки.
+func TestRun_StoreFailClosed(t *testing.T) {
+	cfg := config.RateLimiterConfig{DatabasePath: "limits.db", StoreFailurePolicy: config.StoreFailClosed}
+	report, err := conformance.Run(cfg, brokenStore{}, conformance.Options{
+		Rate: 10, Capacity: 20, Duration: 50 * time.Millisecond, Concurrency: 1,
+	})
+	require.NoError(t, err)
+
+	assert.False(t, report.Passed)
+	assert.Equal(t, "limits.db", report.Store)
+	assert.Zero(t, report.Allowed)
+	assert.Positive(t, report.Errors)
+	assert.Len(t, report.Problems, 2, "ошибки хранилища и отклонение от ожидаемого")
+}
+
+// TestOptions_Validate проверяет отклонение неверных параметров.
+func TestOptions_Validate(t *testing.T) {
+	valid := conformance.Options{Rate: 10, Capacity: 20, Duration: time.Second, Concurrency: 1}
+	require.NoError(t, valid.Validate())
+
+	for name, modify := range map[string]func(*conformance.Options){
+		"rate":        func(o *conformance.Options) { o.Rate = 0 },
+		"capacity":    func(o *conformance.Options) { o.Capacity = 0.5 },
+		"duration":    func(o *conformance.Options) { o.Duration = 0 },
+		"concurrency": func(o *conformance.Options) { o.Concurrency = 0 },
+		"tolerance":   func(o *conformance.Options) { o.Tolerance = -1 },
+	} {
+		opts := valid
+		modify(&opts)
+		assert.Error(t, opts.Validate(), name)
+	}
+
+	_, err := conformance.Run(config.RateLimiterConfig{}, nil, conformance.Options{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,31 @@
+package conformance
+
+import (
+	"fmt"
+	"io"
+
+	"load-balancer/internal/i18n"
+)
+
+// WriteText выводит отчет для оператора.
+func (r *Report) WriteText(w io.Writer) error {
+	store := i18n.T(i18n.RLTestReportMemory)
+	if r.Store != "" {
+		store = r.Store
+	}
+	fmt.Fprintln(w, i18n.T(i18n.RLTestReportSummary, r.Rate, r.Capacity, store, r.Duration, r.Concurrency))
+	fmt.Fprintln(w, i18n.T(i18n.RLTestReportTotals, r.Checks, r.Allowed, r.Denied, r.Errors))
+	fmt.Fprintln(w, i18n.T(i18n.RLTestReportExpected, r.Expected, r.Deviation*100, r.Allowance))
+	fmt.Fprintln(w, i18n.T(i18n.RLTestReportRate, r.MeasuredRate, r.EffectiveRate, r.AvgLatency, r.MaxLatency))
+	if r.Passed {
+		_, err := fmt.Fprintln(w, i18n.T(i18n.RLTestReportPassed))
+		return err
+	}
+	fmt.Fprintln(w, i18n.T(i18n.RLTestReportFailed))
+	for _, problem := range r.Problems {
+		if _, err := fmt.Fprintf(w, "  - %s\n", problem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
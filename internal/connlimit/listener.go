@@ -0,0 +1,103 @@
+// Package connlimit ограничивает число одновременно открытых соединений с одного IP-адреса
+// на уровне листенера (connection_limit.max_per_ip).
+package connlimit
+
+import (
+	"net"
+	"sync"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var rejectedTotal = metrics.NewCounter("connlimit_rejected_total",
+	"Количество соединений, закрытых при принятии из-за превышения connection_limit.max_per_ip.")
+
+// Listener - листенер, который закрывает соединения с IP-адреса, у которого уже открыто maxPerIP соединений.
+// Соединение освобождает место при закрытии, в том числе после Hijack (WebSocket, CONNECT).
+type Listener struct {
+	net.Listener
+	maxPerIP int
+
+	mu   sync.Mutex
+	open map[string]int // IP-адрес -> открытые соединения.
+}
+
+// Wrap возвращает ln с ограничением соединений; maxPerIP <= 0 - ln без изменений.
+func Wrap(ln net.Listener, maxPerIP int) net.Listener {
+	if maxPerIP <= 0 {
+		return ln
+	}
+	i18n.Logf(i18n.ConnLimitEnabled, ln.Addr(), maxPerIP)
+	return &Listener{Listener: ln, maxPerIP: maxPerIP, open: make(map[string]int)}
+}
+
+// Accept принимает следующее соединение в пределах лимита; соединения сверх лимита закрываются
+// без передачи серверу.
+func (l *Listener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip := remoteIP(conn)
+		if open, ok := l.acquire(ip); !ok {
+			rejectedTotal.Inc()
+			i18n.Logf(i18n.ConnLimitRejected, ip, open)
+			_ = conn.Close()
+			continue
+		}
+		return &limitedConn{Conn: conn, release: func() { l.release(ip) }}, nil
+	}
+}
+
+// Open возвращает число открытых соединений с IP-адреса ip.
+func (l *Listener) Open(ip string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.open[ip]
+}
+
+// acquire занимает место для соединения с ip; false - лимит исчерпан, open - открытые соединения.
+func (l *Listener) acquire(ip string) (open int, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	open = l.open[ip]
+	if open >= l.maxPerIP {
+		return open, false
+	}
+	l.open[ip] = open + 1
+	return open + 1, true
+}
+
+func (l *Listener) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.open[ip] <= 1 {
+		delete(l.open, ip)
+		return
+	}
+	l.open[ip]--
+}
+
+// remoteIP возвращает IP-адрес клиента без порта.
+func remoteIP(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// limitedConn освобождает место в лимите при первом закрытии.
+type limitedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
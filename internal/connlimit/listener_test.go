@@ -0,0 +1,77 @@
+package connlimit_test
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/connlimit"
+)
+
+// TestListener проверяет, что соединения сверх лимита закрываются при принятии,
+// а закрытое соединение освобождает место.
+func TestListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	limited := connlimit.Wrap(ln, 2).(*connlimit.Listener)
+	defer limited.Close()
+
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := limited.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+	next := func() net.Conn {
+		select {
+		case conn := <-accepted:
+			return conn
+		case <-time.After(2 * time.Second):
+			t.Fatal("Соединение не принято")
+			return nil
+		}
+	}
+
+	dial()
+	first := next()
+	dial()
+	next()
+	assert.Equal(t, 2, limited.Open("127.0.0.1"))
+
+	// Третье соединение закрывается листенером, сервер его не получает
+	rejected := dial()
+	require.NoError(t, rejected.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = rejected.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Empty(t, accepted)
+
+	// Закрытие освобождает место (повторное закрытие не освобождает его дважды)
+	require.NoError(t, first.Close())
+	_ = first.Close()
+	assert.Equal(t, 1, limited.Open("127.0.0.1"))
+	dial()
+	next()
+	assert.Equal(t, 2, limited.Open("127.0.0.1"))
+}
+
+// TestWrap_NoLimit проверяет, что без лимита листенер не оборачивается.
+func TestWrap_NoLimit(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	assert.Same(t, ln, connlimit.Wrap(ln, 0))
+}
@@ -0,0 +1,240 @@
+package forwardproxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"load-balancer/internal/balancer"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/ratelimiter"
+	"load-balancer/internal/response"
+)
+
+// gatewayKey - ключ контекста, через который транспорту передается выбранный шлюз.
+type gatewayKey struct{}
+
+// Proxy - прямой прокси для исходящих запросов внутренних клиентов.
+// Туннели CONNECT и запросы с абсолютным URL направляются через egress-шлюз,
+// выбранный пулом шлюзов (алгоритм и health checks - как у обычного балансировщика).
+type Proxy struct {
+	gateways    *balancer.Balancer
+	limiter     balancer.Limiter
+	dialTimeout time.Duration
+	forwarder   *httputil.ReverseProxy // Запросы с абсолютным URL (обычный HTTP).
+}
+
+// New создает прямой прокси. gateways - пул egress-шлюзов (URL HTTP-прокси),
+// limiter (может быть nil) ограничивает клиентов так же, как в режиме балансировщика.
+func New(gateways *balancer.Balancer, limiter balancer.Limiter, dialTimeout time.Duration) *Proxy {
+	p := &Proxy{
+		gateways:    gateways,
+		limiter:     limiter,
+		dialTimeout: dialTimeout,
+	}
+	p.forwarder = &httputil.ReverseProxy{
+		// URL запроса уже абсолютный; Rewrite также убирает X-Forwarded-*, чтобы не раскрывать внутренние адреса
+		Rewrite: func(*httputil.ProxyRequest) {},
+		Transport: &http.Transport{
+			Proxy: func(req *http.Request) (*url.URL, error) {
+				return req.Context().Value(gatewayKey{}).(*balancer.Backend).URL, nil
+			},
+			DialContext:         (&net.Dialer{Timeout: dialTimeout}).DialContext,
+			TLSHandshakeTimeout: dialTimeout,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if balancer.ClientDisconnected(w, r) {
+				return
+			}
+			p.gatewayFailed(w, r.Context().Value(gatewayKey{}).(*balancer.Backend), err)
+		},
+	}
+	i18n.Logf(i18n.FPInitialized, gateways.Snapshot().Total, dialTimeout)
+	return p
+}
+
+// ServeHTTP обрабатывает запрос клиента прокси: CONNECT открывает туннель,
+// запрос с абсолютным URL пересылается через шлюз.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	clientID := r.RemoteAddr
+	if p.limiter != nil {
+		var source ratelimiter.IDSource
+		clientID, source = balancer.ClientIdentity(p.limiter, r)
+		limitKey := clientID
+		if keyed, ok := p.limiter.(balancer.KeyLimiter); ok {
+			// У прямого прокси нет маршрутов: {path_prefix} всегда "/"
+			limitKey = keyed.LimitKey(r, clientID, "")
+		}
+		var allowed bool
+		var err error
+		if quota, ok := p.limiter.(balancer.QuotaLimiter); ok {
+			var decision ratelimiter.Decision
+			decision, err = quota.Decide(limitKey, source, 1)
+			allowed = decision.Allowed
+		} else {
+			allowed, err = p.limiter.Check(limitKey)
+		}
+		if err != nil {
+			response.RespondWithError(w, http.StatusServiceUnavailable, response.CodeRateLimitStoreDown, i18n.T(i18n.BalancerStoreUnavailable))
+			return
+		}
+		if !allowed {
+			response.RespondWithError(w, http.StatusTooManyRequests, response.CodeRateLimited, i18n.T(i18n.BalancerRateLimited))
+			return
+		}
+	}
+
+	if r.Method != http.MethodConnect && !r.URL.IsAbs() {
+		response.RespondWithError(w, http.StatusBadRequest, response.CodeNotProxyRequest, i18n.T(i18n.FPNotProxyRequest))
+		return
+	}
+
+	gateway, index, err := p.gateways.NextBackend()
+	if err != nil {
+		response.RespondWithError(w, http.StatusServiceUnavailable, response.CodeNoHealthyBackends, i18n.T(i18n.BalancerAllBackendsDown))
+		return
+	}
+	i18n.Logf(i18n.FPRequest, r.Method, r.Host, clientID, index, gateway.URL)
+
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r, gateway)
+		return
+	}
+	p.forwarder.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), gatewayKey{}, gateway)))
+}
+
+// tunnel устанавливает туннель к r.Host через шлюз и соединяет его с клиентом.
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request, gateway *balancer.Backend) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		response.RespondWithError(w, http.StatusInternalServerError, response.CodeInternal, i18n.T(i18n.FPHijackUnsupported))
+		return
+	}
+
+	upstream, upstreamReader, err := p.connectViaGateway(gateway, r.Host)
+	if err != nil {
+		p.gatewayFailed(w, gateway, err)
+		return
+	}
+	if upstreamReader == nil {
+		// Шлюз доступен, но отказал в туннеле (например, по своей политике)
+		upstream.Close()
+		response.RespondWithError(w, http.StatusBadGateway, response.CodeBadGateway, i18n.T(i18n.FPGatewayRefused, r.Host))
+		return
+	}
+
+	client, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		i18n.Logf(i18n.FPHijackFailed, err)
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+	splice(client, clientBuf.Reader, upstream, upstreamReader)
+}
+
+// connectViaGateway подключается к шлюзу и запрашивает у него туннель к target.
+// Если шлюз ответил не 2xx, возвращает соединение с nil reader: шлюз исправен, но туннель не открыт.
+func (p *Proxy) connectViaGateway(gateway *balancer.Backend, target string) (net.Conn, *bufio.Reader, error) {
+	dialer := &net.Dialer{Timeout: p.dialTimeout}
+	var conn net.Conn
+	var err error
+	if gateway.URL.Scheme == "https" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", gatewayAddr(gateway.URL), &tls.Config{ServerName: gateway.URL.Hostname()})
+	} else {
+		conn, err = dialer.Dial("tcp", gatewayAddr(gateway.URL))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Now().Add(p.dialTimeout))
+
+	connectReq := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: target},
+		Host:   target,
+		Header: make(http.Header),
+	}
+	if user := gateway.URL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		connectReq.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := connectReq.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, connectReq)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		i18n.Logf(i18n.FPGatewayStatus, gateway.URL, target, resp.Status)
+		return conn, nil, nil
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, reader, nil
+}
+
+// gatewayFailed помечает шлюз недоступным и отвечает клиенту 502.
+func (p *Proxy) gatewayFailed(w http.ResponseWriter, gateway *balancer.Backend, err error) {
+	i18n.Logf(i18n.FPGatewayFailed, gateway.URL, err)
+	gateway.SetAlive(false)
+	response.RespondWithError(w, http.StatusBadGateway, response.CodeBadGateway, i18n.T(i18n.BalancerBadGateway))
+}
+
+// gatewayAddr возвращает host:port шлюза, подставляя порт по умолчанию для схемы.
+func gatewayAddr(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// splice копирует данные между клиентом и шлюзом в обе стороны, пока обе стороны не закроются.
+// Readers содержат данные, уже прочитанные в буферы при разборе HTTP.
+func splice(client net.Conn, clientReader io.Reader, upstream net.Conn, upstreamReader io.Reader) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(upstream, clientReader)
+		closeWrite(upstream)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(client, upstreamReader)
+		closeWrite(client)
+	}()
+	wg.Wait()
+	client.Close()
+	upstream.Close()
+}
+
+// closeWrite сообщает другой стороне об окончании данных, не закрывая чтение.
+func closeWrite(conn net.Conn) {
+	if tcp, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = tcp.CloseWrite()
+		return
+	}
+	conn.Close()
+}
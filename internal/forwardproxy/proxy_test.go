@@ -0,0 +1,186 @@
+package forwardproxy_test
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/balancer"
+	"load-balancer/internal/config"
+	"load-balancer/internal/forwardproxy"
+	"load-balancer/internal/ratelimiter"
+	"load-balancer/internal/response"
+)
+
+// fakeGateway - упрощенный egress-шлюз: открывает туннели CONNECT и отвечает на запросы с абсолютным URL.
+type fakeGateway struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newFakeGateway(t *testing.T) *fakeGateway {
+	g := &fakeGateway{}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		g.requests = append(g.requests, r)
+		g.mu.Unlock()
+
+		if r.Method != http.MethodConnect {
+			_, _ = io.WriteString(w, "gateway:"+r.URL.String())
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		client, buf, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		_, _ = client.Write([]byte("HTTP/1.1 200 OK\r\n\r\n"))
+		go func() {
+			_, _ = io.Copy(target, buf)
+			target.Close()
+		}()
+		_, _ = io.Copy(client, target)
+		client.Close()
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func (g *fakeGateway) received() []*http.Request {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]*http.Request(nil), g.requests...)
+}
+
+// newProxy запускает прямой прокси поверх пула шлюзов.
+func newProxy(t *testing.T, limiter *ratelimiter.RateLimiter, gateways ...string) (*httptest.Server, *balancer.Balancer) {
+	pool, err := balancer.New(gateways, limiter, config.HealthCheckConfig{}, "round_robin")
+	require.NoError(t, err)
+	server := httptest.NewServer(forwardproxy.New(pool, limiter, time.Second))
+	t.Cleanup(server.Close)
+	return server, pool
+}
+
+func newClient(proxyURL string) *http.Client {
+	u, _ := url.Parse(proxyURL)
+	return &http.Client{Transport: &http.Transport{
+		Proxy:             http.ProxyURL(u),
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true, // Каждый запрос - новый туннель
+	}}
+}
+
+func disabledLimiter(t *testing.T) *ratelimiter.RateLimiter {
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	return rl
+}
+
+// TestProxy_ConnectBalancing проверяет туннели CONNECT и их распределение по шлюзам.
+func TestProxy_ConnectBalancing(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "secret data")
+	}))
+	defer target.Close()
+
+	gw1, gw2 := newFakeGateway(t), newFakeGateway(t)
+	proxy, _ := newProxy(t, disabledLimiter(t), gw1.URL, gw2.URL)
+	client := newClient(proxy.URL)
+
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get(target.URL)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "secret data", string(body))
+	}
+
+	targetHost := target.Listener.Addr().String()
+	for _, gw := range []*fakeGateway{gw1, gw2} {
+		requests := gw.received()
+		require.Len(t, requests, 2, "Туннели должны распределяться по шлюзам Round Robin")
+		assert.Equal(t, http.MethodConnect, requests[0].Method)
+		assert.Equal(t, targetHost, requests[0].Host)
+	}
+}
+
+// TestProxy_AbsoluteURL проверяет пересылку обычных HTTP-запросов через шлюз.
+func TestProxy_AbsoluteURL(t *testing.T) {
+	gw := newFakeGateway(t)
+	proxy, _ := newProxy(t, disabledLimiter(t), gw.URL)
+
+	resp, err := newClient(proxy.URL).Get("http://internal.example/path?q=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "gateway:http://internal.example/path?q=1", string(body))
+
+	requests := gw.received()
+	require.Len(t, requests, 1)
+	assert.Empty(t, requests[0].Header.Get("X-Forwarded-For"), "Внутренние адреса не должны уходить наружу")
+}
+
+// TestProxy_Errors проверяет ответы при недоступном шлюзе, не-прокси запросе и превышении лимита.
+func TestProxy_Errors(t *testing.T) {
+	decode := func(resp *http.Response) response.ErrorResponse {
+		defer resp.Body.Close()
+		var errResp response.ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		return errResp
+	}
+
+	// Шлюз недоступен: 502 и шлюз исключается, затем 503
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+	proxy, pool := newProxy(t, disabledLimiter(t), deadURL)
+	client := newClient(proxy.URL)
+
+	resp, err := client.Get("http://internal.example/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, response.CodeBadGateway, decode(resp).ErrorCode)
+	assert.False(t, pool.Snapshot().Backends[0].Alive)
+
+	resp, err = client.Get("http://internal.example/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, response.CodeNoHealthyBackends, decode(resp).ErrorCode)
+
+	// Запрос не к прокси (относительный URL)
+	resp, err = http.Get(proxy.URL + "/path")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, response.CodeNotProxyRequest, decode(resp).ErrorCode)
+
+	// Rate Limiter ограничивает клиентов прямого прокси
+	limiter, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 1}, nil)
+	require.NoError(t, err)
+	defer limiter.Stop()
+	gw := newFakeGateway(t)
+	limitedProxy, _ := newProxy(t, limiter, gw.URL)
+	limitedClient := newClient(limitedProxy.URL)
+
+	resp, err = limitedClient.Get("http://internal.example/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = limitedClient.Get("http://internal.example/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, response.CodeRateLimited, decode(resp).ErrorCode)
+}
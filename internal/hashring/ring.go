@@ -0,0 +1,87 @@
+// Package hashring реализует кольцо согласованного хеширования (ring hash) с виртуальными узлами.
+//
+// Ключ (например, ID клиента) отображается на точку кольца и обслуживается первым по часовой
+// стрелке узлом. При выходе узла из строя его ключи переходят к следующим узлам кольца,
+// а ключи остальных узлов не перемещаются; после восстановления узел получает свои ключи обратно.
+package hashring
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// DefaultReplicas - количество виртуальных узлов на единицу веса узла.
+const DefaultReplicas = 100
+
+type point struct {
+	hash uint64
+	node int
+}
+
+// Ring - неизменяемое кольцо узлов. Узлы обозначаются индексами в списке, переданном в New.
+type Ring struct {
+	points []point
+	nodes  int
+}
+
+// New строит кольцо: у узла names[i] weights[i]*replicas виртуальных узлов (вес < 1 считается 1).
+// weights может быть nil - все веса равны 1.
+func New(names []string, weights []int, replicas int) *Ring {
+	if replicas < 1 {
+		replicas = DefaultReplicas
+	}
+	r := &Ring{nodes: len(names)}
+	for node, name := range names {
+		weight := 1
+		if weights != nil && weights[node] > 1 {
+			weight = weights[node]
+		}
+		for i := 0; i < weight*replicas; i++ {
+			r.points = append(r.points, point{hash: hash(name + "#" + strconv.Itoa(i)), node: node})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i].hash < r.points[j].hash
+	})
+	return r
+}
+
+// Walk обходит узлы по кольцу, начиная с позиции ключа: каждый узел передается в visit один раз,
+// обход прекращается, когда visit возвращает false или узлы закончились.
+func (r *Ring) Walk(key string, visit func(node int) bool) {
+	if len(r.points) == 0 {
+		return
+	}
+	h := hash(key)
+	start := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+	seen := make([]bool, r.nodes)
+	left := r.nodes
+	for i := 0; i < len(r.points) && left > 0; i++ {
+		p := r.points[(start+i)%len(r.points)]
+		if seen[p.node] {
+			continue
+		}
+		seen[p.node] = true
+		left--
+		if !visit(p.node) {
+			return
+		}
+	}
+}
+
+// hash - FNV-1a с перемешиванием битов (финализатор splitmix64): у FNV близкие ключи
+// ("backend#1", "backend#2") дают близкие значения, и точки узла легли бы на кольцо кучно.
+func hash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
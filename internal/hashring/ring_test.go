@@ -0,0 +1,62 @@
+package hashring_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"load-balancer/internal/hashring"
+)
+
+// first возвращает узел, обслуживающий ключ, если недоступные узлы пропускаются.
+func first(r *hashring.Ring, key string, down map[int]bool) int {
+	chosen := -1
+	r.Walk(key, func(node int) bool {
+		if down[node] {
+			return true
+		}
+		chosen = node
+		return false
+	})
+	return chosen
+}
+
+// TestRing_Stability проверяет, что при выходе узла из строя перемещаются только его ключи,
+// а после восстановления ключи возвращаются на прежние узлы.
+func TestRing_Stability(t *testing.T) {
+	ring := hashring.New([]string{"a", "b", "c", "d"}, nil, 0)
+	before := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		key := "client-" + strconv.Itoa(i)
+		before[key] = first(ring, key, nil)
+	}
+
+	down := map[int]bool{1: true}
+	for key, node := range before {
+		after := first(ring, key, down)
+		if node != 1 {
+			assert.Equal(t, node, after, "Ключ %s не должен переместиться", key)
+		} else {
+			assert.NotEqual(t, 1, after)
+		}
+	}
+	for key, node := range before {
+		assert.Equal(t, node, first(ring, key, nil))
+	}
+
+	// Все узлы недоступны: обход заканчивается, каждый узел посещен один раз
+	visited := 0
+	ring.Walk("client-1", func(int) bool { visited++; return true })
+	assert.Equal(t, 4, visited)
+}
+
+// TestRing_Weights проверяет, что доля ключей узла пропорциональна его весу.
+func TestRing_Weights(t *testing.T) {
+	ring := hashring.New([]string{"heavy", "light"}, []int{3, 1}, 0)
+	counts := make([]int, 2)
+	for i := 0; i < 20000; i++ {
+		counts[first(ring, "client-"+strconv.Itoa(i), nil)]++
+	}
+	assert.InDelta(t, 0.75, float64(counts[0])/20000, 0.05)
+}
@@ -0,0 +1,80 @@
+package headers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// hopByHop - заголовки соединения (RFC 7230, раздел 6.1; Proxy-* - RFC 7235): они относятся к одному участку
+// пути между клиентом и бэкендом и не пересылаются прокси дальше. Proxy-Connection не стандартный,
+// но его отправляют старые клиенты.
+var hopByHop = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// RemoveHopByHop удаляет заголовки соединения из запроса к бэкенду или ответа клиенту: стандартные
+// и перечисленные в Connection. Исключения:
+//   - при смене протокола (Connection: upgrade) остаются "Connection: Upgrade" и Upgrade, иначе
+//     WebSocket и h2c не установятся;
+//   - "TE: trailers" остается: клиент принимает трейлеры (их использует gRPC).
+func RemoveHopByHop(h http.Header) {
+	var upgrade string
+	for _, name := range connectionTokens(h) {
+		if strings.EqualFold(name, "upgrade") {
+			upgrade = h.Get("Upgrade")
+		}
+		h.Del(name)
+	}
+	trailers := hasToken(h["Te"], "trailers")
+	for _, name := range hopByHop {
+		h.Del(name)
+	}
+
+	if upgrade != "" {
+		h.Set("Connection", "Upgrade")
+		h.Set("Upgrade", upgrade)
+	}
+	if trailers {
+		h.Set("Te", "trailers")
+	}
+}
+
+// UpgradeRequested сообщает, что запрос требует смены протокола (WebSocket, h2c): Connection содержит
+// upgrade (в том числе среди других токенов, например "keep-alive, Upgrade") и задан Upgrade.
+func UpgradeRequested(h http.Header) bool {
+	return h.Get("Upgrade") != "" && hasToken(h["Connection"], "upgrade")
+}
+
+// connectionTokens возвращает имена из заголовков Connection (через запятую, возможно в нескольких строках).
+func connectionTokens(h http.Header) []string {
+	var tokens []string
+	for _, value := range h["Connection"] {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+// hasToken проверяет, что в значениях заголовка есть токен (без учета регистра и параметров после ";").
+func hasToken(values []string, token string) bool {
+	for _, value := range values {
+		for _, candidate := range strings.Split(value, ",") {
+			candidate, _, _ = strings.Cut(candidate, ";")
+			if strings.EqualFold(strings.TrimSpace(candidate), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package headers
+
+import (
+	"net/http"
+
+	"load-balancer/internal/config"
+)
+
+// RedactedValue подставляется в логах вместо значений чувствительных заголовков.
+const RedactedValue = "[REDACTED]"
+
+// Policy определяет, какие входящие заголовки маскируются в логах и какие пересылаются бэкенду.
+type Policy struct {
+	sensitive map[string]struct{}
+	allow     map[string]struct{} // Если не пусто - пересылаются только эти заголовки.
+	deny      map[string]struct{}
+	// stripSensitive - не пересылать чувствительные заголовки (для недоверенных маршрутов).
+	stripSensitive bool
+	acceptEncoding string // Режим Accept-Encoding (config.AcceptEncoding*).
+}
+
+// NewPolicy строит политику из глобальных настроек и (опционально) настроек маршрута.
+// Списки allow/deny маршрута заменяют глобальные, список sensitive - дополняет.
+func NewPolicy(global config.HeaderPolicyConfig, route *config.RouteConfig) *Policy {
+	p := &Policy{
+		sensitive:      toSet(global.Sensitive),
+		allow:          toSet(global.Allow),
+		deny:           toSet(global.Deny),
+		acceptEncoding: global.AcceptEncoding,
+	}
+
+	if route != nil {
+		for name := range toSet(route.Headers.Sensitive) {
+			p.sensitive[name] = struct{}{}
+		}
+		if len(route.Headers.Allow) > 0 {
+			p.allow = toSet(route.Headers.Allow)
+		}
+		if len(route.Headers.Deny) > 0 {
+			p.deny = toSet(route.Headers.Deny)
+		}
+		if route.Headers.AcceptEncoding != "" {
+			p.acceptEncoding = route.Headers.AcceptEncoding
+		}
+		p.stripSensitive = route.Untrusted
+	}
+
+	return p
+}
+
+// IsSensitive проверяет, является ли заголовок чувствительным.
+func (p *Policy) IsSensitive(name string) bool {
+	_, ok := p.sensitive[http.CanonicalHeaderKey(name)]
+	return ok
+}
+
+// Redact возвращает копию заголовков, в которой значения чувствительных заголовков замаскированы.
+// Используется везде, где заголовки попадают в логи.
+func (p *Policy) Redact(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for name, values := range h {
+		if p.IsSensitive(name) {
+			redacted[name] = []string{RedactedValue}
+			continue
+		}
+		redacted[name] = append([]string(nil), values...)
+	}
+	return redacted
+}
+
+// Scrub удаляет из заголовков исходящего запроса все, что не должно дойти до бэкенда,
+// и применяет режим Accept-Encoding.
+func (p *Policy) Scrub(h http.Header) {
+	for name := range h {
+		canonical := http.CanonicalHeaderKey(name)
+		if _, denied := p.deny[canonical]; denied {
+			h.Del(name)
+			continue
+		}
+		if p.stripSensitive && p.IsSensitive(canonical) {
+			h.Del(name)
+			continue
+		}
+		if len(p.allow) > 0 {
+			if _, allowed := p.allow[canonical]; !allowed {
+				h.Del(name)
+			}
+		}
+	}
+	p.applyAcceptEncoding(h)
+}
+
+// applyAcceptEncoding задает Accept-Encoding, отправляемый бэкенду, чтобы кэширование
+// и переписывание ответов могли работать с несжатым телом.
+func (p *Policy) applyAcceptEncoding(h http.Header) {
+	switch p.acceptEncoding {
+	case config.AcceptEncodingStrip:
+		h.Del("Accept-Encoding")
+	case config.AcceptEncodingIdentity:
+		h.Set("Accept-Encoding", "identity")
+	}
+}
+
+// toSet приводит имена заголовков к каноническому виду и складывает их во множество.
+func toSet(names []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		set[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,120 @@
+package headers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/headers"
+)
+
+// TestPolicy_Redact проверяет маскирование чувствительных заголовков для логов.
+func TestPolicy_Redact(t *testing.T) {
+	p := headers.NewPolicy(config.HeaderPolicyConfig{Sensitive: []string{"authorization", "Cookie"}}, nil)
+
+	h := http.Header{}
+	h.Set("Authorization", "Bearer secret")
+	h.Set("Cookie", "session=abc")
+	h.Set("Accept", "text/plain")
+
+	redacted := p.Redact(h)
+	assert.Equal(t, headers.RedactedValue, redacted.Get("Authorization"))
+	assert.Equal(t, headers.RedactedValue, redacted.Get("Cookie"))
+	assert.Equal(t, "text/plain", redacted.Get("Accept"))
+	// Исходные заголовки не должны меняться
+	assert.Equal(t, "Bearer secret", h.Get("Authorization"))
+}
+
+// TestPolicy_Scrub проверяет удаление заголовков перед пересылкой бэкенду.
+func TestPolicy_Scrub(t *testing.T) {
+	global := config.HeaderPolicyConfig{
+		Sensitive: []string{"Authorization", "Cookie"},
+		Deny:      []string{"X-Internal-Debug"},
+	}
+
+	// Доверенный маршрут: удаляются только заголовки из deny
+	h := http.Header{}
+	h.Set("Authorization", "Bearer secret")
+	h.Set("X-Internal-Debug", "1")
+	headers.NewPolicy(global, nil).Scrub(h)
+	assert.Equal(t, "Bearer secret", h.Get("Authorization"))
+	assert.Empty(t, h.Get("X-Internal-Debug"))
+
+	// Недоверенный маршрут: дополнительно удаляются чувствительные заголовки
+	route := &config.RouteConfig{PathPrefix: "/partner", Untrusted: true}
+	h = http.Header{}
+	h.Set("Authorization", "Bearer secret")
+	h.Set("Cookie", "session=abc")
+	h.Set("Accept", "text/plain")
+	headers.NewPolicy(global, route).Scrub(h)
+	assert.Empty(t, h.Get("Authorization"))
+	assert.Empty(t, h.Get("Cookie"))
+	assert.Equal(t, "text/plain", h.Get("Accept"))
+
+	// Allowlist маршрута: пересылаются только перечисленные заголовки
+	route = &config.RouteConfig{PathPrefix: "/api", Headers: config.HeaderPolicyConfig{Allow: []string{"Accept"}}}
+	h = http.Header{}
+	h.Set("Accept", "text/plain")
+	h.Set("X-Custom", "value")
+	headers.NewPolicy(global, route).Scrub(h)
+	assert.Equal(t, "text/plain", h.Get("Accept"))
+	assert.Empty(t, h.Get("X-Custom"))
+}
+
+// TestPolicy_AcceptEncoding проверяет режимы Accept-Encoding и их переопределение в маршруте.
+func TestPolicy_AcceptEncoding(t *testing.T) {
+	scrub := func(global string, route *config.RouteConfig) http.Header {
+		h := http.Header{}
+		h.Set("Accept-Encoding", "br, zstd, gzip")
+		headers.NewPolicy(config.HeaderPolicyConfig{AcceptEncoding: global}, route).Scrub(h)
+		return h
+	}
+
+	assert.Equal(t, "br, zstd, gzip", scrub(config.AcceptEncodingPass, nil).Get("Accept-Encoding"))
+	assert.Empty(t, scrub(config.AcceptEncodingStrip, nil).Values("Accept-Encoding"))
+	assert.Equal(t, "identity", scrub(config.AcceptEncodingIdentity, nil).Get("Accept-Encoding"))
+
+	// Маршрут переопределяет глобальный режим; пустое значение наследует его
+	route := &config.RouteConfig{PathPrefix: "/rewrite", Headers: config.HeaderPolicyConfig{AcceptEncoding: config.AcceptEncodingIdentity}}
+	assert.Equal(t, "identity", scrub(config.AcceptEncodingPass, route).Get("Accept-Encoding"))
+	assert.Empty(t, scrub(config.AcceptEncodingStrip, &config.RouteConfig{PathPrefix: "/api"}).Values("Accept-Encoding"))
+
+	// identity задается, даже если allowlist не пропускает Accept-Encoding
+	route = &config.RouteConfig{PathPrefix: "/api", Headers: config.HeaderPolicyConfig{Allow: []string{"Accept"}, AcceptEncoding: config.AcceptEncodingIdentity}}
+	assert.Equal(t, "identity", scrub(config.AcceptEncodingPass, route).Get("Accept-Encoding"))
+}
+
+// TestRemoveHopByHop проверяет удаление заголовков соединения и исключения для смены протокола и трейлеров.
+func TestRemoveHopByHop(t *testing.T) {
+	h := http.Header{}
+	h.Add("Connection", "keep-alive, X-Hop")
+	h.Add("Connection", "x-other")
+	h.Set("X-Hop", "1")
+	h.Set("X-Other", "1")
+	h.Set("Keep-Alive", "timeout=5")
+	h.Set("Proxy-Authorization", "Basic c2VjcmV0")
+	h.Set("Transfer-Encoding", "chunked")
+	h.Set("Te", "trailers, deflate;q=0.5")
+	h.Set("Accept", "text/plain")
+	headers.RemoveHopByHop(h)
+	assert.Equal(t, http.Header{"Accept": {"text/plain"}, "Te": {"trailers"}}, h)
+
+	// Смена протокола: Upgrade и "Connection: Upgrade" сохраняются, остальное удаляется
+	h = http.Header{}
+	h.Set("Connection", "keep-alive, Upgrade")
+	h.Set("Upgrade", "websocket")
+	h.Set("Keep-Alive", "timeout=5")
+	h.Set("Te", "gzip")
+	assert.True(t, headers.UpgradeRequested(h))
+	headers.RemoveHopByHop(h)
+	assert.Equal(t, http.Header{"Connection": {"Upgrade"}, "Upgrade": {"websocket"}}, h)
+
+	// Upgrade без Connection: upgrade не пересылается
+	h = http.Header{}
+	h.Set("Upgrade", "websocket")
+	assert.False(t, headers.UpgradeRequested(h))
+	headers.RemoveHopByHop(h)
+	assert.Empty(t, h)
+}
@@ -0,0 +1,195 @@
+// Package hooks выполняет сценарий admission_hook на Starlark: собственные правила допуска запросов
+// (отклонение, стоимость в токенах, выбор пула) без изменения кода балансировщика.
+package hooks
+
+import (
+	"math"
+	"os"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var errorsTotal = metrics.NewCounter("hooks_errors_total",
+	"Количество запросов, для которых сценарий admission_hook завершился ошибкой (запрос обработан без его решения).")
+
+// Request - метаданные запроса, которые сценарий получает в аргументе request.
+type Request struct {
+	Method     string
+	Path       string
+	Host       string
+	RemoteAddr string
+	ClientID   string
+	Headers    map[string]string // Имя заголовка в нижнем регистре -> значения через ", ".
+	Query      map[string]string // Параметр строки запроса -> первое значение.
+	// Limiter - корзина клиента до этого запроса; nil - корзины еще нет или Rate Limiter выключен.
+	Limiter *LimiterState
+}
+
+// LimiterState - состояние корзины токенов клиента (request.limiter в сценарии).
+type LimiterState struct {
+	Tokens   float64
+	Capacity float64
+	Rate     float64
+}
+
+// Decision - решение сценария о запросе.
+type Decision struct {
+	Pool string  // Пул, который обработает запрос (primary, spillover, tls:<сайт>); пусто - текущий.
+	Cost float64 // Сколько токенов списывается с корзины клиента; 0 - запрос не проверяется Rate Limiter.
+	// RejectStatus - код ответа (4xx или 5xx), с которым запрос отклоняется; 0 - запрос не отклоняется.
+	RejectStatus int
+	Message      string // Текст ошибки для клиента при отклонении; пусто - текст по умолчанию.
+}
+
+// DefaultDecision - решение, если сценарий ничего не изменил: запрос стоит один токен.
+var DefaultDecision = Decision{Cost: 1}
+
+// Hook - загруженный сценарий. Функция admit(request) вызывается для каждого запроса
+// в отдельном потоке Starlark с пределом шагов, поэтому Decide можно вызывать конкурентно.
+type Hook struct {
+	name     string
+	admit    starlark.Callable
+	maxSteps uint64
+}
+
+// New загружает сценарий из cfg.Script или cfg.Source и находит в нем функцию admit.
+// Глобальные значения сценария замораживаются после загрузки: вызовы admit не могут менять общее состояние.
+func New(cfg config.AdmissionHookConfig) (*Hook, error) {
+	name := "admission_hook.source"
+	var src any = cfg.Source
+	if cfg.Script != "" {
+		data, err := os.ReadFile(cfg.Script)
+		if err != nil {
+			return nil, i18n.Errorf(i18n.HooksReadFailed, cfg.Script, err)
+		}
+		name, src = cfg.Script, data
+	}
+
+	thread := &starlark.Thread{Name: name, Print: printer}
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, name, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	globals.Freeze()
+	admit, ok := globals["admit"].(starlark.Callable)
+	if !ok {
+		return nil, i18n.Errorf(i18n.HooksNoAdmit, name)
+	}
+
+	maxSteps := cfg.MaxSteps
+	if maxSteps == 0 {
+		maxSteps = config.DefaultHookMaxSteps
+	}
+	i18n.Logf(i18n.HooksLoaded, name, maxSteps)
+	return &Hook{name: name, admit: admit, maxSteps: maxSteps}, nil
+}
+
+// printer выводит print() сценария в лог.
+func printer(thread *starlark.Thread, msg string) {
+	i18n.Logf(i18n.HooksPrint, thread.Name, msg)
+}
+
+// Decide вызывает admit(request) и разбирает решение. При ошибке сценария (исключение, превышение
+// max_steps, неверное решение) возвращается DefaultDecision и ошибка.
+func (h *Hook) Decide(req Request) (Decision, error) {
+	thread := &starlark.Thread{Name: h.name, Print: printer}
+	thread.SetMaxExecutionSteps(h.maxSteps)
+	result, err := starlark.Call(thread, h.admit, starlark.Tuple{req.value()}, nil)
+	if err != nil {
+		errorsTotal.Inc()
+		return DefaultDecision, err
+	}
+	decision, err := parseDecision(result)
+	if err != nil {
+		errorsTotal.Inc()
+		return DefaultDecision, err
+	}
+	return decision, nil
+}
+
+// value представляет запрос для сценария: request.method, request.headers["x-tenant"], request.limiter.tokens.
+func (req Request) value() starlark.Value {
+	limiter := starlark.Value(starlark.None)
+	if req.Limiter != nil {
+		limiter = starlarkstruct.FromStringDict(starlark.String("limiter"), starlark.StringDict{
+			"tokens":   starlark.Float(req.Limiter.Tokens),
+			"capacity": starlark.Float(req.Limiter.Capacity),
+			"rate":     starlark.Float(req.Limiter.Rate),
+		})
+	}
+	request := starlarkstruct.FromStringDict(starlark.String("request"), starlark.StringDict{
+		"method":      starlark.String(req.Method),
+		"path":        starlark.String(req.Path),
+		"host":        starlark.String(req.Host),
+		"remote_addr": starlark.String(req.RemoteAddr),
+		"client_id":   starlark.String(req.ClientID),
+		"headers":     stringDict(req.Headers),
+		"query":       stringDict(req.Query),
+		"limiter":     limiter,
+	})
+	request.Freeze()
+	return request
+}
+
+func stringDict(values map[string]string) *starlark.Dict {
+	dict := starlark.NewDict(len(values))
+	for key, value := range values {
+		_ = dict.SetKey(starlark.String(key), starlark.String(value)) // Ключи-строки хешируются всегда
+	}
+	return dict
+}
+
+// parseDecision разбирает результат admit: None - решение по умолчанию, dict с полями pool, cost,
+// reject и message - изменения к нему.
+func parseDecision(result starlark.Value) (Decision, error) {
+	decision := DefaultDecision
+	if result == starlark.None {
+		return decision, nil
+	}
+	dict, ok := result.(*starlark.Dict)
+	if !ok {
+		return decision, i18n.Errorf(i18n.HooksBadResult, result.Type())
+	}
+
+	for _, item := range dict.Items() {
+		key, ok := starlark.AsString(item[0])
+		value := item[1]
+		switch {
+		case !ok:
+			return decision, i18n.Errorf(i18n.HooksBadKey, item[0].String())
+		case key == "pool":
+			pool, ok := starlark.AsString(value)
+			if !ok {
+				return decision, i18n.Errorf(i18n.HooksBadField, key, "string", value.Type())
+			}
+			decision.Pool = pool
+		case key == "cost":
+			cost, ok := starlark.AsFloat(value)
+			if !ok || cost < 0 || math.IsInf(cost, 0) || math.IsNaN(cost) {
+				return decision, i18n.Errorf(i18n.HooksBadField, key, "number >= 0", value.String())
+			}
+			decision.Cost = cost
+		case key == "reject":
+			var status int
+			if err := starlark.AsInt(value, &status); err != nil || status < 400 || status > 599 {
+				return decision, i18n.Errorf(i18n.HooksBadField, key, "4xx/5xx status", value.String())
+			}
+			decision.RejectStatus = status
+		case key == "message":
+			message, ok := starlark.AsString(value)
+			if !ok {
+				return decision, i18n.Errorf(i18n.HooksBadField, key, "string", value.Type())
+			}
+			decision.Message = message
+		default:
+			return decision, i18n.Errorf(i18n.HooksBadKey, key)
+		}
+	}
+	return decision, nil
+}
@@ -0,0 +1,108 @@
+package hooks_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/hooks"
+)
+
+const admitSource = `
+def admit(request):
+    if request.headers.get("x-tenant") == "blocked":
+        return {"reject": 403, "message": "tenant " + request.query.get("id", "?") + " is blocked"}
+    if request.path.startswith("/export"):
+        return {"cost": 5}
+    if request.limiter != None and request.limiter.tokens < 1:
+        return {"pool": "spillover", "cost": 0}
+    return None
+`
+
+// TestHook_Decide проверяет разбор решений сценария и данные запроса, доступные сценарию.
+func TestHook_Decide(t *testing.T) {
+	hook, err := hooks.New(config.AdmissionHookConfig{Enabled: true, Source: admitSource})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		req      hooks.Request
+		expected hooks.Decision
+	}{
+		"без изменений": {
+			req:      hooks.Request{Method: "GET", Path: "/"},
+			expected: hooks.DefaultDecision,
+		},
+		"отклонение": {
+			req: hooks.Request{
+				Path:    "/",
+				Headers: map[string]string{"x-tenant": "blocked"},
+				Query:   map[string]string{"id": "42"},
+			},
+			expected: hooks.Decision{Cost: 1, RejectStatus: 403, Message: "tenant 42 is blocked"},
+		},
+		"стоимость": {
+			req:      hooks.Request{Path: "/export/all"},
+			expected: hooks.Decision{Cost: 5},
+		},
+		"пул по состоянию корзины": {
+			req:      hooks.Request{Path: "/", Limiter: &hooks.LimiterState{Tokens: 0.5, Capacity: 10, Rate: 1}},
+			expected: hooks.Decision{Pool: "spillover"},
+		},
+		"корзина с токенами": {
+			req:      hooks.Request{Path: "/", Limiter: &hooks.LimiterState{Tokens: 3, Capacity: 10, Rate: 1}},
+			expected: hooks.DefaultDecision,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			decision, err := hook.Decide(tt.req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, decision)
+		})
+	}
+}
+
+// TestHook_DecideErrors проверяет, что ошибка сценария дает решение по умолчанию.
+func TestHook_DecideErrors(t *testing.T) {
+	sources := map[string]string{
+		"исключение":              "def admit(request):\n    fail('boom')\n",
+		"тип результата":          "def admit(request):\n    return 'allow'\n",
+		"неизвестное поле":        "def admit(request):\n    return {'weight': 2}\n",
+		"отрицательная стоимость": "def admit(request):\n    return {'cost': -1}\n",
+		"статус не ошибка":        "def admit(request):\n    return {'reject': 200}\n",
+		"max_steps":               "def admit(request):\n    for i in range(1000000):\n        pass\n",
+		"изменение запроса":       "def admit(request):\n    request.headers['x'] = '1'\n",
+	}
+	for name, source := range sources {
+		hook, err := hooks.New(config.AdmissionHookConfig{Enabled: true, Source: source, MaxSteps: 1000})
+		require.NoError(t, err, name)
+		decision, err := hook.Decide(hooks.Request{Path: "/"})
+		assert.Error(t, err, name)
+		assert.Equal(t, hooks.DefaultDecision, decision, name)
+	}
+}
+
+// TestNew проверяет загрузку сценария из файла и отклонение неверных сценариев.
+func TestNew(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "admission.star")
+	require.NoError(t, os.WriteFile(script, []byte("def admit(request):\n    return {'cost': 2}\n"), 0o644))
+	hook, err := hooks.New(config.AdmissionHookConfig{Enabled: true, Script: script})
+	require.NoError(t, err)
+	decision, err := hook.Decide(hooks.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, decision.Cost)
+
+	for name, cfg := range map[string]config.AdmissionHookConfig{
+		"нет файла":        {Script: filepath.Join(t.TempDir(), "missing.star")},
+		"синтаксис":        {Source: "def admit(request)\n    return None\n"},
+		"нет admit":        {Source: "def allow(request):\n    return None\n"},
+		"admit не функция": {Source: "admit = 1\n"},
+	} {
+		_, err := hooks.New(cfg)
+		assert.Error(t, err, name)
+	}
+}
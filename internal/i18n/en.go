@@ -0,0 +1,698 @@
+package i18n
+
+// en - английский каталог.
+var en = map[ID]string{
+	// Общие (internal/i18n)
+	LocaleUnsupported:    "unsupported locale: '%s'. Allowed values: %s",
+	LogLevelUnsupported:  "unsupported log_level: '%s'. Allowed values: %s",
+	LogFormatUnsupported: "unsupported logging.format: '%s'. Allowed values: %s",
+
+	// Запуск и остановка (cmd/balancer)
+	MainStarting:              "Starting load balancer...",
+	MainVersion:               "[Main] Version %s (commit %s, built %s, %s)",
+	MainConfigLoadFailed:      "[Error] Failed to load configuration: %v",
+	MainNoBackends:            "Backend server list (backend_servers) in configuration is empty.",
+	MainNoPort:                "Port (port) is not set in configuration.",
+	MainSQLiteInit:            "[Storage] Initializing SQLite from '%s'...",
+	MainSQLiteFailed:          "[Error] Failed to connect to SQLite DB: %v",
+	MainImportFailed:          "[Error] Failed to import client limits from configuration: %v",
+	MainAccessListFailed:      "Failed to load access lists: %v",
+	MainNoStore:               "[Storage] database_path is not set or Rate Limiter is disabled: client limits, access lists and API keys are kept in memory and lost on restart.",
+	MainRateLimiterFailed:     "[Error] Failed to initialize Rate Limiter: %v",
+	MainBalancerFailed:        "[Error] Failed to create balancer: %v",
+	MainListening:             "Load balancer listening on %s",
+	MainAPIPrefix:             "API is available under /clients/ and /access/",
+	MainMetricsEnabled:        "[Main] Prometheus metrics are served at %s",
+	MainTraceWithoutToken:     "[Warning] trace.token is not set: the decision trace (X-Balancer-Trace) is added to every response",
+	MainBackends:              "Registered backends: %v",
+	MainRateLimiterOn:         "Rate Limiter enabled (Store: %T, Header: '%s')",
+	MainRateLimiterOff:        "Rate Limiter disabled.",
+	MainHealthChecksOn:        "[Main] Health Checks enabled (Interval: %v, Timeout: %v, Path: %s)",
+	MainHealthChecksOff:       "[Main] Health Checks disabled.",
+	MainServeFailed:           "Server failed to start: %v",
+	MainTLSListening:          "Load balancer is serving HTTPS on %s (sites: %d)",
+	MainTLSCertFailed:         "Failed to load certificate for tls.sites[%d]: %v",
+	MainTLSDefaultCertFailed:  "Failed to load listener certificate tls.cert_file: %v",
+	MainTLSClientCAFailed:     "Failed to load tls.client_auth.ca_file: %v",
+	MainBackendTLSFailed:      "Failed to configure backend_tls.%s: %v",
+	MainTLSPoolFailed:         "Failed to create backend pool for tls.sites[%d]: %v",
+	MainTLSServeFailed:        "Failed to start HTTPS server: %v",
+	MainUDPFailed:             "Failed to start UDP proxy: %v",
+	MainFPListening:           "Forward proxy is listening on %s (gateways: %v)",
+	MainFPFailed:              "Failed to create egress gateway pool: %v",
+	MainFPServeFailed:         "Failed to start forward proxy: %v",
+	MainSpilloverFailed:       "Failed to create spillover pool: %v",
+	MainAccessLogFailed:       "Failed to set up access log: %v",
+	MainHookFailed:            "Failed to load admission_hook script: %v",
+	MainShutdownSignal:        "Shutdown signal received, starting graceful shutdown...",
+	MainBackgroundWaitFailed:  "[Warning] Background tasks (%s) did not finish in time: %v",
+	MainSaveStateFailed:       "[Error] Failed to save Rate Limiter state: %v",
+	MainSaveHealthFailed:      "[Error] Failed to save backend health: %v",
+	MainLoadHealthFailed:      "[Warning] Failed to restore backend health: %v",
+	MainShutdownFailed:        "Graceful server shutdown failed: %v",
+	MainServerStopped:         "HTTP server stopped gracefully.",
+	MainDBCloseFailed:         "[Error] Failed to close DB: %v",
+	MainDBClosed:              "DB connection closed.",
+	MainStopped:               "Load balancer stopped successfully.",
+	MainReloading:             "[Main] SIGHUP received, reloading configuration from '%s'...",
+	MainReloadFailed:          "[Error] Failed to apply new configuration: %v",
+	MainReloaded:              "[Main] Configuration reloaded.",
+	MainReloadNoChanges:       "[Main] Configuration parameters have not changed.",
+	MainReloadChanges:         "[Main] Configuration changes (%d): %s",
+	MainReloadRestartRequired: "[Main] Changes that require a restart (%d): %s",
+
+	// Конфигурация (internal/config)
+	ConfigUnknownAlgorithm:            "unsupported load_balancing_algorithm: '%s'. Allowed values: 'round_robin', 'random', 'consistent_hash'",
+	ConfigAlgorithm:                   "[Config] Load balancing algorithm: %s",
+	ConfigDefaultRateFixed:            "[Warning] rate_limiter.default_rate must be > 0, using default value 1",
+	ConfigDefaultCapacityFixed:        "[Warning] rate_limiter.default_capacity must be > 0, using default value 1",
+	ConfigDefaultDatabasePath:         "[Warning] rate_limiter.database_path is not set, using default ./rate_limits.db",
+	ConfigStickyRandomSecret:          "[Warning] sticky_sessions.secret is not set: sticky cookies are signed with a random key and stop working after a restart and on other instances",
+	ConfigDefaultHealthInterval:       "[Config] HealthCheck interval is not set, using default: %s",
+	ConfigDefaultHealthTimeout:        "[Config] HealthCheck timeout is not set, using default: %s",
+	ConfigHealthTimeoutTooLong:        "[Config] Warning: HealthCheck timeout (%s) is greater than or equal to interval (%s). A smaller timeout is recommended.",
+	ConfigDefaultHealthPath:           "[Config] HealthCheck path is not set, using default: %s",
+	ConfigMaxBackoffTooSmall:          "[Config] health_check.max_backoff (%s) is less than interval, backoff disabled.",
+	ConfigHealthChecksOn:              "[Config] Health Checks enabled: Interval=%v, Timeout=%v, Path=%s",
+	ConfigHealthChecksOff:             "[Config] Health Checks disabled.",
+	ConfigNegativeRateLimit:           "rate_limiter.%s must not be negative: %v",
+	ConfigUnknownFailurePolicy:        "unsupported rate_limiter.store_failure_policy: '%s'. Allowed values: '%s', '%s'",
+	ConfigUnknownRateLimitAlgorithm:   "unsupported rate_limiter.algorithm: '%s'. Allowed values: '%s', '%s'",
+	ConfigBadStoreTimeout:             "invalid rate_limiter.store_timeout format (%s): %w",
+	ConfigNegativeStoreTimeout:        "rate_limiter.store_timeout must not be negative: %s",
+	ConfigEmptyClientID:               "rate_limiter.clients: empty client ID",
+	ConfigBadClientLimit:              "rate_limiter.clients['%s']: rate and capacity must be positive",
+	ConfigBadLimitTemplate:            "rate_limiter.templates['%s']: rate and capacity must be positive",
+	ConfigNestedLimitTemplate:         "rate_limiter.templates['%s']: a template cannot reference another template",
+	ConfigUnknownLimitTemplate:        "rate_limiter.clients['%s']: unknown template '%s'",
+	ConfigBadSoftLimitRatio:           "rate_limiter.soft_limit_ratio must be in the range [0, 1), got %v",
+	ConfigBadIPv6PrefixLength:         "rate_limiter.ipv6_prefix_length must be in the range [0, 128], got %d",
+	ConfigBadTrustedProxy:             "rate_limiter.trusted_proxies[%d]: '%s' is not an IP address or CIDR subnet",
+	ConfigBadKeyTemplate:              "rate_limiter.key_template: invalid template '%s': %s",
+	ConfigKeyTemplateUnbalanced:       "unbalanced curly brace",
+	ConfigKeyTemplateUnknownAttr:      "unknown attribute {%s} (available: client_id, ip, method, host, path_prefix, header.<name>)",
+	ConfigBadDenialCacheTTL:           "invalid rate_limiter.denial_cache_ttl '%s': expected a non-negative duration (e.g. 100ms)",
+	ConfigBadIdleBucketTTL:            "invalid rate_limiter.idle_bucket_ttl '%s': expected a non-negative duration (e.g. 10m)",
+	ConfigBadMaxBuckets:               "rate_limiter.max_buckets must not be negative, got %d",
+	ConfigBadMaxInFlightPerClient:     "rate_limiter.max_in_flight_per_client must not be negative, got %d",
+	ConfigBadGlobalLimit:              "rate_limiter.global: rate and capacity must not be negative, got rate=%v, capacity=%v",
+	ConfigAPIKeysWithIdentifierHeader: "rate_limiter.api_keys and rate_limiter.identifier_header cannot be set together: with API keys the client ID comes from the store",
+	ConfigBadAPIKeyCacheTTL:           "invalid rate_limiter.api_keys.cache_ttl '%s': expected a non-negative duration (e.g. 1m)",
+	ConfigBadLimitDecreaseWindow:      "invalid rate_limiter.limit_decrease_window '%s': expected a non-negative duration (e.g. 5m)",
+	ConfigBadHealthInterval:           "invalid HealthCheck interval format (%s): %w",
+	ConfigNonPositiveHealthInterval:   "HealthCheck interval must be positive: %s",
+	ConfigBadHealthTimeout:            "invalid HealthCheck timeout format (%s): %w",
+	ConfigNonPositiveHealthTimeout:    "HealthCheck timeout must be positive: %s",
+	ConfigUnknownHealthCheckType:      "unknown health_check.type '%s' (allowed: http, tcp)",
+	ConfigBadExpectedStatus:           "health_check.expected_status: invalid status code %d",
+	ConfigBadExpectedJSONField:        "health_check.expected_json: invalid field name '%s'",
+	ConfigNegativeWorkers:             "health_check.workers must not be negative: %d",
+	ConfigNegativeLogRepeatEvery:      "health_check.log_repeat_every must not be negative: %d",
+	ConfigBadMaxBackoff:               "invalid health_check.max_backoff format (%s): %w",
+	ConfigBadHealthJitter:             "invalid health_check.jitter format (%s): %w",
+	ConfigHealthJitterRange:           "health_check.jitter (%s) must be at least 0 and less than the interval (%s)",
+	ConfigBadDuration:                 "invalid %s format (%s): %w",
+	ConfigNonPositiveDuration:         "%s must be positive: %s",
+	ConfigBadRejectionThreshold:       "alerts.rejection_rate_threshold must be in range (0, 1]: %v",
+	ConfigNegativeMinRequests:         "alerts.min_requests must not be negative: %d",
+	ConfigBadRoutePrefix:              "routes[%d].path_prefix must start with '/': '%s'",
+	ConfigDuplicateRoutePrefix:        "routes[%d].path_prefix '%s' is specified more than once",
+	ConfigBadAcceptEncoding:           "%s: unknown mode '%s' (allowed: pass, strip, identity)",
+	ConfigUnknownLabeledBackend:       "backend_labels: backend '%s' is not listed in any pool",
+	ConfigEmptyLabel:                  "%s: empty label name",
+	ConfigUnknownLimitedBackend:       "backend_max_connections: backend '%s' is not listed in any pool",
+	ConfigBadMaxConnections:           "backend_max_connections: backend '%s' needs a positive value, got %d",
+	ConfigBadMaxConnectionsPerIP:      "connection_limit.max_per_ip: invalid value %d (expected a non-negative number)",
+	ConfigBadVacuumPages:              "storage_maintenance.vacuum_pages: invalid value %d (expected a non-negative number)",
+	ConfigBadSQLiteJournalMode:        "sqlite.journal_mode: unknown mode '%s' (expected wal, delete, truncate, persist, memory or off)",
+	ConfigBadSQLiteSynchronous:        "sqlite.synchronous: unknown value '%s' (expected off, normal, full or extra)",
+	ConfigBadSQLiteMaxOpenConns:       "sqlite.max_open_conns: invalid value %d (expected a non-negative number)",
+	ConfigBadSQLitePragma:             "sqlite.pragmas: invalid PRAGMA '%s' = '%s' (letters, digits, '_' and '-' are allowed)",
+	ConfigSQLitePragmaReserved:        "sqlite.pragmas: '%s' is set by a dedicated sqlite option",
+	ConfigBadSaturationThreshold:      "saturation_threshold must be in range (0, 1], got %v",
+	ConfigEmptyBackendURL:             "backend_servers[%d]: url is missing",
+	ConfigBadBackendWeight:            "backend_servers: weight of backend '%s' must not be negative, got %d",
+	ConfigBadFlushInterval:            "routes[%d].flush_interval: invalid value '%s' (expected a duration such as 100ms, or -1)",
+	ConfigBadContentType:              "%s: invalid content type '%s' (expected e.g. application/json or text/*)",
+	ConfigBadHoldDown:                 "passive_health.hold_down: invalid value '%s' (expected a non-negative duration such as 30s)",
+	ConfigBadMaxFailures:              "passive_health.max_failures must not be negative, got %d",
+	ConfigBadFailureWindow:            "passive_health.failure_window: invalid value '%s' (expected a positive duration such as 10s)",
+	ConfigBadSlowStart:                "passive_health.slow_start: invalid value '%s' (expected a non-negative duration such as 30s)",
+	ConfigSlowStartWithHash:           "%s: slow_start is not supported with load_balancing_algorithm consistent_hash",
+	ConfigBadBackendPassiveHealth:     "%s: %v",
+	ConfigBadStickyCookieName:         "sticky_sessions.cookie_name: invalid cookie name '%s': %v",
+	ConfigBadStickyTTL:                "sticky_sessions.ttl: invalid value '%s' (expected a positive duration such as 1h)",
+	ConfigBadReadinessInFlightRatio:   "readiness.in_flight_ratio must be in (0, 1], got %v",
+	ConfigBadReadinessStoreTimeout:    "readiness.store_timeout: invalid value '%s' (expected a positive duration such as 1s)",
+	ConfigBadMetricsPath:              "metrics.path: invalid value '%s' (expected a path starting with /, other than / and the service paths /admin/, /clients, /access, /api-keys, /readyz)",
+	ConfigAdminAuthMissing:            "%s: required field",
+	ConfigAdminAuthDuplicate:          "%s: duplicated in admin_auth",
+	ConfigUnknownAdminRole:            "%s: unknown role '%s' (expected %s or %s)",
+	ConfigTracingBadEndpoint:          "tracing.endpoint: invalid collector address '%s' (expected an http:// or https:// URL, e.g. http://otel-collector:4318/v1/traces)",
+	ConfigTracingBadSampleRatio:       "tracing.sample_ratio must be between 0 and 1, got %g",
+	ConfigTracingBadQueue:             "tracing.batch_size (%d) must be at least 1 and queue_size (%d) at least batch_size",
+	ConfigBadRequestMaxAge:            "request_age.max_age: invalid value '%s' (expected a positive duration such as 10s)",
+	ConfigHookSource:                  "admission_hook: set exactly one of script and source",
+	ConfigBadStatsHistoryWindow:       "stats_history.window: invalid value '%s' (expected a duration from 1s to %s)",
+	ConfigUnknownThrottlePolicy:       "unsupported %s: '%s'. Allowed values: '%s', '%s', '%s'",
+	ConfigThrottleWeightWithHash:      "%s: reduce_weight is not supported with load_balancing_algorithm consistent_hash",
+	ConfigUnknownPool:                 "%s: unknown pool '%s' (expected primary, spillover or tls:<first name of a site with backend_servers>)",
+	ConfigBackendTLSNoKeyPair:         "%s: cert_file and key_file must be set together",
+	ConfigBackendTLSSkipVerifyWithCA:  "%s: ca_file has no effect with insecure_skip_verify",
+	ConfigUnknownUpstreamAuthType:     "%s.type: unknown type '%s' (expected %s, %s or %s)",
+	ConfigUpstreamAuthMissing:         "%s: required for type %s",
+	ConfigBadDNSServer:                "%s: invalid DNS server address '%s' (expected IP[:port])",
+	ConfigBadDNSDuration:              "%s: invalid value '%s' (expected a duration such as 5s)",
+	ConfigCanaryWithHash:              "%s: canary split is not supported by the consistent_hash algorithm",
+	ConfigCanaryNoSelector:            "%s: specify the labels of canary backends",
+	ConfigBadCanaryPercent:            "%s: invalid value %d (expected 1 to 99)",
+	ConfigBadCanaryWindow:             "%s: invalid value '%s' (expected a duration from 1s to %s)",
+	ConfigBadCanaryMinRequests:        "%s: invalid value %d (expected a non-negative number)",
+	ConfigBadCanaryErrorRateDelta:     "%s: invalid value %v (expected 0 to 1)",
+	ConfigBadCanaryLatencyRatio:       "%s: invalid value %v (expected 0 or at least 1)",
+	ConfigCanaryNoThresholds:          "%s: automatic rollback requires max_error_rate_delta or max_latency_ratio",
+	ConfigBadThrottlePenalty:          "upstream_throttling.penalty: invalid value '%s' (expected a positive duration such as 30s)",
+	ConfigBadThrottleWeightPercent:    "upstream_throttling.weight_percent: invalid value %d (expected 1 to 99)",
+	ConfigTLSNoPort:                   "tls.port is required when tls.enabled is set",
+	ConfigTLSSamePort:                 "tls.port '%s' is the same as the HTTP listener port",
+	ConfigTLSNoSites:                  "tls: a listener certificate (cert_file and key_file) or at least one site in sites is required",
+	ConfigTLSNoServerNames:            "tls.sites[%d].server_names must not be empty",
+	ConfigTLSNoCertificate:            "tls.sites[%d]: cert_file and key_file are required",
+	ConfigTLSBadServerName:            "tls.sites[%d]: invalid server name '%s'",
+	ConfigTLSDuplicateServerName:      "tls.sites[%d]: server name '%s' is specified more than once",
+	ConfigTLSNoDefaultCertificate:     "tls: cert_file and key_file must be set together",
+	ConfigTLSBadMinVersion:            "tls.min_version '%s' is not supported (allowed: 1.2, 1.3)",
+	ConfigTLSCipherSuitesWithTLS13:    "tls.cipher_suites have no effect with min_version 1.3: TLS 1.3 suites are not configurable",
+	ConfigTLSBadCipherSuite:           "tls.cipher_suites: unknown or insecure TLS 1.2 cipher suite '%s'",
+	ConfigTLSBadClientAuthMode:        "tls.client_auth.mode '%s' is not supported (allowed: none, optional, require)",
+	ConfigTLSClientAuthNoCA:           "tls.client_auth.ca_file is required to verify client certificates",
+	ConfigTLSSubjectHeaderWithoutAuth: "tls.client_auth.subject_header requires mode optional or require",
+	ConfigUDPNoListen:                 "udp.listen is required when udp.enabled is set",
+	ConfigUDPNoBackends:               "udp.backends must contain at least one address",
+	ConfigUDPBadBackend:               "udp.backends[%d]: invalid address '%s': %v",
+	ConfigUDPBadMaxFails:              "udp.max_fails must be at least 1, got %d",
+	ConfigGossipNoListen:              "rate_limiter.gossip.listen is required when rate_limiter.gossip.peers is set",
+	ConfigGossipBadListen:             "rate_limiter.gossip.listen: invalid address '%s': %v",
+	ConfigGossipBadPeer:               "rate_limiter.gossip.peers[%d]: invalid address '%s': %v",
+	ConfigFPNoPort:                    "forward_proxy.port is required when forward_proxy.enabled is set",
+	ConfigFPSamePort:                  "forward_proxy.port '%s' is the same as the HTTP listener port",
+	ConfigBadBindAddress:              "%s: invalid address '%s' (expected an IP address such as 127.0.0.1 or ::1)",
+	ConfigBadAccessEntry:              "%s: invalid access list entry '%s': %v",
+	ConfigEmptyAccessEntry:            "expected an IP address, a CIDR subnet or a client ID without spaces",
+	ConfigFPNoGateways:                "forward_proxy.gateways must contain at least one gateway",
+	ConfigConcurrencyBadMaxInFlight:   "concurrency.max_in_flight must be at least 1, got %d",
+	ConfigConcurrencyBadMaxQueue:      "concurrency.max_queue must not be negative, got %d",
+	ConfigConcurrencyBadPriority:      "concurrency.default_priority must be between 0 and 9, got %d",
+	ConfigBadPriorityTrusted:          "concurrency.priority_trusted[%d]: '%s' is not an IP address or CIDR subnet",
+	ConfigSpilloverNoBackends:         "spillover.backend_servers must contain at least one backend",
+	ConfigSpilloverNegativeBudget:     "spillover.primary_max_in_flight and spillover.primary_max_rps must not be negative, got %d and %v",
+	ConfigSpilloverNoBudget:           "spillover: set primary_max_in_flight and/or primary_max_rps, otherwise the overflow pool is never used",
+	ConfigCaptureBadBufferSize:        "capture.buffer_size must be at least 1, got %d",
+	ConfigCaptureBadMaxBody:           "capture.max_body_bytes must not be negative, got %d",
+	ConfigAccessLogBadSink:            "unsupported access_log.sink: '%s'. Allowed values: '%s', '%s', '%s', '%s'",
+	ConfigAccessLogBadAddress:         "access_log.address for sink %s: invalid address '%s' (syslog: udp://host:port or tcp://host:port, http and kafka: URL, file: path or stdout)",
+	ConfigAccessLogNoTopic:            "access_log.topic is required for the kafka sink",
+	ConfigAccessLogBadBatchSize:       "access_log.batch_size must be at least 1, got %d",
+	ConfigAccessLogBadQueueSize:       "access_log.queue_size (%d) must not be less than batch_size (%d)",
+	ConfigAccessLogBadRetries:         "access_log.max_retries must not be negative, got %d",
+	ConfigAccessLogBadFormat:          "unsupported access_log.format: '%s'. Allowed values: '%s', '%s'",
+	ConfigAccessLogBadMaxSize:         "access_log.max_size_mb must not be negative, got %d",
+	ConfigAccessLogBadMaxBackups:      "access_log.max_backups must be at least 1 when rotation is enabled (max_size_mb > 0), got %d",
+
+	// Списки доступа (internal/access)
+	AccessStoreUnavailable: "access list store is not configured (requires rate_limiter with database_path)",
+	AccessLoaded:           "[Access] Access lists loaded: %d entries from config, %d from the store",
+	AccessPurgeFailed:      "[Access] Failed to delete expired entries from the store: %v",
+
+	// Журнал доступа (internal/accesslog)
+	AccessLogStarted:    "[AccessLog] Shipping access log: sink %s (%s), batches of up to %d entries every %v, queue of %d entries",
+	AccessLogDropped:    "[AccessLog] Access log entries dropped: %d (queue of %d entries is full, sink is too slow)",
+	AccessLogSendFailed: "[AccessLog] Failed to send batch (attempt %d of %d retries): %v, retrying in %v",
+	AccessLogBatchLost:  "[AccessLog] Batch of %d entries lost: %v",
+	AccessLogBadStatus:  "sink responded with %s",
+	AccessLogRotated:    "[AccessLog] Access log file %s rotated, keeping up to %d rotated files",
+	TracingStarted:      "[Tracing] Exporting OpenTelemetry traces: collector %s, service.name %s, sample ratio %g",
+	TracingDropped:      "[Warning] [Tracing] Dropped spans: %d (queue of %d spans is full, collector is falling behind)",
+	TracingExportFailed: "[Warning] [Tracing] Batch of %d spans was not exported to the collector: %v",
+	TracingBadStatus:    "collector responded %s",
+
+	// Ограничение одновременных запросов (internal/admission)
+	AdmissionEnabled:      "[Admission] Concurrency limit: max_in_flight=%d, max_queue=%d, queue_timeout=%v, priority header: %s",
+	AdmissionQueueFull:    "wait queue is full",
+	AdmissionQueueTimeout: "timed out waiting in queue",
+	ConnLimitEnabled:      "[ConnLimit] Per-IP connection limit on %s: %d",
+	ConnLimitRejected:     "[ConnLimit] Connection from %s rejected: %d connections open (connection_limit.max_per_ip)",
+
+	// Алертинг (internal/alerting)
+	AlertsStarted:         "[Alerts] Monitoring started: 429 > %.1f%% over %v (min. %d requests), all backends down > %v, webhook: '%s'",
+	AlertsRejectionRate:   "Rejected (429) request share %.1f%% over %v (requests: %d)",
+	AlertsAllBackendsDown: "All backends (%d) have been down for %v",
+	AlertsEvent:           "[Alerts] %s [%s]: %s (value=%.4f, threshold=%.4f)",
+	AlertsMarshalFailed:   "[Alerts] Failed to marshal event: %v",
+	AlertsWebhookFailed:   "[Alerts] Failed to send event to webhook '%s': %v",
+	AlertsWebhookStatus:   "[Alerts] Webhook '%s' returned status %d",
+
+	// API управления лимитами (internal/api)
+	APINoStore:                    "[API] Warning: no Store provided to APIHandler. CRUD operations will not work.",
+	APIStoreUnavailable:           "Limit store is unavailable",
+	APIDebugPath:                  "[API] Debug: Path after StripPrefix and Trim: '%s' (Original r.URL.Path: '%s')",
+	APIListNotImplemented:         "Listing all clients is not implemented",
+	APIBadListLimit:               "Invalid limit '%s': expected an integer from 1 to %d",
+	APIBadListOffset:              "Invalid offset '%s': expected a non-negative integer",
+	APIListFailed:                 "[API] Failed to list clients: %v",
+	APIListInternal:               "Internal server error while listing clients",
+	APIMethodNotAllowedCollection: "Method %s is not supported for /clients",
+	APIMethodNotAllowedItem:       "Method %s is not supported for /clients/{id}",
+	APIInvalidJSON:                "Failed to parse JSON: %v",
+	APIClientIDRequired:           "Field client_id is required",
+	APINonPositiveLimit:           "rate and capacity must be positive",
+	APIPatchEmpty:                 "Specify rate_per_sec and/or capacity to change",
+	APIClientExists:               "Client with ID '%s' already exists",
+	APICreateFailed:               "[API] Failed to create client '%s': %v",
+	APICreateInternal:             "Internal server error while creating client",
+	APIGetFailed:                  "Failed to read limit from DB: %v",
+	APIClientNotFound:             "Client with ID '%s' not found",
+	APIClientIDMismatch:           "client_id in request body does not match ID in path",
+	APIUpdateNotFound:             "Client with ID '%s' not found for update",
+	APIUpdateFailed:               "[API] Failed to update client '%s': %v",
+	APIUpdateInternal:             "Internal server error while updating client",
+	APIDeleteNotFound:             "Client with ID '%s' not found for deletion",
+	APIDeleteFailed:               "[API] Failed to delete client '%s': %v",
+	APIDeleteInternal:             "Internal server error while deleting client",
+	APIResetNotSupported:          "Bucket reset is unavailable: rate limiter is not attached to the API",
+	APIResetDisabled:              "Rate limiter is disabled: there are no buckets to reset",
+	APIResetFailed:                "[API] Failed to reset bucket of client '%s': %v",
+	APIResetInternal:              "Internal server error while resetting client bucket",
+	APIStateNotSupported:          "Bucket state is unavailable: rate limiter is not attached to the API",
+	APIStateDisabled:              "Rate limiter is disabled: there are no buckets",
+	APIStateFailed:                "[API] Failed to read bucket state of client '%s': %v",
+	OpenAPITitle:                  "load-balancer management API",
+	OpenAPIListClients:            "List clients with individual limits",
+	OpenAPICreateClient:           "Create a client limit",
+	OpenAPIGetClient:              "Get a client limit",
+	OpenAPIUpdateClient:           "Replace a client limit",
+	OpenAPIPatchClient:            "Partially update a client limit",
+	OpenAPIDeleteClient:           "Delete a client limit",
+	OpenAPIResetClient:            "Refill a client's bucket to capacity",
+	OpenAPIClientState:            "Current state of a client's bucket",
+	OpenAPIListAccess:             "List access list entries",
+	OpenAPIPutAccess:              "Add or replace an access list entry",
+	OpenAPIGetAccess:              "Get an access list entry",
+	OpenAPIDeleteAccess:           "Delete an access list entry",
+	OpenAPIListAPIKeys:            "List API keys",
+	OpenAPICreateAPIKey:           "Issue an API key",
+	OpenAPIDeleteAPIKey:           "Revoke an API key",
+	APITrialIncomplete:            "A trial period needs both trial_until and downgrade_to",
+	APITrialInPast:                "trial_until must be in the future",
+	APITrialsNotSupported:         "The store does not support trial periods",
+	APIUnknownPlan:                "Plan '%s' is not found in rate_limiter.templates",
+	APIAdminNotFound:              "Unknown path %s",
+	APIAdminMethodNotAllowed:      "Method %s is not supported for %s",
+	APIHealthCheckFailed:          "Forced check failed: %v",
+	APICaptureDisabled:            "Request capture is not available",
+	APICaptureEmptyFilter:         "Specify client_id and/or path_prefix: capturing all traffic is not supported",
+	APIStateSaveUnavailable:       "State saving is unavailable: the Rate Limiter is not initialized",
+	APIStateSaveFailed:            "[API] Error saving state: %v",
+	APIStateSaveInternal:          "Internal server error while saving state",
+	APIMaintenanceUnavailable:     "Database maintenance is unavailable: the rate limit store is not connected",
+	APIMaintenanceFailed:          "[API] Database maintenance failed: %v",
+	APIMaintenanceInternal:        "Internal server error during database maintenance",
+	APIReloadUnavailable:          "Configuration reload is not available",
+	APIBadTimeSeriesSeconds:       "Invalid seconds value '%s': expected a positive integer",
+	APIPoolNotFound:               "Pool '%s' not found",
+	APICanaryNotConfigured:        "Canary split is not configured for pool '%s'",
+	APIBackendBadLimits:           "weight and max_connections cannot be negative: weight=%d, max_connections=%d",
+	APINoReloadYet:                "Configuration has not been reloaded yet (SIGHUP)",
+	APIAccessStoreUnavailable:     "Access list store is unavailable: entries can only be set in the access_list config section",
+	APIAccessEntryNotFound:        "Entry '%s' not found in the access lists",
+	APIBadAccessValue:             "Invalid value '%s': %v",
+	APIBadAccessList:              "Invalid list '%s': expected deny or allow",
+	APIBadAccessTTL:               "Invalid ttl '%s': expected a positive duration such as 1h",
+	APIAccessPutFailed:            "[API] Failed to save access list entry '%s': %v",
+	APIAccessDeleteFailed:         "[API] Failed to delete access list entry '%s': %v",
+	APIAccessInternal:             "Internal server error while changing the access list",
+	APIAPIKeyNotFound:             "API key '%s' not found",
+	APIAPIKeyPlansNotSupported:    "The store does not support client limits: a key with a plan cannot be issued",
+	APIAPIKeyCreateFailed:         "[API] Failed to issue API key to client '%s': %v",
+	APIAPIKeyListFailed:           "[API] Failed to read API keys: %v",
+	APIAPIKeyDeleteFailed:         "[API] Failed to revoke API key '%s': %v",
+	APIAPIKeyInternal:             "Internal server error while managing API keys",
+	APIUnauthorized:               "Authentication required",
+	APIForbidden:                  "User '%s' is not allowed to use method %s: admin role required",
+	APIAuthFailed:                 "[Warning] [API] Rejected %s %s from %s: missing or invalid credentials",
+	APIAuthForbidden:              "[Warning] [API] User '%s' denied %s %s",
+	APILogLevelChanged:            "[API] Log level changed: %s -> %s",
+	APINotReady:                   "[Warning] [API] Instance is not ready to accept traffic (/readyz returns 503): %v",
+	APIReadyAgain:                 "[API] Instance is ready to accept traffic again",
+
+	// Балансировщик и health checks (internal/balancer)
+	BalancerNoHealthyBackends:      "no healthy backends available",
+	BalancerBackendsSaturated:      "all available backends have reached backend_max_connections",
+	BalancerBackendStatus:          "[HealthCheck] Backend %s is now %s",
+	BalancerBackendDrained:         "[Balancer] Backend %d (%s) drained manually, requests in flight: %d",
+	BalancerBackendEnabled:         "[Balancer] Backend %d (%s) returned to rotation",
+	BalancerBackendRegistered:      "[Balancer] Backend %d (%s) added to the pool at runtime: weight %d, labels %v",
+	BalancerBackendRemoved:         "[Balancer] Backend %d (%s) removed from the pool, requests in flight: %d",
+	BalancerBackendExists:          "backend is already in the pool",
+	BalancerBackendExistsWrap:      "%w: '%s'",
+	BalancerLastBackend:            "cannot remove the last backend of the pool",
+	BalancerHealthRestored:         "[HealthCheck] Backend %d (%s) was down before restart: excluded until the first successful check",
+	BalancerBackendUp:              "up",
+	BalancerBackendDown:            "down",
+	BalancerNoBackends:             "no backend servers specified",
+	BalancerUnknownAlgorithm:       "[Warning] Unknown load balancing algorithm '%s', using 'round_robin'",
+	BalancerRNGInit:                "[Balancer] Random number generator initialized for Random algorithm.",
+	BalancerBadBackendURL:          "failed to parse backend #%d URL ('%s'): %w",
+	BalancerRelativeBackendURL:     "backend #%d URL ('%s') must be absolute (e.g. 'http://host:port')",
+	BalancerProxyFailed:            "[Balancer] Proxy error to Backend #%d (%s) for request from '%s' (RequestID: %s): %v. Marking as down.",
+	BalancerRequestHeaders:         "[Balancer] Request headers: %v",
+	BalancerBadGateway:             "Bad Gateway from Custom Handler",
+	BalancerBackendTimeout:         "backend did not start responding in time",
+	BalancerGatewayTimeout:         "Backend did not respond in time",
+	BalancerAdmissionRejected:      "[Balancer] Request from client %s rejected by concurrency limit: %v",
+	BalancerOverloaded:             "Load balancer is overloaded, retry later",
+	BalancerRequestExpired:         "[Balancer] Request from client %s shed: spent %v in the balancer (request_age.max_age %v)",
+	BalancerRequestTooOld:          "The request waited too long to be processed, retry later",
+	BalancerHookFailed:             "[Balancer] admission_hook script failed for client %s: %v (request is processed without its decision)",
+	BalancerHookRejected:           "[Balancer] admission_hook script rejected request from client %s: %d %s",
+	BalancerHookDenied:             "Request rejected by admission policy",
+	BalancerHookPool:               "[Balancer] admission_hook script routed request from client %s to pool %s: backend %d (%s)",
+	BalancerHookUnknownPool:        "[Balancer] admission_hook script chose unknown pool '%s' for client %s: request is processed by the current pool",
+	BalancerAccessDenied:           "[Balancer] Request from client %s rejected by the deny list",
+	BalancerForbidden:              "Access denied",
+	BalancerBadContentType:         "unexpected backend response Content-Type: '%s'",
+	BalancerEmptyBody:              "empty backend response body",
+	BalancerBackendHeldDown:        "[Balancer] Backend %s held down until %s after an error",
+	BalancerBackendPassiveHealth:   "[Balancer] Backend #%d (%s): passive health max_failures=%d, failure_window=%v, hold_down=%v, slow_start=%v",
+	BalancerBackendFailureCounted:  "[Balancer] Backend %s failure counted: %d of %d within %v",
+	BalancerClientDisconnected:     "[Balancer] Client closed the connection before the response: %s %s (RequestID: %s), upstream request canceled",
+	BalancerSpilloverEnabled:       "[Balancer] Spillover pool: %d backends, primary pool budget: max_in_flight=%d, max_rps=%v (0 - unlimited)",
+	BalancerSpillover:              "[Balancer] Primary pool budget exhausted, request from client %s spills over to backend %d (%s)",
+	BalancerGRPCEnabled:            "[Balancer] gRPC mode enabled: application/grpc calls are proxied over HTTP/2 to %d backends",
+	BalancerGRPCUpstreamStatus:     "backend returned HTTP %d instead of a gRPC response",
+	BalancerGRPCUpstreamMapped:     "[Balancer] gRPC call %s: backend response HTTP %d mapped to grpc-status %d",
+	BalancerBackendAdded:           "[Config] Backend #%d added: %s",
+	BalancerHealthChecksStarted:    "[Balancer] Health Checks started.",
+	BalancerHealthChecksStopping:   "[Balancer] Stopping Health Checks...",
+	BalancerRequestReceived:        "[Request] Request received: Method=%s Path=%s From=%s (%s) RequestID=%s",
+	BalancerStoreUnavailable:       "Rate limiter store unavailable",
+	BalancerRateLimited:            "Rate limit exceeded",
+	BalancerTooManyInFlight:        "Too many concurrent requests",
+	BalancerSelectFailed:           "[Balancer] Backend selection failed (%s): %v. Cannot serve request %s %s from '%s'.",
+	BalancerAllBackendsDown:        "All backend servers are unavailable",
+	BalancerForwarding:             "[Balancer] Forwarding request (%s) from '%s' -> Backend #%d (%s)",
+	BalancerDirector:               "[Balancer] Request '%s' sent to backend #%d (%s)",
+	BalancerRoutesLoaded:           "[Balancer] Routes loaded: %d",
+	BalancerBackendLabels:          "[Balancer] Backend %d (%s): labels %v",
+	BalancerBackendMaxConnections:  "[Balancer] Backend %d (%s): at most %d concurrent requests",
+	BalancerBackendWeight:          "[Balancer] Backend %d (%s): weight %d",
+	BalancerThrottlePolicy:         "[Balancer] Reaction to 429 and 503 responses: %s (backends: %d)",
+	BalancerUpstreamAuth:           "[Balancer] Upstream credentials: %s (backends: %d)",
+	BalancerDNS:                    "[Balancer] Pool backend names are resolved via %s: timeout %v, address cache %v",
+	BalancerBackendTLSInsecure:     "[Warning] [Balancer] Certificates of the pool's https:// backends are not verified (backend_tls.insecure_skip_verify)",
+	BalancerDNSSystem:              "system DNS servers",
+	BalancerThrottleWeightReduced:  "[Balancer] Backend %s responded %d: weight reduced to %d%% until %s",
+	BalancerThrottleRetry:          "[Balancer] Backend %d (%s) responded %d, retrying the request on backend %d (%s)",
+	BalancerThrottleNoRetry:        "[Warning] [Balancer] Backend %d (%s) responded %d, no other backend is available for a retry",
+	BalancerCanary:                 "[Balancer] Canary split: %d%% of requests to backends %v (canary: %d, stable: %d)",
+	BalancerCanaryNoBackends:       "[Warning] [Balancer] canary: no backend of the pool matches selector %v, the split is not applied",
+	BalancerCanaryErrorRate:        "canary error rate %.3f exceeds the stable error rate %.3f by more than %.3f",
+	BalancerCanaryLatency:          "canary average latency %.1f ms exceeds the stable %.1f ms by more than %.2fx",
+	BalancerCanaryRolledBack:       "[Warning] [Balancer] Canary rolled back, requests go to stable backends only: %s",
+	BalancerCanaryResumed:          "[Balancer] Canary split resumed: %d%% of requests to backends %v",
+	BalancerBackendReresolved:      "[Balancer] Backend %s: host addresses after DNS re-resolution %v (previously %v), reconnecting",
+	HealthCheckStarting:            "[HealthCheck] Starting health checks: Interval=%v, Timeout=%v, Path=%s, Workers=%d, Max backoff=%v",
+	HealthCheckStaggered:           "[HealthCheck] First checks of %d backends are spread over the %v interval",
+	HealthCheckStopSignal:          "[HealthCheck] Stop signal received.",
+	HealthCheckFailed:              "[HealthCheck] %v (consecutive failures: %d, next check in %v)",
+	HealthCheckFailedRepeated:      "[HealthCheck] %v (repeated %d times since the last entry, consecutive failures: %d, next check in %v)",
+	HealthCheckRecovered:           "[HealthCheck] Backend %s passed the check after %d consecutive failures",
+	HealthCheckCycle:               "[HealthCheck] Running check cycle (backends queued: %d)...",
+	HealthCheckRequestFailed:       "failed to create request for %s: %w",
+	HealthCheckUnreachable:         "backend check failed for %s: %w",
+	HealthCheckBadStatus:           "backend %s returned non-2xx status: %d",
+	HealthCheckUnexpectedStatus:    "backend %s returned status %d, expected one of %v",
+	HealthCheckBodyMismatch:        "backend %s response does not contain '%s'",
+	HealthCheckBadJSON:             "backend %s response is not JSON: %v",
+	HealthCheckJSONMismatch:        "backend %s response: field %s = %s, expected %s",
+	HealthCheckJSONMissing:         "backend %s response has no field %s",
+	HealthCheckDialFailed:          "backend %s does not accept TCP connections: %w",
+	HealthCheckGRPCStatus:          "gRPC health check of backend %s failed with grpc-status %s: %s",
+	HealthCheckGRPCBadResponse:     "invalid gRPC health check response from backend %s: %w",
+	HealthCheckGRPCNotServing:      "backend %s reports status %s for service '%s' (expected SERVING)",
+	HealthCheckGRPCNoStatus:        "missing grpc-status",
+	HealthCheckGRPCBadFrame:        "malformed gRPC message frame",
+	HealthCheckGRPCBadMessage:      "malformed HealthCheckResponse message",
+	HealthCheckDisabled:            "health checks are disabled",
+	HealthCheckBackendNotFound:     "backend not found",
+	HealthCheckBackendNotFoundWrap: "%w: '%s'",
+	HealthCheckForced:              "[HealthCheck] Forced check: %d of %d healthy",
+	HealthCheckInvalidConfig:       "health check interval and timeout must be positive (interval=%v, timeout=%v)",
+	HealthCheckWorkersNotReloaded:  "[HealthCheck] Worker pool size cannot change without restart: keeping %d (requested %d)",
+	HealthCheckReloaded:            "[HealthCheck] Health check parameters updated: Interval=%v, Timeout=%v, Path=%s, Max backoff=%v",
+
+	// Запись запросов для отладки (internal/capture)
+	CaptureStarted: "[Capture] Request capture enabled: client_id=%q, path_prefix=%q, buffer of %d entries, bodies up to %d bytes",
+	CaptureStopped: "[Capture] Request capture disabled, %d entries in buffer",
+
+	// Прямой прокси для исходящего трафика (internal/forwardproxy)
+	FPInitialized:       "[ForwardProxy] Forward proxy initialized (gateways: %d, dial_timeout: %v)",
+	FPRequest:           "[ForwardProxy] %s %s (client: %s) via gateway %d (%s)",
+	FPNotProxyRequest:   "Expected CONNECT or a request with an absolute URL",
+	FPGatewayFailed:     "[ForwardProxy] Gateway %s failed: %v",
+	FPGatewayStatus:     "[ForwardProxy] Gateway %s refused tunnel to %s: %s",
+	FPGatewayRefused:    "Gateway refused tunnel to %s",
+	FPHijackUnsupported: "Server does not support connection tunneling",
+	FPHijackFailed:      "[ForwardProxy] Failed to hijack client connection: %v",
+
+	// Rate Limiter (internal/ratelimiter)
+	RLStoreUnavailable:      "limit store unavailable",
+	RLStoreTimeout:          "store call timed out",
+	RLDisabled:              "[RateLimiter] Disabled.",
+	RLNoStore:               "[Warning][RateLimiter] Rate limiter is enabled but no store is provided. Only default limits will be used.",
+	RLInitialized:           "[RateLimiter] Initialized (Store: %T). Default Rate=%.2f/sec, Default Capacity=%.2f (IP: %.2f/%.2f, header: %.2f/%.2f)",
+	RLIdentifyByHeader:      ". Client identification by header: '%s' (fallback to IP)",
+	RLKeyTemplate:           ". Bucket key: '%s'",
+	RLIdentifyByIP:          ". Client identification by IP address.",
+	RLIdentifyByAPIKey:      ". Client identification by API key (Authorization: Bearer or '%s'), by IP address without a key.",
+	RLIdentifyByFingerprint: ". HTTPS clients without the header are identified by TLS fingerprint (JA3).",
+	RLFailurePolicy:         ". Store failure policy: %s",
+	RLAlgorithm:             ". Algorithm: %s",
+	RLGlobalLimit:           ". Global limit: rate=%.2f, capacity=%.2f",
+	RLRefillerStarted:       "[RateLimiter] Background bucket refill started (every second).",
+	RLRefillerStopped:       "[RateLimiter] Background refill stopped.",
+	RLBucketsEvicted:        "[RateLimiter] Idle buckets evicted: %d",
+	RLBucketsOverCap:        "[Warning] [RateLimiter] More buckets in memory than rate_limiter.max_buckets (%d): evicted %d least recently used",
+	RLGossipStarted:         "[RateLimiter] Token consumption exchange: listening on %s, %d peers, interval %v",
+	RLGossipSendFailed:      "[Warning] [RateLimiter] Failed to send token consumption to peer %s: %v",
+	RLGossipBadMessage:      "[Warning] [RateLimiter] Malformed exchange message from %s: %v",
+	RLGossipReadFailed:      "[Warning] [RateLimiter] Failed to read exchange message: %v",
+	RLGossipUnknownPeer:     "[Warning] [RateLimiter] Dropped gossip message from %s: not in rate_limiter.gossip.peers",
+	RLGossipBadSignature:    "[Warning] [RateLimiter] Dropped gossip message from %s with an invalid signature (check rate_limiter.gossip.secret)",
+	RLGossipStaleMessage:    "[Warning] [RateLimiter] Dropped gossip message from %s: sent %v ago",
+	RLGossipReplayedMessage: "[Warning] [RateLimiter] Dropped gossip message from %s: sequence %d already accepted",
+	RLGossipKeyTooLong:      "[Warning] [RateLimiter] Bucket key of length %d is not gossiped: it does not fit into a gossip datagram",
+	RLGossipListenFailed:    "failed to listen on rate_limiter.gossip.listen %s: %w",
+	RLSourceIPDefaults:      "IP defaults",
+	RLSourceHeaderDefaults:  "header defaults",
+	RLSourceKeyDefaults:     "composite key defaults",
+	RLSourceStore:           "store",
+	RLSourceNotInStore:      " (not found in store)",
+	RLSourceRecheck:         " (re-check)",
+	RLStateInitial:          "initial (full bucket, time=0)",
+	RLStateSaved:            "saved in DB",
+	RLStateNotInDB:          "initial (not found in DB)",
+	RLLimitsUpdated:         "[RateLimiter] Updating limits for '%s' (source: %s): Rate: %.2f -> %.2f, Capacity: %.2f -> %.2f",
+	RLLimitsRollout:         "[RateLimiter] Gradually reducing limits for '%s' (source: %s) over %v: Rate: %.2f -> %.2f, Capacity: %.2f -> %.2f",
+	RLExistingConfigFailed:  "[RateLimiter] Failed to get limit config for existing client '%s'%s, keeping current limits. Error: %v",
+	RLNewConfigFailed:       "[RateLimiter] Failed to get limit config for new client '%s', using defaults. Error: %v",
+	RLNotStateStore:         "[Error][RateLimiter] Store (%T) reports state support but does not implement StateStore!",
+	RLNewStateFailed:        "[RateLimiter] Failed to get saved state for new client '%s', using initial state. Error: %v",
+	RLNoStateSupport:        "[RateLimiter] Store (%T) does not support state persistence for '%s'. Using initial state.",
+	RLBucketCreating:        "[RateLimiter] Creating new bucket for client '%s'. Config: %s (Rate=%.2f, Capacity=%.2f). State: %s (Tokens=%.2f, LastRefill=%v)",
+	RLBucketCreated:         "[RateLimiter] Bucket for '%s' created and initialized. Current state: Tokens=%.2f, LastRefill=%v",
+	RLRejectedStoreError:    "[RateLimiter] Request from '%s' rejected: %v",
+	RLCheck:                 "[RateLimiter] Check for '%s': %.2f tokens available (limits: rate=%.2f, capacity=%.2f)",
+	RLRejected:              "[RateLimiter] Request from '%s' rejected (limit exceeded)",
+	RLInFlightRejected:      "[RateLimiter] Request from '%s' rejected: %d concurrent requests already in flight (max_in_flight_per_client)",
+	RLGlobalRejected:        "[RateLimiter] Request from '%s' rejected by the global limit rate_limiter.global",
+	RLSoftLimitExceeded:     "[RateLimiter] Client '%s' exceeded the soft limit: %.2f of %.0f tokens left",
+	RLClientIDUnknown:       "[Warning] Could not determine client ID (header: '%s', XFF: '%s', RemoteAddr: '%s'). Using RemoteAddr.",
+	RLAPIKeysNoStore:        "[Warning][RateLimiter] rate_limiter.api_keys is enabled but the store does not support API keys. Clients are identified by IP address.",
+	RLAPIKeyLookupFailed:    "[Warning][RateLimiter] API key lookup failed, client identified by IP address: %v",
+	RLAPIKeyUnknown:         "[RateLimiter] Unknown API key, client identified by IP address",
+	RLSaveSkipped:           "[RateLimiter] State not saved. Enabled: %t, Store: %s, SupportsState: %t",
+	RLSaveNotStateStore:     "[Error][RateLimiter] Store (%T) reports state support but does not implement StateStore! Cannot save.",
+	RLNotStateStoreErr:      "store %T does not implement StateStore",
+	RLSavePreparing:         "[RateLimiter] Preparing to save state of %d buckets...",
+	RLSaveNothing:           "[RateLimiter] No active buckets to save.",
+	RLSaving:                "[RateLimiter] Saving state of %d buckets to store (%T)...",
+	RLBatchUpdateFailed:     "[Error][RateLimiter] Batch bucket state update failed: %v",
+	RLSaveFailed:            "failed to save RateLimiter state: %w",
+	RLSaved:                 "[RateLimiter] State of %d buckets saved successfully.",
+	RLDisabledErr:           "rate limiter is disabled",
+	RLBucketReset:           "[RateLimiter] Bucket '%s' refilled to capacity %.2f",
+	RLResetSaveFailed:       "failed to persist reset of bucket '%s': %w",
+	RLImportNoStore:         "no store configured, cannot import limits for %d clients",
+	RLImportReadFailed:      "failed to read limit for client '%s' during import: %w",
+	RLImportCreateFailed:    "failed to create limit for client '%s' during import: %w",
+	RLImportUpdateFailed:    "failed to update limit for client '%s' during import: %w",
+	RLImported:              "[RateLimiter] Imported limits from configuration: clients=%d, created=%d, updated=%d",
+	RLTrialSweepFailed:      "[RateLimiter] Failed to check expired trial periods: %v",
+	RLTrialUnknownPlan:      "[RateLimiter] Trial period of client '%s' has ended, but plan '%s' is not in rate_limiter.templates: the client keeps the trial limits",
+	RLTrialExpired:          "[RateLimiter] Trial period of client '%s' has ended: the client is moved to plan '%s' (Rate=%.2f, Capacity=%.2f)",
+
+	// JSON-ответы (internal/response)
+	ResponseError:         "[Error] Status: %d, ErrorCode: %s, RequestID: %s, Message: %s",
+	ResponseGRPCError:     "[Error] gRPC status: %d, ErrorCode: %s, RequestID: %s, Message: %s",
+	ResponseMarshalFailed: "[Error] Failed to marshal JSON response: %v",
+	ResponseWriteFailed:   "[Error] Failed to write JSON response to client: %v",
+
+	// Симуляция трафика (internal/simulate)
+	SimUsage:                 "Usage: balancer simulate [-config config.yaml] -scenario traffic.yaml [-json]",
+	SimScenarioReadFailed:    "failed to read scenario '%s': %v",
+	SimScenarioParseFailed:   "failed to parse scenario '%s': %v",
+	SimBadDuration:           "scenario: invalid %s '%s' (expected a positive duration)",
+	SimStepTooLong:           "scenario: step '%s' is longer than duration '%s'",
+	SimBadCapacity:           "scenario: backend_capacity_rps cannot be negative, got %v",
+	SimNoClients:             "scenario: no clients defined (clients)",
+	SimBadClient:             "scenario: clients[%d]: a non-empty id, positive rps and non-negative count are required",
+	SimFailed:                "Simulation failed: %v",
+	SimReportSummary:         "Simulation: %s, algorithm %s, rate limiter %s",
+	SimReportLimiterOn:       "enabled",
+	SimReportLimiterOff:      "disabled",
+	SimReportTotals:          "Requests: %d, allowed: %d, rejected 429: %d (%.1f%%), no available backend (503): %d",
+	SimReportClientsHeader:   "CLIENT\tCOUNT\tLIMIT (rate/capacity)\tREQUESTS\tALLOWED\tREJECTED\tWARNINGS",
+	SimReportBackendsHeader:  "BACKEND\tREQUESTS\tSHARE\tAVERAGE RPS\tPEAK RPS\tSATURATION",
+	SimReportBackendDown:     " (down)",
+	RLTestUsage:              "Usage: balancer ratelimit-test [-config config.yaml] [-rate 10] [-capacity 20] [-duration 30s] [-concurrency 4] [-tolerance 0.05] [-json]",
+	RLTestBadOption:          "invalid -%s value: %v",
+	RLTestFailed:             "rate limiter check failed: %v",
+	RLTestStoreUnavailable:   "rate limit store is unavailable: %v",
+	RLTestStoreErrors:        "%d checks were rejected because of a store error (store_failure_policy: fail_closed)",
+	RLTestSlowStore:          "a limit check took up to %v with store_timeout %v: the store is too slow",
+	RLTestDeviation:          "%d requests allowed, %.1f expected: deviation %+.1f%% exceeds the allowed difference of ±%.1f",
+	RLTestReportMemory:       "in memory",
+	RLTestReportSummary:      "Rate limiter: rate %.4g/s, capacity %.4g, store: %s, load %s with %d workers",
+	RLTestReportTotals:       "Checks: %d, allowed: %d, denied: %d, store errors: %d",
+	RLTestReportExpected:     "Expected allowed: %.1f, deviation: %+.2f%% (allowed difference ±%.1f)",
+	RLTestReportRate:         "Measured refill rate: %.2f/s (expected %.4g/s); check latency: average %s, max %s",
+	RLTestReportPassed:       "Result: PASS",
+	RLTestReportFailed:       "Result: FAIL",
+	StorageMigrateUsage:      "Usage: balancer storage migrate -from sqlite:./rate_limits.db -to sqlite:./new.db [-json]",
+	StorageMigrateOpenFailed: "failed to open storage '%s': %v",
+	StorageMigrateFailed:     "storage migration failed: %v",
+	StorageMigrateReport:     "Migrated: %d clients, %d access list entries, %d backend statuses, %d API keys. Destination data verified against the source.",
+	BenchUsage:               "Usage: balancer bench -target http://localhost:8080/ [-rps 100] [-clients 10] [-duration 30s] [-concurrency 64] [-timeout 5s] [-client-header X-Client-ID] [-trace-token TOKEN] [-json]",
+	BenchBadOption:           "invalid -%s value: %v",
+	BenchFailed:              "[Error] [Bench] Load run failed: %v",
+	BenchReportSummary:       "Target: %s, clients: %d, rate: %g requests/s, duration: %s",
+	BenchReportTotals:        "Requests sent: %d (%.1f/s), skipped (no free slot): %d, no response: %d",
+	BenchReportRates:         "Rate limiter rejections (429): %.2f%%, errors (5xx and no response): %.2f%%",
+	BenchReportLatency:       "Response time, ms: min %.2f, p50 %.2f, p90 %.2f, p99 %.2f, max %.2f",
+	BenchReportStatuses:      "Responses by status:",
+	BenchReportBackends:      "Distribution by backend:",
+	BenchReportNoTrace:       "Backend distribution not measured: responses carry no trace (enable trace and pass -trace-token).",
+
+	// Сценарии допуска (internal/hooks)
+	HooksLoaded:     "[Hooks] admission_hook script %s loaded (max_steps %d)",
+	HooksPrint:      "[Hooks] %s: %s",
+	HooksReadFailed: "failed to read admission_hook script %s: %v",
+	HooksNoAdmit:    "script %s does not define an admit(request) function",
+	HooksBadResult:  "admit returned %s, expected None or dict",
+	HooksBadKey:     "unknown field '%s' in admit decision (expected pool, cost, reject, message)",
+	HooksBadField:   "admit decision field '%s': expected %s, got %s",
+
+	// HTTPS-листенер с маршрутизацией по SNI (internal/sni)
+	SNISiteAdded:        "[SNI] Registered server names: %v",
+	SNINoCertificates:   "no certificates configured",
+	SNINoCACertificates: "file '%s' contains no PEM certificates",
+
+	// Хранилище (internal/storage)
+	StorageClientNotFound:           "client not found",
+	StorageClientExists:             "client already exists",
+	StorageAccessEntryNotFound:      "access list entry not found",
+	StorageAPIKeyNotFound:           "API key not found",
+	StorageOpenFailed:               "failed to open SQLite DB '%s': %w",
+	StoragePingFailed:               "failed to connect to SQLite DB '%s': %w",
+	StorageConnected:                "[Storage] Connected to SQLite DB (pure-go): %s",
+	StorageJournalModeMismatch:      "[Warning] [Storage] SQLite journal mode '%s' is unavailable, using '%s'",
+	StorageSchemaMigrated:           "[Storage] Applied schema migration %d (%s)",
+	StorageSchemaMigrationFailed:    "schema migration %d (%s) failed: %w",
+	StorageSchemaTooNew:             "database schema version %d is newer than supported %d: the database was created by a newer balancer version",
+	StorageSchemaVersionFailed:      "failed to read schema version: %w",
+	StorageGetStateFailed:           "[Storage] Failed to get state for client '%s': %v",
+	StorageQueryStateFailed:         "failed to query state of client '%s': %w",
+	StorageBadLastRefill:            "[Storage] Failed to parse last_refill ('%s') for client '%s': %v. Using zero time.",
+	StorageGetConfigFailed:          "[Storage] Failed to get limit config for client '%s': %v",
+	StorageQueryConfigFailed:        "failed to query limit config of client '%s': %w",
+	StorageGetSavedStateFailed:      "[Storage] Failed to get saved state for client '%s': %v",
+	StorageQuerySavedStateFailed:    "failed to query saved state of client '%s': %w",
+	StorageAddClientFailed:          "failed to add client '%s': %w",
+	StorageAddLimitFailed:           "failed to add limit for '%s': %w",
+	StorageLimitAdded:               "[Storage] Added limit for client '%s': Rate=%.2f, Capacity=%.2f, Tokens=%.2f",
+	StorageUpdateLimitFailed:        "failed to update limit for '%s': %w",
+	StorageUpdatedRowsFailed:        "failed to get updated row count for '%s': %w",
+	StorageUpdateClientFailed:       "failed to update client '%s': %w",
+	StorageLimitUpdated:             "[Storage] Updated limit (rate/capacity) for client '%s': Rate=%.2f, Capacity=%.2f",
+	StorageDeleteLimitFailed:        "failed to delete limit for '%s': %w",
+	StorageListLimitsFailed:         "failed to read client limits: %w",
+	StorageDeletedRowsFailed:        "failed to get deleted row count for '%s': %w",
+	StorageDeleteClientFailed:       "failed to delete client '%s': %w",
+	StorageLimitDeleted:             "[Storage] Deleted limit for client '%s'",
+	StorageBeginTxFailed:            "failed to begin transaction for batch update: %w",
+	StoragePrepareFailed:            "failed to prepare statement for batch update: %w",
+	StorageBatchClientFailed:        "[Storage] Failed to update state for client '%s' in batch: %v",
+	StorageBatchExecFailed:          "batch update failed for client '%s': %w",
+	StorageCommitFailed:             "failed to commit batch update transaction: %w",
+	StorageBatchUpdated:             "[Storage] BatchUpdateClientState: Updated state for %d of %d clients.",
+	StoragePutAccessEntryFailed:     "failed to save access list entry '%s': %w",
+	StorageAccessEntryPut:           "[Storage] Entry '%s' saved to the %s list",
+	StorageDeleteAccessEntryFailed:  "failed to delete access list entry '%s': %w",
+	StorageAccessEntryDeleted:       "[Storage] Entry '%s' deleted from the access list",
+	StorageListAccessFailed:         "failed to read the access list: %w",
+	StoragePutAPIKeyFailed:          "failed to save API key of client '%s': %w",
+	StorageAPIKeyPut:                "[Storage] API key of client '%s' saved",
+	StorageLookupAPIKeyFailed:       "failed to look up API key: %w",
+	StorageDeleteAPIKeyFailed:       "failed to delete API key '%s': %w",
+	StorageAPIKeyDeleted:            "[Storage] API key '%s' revoked",
+	StorageListAPIKeysFailed:        "failed to read API keys: %w",
+	StoragePurgeAccessFailed:        "failed to delete expired access list entries: %w",
+	StorageAccessEntriesExpired:     "[Storage] Deleted expired access list entries: %d",
+	StorageSaveHealthFailed:         "failed to save backend health: %w",
+	StorageHealthSaved:              "[Storage] Saved backend health: %d",
+	StorageEnableVacuumFailed:       "failed to enable auto_vacuum = INCREMENTAL: %w",
+	StorageIncrementalVacuumEnabled: "[Storage] Database switched to auto_vacuum = INCREMENTAL (VACUUM completed)",
+	StorageMaintenanceFailed:        "database maintenance failed: %w",
+	StorageMaintenanceDone:          "[Storage] Database maintenance: removed %d expired access list entries and %d stale backend status rows; freed %d pages, size %d -> %d bytes in %v",
+	StorageMaintenanceRunFailed:     "[Error] [Storage] Scheduled database maintenance failed: %v",
+	StorageMaintenanceStarted:       "[Storage] Scheduled database maintenance: every %v, backend status kept for %v, pages per run %d (0 - all)",
+	StorageLoadHealthFailed:         "failed to read backend health: %w",
+	StorageSetTrialFailed:           "failed to save trial period of client '%s': %w",
+	StorageTrialSet:                 "[Storage] Trial period of client '%s' until %s, then plan '%s'",
+	StorageGetTrialFailed:           "failed to read trial period of client '%s': %w",
+	StorageListTrialsFailed:         "failed to read expired trial periods: %w",
+	StorageDowngradeTrialFailed:     "failed to move client '%s' to a plan after the trial period: %w",
+	StorageUnknownDriver:            "unknown storage driver",
+	StorageUnknownDriverWrap:        "%w: '%s' (expected <driver>:<connection string>, drivers: %s)",
+	StorageNotEmpty:                 "destination storage is not empty",
+	StorageVerificationFailed:       "destination storage data differs from the source",
+	StorageVerificationFailedWrap:   "%w: table %s",
+	StorageExportFailed:             "failed to read data for migration: %w",
+	StorageImportFailed:             "failed to write migrated data: %w",
+	StorageMigrated:                 "[Storage] Migrated %d clients, %d access list entries, %d backend statuses, %d API keys; data verified",
+
+	// UDP-прокси (internal/udpproxy)
+	UDPBadBackend:        "udp.backends[%d]: failed to resolve address '%s': %w",
+	UDPListening:         "[UDP] Receiving datagrams on %s (backends: %d, session_timeout: %v, response_timeout: %v)",
+	UDPStopped:           "[UDP] Stopped receiving datagrams.",
+	UDPReadFailed:        "[UDP] Failed to read datagram: %v",
+	UDPNoHealthyBackends: "[UDP] No healthy backends, datagram from %s dropped",
+	UDPDialFailed:        "[UDP] Failed to open socket to backend %s: %v",
+	UDPWriteFailed:       "[UDP] Failed to send datagram to backend %s: %v",
+	UDPReplyFailed:       "[UDP] Failed to send reply to client %s: %v",
+	UDPBackendDown:       "[UDP] Backend %s excluded from balancing (%s) for %v",
+	UDPBackendUp:         "[UDP] Backend %s is responding again",
+	UDPReasonUnreachable: "port unreachable (ICMP)",
+	UDPReasonTimeout:     "no reply within %v",
+}
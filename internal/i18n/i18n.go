@@ -0,0 +1,132 @@
+// Package i18n содержит каталог сообщений логов и ошибок и выбор языка вывода.
+//
+// Все тексты, которые видит пользователь (логи, ошибки API и конфигурации), берутся
+// из каталога по идентификатору, а не пишутся строками в коде. Так переводы
+// не расходятся между собой, а инструменты, разбирающие вывод, получают стабильный текст.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// ID - идентификатор сообщения в каталоге.
+type ID string
+
+// Поддерживаемые локали.
+const (
+	LocaleRU = "ru"
+	LocaleEN = "en"
+
+	// DefaultLocale используется, если locale не задана в конфигурации.
+	DefaultLocale = LocaleRU
+)
+
+// catalogs - тексты сообщений по локалям. Форматные строки - в синтаксисе fmt.
+var catalogs = map[string]map[ID]string{
+	LocaleRU: ru,
+	LocaleEN: en,
+}
+
+var current atomic.Pointer[map[ID]string]
+
+func init() {
+	catalog := catalogs[DefaultLocale]
+	current.Store(&catalog)
+}
+
+// Locales возвращает отсортированный список поддерживаемых локалей.
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// IsSupported проверяет, есть ли каталог для локали.
+func IsSupported(locale string) bool {
+	_, ok := catalogs[strings.ToLower(strings.TrimSpace(locale))]
+	return ok
+}
+
+// Catalog возвращает копию каталога локали (nil для неизвестной локали).
+// Используется, например, для проверки полноты переводов.
+func Catalog(locale string) map[ID]string {
+	catalog, ok := catalogs[locale]
+	if !ok {
+		return nil
+	}
+	copied := make(map[ID]string, len(catalog))
+	for id, text := range catalog {
+		copied[id] = text
+	}
+	return copied
+}
+
+// SetLocale переключает язык сообщений. Действует на все последующие сообщения процесса.
+func SetLocale(locale string) error {
+	catalog, ok := catalogs[strings.ToLower(strings.TrimSpace(locale))]
+	if !ok {
+		return Errorf(LocaleUnsupported, locale, strings.Join(Locales(), ", "))
+	}
+	current.Store(&catalog)
+	return nil
+}
+
+// format возвращает форматную строку сообщения в текущей локали.
+// Если перевода нет, используется локаль по умолчанию, затем сам идентификатор.
+func format(id ID) string {
+	if text, ok := (*current.Load())[id]; ok {
+		return text
+	}
+	if text, ok := catalogs[DefaultLocale][id]; ok {
+		return text
+	}
+	return string(id)
+}
+
+// T возвращает текст сообщения, подставив аргументы.
+func T(id ID, args ...any) string {
+	if len(args) == 0 {
+		return format(id)
+	}
+	return fmt.Sprintf(format(id), args...)
+}
+
+// Errorf создает ошибку с текстом сообщения; поддерживает %w, как fmt.Errorf.
+func Errorf(id ID, args ...any) error {
+	return fmt.Errorf(format(id), args...)
+}
+
+// Logf пишет сообщение в стандартный лог в текущем формате (см. SetFormat), если его уровень
+// не ниже текущего (см. SetLevel).
+func Logf(id ID, args ...any) {
+	if levelOf(id) < CurrentLevel() {
+		return
+	}
+	write(id, T(id, args...))
+}
+
+// Fatalf пишет сообщение в стандартный лог и завершает процесс.
+func Fatalf(id ID, args ...any) {
+	fatal(id, T(id, args...))
+}
+
+// messageError - ошибка-значение (для errors.Is), текст которой переводится при выводе.
+type messageError struct {
+	id ID
+}
+
+func (e *messageError) Error() string {
+	return T(e.id)
+}
+
+// NewError создает ошибку-значение для объявления sentinel-ошибок пакета.
+// Текст берется в текущей локали в момент вызова Error().
+func NewError(id ID) error {
+	return &messageError{id: id}
+}
@@ -0,0 +1,123 @@
+package i18n_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/i18n"
+)
+
+// verbRe находит форматные глаголы fmt.
+var verbRe = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z]`)
+
+// verbs возвращает форматные глаголы сообщения по порядку, не считая экранированного %%.
+func verbs(format string) []string {
+	return verbRe.FindAllString(strings.ReplaceAll(format, "%%", ""), -1)
+}
+
+// TestCatalogsConsistent проверяет, что у каждого сообщения есть перевод
+// с теми же форматными глаголами в том же порядке.
+func TestCatalogsConsistent(t *testing.T) {
+	ru := i18n.Catalog(i18n.LocaleRU)
+	en := i18n.Catalog(i18n.LocaleEN)
+	require.NotEmpty(t, ru)
+
+	for id, ruText := range ru {
+		enText, ok := en[id]
+		if !assert.True(t, ok, "нет английского перевода для %s", id) {
+			continue
+		}
+		assert.Equal(t, verbs(ruText), verbs(enText), "разные глаголы формата в %s", id)
+	}
+	for id := range en {
+		_, ok := ru[id]
+		assert.True(t, ok, "нет русского текста для %s", id)
+	}
+}
+
+// TestSetLocale проверяет переключение локали и перевод ошибок-значений.
+func TestSetLocale(t *testing.T) {
+	t.Cleanup(func() { _ = i18n.SetLocale(i18n.DefaultLocale) })
+
+	notFound := i18n.NewError(i18n.StorageClientNotFound)
+	wrapped := i18n.Errorf(i18n.StorageUpdateClientFailed, "c1", notFound)
+
+	require.NoError(t, i18n.SetLocale("EN"))
+	assert.Equal(t, "Client with ID 'c1' already exists", i18n.T(i18n.APIClientExists, "c1"))
+	assert.Equal(t, "client not found", notFound.Error())
+	assert.True(t, errors.Is(wrapped, notFound))
+
+	require.NoError(t, i18n.SetLocale(i18n.LocaleRU))
+	assert.Equal(t, "клиент не найден", notFound.Error())
+
+	err := i18n.SetLocale("de")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'de'")
+	assert.Equal(t, "клиент не найден", notFound.Error(), "локаль не должна меняться при ошибке")
+}
+
+// TestLevel проверяет, что сообщения ниже текущего уровня не выводятся.
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		i18n.SetLevel(i18n.DefaultLevel)
+	})
+
+	i18n.Logf(i18n.BalancerDirector, "c1", 0, "http://backend1")
+	assert.Empty(t, buf.String(), "Подробные сообщения по умолчанию не выводятся")
+
+	level, err := i18n.ParseLevel(" Debug ")
+	require.NoError(t, err)
+	i18n.SetLevel(level)
+	i18n.Logf(i18n.BalancerDirector, "c1", 0, "http://backend1")
+	assert.Contains(t, buf.String(), "http://backend1")
+
+	buf.Reset()
+	i18n.SetLevel(i18n.LevelWarn)
+	i18n.Logf(i18n.MainReloaded)
+	assert.Empty(t, buf.String())
+	i18n.Logf(i18n.MainBalancerFailed, "boom")
+	assert.Contains(t, buf.String(), "boom", "Сообщения [Error] выводятся на уровне warn")
+
+	_, err = i18n.ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+// TestFormatJSON проверяет JSON-формат лога: уровень и тег подсистемы выносятся в отдельные поля.
+func TestFormatJSON(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	require.NoError(t, i18n.SetFormat("JSON"))
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		_ = i18n.SetFormat(i18n.FormatText)
+	})
+	assert.Equal(t, i18n.FormatJSON, i18n.CurrentFormat())
+
+	i18n.Logf(i18n.StorageMaintenanceRunFailed, "boom")
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "ERROR", entry["level"])
+	assert.Equal(t, "Storage", entry["component"])
+	assert.Equal(t, string(i18n.StorageMaintenanceRunFailed), entry["message_id"])
+	assert.Contains(t, entry["msg"], "boom")
+	assert.NotContains(t, entry["msg"], "[")
+	assert.NotEmpty(t, entry["time"])
+
+	buf.Reset()
+	i18n.Logf(i18n.BalancerDirector, "c1", 0, "http://backend1")
+	assert.Empty(t, buf.String(), "Уровень действует и в JSON-формате")
+
+	assert.Error(t, i18n.SetFormat("xml"))
+}
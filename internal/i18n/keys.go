@@ -0,0 +1,698 @@
+package i18n
+
+// Идентификаторы сообщений. Тексты - в ru.go и en.go; при добавлении сообщения нужно заполнить обе локали.
+const (
+	// Общие (internal/i18n)
+	LocaleUnsupported    ID = "LocaleUnsupported"
+	LogLevelUnsupported  ID = "LogLevelUnsupported"
+	LogFormatUnsupported ID = "LogFormatUnsupported"
+
+	// Запуск и остановка (cmd/balancer)
+	MainStarting              ID = "MainStarting"
+	MainVersion               ID = "MainVersion"
+	MainConfigLoadFailed      ID = "MainConfigLoadFailed"
+	MainNoBackends            ID = "MainNoBackends"
+	MainNoPort                ID = "MainNoPort"
+	MainSQLiteInit            ID = "MainSQLiteInit"
+	MainSQLiteFailed          ID = "MainSQLiteFailed"
+	MainImportFailed          ID = "MainImportFailed"
+	MainAccessListFailed      ID = "MainAccessListFailed"
+	MainNoStore               ID = "MainNoStore"
+	MainRateLimiterFailed     ID = "MainRateLimiterFailed"
+	MainBalancerFailed        ID = "MainBalancerFailed"
+	MainListening             ID = "MainListening"
+	MainAPIPrefix             ID = "MainAPIPrefix"
+	MainMetricsEnabled        ID = "MainMetricsEnabled"
+	MainTraceWithoutToken     ID = "MainTraceWithoutToken"
+	MainBackends              ID = "MainBackends"
+	MainRateLimiterOn         ID = "MainRateLimiterOn"
+	MainRateLimiterOff        ID = "MainRateLimiterOff"
+	MainHealthChecksOn        ID = "MainHealthChecksOn"
+	MainHealthChecksOff       ID = "MainHealthChecksOff"
+	MainServeFailed           ID = "MainServeFailed"
+	MainTLSListening          ID = "MainTLSListening"
+	MainTLSCertFailed         ID = "MainTLSCertFailed"
+	MainTLSDefaultCertFailed  ID = "MainTLSDefaultCertFailed"
+	MainTLSClientCAFailed     ID = "MainTLSClientCAFailed"
+	MainBackendTLSFailed      ID = "MainBackendTLSFailed"
+	MainTLSPoolFailed         ID = "MainTLSPoolFailed"
+	MainTLSServeFailed        ID = "MainTLSServeFailed"
+	MainUDPFailed             ID = "MainUDPFailed"
+	MainFPListening           ID = "MainFPListening"
+	MainFPFailed              ID = "MainFPFailed"
+	MainFPServeFailed         ID = "MainFPServeFailed"
+	MainSpilloverFailed       ID = "MainSpilloverFailed"
+	MainAccessLogFailed       ID = "MainAccessLogFailed"
+	MainHookFailed            ID = "MainHookFailed"
+	MainShutdownSignal        ID = "MainShutdownSignal"
+	MainBackgroundWaitFailed  ID = "MainBackgroundWaitFailed"
+	MainSaveStateFailed       ID = "MainSaveStateFailed"
+	MainSaveHealthFailed      ID = "MainSaveHealthFailed"
+	MainLoadHealthFailed      ID = "MainLoadHealthFailed"
+	MainShutdownFailed        ID = "MainShutdownFailed"
+	MainServerStopped         ID = "MainServerStopped"
+	MainDBCloseFailed         ID = "MainDBCloseFailed"
+	MainDBClosed              ID = "MainDBClosed"
+	MainStopped               ID = "MainStopped"
+	MainReloading             ID = "MainReloading"
+	MainReloadFailed          ID = "MainReloadFailed"
+	MainReloaded              ID = "MainReloaded"
+	MainReloadNoChanges       ID = "MainReloadNoChanges"
+	MainReloadChanges         ID = "MainReloadChanges"
+	MainReloadRestartRequired ID = "MainReloadRestartRequired"
+
+	// Конфигурация (internal/config)
+	ConfigUnknownAlgorithm            ID = "ConfigUnknownAlgorithm"
+	ConfigAlgorithm                   ID = "ConfigAlgorithm"
+	ConfigDefaultRateFixed            ID = "ConfigDefaultRateFixed"
+	ConfigDefaultCapacityFixed        ID = "ConfigDefaultCapacityFixed"
+	ConfigDefaultDatabasePath         ID = "ConfigDefaultDatabasePath"
+	ConfigStickyRandomSecret          ID = "ConfigStickyRandomSecret"
+	ConfigDefaultHealthInterval       ID = "ConfigDefaultHealthInterval"
+	ConfigDefaultHealthTimeout        ID = "ConfigDefaultHealthTimeout"
+	ConfigHealthTimeoutTooLong        ID = "ConfigHealthTimeoutTooLong"
+	ConfigDefaultHealthPath           ID = "ConfigDefaultHealthPath"
+	ConfigMaxBackoffTooSmall          ID = "ConfigMaxBackoffTooSmall"
+	ConfigHealthChecksOn              ID = "ConfigHealthChecksOn"
+	ConfigHealthChecksOff             ID = "ConfigHealthChecksOff"
+	ConfigNegativeRateLimit           ID = "ConfigNegativeRateLimit"
+	ConfigUnknownFailurePolicy        ID = "ConfigUnknownFailurePolicy"
+	ConfigUnknownRateLimitAlgorithm   ID = "ConfigUnknownRateLimitAlgorithm"
+	ConfigBadStoreTimeout             ID = "ConfigBadStoreTimeout"
+	ConfigNegativeStoreTimeout        ID = "ConfigNegativeStoreTimeout"
+	ConfigEmptyClientID               ID = "ConfigEmptyClientID"
+	ConfigBadClientLimit              ID = "ConfigBadClientLimit"
+	ConfigBadLimitTemplate            ID = "ConfigBadLimitTemplate"
+	ConfigNestedLimitTemplate         ID = "ConfigNestedLimitTemplate"
+	ConfigUnknownLimitTemplate        ID = "ConfigUnknownLimitTemplate"
+	ConfigBadSoftLimitRatio           ID = "ConfigBadSoftLimitRatio"
+	ConfigBadIPv6PrefixLength         ID = "ConfigBadIPv6PrefixLength"
+	ConfigBadTrustedProxy             ID = "ConfigBadTrustedProxy"
+	ConfigBadKeyTemplate              ID = "ConfigBadKeyTemplate"
+	ConfigKeyTemplateUnbalanced       ID = "ConfigKeyTemplateUnbalanced"
+	ConfigKeyTemplateUnknownAttr      ID = "ConfigKeyTemplateUnknownAttr"
+	ConfigBadDenialCacheTTL           ID = "ConfigBadDenialCacheTTL"
+	ConfigBadIdleBucketTTL            ID = "ConfigBadIdleBucketTTL"
+	ConfigBadMaxBuckets               ID = "ConfigBadMaxBuckets"
+	ConfigBadMaxInFlightPerClient     ID = "ConfigBadMaxInFlightPerClient"
+	ConfigBadGlobalLimit              ID = "ConfigBadGlobalLimit"
+	ConfigAPIKeysWithIdentifierHeader ID = "ConfigAPIKeysWithIdentifierHeader"
+	ConfigBadAPIKeyCacheTTL           ID = "ConfigBadAPIKeyCacheTTL"
+	ConfigBadLimitDecreaseWindow      ID = "ConfigBadLimitDecreaseWindow"
+	ConfigBadHealthInterval           ID = "ConfigBadHealthInterval"
+	ConfigNonPositiveHealthInterval   ID = "ConfigNonPositiveHealthInterval"
+	ConfigBadHealthTimeout            ID = "ConfigBadHealthTimeout"
+	ConfigNonPositiveHealthTimeout    ID = "ConfigNonPositiveHealthTimeout"
+	ConfigUnknownHealthCheckType      ID = "ConfigUnknownHealthCheckType"
+	ConfigBadExpectedStatus           ID = "ConfigBadExpectedStatus"
+	ConfigBadExpectedJSONField        ID = "ConfigBadExpectedJSONField"
+	ConfigNegativeWorkers             ID = "ConfigNegativeWorkers"
+	ConfigNegativeLogRepeatEvery      ID = "ConfigNegativeLogRepeatEvery"
+	ConfigBadMaxBackoff               ID = "ConfigBadMaxBackoff"
+	ConfigBadHealthJitter             ID = "ConfigBadHealthJitter"
+	ConfigHealthJitterRange           ID = "ConfigHealthJitterRange"
+	ConfigBadDuration                 ID = "ConfigBadDuration"
+	ConfigNonPositiveDuration         ID = "ConfigNonPositiveDuration"
+	ConfigBadRejectionThreshold       ID = "ConfigBadRejectionThreshold"
+	ConfigNegativeMinRequests         ID = "ConfigNegativeMinRequests"
+	ConfigBadRoutePrefix              ID = "ConfigBadRoutePrefix"
+	ConfigDuplicateRoutePrefix        ID = "ConfigDuplicateRoutePrefix"
+	ConfigBadAcceptEncoding           ID = "ConfigBadAcceptEncoding"
+	ConfigUnknownLabeledBackend       ID = "ConfigUnknownLabeledBackend"
+	ConfigEmptyLabel                  ID = "ConfigEmptyLabel"
+	ConfigUnknownLimitedBackend       ID = "ConfigUnknownLimitedBackend"
+	ConfigBadMaxConnections           ID = "ConfigBadMaxConnections"
+	ConfigBadMaxConnectionsPerIP      ID = "ConfigBadMaxConnectionsPerIP"
+	ConfigBadVacuumPages              ID = "ConfigBadVacuumPages"
+	ConfigBadSQLiteJournalMode        ID = "ConfigBadSQLiteJournalMode"
+	ConfigBadSQLiteSynchronous        ID = "ConfigBadSQLiteSynchronous"
+	ConfigBadSQLiteMaxOpenConns       ID = "ConfigBadSQLiteMaxOpenConns"
+	ConfigBadSQLitePragma             ID = "ConfigBadSQLitePragma"
+	ConfigSQLitePragmaReserved        ID = "ConfigSQLitePragmaReserved"
+	ConfigBadSaturationThreshold      ID = "ConfigBadSaturationThreshold"
+	ConfigEmptyBackendURL             ID = "ConfigEmptyBackendURL"
+	ConfigBadBackendWeight            ID = "ConfigBadBackendWeight"
+	ConfigBadFlushInterval            ID = "ConfigBadFlushInterval"
+	ConfigBadContentType              ID = "ConfigBadContentType"
+	ConfigBadHoldDown                 ID = "ConfigBadHoldDown"
+	ConfigBadMaxFailures              ID = "ConfigBadMaxFailures"
+	ConfigBadFailureWindow            ID = "ConfigBadFailureWindow"
+	ConfigBadSlowStart                ID = "ConfigBadSlowStart"
+	ConfigSlowStartWithHash           ID = "ConfigSlowStartWithHash"
+	ConfigBadBackendPassiveHealth     ID = "ConfigBadBackendPassiveHealth"
+	ConfigBadStickyCookieName         ID = "ConfigBadStickyCookieName"
+	ConfigBadStickyTTL                ID = "ConfigBadStickyTTL"
+	ConfigBadReadinessInFlightRatio   ID = "ConfigBadReadinessInFlightRatio"
+	ConfigBadReadinessStoreTimeout    ID = "ConfigBadReadinessStoreTimeout"
+	ConfigBadMetricsPath              ID = "ConfigBadMetricsPath"
+	ConfigAdminAuthMissing            ID = "ConfigAdminAuthMissing"
+	ConfigAdminAuthDuplicate          ID = "ConfigAdminAuthDuplicate"
+	ConfigUnknownAdminRole            ID = "ConfigUnknownAdminRole"
+	ConfigTracingBadEndpoint          ID = "ConfigTracingBadEndpoint"
+	ConfigTracingBadSampleRatio       ID = "ConfigTracingBadSampleRatio"
+	ConfigTracingBadQueue             ID = "ConfigTracingBadQueue"
+	ConfigBadRequestMaxAge            ID = "ConfigBadRequestMaxAge"
+	ConfigHookSource                  ID = "ConfigHookSource"
+	ConfigBadStatsHistoryWindow       ID = "ConfigBadStatsHistoryWindow"
+	ConfigUnknownThrottlePolicy       ID = "ConfigUnknownThrottlePolicy"
+	ConfigThrottleWeightWithHash      ID = "ConfigThrottleWeightWithHash"
+	ConfigUnknownPool                 ID = "ConfigUnknownPool"
+	ConfigBackendTLSNoKeyPair         ID = "ConfigBackendTLSNoKeyPair"
+	ConfigBackendTLSSkipVerifyWithCA  ID = "ConfigBackendTLSSkipVerifyWithCA"
+	ConfigUnknownUpstreamAuthType     ID = "ConfigUnknownUpstreamAuthType"
+	ConfigUpstreamAuthMissing         ID = "ConfigUpstreamAuthMissing"
+	ConfigBadDNSServer                ID = "ConfigBadDNSServer"
+	ConfigBadDNSDuration              ID = "ConfigBadDNSDuration"
+	ConfigCanaryWithHash              ID = "ConfigCanaryWithHash"
+	ConfigCanaryNoSelector            ID = "ConfigCanaryNoSelector"
+	ConfigBadCanaryPercent            ID = "ConfigBadCanaryPercent"
+	ConfigBadCanaryWindow             ID = "ConfigBadCanaryWindow"
+	ConfigBadCanaryMinRequests        ID = "ConfigBadCanaryMinRequests"
+	ConfigBadCanaryErrorRateDelta     ID = "ConfigBadCanaryErrorRateDelta"
+	ConfigBadCanaryLatencyRatio       ID = "ConfigBadCanaryLatencyRatio"
+	ConfigCanaryNoThresholds          ID = "ConfigCanaryNoThresholds"
+	ConfigBadThrottlePenalty          ID = "ConfigBadThrottlePenalty"
+	ConfigBadThrottleWeightPercent    ID = "ConfigBadThrottleWeightPercent"
+	ConfigTLSNoPort                   ID = "ConfigTLSNoPort"
+	ConfigTLSSamePort                 ID = "ConfigTLSSamePort"
+	ConfigTLSNoSites                  ID = "ConfigTLSNoSites"
+	ConfigTLSNoServerNames            ID = "ConfigTLSNoServerNames"
+	ConfigTLSNoCertificate            ID = "ConfigTLSNoCertificate"
+	ConfigTLSBadServerName            ID = "ConfigTLSBadServerName"
+	ConfigTLSDuplicateServerName      ID = "ConfigTLSDuplicateServerName"
+	ConfigTLSNoDefaultCertificate     ID = "ConfigTLSNoDefaultCertificate"
+	ConfigTLSBadMinVersion            ID = "ConfigTLSBadMinVersion"
+	ConfigTLSCipherSuitesWithTLS13    ID = "ConfigTLSCipherSuitesWithTLS13"
+	ConfigTLSBadCipherSuite           ID = "ConfigTLSBadCipherSuite"
+	ConfigTLSBadClientAuthMode        ID = "ConfigTLSBadClientAuthMode"
+	ConfigTLSClientAuthNoCA           ID = "ConfigTLSClientAuthNoCA"
+	ConfigTLSSubjectHeaderWithoutAuth ID = "ConfigTLSSubjectHeaderWithoutAuth"
+	ConfigUDPNoListen                 ID = "ConfigUDPNoListen"
+	ConfigUDPNoBackends               ID = "ConfigUDPNoBackends"
+	ConfigUDPBadBackend               ID = "ConfigUDPBadBackend"
+	ConfigUDPBadMaxFails              ID = "ConfigUDPBadMaxFails"
+	ConfigGossipNoListen              ID = "ConfigGossipNoListen"
+	ConfigGossipBadListen             ID = "ConfigGossipBadListen"
+	ConfigGossipBadPeer               ID = "ConfigGossipBadPeer"
+	ConfigFPNoPort                    ID = "ConfigFPNoPort"
+	ConfigFPSamePort                  ID = "ConfigFPSamePort"
+	ConfigBadBindAddress              ID = "ConfigBadBindAddress"
+	ConfigBadAccessEntry              ID = "ConfigBadAccessEntry"
+	ConfigEmptyAccessEntry            ID = "ConfigEmptyAccessEntry"
+	ConfigFPNoGateways                ID = "ConfigFPNoGateways"
+	ConfigConcurrencyBadMaxInFlight   ID = "ConfigConcurrencyBadMaxInFlight"
+	ConfigConcurrencyBadMaxQueue      ID = "ConfigConcurrencyBadMaxQueue"
+	ConfigConcurrencyBadPriority      ID = "ConfigConcurrencyBadPriority"
+	ConfigBadPriorityTrusted          ID = "ConfigBadPriorityTrusted"
+	ConfigSpilloverNoBackends         ID = "ConfigSpilloverNoBackends"
+	ConfigSpilloverNegativeBudget     ID = "ConfigSpilloverNegativeBudget"
+	ConfigSpilloverNoBudget           ID = "ConfigSpilloverNoBudget"
+	ConfigCaptureBadBufferSize        ID = "ConfigCaptureBadBufferSize"
+	ConfigCaptureBadMaxBody           ID = "ConfigCaptureBadMaxBody"
+	ConfigAccessLogBadSink            ID = "ConfigAccessLogBadSink"
+	ConfigAccessLogBadAddress         ID = "ConfigAccessLogBadAddress"
+	ConfigAccessLogNoTopic            ID = "ConfigAccessLogNoTopic"
+	ConfigAccessLogBadBatchSize       ID = "ConfigAccessLogBadBatchSize"
+	ConfigAccessLogBadQueueSize       ID = "ConfigAccessLogBadQueueSize"
+	ConfigAccessLogBadRetries         ID = "ConfigAccessLogBadRetries"
+	ConfigAccessLogBadFormat          ID = "ConfigAccessLogBadFormat"
+	ConfigAccessLogBadMaxSize         ID = "ConfigAccessLogBadMaxSize"
+	ConfigAccessLogBadMaxBackups      ID = "ConfigAccessLogBadMaxBackups"
+
+	// Списки доступа (internal/access)
+	AccessStoreUnavailable ID = "AccessStoreUnavailable"
+	AccessLoaded           ID = "AccessLoaded"
+	AccessPurgeFailed      ID = "AccessPurgeFailed"
+
+	// Журнал доступа (internal/accesslog)
+	AccessLogStarted    ID = "AccessLogStarted"
+	AccessLogDropped    ID = "AccessLogDropped"
+	AccessLogSendFailed ID = "AccessLogSendFailed"
+	AccessLogBatchLost  ID = "AccessLogBatchLost"
+	AccessLogBadStatus  ID = "AccessLogBadStatus"
+	AccessLogRotated    ID = "AccessLogRotated"
+	TracingStarted      ID = "TracingStarted"
+	TracingDropped      ID = "TracingDropped"
+	TracingExportFailed ID = "TracingExportFailed"
+	TracingBadStatus    ID = "TracingBadStatus"
+
+	// Ограничение одновременных запросов (internal/admission)
+	AdmissionEnabled      ID = "AdmissionEnabled"
+	AdmissionQueueFull    ID = "AdmissionQueueFull"
+	AdmissionQueueTimeout ID = "AdmissionQueueTimeout"
+	ConnLimitEnabled      ID = "ConnLimitEnabled"
+	ConnLimitRejected     ID = "ConnLimitRejected"
+
+	// Алертинг (internal/alerting)
+	AlertsStarted         ID = "AlertsStarted"
+	AlertsRejectionRate   ID = "AlertsRejectionRate"
+	AlertsAllBackendsDown ID = "AlertsAllBackendsDown"
+	AlertsEvent           ID = "AlertsEvent"
+	AlertsMarshalFailed   ID = "AlertsMarshalFailed"
+	AlertsWebhookFailed   ID = "AlertsWebhookFailed"
+	AlertsWebhookStatus   ID = "AlertsWebhookStatus"
+
+	// API управления лимитами (internal/api)
+	APINoStore                    ID = "APINoStore"
+	APIStoreUnavailable           ID = "APIStoreUnavailable"
+	APIDebugPath                  ID = "APIDebugPath"
+	APIListNotImplemented         ID = "APIListNotImplemented"
+	APIBadListLimit               ID = "APIBadListLimit"
+	APIBadListOffset              ID = "APIBadListOffset"
+	APIListFailed                 ID = "APIListFailed"
+	APIListInternal               ID = "APIListInternal"
+	APIMethodNotAllowedCollection ID = "APIMethodNotAllowedCollection"
+	APIMethodNotAllowedItem       ID = "APIMethodNotAllowedItem"
+	APIInvalidJSON                ID = "APIInvalidJSON"
+	APIClientIDRequired           ID = "APIClientIDRequired"
+	APINonPositiveLimit           ID = "APINonPositiveLimit"
+	APIPatchEmpty                 ID = "APIPatchEmpty"
+	APIClientExists               ID = "APIClientExists"
+	APICreateFailed               ID = "APICreateFailed"
+	APICreateInternal             ID = "APICreateInternal"
+	APIGetFailed                  ID = "APIGetFailed"
+	APIClientNotFound             ID = "APIClientNotFound"
+	APIClientIDMismatch           ID = "APIClientIDMismatch"
+	APIUpdateNotFound             ID = "APIUpdateNotFound"
+	APIUpdateFailed               ID = "APIUpdateFailed"
+	APIUpdateInternal             ID = "APIUpdateInternal"
+	APIDeleteNotFound             ID = "APIDeleteNotFound"
+	APIDeleteFailed               ID = "APIDeleteFailed"
+	APIDeleteInternal             ID = "APIDeleteInternal"
+	APIResetNotSupported          ID = "APIResetNotSupported"
+	APIResetDisabled              ID = "APIResetDisabled"
+	APIResetFailed                ID = "APIResetFailed"
+	APIResetInternal              ID = "APIResetInternal"
+	APIStateNotSupported          ID = "APIStateNotSupported"
+	APIStateDisabled              ID = "APIStateDisabled"
+	APIStateFailed                ID = "APIStateFailed"
+	OpenAPITitle                  ID = "OpenAPITitle"
+	OpenAPIListClients            ID = "OpenAPIListClients"
+	OpenAPICreateClient           ID = "OpenAPICreateClient"
+	OpenAPIGetClient              ID = "OpenAPIGetClient"
+	OpenAPIUpdateClient           ID = "OpenAPIUpdateClient"
+	OpenAPIPatchClient            ID = "OpenAPIPatchClient"
+	OpenAPIDeleteClient           ID = "OpenAPIDeleteClient"
+	OpenAPIResetClient            ID = "OpenAPIResetClient"
+	OpenAPIClientState            ID = "OpenAPIClientState"
+	OpenAPIListAccess             ID = "OpenAPIListAccess"
+	OpenAPIPutAccess              ID = "OpenAPIPutAccess"
+	OpenAPIGetAccess              ID = "OpenAPIGetAccess"
+	OpenAPIDeleteAccess           ID = "OpenAPIDeleteAccess"
+	OpenAPIListAPIKeys            ID = "OpenAPIListAPIKeys"
+	OpenAPICreateAPIKey           ID = "OpenAPICreateAPIKey"
+	OpenAPIDeleteAPIKey           ID = "OpenAPIDeleteAPIKey"
+	APITrialIncomplete            ID = "APITrialIncomplete"
+	APITrialInPast                ID = "APITrialInPast"
+	APITrialsNotSupported         ID = "APITrialsNotSupported"
+	APIUnknownPlan                ID = "APIUnknownPlan"
+	APIAdminNotFound              ID = "APIAdminNotFound"
+	APIAdminMethodNotAllowed      ID = "APIAdminMethodNotAllowed"
+	APIHealthCheckFailed          ID = "APIHealthCheckFailed"
+	APICaptureDisabled            ID = "APICaptureDisabled"
+	APICaptureEmptyFilter         ID = "APICaptureEmptyFilter"
+	APIStateSaveUnavailable       ID = "APIStateSaveUnavailable"
+	APIStateSaveFailed            ID = "APIStateSaveFailed"
+	APIStateSaveInternal          ID = "APIStateSaveInternal"
+	APIMaintenanceUnavailable     ID = "APIMaintenanceUnavailable"
+	APIMaintenanceFailed          ID = "APIMaintenanceFailed"
+	APIMaintenanceInternal        ID = "APIMaintenanceInternal"
+	APIReloadUnavailable          ID = "APIReloadUnavailable"
+	APIBadTimeSeriesSeconds       ID = "APIBadTimeSeriesSeconds"
+	APIPoolNotFound               ID = "APIPoolNotFound"
+	APICanaryNotConfigured        ID = "APICanaryNotConfigured"
+	APIBackendBadLimits           ID = "APIBackendBadLimits"
+	APINoReloadYet                ID = "APINoReloadYet"
+	APIAccessStoreUnavailable     ID = "APIAccessStoreUnavailable"
+	APIAccessEntryNotFound        ID = "APIAccessEntryNotFound"
+	APIBadAccessValue             ID = "APIBadAccessValue"
+	APIBadAccessList              ID = "APIBadAccessList"
+	APIBadAccessTTL               ID = "APIBadAccessTTL"
+	APIAccessPutFailed            ID = "APIAccessPutFailed"
+	APIAccessDeleteFailed         ID = "APIAccessDeleteFailed"
+	APIAccessInternal             ID = "APIAccessInternal"
+	APIAPIKeyNotFound             ID = "APIAPIKeyNotFound"
+	APIAPIKeyPlansNotSupported    ID = "APIAPIKeyPlansNotSupported"
+	APIAPIKeyCreateFailed         ID = "APIAPIKeyCreateFailed"
+	APIAPIKeyListFailed           ID = "APIAPIKeyListFailed"
+	APIAPIKeyDeleteFailed         ID = "APIAPIKeyDeleteFailed"
+	APIAPIKeyInternal             ID = "APIAPIKeyInternal"
+	APIUnauthorized               ID = "APIUnauthorized"
+	APIForbidden                  ID = "APIForbidden"
+	APIAuthFailed                 ID = "APIAuthFailed"
+	APIAuthForbidden              ID = "APIAuthForbidden"
+	APILogLevelChanged            ID = "APILogLevelChanged"
+	APINotReady                   ID = "APINotReady"
+	APIReadyAgain                 ID = "APIReadyAgain"
+
+	// Балансировщик и health checks (internal/balancer)
+	BalancerNoHealthyBackends      ID = "BalancerNoHealthyBackends"
+	BalancerBackendsSaturated      ID = "BalancerBackendsSaturated"
+	BalancerBackendStatus          ID = "BalancerBackendStatus"
+	BalancerBackendDrained         ID = "BalancerBackendDrained"
+	BalancerBackendEnabled         ID = "BalancerBackendEnabled"
+	BalancerBackendRegistered      ID = "BalancerBackendRegistered"
+	BalancerBackendRemoved         ID = "BalancerBackendRemoved"
+	BalancerBackendExists          ID = "BalancerBackendExists"
+	BalancerBackendExistsWrap      ID = "BalancerBackendExistsWrap"
+	BalancerLastBackend            ID = "BalancerLastBackend"
+	BalancerHealthRestored         ID = "BalancerHealthRestored"
+	BalancerBackendUp              ID = "BalancerBackendUp"
+	BalancerBackendDown            ID = "BalancerBackendDown"
+	BalancerNoBackends             ID = "BalancerNoBackends"
+	BalancerUnknownAlgorithm       ID = "BalancerUnknownAlgorithm"
+	BalancerRNGInit                ID = "BalancerRNGInit"
+	BalancerBadBackendURL          ID = "BalancerBadBackendURL"
+	BalancerRelativeBackendURL     ID = "BalancerRelativeBackendURL"
+	BalancerProxyFailed            ID = "BalancerProxyFailed"
+	BalancerRequestHeaders         ID = "BalancerRequestHeaders"
+	BalancerBadGateway             ID = "BalancerBadGateway"
+	BalancerBackendTimeout         ID = "BalancerBackendTimeout"
+	BalancerGatewayTimeout         ID = "BalancerGatewayTimeout"
+	BalancerAdmissionRejected      ID = "BalancerAdmissionRejected"
+	BalancerOverloaded             ID = "BalancerOverloaded"
+	BalancerRequestExpired         ID = "BalancerRequestExpired"
+	BalancerRequestTooOld          ID = "BalancerRequestTooOld"
+	BalancerHookFailed             ID = "BalancerHookFailed"
+	BalancerHookRejected           ID = "BalancerHookRejected"
+	BalancerHookDenied             ID = "BalancerHookDenied"
+	BalancerHookPool               ID = "BalancerHookPool"
+	BalancerHookUnknownPool        ID = "BalancerHookUnknownPool"
+	BalancerAccessDenied           ID = "BalancerAccessDenied"
+	BalancerForbidden              ID = "BalancerForbidden"
+	BalancerBadContentType         ID = "BalancerBadContentType"
+	BalancerEmptyBody              ID = "BalancerEmptyBody"
+	BalancerBackendHeldDown        ID = "BalancerBackendHeldDown"
+	BalancerBackendPassiveHealth   ID = "BalancerBackendPassiveHealth"
+	BalancerBackendFailureCounted  ID = "BalancerBackendFailureCounted"
+	BalancerClientDisconnected     ID = "BalancerClientDisconnected"
+	BalancerSpilloverEnabled       ID = "BalancerSpilloverEnabled"
+	BalancerSpillover              ID = "BalancerSpillover"
+	BalancerGRPCEnabled            ID = "BalancerGRPCEnabled"
+	BalancerGRPCUpstreamStatus     ID = "BalancerGRPCUpstreamStatus"
+	BalancerGRPCUpstreamMapped     ID = "BalancerGRPCUpstreamMapped"
+	BalancerBackendAdded           ID = "BalancerBackendAdded"
+	BalancerHealthChecksStarted    ID = "BalancerHealthChecksStarted"
+	BalancerHealthChecksStopping   ID = "BalancerHealthChecksStopping"
+	BalancerRequestReceived        ID = "BalancerRequestReceived"
+	BalancerStoreUnavailable       ID = "BalancerStoreUnavailable"
+	BalancerRateLimited            ID = "BalancerRateLimited"
+	BalancerTooManyInFlight        ID = "BalancerTooManyInFlight"
+	BalancerSelectFailed           ID = "BalancerSelectFailed"
+	BalancerAllBackendsDown        ID = "BalancerAllBackendsDown"
+	BalancerForwarding             ID = "BalancerForwarding"
+	BalancerDirector               ID = "BalancerDirector"
+	BalancerRoutesLoaded           ID = "BalancerRoutesLoaded"
+	BalancerBackendLabels          ID = "BalancerBackendLabels"
+	BalancerBackendMaxConnections  ID = "BalancerBackendMaxConnections"
+	BalancerBackendWeight          ID = "BalancerBackendWeight"
+	BalancerThrottlePolicy         ID = "BalancerThrottlePolicy"
+	BalancerUpstreamAuth           ID = "BalancerUpstreamAuth"
+	BalancerDNS                    ID = "BalancerDNS"
+	BalancerBackendTLSInsecure     ID = "BalancerBackendTLSInsecure"
+	BalancerDNSSystem              ID = "BalancerDNSSystem"
+	BalancerThrottleWeightReduced  ID = "BalancerThrottleWeightReduced"
+	BalancerThrottleRetry          ID = "BalancerThrottleRetry"
+	BalancerThrottleNoRetry        ID = "BalancerThrottleNoRetry"
+	BalancerCanary                 ID = "BalancerCanary"
+	BalancerCanaryNoBackends       ID = "BalancerCanaryNoBackends"
+	BalancerCanaryErrorRate        ID = "BalancerCanaryErrorRate"
+	BalancerCanaryLatency          ID = "BalancerCanaryLatency"
+	BalancerCanaryRolledBack       ID = "BalancerCanaryRolledBack"
+	BalancerCanaryResumed          ID = "BalancerCanaryResumed"
+	BalancerBackendReresolved      ID = "BalancerBackendReresolved"
+	HealthCheckStarting            ID = "HealthCheckStarting"
+	HealthCheckStaggered           ID = "HealthCheckStaggered"
+	HealthCheckStopSignal          ID = "HealthCheckStopSignal"
+	HealthCheckFailed              ID = "HealthCheckFailed"
+	HealthCheckFailedRepeated      ID = "HealthCheckFailedRepeated"
+	HealthCheckRecovered           ID = "HealthCheckRecovered"
+	HealthCheckCycle               ID = "HealthCheckCycle"
+	HealthCheckRequestFailed       ID = "HealthCheckRequestFailed"
+	HealthCheckUnreachable         ID = "HealthCheckUnreachable"
+	HealthCheckBadStatus           ID = "HealthCheckBadStatus"
+	HealthCheckUnexpectedStatus    ID = "HealthCheckUnexpectedStatus"
+	HealthCheckBodyMismatch        ID = "HealthCheckBodyMismatch"
+	HealthCheckBadJSON             ID = "HealthCheckBadJSON"
+	HealthCheckJSONMismatch        ID = "HealthCheckJSONMismatch"
+	HealthCheckJSONMissing         ID = "HealthCheckJSONMissing"
+	HealthCheckDialFailed          ID = "HealthCheckDialFailed"
+	HealthCheckGRPCStatus          ID = "HealthCheckGRPCStatus"
+	HealthCheckGRPCBadResponse     ID = "HealthCheckGRPCBadResponse"
+	HealthCheckGRPCNotServing      ID = "HealthCheckGRPCNotServing"
+	HealthCheckGRPCNoStatus        ID = "HealthCheckGRPCNoStatus"
+	HealthCheckGRPCBadFrame        ID = "HealthCheckGRPCBadFrame"
+	HealthCheckGRPCBadMessage      ID = "HealthCheckGRPCBadMessage"
+	HealthCheckDisabled            ID = "HealthCheckDisabled"
+	HealthCheckBackendNotFound     ID = "HealthCheckBackendNotFound"
+	HealthCheckBackendNotFoundWrap ID = "HealthCheckBackendNotFoundWrap"
+	HealthCheckForced              ID = "HealthCheckForced"
+	HealthCheckInvalidConfig       ID = "HealthCheckInvalidConfig"
+	HealthCheckWorkersNotReloaded  ID = "HealthCheckWorkersNotReloaded"
+	HealthCheckReloaded            ID = "HealthCheckReloaded"
+
+	// Запись запросов для отладки (internal/capture)
+	CaptureStarted ID = "CaptureStarted"
+	CaptureStopped ID = "CaptureStopped"
+
+	// Прямой прокси для исходящего трафика (internal/forwardproxy)
+	FPInitialized       ID = "FPInitialized"
+	FPRequest           ID = "FPRequest"
+	FPNotProxyRequest   ID = "FPNotProxyRequest"
+	FPGatewayFailed     ID = "FPGatewayFailed"
+	FPGatewayStatus     ID = "FPGatewayStatus"
+	FPGatewayRefused    ID = "FPGatewayRefused"
+	FPHijackUnsupported ID = "FPHijackUnsupported"
+	FPHijackFailed      ID = "FPHijackFailed"
+
+	// Rate Limiter (internal/ratelimiter)
+	RLStoreUnavailable      ID = "RLStoreUnavailable"
+	RLStoreTimeout          ID = "RLStoreTimeout"
+	RLDisabled              ID = "RLDisabled"
+	RLNoStore               ID = "RLNoStore"
+	RLInitialized           ID = "RLInitialized"
+	RLIdentifyByHeader      ID = "RLIdentifyByHeader"
+	RLKeyTemplate           ID = "RLKeyTemplate"
+	RLIdentifyByIP          ID = "RLIdentifyByIP"
+	RLIdentifyByAPIKey      ID = "RLIdentifyByAPIKey"
+	RLIdentifyByFingerprint ID = "RLIdentifyByFingerprint"
+	RLFailurePolicy         ID = "RLFailurePolicy"
+	RLAlgorithm             ID = "RLAlgorithm"
+	RLGlobalLimit           ID = "RLGlobalLimit"
+	RLRefillerStarted       ID = "RLRefillerStarted"
+	RLRefillerStopped       ID = "RLRefillerStopped"
+	RLBucketsEvicted        ID = "RLBucketsEvicted"
+	RLBucketsOverCap        ID = "RLBucketsOverCap"
+	RLGossipStarted         ID = "RLGossipStarted"
+	RLGossipSendFailed      ID = "RLGossipSendFailed"
+	RLGossipBadMessage      ID = "RLGossipBadMessage"
+	RLGossipReadFailed      ID = "RLGossipReadFailed"
+	RLGossipUnknownPeer     ID = "RLGossipUnknownPeer"
+	RLGossipBadSignature    ID = "RLGossipBadSignature"
+	RLGossipStaleMessage    ID = "RLGossipStaleMessage"
+	RLGossipReplayedMessage ID = "RLGossipReplayedMessage"
+	RLGossipKeyTooLong      ID = "RLGossipKeyTooLong"
+	RLGossipListenFailed    ID = "RLGossipListenFailed"
+	RLSourceIPDefaults      ID = "RLSourceIPDefaults"
+	RLSourceHeaderDefaults  ID = "RLSourceHeaderDefaults"
+	RLSourceKeyDefaults     ID = "RLSourceKeyDefaults"
+	RLSourceStore           ID = "RLSourceStore"
+	RLSourceNotInStore      ID = "RLSourceNotInStore"
+	RLSourceRecheck         ID = "RLSourceRecheck"
+	RLStateInitial          ID = "RLStateInitial"
+	RLStateSaved            ID = "RLStateSaved"
+	RLStateNotInDB          ID = "RLStateNotInDB"
+	RLLimitsUpdated         ID = "RLLimitsUpdated"
+	RLLimitsRollout         ID = "RLLimitsRollout"
+	RLExistingConfigFailed  ID = "RLExistingConfigFailed"
+	RLNewConfigFailed       ID = "RLNewConfigFailed"
+	RLNotStateStore         ID = "RLNotStateStore"
+	RLNewStateFailed        ID = "RLNewStateFailed"
+	RLNoStateSupport        ID = "RLNoStateSupport"
+	RLBucketCreating        ID = "RLBucketCreating"
+	RLBucketCreated         ID = "RLBucketCreated"
+	RLRejectedStoreError    ID = "RLRejectedStoreError"
+	RLCheck                 ID = "RLCheck"
+	RLRejected              ID = "RLRejected"
+	RLInFlightRejected      ID = "RLInFlightRejected"
+	RLGlobalRejected        ID = "RLGlobalRejected"
+	RLSoftLimitExceeded     ID = "RLSoftLimitExceeded"
+	RLClientIDUnknown       ID = "RLClientIDUnknown"
+	RLAPIKeysNoStore        ID = "RLAPIKeysNoStore"
+	RLAPIKeyLookupFailed    ID = "RLAPIKeyLookupFailed"
+	RLAPIKeyUnknown         ID = "RLAPIKeyUnknown"
+	RLSaveSkipped           ID = "RLSaveSkipped"
+	RLSaveNotStateStore     ID = "RLSaveNotStateStore"
+	RLNotStateStoreErr      ID = "RLNotStateStoreErr"
+	RLSavePreparing         ID = "RLSavePreparing"
+	RLSaveNothing           ID = "RLSaveNothing"
+	RLSaving                ID = "RLSaving"
+	RLBatchUpdateFailed     ID = "RLBatchUpdateFailed"
+	RLSaveFailed            ID = "RLSaveFailed"
+	RLSaved                 ID = "RLSaved"
+	RLDisabledErr           ID = "RLDisabledErr"
+	RLBucketReset           ID = "RLBucketReset"
+	RLResetSaveFailed       ID = "RLResetSaveFailed"
+	RLImportNoStore         ID = "RLImportNoStore"
+	RLImportReadFailed      ID = "RLImportReadFailed"
+	RLImportCreateFailed    ID = "RLImportCreateFailed"
+	RLImportUpdateFailed    ID = "RLImportUpdateFailed"
+	RLImported              ID = "RLImported"
+	RLTrialSweepFailed      ID = "RLTrialSweepFailed"
+	RLTrialUnknownPlan      ID = "RLTrialUnknownPlan"
+	RLTrialExpired          ID = "RLTrialExpired"
+
+	// JSON-ответы (internal/response)
+	ResponseError         ID = "ResponseError"
+	ResponseGRPCError     ID = "ResponseGRPCError"
+	ResponseMarshalFailed ID = "ResponseMarshalFailed"
+	ResponseWriteFailed   ID = "ResponseWriteFailed"
+
+	// Симуляция трафика (internal/simulate)
+	SimUsage                 ID = "SimUsage"
+	SimScenarioReadFailed    ID = "SimScenarioReadFailed"
+	SimScenarioParseFailed   ID = "SimScenarioParseFailed"
+	SimBadDuration           ID = "SimBadDuration"
+	SimStepTooLong           ID = "SimStepTooLong"
+	SimBadCapacity           ID = "SimBadCapacity"
+	SimNoClients             ID = "SimNoClients"
+	SimBadClient             ID = "SimBadClient"
+	SimFailed                ID = "SimFailed"
+	SimReportSummary         ID = "SimReportSummary"
+	SimReportLimiterOn       ID = "SimReportLimiterOn"
+	SimReportLimiterOff      ID = "SimReportLimiterOff"
+	SimReportTotals          ID = "SimReportTotals"
+	SimReportClientsHeader   ID = "SimReportClientsHeader"
+	SimReportBackendsHeader  ID = "SimReportBackendsHeader"
+	SimReportBackendDown     ID = "SimReportBackendDown"
+	RLTestUsage              ID = "RLTestUsage"
+	RLTestBadOption          ID = "RLTestBadOption"
+	RLTestFailed             ID = "RLTestFailed"
+	RLTestStoreUnavailable   ID = "RLTestStoreUnavailable"
+	RLTestStoreErrors        ID = "RLTestStoreErrors"
+	RLTestSlowStore          ID = "RLTestSlowStore"
+	RLTestDeviation          ID = "RLTestDeviation"
+	RLTestReportMemory       ID = "RLTestReportMemory"
+	RLTestReportSummary      ID = "RLTestReportSummary"
+	RLTestReportTotals       ID = "RLTestReportTotals"
+	RLTestReportExpected     ID = "RLTestReportExpected"
+	RLTestReportRate         ID = "RLTestReportRate"
+	RLTestReportPassed       ID = "RLTestReportPassed"
+	RLTestReportFailed       ID = "RLTestReportFailed"
+	StorageMigrateUsage      ID = "StorageMigrateUsage"
+	StorageMigrateOpenFailed ID = "StorageMigrateOpenFailed"
+	StorageMigrateFailed     ID = "StorageMigrateFailed"
+	StorageMigrateReport     ID = "StorageMigrateReport"
+	BenchUsage               ID = "BenchUsage"
+	BenchBadOption           ID = "BenchBadOption"
+	BenchFailed              ID = "BenchFailed"
+	BenchReportSummary       ID = "BenchReportSummary"
+	BenchReportTotals        ID = "BenchReportTotals"
+	BenchReportRates         ID = "BenchReportRates"
+	BenchReportLatency       ID = "BenchReportLatency"
+	BenchReportStatuses      ID = "BenchReportStatuses"
+	BenchReportBackends      ID = "BenchReportBackends"
+	BenchReportNoTrace       ID = "BenchReportNoTrace"
+
+	// Сценарии допуска (internal/hooks)
+	HooksLoaded     ID = "HooksLoaded"
+	HooksPrint      ID = "HooksPrint"
+	HooksReadFailed ID = "HooksReadFailed"
+	HooksNoAdmit    ID = "HooksNoAdmit"
+	HooksBadResult  ID = "HooksBadResult"
+	HooksBadKey     ID = "HooksBadKey"
+	HooksBadField   ID = "HooksBadField"
+
+	// HTTPS-листенер с маршрутизацией по SNI (internal/sni)
+	SNISiteAdded        ID = "SNISiteAdded"
+	SNINoCertificates   ID = "SNINoCertificates"
+	SNINoCACertificates ID = "SNINoCACertificates"
+
+	// Хранилище (internal/storage)
+	StorageClientNotFound           ID = "StorageClientNotFound"
+	StorageClientExists             ID = "StorageClientExists"
+	StorageAccessEntryNotFound      ID = "StorageAccessEntryNotFound"
+	StorageAPIKeyNotFound           ID = "StorageAPIKeyNotFound"
+	StorageOpenFailed               ID = "StorageOpenFailed"
+	StoragePingFailed               ID = "StoragePingFailed"
+	StorageConnected                ID = "StorageConnected"
+	StorageJournalModeMismatch      ID = "StorageJournalModeMismatch"
+	StorageSchemaMigrated           ID = "StorageSchemaMigrated"
+	StorageSchemaMigrationFailed    ID = "StorageSchemaMigrationFailed"
+	StorageSchemaTooNew             ID = "StorageSchemaTooNew"
+	StorageSchemaVersionFailed      ID = "StorageSchemaVersionFailed"
+	StorageGetStateFailed           ID = "StorageGetStateFailed"
+	StorageQueryStateFailed         ID = "StorageQueryStateFailed"
+	StorageBadLastRefill            ID = "StorageBadLastRefill"
+	StorageGetConfigFailed          ID = "StorageGetConfigFailed"
+	StorageQueryConfigFailed        ID = "StorageQueryConfigFailed"
+	StorageGetSavedStateFailed      ID = "StorageGetSavedStateFailed"
+	StorageQuerySavedStateFailed    ID = "StorageQuerySavedStateFailed"
+	StorageAddClientFailed          ID = "StorageAddClientFailed"
+	StorageAddLimitFailed           ID = "StorageAddLimitFailed"
+	StorageLimitAdded               ID = "StorageLimitAdded"
+	StorageUpdateLimitFailed        ID = "StorageUpdateLimitFailed"
+	StorageUpdatedRowsFailed        ID = "StorageUpdatedRowsFailed"
+	StorageUpdateClientFailed       ID = "StorageUpdateClientFailed"
+	StorageLimitUpdated             ID = "StorageLimitUpdated"
+	StorageDeleteLimitFailed        ID = "StorageDeleteLimitFailed"
+	StorageListLimitsFailed         ID = "StorageListLimitsFailed"
+	StorageDeletedRowsFailed        ID = "StorageDeletedRowsFailed"
+	StorageDeleteClientFailed       ID = "StorageDeleteClientFailed"
+	StorageLimitDeleted             ID = "StorageLimitDeleted"
+	StorageBeginTxFailed            ID = "StorageBeginTxFailed"
+	StoragePrepareFailed            ID = "StoragePrepareFailed"
+	StorageBatchClientFailed        ID = "StorageBatchClientFailed"
+	StorageBatchExecFailed          ID = "StorageBatchExecFailed"
+	StorageCommitFailed             ID = "StorageCommitFailed"
+	StorageBatchUpdated             ID = "StorageBatchUpdated"
+	StoragePutAccessEntryFailed     ID = "StoragePutAccessEntryFailed"
+	StorageAccessEntryPut           ID = "StorageAccessEntryPut"
+	StorageDeleteAccessEntryFailed  ID = "StorageDeleteAccessEntryFailed"
+	StorageAccessEntryDeleted       ID = "StorageAccessEntryDeleted"
+	StorageListAccessFailed         ID = "StorageListAccessFailed"
+	StoragePutAPIKeyFailed          ID = "StoragePutAPIKeyFailed"
+	StorageAPIKeyPut                ID = "StorageAPIKeyPut"
+	StorageLookupAPIKeyFailed       ID = "StorageLookupAPIKeyFailed"
+	StorageDeleteAPIKeyFailed       ID = "StorageDeleteAPIKeyFailed"
+	StorageAPIKeyDeleted            ID = "StorageAPIKeyDeleted"
+	StorageListAPIKeysFailed        ID = "StorageListAPIKeysFailed"
+	StoragePurgeAccessFailed        ID = "StoragePurgeAccessFailed"
+	StorageAccessEntriesExpired     ID = "StorageAccessEntriesExpired"
+	StorageSaveHealthFailed         ID = "StorageSaveHealthFailed"
+	StorageHealthSaved              ID = "StorageHealthSaved"
+	StorageEnableVacuumFailed       ID = "StorageEnableVacuumFailed"
+	StorageIncrementalVacuumEnabled ID = "StorageIncrementalVacuumEnabled"
+	StorageMaintenanceFailed        ID = "StorageMaintenanceFailed"
+	StorageMaintenanceDone          ID = "StorageMaintenanceDone"
+	StorageMaintenanceRunFailed     ID = "StorageMaintenanceRunFailed"
+	StorageMaintenanceStarted       ID = "StorageMaintenanceStarted"
+	StorageLoadHealthFailed         ID = "StorageLoadHealthFailed"
+	StorageSetTrialFailed           ID = "StorageSetTrialFailed"
+	StorageTrialSet                 ID = "StorageTrialSet"
+	StorageGetTrialFailed           ID = "StorageGetTrialFailed"
+	StorageListTrialsFailed         ID = "StorageListTrialsFailed"
+	StorageDowngradeTrialFailed     ID = "StorageDowngradeTrialFailed"
+	StorageUnknownDriver            ID = "StorageUnknownDriver"
+	StorageUnknownDriverWrap        ID = "StorageUnknownDriverWrap"
+	StorageNotEmpty                 ID = "StorageNotEmpty"
+	StorageVerificationFailed       ID = "StorageVerificationFailed"
+	StorageVerificationFailedWrap   ID = "StorageVerificationFailedWrap"
+	StorageExportFailed             ID = "StorageExportFailed"
+	StorageImportFailed             ID = "StorageImportFailed"
+	StorageMigrated                 ID = "StorageMigrated"
+
+	// UDP-прокси (internal/udpproxy)
+	UDPBadBackend        ID = "UDPBadBackend"
+	UDPListening         ID = "UDPListening"
+	UDPStopped           ID = "UDPStopped"
+	UDPReadFailed        ID = "UDPReadFailed"
+	UDPNoHealthyBackends ID = "UDPNoHealthyBackends"
+	UDPDialFailed        ID = "UDPDialFailed"
+	UDPWriteFailed       ID = "UDPWriteFailed"
+	UDPReplyFailed       ID = "UDPReplyFailed"
+	UDPBackendDown       ID = "UDPBackendDown"
+	UDPBackendUp         ID = "UDPBackendUp"
+	UDPReasonUnreachable ID = "UDPReasonUnreachable"
+	UDPReasonTimeout     ID = "UDPReasonTimeout"
+)
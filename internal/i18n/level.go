@@ -0,0 +1,92 @@
+package i18n
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// Level - уровень сообщения лога. Сообщения ниже текущего уровня (см. SetLevel) не выводятся.
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// DefaultLevel используется, если log_level не задан в конфигурации.
+const DefaultLevel = LevelInfo
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel разбирает имя уровня: "debug", "info", "warn" или "error" (без учета регистра).
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for level, levelName := range levelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+	return 0, Errorf(LogLevelUnsupported, name, "debug, info, warn, error")
+}
+
+var minLevel atomic.Int32
+
+func init() {
+	minLevel.Store(int32(DefaultLevel))
+}
+
+// SetLevel задает минимальный уровень выводимых сообщений. Действует на все последующие сообщения процесса.
+func SetLevel(level Level) {
+	minLevel.Store(int32(level))
+}
+
+// CurrentLevel возвращает текущий минимальный уровень сообщений.
+func CurrentLevel() Level {
+	return Level(minLevel.Load())
+}
+
+// debugMessages - подробные сообщения о каждом запросе и каждой проверке, нужные только при отладке.
+var debugMessages = map[ID]bool{
+	BalancerRequestReceived: true,
+	BalancerForwarding:      true,
+	BalancerDirector:        true,
+	BalancerRequestHeaders:  true,
+	APIDebugPath:            true,
+	RLCheck:                 true,
+	RLBucketCreating:        true,
+	RLBucketCreated:         true,
+	RLBucketsEvicted:        true,
+	RLGlobalRejected:        true,
+	RLAPIKeyUnknown:         true,
+	FPRequest:               true,
+	HealthCheckCycle:        true,
+	ConnLimitRejected:       true,
+}
+
+// levelOf возвращает уровень сообщения: подробные сообщения - debug, сообщения с тегом [Error] - error,
+// с тегом [Warning] - warn, остальные - info.
+func levelOf(id ID) Level {
+	if debugMessages[id] {
+		return LevelDebug
+	}
+	text := catalogs[DefaultLocale][id]
+	switch {
+	case strings.HasPrefix(text, "[Error]"):
+		return LevelError
+	case strings.HasPrefix(text, "[Warning]"):
+		return LevelWarn
+	default:
+		return LevelInfo
+	}
+}
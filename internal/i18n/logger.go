@@ -0,0 +1,113 @@
+package i18n
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Форматы вывода лога.
+const (
+	// FormatText - строка для человека: "2006/01/02 15:04:05 [Warning] [Тег] текст" (по умолчанию).
+	FormatText = "text"
+	// FormatJSON - одна JSON-запись на строку для сборщиков логов: time, level, msg (текст без тегов),
+	// message_id (идентификатор сообщения в каталоге) и component (тег подсистемы, например "Balancer",
+	// если он есть).
+	FormatJSON = "json"
+)
+
+// jsonLogger - логгер формата FormatJSON; nil - используется FormatText.
+var jsonLogger atomic.Pointer[slog.Logger]
+
+// SetFormat задает формат вывода лога: FormatText или FormatJSON. Вывод в обоих форматах идет
+// в текущий приемник стандартного лога (log.SetOutput).
+func SetFormat(format string) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatText:
+		jsonLogger.Store(nil)
+	case FormatJSON:
+		// Отбор по уровню выполняет Logf (см. SetLevel), поэтому обработчик пропускает все записи
+		handler := slog.NewJSONHandler(stdLogWriter{}, &slog.HandlerOptions{Level: slog.LevelDebug})
+		jsonLogger.Store(slog.New(handler))
+	default:
+		return Errorf(LogFormatUnsupported, format, FormatText+", "+FormatJSON)
+	}
+	return nil
+}
+
+// CurrentFormat возвращает текущий формат вывода лога.
+func CurrentFormat() string {
+	if jsonLogger.Load() != nil {
+		return FormatJSON
+	}
+	return FormatText
+}
+
+// LogText пишет готовый текст сообщения id, составленный из нескольких сообщений каталога,
+// с уровнем и тегами id (см. Logf).
+func LogText(id ID, text string) {
+	if levelOf(id) < CurrentLevel() {
+		return
+	}
+	write(id, text)
+}
+
+// write выводит текст сообщения id в текущем формате.
+func write(id ID, text string) {
+	logger := jsonLogger.Load()
+	if logger == nil {
+		log.Print(text)
+		return
+	}
+	msg, component := splitTags(text)
+	attrs := []slog.Attr{slog.String("message_id", string(id))}
+	if component != "" {
+		attrs = append(attrs, slog.String("component", component))
+	}
+	logger.LogAttrs(context.Background(), slogLevels[levelOf(id)], msg, attrs...)
+}
+
+// fatal выводит сообщение об ошибке и завершает процесс.
+func fatal(id ID, text string) {
+	if jsonLogger.Load() == nil {
+		log.Fatal(text)
+	}
+	write(id, text)
+	os.Exit(1)
+}
+
+var slogLevels = map[Level]slog.Level{
+	LevelDebug: slog.LevelDebug,
+	LevelInfo:  slog.LevelInfo,
+	LevelWarn:  slog.LevelWarn,
+	LevelError: slog.LevelError,
+}
+
+// splitTags отделяет от текста теги "[Error]", "[Warning]" (уровень передается отдельно) и тег подсистемы:
+// "[Warning] [Balancer] текст" -> "текст", "Balancer".
+func splitTags(text string) (msg, component string) {
+	for strings.HasPrefix(text, "[") {
+		end := strings.Index(text, "] ")
+		if end < 0 {
+			break
+		}
+		tag := text[1:end]
+		text = text[end+2:]
+		if tag == "Error" || tag == "Warning" {
+			continue
+		}
+		component = tag
+		break
+	}
+	return text, component
+}
+
+// stdLogWriter пишет в текущий приемник стандартного лога, чтобы log.SetOutput действовал и на JSON-формат.
+type stdLogWriter struct{}
+
+func (stdLogWriter) Write(p []byte) (int, error) {
+	return log.Writer().Write(p)
+}
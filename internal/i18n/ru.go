@@ -0,0 +1,698 @@
+package i18n
+
+// ru - русский каталог (локаль по умолчанию).
+var ru = map[ID]string{
+	// Общие (internal/i18n)
+	LocaleUnsupported:    "неподдерживаемая locale: '%s'. Допустимые значения: %s",
+	LogLevelUnsupported:  "неподдерживаемый log_level: '%s'. Допустимые значения: %s",
+	LogFormatUnsupported: "неподдерживаемый logging.format: '%s'. Допустимые значения: %s",
+
+	// Запуск и остановка (cmd/balancer)
+	MainStarting:              "Запуск балансировщика...",
+	MainVersion:               "[Main] Версия %s (коммит %s, собрано %s, %s)",
+	MainConfigLoadFailed:      "[Error] Не удалось загрузить конфигурацию: %v",
+	MainNoBackends:            "Список бэкенд-серверов (backend_servers) в конфигурации пуст.",
+	MainNoPort:                "Порт (port) не указан в конфигурации.",
+	MainSQLiteInit:            "[Storage] Инициализация SQLite из '%s'...",
+	MainSQLiteFailed:          "[Error] Не удалось подключиться к БД SQLite: %v",
+	MainImportFailed:          "[Error] Не удалось импортировать лимиты клиентов из конфигурации: %v",
+	MainAccessListFailed:      "Ошибка загрузки списков доступа: %v",
+	MainNoStore:               "[Storage] database_path не задан или Rate Limiter выключен: лимиты клиентов, списки доступа и API-ключи хранятся в памяти и теряются при перезапуске.",
+	MainRateLimiterFailed:     "[Error] Не удалось инициализировать Rate Limiter: %v",
+	MainBalancerFailed:        "[Error] Не удалось создать балансировщик: %v",
+	MainListening:             "Балансировщик запущен на %s",
+	MainAPIPrefix:             "API доступно по префиксам /clients/ и /access/",
+	MainMetricsEnabled:        "[Main] Метрики Prometheus доступны по пути %s",
+	MainTraceWithoutToken:     "[Warning] trace.token не задан: трассировка решений (X-Balancer-Trace) добавляется во все ответы",
+	MainBackends:              "Зарегистрированные бэкенды: %v",
+	MainRateLimiterOn:         "Rate Limiter включен (Store: %T, Header: '%s')",
+	MainRateLimiterOff:        "Rate Limiter выключен.",
+	MainHealthChecksOn:        "[Main] Health Checks включены (Interval: %v, Timeout: %v, Path: %s)",
+	MainHealthChecksOff:       "[Main] Health Checks выключены.",
+	MainServeFailed:           "Ошибка запуска сервера: %v",
+	MainTLSListening:          "Балансировщик принимает HTTPS на %s (сайтов: %d)",
+	MainTLSCertFailed:         "Ошибка загрузки сертификата tls.sites[%d]: %v",
+	MainTLSDefaultCertFailed:  "Ошибка загрузки сертификата листенера tls.cert_file: %v",
+	MainTLSClientCAFailed:     "Ошибка загрузки tls.client_auth.ca_file: %v",
+	MainBackendTLSFailed:      "Ошибка настройки backend_tls.%s: %v",
+	MainTLSPoolFailed:         "Ошибка создания пула бэкендов tls.sites[%d]: %v",
+	MainTLSServeFailed:        "Ошибка запуска HTTPS-сервера: %v",
+	MainUDPFailed:             "Ошибка запуска UDP-прокси: %v",
+	MainFPListening:           "Прямой прокси принимает запросы на %s (шлюзы: %v)",
+	MainFPFailed:              "Ошибка создания пула egress-шлюзов: %v",
+	MainFPServeFailed:         "Ошибка запуска прямого прокси: %v",
+	MainSpilloverFailed:       "Ошибка создания резервного пула: %v",
+	MainAccessLogFailed:       "Ошибка настройки журнала доступа: %v",
+	MainHookFailed:            "Ошибка загрузки сценария admission_hook: %v",
+	MainShutdownSignal:        "Получен сигнал завершения, начинаем Graceful Shutdown...",
+	MainBackgroundWaitFailed:  "[Warning] Фоновые задачи (%s) не завершились вовремя: %v",
+	MainSaveStateFailed:       "[Error] Ошибка сохранения состояния Rate Limiter: %v",
+	MainSaveHealthFailed:      "[Error] Ошибка сохранения статуса бэкендов: %v",
+	MainLoadHealthFailed:      "[Warning] Не удалось восстановить статус бэкендов: %v",
+	MainShutdownFailed:        "Ошибка при Graceful Shutdown сервера: %v",
+	MainServerStopped:         "HTTP-сервер корректно остановлен.",
+	MainDBCloseFailed:         "[Error] Ошибка закрытия БД: %v",
+	MainDBClosed:              "Соединение с БД закрыто.",
+	MainStopped:               "Балансировщик успешно завершил работу.",
+	MainReloading:             "[Main] Получен SIGHUP, перечитываем конфигурацию из '%s'...",
+	MainReloadFailed:          "[Error] Не удалось применить новую конфигурацию: %v",
+	MainReloaded:              "[Main] Конфигурация перечитана.",
+	MainReloadNoChanges:       "[Main] Параметры конфигурации не изменились.",
+	MainReloadChanges:         "[Main] Изменения конфигурации (%d): %s",
+	MainReloadRestartRequired: "[Main] Изменения, требующие перезапуска (%d): %s",
+
+	// Конфигурация (internal/config)
+	ConfigUnknownAlgorithm:            "неподдерживаемый load_balancing_algorithm: '%s'. Допустимые значения: 'round_robin', 'random', 'consistent_hash'",
+	ConfigAlgorithm:                   "[Config] Используемый алгоритм балансировки: %s",
+	ConfigDefaultRateFixed:            "[Warning] rate_limiter.default_rate должен быть > 0, установлено значение по умолчанию 1",
+	ConfigDefaultCapacityFixed:        "[Warning] rate_limiter.default_capacity должен быть > 0, установлено значение по умолчанию 1",
+	ConfigDefaultDatabasePath:         "[Warning] rate_limiter.database_path не указан, используется значение по умолчанию ./rate_limits.db",
+	ConfigStickyRandomSecret:          "[Warning] sticky_sessions.secret не задан: cookie привязки подписываются случайным ключом и перестают действовать после перезапуска и на других экземплярах",
+	ConfigDefaultHealthInterval:       "[Config] Интервал HealthCheck не указан, используется значение по умолчанию: %s",
+	ConfigDefaultHealthTimeout:        "[Config] Таймаут HealthCheck не указан, используется значение по умолчанию: %s",
+	ConfigHealthTimeoutTooLong:        "[Config] Внимание: Таймаут HealthCheck (%s) больше или равен интервалу (%s). Рекомендуется меньший таймаут.",
+	ConfigDefaultHealthPath:           "[Config] Путь HealthCheck не указан, используется значение по умолчанию: %s",
+	ConfigMaxBackoffTooSmall:          "[Config] health_check.max_backoff (%s) меньше интервала, backoff отключен.",
+	ConfigHealthChecksOn:              "[Config] Health Checks включены: Интервал=%v, Таймаут=%v, Путь=%s",
+	ConfigHealthChecksOff:             "[Config] Health Checks выключены.",
+	ConfigNegativeRateLimit:           "rate_limiter.%s не может быть отрицательным: %v",
+	ConfigUnknownFailurePolicy:        "неподдерживаемый rate_limiter.store_failure_policy: '%s'. Допустимые значения: '%s', '%s'",
+	ConfigUnknownRateLimitAlgorithm:   "неподдерживаемый rate_limiter.algorithm: '%s'. Допустимые значения: '%s', '%s'",
+	ConfigBadStoreTimeout:             "неверный формат rate_limiter.store_timeout (%s): %w",
+	ConfigNegativeStoreTimeout:        "rate_limiter.store_timeout не может быть отрицательным: %s",
+	ConfigEmptyClientID:               "rate_limiter.clients: пустой ID клиента",
+	ConfigBadClientLimit:              "rate_limiter.clients['%s']: значения rate и capacity должны быть положительными",
+	ConfigBadLimitTemplate:            "rate_limiter.templates['%s']: значения rate и capacity должны быть положительными",
+	ConfigNestedLimitTemplate:         "rate_limiter.templates['%s']: шаблон не может ссылаться на другой шаблон",
+	ConfigUnknownLimitTemplate:        "rate_limiter.clients['%s']: неизвестный шаблон '%s'",
+	ConfigBadSoftLimitRatio:           "rate_limiter.soft_limit_ratio должен быть в диапазоне [0, 1), получено %v",
+	ConfigBadIPv6PrefixLength:         "rate_limiter.ipv6_prefix_length должен быть в диапазоне [0, 128], получено %d",
+	ConfigBadTrustedProxy:             "rate_limiter.trusted_proxies[%d]: '%s' не является IP-адресом или подсетью CIDR",
+	ConfigBadKeyTemplate:              "rate_limiter.key_template: неверный шаблон '%s': %s",
+	ConfigKeyTemplateUnbalanced:       "непарная фигурная скобка",
+	ConfigKeyTemplateUnknownAttr:      "неизвестный атрибут {%s} (доступны client_id, ip, method, host, path_prefix, header.<имя>)",
+	ConfigBadDenialCacheTTL:           "неверный rate_limiter.denial_cache_ttl '%s': ожидается неотрицательная длительность (например, 100ms)",
+	ConfigBadIdleBucketTTL:            "неверный rate_limiter.idle_bucket_ttl '%s': ожидается неотрицательная длительность (например, 10m)",
+	ConfigBadMaxBuckets:               "rate_limiter.max_buckets не может быть отрицательным, получено %d",
+	ConfigBadMaxInFlightPerClient:     "rate_limiter.max_in_flight_per_client не может быть отрицательным, получено %d",
+	ConfigBadGlobalLimit:              "rate_limiter.global: rate и capacity не могут быть отрицательными, получено rate=%v, capacity=%v",
+	ConfigAPIKeysWithIdentifierHeader: "rate_limiter.api_keys и rate_limiter.identifier_header не задаются вместе: с API-ключами ID клиента берется из хранилища",
+	ConfigBadAPIKeyCacheTTL:           "неверный rate_limiter.api_keys.cache_ttl '%s': ожидается неотрицательная длительность (например, 1m)",
+	ConfigBadLimitDecreaseWindow:      "неверный rate_limiter.limit_decrease_window '%s': ожидается неотрицательная длительность (например, 5m)",
+	ConfigBadHealthInterval:           "неверный формат интервала HealthCheck (%s): %w",
+	ConfigNonPositiveHealthInterval:   "интервал HealthCheck должен быть положительным: %s",
+	ConfigBadHealthTimeout:            "неверный формат таймаута HealthCheck (%s): %w",
+	ConfigNonPositiveHealthTimeout:    "таймаут HealthCheck должен быть положительным: %s",
+	ConfigUnknownHealthCheckType:      "неизвестный health_check.type '%s' (допустимо: http, tcp)",
+	ConfigBadExpectedStatus:           "health_check.expected_status: неверный код ответа %d",
+	ConfigBadExpectedJSONField:        "health_check.expected_json: неверное имя поля '%s'",
+	ConfigNegativeWorkers:             "health_check.workers не может быть отрицательным: %d",
+	ConfigNegativeLogRepeatEvery:      "health_check.log_repeat_every не может быть отрицательным: %d",
+	ConfigBadMaxBackoff:               "неверный формат health_check.max_backoff (%s): %w",
+	ConfigBadHealthJitter:             "неверный формат health_check.jitter (%s): %w",
+	ConfigHealthJitterRange:           "health_check.jitter (%s) должен быть не меньше 0 и меньше интервала (%s)",
+	ConfigBadDuration:                 "неверный формат %s (%s): %w",
+	ConfigNonPositiveDuration:         "%s должен быть положительным: %s",
+	ConfigBadRejectionThreshold:       "alerts.rejection_rate_threshold должен быть в диапазоне (0, 1]: %v",
+	ConfigNegativeMinRequests:         "alerts.min_requests не может быть отрицательным: %d",
+	ConfigBadRoutePrefix:              "routes[%d].path_prefix должен начинаться с '/': '%s'",
+	ConfigDuplicateRoutePrefix:        "routes[%d].path_prefix '%s' указан более одного раза",
+	ConfigBadAcceptEncoding:           "%s: неизвестный режим '%s' (допустимы pass, strip, identity)",
+	ConfigUnknownLabeledBackend:       "backend_labels: бэкенд '%s' не указан ни в одном пуле",
+	ConfigEmptyLabel:                  "%s: пустое имя метки",
+	ConfigUnknownLimitedBackend:       "backend_max_connections: бэкенд '%s' не указан ни в одном пуле",
+	ConfigBadMaxConnections:           "backend_max_connections: для бэкенда '%s' нужно положительное значение, получено %d",
+	ConfigBadMaxConnectionsPerIP:      "connection_limit.max_per_ip: неверное значение %d (ожидается неотрицательное число)",
+	ConfigBadVacuumPages:              "storage_maintenance.vacuum_pages: неверное значение %d (ожидается неотрицательное число)",
+	ConfigBadSQLiteJournalMode:        "sqlite.journal_mode: неизвестный режим '%s' (ожидается wal, delete, truncate, persist, memory или off)",
+	ConfigBadSQLiteSynchronous:        "sqlite.synchronous: неизвестное значение '%s' (ожидается off, normal, full или extra)",
+	ConfigBadSQLiteMaxOpenConns:       "sqlite.max_open_conns: неверное значение %d (ожидается неотрицательное число)",
+	ConfigBadSQLitePragma:             "sqlite.pragmas: неверная PRAGMA '%s' = '%s' (допустимы буквы, цифры, '_' и '-')",
+	ConfigSQLitePragmaReserved:        "sqlite.pragmas: '%s' задается отдельным параметром секции sqlite",
+	ConfigBadSaturationThreshold:      "saturation_threshold должен быть в диапазоне (0, 1], получено %v",
+	ConfigEmptyBackendURL:             "backend_servers[%d]: не указан url",
+	ConfigBadBackendWeight:            "backend_servers: вес бэкенда '%s' не может быть отрицательным, получено %d",
+	ConfigBadFlushInterval:            "routes[%d].flush_interval: неверное значение '%s' (ожидается длительность, например 100ms, или -1)",
+	ConfigBadContentType:              "%s: неверный тип содержимого '%s' (ожидается, например, application/json или text/*)",
+	ConfigBadHoldDown:                 "passive_health.hold_down: неверное значение '%s' (ожидается неотрицательная длительность, например 30s)",
+	ConfigBadMaxFailures:              "passive_health.max_failures не может быть отрицательным, получено %d",
+	ConfigBadFailureWindow:            "passive_health.failure_window: неверное значение '%s' (ожидается положительная длительность, например 10s)",
+	ConfigBadSlowStart:                "passive_health.slow_start: неверное значение '%s' (ожидается неотрицательная длительность, например 30s)",
+	ConfigSlowStartWithHash:           "%s: slow_start не поддерживается с load_balancing_algorithm consistent_hash",
+	ConfigBadBackendPassiveHealth:     "%s: %v",
+	ConfigBadStickyCookieName:         "sticky_sessions.cookie_name: недопустимое имя cookie '%s': %v",
+	ConfigBadStickyTTL:                "sticky_sessions.ttl: неверное значение '%s' (ожидается положительная длительность, например 1h)",
+	ConfigBadReadinessInFlightRatio:   "readiness.in_flight_ratio должен быть в диапазоне (0, 1], получено %v",
+	ConfigBadReadinessStoreTimeout:    "readiness.store_timeout: неверное значение '%s' (ожидается положительная длительность, например 1s)",
+	ConfigBadMetricsPath:              "metrics.path: неверное значение '%s' (ожидается путь, начинающийся с /, кроме / и служебных путей /admin/, /clients, /access, /api-keys, /readyz)",
+	ConfigAdminAuthMissing:            "%s: обязательное поле",
+	ConfigAdminAuthDuplicate:          "%s: повторяется в admin_auth",
+	ConfigUnknownAdminRole:            "%s: неизвестная роль '%s' (ожидается %s или %s)",
+	ConfigTracingBadEndpoint:          "tracing.endpoint: неверный адрес коллектора '%s' (ожидается URL http:// или https://, например http://otel-collector:4318/v1/traces)",
+	ConfigTracingBadSampleRatio:       "tracing.sample_ratio должен быть от 0 до 1, получено %g",
+	ConfigTracingBadQueue:             "tracing.batch_size (%d) должен быть не меньше 1, а queue_size (%d) - не меньше batch_size",
+	ConfigBadRequestMaxAge:            "request_age.max_age: неверное значение '%s' (ожидается положительная длительность, например 10s)",
+	ConfigHookSource:                  "admission_hook: задайте ровно одно из script и source",
+	ConfigBadStatsHistoryWindow:       "stats_history.window: неверное значение '%s' (ожидается длительность от 1s до %s)",
+	ConfigUnknownThrottlePolicy:       "неподдерживаемый %s: '%s'. Допустимые значения: '%s', '%s', '%s'",
+	ConfigThrottleWeightWithHash:      "%s: reduce_weight не поддерживается с load_balancing_algorithm consistent_hash",
+	ConfigUnknownPool:                 "%s: неизвестный пул '%s' (ожидается primary, spillover или tls:<первое имя сайта с backend_servers>)",
+	ConfigBackendTLSNoKeyPair:         "%s: cert_file и key_file задаются вместе",
+	ConfigBackendTLSSkipVerifyWithCA:  "%s: ca_file не используется при insecure_skip_verify",
+	ConfigUnknownUpstreamAuthType:     "%s.type: неизвестный способ '%s' (ожидается %s, %s или %s)",
+	ConfigUpstreamAuthMissing:         "%s: обязателен для type %s",
+	ConfigBadDNSServer:                "%s: неверный адрес DNS-сервера '%s' (ожидается IP[:порт])",
+	ConfigBadDNSDuration:              "%s: неверное значение '%s' (ожидается длительность, например 5s)",
+	ConfigCanaryWithHash:              "%s: канареечное разделение не поддерживается алгоритмом consistent_hash",
+	ConfigCanaryNoSelector:            "%s: укажите метки канареечных бэкендов",
+	ConfigBadCanaryPercent:            "%s: неверное значение %d (ожидается от 1 до 99)",
+	ConfigBadCanaryWindow:             "%s: неверное значение '%s' (ожидается длительность от 1s до %s)",
+	ConfigBadCanaryMinRequests:        "%s: неверное значение %d (ожидается неотрицательное число)",
+	ConfigBadCanaryErrorRateDelta:     "%s: неверное значение %v (ожидается от 0 до 1)",
+	ConfigBadCanaryLatencyRatio:       "%s: неверное значение %v (ожидается 0 или не меньше 1)",
+	ConfigCanaryNoThresholds:          "%s: для автоматического отката задайте max_error_rate_delta или max_latency_ratio",
+	ConfigBadThrottlePenalty:          "upstream_throttling.penalty: неверное значение '%s' (ожидается положительная длительность, например 30s)",
+	ConfigBadThrottleWeightPercent:    "upstream_throttling.weight_percent: неверное значение %d (ожидается от 1 до 99)",
+	ConfigTLSNoPort:                   "tls.port обязателен при tls.enabled",
+	ConfigTLSSamePort:                 "tls.port '%s' совпадает с портом HTTP-листенера",
+	ConfigTLSNoSites:                  "tls: нужен сертификат листенера (cert_file и key_file) или хотя бы один сайт в sites",
+	ConfigTLSNoServerNames:            "tls.sites[%d].server_names не может быть пустым",
+	ConfigTLSNoCertificate:            "tls.sites[%d]: cert_file и key_file обязательны",
+	ConfigTLSBadServerName:            "tls.sites[%d]: недопустимое имя сервера '%s'",
+	ConfigTLSDuplicateServerName:      "tls.sites[%d]: имя сервера '%s' указано более одного раза",
+	ConfigTLSNoDefaultCertificate:     "tls: cert_file и key_file задаются вместе",
+	ConfigTLSBadMinVersion:            "tls.min_version '%s' не поддерживается (допустимо: 1.2, 1.3)",
+	ConfigTLSCipherSuitesWithTLS13:    "tls.cipher_suites не применяются при min_version 1.3: наборы TLS 1.3 не настраиваются",
+	ConfigTLSBadCipherSuite:           "tls.cipher_suites: неизвестный или небезопасный набор шифров TLS 1.2 '%s'",
+	ConfigTLSBadClientAuthMode:        "tls.client_auth.mode '%s' не поддерживается (допустимо: none, optional, require)",
+	ConfigTLSClientAuthNoCA:           "tls.client_auth.ca_file обязателен для проверки клиентских сертификатов",
+	ConfigTLSSubjectHeaderWithoutAuth: "tls.client_auth.subject_header требует mode optional или require",
+	ConfigUDPNoListen:                 "udp.listen обязателен при udp.enabled",
+	ConfigUDPNoBackends:               "udp.backends должен содержать хотя бы один адрес",
+	ConfigUDPBadBackend:               "udp.backends[%d]: неверный адрес '%s': %v",
+	ConfigUDPBadMaxFails:              "udp.max_fails должен быть не меньше 1, получено %d",
+	ConfigGossipNoListen:              "rate_limiter.gossip.listen обязателен при заданных rate_limiter.gossip.peers",
+	ConfigGossipBadListen:             "rate_limiter.gossip.listen: неверный адрес '%s': %v",
+	ConfigGossipBadPeer:               "rate_limiter.gossip.peers[%d]: неверный адрес '%s': %v",
+	ConfigFPNoPort:                    "forward_proxy.port обязателен при forward_proxy.enabled",
+	ConfigFPSamePort:                  "forward_proxy.port '%s' совпадает с портом HTTP-листенера",
+	ConfigBadBindAddress:              "%s: неверный адрес '%s' (ожидается IP-адрес, например 127.0.0.1 или ::1)",
+	ConfigBadAccessEntry:              "%s: неверная запись списка доступа '%s': %v",
+	ConfigEmptyAccessEntry:            "ожидается IP-адрес, подсеть CIDR или ID клиента без пробелов",
+	ConfigFPNoGateways:                "forward_proxy.gateways должен содержать хотя бы один шлюз",
+	ConfigConcurrencyBadMaxInFlight:   "concurrency.max_in_flight должен быть не меньше 1, получено %d",
+	ConfigConcurrencyBadMaxQueue:      "concurrency.max_queue не может быть отрицательным, получено %d",
+	ConfigConcurrencyBadPriority:      "concurrency.default_priority должен быть от 0 до 9, получено %d",
+	ConfigBadPriorityTrusted:          "concurrency.priority_trusted[%d]: '%s' не является IP-адресом или подсетью CIDR",
+	ConfigSpilloverNoBackends:         "spillover.backend_servers должен содержать хотя бы один бэкенд",
+	ConfigSpilloverNegativeBudget:     "spillover.primary_max_in_flight и spillover.primary_max_rps не могут быть отрицательными, получено %d и %v",
+	ConfigSpilloverNoBudget:           "spillover: задайте primary_max_in_flight и/или primary_max_rps, иначе резервный пул не используется",
+	ConfigCaptureBadBufferSize:        "capture.buffer_size должен быть не меньше 1, получено %d",
+	ConfigCaptureBadMaxBody:           "capture.max_body_bytes не может быть отрицательным, получено %d",
+	ConfigAccessLogBadSink:            "неподдерживаемый access_log.sink: '%s'. Допустимые значения: '%s', '%s', '%s', '%s'",
+	ConfigAccessLogBadAddress:         "access_log.address для приемника %s: неверный адрес '%s' (syslog: udp://host:port или tcp://host:port, http и kafka: URL, file: путь или stdout)",
+	ConfigAccessLogNoTopic:            "access_log.topic обязателен для приемника kafka",
+	ConfigAccessLogBadBatchSize:       "access_log.batch_size должен быть не меньше 1, получено %d",
+	ConfigAccessLogBadQueueSize:       "access_log.queue_size (%d) должен быть не меньше batch_size (%d)",
+	ConfigAccessLogBadRetries:         "access_log.max_retries не может быть отрицательным, получено %d",
+	ConfigAccessLogBadFormat:          "неподдерживаемый access_log.format: '%s'. Допустимые значения: '%s', '%s'",
+	ConfigAccessLogBadMaxSize:         "access_log.max_size_mb не может быть отрицательным, получено %d",
+	ConfigAccessLogBadMaxBackups:      "access_log.max_backups должен быть не меньше 1 при ротации (max_size_mb > 0), получено %d",
+
+	// Списки доступа (internal/access)
+	AccessStoreUnavailable: "хранилище списков доступа не подключено (нужен rate_limiter с database_path)",
+	AccessLoaded:           "[Access] Списки доступа загружены: записей из конфигурации %d, из хранилища %d",
+	AccessPurgeFailed:      "[Access] Не удалось удалить истекшие записи из хранилища: %v",
+
+	// Журнал доступа (internal/accesslog)
+	AccessLogStarted:    "[AccessLog] Отправка журнала доступа: приемник %s (%s), пачки до %d записей каждые %v, очередь %d записей",
+	AccessLogDropped:    "[AccessLog] Отброшено записей журнала доступа: %d (очередь на %d записей переполнена, приемник не успевает)",
+	AccessLogSendFailed: "[AccessLog] Ошибка отправки пачки (попытка %d из %d повторов): %v, повтор через %v",
+	AccessLogBatchLost:  "[AccessLog] Пачка из %d записей потеряна: %v",
+	AccessLogBadStatus:  "приемник ответил %s",
+	AccessLogRotated:    "[AccessLog] Файл журнала доступа %s ротирован, хранится ротированных файлов: до %d",
+	TracingStarted:      "[Tracing] Отправка трасс OpenTelemetry: коллектор %s, service.name %s, доля записываемых трасс %g",
+	TracingDropped:      "[Warning] [Tracing] Отброшено span: %d (очередь на %d span переполнена, коллектор не успевает)",
+	TracingExportFailed: "[Warning] [Tracing] Пачка из %d span не отправлена в коллектор: %v",
+	TracingBadStatus:    "коллектор ответил %s",
+
+	// Ограничение одновременных запросов (internal/admission)
+	AdmissionEnabled:      "[Admission] Ограничение одновременных запросов: max_in_flight=%d, max_queue=%d, queue_timeout=%v, заголовок приоритета: %s",
+	AdmissionQueueFull:    "очередь ожидания заполнена",
+	AdmissionQueueTimeout: "истекло время ожидания в очереди",
+	ConnLimitEnabled:      "[ConnLimit] Ограничение соединений с одного IP-адреса на %s: %d",
+	ConnLimitRejected:     "[ConnLimit] Соединение с %s отклонено: открыто %d соединений (connection_limit.max_per_ip)",
+
+	// Алертинг (internal/alerting)
+	AlertsStarted:         "[Alerts] Мониторинг запущен: 429 > %.1f%% за %v (мин. %d запросов), все бэкенды недоступны > %v, webhook: '%s'",
+	AlertsRejectionRate:   "Доля отклоненных (429) запросов %.1f%% за %v (запросов: %d)",
+	AlertsAllBackendsDown: "Все бэкенды (%d) недоступны в течение %v",
+	AlertsEvent:           "[Alerts] %s [%s]: %s (значение=%.4f, порог=%.4f)",
+	AlertsMarshalFailed:   "[Alerts] Ошибка маршалинга события: %v",
+	AlertsWebhookFailed:   "[Alerts] Ошибка отправки события на webhook '%s': %v",
+	AlertsWebhookStatus:   "[Alerts] Webhook '%s' вернул статус %d",
+
+	// API управления лимитами (internal/api)
+	APINoStore:                    "[API] Warning: Хранилище (Store) не предоставлено APIHandler. CRUD операции не будут работать.",
+	APIStoreUnavailable:           "Хранилище лимитов недоступно",
+	APIDebugPath:                  "[API] Debug: Path after StripPrefix and Trim: '%s' (Original r.URL.Path: '%s')",
+	APIListNotImplemented:         "Получение списка всех клиентов не реализовано",
+	APIBadListLimit:               "Неверный limit '%s': ожидается целое число от 1 до %d",
+	APIBadListOffset:              "Неверный offset '%s': ожидается неотрицательное целое число",
+	APIListFailed:                 "[API] Ошибка при чтении списка клиентов: %v",
+	APIListInternal:               "Внутренняя ошибка сервера при чтении списка клиентов",
+	APIMethodNotAllowedCollection: "Метод %s не поддерживается для /clients",
+	APIMethodNotAllowedItem:       "Метод %s не поддерживается для /clients/{id}",
+	APIInvalidJSON:                "Ошибка парсинга JSON: %v",
+	APIClientIDRequired:           "Поле client_id обязательно",
+	APINonPositiveLimit:           "Значения rate и capacity должны быть положительными",
+	APIPatchEmpty:                 "Укажите rate_per_sec и/или capacity для изменения",
+	APIClientExists:               "Клиент с ID '%s' уже существует",
+	APICreateFailed:               "[API] Ошибка при создании клиента '%s': %v",
+	APICreateInternal:             "Внутренняя ошибка сервера при создании клиента",
+	APIGetFailed:                  "Ошибка получения лимита из БД: %v",
+	APIClientNotFound:             "Клиент с ID '%s' не найден",
+	APIClientIDMismatch:           "client_id в теле запроса не совпадает с ID в пути",
+	APIUpdateNotFound:             "Клиент с ID '%s' не найден для обновления",
+	APIUpdateFailed:               "[API] Ошибка при обновлении клиента '%s': %v",
+	APIUpdateInternal:             "Внутренняя ошибка сервера при обновлении клиента",
+	APIDeleteNotFound:             "Клиент с ID '%s' не найден для удаления",
+	APIDeleteFailed:               "[API] Ошибка при удалении клиента '%s': %v",
+	APIDeleteInternal:             "Внутренняя ошибка сервера при удалении клиента",
+	APIResetNotSupported:          "Сброс корзин недоступен: Rate Limiter не подключен к API",
+	APIResetDisabled:              "Rate Limiter выключен: корзин для сброса нет",
+	APIResetFailed:                "[API] Ошибка при сбросе корзины клиента '%s': %v",
+	APIResetInternal:              "Внутренняя ошибка сервера при сбросе корзины клиента",
+	APIStateNotSupported:          "Состояние корзин недоступно: Rate Limiter не подключен к API",
+	APIStateDisabled:              "Rate Limiter выключен: корзин нет",
+	APIStateFailed:                "[API] Ошибка при чтении состояния корзины клиента '%s': %v",
+	OpenAPITitle:                  "API управления load-balancer",
+	OpenAPIListClients:            "Список клиентов с индивидуальными лимитами",
+	OpenAPICreateClient:           "Создать лимит клиента",
+	OpenAPIGetClient:              "Получить лимит клиента",
+	OpenAPIUpdateClient:           "Заменить лимит клиента",
+	OpenAPIPatchClient:            "Изменить часть лимита клиента",
+	OpenAPIDeleteClient:           "Удалить лимит клиента",
+	OpenAPIResetClient:            "Заполнить корзину клиента до емкости",
+	OpenAPIClientState:            "Текущее состояние корзины клиента",
+	OpenAPIListAccess:             "Записи списков доступа",
+	OpenAPIPutAccess:              "Добавить или заменить запись списка доступа",
+	OpenAPIGetAccess:              "Получить запись списка доступа",
+	OpenAPIDeleteAccess:           "Удалить запись списка доступа",
+	OpenAPIListAPIKeys:            "Список API-ключей",
+	OpenAPICreateAPIKey:           "Выдать API-ключ",
+	OpenAPIDeleteAPIKey:           "Отозвать API-ключ",
+	APITrialIncomplete:            "Для пробного периода нужны оба поля: trial_until и downgrade_to",
+	APITrialInPast:                "trial_until должен быть в будущем",
+	APITrialsNotSupported:         "Хранилище не поддерживает пробные периоды",
+	APIUnknownPlan:                "План '%s' не найден в rate_limiter.templates",
+	APIAdminNotFound:              "Неизвестный путь %s",
+	APIAdminMethodNotAllowed:      "Метод %s не поддерживается для %s",
+	APIHealthCheckFailed:          "Ошибка принудительной проверки: %v",
+	APICaptureDisabled:            "Запись запросов недоступна",
+	APICaptureEmptyFilter:         "Укажите client_id и/или path_prefix: запись всего трафика не поддерживается",
+	APIStateSaveUnavailable:       "Сохранение состояния недоступно: Rate Limiter не инициализирован",
+	APIStateSaveFailed:            "[API] Ошибка при сохранении состояния: %v",
+	APIStateSaveInternal:          "Внутренняя ошибка сервера при сохранении состояния",
+	APIMaintenanceUnavailable:     "Обслуживание базы недоступно: хранилище лимитов не подключено",
+	APIMaintenanceFailed:          "[API] Ошибка при обслуживании базы: %v",
+	APIMaintenanceInternal:        "Внутренняя ошибка сервера при обслуживании базы",
+	APIReloadUnavailable:          "Перечитывание конфигурации недоступно",
+	APIBadTimeSeriesSeconds:       "Неверное значение seconds '%s': ожидается положительное целое число",
+	APIPoolNotFound:               "Пул '%s' не найден",
+	APICanaryNotConfigured:        "Для пула '%s' не настроено канареечное разделение (canary)",
+	APIBackendBadLimits:           "weight и max_connections не могут быть отрицательными: weight=%d, max_connections=%d",
+	APINoReloadYet:                "Конфигурация еще не перечитывалась (SIGHUP)",
+	APIAccessStoreUnavailable:     "Хранилище списков доступа недоступно: записи можно задать только в access_list конфигурации",
+	APIAccessEntryNotFound:        "Запись '%s' не найдена в списках доступа",
+	APIBadAccessValue:             "Неверное значение '%s': %v",
+	APIBadAccessList:              "Неверный список '%s': ожидается deny или allow",
+	APIBadAccessTTL:               "Неверный ttl '%s': ожидается положительная длительность, например 1h",
+	APIAccessPutFailed:            "[API] Ошибка при сохранении записи списка доступа '%s': %v",
+	APIAccessDeleteFailed:         "[API] Ошибка при удалении записи списка доступа '%s': %v",
+	APIAccessInternal:             "Внутренняя ошибка сервера при изменении списка доступа",
+	APIAPIKeyNotFound:             "API-ключ '%s' не найден",
+	APIAPIKeyPlansNotSupported:    "Хранилище не поддерживает лимиты клиентов: ключ с планом выдать нельзя",
+	APIAPIKeyCreateFailed:         "[API] Ошибка при выдаче API-ключа клиенту '%s': %v",
+	APIAPIKeyListFailed:           "[API] Ошибка при чтении API-ключей: %v",
+	APIAPIKeyDeleteFailed:         "[API] Ошибка при отзыве API-ключа '%s': %v",
+	APIAPIKeyInternal:             "Внутренняя ошибка сервера при работе с API-ключами",
+	APIUnauthorized:               "Требуется аутентификация",
+	APIForbidden:                  "Пользователю '%s' запрещен метод %s: нужна роль admin",
+	APIAuthFailed:                 "[Warning] [API] Отклонен запрос %s %s от %s: нет учетных данных или они неверны",
+	APIAuthForbidden:              "[Warning] [API] Пользователю '%s' запрещен запрос %s %s",
+	APILogLevelChanged:            "[API] Уровень логов изменен: %s -> %s",
+	APINotReady:                   "[Warning] [API] Экземпляр не готов принимать трафик (/readyz отвечает 503): %v",
+	APIReadyAgain:                 "[API] Экземпляр снова готов принимать трафик",
+
+	// Балансировщик и health checks (internal/balancer)
+	BalancerNoHealthyBackends:      "нет доступных бэкендов",
+	BalancerBackendsSaturated:      "все доступные бэкенды достигли backend_max_connections",
+	BalancerBackendStatus:          "[HealthCheck] Бэкенд %s теперь %s",
+	BalancerBackendDrained:         "[Balancer] Бэкенд %d (%s) выведен из балансировки вручную, запросов в обработке: %d",
+	BalancerBackendEnabled:         "[Balancer] Бэкенд %d (%s) возвращен в балансировку",
+	BalancerBackendRegistered:      "[Balancer] Бэкенд %d (%s) добавлен в пул во время работы: вес %d, метки %v",
+	BalancerBackendRemoved:         "[Balancer] Бэкенд %d (%s) удален из пула, запросов в обработке: %d",
+	BalancerBackendExists:          "бэкенд уже есть в пуле",
+	BalancerBackendExistsWrap:      "%w: '%s'",
+	BalancerLastBackend:            "нельзя удалить последний бэкенд пула",
+	BalancerHealthRestored:         "[HealthCheck] Бэкенд %d (%s) был недоступен до перезапуска: исключен до первой успешной проверки",
+	BalancerBackendUp:              "доступен",
+	BalancerBackendDown:            "недоступен",
+	BalancerNoBackends:             "не указаны бэкенд-серверы",
+	BalancerUnknownAlgorithm:       "[Warning] Неизвестный алгоритм балансировки '%s', используется 'round_robin'",
+	BalancerRNGInit:                "[Balancer] Инициализирован генератор случайных чисел для Random алгоритма.",
+	BalancerBadBackendURL:          "ошибка парсинга URL бэкенда #%d ('%s'): %w",
+	BalancerRelativeBackendURL:     "URL бэкенда #%d ('%s') должен быть абсолютным (например, 'http://host:port')",
+	BalancerProxyFailed:            "[Balancer] Ошибка проксирования на Бэкенд #%d (%s) для запроса от '%s' (RequestID: %s): %v. Помечаем как нерабочий.",
+	BalancerRequestHeaders:         "[Balancer] Заголовки запроса: %v",
+	BalancerBadGateway:             "Bad Gateway from Custom Handler",
+	BalancerBackendTimeout:         "бэкенд не начал отвечать за отведенное время",
+	BalancerGatewayTimeout:         "Бэкенд не ответил вовремя",
+	BalancerAdmissionRejected:      "[Balancer] Запрос клиента %s отклонен ограничением одновременных запросов: %v",
+	BalancerOverloaded:             "Балансировщик перегружен, повторите запрос позже",
+	BalancerRequestExpired:         "[Balancer] Запрос клиента %s отброшен: провел в балансировщике %v (request_age.max_age %v)",
+	BalancerRequestTooOld:          "Запрос слишком долго ожидал обработки, повторите его позже",
+	BalancerHookFailed:             "[Balancer] Ошибка сценария admission_hook для клиента %s: %v (запрос обрабатывается без решения сценария)",
+	BalancerHookRejected:           "[Balancer] Сценарий admission_hook отклонил запрос клиента %s: %d %s",
+	BalancerHookDenied:             "Запрос отклонен политикой допуска",
+	BalancerHookPool:               "[Balancer] Сценарий admission_hook направил запрос клиента %s в пул %s: бэкенд %d (%s)",
+	BalancerHookUnknownPool:        "[Balancer] Сценарий admission_hook выбрал неизвестный пул '%s' для клиента %s: запрос обрабатывается текущим пулом",
+	BalancerAccessDenied:           "[Balancer] Запрос клиента %s отклонен списком запрета",
+	BalancerForbidden:              "Доступ запрещен",
+	BalancerBadContentType:         "недопустимый Content-Type ответа бэкенда: '%s'",
+	BalancerEmptyBody:              "пустое тело ответа бэкенда",
+	BalancerBackendHeldDown:        "[Balancer] Бэкенд %s исключен из балансировки до %s после ошибки",
+	BalancerBackendPassiveHealth:   "[Balancer] Бэкенд #%d (%s): пассивная проверка max_failures=%d, failure_window=%v, hold_down=%v, slow_start=%v",
+	BalancerBackendFailureCounted:  "[Balancer] Ошибка бэкенда %s учтена: %d из %d за %v",
+	BalancerClientDisconnected:     "[Balancer] Клиент закрыл соединение до ответа: %s %s (RequestID: %s), запрос к бэкенду отменен",
+	BalancerSpilloverEnabled:       "[Balancer] Резервный пул: %d бэкендов, бюджет основного пула: max_in_flight=%d, max_rps=%v (0 - без ограничения)",
+	BalancerSpillover:              "[Balancer] Бюджет основного пула исчерпан, запрос клиента %s переливается на резервный бэкенд %d (%s)",
+	BalancerGRPCEnabled:            "[Balancer] Режим gRPC включен: вызовы application/grpc проксируются по HTTP/2 на %d бэкендов",
+	BalancerGRPCUpstreamStatus:     "бэкенд вернул HTTP %d вместо ответа gRPC",
+	BalancerGRPCUpstreamMapped:     "[Balancer] gRPC-вызов %s: ответ бэкенда HTTP %d преобразован в grpc-status %d",
+	BalancerBackendAdded:           "[Config] Бэкенд #%d добавлен: %s",
+	BalancerHealthChecksStarted:    "[Balancer] Health Checks запущены.",
+	BalancerHealthChecksStopping:   "[Balancer] Остановка Health Checks...",
+	BalancerRequestReceived:        "[Request] Получен запрос: Метод=%s Путь=%s От=%s (%s) RequestID=%s",
+	BalancerStoreUnavailable:       "Rate limiter store unavailable",
+	BalancerRateLimited:            "Rate limit exceeded",
+	BalancerTooManyInFlight:        "Слишком много одновременных запросов",
+	BalancerSelectFailed:           "[Balancer] Ошибка выбора бэкенда (%s): %v. Невозможно обработать запрос %s %s от '%s'.",
+	BalancerAllBackendsDown:        "All backend servers are unavailable",
+	BalancerForwarding:             "[Balancer] Перенаправление запроса (%s) от '%s' -> Бэкенд #%d (%s)",
+	BalancerDirector:               "[Balancer] Запрос '%s' отправлен на бэкенд #%d (%s)",
+	BalancerRoutesLoaded:           "[Balancer] Загружено маршрутов: %d",
+	BalancerBackendLabels:          "[Balancer] Бэкенд %d (%s): метки %v",
+	BalancerBackendMaxConnections:  "[Balancer] Бэкенд %d (%s): не более %d одновременных запросов",
+	BalancerBackendWeight:          "[Balancer] Бэкенд %d (%s): вес %d",
+	BalancerThrottlePolicy:         "[Balancer] Реакция на ответы 429 и 503: %s (бэкендов: %d)",
+	BalancerUpstreamAuth:           "[Balancer] Учетные данные для бэкендов: %s (бэкендов: %d)",
+	BalancerDNS:                    "[Balancer] Имена бэкендов пула разрешаются через %s: таймаут %v, кэш адресов %v",
+	BalancerBackendTLSInsecure:     "[Warning] [Balancer] Сертификаты https:// бэкендов пула не проверяются (backend_tls.insecure_skip_verify)",
+	BalancerDNSSystem:              "системные DNS-серверы",
+	BalancerThrottleWeightReduced:  "[Balancer] Бэкенд %s ответил %d: вес снижен до %d%% до %s",
+	BalancerThrottleRetry:          "[Balancer] Бэкенд %d (%s) ответил %d, запрос повторяется на бэкенде %d (%s)",
+	BalancerThrottleNoRetry:        "[Warning] [Balancer] Бэкенд %d (%s) ответил %d, другого доступного бэкенда для повтора нет",
+	BalancerCanary:                 "[Balancer] Канареечное разделение: %d%% запросов на бэкенды %v (канареечных: %d, стабильных: %d)",
+	BalancerCanaryNoBackends:       "[Warning] [Balancer] canary: ни один бэкенд пула не подходит под селектор %v, разделение не применяется",
+	BalancerCanaryErrorRate:        "доля ошибок канарейки %.3f превышает долю ошибок стабильных бэкендов %.3f более чем на %.3f",
+	BalancerCanaryLatency:          "среднее время ответа канарейки %.1f мс превышает стабильное %.1f мс более чем в %.2f раза",
+	BalancerCanaryRolledBack:       "[Warning] [Balancer] Канарейка откачена, запросы направляются только стабильным бэкендам: %s",
+	BalancerCanaryResumed:          "[Balancer] Канареечное разделение возобновлено: %d%% запросов на бэкенды %v",
+	BalancerBackendReresolved:      "[Balancer] Бэкенд %s: адреса хоста после повторного разрешения DNS %v (были %v), соединения пересоздаются",
+	HealthCheckStarting:            "[HealthCheck] Запуск проверок состояния: Интервал=%v, Таймаут=%v, Путь=%s, Воркеров=%d, Макс. backoff=%v",
+	HealthCheckStaggered:           "[HealthCheck] Первые проверки %d бэкендов распределены по интервалу %v",
+	HealthCheckStopSignal:          "[HealthCheck] Получен сигнал остановки проверок.",
+	HealthCheckFailed:              "[HealthCheck] %v (неудач подряд: %d, следующая проверка через %v)",
+	HealthCheckFailedRepeated:      "[HealthCheck] %v (повторилось %d раз с прошлой записи, неудач подряд: %d, следующая проверка через %v)",
+	HealthCheckRecovered:           "[HealthCheck] Бэкенд %s прошел проверку после %d неудач подряд",
+	HealthCheckCycle:               "[HealthCheck] Выполнение цикла проверок (бэкендов в очереди: %d)...",
+	HealthCheckRequestFailed:       "ошибка создания запроса для %s: %w",
+	HealthCheckUnreachable:         "ошибка проверки бэкенда %s: %w",
+	HealthCheckBadStatus:           "бэкенд %s вернул не-2xx статус: %d",
+	HealthCheckUnexpectedStatus:    "бэкенд %s вернул статус %d, ожидается один из %v",
+	HealthCheckBodyMismatch:        "ответ бэкенда %s не содержит '%s'",
+	HealthCheckBadJSON:             "ответ бэкенда %s не является JSON: %v",
+	HealthCheckJSONMismatch:        "ответ бэкенда %s: поле %s = %s, ожидается %s",
+	HealthCheckJSONMissing:         "в ответе бэкенда %s нет поля %s",
+	HealthCheckDialFailed:          "бэкенд %s не принимает TCP-соединения: %w",
+	HealthCheckGRPCStatus:          "проверка gRPC бэкенда %s завершилась с grpc-status %s: %s",
+	HealthCheckGRPCBadResponse:     "неверный ответ проверки gRPC бэкенда %s: %w",
+	HealthCheckGRPCNotServing:      "бэкенд %s сообщает статус %s для сервиса '%s' (ожидается SERVING)",
+	HealthCheckGRPCNoStatus:        "нет grpc-status",
+	HealthCheckGRPCBadFrame:        "неверный кадр сообщения gRPC",
+	HealthCheckGRPCBadMessage:      "неверное сообщение HealthCheckResponse",
+	HealthCheckDisabled:            "health checks выключены",
+	HealthCheckBackendNotFound:     "бэкенд не найден",
+	HealthCheckBackendNotFoundWrap: "%w: '%s'",
+	HealthCheckForced:              "[HealthCheck] Принудительная проверка: работоспособно %d из %d",
+	HealthCheckInvalidConfig:       "интервал и таймаут проверок должны быть положительными (интервал=%v, таймаут=%v)",
+	HealthCheckWorkersNotReloaded:  "[HealthCheck] Размер пула воркеров не меняется без перезапуска: остается %d (запрошено %d)",
+	HealthCheckReloaded:            "[HealthCheck] Параметры проверок обновлены: Интервал=%v, Таймаут=%v, Путь=%s, Макс. backoff=%v",
+
+	// Запись запросов для отладки (internal/capture)
+	CaptureStarted: "[Capture] Запись запросов включена: client_id=%q, path_prefix=%q, буфер %d записей, тело до %d байт",
+	CaptureStopped: "[Capture] Запись запросов выключена, в буфере %d записей",
+
+	// Прямой прокси для исходящего трафика (internal/forwardproxy)
+	FPInitialized:       "[ForwardProxy] Прямой прокси инициализирован (шлюзов: %d, dial_timeout: %v)",
+	FPRequest:           "[ForwardProxy] %s %s (client: %s) через шлюз %d (%s)",
+	FPNotProxyRequest:   "Ожидается CONNECT или запрос с абсолютным URL",
+	FPGatewayFailed:     "[ForwardProxy] Ошибка шлюза %s: %v",
+	FPGatewayStatus:     "[ForwardProxy] Шлюз %s отказал в туннеле к %s: %s",
+	FPGatewayRefused:    "Шлюз отказал в туннеле к %s",
+	FPHijackUnsupported: "Сервер не поддерживает туннелирование соединений",
+	FPHijackFailed:      "[ForwardProxy] Не удалось перехватить соединение клиента: %v",
+
+	// Rate Limiter (internal/ratelimiter)
+	RLStoreUnavailable:      "хранилище лимитов недоступно",
+	RLStoreTimeout:          "превышен таймаут обращения к хранилищу",
+	RLDisabled:              "[RateLimiter] Выключен.",
+	RLNoStore:               "[Warning][RateLimiter] Rate limiter включен, но хранилище (store) не предоставлено. Будут использоваться только дефолтные лимиты.",
+	RLInitialized:           "[RateLimiter] Инициализирован (Store: %T). Default Rate=%.2f/sec, Default Capacity=%.2f (IP: %.2f/%.2f, заголовок: %.2f/%.2f)",
+	RLIdentifyByHeader:      ". Идентификация клиента по заголовку: '%s' (fallback на IP)",
+	RLKeyTemplate:           ". Ключ корзины: '%s'",
+	RLIdentifyByIP:          ". Идентификация клиента по IP-адресу.",
+	RLIdentifyByAPIKey:      ". Идентификация клиента по API-ключу (Authorization: Bearer или '%s'), без ключа - по IP-адресу.",
+	RLIdentifyByFingerprint: ". Клиенты HTTPS без заголовка идентифицируются по отпечатку TLS (JA3).",
+	RLFailurePolicy:         ". Политика при ошибках хранилища: %s",
+	RLAlgorithm:             ". Алгоритм: %s",
+	RLGlobalLimit:           ". Общий лимит: rate=%.2f, capacity=%.2f",
+	RLRefillerStarted:       "[RateLimiter] Запущено фоновое пополнение корзин (каждую секунду).",
+	RLRefillerStopped:       "[RateLimiter] Фоновое пополнение остановлено.",
+	RLBucketsEvicted:        "[RateLimiter] Удалено простаивающих корзин: %d",
+	RLBucketsOverCap:        "[Warning] [RateLimiter] Корзин в памяти больше rate_limiter.max_buckets (%d): удалено давно не использовавшихся - %d",
+	RLGossipStarted:         "[RateLimiter] Обмен расходом токенов: прием на %s, пиров %d, интервал %v",
+	RLGossipSendFailed:      "[Warning] [RateLimiter] Не удалось отправить расход токенов пиру %s: %v",
+	RLGossipBadMessage:      "[Warning] [RateLimiter] Некорректное сообщение обмена от %s: %v",
+	RLGossipReadFailed:      "[Warning] [RateLimiter] Ошибка чтения сообщения обмена: %v",
+	RLGossipUnknownPeer:     "[Warning] [RateLimiter] Сообщение обмена с адреса %s не из rate_limiter.gossip.peers отброшено",
+	RLGossipBadSignature:    "[Warning] [RateLimiter] Сообщение обмена от %s с неверной подписью отброшено (проверьте rate_limiter.gossip.secret)",
+	RLGossipStaleMessage:    "[Warning] [RateLimiter] Сообщение обмена от %s отброшено: отправлено %v назад",
+	RLGossipReplayedMessage: "[Warning] [RateLimiter] Сообщение обмена от %s отброшено: номер %d уже принят",
+	RLGossipKeyTooLong:      "[Warning] [RateLimiter] Расход корзины с ключом длиной %d не рассылается: ключ не помещается в датаграмму обмена",
+	RLGossipListenFailed:    "не удалось открыть rate_limiter.gossip.listen %s: %w",
+	RLSourceIPDefaults:      "дефолтными для IP",
+	RLSourceHeaderDefaults:  "дефолтными для заголовка",
+	RLSourceKeyDefaults:     "дефолтными для составного ключа",
+	RLSourceStore:           "хранилища",
+	RLSourceNotInStore:      " (не найден в хранилище)",
+	RLSourceRecheck:         " (повторная проверка)",
+	RLStateInitial:          "начальное (полная корзина, время=0)",
+	RLStateSaved:            "сохраненное из БД",
+	RLStateNotInDB:          "начальное (не найдено в БД)",
+	RLLimitsUpdated:         "[RateLimiter] Обновление лимитов для '%s' (источник: %s): Rate: %.2f -> %.2f, Capacity: %.2f -> %.2f",
+	RLLimitsRollout:         "[RateLimiter] Плавное снижение лимитов для '%s' (источник: %s) за %v: Rate: %.2f -> %.2f, Capacity: %.2f -> %.2f",
+	RLExistingConfigFailed:  "[RateLimiter] Ошибка получения конфига лимита для существующего клиента '%s'%s, используются текущие. Ошибка: %v",
+	RLNewConfigFailed:       "[RateLimiter] Ошибка получения конфига лимита для нового клиента '%s', используются дефолтные. Ошибка: %v",
+	RLNotStateStore:         "[Error][RateLimiter] Store (%T) сообщает о поддержке состояния, но не реализует StateStore!",
+	RLNewStateFailed:        "[RateLimiter] Ошибка получения сохраненного состояния для нового клиента '%s', используется начальное. Ошибка: %v",
+	RLNoStateSupport:        "[RateLimiter] Хранилище (%T) не поддерживает сохранение состояния для '%s'. Используется начальное.",
+	RLBucketCreating:        "[RateLimiter] Создается новая корзина для клиента '%s'. Конфиг: %s (Rate=%.2f, Capacity=%.2f). Состояние: %s (Tokens=%.2f, LastRefill=%v)",
+	RLBucketCreated:         "[RateLimiter] Корзина для '%s' создана и инициализирована. Текущее состояние: Tokens=%.2f, LastRefill=%v",
+	RLRejectedStoreError:    "[RateLimiter] Запрос от '%s' отклонен: %v",
+	RLCheck:                 "[RateLimiter] Проверка для '%s': %.2f токенов доступно (лимиты: rate=%.2f, capacity=%.2f)",
+	RLRejected:              "[RateLimiter] Запрос от '%s' отклонен (лимит превышен)",
+	RLInFlightRejected:      "[RateLimiter] Запрос от '%s' отклонен: уже %d одновременных запросов (max_in_flight_per_client)",
+	RLGlobalRejected:        "[RateLimiter] Запрос от '%s' отклонен общим лимитом rate_limiter.global",
+	RLSoftLimitExceeded:     "[RateLimiter] Клиент '%s' превысил мягкий порог: осталось %.2f из %.0f токенов",
+	RLClientIDUnknown:       "[Warning] Не удалось определить ID клиента (заголовок: '%s', XFF: '%s', RemoteAddr: '%s'). Используется RemoteAddr.",
+	RLAPIKeysNoStore:        "[Warning][RateLimiter] rate_limiter.api_keys включен, но хранилище не поддерживает API-ключи. Клиенты идентифицируются по IP-адресу.",
+	RLAPIKeyLookupFailed:    "[Warning][RateLimiter] Ошибка поиска API-ключа, клиент идентифицирован по IP-адресу: %v",
+	RLAPIKeyUnknown:         "[RateLimiter] Неизвестный API-ключ, клиент идентифицирован по IP-адресу",
+	RLSaveSkipped:           "[RateLimiter] Сохранение состояния не выполнено. Enabled: %t, Store: %s, SupportsState: %t",
+	RLSaveNotStateStore:     "[Error][RateLimiter] Store (%T) сообщает о поддержке состояния, но не реализует StateStore! Сохранение невозможно.",
+	RLNotStateStoreErr:      "store %T не реализует StateStore",
+	RLSavePreparing:         "[RateLimiter] Подготовка к сохранению состояния %d корзин...",
+	RLSaveNothing:           "[RateLimiter] Нет активных корзин для сохранения.",
+	RLSaving:                "[RateLimiter] Сохранение состояния %d корзин в хранилище (%T)...",
+	RLBatchUpdateFailed:     "[Error][RateLimiter] Ошибка при массовом обновлении состояния корзин: %v",
+	RLSaveFailed:            "ошибка сохранения состояния RateLimiter: %w",
+	RLSaved:                 "[RateLimiter] Состояние %d корзин успешно сохранено.",
+	RLDisabledErr:           "Rate Limiter выключен",
+	RLBucketReset:           "[RateLimiter] Корзина '%s' заполнена до емкости %.2f",
+	RLResetSaveFailed:       "не удалось сохранить сброс корзины '%s': %w",
+	RLImportNoStore:         "хранилище не задано, невозможно импортировать лимиты %d клиентов",
+	RLImportReadFailed:      "ошибка чтения лимита клиента '%s' при импорте: %w",
+	RLImportCreateFailed:    "ошибка создания лимита клиента '%s' при импорте: %w",
+	RLImportUpdateFailed:    "ошибка обновления лимита клиента '%s' при импорте: %w",
+	RLImported:              "[RateLimiter] Импорт лимитов из конфигурации: клиентов=%d, создано=%d, обновлено=%d",
+	RLTrialSweepFailed:      "[RateLimiter] Ошибка проверки закончившихся пробных периодов: %v",
+	RLTrialUnknownPlan:      "[RateLimiter] Пробный период клиента '%s' закончился, но плана '%s' нет в rate_limiter.templates: клиент остается на пробных лимитах",
+	RLTrialExpired:          "[RateLimiter] Пробный период клиента '%s' закончился: клиент переведен на план '%s' (Rate=%.2f, Capacity=%.2f)",
+
+	// JSON-ответы (internal/response)
+	ResponseError:         "[Error] Status: %d, ErrorCode: %s, RequestID: %s, Message: %s",
+	ResponseGRPCError:     "[Error] gRPC status: %d, ErrorCode: %s, RequestID: %s, Message: %s",
+	ResponseMarshalFailed: "[Error] Ошибка маршалинга JSON-ответа: %v",
+	ResponseWriteFailed:   "[Error] Ошибка записи JSON-ответа клиенту: %v",
+
+	// Симуляция трафика (internal/simulate)
+	SimUsage:                 "Использование: balancer simulate [-config config.yaml] -scenario traffic.yaml [-json]",
+	SimScenarioReadFailed:    "не удалось прочитать сценарий '%s': %v",
+	SimScenarioParseFailed:   "не удалось разобрать сценарий '%s': %v",
+	SimBadDuration:           "сценарий: неверное значение %s '%s' (ожидается положительная длительность)",
+	SimStepTooLong:           "сценарий: шаг '%s' больше длительности '%s'",
+	SimBadCapacity:           "сценарий: backend_capacity_rps не может быть отрицательным, получено %v",
+	SimNoClients:             "сценарий: не задан ни один клиент (clients)",
+	SimBadClient:             "сценарий: clients[%d]: нужны непустой id, положительный rps и неотрицательный count",
+	SimFailed:                "Симуляция не выполнена: %v",
+	SimReportSummary:         "Симуляция: %s, алгоритм %s, Rate Limiter %s",
+	SimReportLimiterOn:       "включен",
+	SimReportLimiterOff:      "выключен",
+	SimReportTotals:          "Запросов: %d, пропущено: %d, отклонено 429: %d (%.1f%%), без доступного бэкенда (503): %d",
+	SimReportClientsHeader:   "КЛИЕНТ\tКОЛ-ВО\tЛИМИТ (rate/capacity)\tЗАПРОСОВ\tПРОПУЩЕНО\tОТКЛОНЕНО\tПРЕДУПРЕЖДЕНИЙ",
+	SimReportBackendsHeader:  "БЭКЕНД\tЗАПРОСОВ\tДОЛЯ\tСРЕДНИЙ RPS\tПИКОВЫЙ RPS\tНАСЫЩЕНИЕ",
+	SimReportBackendDown:     " (недоступен)",
+	RLTestUsage:              "Использование: balancer ratelimit-test [-config config.yaml] [-rate 10] [-capacity 20] [-duration 30s] [-concurrency 4] [-tolerance 0.05] [-json]",
+	RLTestBadOption:          "неверное значение -%s: %v",
+	RLTestFailed:             "проверка Rate Limiter не выполнена: %v",
+	RLTestStoreUnavailable:   "хранилище лимитов недоступно: %v",
+	RLTestStoreErrors:        "%d проверок отклонены из-за ошибки хранилища (store_failure_policy: fail_closed)",
+	RLTestSlowStore:          "проверка лимита заняла до %v при store_timeout %v: хранилище не успевает отвечать",
+	RLTestDeviation:          "пропущено %d запросов при ожидаемых %.1f: отклонение %+.1f%% больше допустимого расхождения ±%.1f",
+	RLTestReportMemory:       "в памяти",
+	RLTestReportSummary:      "Rate Limiter: rate %.4g/с, capacity %.4g, хранилище: %s, нагрузка %s в %d потоков",
+	RLTestReportTotals:       "Проверок: %d, пропущено: %d, отклонено: %d, ошибок хранилища: %d",
+	RLTestReportExpected:     "Ожидалось пропущенных: %.1f, отклонение: %+.2f%% (допустимо расхождение ±%.1f)",
+	RLTestReportRate:         "Измеренная скорость пополнения: %.2f/с (ожидается %.4g/с); время проверки: среднее %s, максимум %s",
+	RLTestReportPassed:       "Результат: соответствует",
+	RLTestReportFailed:       "Результат: НЕ соответствует",
+	StorageMigrateUsage:      "Использование: balancer storage migrate -from sqlite:./rate_limits.db -to sqlite:./new.db [-json]",
+	StorageMigrateOpenFailed: "не удалось открыть хранилище '%s': %v",
+	StorageMigrateFailed:     "перенос хранилища не выполнен: %v",
+	StorageMigrateReport:     "Перенесено: клиентов %d, записей списков доступа %d, статусов бэкендов %d, API-ключей %d. Данные в хранилище назначения сверены с исходными.",
+	BenchUsage:               "Использование: balancer bench -target http://localhost:8080/ [-rps 100] [-clients 10] [-duration 30s] [-concurrency 64] [-timeout 5s] [-client-header X-Client-ID] [-trace-token TOKEN] [-json]",
+	BenchBadOption:           "неверное значение -%s: %v",
+	BenchFailed:              "[Error] [Bench] Нагрузка не выполнена: %v",
+	BenchReportSummary:       "Цель: %s, клиентов: %d, частота: %g запросов/с, длительность: %s",
+	BenchReportTotals:        "Отправлено запросов: %d (%.1f/с), пропущено (нет свободного слота): %d, без ответа: %d",
+	BenchReportRates:         "Отказы Rate Limiter (429): %.2f%%, ошибки (5xx и без ответа): %.2f%%",
+	BenchReportLatency:       "Время ответа, мс: min %.2f, p50 %.2f, p90 %.2f, p99 %.2f, max %.2f",
+	BenchReportStatuses:      "Ответы по статусам:",
+	BenchReportBackends:      "Распределение по бэкендам:",
+	BenchReportNoTrace:       "Распределение по бэкендам не измерено: ответы без трассировки (включите trace и укажите -trace-token).",
+
+	// Сценарии допуска (internal/hooks)
+	HooksLoaded:     "[Hooks] Сценарий admission_hook %s загружен (max_steps %d)",
+	HooksPrint:      "[Hooks] %s: %s",
+	HooksReadFailed: "не удалось прочитать сценарий admission_hook %s: %v",
+	HooksNoAdmit:    "сценарий %s не определяет функцию admit(request)",
+	HooksBadResult:  "admit вернул %s, ожидается None или dict",
+	HooksBadKey:     "неизвестное поле '%s' в решении admit (ожидаются pool, cost, reject, message)",
+	HooksBadField:   "поле '%s' решения admit: ожидается %s, получено %s",
+
+	// HTTPS-листенер с маршрутизацией по SNI (internal/sni)
+	SNISiteAdded:        "[SNI] Зарегистрированы домены: %v",
+	SNINoCertificates:   "не задано ни одного сертификата",
+	SNINoCACertificates: "в файле '%s' нет сертификатов PEM",
+
+	// Хранилище (internal/storage)
+	StorageClientNotFound:           "клиент не найден",
+	StorageClientExists:             "клиент уже существует",
+	StorageAccessEntryNotFound:      "запись списка доступа не найдена",
+	StorageAPIKeyNotFound:           "API-ключ не найден",
+	StorageOpenFailed:               "ошибка открытия БД SQLite '%s': %w",
+	StoragePingFailed:               "ошибка подключения к БД SQLite '%s': %w",
+	StorageConnected:                "[Storage] Успешно подключено к SQLite DB (pure-go): %s",
+	StorageJournalModeMismatch:      "[Warning] [Storage] Режим журнала SQLite '%s' недоступен, используется '%s'",
+	StorageSchemaMigrated:           "[Storage] Применена миграция схемы %d (%s)",
+	StorageSchemaMigrationFailed:    "ошибка миграции схемы %d (%s): %w",
+	StorageSchemaTooNew:             "версия схемы базы %d новее поддерживаемой %d: база создана более новой версией балансировщика",
+	StorageSchemaVersionFailed:      "ошибка чтения версии схемы: %w",
+	StorageGetStateFailed:           "[Storage] Ошибка получения состояния для клиента '%s': %v",
+	StorageQueryStateFailed:         "ошибка запроса состояния клиента '%s': %w",
+	StorageBadLastRefill:            "[Storage] Ошибка парсинга last_refill ('%s') для клиента '%s': %v. Используется нулевое время.",
+	StorageGetConfigFailed:          "[Storage] Ошибка получения конфига лимита для клиента '%s': %v",
+	StorageQueryConfigFailed:        "ошибка запроса конфига лимита клиента '%s': %w",
+	StorageGetSavedStateFailed:      "[Storage] Ошибка получения сохраненного состояния для клиента '%s': %v",
+	StorageQuerySavedStateFailed:    "ошибка запроса сохраненного состояния клиента '%s': %w",
+	StorageAddClientFailed:          "ошибка добавления клиента '%s': %w",
+	StorageAddLimitFailed:           "ошибка добавления лимита для '%s': %w",
+	StorageLimitAdded:               "[Storage] Добавлен лимит для клиента '%s': Rate=%.2f, Capacity=%.2f, Tokens=%.2f",
+	StorageUpdateLimitFailed:        "ошибка обновления лимита для '%s': %w",
+	StorageUpdatedRowsFailed:        "ошибка получения количества обновленных строк для '%s': %w",
+	StorageUpdateClientFailed:       "ошибка обновления клиента '%s': %w",
+	StorageLimitUpdated:             "[Storage] Обновлен лимит (rate/capacity) для клиента '%s': Rate=%.2f, Capacity=%.2f",
+	StorageDeleteLimitFailed:        "ошибка удаления лимита для '%s': %w",
+	StorageListLimitsFailed:         "ошибка чтения списка лимитов клиентов: %w",
+	StorageDeletedRowsFailed:        "ошибка получения количества удаленных строк для '%s': %w",
+	StorageDeleteClientFailed:       "ошибка удаления клиента '%s': %w",
+	StorageLimitDeleted:             "[Storage] Удален лимит для клиента '%s'",
+	StorageBeginTxFailed:            "ошибка начала транзакции для batch update: %w",
+	StoragePrepareFailed:            "ошибка подготовки запроса для batch update: %w",
+	StorageBatchClientFailed:        "[Storage] Ошибка обновления состояния для клиента '%s' в batch: %v",
+	StorageBatchExecFailed:          "ошибка выполнения batch update для клиента '%s': %w",
+	StorageCommitFailed:             "ошибка commit транзакции для batch update: %w",
+	StorageBatchUpdated:             "[Storage] BatchUpdateClientState: Успешно обновлено состояние для %d из %d клиентов.",
+	StoragePutAccessEntryFailed:     "ошибка сохранения записи списка доступа '%s': %w",
+	StorageAccessEntryPut:           "[Storage] Запись '%s' сохранена в списке %s",
+	StorageDeleteAccessEntryFailed:  "ошибка удаления записи списка доступа '%s': %w",
+	StorageAccessEntryDeleted:       "[Storage] Запись '%s' удалена из списка доступа",
+	StorageListAccessFailed:         "ошибка чтения списка доступа: %w",
+	StoragePutAPIKeyFailed:          "ошибка сохранения API-ключа клиента '%s': %w",
+	StorageAPIKeyPut:                "[Storage] API-ключ клиента '%s' сохранен",
+	StorageLookupAPIKeyFailed:       "ошибка поиска API-ключа: %w",
+	StorageDeleteAPIKeyFailed:       "ошибка удаления API-ключа '%s': %w",
+	StorageAPIKeyDeleted:            "[Storage] API-ключ '%s' отозван",
+	StorageListAPIKeysFailed:        "ошибка чтения API-ключей: %w",
+	StoragePurgeAccessFailed:        "ошибка удаления истекших записей списка доступа: %w",
+	StorageAccessEntriesExpired:     "[Storage] Удалено истекших записей списка доступа: %d",
+	StorageSaveHealthFailed:         "ошибка сохранения статуса бэкендов: %w",
+	StorageHealthSaved:              "[Storage] Сохранен статус бэкендов: %d",
+	StorageEnableVacuumFailed:       "ошибка включения auto_vacuum = INCREMENTAL: %w",
+	StorageIncrementalVacuumEnabled: "[Storage] База переведена в режим auto_vacuum = INCREMENTAL (выполнен VACUUM)",
+	StorageMaintenanceFailed:        "ошибка обслуживания базы: %w",
+	StorageMaintenanceDone:          "[Storage] Обслуживание базы: удалено истекших записей списка доступа %d, устаревших записей статуса бэкендов %d; освобождено страниц %d, размер %d -> %d байт за %v",
+	StorageMaintenanceRunFailed:     "[Error] [Storage] Плановое обслуживание базы не выполнено: %v",
+	StorageMaintenanceStarted:       "[Storage] Плановое обслуживание базы: каждые %v, статус бэкендов хранится %v, страниц за раз %d (0 - все)",
+	StorageLoadHealthFailed:         "ошибка чтения статуса бэкендов: %w",
+	StorageSetTrialFailed:           "ошибка сохранения пробного периода клиента '%s': %w",
+	StorageTrialSet:                 "[Storage] Пробный период клиента '%s' до %s, затем план '%s'",
+	StorageGetTrialFailed:           "ошибка чтения пробного периода клиента '%s': %w",
+	StorageListTrialsFailed:         "ошибка чтения закончившихся пробных периодов: %w",
+	StorageDowngradeTrialFailed:     "ошибка перевода клиента '%s' на план после пробного периода: %w",
+	StorageUnknownDriver:            "неизвестный драйвер хранилища",
+	StorageUnknownDriverWrap:        "%w: '%s' (ожидается <драйвер>:<строка подключения>, драйверы: %s)",
+	StorageNotEmpty:                 "хранилище назначения не пустое",
+	StorageVerificationFailed:       "данные в хранилище назначения отличаются от исходных",
+	StorageVerificationFailedWrap:   "%w: таблица %s",
+	StorageExportFailed:             "ошибка чтения данных для переноса: %w",
+	StorageImportFailed:             "ошибка записи перенесенных данных: %w",
+	StorageMigrated:                 "[Storage] Перенесено клиентов %d, записей списков доступа %d, статусов бэкендов %d, API-ключей %d; данные сверены",
+
+	// UDP-прокси (internal/udpproxy)
+	UDPBadBackend:        "udp.backends[%d]: не удалось разрешить адрес '%s': %w",
+	UDPListening:         "[UDP] Прием датаграмм на %s (бэкендов: %d, session_timeout: %v, response_timeout: %v)",
+	UDPStopped:           "[UDP] Прием датаграмм остановлен.",
+	UDPReadFailed:        "[UDP] Ошибка чтения датаграммы: %v",
+	UDPNoHealthyBackends: "[UDP] Нет доступных бэкендов, датаграмма от %s отброшена",
+	UDPDialFailed:        "[UDP] Не удалось открыть сокет к бэкенду %s: %v",
+	UDPWriteFailed:       "[UDP] Ошибка отправки датаграммы бэкенду %s: %v",
+	UDPReplyFailed:       "[UDP] Ошибка отправки ответа клиенту %s: %v",
+	UDPBackendDown:       "[UDP] Бэкенд %s исключен из балансировки (%s) на %v",
+	UDPBackendUp:         "[UDP] Бэкенд %s снова отвечает",
+	UDPReasonUnreachable: "порт недоступен (ICMP)",
+	UDPReasonTimeout:     "нет ответа за %v",
+}
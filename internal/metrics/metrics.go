@@ -0,0 +1,297 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter - монотонно возрастающий счетчик событий.
+type Counter struct {
+	name  string
+	help  string
+	value atomic.Uint64
+}
+
+// Inc увеличивает счетчик на единицу.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add увеличивает счетчик на n.
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value возвращает текущее значение счетчика.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Name возвращает имя метрики.
+func (c *Counter) Name() string {
+	return c.name
+}
+
+// Help возвращает описание метрики.
+func (c *Counter) Help() string {
+	return c.help
+}
+
+// Histogram - распределение наблюдаемых значений по корзинам с заданными верхними границами.
+type Histogram struct {
+	name    string
+	help    string
+	bounds  []float64       // Верхние границы корзин по возрастанию.
+	buckets []atomic.Uint64 // Наблюдения по корзинам; последняя - больше всех границ.
+	count   atomic.Uint64
+	sumBits atomic.Uint64 // Сумма наблюдений (биты float64).
+}
+
+// HistogramBucket - число наблюдений, не превышающих UpperBound (накопительно, как в Prometheus).
+type HistogramBucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// HistogramSnapshot - согласованный на момент чтения срез гистограммы.
+type HistogramSnapshot struct {
+	Buckets []HistogramBucket // Без корзины +Inf: ее значение равно Count.
+	Count   uint64
+	Sum     float64
+}
+
+// Observe учитывает наблюдение.
+func (h *Histogram) Observe(value float64) {
+	h.buckets[sort.SearchFloat64s(h.bounds, value)].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sumBits.Load()
+		sum := math.Float64frombits(old) + value
+		if h.sumBits.CompareAndSwap(old, math.Float64bits(sum)) {
+			return
+		}
+	}
+}
+
+// Snapshot возвращает накопительные значения корзин, количество и сумму наблюдений.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	snapshot := HistogramSnapshot{Buckets: make([]HistogramBucket, len(h.bounds))}
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.buckets[i].Load()
+		snapshot.Buckets[i] = HistogramBucket{UpperBound: bound, Count: cumulative}
+	}
+	snapshot.Count = cumulative + h.buckets[len(h.bounds)].Load()
+	snapshot.Sum = math.Float64frombits(h.sumBits.Load())
+	return snapshot
+}
+
+// Name возвращает имя метрики.
+func (h *Histogram) Name() string {
+	return h.name
+}
+
+// Help возвращает описание метрики.
+func (h *Histogram) Help() string {
+	return h.help
+}
+
+// Gauge - значение, которое может как расти, так и уменьшаться (например, запросы в обработке).
+type Gauge struct {
+	name  string
+	help  string
+	value atomic.Int64
+}
+
+// Inc увеличивает значение на единицу.
+func (g *Gauge) Inc() {
+	g.value.Add(1)
+}
+
+// Dec уменьшает значение на единицу.
+func (g *Gauge) Dec() {
+	g.value.Add(-1)
+}
+
+// Set устанавливает значение.
+func (g *Gauge) Set(value int64) {
+	g.value.Store(value)
+}
+
+// Value возвращает текущее значение.
+func (g *Gauge) Value() int64 {
+	return g.value.Load()
+}
+
+// Name возвращает имя метрики.
+func (g *Gauge) Name() string {
+	return g.name
+}
+
+// Help возвращает описание метрики.
+func (g *Gauge) Help() string {
+	return g.help
+}
+
+// registry хранит все зарегистрированные метрики процесса.
+var registry = struct {
+	mu         sync.RWMutex
+	counters   map[string]*Counter
+	gauges     map[string]*Gauge
+	histograms map[string]*Histogram
+	collectors map[string]Collector
+}{
+	counters:   make(map[string]*Counter),
+	gauges:     make(map[string]*Gauge),
+	histograms: make(map[string]*Histogram),
+	collectors: make(map[string]Collector),
+}
+
+// NewCounter создает счетчик и регистрирует его в глобальном реестре.
+// Повторный вызов с тем же именем возвращает уже зарегистрированный счетчик.
+func NewCounter(name, help string) *Counter {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if c, ok := registry.counters[name]; ok {
+		return c
+	}
+	c := &Counter{name: name, help: help}
+	registry.counters[name] = c
+	return c
+}
+
+// Counters возвращает все зарегистрированные счетчики, отсортированные по имени.
+func Counters() []*Counter {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	result := make([]*Counter, 0, len(registry.counters))
+	for _, c := range registry.counters {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result
+}
+
+// NewHistogram создает гистограмму с верхними границами корзин bounds и регистрирует ее в глобальном реестре.
+// Повторный вызов с тем же именем возвращает уже зарегистрированную гистограмму.
+func NewHistogram(name, help string, bounds []float64) *Histogram {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if h, ok := registry.histograms[name]; ok {
+		return h
+	}
+	h := NewUnregisteredHistogram(bounds)
+	h.name, h.help = name, help
+	registry.histograms[name] = h
+	return h
+}
+
+// NewUnregisteredHistogram создает гистограмму без имени, не попадающую в реестр: например, гистограмму
+// одного бэкенда, которую экспортирует Collector пула с метками.
+func NewUnregisteredHistogram(bounds []float64) *Histogram {
+	sorted := append([]float64(nil), bounds...)
+	sort.Float64s(sorted)
+	return &Histogram{bounds: sorted, buckets: make([]atomic.Uint64, len(sorted)+1)}
+}
+
+// Histograms возвращает все зарегистрированные гистограммы, отсортированные по имени.
+func Histograms() []*Histogram {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	result := make([]*Histogram, 0, len(registry.histograms))
+	for _, h := range registry.histograms {
+		result = append(result, h)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result
+}
+
+// NewGauge создает показатель и регистрирует его в глобальном реестре.
+// Повторный вызов с тем же именем возвращает уже зарегистрированный показатель.
+func NewGauge(name, help string) *Gauge {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if g, ok := registry.gauges[name]; ok {
+		return g
+	}
+	g := &Gauge{name: name, help: help}
+	registry.gauges[name] = g
+	return g
+}
+
+// Gauges возвращает все зарегистрированные показатели, отсортированные по имени.
+func Gauges() []*Gauge {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	result := make([]*Gauge, 0, len(registry.gauges))
+	for _, g := range registry.gauges {
+		result = append(result, g)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result
+}
+
+// Типы семейств метрик (см. Family).
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Label - метка значения метрики.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample - значение метрики с метками. Для семейства TypeHistogram используется Histogram, а не Value.
+type Sample struct {
+	Labels    []Label
+	Value     float64
+	Histogram HistogramSnapshot
+}
+
+// Family - метрика с набором значений, различающихся метками.
+type Family struct {
+	Name    string
+	Help    string
+	Type    string // TypeCounter, TypeGauge или TypeHistogram.
+	Samples []Sample
+}
+
+// Collector возвращает метрики, которые вычисляются при экспорте: например, по бэкендам пула,
+// состав которого меняется во время работы.
+type Collector func() []Family
+
+// RegisterCollector регистрирует Collector под ключом key; повторная регистрация с тем же ключом
+// заменяет прежний. Семейства с одинаковым именем из разных Collector экспортируются как одно.
+func RegisterCollector(key string, collector Collector) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.collectors[key] = collector
+}
+
+// collectors возвращает зарегистрированные Collector в порядке ключей.
+func collectors() []Collector {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	keys := make([]string, 0, len(registry.collectors))
+	for key := range registry.collectors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]Collector, len(keys))
+	for i, key := range keys {
+		result[i] = registry.collectors[key]
+	}
+	return result
+}
@@ -0,0 +1,121 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/metrics"
+)
+
+// TestCounter проверяет работу счетчика и повторную регистрацию по имени.
+func TestCounter(t *testing.T) {
+	c := metrics.NewCounter("test_counter_total", "Тестовый счетчик")
+	c.Inc()
+	c.Add(2)
+	assert.Equal(t, uint64(3), c.Value())
+
+	// Повторная регистрация должна вернуть тот же счетчик
+	same := metrics.NewCounter("test_counter_total", "Другое описание")
+	assert.Same(t, c, same)
+	assert.Equal(t, "Тестовый счетчик", same.Help())
+
+	found := false
+	for _, registered := range metrics.Counters() {
+		if registered.Name() == "test_counter_total" {
+			found = true
+		}
+	}
+	assert.True(t, found, "Счетчик должен присутствовать в реестре")
+}
+
+// TestHistogram проверяет распределение наблюдений по корзинам и повторную регистрацию по имени.
+func TestHistogram(t *testing.T) {
+	h := metrics.NewHistogram("test_histogram", "Тестовая гистограмма", []float64{10, 1, 5})
+	for _, value := range []float64{0, 1, 3, 5, 7, 100} {
+		h.Observe(value)
+	}
+
+	snapshot := h.Snapshot()
+	assert.Equal(t, []metrics.HistogramBucket{
+		{UpperBound: 1, Count: 2},
+		{UpperBound: 5, Count: 4},
+		{UpperBound: 10, Count: 5},
+	}, snapshot.Buckets, "Границы сортируются, значения на границе попадают в корзину")
+	assert.Equal(t, uint64(6), snapshot.Count)
+	assert.InDelta(t, 116.0, snapshot.Sum, 1e-9)
+
+	assert.Same(t, h, metrics.NewHistogram("test_histogram", "", nil))
+	assert.Contains(t, metrics.Histograms(), h)
+}
+
+// TestGauge проверяет изменение показателя и повторную регистрацию по имени.
+func TestGauge(t *testing.T) {
+	g := metrics.NewGauge("test_gauge", "Тестовый показатель")
+	g.Inc()
+	g.Inc()
+	g.Dec()
+	assert.Equal(t, int64(1), g.Value())
+	g.Set(10)
+	assert.Equal(t, int64(10), g.Value())
+	assert.Same(t, g, metrics.NewGauge("test_gauge", ""))
+	assert.Contains(t, metrics.Gauges(), g)
+}
+
+// TestWritePrometheus проверяет текстовый формат: метрики реестра, семейства Collector с метками,
+// объединение одноименных семейств и экранирование значений меток.
+func TestWritePrometheus(t *testing.T) {
+	metrics.NewCounter("test_exposition_total", "Счетчик\nс переводом строки").Add(7)
+	h := metrics.NewUnregisteredHistogram([]float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(3)
+	for _, pool := range []string{"a", `b"c`} {
+		metrics.RegisterCollector("test:"+pool, func() []metrics.Family {
+			labels := []metrics.Label{{Name: "pool", Value: pool}}
+			return []metrics.Family{
+				{Name: "test_pool_up", Help: "Доступность пула.", Type: metrics.TypeGauge,
+					Samples: []metrics.Sample{{Labels: labels, Value: 1}}},
+				{Name: "test_pool_seconds", Help: "Время ответа.", Type: metrics.TypeHistogram,
+					Samples: []metrics.Sample{{Labels: labels, Histogram: h.Snapshot()}}},
+			}
+		})
+	}
+
+	var out strings.Builder
+	require.NoError(t, metrics.WritePrometheus(&out))
+	text := out.String()
+	assert.Contains(t, text, "# HELP test_exposition_total Счетчик\\nс переводом строки\n# TYPE test_exposition_total counter\ntest_exposition_total 7\n")
+	assert.Contains(t, text, "# TYPE test_pool_up gauge\ntest_pool_up{pool=\"a\"} 1\ntest_pool_up{pool=\"b\\\"c\"} 1\n",
+		"Одноименные семейства разных Collector экспортируются под одним заголовком")
+	assert.Equal(t, 1, strings.Count(text, "# TYPE test_pool_up "))
+	assert.Contains(t, text, `test_pool_seconds_bucket{pool="a",le="0.1"} 1
+test_pool_seconds_bucket{pool="a",le="1"} 1
+test_pool_seconds_bucket{pool="a",le="+Inf"} 2
+test_pool_seconds_sum{pool="a"} 3.05
+test_pool_seconds_count{pool="a"} 2
+`)
+	assert.Contains(t, text, "http_server_in_flight_requests 0\n")
+}
+
+// TestHandlerAndInFlight проверяет ответ эндпоинта метрик и учет запросов в обработке.
+func TestHandlerAndInFlight(t *testing.T) {
+	var during string
+	handler := metrics.InFlight(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		metrics.Handler().ServeHTTP(rec, r)
+		during = rec.Body.String()
+		w.Header().Set("Content-Type", rec.Header().Get("Content-Type"))
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, metrics.ContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, during, "http_server_in_flight_requests 1\n", "Запрос учитывается, пока обрабатывается")
+
+	rec = httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
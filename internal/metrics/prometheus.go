@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType - тип ответа с метриками в текстовом формате Prometheus 0.0.4.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// httpInFlight - запросы, которые обрабатывает HTTP-сервер балансировщика (см. InFlight).
+var httpInFlight = NewGauge("http_server_in_flight_requests",
+	"Количество запросов, которые обрабатывает HTTP-сервер балансировщика в данный момент.")
+
+// InFlight учитывает запросы к next в показателе http_server_in_flight_requests.
+func InFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Handler отдает все метрики реестра в текстовом формате Prometheus (GET /metrics).
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		if r.Method == http.MethodHead {
+			return
+		}
+		_ = WritePrometheus(w) // Ошибка записи означает, что клиент отключился
+	})
+}
+
+// Gather возвращает все метрики реестра и Collector, отсортированные по имени. Семейства с одинаковым
+// именем объединяются: описание и тип берутся из первого.
+func Gather() []Family {
+	var families []Family
+	for _, c := range Counters() {
+		families = append(families, Family{Name: c.name, Help: c.help, Type: TypeCounter,
+			Samples: []Sample{{Value: float64(c.Value())}}})
+	}
+	for _, g := range Gauges() {
+		families = append(families, Family{Name: g.name, Help: g.help, Type: TypeGauge,
+			Samples: []Sample{{Value: float64(g.Value())}}})
+	}
+	for _, h := range Histograms() {
+		families = append(families, Family{Name: h.name, Help: h.help, Type: TypeHistogram,
+			Samples: []Sample{{Histogram: h.Snapshot()}}})
+	}
+
+	index := make(map[string]int, len(families))
+	for i, family := range families {
+		index[family.Name] = i
+	}
+	for _, collect := range collectors() {
+		for _, family := range collect() {
+			if i, ok := index[family.Name]; ok {
+				families[i].Samples = append(families[i].Samples, family.Samples...)
+				continue
+			}
+			index[family.Name] = len(families)
+			families = append(families, family)
+		}
+	}
+	sort.SliceStable(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families
+}
+
+// WritePrometheus записывает метрики (см. Gather) в w в текстовом формате Prometheus.
+func WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, family := range Gather() {
+		if len(family.Samples) == 0 {
+			continue
+		}
+		bw.WriteString("# HELP " + family.Name + " " + escapeHelp(family.Help) + "\n")
+		bw.WriteString("# TYPE " + family.Name + " " + family.Type + "\n")
+		for _, sample := range family.Samples {
+			if family.Type != TypeHistogram {
+				writeSample(bw, family.Name, sample.Labels, sample.Value)
+				continue
+			}
+			for _, bucket := range sample.Histogram.Buckets {
+				le := Label{Name: "le", Value: formatFloat(bucket.UpperBound)}
+				writeSample(bw, family.Name+"_bucket", append(slices.Clip(sample.Labels), le), float64(bucket.Count))
+			}
+			inf := Label{Name: "le", Value: "+Inf"}
+			writeSample(bw, family.Name+"_bucket", append(slices.Clip(sample.Labels), inf), float64(sample.Histogram.Count))
+			writeSample(bw, family.Name+"_sum", sample.Labels, sample.Histogram.Sum)
+			writeSample(bw, family.Name+"_count", sample.Labels, float64(sample.Histogram.Count))
+		}
+	}
+	return bw.Flush()
+}
+
+// writeSample записывает строку "имя{метки} значение".
+func writeSample(w *bufio.Writer, name string, labels []Label, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label.Name + `="` + escapeLabel(label.Value) + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// formatFloat форматирует значение так, как его ожидает Prometheus (+Inf, -Inf, NaN).
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
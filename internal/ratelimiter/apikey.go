@@ -0,0 +1,106 @@
+package ratelimiter
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+	"load-balancer/internal/storage"
+)
+
+var apiKeyUnknownTotal = metrics.NewCounter("ratelimiter_api_key_unknown_total",
+	"Количество запросов с неизвестным API-ключом, идентифицированных по IP-адресу.")
+
+// APIKeyStore - хранилище API-ключей клиентов (реализуется *storage.DB).
+type APIKeyStore interface {
+	LookupAPIKey(hash string) (key storage.APIKey, found bool, err error)
+}
+
+// apiKeys находит ID клиента по API-ключу запроса (rate_limiter.api_keys).
+type apiKeys struct {
+	store  APIKeyStore
+	header string
+	ttl    time.Duration
+	// cache - найденные ключи по хэшу (apiKeyLookup). Неизвестные ключи не запоминаются: иначе поток
+	// запросов со случайными ключами занимал бы память до истечения ttl.
+	cache sync.Map
+}
+
+// apiKeyLookup - запомненный результат поиска ключа.
+type apiKeyLookup struct {
+	clientID string
+	until    int64 // Время истечения в наносекундах Unix.
+}
+
+// requestAPIKey возвращает API-ключ запроса: из Authorization: Bearer <ключ>, иначе из заголовка header.
+func requestAPIKey(r *http.Request, header string) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		if token = strings.TrimSpace(token); token != "" {
+			return token
+		}
+	}
+	return strings.TrimSpace(r.Header.Get(header))
+}
+
+// apiKeyClientID возвращает ID клиента, которому выдан API-ключ запроса. false - ключа в запросе нет,
+// он неизвестен или хранилище недоступно: клиент идентифицируется как анонимный.
+func (rl *RateLimiter) apiKeyClientID(r *http.Request) (string, bool) {
+	key := requestAPIKey(r, rl.apiKeys.header)
+	if key == "" {
+		return "", false
+	}
+	hash := storage.HashAPIKey(key)
+	now := time.Now()
+	if cached, ok := rl.apiKeys.cache.Load(hash); ok {
+		if now.UnixNano() < cached.(apiKeyLookup).until {
+			return cached.(apiKeyLookup).clientID, true
+		}
+		rl.apiKeys.cache.CompareAndDelete(hash, cached)
+	}
+
+	var found storage.APIKey
+	var ok bool
+	err := rl.withStoreTimeout(func() error {
+		var callErr error
+		found, ok, callErr = rl.apiKeys.store.LookupAPIKey(hash)
+		return callErr
+	})
+	if err != nil {
+		// Ошибка не запоминается: ключ проверяется снова со следующим запросом
+		storeErrorsTotal.Inc()
+		i18n.Logf(i18n.RLAPIKeyLookupFailed, err)
+		return "", false
+	}
+	if !ok {
+		apiKeyUnknownTotal.Inc()
+		i18n.Logf(i18n.RLAPIKeyUnknown)
+		return "", false
+	}
+	rl.apiKeys.cache.Store(hash, apiKeyLookup{clientID: found.ClientID, until: now.Add(rl.apiKeys.ttl).UnixNano()})
+	return found.ClientID, true
+}
+
+// ForgetAPIKey удаляет запомненный результат поиска ключа с хэшем hash, чтобы отзыв ключа через API
+// действовал на этом экземпляре сразу, а не через rate_limiter.api_keys.cache_ttl.
+func (rl *RateLimiter) ForgetAPIKey(hash string) {
+	if rl.apiKeys != nil {
+		rl.apiKeys.cache.Delete(hash)
+	}
+}
+
+// purgeAPIKeyCache удаляет истекшие результаты поиска ключей.
+func (rl *RateLimiter) purgeAPIKeyCache() {
+	if rl.apiKeys == nil {
+		return
+	}
+	now := time.Now().UnixNano()
+	rl.apiKeys.cache.Range(func(hash, value any) bool {
+		if now >= value.(apiKeyLookup).until {
+			rl.apiKeys.cache.CompareAndDelete(hash, value)
+		}
+		return true
+	})
+}
@@ -0,0 +1,73 @@
+package ratelimiter
+
+import (
+	"sort"
+	"time"
+
+	"load-balancer/internal/i18n"
+)
+
+// lruEntry - корзина-кандидат на удаление сверх max_buckets.
+type lruEntry struct {
+	key      string
+	lastUsed time.Time
+}
+
+// idle сообщает, что корзину можно удалить как простаивающую: запросов не было дольше ttl, а корзина полна,
+// то есть не отличается от новой. Должен вызываться под блокировкой bucket.mu.
+func (tb *TokenBucket) idle(now time.Time, ttl time.Duration) bool {
+	return now.Sub(tb.lastUsed) >= ttl && tb.rollout == nil && tb.tokens >= tb.capacity-floatEpsilon
+}
+
+// evictBuckets удаляет из памяти простаивающие корзины idle (см. rate_limiter.idle_bucket_ttl), а затем,
+// если корзин больше max_buckets, - дольше всех не использовавшиеся из lru. Перед удалением корзина
+// проверяется повторно: запрос мог прийти, пока карта не была заблокирована.
+func (rl *RateLimiter) evictBuckets(now time.Time, idle []string, lru []lruEntry) {
+	if len(idle) == 0 && len(lru) == 0 {
+		return
+	}
+	// Сортировка - до блокировки карты: она не должна задерживать поиск корзин
+	sort.Slice(lru, func(i, j int) bool { return lru[i].lastUsed.Before(lru[j].lastUsed) })
+
+	rl.mu.Lock()
+	idleEvicted := 0
+	for _, key := range idle {
+		bucket, ok := rl.buckets[key]
+		if !ok {
+			continue
+		}
+		bucket.mu.Lock()
+		stillIdle := bucket.idle(now, rl.idleBucketTTL)
+		bucket.mu.Unlock()
+		if stillIdle {
+			delete(rl.buckets, key)
+			idleEvicted++
+		}
+	}
+	lruEvicted := 0
+	for _, entry := range lru {
+		if len(rl.buckets) <= rl.maxBuckets {
+			break
+		}
+		bucket, ok := rl.buckets[entry.key]
+		if !ok {
+			continue
+		}
+		bucket.mu.Lock()
+		unused := bucket.lastUsed.Equal(entry.lastUsed)
+		bucket.mu.Unlock()
+		if unused {
+			delete(rl.buckets, entry.key)
+			lruEvicted++
+		}
+	}
+	rl.mu.Unlock()
+
+	bucketsEvictedTotal.Add(uint64(idleEvicted + lruEvicted))
+	if idleEvicted > 0 {
+		i18n.Logf(i18n.RLBucketsEvicted, idleEvicted)
+	}
+	if lruEvicted > 0 {
+		i18n.Logf(i18n.RLBucketsOverCap, rl.maxBuckets, lruEvicted)
+	}
+}
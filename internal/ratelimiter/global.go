@@ -0,0 +1,42 @@
+package ratelimiter
+
+import (
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var globalDeniedTotal = metrics.NewCounter("ratelimiter_global_denied_total",
+	"Количество запросов, отклоненных общим лимитом rate_limiter.global (ответ 503).")
+
+// takeGlobal списывает cost токенов из общей корзины (rate_limiter.global). При нехватке возвращает
+// решение об отказе со временем до появления токенов. Без общего лимита запрос всегда проходит.
+func (rl *RateLimiter) takeGlobal(clientID string, cost float64) (Decision, bool) {
+	g := rl.global
+	if g == nil {
+		return Decision{}, true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.tokens >= cost-floatEpsilon {
+		g.consume(cost)
+		return Decision{}, true
+	}
+	globalDeniedTotal.Inc()
+	i18n.Logf(i18n.RLGlobalRejected, clientID)
+	return Decision{Global: true, RetryAfter: g.untilTokens(cost)}, false
+}
+
+// refundGlobal возвращает в общую корзину токены запроса, который не прошел по корзине клиента:
+// общий лимит считает только пропущенные запросы.
+func (rl *RateLimiter) refundGlobal(cost float64) {
+	g := rl.global
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tokens = min(g.capacity, g.tokens+cost)
+	if g.window != nil {
+		g.window.current = max(g.window.current-cost, 0)
+	}
+}
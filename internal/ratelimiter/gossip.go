@@ -0,0 +1,408 @@
+package ratelimiter
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+// maxGossipMessageSize - размер буфера приема сообщений обмена.
+const maxGossipMessageSize = 64 * 1024
+
+// maxGossipDatagramSize - наибольший размер датаграммы обмена вместе с подписью: расход большого числа
+// клиентов рассылается несколькими датаграммами, каждая меньше предела UDP и буфера приема.
+const maxGossipDatagramSize = 32 * 1024
+
+// maxGossipMessageAge - насколько подписанное сообщение может отличаться по времени отправки от часов получателя.
+// Более старые сообщения отбрасываются; повтор перехваченной датаграммы в пределах этого окна отсекает
+// номер сообщения (см. gossipReplay).
+const maxGossipMessageAge = 30 * time.Second
+
+// gossipReplayWindow - сколько последних номеров сообщений экземпляра запоминает получатель: датаграммы
+// одной рассылки могут прийти не по порядку, более старые номера отбрасываются.
+const gossipReplayWindow = 64
+
+var (
+	gossipSentTotal = metrics.NewCounter("ratelimiter_gossip_messages_sent_total",
+		"Количество сообщений с расходом токенов, отправленных другим экземплярам.")
+	gossipReceivedTotal = metrics.NewCounter("ratelimiter_gossip_messages_received_total",
+		"Количество сообщений с расходом токенов, полученных от других экземпляров.")
+	gossipErrorsTotal = metrics.NewCounter("ratelimiter_gossip_errors_total",
+		"Количество ошибок отправки и некорректных сообщений при обмене расходом токенов.")
+	gossipRejectedTotal = metrics.NewCounter("ratelimiter_gossip_messages_rejected_total",
+		"Количество сообщений обмена, отброшенных из-за адреса не из peers, неверной подписи, устаревшего времени отправки или повтора.")
+)
+
+// gossipMessage - датаграмма обмена: сколько токенов израсходовали клиенты экземпляра с прошлой рассылки.
+// С rate_limiter.gossip.secret перед JSON идет HMAC-SHA256 от него (sha256.Size байт).
+type gossipMessage struct {
+	Node     string             `json:"node"`     // Случайный ID экземпляра-отправителя.
+	Consumed map[string]float64 `json:"consumed"` // Ключ корзины -> израсходовано токенов.
+	Sent     int64              `json:"sent"`     // Время отправки в наносекундах Unix.
+	Seq      uint64             `json:"seq"`      // Номер сообщения экземпляра, растет с каждой датаграммой.
+}
+
+// gossipReplay - принятые номера сообщений одного экземпляра: наибольший номер и битовая маска
+// gossipReplayWindow номеров перед ним.
+type gossipReplay struct {
+	last   uint64
+	window uint64
+	seen   time.Time // Когда принято последнее сообщение экземпляра.
+}
+
+// accept отмечает номер сообщения принятым; false - сообщение с этим номером уже принималось
+// или слишком старое.
+func (r *gossipReplay) accept(seq uint64) bool {
+	switch {
+	case seq > r.last:
+		if shift := seq - r.last; shift <= gossipReplayWindow {
+			r.window = r.window<<shift | 1<<(shift-1)
+		} else {
+			r.window = 0
+		}
+		r.last = seq
+		return true
+	case r.last-seq > gossipReplayWindow || seq == r.last:
+		return false
+	}
+	bit := uint64(1) << (r.last - seq - 1)
+	if r.window&bit != 0 {
+		return false
+	}
+	r.window |= bit
+	return true
+}
+
+// gossipPeer - адрес другого экземпляра. Адрес разрешается заново на каждой рассылке, пока не разрешится,
+// чтобы пир, еще не зарегистрированный в DNS при старте, не выпадал из обмена.
+type gossipPeer struct {
+	name string
+	addr *net.UDPAddr
+}
+
+// gossip рассылает расход токенов пирам и списывает расход пиров из корзин (см. config.GossipConfig).
+type gossip struct {
+	node     string
+	conn     *net.UDPConn
+	interval time.Duration
+	secret   []byte // Ключ подписи сообщений, nil - сообщения не подписываются.
+
+	peersMu sync.RWMutex // Защищает адреса peers: они разрешаются при рассылке и проверяются при приеме
+	peers   []*gossipPeer
+
+	mu       sync.Mutex
+	consumed map[string]float64 // Расход с прошлой рассылки.
+	seq      uint64             // Номер последнего отправленного сообщения.
+
+	// Принятые номера сообщений по ID экземпляра, только с подписью. Используется лишь горутиной приема.
+	replay      map[string]*gossipReplay
+	replayPrune time.Time
+}
+
+// startGossip открывает сокет обмена и запускает рассылку и прием в фоне.
+func (rl *RateLimiter) startGossip(cfg config.GossipConfig) error {
+	addr, err := net.ResolveUDPAddr("udp", cfg.Listen)
+	if err != nil {
+		return i18n.Errorf(i18n.RLGossipListenFailed, cfg.Listen, err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return i18n.Errorf(i18n.RLGossipListenFailed, cfg.Listen, err)
+	}
+
+	node := make([]byte, 8)
+	rand.Read(node)
+	g := &gossip{
+		node:     hex.EncodeToString(node),
+		conn:     conn,
+		interval: cfg.Interval,
+		consumed: make(map[string]float64),
+		replay:   make(map[string]*gossipReplay),
+	}
+	if cfg.Secret != "" {
+		g.secret = []byte(cfg.Secret)
+	}
+	if g.interval <= 0 {
+		g.interval = config.DefaultGossipInterval
+	}
+	for _, peer := range cfg.Peers {
+		g.peers = append(g.peers, &gossipPeer{name: peer})
+	}
+	g.resolvePeers()
+	rl.gossip = g
+
+	rl.wg.Add(2)
+	go rl.gossipSender()
+	go rl.gossipReceiver()
+	i18n.Logf(i18n.RLGossipStarted, conn.LocalAddr(), len(g.peers), g.interval)
+	return nil
+}
+
+// GossipAddr возвращает адрес, на котором принимаются сообщения обмена; nil, если обмен выключен.
+func (rl *RateLimiter) GossipAddr() net.Addr {
+	if rl.gossip == nil {
+		return nil
+	}
+	return rl.gossip.conn.LocalAddr()
+}
+
+// recordConsumption учитывает токены, израсходованные клиентом, для следующей рассылки.
+func (g *gossip) recordConsumption(key string, tokens float64) {
+	g.mu.Lock()
+	g.consumed[key] += tokens
+	g.mu.Unlock()
+}
+
+// gossipSender - горутина, раз в интервал рассылающая накопленный расход всем пирам.
+func (rl *RateLimiter) gossipSender() {
+	defer rl.wg.Done()
+	ticker := time.NewTicker(rl.gossip.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			rl.gossip.resolvePeers()
+			rl.gossip.flush()
+		case <-rl.quit:
+			return
+		}
+	}
+}
+
+// flush отправляет пирам расход с прошлой рассылки. Расход, который не удалось отправить, не повторяется:
+// пир пропустит его, и общий лимит на это время будет превышен.
+func (g *gossip) flush() {
+	g.mu.Lock()
+	consumed := g.consumed
+	g.consumed = make(map[string]float64, len(consumed))
+	g.mu.Unlock()
+	if len(consumed) == 0 || len(g.peers) == 0 {
+		return
+	}
+
+	var payloads [][]byte
+	sent := time.Now().UnixNano()
+	// Размер пустого сообщения с подписью; каждая корзина добавляет к нему "ключ":расход и запятую
+	envelope, _ := json.Marshal(gossipMessage{Node: g.node, Consumed: map[string]float64{}, Sent: sent, Seq: math.MaxUint64})
+	base := len(envelope)
+	if g.secret != nil {
+		base += sha256.Size
+	}
+	batch := make(map[string]float64)
+	size := base
+	encode := func() {
+		g.mu.Lock()
+		g.seq++
+		seq := g.seq
+		g.mu.Unlock()
+		payload, err := json.Marshal(gossipMessage{Node: g.node, Consumed: batch, Sent: sent, Seq: seq})
+		if err == nil {
+			payloads = append(payloads, g.sign(payload))
+		}
+		batch = make(map[string]float64)
+		size = base
+	}
+	for key, tokens := range consumed {
+		encodedKey, _ := json.Marshal(key)
+		encodedTokens, _ := json.Marshal(tokens)
+		entry := len(encodedKey) + len(encodedTokens) + 2
+		if base+entry > maxGossipDatagramSize {
+			gossipErrorsTotal.Inc()
+			i18n.Logf(i18n.RLGossipKeyTooLong, len(key))
+			continue
+		}
+		if size+entry > maxGossipDatagramSize {
+			encode()
+		}
+		batch[key] = tokens
+		size += entry
+	}
+	if len(batch) > 0 {
+		encode()
+	}
+
+	g.peersMu.RLock()
+	peers := make([]gossipPeer, 0, len(g.peers))
+	for _, peer := range g.peers {
+		if peer.addr != nil {
+			peers = append(peers, *peer)
+		}
+	}
+	g.peersMu.RUnlock()
+	for _, peer := range peers {
+		for _, payload := range payloads {
+			if _, err := g.conn.WriteToUDP(payload, peer.addr); err != nil {
+				gossipErrorsTotal.Inc()
+				i18n.Logf(i18n.RLGossipSendFailed, peer.name, err)
+				continue
+			}
+			gossipSentTotal.Inc()
+		}
+	}
+}
+
+// resolvePeers разрешает адреса пиров, которые еще не разрешены.
+func (g *gossip) resolvePeers() {
+	g.peersMu.Lock()
+	defer g.peersMu.Unlock()
+	for _, peer := range g.peers {
+		if peer.addr != nil {
+			continue
+		}
+		addr, err := net.ResolveUDPAddr("udp", peer.name)
+		if err != nil {
+			gossipErrorsTotal.Inc()
+			i18n.Logf(i18n.RLGossipSendFailed, peer.name, err)
+			continue
+		}
+		peer.addr = addr
+	}
+}
+
+// knownPeer сообщает, что датаграмма пришла с адреса одного из пиров. Пир отправляет сообщения
+// с сокета gossip.listen, поэтому сравниваются и адрес, и порт.
+func (g *gossip) knownPeer(from *net.UDPAddr) bool {
+	g.peersMu.RLock()
+	defer g.peersMu.RUnlock()
+	for _, peer := range g.peers {
+		if peer.addr != nil && peer.addr.Port == from.Port && peer.addr.IP.Equal(from.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// sign добавляет перед сообщением подпись HMAC-SHA256, если задан rate_limiter.gossip.secret.
+func (g *gossip) sign(payload []byte) []byte {
+	if g.secret == nil {
+		return payload
+	}
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write(payload)
+	return append(mac.Sum(nil), payload...)
+}
+
+// verify проверяет подпись датаграммы и возвращает сообщение без нее.
+func (g *gossip) verify(datagram []byte) ([]byte, bool) {
+	if g.secret == nil {
+		return datagram, true
+	}
+	if len(datagram) < sha256.Size {
+		return nil, false
+	}
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write(datagram[sha256.Size:])
+	if !hmac.Equal(mac.Sum(nil), datagram[:sha256.Size]) {
+		return nil, false
+	}
+	return datagram[sha256.Size:], true
+}
+
+// gossipReceiver - горутина приема сообщений от пиров.
+func (rl *RateLimiter) gossipReceiver() {
+	defer rl.wg.Done()
+	buf := make([]byte, maxGossipMessageSize)
+	for {
+		n, from, err := rl.gossip.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			i18n.Logf(i18n.RLGossipReadFailed, err)
+			continue
+		}
+
+		if !rl.gossip.knownPeer(from) {
+			gossipRejectedTotal.Inc()
+			i18n.Logf(i18n.RLGossipUnknownPeer, from)
+			continue
+		}
+		payload, ok := rl.gossip.verify(buf[:n])
+		if !ok {
+			gossipRejectedTotal.Inc()
+			i18n.Logf(i18n.RLGossipBadSignature, from)
+			continue
+		}
+
+		var msg gossipMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			gossipErrorsTotal.Inc()
+			i18n.Logf(i18n.RLGossipBadMessage, from, err)
+			continue
+		}
+		if msg.Node == rl.gossip.node {
+			// Собственный адрес в peers: одинаковый список пиров удобно задавать всем экземплярам
+			continue
+		}
+		if rl.gossip.secret != nil {
+			if age := time.Since(time.Unix(0, msg.Sent)); age > maxGossipMessageAge || age < -maxGossipMessageAge {
+				gossipRejectedTotal.Inc()
+				i18n.Logf(i18n.RLGossipStaleMessage, from, age)
+				continue
+			}
+			if !rl.gossip.acceptSeq(msg.Node, msg.Seq) {
+				gossipRejectedTotal.Inc()
+				i18n.Logf(i18n.RLGossipReplayedMessage, from, msg.Seq)
+				continue
+			}
+		}
+		gossipReceivedTotal.Inc()
+		rl.applyPeerConsumption(msg.Consumed)
+	}
+}
+
+// acceptSeq отмечает номер сообщения экземпляра принятым; false - повтор уже принятой датаграммы.
+// Записи экземпляров, от которых давно нет сообщений, удаляются: их датаграммы отбросит проверка возраста.
+func (g *gossip) acceptSeq(node string, seq uint64) bool {
+	now := time.Now()
+	if now.Sub(g.replayPrune) > maxGossipMessageAge {
+		g.replayPrune = now
+		for id, r := range g.replay {
+			// Принятое сообщение отправлено не позже чем через maxGossipMessageAge после приема
+			// и принимается еще столько же после отправки
+			if now.Sub(r.seen) > 2*maxGossipMessageAge {
+				delete(g.replay, id)
+			}
+		}
+	}
+	r, ok := g.replay[node]
+	if !ok {
+		r = &gossipReplay{}
+		g.replay[node] = r
+	}
+	if !r.accept(seq) {
+		return false
+	}
+	r.seen = now
+	return true
+}
+
+// applyPeerConsumption списывает расход пира из корзин клиентов. Корзины, которых еще нет в памяти,
+// пропускаются: новая корзина создается с лимитами и состоянием из хранилища, как без обмена.
+func (rl *RateLimiter) applyPeerConsumption(consumed map[string]float64) {
+	for key, tokens := range consumed {
+		if tokens <= 0 {
+			continue
+		}
+		rl.mu.RLock()
+		bucket, ok := rl.buckets[key]
+		rl.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		bucket.mu.Lock()
+		bucket.consume(min(tokens, max(bucket.tokens, 0)))
+		bucket.mu.Unlock()
+	}
+}
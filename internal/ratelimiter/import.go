@@ -0,0 +1,53 @@
+package ratelimiter
+
+import (
+	"sort"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+)
+
+// ImportClientLimits синхронизирует лимиты клиентов из конфигурации в хранилище:
+// отсутствующие клиенты создаются, клиенты с отличающимися лимитами обновляются.
+// Клиенты, которые есть только в хранилище, не затрагиваются.
+// Возвращает количество созданных и обновленных записей.
+func ImportClientLimits(store StoreConfigInterface, clients map[string]config.ClientRateConfig) (created, updated int, err error) {
+	if len(clients) == 0 {
+		return 0, 0, nil
+	}
+	if store == nil {
+		return 0, 0, i18n.Errorf(i18n.RLImportNoStore, len(clients))
+	}
+
+	// Обходим клиентов в детерминированном порядке, чтобы логи были воспроизводимыми
+	clientIDs := make([]string, 0, len(clients))
+	for clientID := range clients {
+		clientIDs = append(clientIDs, clientID)
+	}
+	sort.Strings(clientIDs)
+
+	for _, clientID := range clientIDs {
+		limit := clients[clientID]
+
+		rate, capacity, found, getErr := store.GetClientLimitConfig(clientID)
+		if getErr != nil {
+			return created, updated, i18n.Errorf(i18n.RLImportReadFailed, clientID, getErr)
+		}
+
+		switch {
+		case !found:
+			if err := store.CreateClientLimit(clientID, limit); err != nil {
+				return created, updated, i18n.Errorf(i18n.RLImportCreateFailed, clientID, err)
+			}
+			created++
+		case rate != limit.Rate || capacity != limit.Capacity:
+			if err := store.UpdateClientLimit(clientID, limit); err != nil {
+				return created, updated, i18n.Errorf(i18n.RLImportUpdateFailed, clientID, err)
+			}
+			updated++
+		}
+	}
+
+	i18n.Logf(i18n.RLImported, len(clients), created, updated)
+	return created, updated, nil
+}
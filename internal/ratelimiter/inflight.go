@@ -0,0 +1,49 @@
+package ratelimiter
+
+import (
+	"sync"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var inFlightRejectedTotal = metrics.NewCounter("ratelimiter_in_flight_rejected_total",
+	"Количество запросов, отклоненных с 429 из-за лимита одновременных запросов клиента (max_in_flight_per_client).")
+
+// inFlightCounter считает одновременные запросы клиентов (rate_limiter.max_in_flight_per_client).
+// Клиенты без запросов в обработке удаляются из карты, поэтому она не растет со временем.
+type inFlightCounter struct {
+	max     int // 0 - без ограничения.
+	mu      sync.Mutex
+	clients map[string]int
+}
+
+// AcquireInFlight занимает слот одновременного запроса клиента. ok = false - у клиента уже
+// max_in_flight_per_client запросов в обработке; иначе release нужно вызвать после ответа.
+// Без ограничения (или с выключенным Rate Limiter) слот выдается всегда.
+func (rl *RateLimiter) AcquireInFlight(clientID string) (release func(), ok bool) {
+	c := &rl.inFlight
+	if !rl.enabled || c.max <= 0 {
+		return func() {}, true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if active := c.clients[clientID]; active >= c.max {
+		inFlightRejectedTotal.Inc()
+		i18n.Logf(i18n.RLInFlightRejected, clientID, active)
+		return nil, false
+	}
+	c.clients[clientID]++
+	var once sync.Once
+	return func() { once.Do(func() { c.release(clientID) }) }, true
+}
+
+func (c *inFlightCounter) release(clientID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clients[clientID] <= 1 {
+		delete(c.clients, clientID)
+		return
+	}
+	c.clients[clientID]--
+}
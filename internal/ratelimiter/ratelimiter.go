@@ -1,18 +1,58 @@
 package ratelimiter
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"net"
 	"net/http"
+	"net/netip"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+	"load-balancer/internal/sni"
 	"load-balancer/internal/storage"
 )
 
+// ErrStoreUnavailable возвращается Check, когда хранилище лимитов вернуло ошибку
+// или не ответило вовремя, а политика rate_limiter.store_failure_policy равна fail_closed.
+var ErrStoreUnavailable = i18n.NewError(i18n.RLStoreUnavailable)
+
+// RefillInterval - период фонового пополнения корзин. Между пополнениями токены не добавляются,
+// а за одно пополнение корзина получает не больше своей емкости.
+const RefillInterval = time.Second
+
+// errStoreTimeout возвращается, если хранилище не ответило за rate_limiter.store_timeout.
+var errStoreTimeout = i18n.NewError(i18n.RLStoreTimeout)
+
+var (
+	storeErrorsTotal = metrics.NewCounter("ratelimiter_store_errors_total",
+		"Количество ошибок и таймаутов при обращении Rate Limiter к хранилищу лимитов.")
+	storeFailClosedTotal = metrics.NewCounter("ratelimiter_store_fail_closed_total",
+		"Количество запросов, отклоненных с 503 из-за недоступности хранилища (fail_closed).")
+	allowedTotal = metrics.NewCounter("ratelimiter_allowed_total",
+		"Количество запросов, пропущенных Rate Limiter (токен списан).")
+	deniedTotal = metrics.NewCounter("ratelimiter_denied_total",
+		"Количество запросов, отклоненных Rate Limiter с 429 (в корзине нет токена).")
+	bucketsCreatedTotal = metrics.NewCounter("ratelimiter_buckets_created_total",
+		"Количество созданных в памяти корзин токенов (новых клиентов).")
+	softLimitWarningsTotal = metrics.NewCounter("ratelimiter_soft_limit_warnings_total",
+		"Количество пропущенных запросов, превысивших мягкий порог (ответ с заголовком X-RateLimit-Warning).")
+	denialCacheHitsTotal = metrics.NewCounter("ratelimiter_denial_cache_hits_total",
+		"Количество отказов 429, выданных из кэша отказов без обращения к корзине.")
+	bucketsEvictedTotal = metrics.NewCounter("ratelimiter_buckets_evicted_total",
+		"Количество корзин токенов, удаленных из памяти.")
+	// tokensRemaining показывает запас клиентов на момент решения: много решений около нуля -
+	// лимиты близки к исчерпанию, много у емкости - лимиты можно снижать.
+	tokensRemaining = metrics.NewHistogram("ratelimiter_tokens_remaining",
+		"Количество токенов в корзине клиента сразу после решения Rate Limiter.",
+		[]float64{0, 1, 2, 5, 10, 20, 50, 100, 200, 500, 1000})
+)
+
 type StoreConfigInterface interface {
 	// GetClientLimitConfig извлекает только конфигурацию лимита (rate, capacity) для клиента.
 	GetClientLimitConfig(clientID string) (rate, capacity float64, found bool, err error)
@@ -37,6 +77,16 @@ type TokenBucket struct {
 	tokens float64
 	// lastRefill - время последнего пополнения.
 	lastRefill time.Time
+	// lastUsed - время последнего запроса клиента (см. rate_limiter.idle_bucket_ttl и max_buckets).
+	lastUsed time.Time
+	// rollout - плавное снижение лимитов (см. rate_limiter.limit_decrease_window), nil - перехода нет.
+	rollout *limitRollout
+	// window - счетчик скользящего окна (rate_limiter.algorithm: sliding_window), nil - корзина токенов.
+	// Со скользящим окном tokens - емкость за вычетом расхода в окне.
+	window *slidingWindow
+	// anonymous - корзина создана для клиента, определенного по IP-адресу или отпечатку TLS (см. IDSource):
+	// без индивидуального лимита она получает default_rate_ip/default_capacity_ip.
+	anonymous bool
 	// mu - мьютекс для защиты доступа к полям корзины.
 	mu sync.Mutex
 }
@@ -53,51 +103,185 @@ type RateLimiter struct {
 	defaultRate float64
 	// defaultCapacity - емкость корзины по умолчанию для новых клиентов.
 	defaultCapacity float64
+	// ipDefaults и headerDefaults - лимиты по умолчанию для клиентов, идентифицированных
+	// по IP-адресу и по заголовку соответственно.
+	ipDefaults     config.ClientRateConfig
+	headerDefaults config.ClientRateConfig
 	// identifierHeader - Имя заголовка для идентификации клиента.
 	identifierHeader string
+	// fingerprintIdentity - идентифицировать клиентов HTTPS без заголовка по отпечатку JA3.
+	fingerprintIdentity bool
+	// trustedProxies - подсети прокси, которым доверяется X-Forwarded-For (rate_limiter.trusted_proxies).
+	trustedProxies []netip.Prefix
+	// apiKeys - идентификация по API-ключам из хранилища (rate_limiter.api_keys), nil - выключена.
+	apiKeys *apiKeys
 	// enabled - флаг, включен ли rate limiter.
 	enabled bool
+	// slidingWindow - новые корзины считают расход скользящим окном (rate_limiter.algorithm: sliding_window).
+	slidingWindow bool
+	// failClosed - отклонять ли запросы при ошибке хранилища (политика fail_closed).
+	failClosed bool
+	// storeTimeout - максимальное время ожидания ответа хранилища (0 - без ограничения).
+	storeTimeout time.Duration
+	// softLimitRatio - доля израсходованной емкости, после которой запросы получают предупреждение (0 - выключено).
+	softLimitRatio float64
+	// denialCacheTTL - наибольшее время, на которое запоминается отказ клиенту (0 - кэш выключен).
+	denialCacheTTL time.Duration
+	// idleBucketTTL - через сколько без запросов полная корзина удаляется из памяти (0 - не удаляется).
+	idleBucketTTL time.Duration
+	// maxBuckets - наибольшее число корзин в памяти (0 - без ограничения).
+	maxBuckets int
+	// global - общая корзина всего сервиса (rate_limiter.global), nil - общего лимита нет.
+	global *TokenBucket
+	// inFlight - одновременные запросы клиентов (см. AcquireInFlight).
+	inFlight inFlightCounter
+	// limitDecreaseWindow - за какое время вступает в силу снижение лимитов клиента (0 - сразу).
+	limitDecreaseWindow time.Duration
+	// ipv6PrefixLength - длина префикса, по которому объединяются IPv6-клиенты (0 - каждый адрес отдельно).
+	ipv6PrefixLength int
+	// keyParts - шаблон ключа корзины (rate_limiter.key_template), nil - ключом служит ID клиента.
+	keyParts []config.KeyPart
+	// deniedUntil - кэш отказов: clientID -> denial со временем, до которого запросы клиента отклоняются
+	// без блокировки корзины и обращения к хранилищу.
+	deniedUntil sync.Map
+	// gossip - обмен расходом токенов с другими экземплярами (rate_limiter.gossip), nil - выключен.
+	gossip *gossip
+	// plans - планы, на которые клиенты переводятся после пробного периода (rate_limiter.templates).
+	plans atomic.Pointer[map[string]config.ClientRateConfig]
 
 	// Поля для фонового пополнения
 	ticker *time.Ticker
 	quit   chan struct{}
+	wg     sync.WaitGroup // Отслеживает фоновые горутины (см. Wait)
 }
 
 func New(cfg *config.RateLimiterConfig, store StoreConfigInterface) (*RateLimiter, error) {
 	if !cfg.Enabled {
-		log.Println("[RateLimiter] Выключен.")
+		i18n.Logf(i18n.RLDisabled)
 		return NewDisabled(), nil
 	}
 
 	if store == nil {
-		log.Printf("[Warning][RateLimiter] Rate limiter включен, но хранилище (store) не предоставлено. Будут использоваться только дефолтные лимиты.")
+		i18n.Logf(i18n.RLNoStore)
 	}
 
 	rl := &RateLimiter{
-		store:            store,
-		buckets:          make(map[string]*TokenBucket),
-		defaultRate:      cfg.DefaultRate,
-		defaultCapacity:  cfg.DefaultCapacity,
-		identifierHeader: cfg.IdentifierHeader,
-		quit:             make(chan struct{}),
-		enabled:          true,
+		store:               store,
+		buckets:             make(map[string]*TokenBucket),
+		defaultRate:         cfg.DefaultRate,
+		defaultCapacity:     cfg.DefaultCapacity,
+		ipDefaults:          resolveDefaults(cfg.DefaultRateIP, cfg.DefaultCapacityIP, cfg),
+		headerDefaults:      resolveDefaults(cfg.DefaultRateHeader, cfg.DefaultCapacityHeader, cfg),
+		identifierHeader:    cfg.IdentifierHeader,
+		fingerprintIdentity: cfg.TLSFingerprintIdentity,
+		trustedProxies:      cfg.TrustedProxyPrefixes,
+		quit:                make(chan struct{}),
+		enabled:             true,
+		slidingWindow:       cfg.Algorithm == config.RateLimitSlidingWindow,
+		failClosed:          cfg.StoreFailurePolicy == config.StoreFailClosed,
+		storeTimeout:        cfg.StoreTimeout,
+		softLimitRatio:      cfg.SoftLimitRatio,
+		denialCacheTTL:      cfg.DenialCacheTTL,
+		limitDecreaseWindow: cfg.LimitDecreaseWindow,
+		idleBucketTTL:       cfg.IdleBucketTTL,
+		maxBuckets:          cfg.MaxBuckets,
+		inFlight:            inFlightCounter{max: cfg.MaxInFlightPerClient, clients: make(map[string]int)},
+		ipv6PrefixLength:    cfg.IPv6PrefixLength,
+	}
+	if cfg.Global.Enabled() {
+		rl.global = &TokenBucket{rate: cfg.Global.Rate, capacity: cfg.Global.Capacity, tokens: cfg.Global.Capacity}
+		if rl.slidingWindow {
+			rl.global.window = &slidingWindow{}
+		}
+		rl.global.refill()
+	}
+	if cfg.APIKeys.Enabled {
+		if keyStore, ok := store.(APIKeyStore); ok {
+			rl.apiKeys = &apiKeys{store: keyStore, header: cfg.APIKeys.Header, ttl: cfg.APIKeys.CacheTTL}
+		} else {
+			i18n.Logf(i18n.RLAPIKeysNoStore)
+		}
+	}
+	// Шаблон "{client_id}" совпадает с поведением по умолчанию
+	if len(cfg.KeyParts) != 1 || cfg.KeyParts[0].Attr != config.KeyAttrClientID {
+		rl.keyParts = cfg.KeyParts
 	}
 
-	logMsg := fmt.Sprintf("[RateLimiter] Инициализирован (Store: %T). Default Rate=%.2f/sec, Default Capacity=%.2f", store, cfg.DefaultRate, cfg.DefaultCapacity)
+	logMsg := i18n.T(i18n.RLInitialized,
+		store, cfg.DefaultRate, cfg.DefaultCapacity,
+		rl.ipDefaults.Rate, rl.ipDefaults.Capacity, rl.headerDefaults.Rate, rl.headerDefaults.Capacity)
 	if rl.identifierHeader != "" {
-		logMsg += fmt.Sprintf(". Идентификация клиента по заголовку: '%s' (fallback на IP)", rl.identifierHeader)
+		logMsg += i18n.T(i18n.RLIdentifyByHeader, rl.identifierHeader)
+	} else if rl.apiKeys != nil {
+		logMsg += i18n.T(i18n.RLIdentifyByAPIKey, rl.apiKeys.header)
+	} else {
+		logMsg += i18n.T(i18n.RLIdentifyByIP)
+	}
+	if rl.fingerprintIdentity {
+		logMsg += i18n.T(i18n.RLIdentifyByFingerprint)
+	}
+	if cfg.KeyTemplate != "" {
+		logMsg += i18n.T(i18n.RLKeyTemplate, cfg.KeyTemplate)
+	}
+	if rl.failClosed {
+		logMsg += i18n.T(i18n.RLFailurePolicy, config.StoreFailClosed)
 	} else {
-		logMsg += ". Идентификация клиента по IP-адресу."
+		logMsg += i18n.T(i18n.RLFailurePolicy, config.StoreFailOpen)
 	}
-	log.Println(logMsg)
+	if rl.slidingWindow {
+		logMsg += i18n.T(i18n.RLAlgorithm, config.RateLimitSlidingWindow)
+	}
+	if rl.global != nil {
+		logMsg += i18n.T(i18n.RLGlobalLimit, cfg.Global.Rate, cfg.Global.Capacity)
+	}
+	i18n.LogText(i18n.RLInitialized, logMsg)
 
-	rl.ticker = time.NewTicker(1 * time.Second)
+	rl.ticker = time.NewTicker(RefillInterval)
+	rl.wg.Add(1)
 	go rl.backgroundRefiller()
-	log.Printf("[RateLimiter] Запущено фоновое пополнение корзин (каждую секунду).")
+	i18n.Logf(i18n.RLRefillerStarted)
+
+	rl.SetPlans(cfg.Templates)
+	if trialStore, ok := store.(TrialStore); ok {
+		rl.wg.Add(1)
+		go rl.trialSweeper(trialStore)
+	}
+
+	if cfg.Gossip.Enabled() {
+		if err := rl.startGossip(cfg.Gossip); err != nil {
+			rl.Stop()
+			return nil, err
+		}
+	}
 
 	return rl, nil
 }
 
+// resolveDefaults возвращает лимиты по умолчанию для типа идентификатора,
+// подставляя общие default_rate/default_capacity вместо незаданных (нулевых) значений.
+func resolveDefaults(rate, capacity float64, cfg *config.RateLimiterConfig) config.ClientRateConfig {
+	if rate <= 0 {
+		rate = cfg.DefaultRate
+	}
+	if capacity <= 0 {
+		capacity = cfg.DefaultCapacity
+	}
+	return config.ClientRateConfig{Rate: rate, Capacity: capacity}
+}
+
+// defaultsFor возвращает лимиты по умолчанию для клиента в зависимости от способа идентификации:
+// anonymous - клиент определен по IP-адресу или отпечатку TLS (см. IDSource.Anonymous).
+// Для составных ключей (key_template) действуют общие default_rate/default_capacity.
+func (rl *RateLimiter) defaultsFor(anonymous bool) (config.ClientRateConfig, string) {
+	if rl.keyParts != nil {
+		return config.ClientRateConfig{Rate: rl.defaultRate, Capacity: rl.defaultCapacity}, i18n.T(i18n.RLSourceKeyDefaults)
+	}
+	if anonymous {
+		return rl.ipDefaults, i18n.T(i18n.RLSourceIPDefaults)
+	}
+	return rl.headerDefaults, i18n.T(i18n.RLSourceHeaderDefaults)
+}
+
 // NewDisabled создает "выключенный" экземпляр RateLimiter, который всегда разрешает запросы.
 func NewDisabled() *RateLimiter {
 	return &RateLimiter{
@@ -111,23 +295,61 @@ func (rl *RateLimiter) Stop() {
 	if rl.ticker != nil {
 		rl.ticker.Stop() // Останавливаем тикер
 		close(rl.quit)   // Закрываем канал, чтобы сигнализировать горутине
-		log.Printf("[RateLimiter] Фоновое пополнение остановлено.")
+		if rl.gossip != nil {
+			rl.gossip.conn.Close() // Прерываем прием сообщений обмена
+		}
+		i18n.Logf(i18n.RLRefillerStopped)
+	}
+}
+
+// Wait ожидает завершения фоновых горутин после Stop.
+// Возвращает ошибку контекста, если горутины не завершились до его отмены.
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		rl.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-// backgroundRefiller - горутина, периодически пополняющая все активные корзины.
+// backgroundRefiller - горутина, периодически пополняющая все активные корзины
+// и удаляющая лишние (см. evictBuckets).
 func (rl *RateLimiter) backgroundRefiller() {
+	defer rl.wg.Done()
 	for {
 		select {
 		case <-rl.ticker.C: // Ждем сигнала от тикера
+			now := time.Now()
+			var idle []string
+			var lru []lruEntry
 			// Проходим по всем существующим корзинам и пополняем их
 			rl.mu.RLock() // Блокируем карту buckets на чтение
-			for _, bucket := range rl.buckets {
-				bucket.mu.Lock()   // Блокируем конкретную корзину на запись
-				bucket.refill()    // Вызываем пополнение
+			overCap := rl.maxBuckets > 0 && len(rl.buckets) > rl.maxBuckets
+			for key, bucket := range rl.buckets {
+				bucket.mu.Lock() // Блокируем конкретную корзину на запись
+				bucket.refill()  // Вызываем пополнение
+				if rl.idleBucketTTL > 0 && bucket.idle(now, rl.idleBucketTTL) {
+					idle = append(idle, key)
+				} else if overCap {
+					lru = append(lru, lruEntry{key: key, lastUsed: bucket.lastUsed})
+				}
 				bucket.mu.Unlock() // Разблокируем корзину
 			}
 			rl.mu.RUnlock() // Разблокируем карту
+			if rl.global != nil {
+				rl.global.mu.Lock()
+				rl.global.refill()
+				rl.global.mu.Unlock()
+			}
+			rl.evictBuckets(now, idle, lru)
+			rl.purgeDenialCache()
+			rl.purgeAPIKeyCache()
 
 		case <-rl.quit: // Ждем сигнала на выход
 			// Получен сигнал завершения
@@ -140,6 +362,11 @@ func (rl *RateLimiter) backgroundRefiller() {
 // Должен вызываться под мьютексом bucket.mu.
 func (tb *TokenBucket) refill() {
 	now := time.Now()
+	tb.advanceRollout(now)
+	if tb.window != nil {
+		tb.slide(now)
+		return
+	}
 	// Если lastRefill еще не установлен (нулевое время), считаем, что пополнение начинается сейчас
 	if tb.lastRefill.IsZero() {
 		tb.lastRefill = now
@@ -156,26 +383,102 @@ func (tb *TokenBucket) refill() {
 	tb.lastRefill = now // Обновляем время ТОЛЬКО после успешного добавления
 }
 
-// updateBucketIfNeeded обновляет параметры rate и capacity существующей корзины, если они отличаются от переданных.
-// Должен вызываться под блокировкой bucket.mu.
-func updateBucketIfNeeded(bucket *TokenBucket, newRate, newCapacity float64, clientID, source string) {
-	rateChanged := bucket.rate != newRate
-	capacityChanged := bucket.capacity != newCapacity
-
-	if rateChanged || capacityChanged {
-		log.Printf("[RateLimiter] Обновление лимитов для '%s' (источник: %s): Rate: %.2f -> %.2f, Capacity: %.2f -> %.2f",
-			clientID, source, bucket.rate, newRate, bucket.capacity, newCapacity)
-		bucket.rate = newRate
-		bucket.capacity = newCapacity
-		if bucket.tokens > bucket.capacity {
-			bucket.tokens = bucket.capacity
-		}
+// withStoreTimeout выполняет обращение к хранилищу с учетом storeTimeout.
+// При превышении таймаута возвращает errStoreTimeout; сам вызов продолжает выполняться в фоне.
+func (rl *RateLimiter) withStoreTimeout(call func() error) error {
+	if rl.storeTimeout <= 0 {
+		return call()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- call()
+	}()
+
+	timer := time.NewTimer(rl.storeTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return errStoreTimeout
 	}
 }
 
+// fetchLimitConfig получает конфигурацию лимита клиента из хранилища с учетом таймаута.
+// Ошибки и таймауты учитываются в метрике ratelimiter_store_errors_total.
+func (rl *RateLimiter) fetchLimitConfig(clientID string) (rate, capacity float64, found bool, err error) {
+	err = rl.withStoreTimeout(func() error {
+		var callErr error
+		rate, capacity, found, callErr = rl.store.GetClientLimitConfig(clientID)
+		return callErr
+	})
+	if err != nil {
+		storeErrorsTotal.Inc()
+		return 0, 0, false, err
+	}
+	return rate, capacity, found, nil
+}
+
+// fetchSavedState получает сохраненное состояние корзины клиента с учетом таймаута.
+func (rl *RateLimiter) fetchSavedState(stateStore StateStore, clientID string) (tokens float64, lastRefill time.Time, found bool, err error) {
+	err = rl.withStoreTimeout(func() error {
+		var callErr error
+		tokens, lastRefill, found, callErr = stateStore.GetClientSavedState(clientID)
+		return callErr
+	})
+	if err != nil {
+		storeErrorsTotal.Inc()
+		return 0, time.Time{}, false, err
+	}
+	return tokens, lastRefill, found, nil
+}
+
+// storeFailure применяет политику store_failure_policy к ошибке хранилища:
+// при fail_closed возвращает ErrStoreUnavailable, при fail_open - nil.
+func (rl *RateLimiter) storeFailure(err error) error {
+	if rl.failClosed {
+		return fmt.Errorf("%w: %v", ErrStoreUnavailable, err)
+	}
+	return nil
+}
+
+// refreshBucketLimits сверяет лимиты существующей корзины с хранилищем и обновляет их при необходимости.
+// source дополняет описание источника в логах (например, для повторной проверки).
+func (rl *RateLimiter) refreshBucketLimits(bucket *TokenBucket, clientID, source string) error {
+	if rl.store == nil {
+		// Store не задан - оставляем текущие значения корзины
+		return nil
+	}
+
+	dbRate, dbCapacity, configFound, configErr := rl.fetchLimitConfig(clientID)
+	if configErr != nil {
+		i18n.Logf(i18n.RLExistingConfigFailed, clientID, source, configErr)
+		// В случае ошибки оставляем текущие rate/capacity корзины
+		return rl.storeFailure(configErr)
+	}
+
+	configSource := i18n.T(i18n.RLSourceStore)
+	if !configFound {
+		defaults, defaultsSource := rl.defaultsFor(bucket.anonymous)
+		configSource = defaultsSource + i18n.T(i18n.RLSourceNotInStore)
+		dbRate = defaults.Rate
+		dbCapacity = defaults.Capacity
+	}
+
+	bucket.mu.Lock()
+	rl.updateBucketIfNeeded(bucket, dbRate, dbCapacity, clientID, configSource+source)
+	bucket.mu.Unlock()
+	return nil
+}
+
 // getOrCreateBucket находит или создает корзину токенов в памяти для клиента,
-// загружая начальное состояние из хранилища, если оно доступно.
-func (rl *RateLimiter) getOrCreateBucket(clientID string) *TokenBucket {
+// загружая начальное состояние из хранилища, если оно доступно. source - как определен клиент:
+// от него зависят лимиты по умолчанию новой корзины.
+// Возвращает ErrStoreUnavailable, если хранилище недоступно и действует политика fail_closed;
+// в этом случае новая корзина не создается, чтобы следующий запрос повторил обращение к хранилищу.
+func (rl *RateLimiter) getOrCreateBucket(clientID string, source IDSource) (*TokenBucket, error) {
 	// 1. Поиск существующей корзины в памяти (под RLock)
 	rl.mu.RLock()
 	bucket, exists := rl.buckets[clientID]
@@ -184,42 +487,7 @@ func (rl *RateLimiter) getOrCreateBucket(clientID string) *TokenBucket {
 	if exists {
 		// Корзина найдена. Ее состояние (токены, время) актуально, т.к. управляется в памяти.
 		// Но ее лимиты (rate, capacity) могли измениться в БД. Проверим и обновим их.
-		var dbRate, dbCapacity float64
-		var configFound bool
-		var configErr error
-		configSource := "дефолтными"
-
-		if rl.store != nil {
-			dbRate, dbCapacity, configFound, configErr = rl.store.GetClientLimitConfig(clientID)
-			if configErr != nil {
-				log.Printf("[RateLimiter] Ошибка получения конфига лимита для существующего клиента '%s', используются текущие. Ошибка: %v", clientID, configErr)
-				// В случае ошибки оставляем текущие rate/capacity корзины
-				bucket.mu.Lock() // Блокируем только для чтения текущих значений
-				dbRate = bucket.rate
-				dbCapacity = bucket.capacity
-				bucket.mu.Unlock()
-				configSource = "текущими (ошибка БД)"
-			} else if configFound {
-				configSource = "хранилища"
-			} else {
-				configSource = "дефолтными (не найден в хранилище)"
-				dbRate = rl.defaultRate
-				dbCapacity = rl.defaultCapacity
-			}
-		} else {
-			// Store не задан, используем дефолтные (хотя корзина уже есть?)
-			// Логичнее оставить текущие значения корзины
-			bucket.mu.Lock()
-			dbRate = bucket.rate
-			dbCapacity = bucket.capacity
-			bucket.mu.Unlock()
-			configSource = "текущими (store=nil)"
-		}
-
-		bucket.mu.Lock()
-		updateBucketIfNeeded(bucket, dbRate, dbCapacity, clientID, configSource)
-		bucket.mu.Unlock()
-		return bucket
+		return bucket, rl.refreshBucketLimits(bucket, clientID, "")
 	}
 
 	// --- Корзины в памяти не было, создаем новую ---
@@ -228,95 +496,72 @@ func (rl *RateLimiter) getOrCreateBucket(clientID string) *TokenBucket {
 	bucket, exists = rl.buckets[clientID]
 	if exists {
 		rl.mu.Unlock()
-		// Повторно обновляем лимиты, как в блоке if exists выше
-		// Код немного дублируется, но это проще, чем выносить в отдельную функцию
-		var dbRate, dbCapacity float64
-		var configFound bool
-		var configErr error
-		configSource := "дефолтными"
-		if rl.store != nil {
-			dbRate, dbCapacity, configFound, configErr = rl.store.GetClientLimitConfig(clientID)
-			if configErr != nil {
-				log.Printf("[RateLimiter] Ошибка получения конфига лимита для существующего клиента '%s' (повторно), используются текущие. Ошибка: %v", clientID, configErr)
-				bucket.mu.Lock()
-				dbRate = bucket.rate
-				dbCapacity = bucket.capacity
-				bucket.mu.Unlock()
-				configSource = "текущими (ошибка БД)"
-			} else if configFound {
-				configSource = "хранилища"
-			} else {
-				configSource = "дефолтными (не найден в хранилище)"
-				dbRate = rl.defaultRate
-				dbCapacity = rl.defaultCapacity
-			}
-		} else {
-			bucket.mu.Lock()
-			dbRate = bucket.rate
-			dbCapacity = bucket.capacity
-			bucket.mu.Unlock()
-			configSource = "текущими (store=nil)"
-		}
-		bucket.mu.Lock()
-		updateBucketIfNeeded(bucket, dbRate, dbCapacity, clientID, configSource+" (повторная проверка)")
-		bucket.mu.Unlock()
-		return bucket
+		return bucket, rl.refreshBucketLimits(bucket, clientID, i18n.T(i18n.RLSourceRecheck))
 	}
 
 	// --- Действительно создаем новую корзину ---
 
 	// 2. Получаем конфигурацию (rate, capacity)
-	initialRate := rl.defaultRate
-	initialCapacity := rl.defaultCapacity
-	configSource := "дефолтными"
+	defaults, defaultsSource := rl.defaultsFor(source.Anonymous())
+	initialRate := defaults.Rate
+	initialCapacity := defaults.Capacity
+	configSource := defaultsSource
 	if rl.store != nil {
-		dbRate, dbCapacity, configFound, configErr := rl.store.GetClientLimitConfig(clientID)
+		dbRate, dbCapacity, configFound, configErr := rl.fetchLimitConfig(clientID)
 		if configErr != nil {
-			log.Printf("[RateLimiter] Ошибка получения конфига лимита для нового клиента '%s', используются дефолтные. Ошибка: %v", clientID, configErr)
+			i18n.Logf(i18n.RLNewConfigFailed, clientID, configErr)
+			if err := rl.storeFailure(configErr); err != nil {
+				rl.mu.Unlock()
+				return nil, err
+			}
 			// Оставляем дефолтные initialRate, initialCapacity
 		} else if configFound {
 			initialRate = dbRate
 			initialCapacity = dbCapacity
-			configSource = "хранилища"
+			configSource = i18n.T(i18n.RLSourceStore)
 		} else {
-			configSource = "дефолтными (не найден в хранилище)"
+			configSource = defaultsSource + i18n.T(i18n.RLSourceNotInStore)
 		}
 	}
 
 	// 3. Получаем сохраненное состояние (tokens, lastRefill), если store поддерживает это.
 	initialTokens := initialCapacity // По умолчанию - полная корзина
 	initialLastRefill := time.Time{} // По умолчанию - нулевое время (refill начнется с now)
-	stateSource := "начальное (полная корзина, время=0)"
+	stateSource := i18n.T(i18n.RLStateInitial)
 
 	// Проверяем поддержку сохранения и делаем type assertion на StateStore
 	if rl.store != nil && rl.store.SupportsStatePersistence() {
 		stateStore, ok := rl.store.(StateStore)
 		if !ok {
 			// Это не должно происходить, если SupportsStatePersistence == true
-			log.Printf("[Error][RateLimiter] Store (%T) сообщает о поддержке состояния, но не реализует StateStore!", rl.store)
+			i18n.Logf(i18n.RLNotStateStore, rl.store)
 		} else {
 			// Используем интерфейс StateStore для доступа к методам
-			savedTokens, savedLastRefill, stateFound, stateErr := stateStore.GetClientSavedState(clientID)
+			savedTokens, savedLastRefill, stateFound, stateErr := rl.fetchSavedState(stateStore, clientID)
 			if stateErr != nil {
-				log.Printf("[RateLimiter] Ошибка получения сохраненного состояния для нового клиента '%s', используется начальное. Ошибка: %v", clientID, stateErr)
+				i18n.Logf(i18n.RLNewStateFailed, clientID, stateErr)
+				if err := rl.storeFailure(stateErr); err != nil {
+					rl.mu.Unlock()
+					return nil, err
+				}
 				// Оставляем начальные initialTokens, initialLastRefill
 			} else if stateFound {
 				initialTokens = savedTokens
 				initialLastRefill = savedLastRefill // Используем сохраненное время
-				stateSource = "сохраненное из БД"
+				stateSource = i18n.T(i18n.RLStateSaved)
 				// Обрезаем токены по загруженной емкости
 				if initialTokens > initialCapacity {
 					initialTokens = initialCapacity
 				}
 			} else {
-				stateSource = "начальное (не найдено в БД)"
+				stateSource = i18n.T(i18n.RLStateNotInDB)
 			}
 		}
 	} else if rl.store != nil {
-		log.Printf("[RateLimiter] Хранилище (%T) не поддерживает сохранение состояния для '%s'. Используется начальное.", rl.store, clientID)
+		i18n.Logf(i18n.RLNoStateSupport, rl.store, clientID)
 	}
 
-	log.Printf("[RateLimiter] Создается новая корзина для клиента '%s'. Конфиг: %s (Rate=%.2f, Capacity=%.2f). Состояние: %s (Tokens=%.2f, LastRefill=%v)",
+	i18n.Logf(i18n.RLBucketCreating,
 		clientID, configSource, initialRate, initialCapacity, stateSource, initialTokens, initialLastRefill)
 
 	newBucket := &TokenBucket{
@@ -324,6 +569,12 @@ func (rl *RateLimiter) getOrCreateBucket(clientID string) *TokenBucket {
 		rate:       initialRate,
 		tokens:     initialTokens,
 		lastRefill: initialLastRefill, // Может быть time.Time{}
+		lastUsed:   time.Now(),
+		anonymous:  source.Anonymous(),
+	}
+	if rl.slidingWindow {
+		// Сохраненный расход считается расходом интервала, начавшегося в момент сохранения
+		newBucket.window = &slidingWindow{start: initialLastRefill, current: initialCapacity - initialTokens}
 	}
 
 	// 4. Выполняем первоначальное пополнение, если lastRefill было загружено из БД
@@ -336,40 +587,368 @@ func (rl *RateLimiter) getOrCreateBucket(clientID string) *TokenBucket {
 
 	rl.buckets[clientID] = newBucket
 	rl.mu.Unlock() // Разблокируем карту buckets ПОСЛЕ добавления
+	bucketsCreatedTotal.Inc()
 
-	log.Printf("[RateLimiter] Корзина для '%s' создана и инициализирована. Текущее состояние: Tokens=%.2f, LastRefill=%v",
+	i18n.Logf(i18n.RLBucketCreated,
 		clientID, currentTokens, currentLastRefill)
 
-	return newBucket
+	return newBucket, nil
 }
 
 // Маленькое значение для сравнения float
 const floatEpsilon = 1e-9
 
 // Allow проверяет, разрешен ли запрос от данного клиента.
+// Ошибка хранилища при политике fail_closed трактуется как отказ.
 func (rl *RateLimiter) Allow(clientID string) bool {
+	allowed, _ := rl.Check(clientID)
+	return allowed
+}
+
+// Check проверяет, разрешен ли запрос от данного клиента, и расходует токен при успехе.
+// Возвращает ErrStoreUnavailable, если хранилище недоступно и действует политика fail_closed.
+func (rl *RateLimiter) Check(clientID string) (bool, error) {
+	allowed, _, err := rl.CheckSoft(clientID)
+	return allowed, err
+}
+
+// CheckSoft работает как Check и дополнительно возвращает предупреждение, если пропущенный запрос
+// превысил мягкий порог rate_limiter.soft_limit_ratio (значение для заголовка X-RateLimit-Warning).
+// Пустая строка означает, что порог не превышен.
+func (rl *RateLimiter) CheckSoft(clientID string) (bool, string, error) {
+	return rl.CheckCost(clientID, 1)
+}
+
+// CheckCost работает как CheckSoft для запроса стоимостью cost токенов (см. admission_hook):
+// запрос пропускается, если в корзине есть cost токенов, и они списываются. Способ идентификации
+// клиента определяется по виду ID (см. SourceOf); при обработке запроса используется Decide.
+func (rl *RateLimiter) CheckCost(clientID string, cost float64) (bool, string, error) {
+	decision, err := rl.Decide(clientID, SourceOf(clientID), cost)
+	return decision.Allowed, decision.Warning, err
+}
+
+// Decision - решение по запросу вместе с состоянием корзины клиента после него (заголовки X-RateLimit-*).
+type Decision struct {
+	Allowed bool
+	// Warning - предупреждение о превышении мягкого порога (X-RateLimit-Warning), пусто - порог не превышен.
+	Warning string
+	// Limit - емкость корзины, то есть сколько запросов клиент может отправить подряд; 0 - лимит не проверялся
+	// (Rate Limiter выключен).
+	Limit float64
+	// Remaining - токенов в корзине после запроса.
+	Remaining float64
+	// Reset - через сколько корзина пополнится до емкости; 0 - корзина полна или не пополняется.
+	Reset time.Duration
+	// RetryAfter - для отклоненного запроса: через сколько в корзине наберется токенов на него.
+	RetryAfter time.Duration
+	// Global - запрос отклонен общим лимитом rate_limiter.global; Limit, Remaining и Reset не заполнены.
+	Global bool
+}
+
+// Decide работает как CheckCost и дополнительно возвращает емкость корзины, остаток токенов и время
+// до пополнения. source - как определен клиент (см. ClientIdentity).
+func (rl *RateLimiter) Decide(clientID string, source IDSource, cost float64) (Decision, error) {
 	if !rl.enabled {
-		return true
+		return Decision{Allowed: true}, nil
 	}
 
-	bucket := rl.getOrCreateBucket(clientID)
+	// Клиент недавно получил отказ и токен еще не мог накопиться: отвечаем сразу
+	if denied, ok := rl.cachedDenial(clientID); ok {
+		denialCacheHitsTotal.Inc()
+		deniedTotal.Inc()
+		retryAfter := time.Until(time.Unix(0, denied.until))
+		return Decision{Limit: denied.limit, Reset: denied.reset, RetryAfter: retryAfter}, nil
+	}
+
+	// Общий лимит проверяется до корзины клиента: при перегрузке корзины клиентов не расходуются
+	if denied, ok := rl.takeGlobal(clientID, cost); !ok {
+		return denied, nil
+	}
+
+	bucket, err := rl.getOrCreateBucket(clientID, source)
+	if err != nil {
+		rl.refundGlobal(cost)
+		storeFailClosedTotal.Inc()
+		i18n.Logf(i18n.RLRejectedStoreError, clientID, err)
+		return Decision{}, err
+	}
 
 	bucket.mu.Lock()
 	defer bucket.mu.Unlock()
+	bucket.lastUsed = time.Now()
 
 	// Пополнение происходит в фоне тикером, здесь его вызывать не нужно.
 
-	log.Printf("[RateLimiter] Проверка для '%s': %.2f токенов доступно (лимиты: rate=%.2f, capacity=%.2f)",
+	i18n.Logf(i18n.RLCheck,
 		clientID, bucket.tokens, bucket.rate, bucket.capacity)
 
 	// Используем сравнение с эпсилон для float
-	if bucket.tokens >= 1.0-floatEpsilon {
-		bucket.tokens--
+	if bucket.tokens >= cost-floatEpsilon {
+		bucket.consume(cost)
+		allowedTotal.Inc()
+		if rl.gossip != nil {
+			rl.gossip.recordConsumption(clientID, cost)
+		}
+		tokensRemaining.Observe(bucket.tokens)
+		decision := bucket.decision(cost)
+		decision.Allowed = true
+		decision.Warning = rl.softLimitWarning(bucket, clientID)
+		return decision, nil
+	}
+
+	i18n.Logf(i18n.RLRejected, clientID)
+	deniedTotal.Inc()
+	rl.refundGlobal(cost)
+	tokensRemaining.Observe(bucket.tokens)
+	// Отказ дорогому запросу не означает отказа обычному: в кэш попадают только отказы при нехватке одного токена
+	if cost <= 1 {
+		rl.cacheDenial(bucket, clientID)
+	}
+	return bucket.decision(cost), nil
+}
+
+// decision возвращает состояние корзины для Decision; RetryAfter - время до cost токенов.
+// Должен вызываться под блокировкой bucket.mu.
+func (tb *TokenBucket) decision(cost float64) Decision {
+	return Decision{
+		Limit:      tb.capacity,
+		Remaining:  max(tb.tokens, 0),
+		Reset:      tb.untilTokens(tb.capacity),
+		RetryAfter: tb.untilTokens(cost),
+	}
+}
+
+// untilTokens возвращает, через сколько в корзине будет n токенов при текущей скорости пополнения
+// (со скользящим окном - по мере выхода расхода из окна); 0 - уже есть или корзина не пополняется. Должен вызываться под блокировкой bucket.mu.
+func (tb *TokenBucket) untilTokens(n float64) time.Duration {
+	if tb.window != nil {
+		return tb.untilWindowTokens(n)
+	}
+	if tb.tokens >= n-floatEpsilon || tb.rate <= 0 {
+		return 0
+	}
+	return time.Duration((n - tb.tokens) / tb.rate * float64(time.Second))
+}
+
+// denial - запомненный отказ клиенту (см. cacheDenial) с состоянием корзины для заголовков X-RateLimit-*.
+type denial struct {
+	until int64         // UnixNano, до которого запросы клиента отклоняются.
+	limit float64       // Емкость корзины.
+	reset time.Duration // Время до пополнения корзины на момент отказа.
+}
+
+// cacheDenial запоминает отказ клиенту до момента, когда в корзине может появиться токен,
+// но не дольше denialCacheTTL. Должен вызываться под блокировкой bucket.mu.
+func (rl *RateLimiter) cacheDenial(bucket *TokenBucket, clientID string) {
+	if rl.denialCacheTTL <= 0 {
+		return
+	}
+	ttl := rl.denialCacheTTL
+	if bucket.rate > 0 {
+		// Время до появления целого токена при текущей скорости пополнения
+		if untilToken := bucket.untilTokens(1); untilToken < ttl {
+			ttl = untilToken
+		}
+	}
+	if ttl > 0 {
+		rl.deniedUntil.Store(clientID, denial{
+			until: time.Now().Add(ttl).UnixNano(),
+			limit: bucket.capacity,
+			reset: bucket.untilTokens(bucket.capacity),
+		})
+	}
+}
+
+// cachedDenial возвращает действующий запомненный отказ клиенту; истекший отказ удаляется.
+func (rl *RateLimiter) cachedDenial(clientID string) (denial, bool) {
+	if rl.denialCacheTTL <= 0 {
+		return denial{}, false
+	}
+	value, ok := rl.deniedUntil.Load(clientID)
+	if !ok {
+		return denial{}, false
+	}
+	if denied := value.(denial); time.Now().UnixNano() < denied.until {
+		return denied, true
+	}
+	rl.deniedUntil.CompareAndDelete(clientID, value)
+	return denial{}, false
+}
+
+// purgeDenialCache удаляет истекшие отказы клиентов, которые больше не присылали запросов.
+func (rl *RateLimiter) purgeDenialCache() {
+	now := time.Now().UnixNano()
+	rl.deniedUntil.Range(func(clientID, value any) bool {
+		if now >= value.(denial).until {
+			rl.deniedUntil.CompareAndDelete(clientID, value)
+		}
 		return true
+	})
+}
+
+// softLimitWarning возвращает предупреждение, если клиент израсходовал больше softLimitRatio емкости корзины.
+// Должен вызываться под блокировкой bucket.mu.
+func (rl *RateLimiter) softLimitWarning(bucket *TokenBucket, clientID string) string {
+	if rl.softLimitRatio <= 0 || bucket.tokens > bucket.capacity*(1-rl.softLimitRatio) {
+		return ""
 	}
+	softLimitWarningsTotal.Inc()
+	i18n.Logf(i18n.RLSoftLimitExceeded, clientID, bucket.tokens, bucket.capacity)
+	return fmt.Sprintf("soft limit exceeded; remaining=%.0f; capacity=%.0f", bucket.tokens, bucket.capacity)
+}
 
-	log.Printf("[RateLimiter] Запрос от '%s' отклонен (лимит превышен)", clientID)
-	return false
+// BucketSummary - сводка по корзинам токенов в памяти (GET /admin/state).
+type BucketSummary struct {
+	Enabled bool `json:"enabled"`
+	Buckets int  `json:"buckets"` // Клиентов с корзиной в памяти.
+	// Exhausted - корзины, в которых нет целого токена (следующий запрос клиента получит 429).
+	Exhausted int `json:"exhausted"`
+	// AverageFill - средняя доля заполнения корзин (1 - все корзины полные).
+	AverageFill  float64 `json:"average_fill"`
+	DeniedCached int     `json:"denied_cached"` // Клиентов в кэше отказов.
+}
+
+// RegisterMetrics регистрирует в реестре metrics показатель ratelimiter_active_buckets - число корзин
+// токенов в памяти. Повторная регистрация (например, другого Rate Limiter) заменяет прежнюю.
+func (rl *RateLimiter) RegisterMetrics() {
+	metrics.RegisterCollector("ratelimiter", func() []metrics.Family {
+		rl.mu.RLock()
+		buckets := len(rl.buckets)
+		rl.mu.RUnlock()
+		return []metrics.Family{{
+			Name:    "ratelimiter_active_buckets",
+			Help:    "Количество корзин токенов в памяти (клиентов, обращавшихся к балансировщику).",
+			Type:    metrics.TypeGauge,
+			Samples: []metrics.Sample{{Value: float64(buckets)}},
+		}}
+	})
+}
+
+// Summary возвращает сводку по корзинам токенов в памяти.
+func (rl *RateLimiter) Summary() BucketSummary {
+	summary := BucketSummary{Enabled: rl.enabled}
+
+	rl.mu.RLock()
+	summary.Buckets = len(rl.buckets)
+	var fill float64
+	for _, bucket := range rl.buckets {
+		bucket.mu.Lock()
+		if bucket.tokens < 1.0-floatEpsilon {
+			summary.Exhausted++
+		}
+		if bucket.capacity > 0 {
+			fill += bucket.tokens / bucket.capacity
+		}
+		bucket.mu.Unlock()
+	}
+	rl.mu.RUnlock()
+	if summary.Buckets > 0 {
+		summary.AverageFill = fill / float64(summary.Buckets)
+	}
+
+	now := time.Now().UnixNano()
+	rl.deniedUntil.Range(func(_, value any) bool {
+		if now < value.(denial).until {
+			summary.DeniedCached++
+		}
+		return true
+	})
+	return summary
+}
+
+// ClientSnapshot - корзина клиента в снимке Rate Limiter.
+type ClientSnapshot struct {
+	Key      string  `json:"key"` // Ключ корзины: ID клиента или ключ по rate_limiter.key_template.
+	Tokens   float64 `json:"tokens"`
+	Capacity float64 `json:"capacity"`
+	Rate     float64 `json:"rate"` // Токенов в секунду.
+	// TargetRate, TargetCapacity и RolloutUntil описывают плавное снижение лимитов (limit_decrease_window):
+	// к каким значениям и к какому моменту придут rate и capacity. Пусто, если снижения нет.
+	TargetRate     *float64   `json:"target_rate,omitempty"`
+	TargetCapacity *float64   `json:"target_capacity,omitempty"`
+	RolloutUntil   *time.Time `json:"rollout_until,omitempty"`
+	// DeniedUntil - до этого момента запросы клиента отклоняются из кэша отказов; пусто, если отказа нет.
+	DeniedUntil *time.Time `json:"denied_until,omitempty"`
+}
+
+// Snapshot - снимок Rate Limiter: сводка и корзины клиентов в памяти.
+type Snapshot struct {
+	BucketSummary
+	Clients []ClientSnapshot `json:"clients"` // Отсортированы по Key.
+}
+
+// Snapshot возвращает снимок корзин токенов в памяти: сводку и состояние корзины каждого клиента
+// (токены на момент вызова). Снимок - копия, не связанная с Rate Limiter. В отличие от Summary,
+// размер снимка растет с числом клиентов.
+func (rl *RateLimiter) Snapshot() Snapshot {
+	snapshot := Snapshot{BucketSummary: BucketSummary{Enabled: rl.enabled}}
+
+	rl.mu.RLock()
+	snapshot.Clients = make([]ClientSnapshot, 0, len(rl.buckets))
+	for key, bucket := range rl.buckets {
+		bucket.mu.Lock()
+		bucket.refill()
+		client := ClientSnapshot{
+			Key:      key,
+			Tokens:   bucket.tokens,
+			Capacity: bucket.capacity,
+			Rate:     bucket.rate,
+		}
+		if r := bucket.rollout; r != nil {
+			rate, capacity, until := r.toRate, r.toCapacity, r.start.Add(r.window)
+			client.TargetRate, client.TargetCapacity, client.RolloutUntil = &rate, &capacity, &until
+		}
+		snapshot.Clients = append(snapshot.Clients, client)
+		bucket.mu.Unlock()
+	}
+	rl.mu.RUnlock()
+	sort.Slice(snapshot.Clients, func(i, j int) bool {
+		return snapshot.Clients[i].Key < snapshot.Clients[j].Key
+	})
+
+	snapshot.Buckets = len(snapshot.Clients)
+	var fill float64
+	for _, client := range snapshot.Clients {
+		if client.Tokens < 1.0-floatEpsilon {
+			snapshot.Exhausted++
+		}
+		if client.Capacity > 0 {
+			fill += client.Tokens / client.Capacity
+		}
+	}
+	if snapshot.Buckets > 0 {
+		snapshot.AverageFill = fill / float64(snapshot.Buckets)
+	}
+
+	now := time.Now().UnixNano()
+	rl.deniedUntil.Range(func(key, value any) bool {
+		until := value.(denial).until
+		if now >= until {
+			return true
+		}
+		snapshot.DeniedCached++
+		i := sort.Search(len(snapshot.Clients), func(i int) bool { return snapshot.Clients[i].Key >= key.(string) })
+		if i < len(snapshot.Clients) && snapshot.Clients[i].Key == key.(string) {
+			deniedUntil := time.Unix(0, until)
+			snapshot.Clients[i].DeniedUntil = &deniedUntil
+		}
+		return true
+	})
+	return snapshot
+}
+
+// Bucket возвращает состояние корзины по ключу без расхода токенов и без обращения к хранилищу;
+// found = false, если корзины в памяти нет.
+func (rl *RateLimiter) Bucket(key string) (tokens, capacity, rate float64, found bool) {
+	rl.mu.RLock()
+	bucket, ok := rl.buckets[key]
+	rl.mu.RUnlock()
+	if !ok {
+		return 0, 0, 0, false
+	}
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+	return bucket.tokens, bucket.capacity, bucket.rate, true
 }
 
 // IsEnabled возвращает true, если Rate Limiter включен.
@@ -377,42 +956,194 @@ func (rl *RateLimiter) IsEnabled() bool {
 	return rl.enabled
 }
 
-// GetClientID извлекает идентификатор клиента из HTTP-запроса.
-// Сначала проверяет настроенный заголовок, затем IP-адрес.
-// Возвращает ID клиента как строку.
+// FingerprintIDPrefix - префикс ID клиента, идентифицированного по отпечатку TLS (tls_fingerprint_identity).
+const FingerprintIDPrefix = "ja3:"
+
+// IDSource - способ, которым определен ID клиента (см. ClientIdentity).
+type IDSource int
+
+const (
+	SourceHeader      IDSource = iota // Заголовок rate_limiter.identifier_header
+	SourceAPIKey                      // API-ключ (rate_limiter.api_keys)
+	SourceFingerprint                 // Отпечаток TLS (tls_fingerprint_identity)
+	SourceAddress                     // IP-адрес или IPv6-префикс (ipv6_prefix_length)
+)
+
+// Anonymous сообщает, что клиент не назвал себя, а определен по адресу или отпечатку TLS:
+// такие клиенты получают лимиты по умолчанию default_rate_ip/default_capacity_ip.
+func (s IDSource) Anonymous() bool {
+	return s == SourceFingerprint || s == SourceAddress
+}
+
+// SourceOf определяет способ идентификации по виду ID, полученного не из запроса (Check, API администратора):
+// IP-адрес, IPv6-префикс или отпечаток TLS считаются анонимным клиентом. Значение заголовка может выглядеть
+// так же, поэтому при обработке запроса способ берется из ClientIdentity.
+func SourceOf(clientID string) IDSource {
+	switch {
+	case IsAddressID(clientID):
+		return SourceAddress
+	case strings.HasPrefix(clientID, FingerprintIDPrefix):
+		return SourceFingerprint
+	default:
+		return SourceHeader
+	}
+}
+
+// GetClientID извлекает идентификатор клиента из HTTP-запроса (см. ClientIdentity).
 func (rl *RateLimiter) GetClientID(r *http.Request) string {
-	// 1. Проверяем кастомный заголовок, если он настроен.
+	clientID, _ := rl.ClientIdentity(r)
+	return clientID
+}
+
+// ClientIdentity извлекает идентификатор клиента из HTTP-запроса и возвращает, как он определен.
+// Сначала проверяет API-ключ (если включен rate_limiter.api_keys) или настроенный заголовок,
+// затем отпечаток TLS (если включен tls_fingerprint_identity), затем IP-адрес.
+func (rl *RateLimiter) ClientIdentity(r *http.Request) (string, IDSource) {
+	// 1. API-ключ ищется в хранилище: в отличие от identifier_header, ID клиента нельзя подставить.
+	if rl.apiKeys != nil {
+		if clientID, ok := rl.apiKeyClientID(r); ok {
+			return clientID, SourceAPIKey
+		}
+	}
+
+	// 2. Проверяем кастомный заголовок, если он настроен.
 	if rl.identifierHeader != "" {
 		clientID := r.Header.Get(rl.identifierHeader)
 		if clientID != "" {
 			// Используем значение из заголовка.
-			return clientID
+			return clientID, SourceHeader
+		}
+	}
+
+	// 3. Отпечаток TLS ClientHello не меняется при смене адреса клиентом.
+	if rl.fingerprintIdentity {
+		if fingerprint := sni.Fingerprint(r); fingerprint != "" {
+			return FingerprintIDPrefix + fingerprint, SourceFingerprint
+		}
+	}
+
+	// 4. Если заголовок не настроен или пуст, используем IP-адрес.
+	if id, ok := rl.requestAddressID(r); ok {
+		return id, SourceAddress
+	}
+
+	// Крайний случай: не удалось извлечь чистый IP.
+	i18n.Logf(i18n.RLClientIDUnknown, rl.identifierHeader, r.Header.Get("X-Forwarded-For"), r.RemoteAddr)
+	return r.RemoteAddr, SourceAddress
+}
+
+// requestAddressID возвращает ID клиента по IP-адресу: RemoteAddr или, если запрос пришел от доверенного
+// прокси, адрес из X-Forwarded-For (см. forwardedClient). false - адрес извлечь не удалось,
+// возвращается RemoteAddr как есть.
+func (rl *RateLimiter) requestAddressID(r *http.Request) (string, bool) {
+	addr, ok := parseClientAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr, false
+	}
+	if rl.trustedProxy(addr) {
+		addr = rl.forwardedClient(r, addr)
+	}
+	return rl.addressID(addr), true
+}
+
+// forwardedClient возвращает адрес клиента из X-Forwarded-For запроса, пришедшего от доверенного прокси proxy.
+// Каждый прокси дописывает адрес своего отправителя в конец, поэтому записи читаются справа налево,
+// пока их добавляют доверенные прокси: первый недоверенный адрес и есть клиент, а записи левее него
+// клиент мог подставить сам. Некорректная запись прерывает разбор - клиентом считается последний
+// прокси, чьему адресу можно верить.
+func (rl *RateLimiter) forwardedClient(r *http.Request, proxy netip.Addr) netip.Addr {
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	addr := proxy
+	for i := len(hops) - 1; i >= 0 && rl.trustedProxy(addr); i-- {
+		hop, ok := parseClientAddr(hops[i])
+		if !ok {
+			break
 		}
+		addr = hop
 	}
+	return addr
+}
 
-	// 2. Если заголовок не настроен или пуст, используем IP-адрес.
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		parts := strings.Split(xff, ",")
-		for _, part := range parts {
-			ip := strings.TrimSpace(part)
-			if ip != "" && net.ParseIP(ip) != nil {
-				return ip // Возвращаем первый валидный IP из XFF
+// trustedProxy сообщает, что адрес принадлежит прокси из rate_limiter.trusted_proxies.
+func (rl *RateLimiter) trustedProxy(addr netip.Addr) bool {
+	for _, prefix := range rl.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// LimitKey возвращает ключ корзины для запроса по шаблону rate_limiter.key_template: например,
+// "{client_id}:{path_prefix}" дает клиенту отдельный лимит на каждый маршрут. clientID - результат
+// GetClientID, pathPrefix - префикс совпавшего маршрута ("" - маршрут по умолчанию).
+// Без шаблона ключом служит clientID. Индивидуальные лимиты (rate_limiter.clients, /clients) задаются по ключу.
+func (rl *RateLimiter) LimitKey(r *http.Request, clientID, pathPrefix string) string {
+	if rl.keyParts == nil {
+		return clientID
+	}
+	var key strings.Builder
+	for _, part := range rl.keyParts {
+		switch part.Attr {
+		case "":
+			key.WriteString(part.Literal)
+		case config.KeyAttrClientID:
+			key.WriteString(clientID)
+		case config.KeyAttrIP:
+			id, _ := rl.requestAddressID(r)
+			key.WriteString(id)
+		case config.KeyAttrMethod:
+			key.WriteString(r.Method)
+		case config.KeyAttrHost:
+			key.WriteString(r.Host)
+		case config.KeyAttrPathPrefix:
+			if pathPrefix == "" {
+				pathPrefix = "/"
 			}
+			key.WriteString(pathPrefix)
+		case config.KeyAttrHeader:
+			key.WriteString(r.Header.Get(part.Header))
+		case config.KeyAttrTLSFingerprint:
+			key.WriteString(sni.Fingerprint(r))
 		}
 	}
+	return key.String()
+}
 
-	// Используем RemoteAddr как fallback.
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err == nil {
-		if net.ParseIP(ip) != nil {
-			return ip
+// parseClientAddr разбирает адрес клиента из XFF или RemoteAddr: "203.0.113.7", "2001:db8::1",
+// "[2001:db8::1]", "[fe80::1%eth0]:443". Порт и зона IPv6 отбрасываются, IPv4-mapped адреса
+// (::ffff:203.0.113.7) приводятся к IPv4, чтобы один клиент не получал разные ID.
+func parseClientAddr(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		addrPort, err := netip.ParseAddrPort(s)
+		if err != nil {
+			return netip.Addr{}, false
 		}
+		addr = addrPort.Addr()
 	}
+	return addr.WithZone("").Unmap(), true
+}
 
-	// Крайний случай: не удалось извлечь чистый IP.
-	log.Printf("[Warning] Не удалось определить ID клиента (заголовок: '%s', XFF: '%s', RemoteAddr: '%s'). Используется RemoteAddr.", rl.identifierHeader, xff, r.RemoteAddr)
-	return r.RemoteAddr
+// addressID возвращает ID клиента для адреса в канонической форме. При заданном ipv6_prefix_length
+// IPv6-адреса объединяются по префиксу (например, "2001:db8:1:2::/64"), чтобы клиент не мог обойти лимит,
+// перебирая адреса своей подсети.
+func (rl *RateLimiter) addressID(addr netip.Addr) string {
+	if addr.Is6() && rl.ipv6PrefixLength > 0 {
+		return netip.PrefixFrom(addr, rl.ipv6PrefixLength).Masked().String()
+	}
+	return addr.String()
+}
+
+// IsAddressID сообщает, что ID клиента имеет вид IP-адреса или IPv6-префикса (см. addressID). Значение
+// заголовка идентификации может выглядеть так же: как определен клиент запроса, сообщает ClientIdentity.
+func IsAddressID(clientID string) bool {
+	if _, err := netip.ParseAddr(clientID); err == nil {
+		return true
+	}
+	_, err := netip.ParsePrefix(clientID)
+	return err == nil
 }
 
 // Helper function min
@@ -432,7 +1163,7 @@ func (rl *RateLimiter) SaveState() error {
 		if rl.store != nil {
 			storeType = fmt.Sprintf("%T", rl.store)
 		}
-		log.Printf("[RateLimiter] Сохранение состояния не выполнено. Enabled: %t, Store: %s, SupportsState: %t",
+		i18n.Logf(i18n.RLSaveSkipped,
 			rl.enabled, storeType, rl.store != nil && rl.store.SupportsStatePersistence())
 		return nil // Не ошибка, просто не сохраняем
 	}
@@ -440,15 +1171,15 @@ func (rl *RateLimiter) SaveState() error {
 	// Делаем type assertion на StateStore
 	stateStore, ok := rl.store.(StateStore)
 	if !ok {
-		log.Printf("[Error][RateLimiter] Store (%T) сообщает о поддержке состояния, но не реализует StateStore! Сохранение невозможно.", rl.store)
-		return fmt.Errorf("store %T не реализует StateStore", rl.store)
+		i18n.Logf(i18n.RLSaveNotStateStore, rl.store)
+		return i18n.Errorf(i18n.RLNotStateStoreErr, rl.store)
 	}
 
 	// Собираем состояния всех корзин
 	statesToSave := make(map[string]storage.ClientState) // Используем тип из storage
 
 	rl.mu.RLock() // Блокируем карту buckets на чтение
-	log.Printf("[RateLimiter] Подготовка к сохранению состояния %d корзин...", len(rl.buckets))
+	i18n.Logf(i18n.RLSavePreparing, len(rl.buckets))
 	for clientID, bucket := range rl.buckets {
 		bucket.mu.Lock() // Блокируем конкретную корзину на время чтения ее состояния
 		// Копируем актуальное состояние
@@ -461,19 +1192,19 @@ func (rl *RateLimiter) SaveState() error {
 	rl.mu.RUnlock() // Разблокируем карту buckets
 
 	if len(statesToSave) == 0 {
-		log.Println("[RateLimiter] Нет активных корзин для сохранения.")
+		i18n.Logf(i18n.RLSaveNothing)
 		return nil
 	}
 
-	log.Printf("[RateLimiter] Сохранение состояния %d корзин в хранилище (%T)...", len(statesToSave), rl.store)
+	i18n.Logf(i18n.RLSaving, len(statesToSave), rl.store)
 
 	// Вызываем метод конкретной реализации *storage.DB
 	err := stateStore.BatchUpdateClientState(statesToSave) // Передаем map[string]storage.ClientState
 	if err != nil {
-		log.Printf("[Error][RateLimiter] Ошибка при массовом обновлении состояния корзин: %v", err)
-		return fmt.Errorf("ошибка сохранения состояния RateLimiter: %w", err) // Возвращаем ошибку
+		i18n.Logf(i18n.RLBatchUpdateFailed, err)
+		return i18n.Errorf(i18n.RLSaveFailed, err) // Возвращаем ошибку
 	}
 
-	log.Printf("[RateLimiter] Состояние %d корзин успешно сохранено.", len(statesToSave))
+	i18n.Logf(i18n.RLSaved, len(statesToSave))
 	return nil
 }
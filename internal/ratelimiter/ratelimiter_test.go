@@ -1,7 +1,19 @@
 package ratelimiter_test
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,7 +22,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"load-balancer/internal/config"
+	"load-balancer/internal/metrics"
 	"load-balancer/internal/ratelimiter"
+	"load-balancer/internal/sni"
 	"load-balancer/internal/storage"
 )
 
@@ -171,7 +185,8 @@ func TestNewRateLimiter(t *testing.T) {
 // TestRateLimiter_GetClientID проверяет получение ID клиента.
 func TestRateLimiter_GetClientID(t *testing.T) {
 	cfgHeader := &config.RateLimiterConfig{Enabled: true, IdentifierHeader: "X-Real-ID"}
-	cfgIP := &config.RateLimiterConfig{Enabled: true, IdentifierHeader: ""} // Без заголовка, используем IP
+	cfgIP := &config.RateLimiterConfig{Enabled: true, IdentifierHeader: "", // Без заголовка, используем IP
+		TrustedProxyPrefixes: []netip.Prefix{netip.MustParsePrefix("172.16.0.0/12")}}
 
 	rlHeader, _ := ratelimiter.New(cfgHeader, nil)
 	rlIP, _ := ratelimiter.New(cfgIP, nil)
@@ -194,17 +209,39 @@ func TestRateLimiter_GetClientID(t *testing.T) {
 	reqIP.RemoteAddr = "192.0.2.2:54321"
 	assert.Equal(t, "192.0.2.2", rlIP.GetClientID(reqIP), "Должен быть IP из RemoteAddr")
 
-	// Тест 4: Используем X-Forwarded-For
+	// Тест 4: X-Forwarded-For от доверенного прокси: клиент - последний адрес, дописанный доверенным прокси,
+	// а не первый, который клиент мог подставить сам
 	reqXFF := httptest.NewRequest("GET", "/", nil)
 	reqXFF.Header.Set("X-Forwarded-For", "10.0.0.1, 192.0.2.3")
 	reqXFF.RemoteAddr = "172.16.0.1:8080"
-	assert.Equal(t, "10.0.0.1", rlIP.GetClientID(reqXFF), "Должен быть первый IP из XFF")
+	assert.Equal(t, "192.0.2.3", rlIP.GetClientID(reqXFF), "Должен быть крайний правый недоверенный IP из XFF")
 
 	// Тест 5: X-Forwarded-For с пробелами и невалидными записями
-	reqXFFSpaced := httptest.NewRequest("GET", "/", nil)
-	reqXFFSpaced.Header.Set("X-Forwarded-For", " garbage , 10.0.0.2 , unknown")
-	reqXFFSpaced.RemoteAddr = "172.16.0.1:8080"
-	assert.Equal(t, "10.0.0.2", rlIP.GetClientID(reqXFFSpaced), "Должен быть валидный IP из XFF после очистки")
+	xffCases := map[string]string{
+		" garbage , 10.0.0.2 , 172.16.0.9 ": "10.0.0.2",   // Цепочка доверенных прокси пропускается
+		"10.0.0.2, unknown":                 "172.16.0.1", // Некорректная запись прерывает разбор
+		"172.16.0.9, 172.16.0.8":            "172.16.0.9", // Все адреса доверенные - берется крайний левый
+	}
+	for xff, expected := range xffCases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Forwarded-For", xff)
+		req.RemoteAddr = "172.16.0.1:8080"
+		assert.Equal(t, expected, rlIP.GetClientID(req), xff)
+	}
+	reqMultiXFF := httptest.NewRequest("GET", "/", nil)
+	reqMultiXFF.Header.Add("X-Forwarded-For", "198.51.100.1")
+	reqMultiXFF.Header.Add("X-Forwarded-For", "172.16.0.9")
+	reqMultiXFF.RemoteAddr = "172.16.0.1:8080"
+	assert.Equal(t, "198.51.100.1", rlIP.GetClientID(reqMultiXFF), "Несколько заголовков XFF читаются как один список")
+
+	// Тест 5a: X-Forwarded-For от недоверенного адреса не учитывается: клиент не может подменить свой ID
+	reqSpoofed := httptest.NewRequest("GET", "/", nil)
+	reqSpoofed.Header.Set("X-Forwarded-For", "10.0.0.1")
+	reqSpoofed.RemoteAddr = "192.0.2.5:8080"
+	assert.Equal(t, "192.0.2.5", rlIP.GetClientID(reqSpoofed), "XFF от недоверенного клиента игнорируется")
+	rlNoProxies, _ := ratelimiter.New(&config.RateLimiterConfig{Enabled: true}, nil)
+	defer rlNoProxies.Stop()
+	assert.Equal(t, "172.16.0.1", rlNoProxies.GetClientID(reqXFF), "Без trusted_proxies XFF не учитывается")
 
 	// Тест 6: Только заголовок настроен, XFF есть, но заголовок приоритетнее
 	reqHeaderWithXFF := httptest.NewRequest("GET", "/", nil)
@@ -219,6 +256,132 @@ func TestRateLimiter_GetClientID(t *testing.T) {
 	assert.Equal(t, "invalid-address", rlIP.GetClientID(reqInvalidAddr), "Должен возвращаться RemoteAddr как есть при ошибке парсинга")
 }
 
+// TestRateLimiter_GetClientID_TLSFingerprint проверяет идентификацию клиентов HTTPS по отпечатку JA3.
+func TestRateLimiter_GetClientID_TLSFingerprint(t *testing.T) {
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 1, DefaultCapacity: 1,
+		IdentifierHeader: "X-Client-ID", TLSFingerprintIdentity: true}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	router := sni.NewRouter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, rl.GetClientID(r))
+	}))
+	server := httptest.NewUnstartedServer(router)
+	server.TLS = router.TLSConfig()
+	server.Config.ConnContext = router.ConnContext
+	server.Config.ConnState = router.ConnState
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(clientHeader string) string {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		if clientHeader != "" {
+			req.Header.Set("X-Client-ID", clientHeader)
+		}
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	assert.Regexp(t, "^"+ratelimiter.FingerprintIDPrefix+"[0-9a-f]{32}$", get(""))
+	assert.Equal(t, "partner", get("partner"), "Заголовок идентификации важнее отпечатка")
+
+	plain := httptest.NewRequest(http.MethodGet, "/", nil)
+	plain.RemoteAddr = "203.0.113.7:40000"
+	assert.Equal(t, "203.0.113.7", rl.GetClientID(plain), "Без TLS клиент идентифицируется по IP")
+}
+
+// TestRateLimiter_GetClientID_APIKey проверяет идентификацию по API-ключам из хранилища: ключ из Authorization
+// или X-API-Key дает ID клиента, неизвестный ключ - идентификацию по IP и не запоминается, отзыв действует
+// после ForgetAPIKey.
+func TestRateLimiter_GetClientID_APIKey(t *testing.T) {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "keys.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	hash := storage.HashAPIKey("secret")
+	require.NoError(t, db.PutAPIKey(storage.APIKey{Hash: hash, ClientID: "acme", CreatedAt: time.Now()}))
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 1, DefaultCapacity: 1,
+		APIKeys: config.APIKeysConfig{Enabled: true, Header: config.DefaultAPIKeyHeader, CacheTTL: time.Hour}}, db)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	request := func(header, value string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		return req
+	}
+	assert.Equal(t, "acme", rl.GetClientID(request("Authorization", "Bearer secret")))
+	assert.Equal(t, "acme", rl.GetClientID(request("Authorization", "bearer  secret ")))
+	assert.Equal(t, "acme", rl.GetClientID(request("X-API-Key", "secret")))
+	assert.Equal(t, "192.0.2.1", rl.GetClientID(request("Authorization", "Basic secret")))
+	assert.Equal(t, "192.0.2.1", rl.GetClientID(request("X-API-Key", "forged")), "Неизвестный ключ - анонимный клиент")
+	assert.Equal(t, "192.0.2.1", rl.GetClientID(request("", "")))
+
+	// Найденный ключ запоминается: отзыв в хранилище действует после ForgetAPIKey или cache_ttl
+	require.NoError(t, db.DeleteAPIKey(hash))
+	assert.Equal(t, "acme", rl.GetClientID(request("X-API-Key", "secret")))
+	rl.ForgetAPIKey(hash)
+	assert.Equal(t, "192.0.2.1", rl.GetClientID(request("X-API-Key", "secret")))
+
+	// Неизвестный ключ не запоминается: выданный после отказа ключ действует сразу
+	require.NoError(t, db.PutAPIKey(storage.APIKey{Hash: storage.HashAPIKey("forged"), ClientID: "late", CreatedAt: time.Now()}))
+	assert.Equal(t, "late", rl.GetClientID(request("X-API-Key", "forged")))
+}
+
+// TestRateLimiter_GetClientID_IPv6 проверяет нормализацию IPv6-адресов и объединение клиентов по префиксу.
+func TestRateLimiter_GetClientID_IPv6(t *testing.T) {
+	rl, _ := ratelimiter.New(&config.RateLimiterConfig{Enabled: true,
+		TrustedProxyPrefixes: []netip.Prefix{netip.MustParsePrefix("172.16.0.0/12")}}, nil)
+	defer rl.Stop()
+
+	cases := []struct {
+		remoteAddr, xff, expected string
+	}{
+		{"[2001:db8::1]:443", "", "2001:db8::1"},
+		{"[fe80::1%eth0]:443", "", "fe80::1"},                         // Зона отбрасывается
+		{"[::ffff:192.0.2.1]:443", "", "192.0.2.1"},                   // IPv4-mapped приводится к IPv4
+		{"[2001:DB8:0:0::1]:443", "", "2001:db8::1"},                  // Каноническая форма
+		{"172.16.0.1:8080", "10.0.0.1, [2001:db8::2]", "2001:db8::2"}, // Литерал в скобках в XFF
+		{"172.16.0.1:8080", "[2001:db8::3]:1234", "2001:db8::3"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tc.remoteAddr
+		if tc.xff != "" {
+			req.Header.Set("X-Forwarded-For", tc.xff)
+		}
+		assert.Equal(t, tc.expected, rl.GetClientID(req), tc.remoteAddr+" "+tc.xff)
+	}
+
+	rlPrefix, _ := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 1, DefaultCapacity: 1, IPv6PrefixLength: 64}, nil)
+	defer rlPrefix.Stop()
+	first := httptest.NewRequest("GET", "/", nil)
+	first.RemoteAddr = "[2001:db8:1:2::10]:443"
+	second := httptest.NewRequest("GET", "/", nil)
+	second.RemoteAddr = "[2001:db8:1:2:aaaa::20]:443"
+	ipv4 := httptest.NewRequest("GET", "/", nil)
+	ipv4.RemoteAddr = "192.0.2.1:443"
+	assert.Equal(t, "2001:db8:1:2::/64", rlPrefix.GetClientID(first))
+	assert.Equal(t, "2001:db8:1:2::/64", rlPrefix.GetClientID(second))
+	assert.Equal(t, "192.0.2.1", rlPrefix.GetClientID(ipv4), "IPv4 не объединяется по префиксу")
+
+	// Адреса одной подсети делят корзину
+	allowed, err := rlPrefix.Check(rlPrefix.GetClientID(first))
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = rlPrefix.Check(rlPrefix.GetClientID(second))
+	require.NoError(t, err)
+	assert.False(t, allowed, "Смена адреса внутри /64 не должна давать новую корзину")
+}
+
 // TestRateLimiter_LoadState проверяет загрузку состояния из store (*storage.DB)
 func TestRateLimiter_LoadState(t *testing.T) {
 	mockStore := NewMockStore().AsDB() // Используем старое имя
@@ -384,3 +547,888 @@ func TestRateLimiter_SaveState_NotDB(t *testing.T) {
 	mockStore.AssertBatchUpdateNotCalled(t)
 	mockStore.AssertExpectations(t)
 }
+
+// TestRateLimiter_StoreFailurePolicy проверяет поведение при ошибке хранилища для fail_open и fail_closed.
+func TestRateLimiter_StoreFailurePolicy(t *testing.T) {
+	storeErr := errors.New("database is locked")
+
+	// fail_open: запрос проходит с дефолтными лимитами
+	openStore := NewMockStore()
+	openStore.On("GetClientLimitConfig", "open-client").Return(0.0, 0.0, false, storeErr)
+	rlOpen, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 1, DefaultCapacity: 1, StoreFailurePolicy: config.StoreFailOpen,
+	}, openStore)
+	require.NoError(t, err)
+	defer rlOpen.Stop()
+
+	allowed, err := rlOpen.Check("open-client")
+	require.NoError(t, err, "fail_open не должен возвращать ошибку")
+	assert.True(t, allowed, "fail_open должен пропускать запрос с дефолтными лимитами")
+
+	// fail_closed: запрос отклоняется с ErrStoreUnavailable, корзина не создается
+	closedStore := NewMockStore()
+	closedStore.On("GetClientLimitConfig", "closed-client").Return(0.0, 0.0, false, storeErr).Once()
+	closedStore.On("GetClientLimitConfig", "closed-client").Return(5.0, 5.0, true, nil)
+	rlClosed, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 1, DefaultCapacity: 1, StoreFailurePolicy: config.StoreFailClosed,
+	}, closedStore)
+	require.NoError(t, err)
+	defer rlClosed.Stop()
+
+	allowed, err = rlClosed.Check("closed-client")
+	require.ErrorIs(t, err, ratelimiter.ErrStoreUnavailable)
+	assert.False(t, allowed)
+
+	// После восстановления хранилища запрос снова проходит
+	allowed, err = rlClosed.Check("closed-client")
+	require.NoError(t, err)
+	assert.True(t, allowed, "После восстановления хранилища запрос должен проходить")
+}
+
+// TestRateLimiter_StoreTimeout проверяет, что медленное хранилище считается недоступным по таймауту.
+func TestRateLimiter_StoreTimeout(t *testing.T) {
+	slowStore := NewMockStore()
+	slowStore.On("GetClientLimitConfig", "slow-client").
+		After(200*time.Millisecond).
+		Return(10.0, 10.0, true, nil)
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled:            true,
+		DefaultRate:        1,
+		DefaultCapacity:    1,
+		StoreFailurePolicy: config.StoreFailClosed,
+		StoreTimeout:       20 * time.Millisecond,
+	}, slowStore)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	start := time.Now()
+	allowed, err := rl.Check("slow-client")
+	assert.Less(t, time.Since(start), 150*time.Millisecond, "Check должен завершиться по таймауту")
+	require.ErrorIs(t, err, ratelimiter.ErrStoreUnavailable)
+	assert.False(t, allowed)
+}
+
+// TestImportClientLimits проверяет синхронизацию лимитов из конфигурации в хранилище.
+func TestImportClientLimits(t *testing.T) {
+	mockStore := NewMockStore()
+	newLimit := config.ClientRateConfig{Rate: 1, Capacity: 10}
+	changedLimit := config.ClientRateConfig{Rate: 5, Capacity: 50}
+	sameLimit := config.ClientRateConfig{Rate: 2, Capacity: 20}
+
+	mockStore.On("GetClientLimitConfig", "new-client").Return(0.0, 0.0, false, nil)
+	mockStore.On("CreateClientLimit", "new-client", newLimit).Return(nil).Once()
+	mockStore.On("GetClientLimitConfig", "changed-client").Return(1.0, 1.0, true, nil)
+	mockStore.On("UpdateClientLimit", "changed-client", changedLimit).Return(nil).Once()
+	mockStore.On("GetClientLimitConfig", "same-client").Return(2.0, 20.0, true, nil)
+
+	created, updated, err := ratelimiter.ImportClientLimits(mockStore, map[string]config.ClientRateConfig{
+		"new-client":     newLimit,
+		"changed-client": changedLimit,
+		"same-client":    sameLimit,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, created)
+	assert.Equal(t, 1, updated)
+	mockStore.AssertExpectations(t)
+	mockStore.AssertNotCalled(t, "UpdateClientLimit", "same-client", sameLimit)
+
+	// Без хранилища импорт невозможен
+	_, _, err = ratelimiter.ImportClientLimits(nil, map[string]config.ClientRateConfig{"c": newLimit})
+	assert.Error(t, err)
+}
+
+// TestRateLimiter_DefaultsPerIdentifierType проверяет разные лимиты по умолчанию для IP и заголовка.
+func TestRateLimiter_DefaultsPerIdentifierType(t *testing.T) {
+	cfg := &config.RateLimiterConfig{
+		Enabled:               true,
+		DefaultRate:           1,
+		DefaultCapacity:       1,
+		DefaultCapacityIP:     2,
+		DefaultCapacityHeader: 5,
+	}
+	rl, err := ratelimiter.New(cfg, nil)
+	require.NoError(t, err)
+	rl.Stop() // Фоновое пополнение не нужно: проверяем только начальную емкость
+
+	countAllowed := func(clientID string) int {
+		allowed := 0
+		for i := 0; i < 10; i++ {
+			if rl.Allow(clientID) {
+				allowed++
+			}
+		}
+		return allowed
+	}
+
+	assert.Equal(t, 2, countAllowed("203.0.113.7"), "Для IP-клиента должна использоваться default_capacity_ip")
+	assert.Equal(t, 2, countAllowed("2001:db8::1"), "IPv6-адрес тоже считается IP-клиентом")
+	assert.Equal(t, 5, countAllowed("api-client"), "Для клиента из заголовка должна использоваться default_capacity_header")
+}
+
+// TestRateLimiter_DefaultsPerIdentitySource проверяет, что лимиты по умолчанию зависят от того, как определен
+// клиент, а не от вида ID: клиент, передавший в заголовке IP-адрес, получает default_capacity_header.
+func TestRateLimiter_DefaultsPerIdentitySource(t *testing.T) {
+	cfg := &config.RateLimiterConfig{
+		Enabled:               true,
+		IdentifierHeader:      "X-Client-ID",
+		DefaultRate:           1,
+		DefaultCapacity:       1,
+		DefaultCapacityIP:     2,
+		DefaultCapacityHeader: 5,
+	}
+	rl, err := ratelimiter.New(cfg, nil)
+	require.NoError(t, err)
+	rl.Stop() // Фоновое пополнение не нужно: проверяем только начальную емкость
+
+	headerReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	headerReq.Header.Set("X-Client-ID", "10.0.0.1")
+	headerReq.RemoteAddr = "192.0.2.1:12345"
+	clientID, source := rl.ClientIdentity(headerReq)
+	assert.Equal(t, "10.0.0.1", clientID)
+	assert.Equal(t, ratelimiter.SourceHeader, source)
+	decision, err := rl.Decide(clientID, source, 1)
+	require.NoError(t, err)
+	assert.Equal(t, float64(5), decision.Limit, "ID из заголовка, похожий на IP, получает default_capacity_header")
+
+	addrReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	addrReq.RemoteAddr = "192.0.2.9:12345"
+	clientID, source = rl.ClientIdentity(addrReq)
+	assert.Equal(t, "192.0.2.9", clientID)
+	assert.Equal(t, ratelimiter.SourceAddress, source)
+	decision, err = rl.Decide(clientID, source, 1)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), decision.Limit)
+
+	// Лимиты уже созданной корзины не зависят от способа идентификации следующих запросов
+	decision, err = rl.Decide("10.0.0.1", ratelimiter.SourceAddress, 1)
+	require.NoError(t, err)
+	assert.Equal(t, float64(5), decision.Limit)
+}
+
+// TestRateLimiter_LimitKey проверяет ключи корзин по шаблону rate_limiter.key_template.
+func TestRateLimiter_LimitKey(t *testing.T) {
+	parts, err := config.ParseKeyTemplate("{header.x-tenant}:{method}:{path_prefix}@{ip}")
+	require.NoError(t, err)
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 0.001, DefaultCapacity: 1, IdentifierHeader: "X-Client-ID", KeyParts: parts,
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	req := httptest.NewRequest("POST", "/api/orders", nil)
+	req.RemoteAddr = "192.0.2.1:443"
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("X-Client-ID", "user-1")
+	clientID := rl.GetClientID(req)
+	assert.Equal(t, "user-1", clientID, "Шаблон не меняет ID клиента")
+	assert.Equal(t, "acme:POST:/api@192.0.2.1", rl.LimitKey(req, clientID, "/api"))
+	assert.Equal(t, "acme:POST:/@192.0.2.1", rl.LimitKey(req, clientID, ""), "Маршрут по умолчанию")
+
+	// Разные ключи - разные корзины; для составного ключа действуют общие лимиты по умолчанию
+	assert.True(t, rl.Allow("acme:POST:/api@192.0.2.1"))
+	assert.False(t, rl.Allow("acme:POST:/api@192.0.2.1"))
+	assert.True(t, rl.Allow("acme:GET:/api@192.0.2.1"))
+
+	plain, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 1, DefaultCapacity: 1}, nil)
+	require.NoError(t, err)
+	defer plain.Stop()
+	assert.Equal(t, "user-1", plain.LimitKey(req, "user-1", "/api"), "Без шаблона ключ равен ID клиента")
+
+	for _, bad := range []string{"{client_id", "client_id}", "{tenant}", "{header.}"} {
+		_, err := config.ParseKeyTemplate(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+// TestRateLimiter_Wait проверяет ожидание завершения фонового пополнения после Stop.
+func TestRateLimiter_Wait(t *testing.T) {
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 1, DefaultCapacity: 1}, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	rl.Stop()
+	assert.NoError(t, rl.Wait(ctx), "Горутина пополнения должна завершиться после Stop")
+
+	// Пока Stop не вызван, Wait завершается по контексту
+	rlRunning, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 1, DefaultCapacity: 1}, nil)
+	require.NoError(t, err)
+	defer rlRunning.Stop()
+	shortCtx, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	assert.ErrorIs(t, rlRunning.Wait(shortCtx), context.DeadlineExceeded)
+
+	// У выключенного Rate Limiter фоновых горутин нет
+	assert.NoError(t, ratelimiter.NewDisabled().Wait(ctx))
+}
+
+// TestRateLimiter_Metrics проверяет счетчики решений, созданных корзин и гистограмму остатка токенов.
+func TestRateLimiter_Metrics(t *testing.T) {
+	allowed := metrics.NewCounter("ratelimiter_allowed_total", "")
+	denied := metrics.NewCounter("ratelimiter_denied_total", "")
+	created := metrics.NewCounter("ratelimiter_buckets_created_total", "")
+	remaining := metrics.NewHistogram("ratelimiter_tokens_remaining", "", nil)
+	allowedBefore, deniedBefore, createdBefore := allowed.Value(), denied.Value(), created.Value()
+	remainingBefore := remaining.Snapshot()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 2}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	for i := 0; i < 3; i++ {
+		rl.Allow("metrics-client")
+	}
+
+	assert.Equal(t, uint64(2), allowed.Value()-allowedBefore)
+	assert.Equal(t, uint64(1), denied.Value()-deniedBefore)
+	assert.Equal(t, uint64(1), created.Value()-createdBefore, "Корзина создается один раз на клиента")
+
+	// Остатки после решений: 1, 0, 0 - все три наблюдения не больше 1
+	remainingAfter := remaining.Snapshot()
+	assert.Equal(t, uint64(3), remainingAfter.Count-remainingBefore.Count)
+	assert.Equal(t, uint64(3), remainingAfter.Buckets[1].Count-remainingBefore.Buckets[1].Count)
+}
+
+// TestRateLimiter_SoftLimit проверяет предупреждение после мягкого порога до жесткого отказа.
+func TestRateLimiter_SoftLimit(t *testing.T) {
+	warnings := metrics.NewCounter("ratelimiter_soft_limit_warnings_total", "")
+	warningsBefore := warnings.Value()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 5, SoftLimitRatio: 0.5}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	// Емкость 5, порог 50%: предупреждение, когда осталось не больше 2.5 токенов
+	var got []string
+	for i := 0; i < 5; i++ {
+		allowed, warning, err := rl.CheckSoft("soft-client")
+		require.NoError(t, err)
+		require.True(t, allowed)
+		got = append(got, warning)
+	}
+	assert.Equal(t, []string{"", "",
+		"soft limit exceeded; remaining=2; capacity=5",
+		"soft limit exceeded; remaining=1; capacity=5",
+		"soft limit exceeded; remaining=0; capacity=5"}, got)
+	assert.Equal(t, uint64(3), warnings.Value()-warningsBefore)
+
+	allowed, warning, err := rl.CheckSoft("soft-client")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Empty(t, warning, "Отклоненный запрос не получает предупреждения")
+}
+
+// TestRateLimiter_DenialCache проверяет, что повторные отказы выдаются из кэша до истечения TTL.
+func TestRateLimiter_DenialCache(t *testing.T) {
+	hits := metrics.NewCounter("ratelimiter_denial_cache_hits_total", "")
+	hitsBefore := hits.Value()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 1, DenialCacheTTL: 50 * time.Millisecond}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	assert.True(t, rl.Allow("flood-client"))
+	assert.False(t, rl.Allow("flood-client"), "Отказ по корзине запоминается")
+	assert.False(t, rl.Allow("flood-client"))
+	assert.False(t, rl.Allow("flood-client"))
+	assert.Equal(t, uint64(2), hits.Value()-hitsBefore)
+	assert.True(t, rl.Allow("other-client"), "Кэш отказов не влияет на других клиентов")
+
+	// После TTL решение снова принимается по корзине
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, rl.Allow("flood-client"))
+	assert.Equal(t, uint64(2), hits.Value()-hitsBefore)
+}
+
+// TestRateLimiter_ResetBucket проверяет заполнение корзины, снятие запомненного отказа и сохранение сброса.
+func TestRateLimiter_ResetBucket(t *testing.T) {
+	_, err := ratelimiter.NewDisabled().ResetBucket("client")
+	assert.ErrorIs(t, err, ratelimiter.ErrDisabled)
+
+	for _, algorithm := range []string{"", config.RateLimitSlidingWindow} {
+		rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, Algorithm: algorithm, DefaultRate: 0.001, DefaultCapacity: 2, DenialCacheTTL: time.Minute}, nil)
+		require.NoError(t, err)
+		assert.True(t, rl.Allow("throttled"), algorithm)
+		assert.True(t, rl.Allow("throttled"), algorithm)
+		assert.False(t, rl.Allow("throttled"), algorithm)
+
+		capacity, err := rl.ResetBucket("throttled")
+		require.NoError(t, err, algorithm)
+		assert.Equal(t, 2.0, capacity, algorithm)
+		assert.True(t, rl.Allow("throttled"), "Отказ не запоминается после сброса: %s", algorithm)
+		assert.True(t, rl.Allow("throttled"), algorithm)
+		assert.False(t, rl.Allow("throttled"), algorithm)
+		rl.Stop()
+	}
+
+	// Сброс сохраняется в хранилище, даже если корзины еще нет в памяти
+	mockStore := NewMockStore().AsDB()
+	mockStore.On("GetClientLimitConfig", "restored").Return(1.0, 5.0, true, nil)
+	mockStore.ExpectGetClientSavedState("restored", 0, time.Now(), true, nil)
+	mockStore.ExpectBatchUpdate(nil)
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true}, mockStore)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	capacity, err := rl.ResetBucket("restored")
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, capacity)
+	require.Contains(t, mockStore.capturedBatchUpdate, "restored")
+	assert.Equal(t, 5.0, mockStore.capturedBatchUpdate["restored"].Tokens)
+	tokens, _, _, found := rl.Bucket("restored")
+	assert.True(t, found)
+	assert.InDelta(t, 5.0, tokens, 0.01)
+}
+
+// TestRateLimiter_BucketState проверяет состояние корзины из памяти, из хранилища и по умолчанию.
+func TestRateLimiter_BucketState(t *testing.T) {
+	_, err := ratelimiter.NewDisabled().BucketState("client")
+	assert.ErrorIs(t, err, ratelimiter.ErrDisabled)
+
+	mockStore := NewMockStore().AsDB()
+	mockStore.On("GetClientLimitConfig", "saved").Return(1.0, 10.0, true, nil)
+	savedAt := time.Now().Add(-2 * time.Second)
+	mockStore.ExpectGetClientSavedState("saved", 3, savedAt, true, nil)
+	mockStore.On("GetClientLimitConfig", "new").Return(0.0, 0.0, false, nil)
+	mockStore.ExpectGetClientSavedState("new", 0, time.Time{}, false, nil)
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 2, DenialCacheTTL: time.Minute}, mockStore)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	state, err := rl.BucketState("saved")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimiter.BucketSourceStore, state.Source)
+	assert.InDelta(t, 5.0, state.Tokens, 0.1, "Сохраненные токены плюс пополнение за 2 секунды")
+	assert.Equal(t, 10.0, state.Capacity)
+	require.NotNil(t, state.LastRefill)
+	assert.True(t, savedAt.Equal(*state.LastRefill))
+
+	state, err = rl.BucketState("new")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimiter.BucketState{Key: "new", Source: ratelimiter.BucketSourceDefault, Tokens: 2, Capacity: 2, Rate: 0.001}, state)
+	_, _, _, found := rl.Bucket("new")
+	assert.False(t, found, "Запрос состояния не создает корзину")
+
+	require.True(t, rl.Allow("new"))
+	require.True(t, rl.Allow("new"))
+	require.False(t, rl.Allow("new"))
+	state, err = rl.BucketState("new")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimiter.BucketSourceMemory, state.Source)
+	assert.InDelta(t, 0, state.Tokens, 0.01)
+	assert.NotNil(t, state.LastRefill)
+	assert.NotNil(t, state.DeniedUntil, "Отказ запомнен в кэше")
+	mockStore.AssertExpectations(t)
+}
+
+// TestRateLimiter_BucketEviction проверяет удаление простаивающих полных корзин и корзин сверх max_buckets.
+func TestRateLimiter_BucketEviction(t *testing.T) {
+	evicted := metrics.NewCounter("ratelimiter_buckets_evicted_total", "")
+	evictedBefore := evicted.Value()
+
+	idle, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 100, DefaultCapacity: 2, IdleBucketTTL: 10 * time.Millisecond,
+	}, nil)
+	require.NoError(t, err)
+	defer idle.Stop()
+	capped, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 2, MaxBuckets: 2}, nil)
+	require.NoError(t, err)
+	defer capped.Stop()
+	slow, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 0.001, DefaultCapacity: 2, IdleBucketTTL: 10 * time.Millisecond,
+	}, nil)
+	require.NoError(t, err)
+	defer slow.Stop()
+
+	idle.Allow("scanner-1")
+	idle.Allow("scanner-2")
+	slow.Allow("slow-client")
+	for _, client := range []string{"old-1", "old-2", "recent-1", "recent-2"} {
+		capped.Allow(client)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	require.Eventually(t, func() bool { return idle.Summary().Buckets == 0 }, 3*time.Second, 50*time.Millisecond,
+		"Полные корзины без запросов удаляются")
+	require.Eventually(t, func() bool { return capped.Summary().Buckets == 2 }, 3*time.Second, 50*time.Millisecond)
+	clients := capped.Snapshot().Clients
+	assert.Equal(t, "recent-1", clients[0].Key, "Удаляются дольше всех не использовавшиеся")
+	assert.Equal(t, "recent-2", clients[1].Key)
+	assert.Equal(t, uint64(4), evicted.Value()-evictedBefore)
+	assert.Equal(t, 1, slow.Summary().Buckets, "Неполная корзина не удаляется: клиент не получает лимит заново")
+}
+
+// TestRateLimiter_AcquireInFlight проверяет лимит одновременных запросов клиента.
+func TestRateLimiter_AcquireInFlight(t *testing.T) {
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 1, DefaultCapacity: 1, MaxInFlightPerClient: 2}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	first, ok := rl.AcquireInFlight("slow-client")
+	require.True(t, ok)
+	second, ok := rl.AcquireInFlight("slow-client")
+	require.True(t, ok)
+	_, ok = rl.AcquireInFlight("slow-client")
+	assert.False(t, ok, "Третий одновременный запрос отклоняется")
+	_, ok = rl.AcquireInFlight("other-client")
+	assert.True(t, ok, "Лимит считается отдельно для каждого клиента")
+
+	first()
+	first() // Повторное освобождение не освобождает чужой слот
+	third, ok := rl.AcquireInFlight("slow-client")
+	require.True(t, ok)
+	_, ok = rl.AcquireInFlight("slow-client")
+	assert.False(t, ok)
+	second()
+	third()
+
+	unlimited, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 1, DefaultCapacity: 1}, nil)
+	require.NoError(t, err)
+	defer unlimited.Stop()
+	for range 10 {
+		_, ok := unlimited.AcquireInFlight("client")
+		require.True(t, ok)
+	}
+}
+
+// TestRateLimiter_Summary проверяет сводку по корзинам в памяти.
+func TestRateLimiter_Summary(t *testing.T) {
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 2}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	rl.Allow("full-client")
+	rl.Allow("empty-client")
+	rl.Allow("empty-client")
+
+	summary := rl.Summary()
+	assert.True(t, summary.Enabled)
+	assert.Equal(t, 2, summary.Buckets)
+	assert.Equal(t, 1, summary.Exhausted)
+	assert.InDelta(t, 0.25, summary.AverageFill, 0.01)
+}
+
+// TestRateLimiter_Snapshot проверяет снимок корзин клиентов.
+func TestRateLimiter_Snapshot(t *testing.T) {
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 2, DenialCacheTTL: time.Minute}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	rl.Allow("b-client")
+	rl.Allow("a-client")
+	rl.Allow("a-client")
+	rl.Allow("a-client") // Отказ запоминается в кэше
+
+	snapshot := rl.Snapshot()
+	assert.True(t, snapshot.Enabled)
+	assert.Equal(t, 2, snapshot.Buckets)
+	assert.Equal(t, 1, snapshot.Exhausted)
+	assert.Equal(t, 1, snapshot.DeniedCached)
+	require.Len(t, snapshot.Clients, 2)
+
+	a, b := snapshot.Clients[0], snapshot.Clients[1]
+	assert.Equal(t, "a-client", a.Key)
+	assert.InDelta(t, 0, a.Tokens, 0.01)
+	assert.Equal(t, 2.0, a.Capacity)
+	assert.Equal(t, 0.001, a.Rate)
+	require.NotNil(t, a.DeniedUntil)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), *a.DeniedUntil, 5*time.Second)
+
+	assert.Equal(t, "b-client", b.Key)
+	assert.InDelta(t, 1, b.Tokens, 0.01)
+	assert.Nil(t, b.DeniedUntil)
+
+	// Снимок не меняется вместе с Rate Limiter
+	rl.Allow("b-client")
+	assert.InDelta(t, 1, b.Tokens, 0.01)
+	assert.Len(t, snapshot.Clients, 2)
+}
+
+// TestRateLimiter_LimitDecreaseWindow проверяет, что снижение лимитов растягивается на limit_decrease_window,
+// а повышение действует сразу.
+func TestRateLimiter_LimitDecreaseWindow(t *testing.T) {
+	const window = 400 * time.Millisecond
+	store := NewMockStore()
+	store.On("GetClientLimitConfig", "partner").Return(100.0, 100.0, true, nil).Once()
+	store.On("GetClientLimitConfig", "partner").Return(10.0, 10.0, true, nil).Twice()
+	store.On("GetClientLimitConfig", "partner").Return(50.0, 60.0, true, nil).Once()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 1, DefaultCapacity: 1, LimitDecreaseWindow: window,
+	}, store)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	require.True(t, rl.Allow("partner"))
+	require.True(t, rl.Allow("partner")) // Лимит снижен через API: начинается плавный переход
+	started := time.Now()
+
+	client := rl.Snapshot().Clients[0]
+	assert.InDelta(t, 100, client.Rate, 10)
+	assert.InDelta(t, 100, client.Capacity, 10)
+	require.NotNil(t, client.TargetRate)
+	assert.Equal(t, 10.0, *client.TargetRate)
+	assert.Equal(t, 10.0, *client.TargetCapacity)
+	require.NotNil(t, client.RolloutUntil)
+	assert.WithinDuration(t, started.Add(window), *client.RolloutUntil, 50*time.Millisecond)
+
+	// Середина перехода: лимиты между прежними и новыми, повторное чтение того же лимита переход не сбрасывает
+	time.Sleep(window / 2)
+	require.True(t, rl.Allow("partner"))
+	client = rl.Snapshot().Clients[0]
+	assert.Greater(t, client.Rate, 10.0)
+	assert.Less(t, client.Rate, 100.0)
+	assert.LessOrEqual(t, client.Tokens, client.Capacity)
+	require.NotNil(t, client.RolloutUntil)
+	assert.WithinDuration(t, started.Add(window), *client.RolloutUntil, 50*time.Millisecond)
+
+	time.Sleep(time.Until(started.Add(window + 50*time.Millisecond)))
+	client = rl.Snapshot().Clients[0]
+	assert.Equal(t, 10.0, client.Rate)
+	assert.Equal(t, 10.0, client.Capacity)
+	assert.Nil(t, client.TargetRate)
+	assert.Nil(t, client.RolloutUntil)
+
+	// Повышение действует сразу
+	rl.Allow("partner")
+	client = rl.Snapshot().Clients[0]
+	assert.Equal(t, 50.0, client.Rate)
+	assert.Equal(t, 60.0, client.Capacity)
+	assert.Nil(t, client.TargetRate)
+	store.AssertExpectations(t)
+}
+
+// TestRateLimiter_CheckCost проверяет списание нескольких токенов за запрос и чтение корзины без расхода.
+func TestRateLimiter_CheckCost(t *testing.T) {
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 0.001, DefaultCapacity: 5, DenialCacheTTL: time.Minute,
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	_, _, _, found := rl.Bucket("client")
+	assert.False(t, found, "Корзины до первого запроса нет")
+
+	allowed, _, err := rl.CheckCost("client", 4)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	tokens, capacity, rate, found := rl.Bucket("client")
+	require.True(t, found)
+	assert.InDelta(t, 1, tokens, 0.01)
+	assert.Equal(t, 5.0, capacity)
+	assert.Equal(t, 0.001, rate)
+
+	// Дорогой запрос отклоняется, но отказ не кэшируется: обычный запрос еще проходит
+	allowed, _, err = rl.CheckCost("client", 2)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.True(t, rl.Allow("client"))
+	assert.False(t, rl.Allow("client"))
+}
+
+// TestRateLimiter_Decide проверяет состояние корзины в решении: остаток, время до пополнения и повтора,
+// в том числе для отказа из кэша.
+func TestRateLimiter_Decide(t *testing.T) {
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 1, DefaultCapacity: 3, DenialCacheTTL: time.Minute,
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	decision, err := rl.Decide("client", ratelimiter.SourceHeader, 1)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 3.0, decision.Limit)
+	assert.InDelta(t, 2, decision.Remaining, 0.01)
+	assert.InDelta(t, time.Second, decision.Reset, float64(50*time.Millisecond))
+	assert.Zero(t, decision.RetryAfter)
+
+	decision, err = rl.Decide("client", ratelimiter.SourceHeader, 3)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.InDelta(t, time.Second, decision.RetryAfter, float64(50*time.Millisecond), "Не хватает одного токена")
+
+	_, _ = rl.Decide("client", ratelimiter.SourceHeader, 2)
+	decision, err = rl.Decide("client", ratelimiter.SourceHeader, 1)
+	require.NoError(t, err)
+	require.False(t, decision.Allowed)
+	assert.Equal(t, 3.0, decision.Limit)
+	assert.Zero(t, decision.Remaining)
+	assert.InDelta(t, 3*time.Second, decision.Reset, float64(50*time.Millisecond))
+	assert.InDelta(t, time.Second, decision.RetryAfter, float64(50*time.Millisecond))
+
+	// Отказ из кэша сообщает то же состояние без обращения к корзине
+	decision, err = rl.Decide("client", ratelimiter.SourceHeader, 1)
+	require.NoError(t, err)
+	require.False(t, decision.Allowed)
+	assert.Equal(t, 3.0, decision.Limit)
+	assert.Positive(t, decision.RetryAfter)
+	assert.LessOrEqual(t, decision.RetryAfter, time.Second)
+
+	disabled, err := ratelimiter.New(&config.RateLimiterConfig{}, nil)
+	require.NoError(t, err)
+	defer disabled.Stop()
+	decision, err = disabled.Decide("client", ratelimiter.SourceHeader, 1)
+	require.NoError(t, err)
+	assert.Equal(t, ratelimiter.Decision{Allowed: true}, decision)
+}
+
+// TestRateLimiter_SlidingWindow проверяет, что скользящее окно строже корзины токенов после всплеска:
+// расход выходит из окна постепенно, а не пополняется со скоростью rate.
+func TestRateLimiter_SlidingWindow(t *testing.T) {
+	newLimiter := func(algorithm string) *ratelimiter.RateLimiter {
+		rl, err := ratelimiter.New(&config.RateLimiterConfig{
+			Enabled: true, DefaultRate: 10, DefaultCapacity: 2, Algorithm: algorithm,
+		}, nil)
+		require.NoError(t, err)
+		t.Cleanup(rl.Stop)
+		return rl
+	}
+	bucket := newLimiter(config.RateLimitTokenBucket)
+	window := newLimiter(config.RateLimitSlidingWindow) // Окно 200ms
+
+	started := time.Now()
+	for _, rl := range []*ratelimiter.RateLimiter{bucket, window} {
+		require.True(t, rl.Allow("burst-client"))
+		require.True(t, rl.Allow("burst-client"))
+		require.False(t, rl.Allow("burst-client"))
+	}
+
+	// Через 120ms корзина накопила токен, а весь расход еще в окне
+	time.Sleep(time.Until(started.Add(120 * time.Millisecond)))
+	bucket.Snapshot()
+	window.Snapshot()
+	assert.True(t, bucket.Allow("burst-client"))
+	decision, err := window.Decide("burst-client", ratelimiter.SourceHeader, 1)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	// Токен освободится, когда в окне останется половина расхода первого интервала: через 300ms от начала
+	assert.InDelta(t, 180*time.Millisecond, decision.RetryAfter, float64(40*time.Millisecond))
+
+	time.Sleep(time.Until(started.Add(320 * time.Millisecond)))
+	window.Snapshot()
+	assert.True(t, window.Allow("burst-client"))
+	assert.False(t, window.Allow("burst-client"), "В окне не больше capacity запросов")
+
+	client := window.Snapshot().Clients[0]
+	assert.Equal(t, 2.0, client.Capacity)
+	assert.Less(t, client.Tokens, 1.0)
+}
+
+// TestRateLimiter_GlobalLimit проверяет общий лимит: он ограничивает сумму запросов всех клиентов,
+// не расходует корзины клиентов при отказе и не считает запросы, отклоненные корзиной клиента.
+func TestRateLimiter_GlobalLimit(t *testing.T) {
+	globalDenied := metrics.NewCounter("ratelimiter_global_denied_total", "")
+	deniedBefore := globalDenied.Value()
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 0.001, DefaultCapacity: 2,
+		Global: config.GlobalLimitConfig{Rate: 0.001, Capacity: 3},
+	}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	assert.True(t, rl.Allow("client-a"))
+	assert.True(t, rl.Allow("client-a"))
+	assert.False(t, rl.Allow("client-a"), "Отказ корзины клиента не расходует общий лимит")
+	assert.True(t, rl.Allow("client-b"))
+
+	decision, err := rl.Decide("client-b", ratelimiter.SourceHeader, 1)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.True(t, decision.Global)
+	assert.Positive(t, decision.RetryAfter)
+	tokens, _, _, found := rl.Bucket("client-b")
+	require.True(t, found)
+	assert.InDelta(t, 1, tokens, 0.01, "Отказ по общему лимиту не расходует корзину клиента")
+	assert.Equal(t, uint64(1), globalDenied.Value()-deniedBefore)
+}
+
+// TestRateLimiter_Gossip проверяет, что расход токенов на одном экземпляре списывается из корзины на другом.
+func TestRateLimiter_Gossip(t *testing.T) {
+	received := metrics.NewCounter("ratelimiter_gossip_messages_received_total", "")
+	receivedBefore := received.Value()
+
+	// Сообщения принимаются только с адресов peers, поэтому адрес второго экземпляра выбирается заранее
+	reserved, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	secondAddr := reserved.LocalAddr().String()
+	require.NoError(t, reserved.Close())
+
+	gossip := config.GossipConfig{Listen: "127.0.0.1:0", Peers: []string{secondAddr}, Interval: 20 * time.Millisecond, Secret: "shared"}
+	first, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 3, Gossip: gossip}, nil)
+	require.NoError(t, err)
+	defer first.Stop()
+	require.NotNil(t, first.GossipAddr())
+
+	gossip = config.GossipConfig{Listen: secondAddr, Peers: []string{first.GossipAddr().String()}, Interval: 20 * time.Millisecond, Secret: "shared"}
+	second, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 3, Gossip: gossip}, nil)
+	require.NoError(t, err)
+	defer second.Stop()
+
+	assert.True(t, first.Allow("shared-client"))
+	assert.True(t, second.Allow("shared-client"))
+	assert.True(t, second.Allow("shared-client"))
+
+	// Расход каждого экземпляра доходит до другого: ни в одной корзине не остается токенов
+	require.Eventually(t, func() bool {
+		return first.Summary().Exhausted == 1 && second.Summary().Exhausted == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.False(t, first.Allow("shared-client"), "Общий лимит исчерпан: 1 запрос на первом и 2 на втором")
+	assert.False(t, second.Allow("shared-client"))
+	assert.Equal(t, uint64(2), received.Value()-receivedBefore, "По одному подписанному сообщению каждому экземпляру")
+
+	plain, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 1, DefaultCapacity: 1}, nil)
+	require.NoError(t, err)
+	defer plain.Stop()
+	assert.Nil(t, plain.GossipAddr(), "Без rate_limiter.gossip.listen обмен выключен")
+}
+
+// TestRateLimiter_GossipRejectsForeignMessages проверяет, что сообщения обмена с адреса не из peers
+// и с неверной подписью не списывают токены.
+func TestRateLimiter_GossipRejectsForeignMessages(t *testing.T) {
+	attacker, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer attacker.Close()
+
+	for name, gossip := range map[string]config.GossipConfig{
+		"unknown peer":  {Listen: "127.0.0.1:0", Peers: []string{"127.0.0.1:1"}},
+		"bad signature": {Listen: "127.0.0.1:0", Peers: []string{attacker.LocalAddr().String()}, Secret: "shared"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 3, Gossip: gossip}, nil)
+			require.NoError(t, err)
+			defer rl.Stop()
+			require.True(t, rl.Allow("victim"))
+
+			rejected := metrics.NewCounter("ratelimiter_gossip_messages_rejected_total", "")
+			before := rejected.Value()
+			payload := fmt.Sprintf(`{"node":"attacker","consumed":{"victim":100},"sent":%d}`, time.Now().UnixNano())
+			_, err = attacker.WriteTo([]byte(payload), rl.GossipAddr())
+			require.NoError(t, err)
+			require.Eventually(t, func() bool { return rejected.Value() > before }, time.Second, 5*time.Millisecond)
+
+			state, err := rl.BucketState("victim")
+			require.NoError(t, err)
+			assert.InDelta(t, 2, state.Tokens, 0.01, "Чужое сообщение не списывает токены")
+		})
+	}
+}
+
+// TestRateLimiter_GossipRejectsReplay проверяет, что подписанная датаграмма пира списывает токены один раз:
+// повтор отбрасывается по номеру сообщения, а пришедшее не по порядку более раннее сообщение принимается.
+func TestRateLimiter_GossipRejectsReplay(t *testing.T) {
+	peer, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer peer.Close()
+
+	gossip := config.GossipConfig{Listen: "127.0.0.1:0", Peers: []string{peer.LocalAddr().String()}, Secret: "shared"}
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 10, Gossip: gossip}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	require.True(t, rl.Allow("victim"))
+
+	received := metrics.NewCounter("ratelimiter_gossip_messages_received_total", "")
+	rejected := metrics.NewCounter("ratelimiter_gossip_messages_rejected_total", "")
+	receivedBefore, rejectedBefore := received.Value(), rejected.Value()
+	datagram := func(seq int) []byte {
+		payload := fmt.Sprintf(`{"node":"peer","consumed":{"victim":1},"sent":%d,"seq":%d}`, time.Now().UnixNano(), seq)
+		mac := hmac.New(sha256.New, []byte("shared"))
+		mac.Write([]byte(payload))
+		return append(mac.Sum(nil), payload...)
+	}
+	second := datagram(2)
+	for _, d := range [][]byte{second, second, datagram(1), datagram(1)} {
+		_, err = peer.WriteTo(d, rl.GossipAddr())
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool {
+		return received.Value()+rejected.Value()-receivedBefore-rejectedBefore == 4
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, uint64(2), received.Value()-receivedBefore)
+	assert.Equal(t, uint64(2), rejected.Value()-rejectedBefore, "Повторы отброшены")
+
+	state, err := rl.BucketState("victim")
+	require.NoError(t, err)
+	assert.InDelta(t, 7, state.Tokens, 0.01, "Списан расход двух разных сообщений")
+}
+
+// TestRateLimiter_GossipSplitsLargeConsumption проверяет, что расход многих клиентов с длинными ключами
+// рассылается датаграммами, каждая из которых помещается в буфер приема.
+func TestRateLimiter_GossipSplitsLargeConsumption(t *testing.T) {
+	peer, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer peer.Close()
+
+	gossip := config.GossipConfig{Listen: "127.0.0.1:0", Peers: []string{peer.LocalAddr().String()}, Interval: 20 * time.Millisecond}
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{Enabled: true, DefaultRate: 0.001, DefaultCapacity: 3, Gossip: gossip}, nil)
+	require.NoError(t, err)
+	defer rl.Stop()
+	const clients = 300
+	for i := range clients {
+		require.True(t, rl.Allow(fmt.Sprintf("%s-%d", strings.Repeat("c", 400), i)))
+	}
+
+	keys := make(map[string]bool)
+	buf := make([]byte, 128*1024)
+	require.NoError(t, peer.SetReadDeadline(time.Now().Add(2*time.Second)))
+	for len(keys) < clients {
+		n, _, err := peer.ReadFromUDP(buf)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, n, 64*1024, "Датаграмма помещается в буфер приема")
+		var msg struct {
+			Consumed map[string]float64 `json:"consumed"`
+		}
+		require.NoError(t, json.Unmarshal(buf[:n], &msg))
+		for key := range msg.Consumed {
+			keys[key] = true
+		}
+	}
+	assert.Len(t, keys, clients)
+}
+
+// TestRateLimiter_ExpireTrials проверяет перевод клиентов с закончившимся пробным периодом на план
+// из rate_limiter.templates и перечитывание планов.
+func TestRateLimiter_ExpireTrials(t *testing.T) {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "trials.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	for clientID, plan := range map[string]string{"trial": "free", "lost-plan": "legacy"} {
+		require.NoError(t, db.CreateClientLimit(clientID, config.ClientRateConfig{Rate: 50, Capacity: 500}))
+		require.NoError(t, db.SetClientTrial(storage.ClientTrial{ClientID: clientID, Until: now.Add(time.Hour), Plan: plan}))
+	}
+
+	rl, err := ratelimiter.New(&config.RateLimiterConfig{
+		Enabled: true, DefaultRate: 1, DefaultCapacity: 1,
+		Templates: map[string]config.ClientRateConfig{"free": {Rate: 1, Capacity: 3}},
+	}, db)
+	require.NoError(t, err)
+	defer rl.Stop()
+
+	require.True(t, rl.Allow("trial"))
+	assert.Equal(t, 500.0, rl.Snapshot().Clients[0].Capacity, "Пробный период еще идет")
+	downgraded, err := rl.ExpireTrials(db, now)
+	require.NoError(t, err)
+	assert.Zero(t, downgraded)
+
+	downgraded, err = rl.ExpireTrials(db, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, downgraded, "Клиент с неизвестным планом остается на пробных лимитах")
+	rate, capacity, _, err := db.GetClientLimitConfig("trial")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 3}, []float64{rate, capacity})
+	require.True(t, rl.Allow("trial"))
+	assert.Equal(t, 3.0, rl.Snapshot().Clients[0].Capacity, "Корзина получает лимиты плана при следующем запросе")
+
+	rl.SetPlans(map[string]config.ClientRateConfig{"legacy": {Rate: 2, Capacity: 4}})
+	downgraded, err = rl.ExpireTrials(db, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, downgraded)
+	rate, capacity, _, err = db.GetClientLimitConfig("lost-plan")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{2, 4}, []float64{rate, capacity})
+}
@@ -0,0 +1,57 @@
+package ratelimiter
+
+import (
+	"time"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/storage"
+)
+
+// ErrDisabled - операция с корзинами при выключенном Rate Limiter.
+var ErrDisabled = i18n.NewError(i18n.RLDisabledErr)
+
+// ResetBucket заполняет корзину с ключом key (ID клиента или ключ по key_template, см. LimitKey) до емкости
+// и снимает запомненный отказ, чтобы ошибочно ограниченный клиент сразу получил полный лимит. Если хранилище
+// сохраняет состояние корзин, полная корзина сохраняется и в нем: иначе после перезапуска или вытеснения
+// корзины из памяти восстановилось бы прежнее состояние. Корзины нет в памяти - она создается.
+// Сброс действует на этом экземпляре; другие экземпляры получают его только через хранилище.
+// Возвращает емкость корзины.
+func (rl *RateLimiter) ResetBucket(key string) (float64, error) {
+	if !rl.enabled {
+		return 0, ErrDisabled
+	}
+	bucket, err := rl.getOrCreateBucket(key, SourceOf(key))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	bucket.mu.Lock()
+	bucket.tokens = bucket.capacity
+	bucket.lastRefill = now
+	if bucket.window != nil {
+		*bucket.window = slidingWindow{start: now}
+	}
+	capacity := bucket.capacity
+	bucket.mu.Unlock()
+	rl.deniedUntil.Delete(key)
+	i18n.Logf(i18n.RLBucketReset, key, capacity)
+
+	if rl.store == nil || !rl.store.SupportsStatePersistence() {
+		return capacity, nil
+	}
+	stateStore, ok := rl.store.(StateStore)
+	if !ok {
+		return capacity, nil
+	}
+	err = rl.withStoreTimeout(func() error {
+		return stateStore.BatchUpdateClientState(map[string]storage.ClientState{
+			key: {Tokens: capacity, LastRefill: now},
+		})
+	})
+	if err != nil {
+		storeErrorsTotal.Inc()
+		return capacity, i18n.Errorf(i18n.RLResetSaveFailed, key, err)
+	}
+	return capacity, nil
+}
@@ -0,0 +1,93 @@
+package ratelimiter
+
+import (
+	"time"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+var limitRolloutsTotal = metrics.NewCounter("ratelimiter_limit_rollouts_total",
+	"Количество плавных снижений лимитов клиентов (rate_limiter.limit_decrease_window).")
+
+// limitRollout - плавный переход корзины к сниженным лимитам: rate и capacity линейно меняются
+// от from* к to* за window с момента start.
+type limitRollout struct {
+	fromRate, fromCapacity float64
+	toRate, toCapacity     float64
+	start                  time.Time
+	window                 time.Duration
+}
+
+// at возвращает лимиты на момент now; done - переход завершен.
+func (r *limitRollout) at(now time.Time) (rate, capacity float64, done bool) {
+	progress := float64(now.Sub(r.start)) / float64(r.window)
+	if progress >= 1 {
+		return r.toRate, r.toCapacity, true
+	}
+	progress = max(progress, 0)
+	rate = r.fromRate + (r.toRate-r.fromRate)*progress
+	capacity = r.fromCapacity + (r.toCapacity-r.fromCapacity)*progress
+	return rate, capacity, false
+}
+
+// advanceRollout приводит rate и capacity корзины к значениям перехода на момент now.
+// Должен вызываться под блокировкой bucket.mu.
+func (tb *TokenBucket) advanceRollout(now time.Time) {
+	if tb.rollout == nil {
+		return
+	}
+	var done bool
+	tb.rate, tb.capacity, done = tb.rollout.at(now)
+	tb.tokens = min(tb.tokens, tb.capacity)
+	if done {
+		tb.rollout = nil
+	}
+}
+
+// targetLimits возвращает лимиты, к которым придет корзина: итог перехода или текущие.
+// Должен вызываться под блокировкой bucket.mu.
+func (tb *TokenBucket) targetLimits() (rate, capacity float64) {
+	if tb.rollout != nil {
+		return tb.rollout.toRate, tb.rollout.toCapacity
+	}
+	return tb.rate, tb.capacity
+}
+
+// updateBucketIfNeeded обновляет параметры rate и capacity существующей корзины, если они отличаются от переданных.
+// Снижение при заданном limit_decrease_window растягивается на это время; повышение действует сразу.
+// Должен вызываться под блокировкой bucket.mu.
+func (rl *RateLimiter) updateBucketIfNeeded(bucket *TokenBucket, newRate, newCapacity float64, clientID, source string) {
+	targetRate, targetCapacity := bucket.targetLimits()
+	if targetRate == newRate && targetCapacity == newCapacity {
+		return
+	}
+
+	now := time.Now()
+	bucket.advanceRollout(now)
+	if rl.limitDecreaseWindow > 0 && (newRate < bucket.rate || newCapacity < bucket.capacity) {
+		i18n.Logf(i18n.RLLimitsRollout,
+			clientID, source, rl.limitDecreaseWindow, bucket.rate, newRate, bucket.capacity, newCapacity)
+		limitRolloutsTotal.Inc()
+		// Повышаемая часть лимитов (например, capacity при снижении rate) действует сразу
+		bucket.rollout = &limitRollout{
+			fromRate:     max(bucket.rate, newRate),
+			fromCapacity: max(bucket.capacity, newCapacity),
+			toRate:       newRate,
+			toCapacity:   newCapacity,
+			start:        now,
+			window:       rl.limitDecreaseWindow,
+		}
+		bucket.rate, bucket.capacity = bucket.rollout.fromRate, bucket.rollout.fromCapacity
+		return
+	}
+
+	i18n.Logf(i18n.RLLimitsUpdated,
+		clientID, source, bucket.rate, newRate, bucket.capacity, newCapacity)
+	bucket.rollout = nil
+	bucket.rate = newRate
+	bucket.capacity = newCapacity
+	if bucket.tokens > bucket.capacity {
+		bucket.tokens = bucket.capacity
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimiter
+
+import (
+	"time"
+)
+
+// Источники состояния корзины в BucketState.
+const (
+	BucketSourceMemory  = "memory"  // Корзина в памяти.
+	BucketSourceStore   = "store"   // Корзины нет в памяти, состояние восстановлено из хранилища.
+	BucketSourceDefault = "default" // Корзины нет ни в памяти, ни в хранилище: первый запрос получит полную корзину.
+)
+
+// BucketState - состояние корзины клиента для отладки отказов 429 (GET /clients/{id}/state).
+type BucketState struct {
+	Key      string  `json:"key"`
+	Source   string  `json:"source"` // BucketSourceMemory, BucketSourceStore или BucketSourceDefault.
+	Tokens   float64 `json:"tokens"` // Токены на момент запроса, с учетом пополнения.
+	Capacity float64 `json:"capacity"`
+	Rate     float64 `json:"rate"` // Токенов в секунду.
+	// LastRefill - когда корзина последний раз пополнялась (запрос клиента или сохранение); пусто для BucketSourceDefault.
+	LastRefill *time.Time `json:"last_refill,omitempty"`
+	// DeniedUntil - до этого момента запросы клиента отклоняются из кэша отказов; пусто, если отказа нет.
+	DeniedUntil *time.Time `json:"denied_until,omitempty"`
+}
+
+// BucketState возвращает состояние корзины с ключом key (ID клиента или ключ по key_template, см. LimitKey)
+// без расхода токенов. Корзины нет в памяти - состояние рассчитывается так же, как при ее создании:
+// по лимитам и сохраненному состоянию из хранилища; сама корзина при этом не создается.
+func (rl *RateLimiter) BucketState(key string) (BucketState, error) {
+	if !rl.enabled {
+		return BucketState{}, ErrDisabled
+	}
+	state := BucketState{Key: key, Source: BucketSourceMemory}
+
+	rl.mu.RLock()
+	bucket, ok := rl.buckets[key]
+	rl.mu.RUnlock()
+	if !ok {
+		var err error
+		if bucket, state.Source, err = rl.storedBucket(key); err != nil {
+			return BucketState{}, err
+		}
+	}
+
+	bucket.mu.Lock()
+	lastRefill := bucket.lastRefill
+	bucket.refill()
+	state.Tokens, state.Capacity, state.Rate = max(bucket.tokens, 0), bucket.capacity, bucket.rate
+	bucket.mu.Unlock()
+	if !lastRefill.IsZero() {
+		state.LastRefill = &lastRefill
+	}
+
+	if value, ok := rl.deniedUntil.Load(key); ok && time.Now().UnixNano() < value.(denial).until {
+		deniedUntil := time.Unix(0, value.(denial).until)
+		state.DeniedUntil = &deniedUntil
+	}
+	return state, nil
+}
+
+// storedBucket собирает корзину, которую получил бы клиент без корзины в памяти, не добавляя ее в Rate Limiter.
+// В отличие от getOrCreateBucket, ошибки хранилища возвращаются независимо от store_failure_policy.
+func (rl *RateLimiter) storedBucket(key string) (*TokenBucket, string, error) {
+	defaults, _ := rl.defaultsFor(SourceOf(key).Anonymous())
+	bucket := &TokenBucket{rate: defaults.Rate, capacity: defaults.Capacity, tokens: defaults.Capacity}
+	source := BucketSourceDefault
+	if rl.store != nil {
+		rate, capacity, found, err := rl.fetchLimitConfig(key)
+		if err != nil {
+			return nil, "", err
+		}
+		if found {
+			bucket.rate, bucket.capacity, bucket.tokens = rate, capacity, capacity
+		}
+		if stateStore, ok := rl.store.(StateStore); ok && rl.store.SupportsStatePersistence() {
+			tokens, lastRefill, found, err := rl.fetchSavedState(stateStore, key)
+			if err != nil {
+				return nil, "", err
+			}
+			if found {
+				bucket.tokens, bucket.lastRefill = min(tokens, bucket.capacity), lastRefill
+				source = BucketSourceStore
+			}
+		}
+	}
+	if rl.slidingWindow {
+		bucket.window = &slidingWindow{start: bucket.lastRefill, current: bucket.capacity - bucket.tokens}
+	}
+	return bucket, source, nil
+}
@@ -0,0 +1,87 @@
+package ratelimiter
+
+import (
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+	"load-balancer/internal/storage"
+)
+
+// TrialSweepInterval - период проверки закончившихся пробных периодов клиентов.
+const TrialSweepInterval = time.Minute
+
+var trialsExpiredTotal = metrics.NewCounter("ratelimiter_trials_expired_total",
+	"Количество клиентов, переведенных на план после окончания пробного периода.")
+
+// TrialStore - хранилище пробных лимитов клиентов (реализуется *storage.DB).
+type TrialStore interface {
+	ExpiredClientTrials(now time.Time) ([]storage.ClientTrial, error)
+	DowngradeClientTrial(trial storage.ClientTrial, limit config.ClientRateConfig) (bool, error)
+}
+
+// SetPlans задает планы (rate_limiter.templates), на которые клиенты переводятся после пробного периода.
+// Может вызываться во время работы (при перечитывании конфигурации).
+func (rl *RateLimiter) SetPlans(plans map[string]config.ClientRateConfig) {
+	rl.plans.Store(&plans)
+}
+
+// Plan возвращает лимиты плана по имени.
+func (rl *RateLimiter) Plan(name string) (config.ClientRateConfig, bool) {
+	plans := rl.plans.Load()
+	if plans == nil {
+		return config.ClientRateConfig{}, false
+	}
+	plan, ok := (*plans)[name]
+	return plan, ok
+}
+
+// trialSweeper - горутина, переводящая клиентов с закончившимся пробным периодом на их планы.
+// Первая проверка выполняется сразу: пробные периоды могли закончиться, пока балансировщик не работал.
+func (rl *RateLimiter) trialSweeper(store TrialStore) {
+	defer rl.wg.Done()
+	ticker := time.NewTicker(TrialSweepInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := rl.ExpireTrials(store, time.Now()); err != nil {
+			i18n.Logf(i18n.RLTrialSweepFailed, err)
+		}
+		select {
+		case <-ticker.C:
+		case <-rl.quit:
+			return
+		}
+	}
+}
+
+// ExpireTrials переводит клиентов, чей пробный период закончился к моменту now, на лимиты их планов.
+// Клиент с неизвестным планом (шаблон удален из конфигурации) остается на пробных лимитах до исправления
+// конфигурации. Корзины в памяти получают новые лимиты при следующем запросе клиента.
+// Возвращает количество переведенных клиентов.
+func (rl *RateLimiter) ExpireTrials(store TrialStore, now time.Time) (int, error) {
+	trials, err := store.ExpiredClientTrials(now)
+	if err != nil {
+		return 0, err
+	}
+
+	downgraded := 0
+	for _, trial := range trials {
+		plan, ok := rl.Plan(trial.Plan)
+		if !ok {
+			i18n.Logf(i18n.RLTrialUnknownPlan, trial.ClientID, trial.Plan)
+			continue
+		}
+		changed, err := store.DowngradeClientTrial(trial, plan)
+		if err != nil {
+			return downgraded, err
+		}
+		if !changed {
+			continue // Пробный период изменили после выборки
+		}
+		downgraded++
+		trialsExpiredTotal.Inc()
+		i18n.Logf(i18n.RLTrialExpired, trial.ClientID, trial.Plan, plan.Rate, plan.Capacity)
+	}
+	return downgraded, nil
+}
@@ -0,0 +1,92 @@
+package ratelimiter
+
+import (
+	"math"
+	"time"
+)
+
+// slidingWindow - счетчик скользящего окна (rate_limiter.algorithm: sliding_window). Окно длиной capacity/rate
+// делится на фиксированные интервалы; расход за последнее окно оценивается как расход текущего интервала
+// плюс доля расхода предыдущего, пропорциональная его перекрытию с окном. В отличие от корзины токенов,
+// клиент, израсходовавший capacity разом, снова получает запросы только по мере выхода расхода из окна,
+// а не по одному каждые 1/rate секунд.
+type slidingWindow struct {
+	start    time.Time // Начало текущего интервала.
+	current  float64   // Расход в текущем интервале.
+	previous float64   // Расход в предыдущем интервале.
+}
+
+// windowLength возвращает длину окна корзины; 0 - окно не сдвигается (rate = 0).
+// Должен вызываться под блокировкой bucket.mu.
+func (tb *TokenBucket) windowLength() time.Duration {
+	if tb.rate <= 0 {
+		return 0
+	}
+	return time.Duration(tb.capacity / tb.rate * float64(time.Second))
+}
+
+// slide сдвигает окно на момент now и пересчитывает tokens как емкость за вычетом расхода в окне.
+// Должен вызываться под блокировкой bucket.mu.
+func (tb *TokenBucket) slide(now time.Time) {
+	w := tb.window
+	length := tb.windowLength()
+	if w.start.IsZero() {
+		w.start = now
+	}
+	if length > 0 {
+		if elapsed := now.Sub(w.start); elapsed >= length {
+			intervals := elapsed / length
+			w.previous = w.current
+			if intervals > 1 {
+				w.previous = 0
+			}
+			w.current = 0
+			w.start = w.start.Add(intervals * length)
+		}
+	}
+	tb.tokens = max(tb.capacity-tb.windowUsage(now, length), 0)
+	tb.lastRefill = now
+}
+
+// windowUsage возвращает расход за окно, заканчивающееся в now. Должен вызываться под блокировкой bucket.mu.
+func (tb *TokenBucket) windowUsage(now time.Time, length time.Duration) float64 {
+	w := tb.window
+	if length <= 0 {
+		return w.previous + w.current
+	}
+	overlap := 1 - float64(now.Sub(w.start))/float64(length)
+	return w.previous*max(overlap, 0) + w.current
+}
+
+// consume списывает cost токенов; в скользящем окне расход учитывается в текущем интервале.
+// Должен вызываться под блокировкой bucket.mu.
+func (tb *TokenBucket) consume(cost float64) {
+	tb.tokens -= cost
+	if tb.window != nil {
+		tb.window.current += cost
+	}
+}
+
+// untilWindowTokens возвращает, через сколько расход выйдет из окна настолько, что в нем освободится n токенов;
+// 0 - уже свободно или окно не сдвигается. Должен вызываться под блокировкой bucket.mu.
+func (tb *TokenBucket) untilWindowTokens(n float64) time.Duration {
+	w := tb.window
+	length := tb.windowLength()
+	if length <= 0 || tb.tokens >= n-floatEpsilon {
+		return 0
+	}
+	allowed := max(tb.capacity-n, 0) // Допустимый расход в окне
+	// Пока идет текущий интервал, из окна постепенно уходит расход предыдущего
+	var at float64 // Момент в долях окна от начала текущего интервала
+	switch {
+	case w.current <= allowed && w.previous > 0:
+		at = 1 - (allowed-w.current)/w.previous
+	case w.current <= allowed:
+		return 0
+	case w.current > 0:
+		// В следующем интервале из окна уходит расход текущего
+		at = 2 - allowed/w.current
+	}
+	until := w.start.Add(time.Duration(math.Ceil(at * float64(length)))).Sub(tb.lastRefill)
+	return max(until, 0)
+}
@@ -0,0 +1,59 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header - заголовок, в котором передается идентификатор запроса.
+const Header = "X-Request-ID"
+
+// maxLength - максимальная длина идентификатора, принимаемого от клиента.
+const maxLength = 128
+
+type contextKey struct{}
+
+// Middleware присваивает каждому запросу идентификатор: берет корректный X-Request-ID клиента
+// или генерирует новый. Идентификатор кладется в контекст, в заголовок запроса
+// (чтобы дойти до бэкенда) и в заголовок ответа (чтобы его видел клиент).
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = New()
+		}
+		r.Header.Set(Header, id)
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, id)))
+	})
+}
+
+// FromContext возвращает идентификатор запроса или пустую строку, если его нет.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// New генерирует новый случайный идентификатор (32 hex-символа).
+func New() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// valid проверяет, что идентификатор клиента безопасно писать в логи и заголовки.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
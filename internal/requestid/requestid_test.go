@@ -0,0 +1,47 @@
+package requestid_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"load-balancer/internal/requestid"
+)
+
+// TestMiddleware проверяет генерацию и проброс идентификатора запроса.
+func TestMiddleware(t *testing.T) {
+	var seenCtx, seenHeader string
+	handler := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenCtx = requestid.FromContext(r.Context())
+		seenHeader = r.Header.Get(requestid.Header)
+	}))
+
+	// Без заголовка - генерируется новый идентификатор
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	generated := rr.Header().Get(requestid.Header)
+	assert.Len(t, generated, 32)
+	assert.Equal(t, generated, seenCtx)
+	assert.Equal(t, generated, seenHeader)
+
+	// Корректный идентификатор клиента сохраняется
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestid.Header, "client-req-42")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, "client-req-42", rr.Header().Get(requestid.Header))
+	assert.Equal(t, "client-req-42", seenCtx)
+
+	// Некорректный (слишком длинный или с недопустимыми символами) - заменяется
+	for _, bad := range []string{strings.Repeat("a", 200), "id with spaces", "id\r\ninjected"} {
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header[requestid.Header] = []string{bad}
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.NotEqual(t, bad, seenCtx)
+		assert.Len(t, seenCtx, 32)
+	}
+}
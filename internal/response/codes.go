@@ -0,0 +1,57 @@
+package response
+
+// ErrorCode - стабильный машиночитаемый код ошибки, передаваемый в поле error_code.
+// В отличие от message, значения кодов не меняются и подходят для обработки клиентами.
+type ErrorCode string
+
+// Коды ошибок прокси.
+const (
+	CodeRateLimited        ErrorCode = "RATE_LIMITED"
+	CodeRateLimitStoreDown ErrorCode = "RATE_LIMIT_STORE_UNAVAILABLE"
+	CodeNoHealthyBackends  ErrorCode = "NO_HEALTHY_BACKENDS"
+	CodeBadGateway         ErrorCode = "BAD_GATEWAY"
+	// CodeGatewayTimeout - бэкенд не начал отвечать за timeouts.backend_response (или backend_timeout маршрута).
+	CodeGatewayTimeout ErrorCode = "GATEWAY_TIMEOUT"
+	// CodeNotProxyRequest - forward-прокси получил запрос без CONNECT и без абсолютного URL.
+	CodeNotProxyRequest ErrorCode = "NOT_A_PROXY_REQUEST"
+	// CodeOverloaded - все слоты concurrency guard заняты, а очередь заполнена или ожидание истекло.
+	CodeOverloaded ErrorCode = "OVERLOADED"
+	// CodeAccessDenied - клиент в списке запрета (access_list или /access).
+	CodeAccessDenied ErrorCode = "ACCESS_DENIED"
+	// CodeRequestExpired - запрос провел в балансировщике (очередь, повторы) дольше request_age.max_age.
+	CodeRequestExpired ErrorCode = "REQUEST_EXPIRED"
+	// CodeTooManyInFlight - у клиента уже rate_limiter.max_in_flight_per_client запросов в обработке.
+	CodeTooManyInFlight ErrorCode = "TOO_MANY_IN_FLIGHT"
+	// CodeHookRejected - запрос отклонен сценарием admission_hook.
+	CodeHookRejected ErrorCode = "HOOK_REJECTED"
+)
+
+// Коды ошибок API управления.
+const (
+	CodeStoreUnavailable ErrorCode = "STORE_UNAVAILABLE"
+	CodeInvalidJSON      ErrorCode = "INVALID_JSON"
+	CodeValidationFailed ErrorCode = "VALIDATION_FAILED"
+	CodeClientExists     ErrorCode = "CLIENT_EXISTS"
+	CodeClientNotFound   ErrorCode = "CLIENT_NOT_FOUND"
+	CodeEntryNotFound    ErrorCode = "ACCESS_ENTRY_NOT_FOUND"
+	CodeAPIKeyNotFound   ErrorCode = "API_KEY_NOT_FOUND"
+	CodeMethodNotAllowed ErrorCode = "METHOD_NOT_ALLOWED"
+	CodeNotImplemented   ErrorCode = "NOT_IMPLEMENTED"
+	CodeInternal         ErrorCode = "INTERNAL_ERROR"
+	// CodeUnauthorized - запрос без учетных данных admin_auth или с неверными.
+	CodeUnauthorized ErrorCode = "UNAUTHORIZED"
+	// CodeForbidden - роли пользователя admin_auth не хватает для метода запроса.
+	CodeForbidden ErrorCode = "FORBIDDEN"
+	// CodeRateLimiterDisabled - операция с корзинами при выключенном rate_limiter.
+	CodeRateLimiterDisabled ErrorCode = "RATE_LIMITER_DISABLED"
+)
+
+// Коды ошибок служебного API (/admin).
+const (
+	CodeNotFound        ErrorCode = "NOT_FOUND"
+	CodeBackendNotFound ErrorCode = "BACKEND_NOT_FOUND"
+	CodeBackendExists   ErrorCode = "BACKEND_EXISTS"
+	// CodeLastBackend - попытка удалить единственный бэкенд пула.
+	CodeLastBackend          ErrorCode = "LAST_BACKEND"
+	CodeHealthChecksDisabled ErrorCode = "HEALTH_CHECKS_DISABLED"
+)
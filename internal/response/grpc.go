@@ -0,0 +1,85 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/requestid"
+)
+
+// GRPCStatus - код статуса gRPC (google.golang.org/grpc/codes), передаваемый в трейлере grpc-status.
+type GRPCStatus int
+
+// Коды gRPC, в которые отображаются ошибки балансировщика и бэкендов.
+const (
+	GRPCUnknown           GRPCStatus = 2
+	GRPCPermissionDenied  GRPCStatus = 7
+	GRPCResourceExhausted GRPCStatus = 8
+	GRPCUnimplemented     GRPCStatus = 12
+	GRPCInternal          GRPCStatus = 13
+	GRPCUnavailable       GRPCStatus = 14
+	GRPCUnauthenticated   GRPCStatus = 16
+)
+
+// GRPCContentType - базовый Content-Type запросов и ответов gRPC.
+const GRPCContentType = "application/grpc"
+
+// IsGRPC сообщает, является ли запрос вызовом gRPC ("application/grpc" или "application/grpc+<codec>").
+// gRPC-Web ("application/grpc-web") сюда не относится: он работает поверх HTTP/1.1 и не использует трейлеры.
+func IsGRPC(r *http.Request) bool {
+	return IsGRPCContentType(r.Header.Get("Content-Type"))
+}
+
+// IsGRPCContentType сообщает, относится ли Content-Type к gRPC.
+func IsGRPCContentType(ct string) bool {
+	return ct == GRPCContentType || strings.HasPrefix(ct, GRPCContentType+"+") || strings.HasPrefix(ct, GRPCContentType+";")
+}
+
+// GRPCStatusFromHTTP отображает HTTP-статус в код gRPC по таблице из спецификации
+// "HTTP to gRPC Status Code Mapping".
+func GRPCStatusFromHTTP(statusCode int) GRPCStatus {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return GRPCInternal
+	case http.StatusUnauthorized:
+		return GRPCUnauthenticated
+	case http.StatusForbidden:
+		return GRPCPermissionDenied
+	case http.StatusNotFound:
+		return GRPCUnimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return GRPCUnavailable
+	default:
+		return GRPCUnknown
+	}
+}
+
+// RespondWithGRPCError отправляет ответ gRPC "Trailers-Only": HTTP 200 без тела,
+// статус вызова передается в заголовках grpc-status и grpc-message.
+// Клиенты gRPC не умеют разбирать JSON-ошибки, поэтому для них используется этот формат.
+func RespondWithGRPCError(w http.ResponseWriter, status GRPCStatus, errorCode ErrorCode, message string) {
+	i18n.Logf(i18n.ResponseGRPCError, status, errorCode, w.Header().Get(requestid.Header), message)
+	h := w.Header()
+	h.Set("Content-Type", GRPCContentType)
+	h.Set("Grpc-Status", strconv.Itoa(int(status)))
+	h.Set("Grpc-Message", EncodeGRPCMessage(message))
+	w.WriteHeader(http.StatusOK)
+}
+
+// EncodeGRPCMessage кодирует grpc-message: печатные ASCII-символы, кроме '%', передаются как есть,
+// остальные байты - в виде %XX (требование протокола gRPC over HTTP/2).
+func EncodeGRPCMessage(message string) string {
+	var sb strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
@@ -2,23 +2,32 @@ package response
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
+
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/requestid"
 )
 
 // ErrorResponse представляет стандартный формат ответа для ошибок API.
 type ErrorResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code      int       `json:"code"`
+	ErrorCode ErrorCode `json:"error_code"`
+	Message   string    `json:"message"`
+	RequestID string    `json:"request_id,omitempty"` // Идентификатор запроса для поиска в логах.
 }
 
 // RespondWithError отправляет JSON-ответ с ошибкой.
-func RespondWithError(w http.ResponseWriter, statusCode int, message string) {
+// errorCode - стабильный машиночитаемый код (см. codes.go), message - описание для человека.
+// Идентификатор запроса берется из заголовка ответа, выставленного requestid.Middleware.
+func RespondWithError(w http.ResponseWriter, statusCode int, errorCode ErrorCode, message string) {
+	requestID := w.Header().Get(requestid.Header)
 	// Логируем ошибку перед отправкой ответа
-	log.Printf("[Error] Status: %d, Message: %s", statusCode, message)
+	i18n.Logf(i18n.ResponseError, statusCode, errorCode, requestID, message)
 	responsePayload := ErrorResponse{
-		Code:    statusCode,
-		Message: message,
+		Code:      statusCode,
+		ErrorCode: errorCode,
+		Message:   message,
+		RequestID: requestID,
 	}
 	RespondWithJSON(w, statusCode, responsePayload)
 }
@@ -29,7 +38,7 @@ func RespondWithJSON(w http.ResponseWriter, statusCode int, payload interface{})
 	response, err := json.Marshal(payload)
 	if err != nil {
 		// В случае ошибки маршалинга, отправляем текстовую ошибку 500
-		log.Printf("[Error] Ошибка маршалинга JSON-ответа: %v", err)
+		i18n.Logf(i18n.ResponseMarshalFailed, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Internal Server Error")) // Пишем как []byte
 		return
@@ -40,6 +49,6 @@ func RespondWithJSON(w http.ResponseWriter, statusCode int, payload interface{})
 	_, err = w.Write(response)
 	if err != nil {
 		// Логируем ошибку записи ответа, но статус уже отправлен
-		log.Printf("[Error] Ошибка записи JSON-ответа клиенту: %v", err)
+		i18n.Logf(i18n.ResponseWriteFailed, err)
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"load-balancer/internal/requestid"
 	"load-balancer/internal/response"
 )
 
@@ -18,7 +19,7 @@ func TestRespondWithError(t *testing.T) {
 	code := http.StatusNotFound
 	message := "Resource not found"
 
-	response.RespondWithError(w, code, message)
+	response.RespondWithError(w, code, response.CodeClientNotFound, message)
 
 	// Проверяем статус код
 	assert.Equal(t, code, w.Code, "Неверный статус код")
@@ -32,6 +33,25 @@ func TestRespondWithError(t *testing.T) {
 	require.NoError(t, err, "Не удалось распарсить JSON ответа")
 	assert.Equal(t, code, errResp.Code, "Неверный код в теле ответа JSON")
 	assert.Equal(t, message, errResp.Message, "Неверное сообщение об ошибке в JSON")
+	assert.Equal(t, response.CodeClientNotFound, errResp.ErrorCode, "Неверный error_code в JSON")
+	assert.Empty(t, errResp.RequestID, "request_id не должен появляться без middleware")
+}
+
+// TestRespondWithError_RequestID проверяет, что идентификатор запроса попадает в тело ошибки.
+func TestRespondWithError_RequestID(t *testing.T) {
+	handler := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.RespondWithError(w, http.StatusTooManyRequests, response.CodeRateLimited, "Rate limit exceeded")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestid.Header, "req-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var errResp response.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, "req-123", errResp.RequestID, "Неверный request_id в JSON")
+	assert.Equal(t, "req-123", w.Header().Get(requestid.Header), "Неверный заголовок X-Request-ID")
 }
 
 // TestRespondWithJSON проверяет функцию RespondWithJSON.
@@ -74,3 +94,35 @@ func TestRespondWithJSON_MarshalError(t *testing.T) {
 	// Проверяем тело ответа (должно быть текстовое "Internal Server Error")
 	assert.Equal(t, "Internal Server Error", w.Body.String(), "Неверное сообщение при ошибке маршалинга")
 }
+
+// TestRespondWithGRPCError проверяет ответ gRPC "Trailers-Only".
+func TestRespondWithGRPCError(t *testing.T) {
+	w := httptest.NewRecorder()
+	response.RespondWithGRPCError(w, response.GRPCResourceExhausted, response.CodeRateLimited, "лимит 100%")
+
+	assert.Equal(t, http.StatusOK, w.Code, "gRPC передает ошибку статусом вызова, а не HTTP-кодом")
+	assert.Equal(t, "application/grpc", w.Header().Get("Content-Type"))
+	assert.Equal(t, "8", w.Header().Get("Grpc-Status"))
+	assert.Equal(t, "%D0%BB%D0%B8%D0%BC%D0%B8%D1%82 100%25", w.Header().Get("Grpc-Message"))
+	assert.Empty(t, w.Body.String())
+}
+
+// TestIsGRPC проверяет распознавание вызовов gRPC по Content-Type.
+func TestIsGRPC(t *testing.T) {
+	tests := map[string]bool{
+		"application/grpc":       true,
+		"application/grpc+proto": true,
+		"application/grpc-web":   false,
+		"application/json":       false,
+		"":                       false,
+	}
+	for ct, want := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Content-Type", ct)
+		assert.Equal(t, want, response.IsGRPC(req), "Content-Type %q", ct)
+	}
+
+	assert.Equal(t, response.GRPCUnavailable, response.GRPCStatusFromHTTP(http.StatusBadGateway))
+	assert.Equal(t, response.GRPCUnimplemented, response.GRPCStatusFromHTTP(http.StatusNotFound))
+	assert.Equal(t, response.GRPCUnknown, response.GRPCStatusFromHTTP(http.StatusTeapot))
+}
@@ -0,0 +1,49 @@
+package simulate
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"load-balancer/internal/i18n"
+)
+
+// WriteText выводит отчет в виде таблиц для оператора.
+func (r *Report) WriteText(w io.Writer) error {
+	limiter := i18n.T(i18n.SimReportLimiterOff)
+	if r.RateLimiter {
+		limiter = i18n.T(i18n.SimReportLimiterOn)
+	}
+	fmt.Fprintln(w, i18n.T(i18n.SimReportSummary, r.Duration, r.Algorithm, limiter))
+	fmt.Fprintln(w, i18n.T(i18n.SimReportTotals, r.Requests, r.Allowed, r.Rejected, percent(r.Rejected, r.Requests), r.NoBackend))
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, i18n.T(i18n.SimReportClientsHeader))
+	for _, c := range r.Clients {
+		fmt.Fprintf(tw, "%s\t%d\t%.4g/%.4g\t%d\t%d\t%d (%.1f%%)\t%d\n",
+			c.ID, c.Count, c.Rate, c.Capacity, c.Requests, c.Allowed, c.Rejected, percent(c.Rejected, c.Requests), c.SoftWarnings)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, i18n.T(i18n.SimReportBackendsHeader))
+	for _, b := range r.Backends {
+		status := ""
+		if b.Down {
+			status = i18n.T(i18n.SimReportBackendDown)
+		}
+		saturation := "-"
+		if b.Saturation > 0 {
+			saturation = fmt.Sprintf("%.0f%%", b.Saturation*100)
+		}
+		fmt.Fprintf(tw, "%s%s\t%d\t%.1f%%\t%.1f\t%.0f\t%s\n",
+			b.URL, status, b.Requests, b.Share*100, b.AverageRPS, b.PeakRPS, saturation)
+	}
+	return tw.Flush()
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
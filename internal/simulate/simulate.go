@@ -0,0 +1,482 @@
+package simulate
+
+import (
+	"math"
+	"math/rand"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/hashring"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/ratelimiter"
+)
+
+// Scenario описывает синтетический трафик для симуляции.
+type Scenario struct {
+	DurationStr string `yaml:"duration"` // Длительность симуляции (например, "60s"), по умолчанию 60s.
+	// StepStr - шаг модельного времени (например, "10ms"): за шаг корзины пополняются,
+	// а клиенты отправляют накопившиеся запросы. По умолчанию 10ms.
+	StepStr string `yaml:"step"`
+	Seed    int64  `yaml:"seed"` // Начальное значение генератора для алгоритма random.
+	// BackendCapacityRPS - сколько запросов в секунду выдерживает один бэкенд (0 - насыщение не оценивается).
+	BackendCapacityRPS float64 `yaml:"backend_capacity_rps"`
+	// BackendsDown - URL бэкендов, которые считаются недоступными всю симуляцию.
+	BackendsDown []string        `yaml:"backends_down"`
+	Clients      []ClientTraffic `yaml:"clients"`
+
+	Duration time.Duration `yaml:"-"`
+	Step     time.Duration `yaml:"-"`
+}
+
+// ClientTraffic - группа одинаковых клиентов.
+type ClientTraffic struct {
+	// ID - идентификатор клиента, как его определяет Rate Limiter: значение identifier_header или IP-адрес.
+	ID string `yaml:"id"`
+	// Count - число клиентов в группе. Для IP-адреса клиенты получают следующие по порядку адреса,
+	// для остальных ID - суффиксы "-1", "-2" и т.д. По умолчанию 1.
+	Count int     `yaml:"count"`
+	RPS   float64 `yaml:"rps"`  // Запросов в секунду от каждого клиента группы.
+	Path  string  `yaml:"path"` // Путь запросов (для выбора маршрута), по умолчанию "/".
+}
+
+// LoadScenario читает и проверяет описание трафика.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, i18n.Errorf(i18n.SimScenarioReadFailed, path, err)
+	}
+	var sc Scenario
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		return nil, i18n.Errorf(i18n.SimScenarioParseFailed, path, err)
+	}
+	if err := sc.Parse(); err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+// Parse применяет значения по умолчанию, разбирает длительности и проверяет сценарий.
+func (sc *Scenario) Parse() error {
+	durations := []struct {
+		name  string
+		value *string
+		def   string
+		dest  *time.Duration
+	}{
+		{"duration", &sc.DurationStr, "60s", &sc.Duration},
+		{"step", &sc.StepStr, "10ms", &sc.Step},
+	}
+	for _, d := range durations {
+		if *d.value == "" {
+			*d.value = d.def
+		}
+		parsed, err := time.ParseDuration(*d.value)
+		if err != nil || parsed <= 0 {
+			return i18n.Errorf(i18n.SimBadDuration, d.name, *d.value)
+		}
+		*d.dest = parsed
+	}
+	if sc.Step > sc.Duration {
+		return i18n.Errorf(i18n.SimStepTooLong, sc.StepStr, sc.DurationStr)
+	}
+	if sc.BackendCapacityRPS < 0 {
+		return i18n.Errorf(i18n.SimBadCapacity, sc.BackendCapacityRPS)
+	}
+	if len(sc.Clients) == 0 {
+		return i18n.Errorf(i18n.SimNoClients)
+	}
+	for i := range sc.Clients {
+		client := &sc.Clients[i]
+		if client.ID == "" || client.RPS <= 0 || client.Count < 0 {
+			return i18n.Errorf(i18n.SimBadClient, i)
+		}
+		if client.Count == 0 {
+			client.Count = 1
+		}
+		if client.Path == "" {
+			client.Path = "/"
+		}
+	}
+	return nil
+}
+
+// ClientReport - итоги группы клиентов.
+type ClientReport struct {
+	ID           string  `json:"id"`
+	Count        int     `json:"count"`
+	Requests     int     `json:"requests"`
+	Allowed      int     `json:"allowed"`
+	Rejected     int     `json:"rejected"` // Отклонены Rate Limiter (429, а сверх rate_limiter.global - 503).
+	SoftWarnings int     `json:"soft_warnings"`
+	Rate         float64 `json:"rate"` // Лимит одного клиента группы (0 - Rate Limiter выключен).
+	Capacity     float64 `json:"capacity"`
+}
+
+// BackendReport - нагрузка на один бэкенд.
+type BackendReport struct {
+	URL        string  `json:"url"`
+	Down       bool    `json:"down,omitempty"`
+	Requests   int     `json:"requests"`
+	Share      float64 `json:"share"` // Доля от всех запросов, дошедших до бэкендов.
+	AverageRPS float64 `json:"average_rps"`
+	PeakRPS    float64 `json:"peak_rps"` // Наибольшее число запросов за секунду модельного времени.
+	// Saturation - PeakRPS относительно backend_capacity_rps (0, если емкость не задана).
+	Saturation float64 `json:"saturation"`
+}
+
+// Report - результат симуляции.
+type Report struct {
+	Duration    string          `json:"duration"`
+	Algorithm   string          `json:"algorithm"`
+	RateLimiter bool            `json:"rate_limiter"`
+	Requests    int             `json:"requests"`
+	Allowed     int             `json:"allowed"`
+	Rejected    int             `json:"rejected"`
+	NoBackend   int             `json:"no_backend"` // Пропущены Rate Limiter, но для маршрута нет доступных бэкендов (503).
+	Clients     []ClientReport  `json:"clients"`
+	Backends    []BackendReport `json:"backends"`
+}
+
+// epsilon компенсирует ошибку округления при накоплении дробных запросов и токенов.
+const epsilon = 1e-9
+
+// bucket - модель корзины токенов (см. ratelimiter.TokenBucket).
+type bucket struct {
+	rate, capacity, tokens float64
+	// Модель скользящего окна (rate_limiter.algorithm: sliding_window): время от начала текущего интервала
+	// и расход в текущем и предыдущем интервалах.
+	window            bool
+	elapsed           float64
+	current, previous float64
+}
+
+// advance пополняет корзину за step секунд модельного времени.
+func (b *bucket) advance(step float64) {
+	if !b.window {
+		b.tokens = math.Min(b.capacity, b.tokens+b.rate*step)
+		return
+	}
+	if b.rate <= 0 {
+		return
+	}
+	length := b.capacity / b.rate
+	b.elapsed += step
+	if b.elapsed >= length {
+		intervals := math.Floor(b.elapsed / length)
+		b.previous = b.current
+		if intervals > 1 {
+			b.previous = 0
+		}
+		b.current = 0
+		b.elapsed -= intervals * length
+	}
+	b.tokens = math.Max(b.capacity-b.previous*(1-b.elapsed/length)-b.current, 0)
+}
+
+// take списывает токен за пропущенный запрос.
+func (b *bucket) take() {
+	b.tokens--
+	b.current++
+}
+
+// instance - один клиент группы.
+type instance struct {
+	group   int
+	key     string  // Корзина клиента (с учетом объединения IPv6 по префиксу).
+	pending float64 // Накопленная дробная часть запросов.
+}
+
+// pool - доступные бэкенды маршрута с очередью Round Robin.
+type pool struct {
+	candidates []int // Индексы доступных бэкендов, подходящих под backend_selector.
+	weights    []int // Веса кандидатов (backend_servers[].weight).
+	current    []int // Текущие веса плавного взвешенного Round Robin.
+	// ring - кольцо consistent_hash по всем бэкендам; inPool - какие из них являются кандидатами.
+	ring   *hashring.Ring
+	inPool map[int]bool
+}
+
+// next выбирает бэкенд так же, как балансировщик: по кольцу для ключа клиента (consistent_hash),
+// случайно с вероятностью по весу (random) или плавным взвешенным Round Robin.
+// При равных весах это обычный Round Robin.
+func (p *pool) next(rng *rand.Rand, algorithm, key string) int {
+	if algorithm == "consistent_hash" {
+		chosen := p.candidates[0]
+		p.ring.Walk(key, func(idx int) bool {
+			if p.inPool[idx] {
+				chosen = idx
+				return false
+			}
+			return true
+		})
+		return chosen
+	}
+	random := algorithm == "random"
+	total := 0
+	for _, weight := range p.weights {
+		total += weight
+	}
+	if random {
+		n := rng.Intn(total)
+		for i, weight := range p.weights {
+			if n -= weight; n < 0 {
+				return p.candidates[i]
+			}
+		}
+	}
+	best := 0
+	for i, weight := range p.weights {
+		p.current[i] += weight
+		if p.current[i] > p.current[best] {
+			best = i
+		}
+	}
+	p.current[best] -= total
+	return p.candidates[best]
+}
+
+// simRoute - маршрут из конфигурации.
+type simRoute struct {
+	prefix string
+	pool   *pool
+}
+
+// Run моделирует трафик сценария на конфигурации cfg без сети и реального времени.
+// Модель повторяет решения балансировщика: лимиты клиентов из конфигурации (индивидуальные, по шаблону
+// или по умолчанию для типа идентификатора), мягкий порог, маршруты с backend_selector и алгоритм выбора бэкенда.
+// Лимиты, созданные через API и хранящиеся только в базе, не учитываются.
+func Run(cfg *config.Config, sc *Scenario) *Report {
+	rlCfg := cfg.RateLimiter
+	report := &Report{
+		Duration:    sc.Duration.String(),
+		Algorithm:   strings.ToLower(cfg.LoadBalancingAlgorithm),
+		RateLimiter: rlCfg.Enabled,
+	}
+	if report.Algorithm != "random" && report.Algorithm != "consistent_hash" {
+		report.Algorithm = "round_robin"
+	}
+
+	down := make(map[string]bool, len(sc.BackendsDown))
+	for _, url := range sc.BackendsDown {
+		down[url] = true
+	}
+	for _, url := range cfg.BackendServers {
+		report.Backends = append(report.Backends, BackendReport{URL: url, Down: down[url]})
+	}
+	routes, defaultPool := buildRoutes(cfg, report.Backends)
+
+	// Клиенты и их корзины
+	buckets := make(map[string]*bucket)
+	var instances []*instance
+	for g, group := range sc.Clients {
+		report.Clients = append(report.Clients, ClientReport{ID: group.ID, Count: group.Count})
+		for j := 0; j < group.Count; j++ {
+			key := bucketKey(clientID(group.ID, j, group.Count), rlCfg.IPv6PrefixLength)
+			if _, ok := buckets[key]; !ok && rlCfg.Enabled {
+				limit := limitFor(&rlCfg, key)
+				buckets[key] = &bucket{rate: limit.Rate, capacity: limit.Capacity, tokens: limit.Capacity,
+					window: rlCfg.Algorithm == config.RateLimitSlidingWindow}
+				report.Clients[g].Rate, report.Clients[g].Capacity = limit.Rate, limit.Capacity
+			}
+			// Клиенты группы сдвинуты по фазе, чтобы не отправлять запросы одновременно
+			instances = append(instances, &instance{group: g, key: key, pending: float64(j) / float64(group.Count)})
+		}
+	}
+
+	// Общая корзина всего сервиса (rate_limiter.global)
+	var global *bucket
+	if rlCfg.Enabled && rlCfg.Global.Enabled() {
+		global = &bucket{rate: rlCfg.Global.Rate, capacity: rlCfg.Global.Capacity, tokens: rlCfg.Global.Capacity,
+			window: rlCfg.Algorithm == config.RateLimitSlidingWindow}
+	}
+
+	rng := rand.New(rand.NewSource(sc.Seed))
+	step := sc.Step.Seconds()
+	steps := int(sc.Duration / sc.Step)
+	stepsPerSecond := max(int(time.Second/sc.Step), 1)
+	window := make([]int, len(report.Backends))
+
+	for s := 0; s < steps; s++ {
+		for _, b := range buckets {
+			b.advance(step)
+		}
+		if global != nil {
+			global.advance(step)
+		}
+		for _, inst := range instances {
+			group := sc.Clients[inst.group]
+			inst.pending += group.RPS * step
+			for ; inst.pending >= 1-epsilon; inst.pending-- {
+				stats := &report.Clients[inst.group]
+				stats.Requests++
+				if b := buckets[inst.key]; b != nil {
+					// Общий лимит проверяется первым, но расходуется только пропущенными запросами
+					if b.tokens < 1-epsilon || (global != nil && global.tokens < 1-epsilon) {
+						stats.Rejected++
+						continue
+					}
+					if global != nil {
+						global.take()
+					}
+					b.take()
+					if rlCfg.SoftLimitRatio > 0 && b.tokens <= b.capacity*(1-rlCfg.SoftLimitRatio) {
+						stats.SoftWarnings++
+					}
+				}
+				stats.Allowed++
+
+				p := matchPool(routes, defaultPool, group.Path)
+				if len(p.candidates) == 0 {
+					report.NoBackend++
+					continue
+				}
+				idx := p.next(rng, report.Algorithm, inst.key)
+				report.Backends[idx].Requests++
+				window[idx]++
+			}
+		}
+		// Пиковая нагрузка считается по полным секундам модельного времени
+		if (s+1)%stepsPerSecond == 0 {
+			for i, count := range window {
+				report.Backends[i].PeakRPS = math.Max(report.Backends[i].PeakRPS, float64(count))
+				window[i] = 0
+			}
+		}
+	}
+
+	seconds := sc.Duration.Seconds()
+	served := 0
+	for _, backend := range report.Backends {
+		served += backend.Requests
+	}
+	for i := range report.Backends {
+		backend := &report.Backends[i]
+		backend.AverageRPS = float64(backend.Requests) / seconds
+		if steps < stepsPerSecond {
+			backend.PeakRPS = backend.AverageRPS // Симуляция короче секунды
+		}
+		if served > 0 {
+			backend.Share = float64(backend.Requests) / float64(served)
+		}
+		if sc.BackendCapacityRPS > 0 {
+			backend.Saturation = backend.PeakRPS / sc.BackendCapacityRPS
+		}
+	}
+	for _, client := range report.Clients {
+		report.Requests += client.Requests
+		report.Allowed += client.Allowed
+		report.Rejected += client.Rejected
+	}
+	return report
+}
+
+// buildRoutes готовит маршруты с самым длинным префиксом первым (как в балансировщике).
+// Маршруты без backend_selector используют общую с маршрутом по умолчанию очередь Round Robin.
+func buildRoutes(cfg *config.Config, backends []BackendReport) ([]simRoute, *pool) {
+	names := make([]string, len(backends))
+	weights := make([]int, len(backends))
+	for i, backend := range backends {
+		names[i] = backend.URL
+		weights[i] = cfg.BackendWeights[backend.URL]
+	}
+	ring := hashring.New(names, weights, hashring.DefaultReplicas)
+
+	newPool := func(selector map[string]string) *pool {
+		p := &pool{ring: ring, inPool: make(map[int]bool)}
+		for i, backend := range backends {
+			if !backend.Down && matches(cfg.BackendLabels[backend.URL], selector) {
+				p.candidates = append(p.candidates, i)
+				p.inPool[i] = true
+				weight := 1
+				if w, ok := cfg.BackendWeights[backend.URL]; ok {
+					weight = w
+				}
+				p.weights = append(p.weights, weight)
+				p.current = append(p.current, 0)
+			}
+		}
+		return p
+	}
+
+	defaultPool := newPool(nil)
+	routes := make([]simRoute, 0, len(cfg.Routes))
+	for _, rc := range cfg.Routes {
+		rt := simRoute{prefix: rc.PathPrefix, pool: defaultPool}
+		if len(rc.BackendSelector) > 0 {
+			rt.pool = newPool(rc.BackendSelector)
+		}
+		routes = append(routes, rt)
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].prefix) > len(routes[j].prefix)
+	})
+	return routes, defaultPool
+}
+
+// matchPool возвращает бэкенды маршрута с самым длинным совпавшим префиксом.
+func matchPool(routes []simRoute, defaultPool *pool, path string) *pool {
+	for _, rt := range routes {
+		if strings.HasPrefix(path, rt.prefix) {
+			return rt.pool
+		}
+	}
+	return defaultPool
+}
+
+// matches повторяет balancer.Backend.Matches.
+func matches(labels, selector map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// clientID возвращает ID j-го клиента группы.
+func clientID(id string, j, count int) string {
+	if count == 1 {
+		return id
+	}
+	if addr, err := netip.ParseAddr(id); err == nil {
+		for ; j > 0; j-- {
+			addr = addr.Next()
+		}
+		return addr.String()
+	}
+	return id + "-" + strconv.Itoa(j+1)
+}
+
+// bucketKey повторяет объединение IPv6-клиентов по префиксу (rate_limiter.ipv6_prefix_length).
+func bucketKey(id string, prefixLength int) string {
+	if addr, err := netip.ParseAddr(id); err == nil && addr.Is6() && prefixLength > 0 {
+		return netip.PrefixFrom(addr, prefixLength).Masked().String()
+	}
+	return id
+}
+
+// limitFor возвращает лимит клиента: индивидуальный из rate_limiter.clients (шаблоны уже подставлены
+// при загрузке конфигурации) или по умолчанию для способа идентификации.
+func limitFor(cfg *config.RateLimiterConfig, clientID string) config.ClientRateConfig {
+	if limit, ok := cfg.Clients[clientID]; ok {
+		return limit
+	}
+	rate, capacity := cfg.DefaultRateHeader, cfg.DefaultCapacityHeader
+	if ratelimiter.IsAddressID(clientID) {
+		rate, capacity = cfg.DefaultRateIP, cfg.DefaultCapacityIP
+	}
+	if rate <= 0 {
+		rate = cfg.DefaultRate
+	}
+	if capacity <= 0 {
+		capacity = cfg.DefaultCapacity
+	}
+	return config.ClientRateConfig{Rate: rate, Capacity: capacity}
+}
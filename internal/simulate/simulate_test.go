@@ -0,0 +1,151 @@
+package simulate_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/simulate"
+)
+
+func newScenario(t *testing.T, sc simulate.Scenario) *simulate.Scenario {
+	t.Helper()
+	require.NoError(t, sc.Parse())
+	return &sc
+}
+
+// TestRun_RateLimits проверяет отказы 429 по лимитам клиента, шаблону по умолчанию и объединению IPv6 по префиксу.
+func TestRun_RateLimits(t *testing.T) {
+	cfg := &config.Config{
+		BackendServers: []string{"http://a", "http://b"},
+		RateLimiter: config.RateLimiterConfig{
+			Enabled: true, DefaultRate: 1, DefaultCapacity: 5, IPv6PrefixLength: 64,
+			Clients: map[string]config.ClientRateConfig{"vip": {Rate: 100, Capacity: 100}},
+		},
+	}
+	sc := newScenario(t, simulate.Scenario{
+		DurationStr: "10s",
+		Clients: []simulate.ClientTraffic{
+			{ID: "vip", RPS: 50},
+			{ID: "user", RPS: 10},
+			{ID: "2001:db8::1", Count: 4, RPS: 1}, // Одна подсеть /64 - одна корзина
+		},
+	})
+
+	report := simulate.Run(cfg, sc)
+	vip, user, subnet := report.Clients[0], report.Clients[1], report.Clients[2]
+	assert.Equal(t, 500, vip.Requests)
+	assert.Zero(t, vip.Rejected, "Лимит vip выше его трафика")
+	assert.Equal(t, 100, user.Requests)
+	// Емкость 5 плюс пополнение 1 токен/с за 10 секунд
+	assert.InDelta(t, 15, user.Allowed, 1)
+	assert.Equal(t, 40, subnet.Requests)
+	assert.InDelta(t, 15, subnet.Allowed, 1, "Адреса одной подсети делят лимит")
+	assert.Equal(t, report.Requests, report.Allowed+report.Rejected)
+}
+
+// TestRun_SlidingWindow проверяет, что модель скользящего окна пропускает не больше capacity запросов за окно.
+func TestRun_SlidingWindow(t *testing.T) {
+	cfg := &config.Config{
+		BackendServers: []string{"http://a"},
+		RateLimiter: config.RateLimiterConfig{
+			Enabled: true, DefaultRate: 1, DefaultCapacity: 5, Algorithm: config.RateLimitSlidingWindow,
+		},
+	}
+	sc := newScenario(t, simulate.Scenario{
+		DurationStr: "10s",
+		Clients:     []simulate.ClientTraffic{{ID: "user", RPS: 10}},
+	})
+
+	report := simulate.Run(cfg, sc)
+	// Окно 5 секунд: по 5 запросов за каждое, против 15 у корзины токенов
+	assert.Equal(t, 100, report.Clients[0].Requests)
+	assert.InDelta(t, 10, report.Clients[0].Allowed, 1)
+}
+
+// TestRun_GlobalLimit проверяет, что общий лимит ограничивает сумму запросов клиентов.
+func TestRun_GlobalLimit(t *testing.T) {
+	cfg := &config.Config{
+		BackendServers: []string{"http://a"},
+		RateLimiter: config.RateLimiterConfig{
+			Enabled: true, DefaultRate: 100, DefaultCapacity: 100,
+			Global: config.GlobalLimitConfig{Rate: 10, Capacity: 10},
+		},
+	}
+	sc := newScenario(t, simulate.Scenario{
+		DurationStr: "10s",
+		Clients:     []simulate.ClientTraffic{{ID: "user", Count: 4, RPS: 5}},
+	})
+
+	report := simulate.Run(cfg, sc)
+	assert.Equal(t, 200, report.Requests)
+	// Всплеск 10 плюс 10 запросов в секунду на всех клиентов
+	assert.InDelta(t, 110, report.Allowed, 2)
+}
+
+// TestRun_Distribution проверяет Round Robin, маршрут с backend_selector, недоступные бэкенды и насыщение.
+func TestRun_Distribution(t *testing.T) {
+	cfg := &config.Config{
+		BackendServers:         []string{"http://a", "http://b", "http://gpu"},
+		BackendLabels:          map[string]map[string]string{"http://gpu": {"gpu": "true"}},
+		LoadBalancingAlgorithm: "round_robin",
+		Routes:                 []config.RouteConfig{{PathPrefix: "/ml", BackendSelector: map[string]string{"gpu": "true"}}},
+	}
+	sc := newScenario(t, simulate.Scenario{
+		DurationStr:        "10s",
+		BackendCapacityRPS: 10,
+		BackendsDown:       []string{"http://b"},
+		Clients: []simulate.ClientTraffic{
+			{ID: "web", RPS: 20},
+			{ID: "ml", RPS: 5, Path: "/ml/predict"},
+		},
+	})
+
+	report := simulate.Run(cfg, sc)
+	assert.False(t, report.RateLimiter)
+	assert.Zero(t, report.Rejected)
+	a, b, gpu := report.Backends[0], report.Backends[1], report.Backends[2]
+	assert.True(t, b.Down)
+	assert.Zero(t, b.Requests)
+	// Общий пул без недоступного бэкенда - a и gpu, маршрут /ml - только gpu
+	assert.Equal(t, 100, a.Requests)
+	assert.Equal(t, 150, gpu.Requests)
+	assert.InDelta(t, 15, gpu.PeakRPS, 1)
+	assert.InDelta(t, 1.5, gpu.Saturation, 0.1)
+
+	var out bytes.Buffer
+	require.NoError(t, report.WriteText(&out))
+	assert.Contains(t, out.String(), "http://gpu")
+}
+
+// TestLoadScenario проверяет значения по умолчанию и валидацию сценария.
+func TestLoadScenario(t *testing.T) {
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "scenario.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	sc, err := simulate.LoadScenario(write("clients:\n  - id: user1\n    rps: 5\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "60s", sc.DurationStr)
+	assert.Equal(t, 1, sc.Clients[0].Count)
+	assert.Equal(t, "/", sc.Clients[0].Path)
+
+	invalid := map[string]string{
+		"no clients":   "duration: '10s'\n",
+		"zero rps":     "clients:\n  - id: user1\n",
+		"no id":        "clients:\n  - rps: 5\n",
+		"bad duration": "duration: 'soon'\nclients:\n  - id: user1\n    rps: 5\n",
+		"long step":    "duration: '1s'\nstep: '2s'\nclients:\n  - id: user1\n    rps: 5\n",
+	}
+	for name, content := range invalid {
+		_, err := simulate.LoadScenario(write(content))
+		assert.Error(t, err, name)
+	}
+}
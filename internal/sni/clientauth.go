@@ -0,0 +1,22 @@
+package sni
+
+import (
+	"crypto/x509"
+	"os"
+
+	"load-balancer/internal/i18n"
+)
+
+// LoadCertPool читает сертификаты УЦ (PEM): для проверки клиентских сертификатов (tls.client_auth.ca_file)
+// или сертификатов бэкендов (backend_tls.<пул>.ca_file).
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, i18n.Errorf(i18n.SNINoCACertificates, path)
+	}
+	return pool, nil
+}
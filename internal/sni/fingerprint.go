@@ -0,0 +1,121 @@
+package sni
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// fingerprintKey - ключ контекста соединения с отпечатком TLS-клиента.
+type fingerprintKey struct{}
+
+// fingerprintHolder заполняется при разборе ClientHello и читается обработчиками запросов соединения.
+type fingerprintHolder struct {
+	hash atomic.Pointer[string]
+}
+
+// fingerprints связывает TLS-соединения, еще проходящие рукопожатие, с их отпечатками.
+// Ключ - исходное TCP-соединение: его получает и ConnContext (через tls.Conn.NetConn), и ClientHelloInfo.Conn.
+type fingerprints struct {
+	conns sync.Map // net.Conn -> *fingerprintHolder
+}
+
+// JA3 возвращает строку JA3 ClientHello ("версия,шифры,расширения,кривые,форматы точек") и ее MD5 в hex.
+// Значения GREASE (RFC 8701) не учитываются: браузеры выбирают их случайно при каждом соединении.
+func JA3(hello *tls.ClientHelloInfo) (string, string) {
+	// Версия из заголовка ClientHello: клиенты с расширением supported_versions всегда указывают TLS 1.2,
+	// без расширения crypto/tls выводит список версий из заголовка, начиная с него
+	version := uint16(tls.VersionTLS12)
+	if len(hello.SupportedVersions) > 0 && hello.SupportedVersions[0] < version {
+		version = hello.SupportedVersions[0]
+	}
+	points := make([]uint16, len(hello.SupportedPoints))
+	for i, point := range hello.SupportedPoints {
+		points[i] = uint16(point)
+	}
+	curves := make([]uint16, len(hello.SupportedCurves))
+	for i, curve := range hello.SupportedCurves {
+		curves[i] = uint16(curve)
+	}
+
+	ja3 := strings.Join([]string{
+		strconv.Itoa(int(version)),
+		joinValues(hello.CipherSuites),
+		joinValues(hello.Extensions),
+		joinValues(curves),
+		joinValues(points),
+	}, ",")
+	sum := md5.Sum([]byte(ja3))
+	return ja3, hex.EncodeToString(sum[:])
+}
+
+// joinValues соединяет значения через "-", пропуская GREASE.
+func joinValues(values []uint16) string {
+	var b strings.Builder
+	for _, value := range values {
+		if isGREASE(value) {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		b.WriteString(strconv.Itoa(int(value)))
+	}
+	return b.String()
+}
+
+// isGREASE сообщает, что значение зарезервировано GREASE: 0x0a0a, 0x1a1a, ..., 0xfafa.
+func isGREASE(value uint16) bool {
+	return value&0x0f0f == 0x0a0a && value>>8 == value&0xff
+}
+
+// ConnContext - http.Server.ConnContext HTTPS-листенера: готовит место для отпечатка соединения.
+func (r *Router) ConnContext(ctx context.Context, c net.Conn) context.Context {
+	tlsConn, ok := c.(*tls.Conn)
+	if !ok {
+		return ctx
+	}
+	holder := &fingerprintHolder{}
+	r.fingerprints.conns.Store(tlsConn.NetConn(), holder)
+	return context.WithValue(ctx, fingerprintKey{}, holder)
+}
+
+// ConnState - http.Server.ConnState HTTPS-листенера: забывает закрытые соединения.
+func (r *Router) ConnState(c net.Conn, state http.ConnState) {
+	if state != http.StateClosed && state != http.StateHijacked {
+		return
+	}
+	if tlsConn, ok := c.(*tls.Conn); ok {
+		r.fingerprints.conns.Delete(tlsConn.NetConn())
+	}
+}
+
+// recordFingerprint - tls.Config.GetConfigForClient: вычисляет отпечаток ClientHello
+// (в том числе при возобновлении сессии, когда сертификат не запрашивается). Конфигурация не меняется.
+func (r *Router) recordFingerprint(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	if value, ok := r.fingerprints.conns.Load(hello.Conn); ok {
+		_, hash := JA3(hello)
+		value.(*fingerprintHolder).hash.Store(&hash)
+	}
+	return nil, nil
+}
+
+// Fingerprint возвращает MD5 отпечатка JA3 TLS-клиента, отправившего запрос.
+// Пустая строка - запрос пришел не через HTTPS-листенер с SNI-маршрутизацией.
+func Fingerprint(req *http.Request) string {
+	holder, ok := req.Context().Value(fingerprintKey{}).(*fingerprintHolder)
+	if !ok {
+		return ""
+	}
+	if hash := holder.hash.Load(); hash != nil {
+		return *hash
+	}
+	return ""
+}
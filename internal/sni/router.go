@@ -0,0 +1,124 @@
+package sni
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+
+	"load-balancer/internal/i18n"
+)
+
+// site - сертификат и обработчик группы доменов.
+type site struct {
+	cert    *tls.Certificate
+	handler http.Handler
+}
+
+// Router выбирает сертификат по имени сервера из TLS ClientHello (SNI) еще до разбора HTTP,
+// а затем направляет запрос в пул бэкендов этого домена.
+// Клиенты без SNI маршрутизируются по заголовку Host.
+// Все сайты добавляются до запуска сервера; после этого Router только читается.
+type Router struct {
+	exact     map[string]*site // Точные имена ("api.example.com").
+	wildcards map[string]*site // Шаблоны "*.example.com" хранятся по суффиксу ".example.com".
+	first     *site            // Сертификат для клиентов без SNI и с неизвестным именем (см. SetDefault).
+	fallback  http.Handler     // Обработчик для неизвестных имен.
+	// fingerprints - отпечатки JA3 соединений (см. ConnContext и Fingerprint).
+	fingerprints fingerprints
+}
+
+// NewRouter создает Router; fallback обслуживает запросы к доменам без собственного пула.
+func NewRouter(fallback http.Handler) *Router {
+	return &Router{
+		exact:     make(map[string]*site),
+		wildcards: make(map[string]*site),
+		fallback:  fallback,
+	}
+}
+
+// Add регистрирует домены с сертификатом и обработчиком. Если handler равен nil,
+// запросы к доменам идут в обработчик по умолчанию.
+func (r *Router) Add(serverNames []string, cert tls.Certificate, handler http.Handler) {
+	if handler == nil {
+		handler = r.fallback
+	}
+	s := &site{cert: &cert, handler: handler}
+	if r.first == nil {
+		r.first = s
+	}
+	for _, name := range serverNames {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "*.") {
+			r.wildcards[name[1:]] = s
+		} else {
+			r.exact[name] = s
+		}
+	}
+	i18n.Logf(i18n.SNISiteAdded, serverNames)
+}
+
+// SetDefault задает сертификат для клиентов без SNI и с именами, не зарегистрированными в Add, вместо
+// сертификата первого сайта. Их запросы обслуживает обработчик по умолчанию.
+func (r *Router) SetDefault(cert tls.Certificate) {
+	r.first = &site{cert: &cert, handler: r.fallback}
+}
+
+// TLSConfig возвращает конфигурацию HTTPS-листенера, выбирающую сертификат по SNI.
+func (r *Router) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetCertificate:     r.getCertificate,
+		GetConfigForClient: r.recordFingerprint,
+	}
+}
+
+// getCertificate подбирает сертификат для ClientHello; неизвестные имена получают сертификат первого сайта.
+func (r *Router) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if s := r.lookup(hello.ServerName); s != nil {
+		return s.cert, nil
+	}
+	if r.first == nil {
+		return nil, i18n.Errorf(i18n.SNINoCertificates)
+	}
+	return r.first.cert, nil
+}
+
+// ServeHTTP направляет запрос в пул домена: по имени из SNI, а без него - по заголовку Host.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if s := r.lookup(ServerName(req)); s != nil {
+		s.handler.ServeHTTP(w, req)
+		return
+	}
+	r.fallback.ServeHTTP(w, req)
+}
+
+// lookup ищет сайт по точному имени, затем по шаблону на один уровень выше.
+func (r *Router) lookup(name string) *site {
+	if name == "" {
+		return nil
+	}
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if s, ok := r.exact[name]; ok {
+		return s
+	}
+	// "*.example.com" покрывает ровно один сегмент, как и в сертификатах
+	if dot := strings.IndexByte(name, '.'); dot > 0 {
+		if s, ok := r.wildcards[name[dot:]]; ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// ServerName возвращает имя сервера запроса: из SNI, если клиент его передал, иначе из Host без порта.
+func ServerName(req *http.Request) string {
+	if req.TLS != nil && req.TLS.ServerName != "" {
+		return req.TLS.ServerName
+	}
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return host
+}
@@ -0,0 +1,251 @@
+package sni_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/sni"
+)
+
+// newCertificate создает самоподписанный сертификат для указанных имен.
+func newCertificate(t *testing.T, names ...string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// namedHandler отвечает своим именем, чтобы было видно, какой пул обработал запрос.
+func namedHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, name)
+	})
+}
+
+func newTestRouter(t *testing.T) *sni.Router {
+	router := sni.NewRouter(namedHandler("default"))
+	router.Add([]string{"api.example.com"}, newCertificate(t, "api.example.com"), namedHandler("api"))
+	router.Add([]string{"*.shop.example.com"}, newCertificate(t, "*.shop.example.com"), namedHandler("shop"))
+	router.Add([]string{"static.example.com"}, newCertificate(t, "static.example.com"), nil)
+	return router
+}
+
+// TestRouter_GetCertificate проверяет выбор сертификата по SNI.
+func TestRouter_GetCertificate(t *testing.T) {
+	cfg := newTestRouter(t).TLSConfig()
+
+	tests := []struct {
+		serverName string
+		wantCN     string
+	}{
+		{"api.example.com", "api.example.com"},
+		{"API.Example.com", "api.example.com"},
+		{"eu.shop.example.com", "*.shop.example.com"},
+		{"a.eu.shop.example.com", "api.example.com"}, // Шаблон покрывает только один сегмент
+		{"unknown.org", "api.example.com"},           // Неизвестное имя - сертификат первого сайта
+		{"", "api.example.com"},                      // Клиент без SNI
+	}
+	for _, tt := range tests {
+		cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{ServerName: tt.serverName})
+		require.NoError(t, err)
+		assert.Equal(t, tt.wantCN, cert.Leaf.Subject.CommonName, "SNI %q", tt.serverName)
+	}
+
+	_, err := sni.NewRouter(namedHandler("default")).TLSConfig().GetCertificate(&tls.ClientHelloInfo{})
+	assert.Error(t, err, "Без сертификатов рукопожатие невозможно")
+
+	// Сертификат листенера заменяет сертификат первого сайта для клиентов без SNI и неизвестных имен
+	router := newTestRouter(t)
+	router.SetDefault(newCertificate(t, "balancer.local"))
+	cfg = router.TLSConfig()
+	for serverName, wantCN := range map[string]string{"": "balancer.local", "unknown.org": "balancer.local", "api.example.com": "api.example.com"} {
+		cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+		require.NoError(t, err)
+		assert.Equal(t, wantCN, cert.Leaf.Subject.CommonName, "SNI %q", serverName)
+	}
+}
+
+// TestRouter_ServeHTTP проверяет выбор пула по SNI и по Host для клиентов без SNI.
+func TestRouter_ServeHTTP(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name       string
+		serverName string // Имя из SNI ("" - клиент не передал SNI)
+		host       string
+		want       string
+	}{
+		{"SNI", "api.example.com", "ignored.example.com", "api"},
+		{"SNI wildcard", "eu.shop.example.com", "", "shop"},
+		{"Host fallback with port", "", "api.example.com:8443", "api"},
+		{"site without pool", "static.example.com", "", "default"},
+		{"unknown name", "unknown.org", "", "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tt.host
+			req.TLS = &tls.ConnectionState{ServerName: tt.serverName}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+}
+
+// TestRouter_TLSListener проверяет маршрутизацию через настоящее TLS-рукопожатие.
+func TestRouter_TLSListener(t *testing.T) {
+	router := newTestRouter(t)
+	server := httptest.NewUnstartedServer(router)
+	server.TLS = router.TLSConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(serverName string) (string, string) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{ServerName: serverName, InsecureSkipVerify: true},
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+			},
+		}}
+		resp, err := client.Get("https://" + serverName + "/")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body), resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	body, cn := get("api.example.com")
+	assert.Equal(t, "api", body)
+	assert.Equal(t, "api.example.com", cn)
+
+	body, cn = get("eu.shop.example.com")
+	assert.Equal(t, "shop", body)
+	assert.Equal(t, "*.shop.example.com", cn)
+}
+
+// TestRouter_ClientAuth проверяет взаимную аутентификацию: клиентские сертификаты проверяются по УЦ из файла PEM.
+func TestRouter_ClientAuth(t *testing.T) {
+	clientCert := newCertificate(t, "client-1")
+	caFile := filepath.Join(t.TempDir(), "clients-ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientCert.Certificate[0]}), 0o600))
+	clientCAs, err := sni.LoadCertPool(caFile)
+	require.NoError(t, err)
+
+	router := sni.NewRouter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	router.Add([]string{"api.example.com"}, newCertificate(t, "api.example.com"), nil)
+	server := httptest.NewUnstartedServer(router)
+	server.TLS = router.TLSConfig()
+	server.TLS.ClientCAs = clientCAs
+	server.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(certs ...tls.Certificate) (string, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{ServerName: "api.example.com", InsecureSkipVerify: true, Certificates: certs},
+		}}
+		defer client.CloseIdleConnections()
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	body, err := get(clientCert)
+	require.NoError(t, err)
+	assert.Equal(t, "client-1", body)
+
+	_, err = get()
+	assert.Error(t, err, "Без сертификата соединение отклоняется")
+	_, err = get(newCertificate(t, "intruder"))
+	assert.Error(t, err, "Сертификат не от доверенного УЦ отклоняется")
+
+	badFile := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(badFile, []byte("not a certificate"), 0o600))
+	_, err = sni.LoadCertPool(badFile)
+	assert.Error(t, err)
+}
+
+// TestJA3 проверяет строку JA3 и отбрасывание значений GREASE.
+func TestJA3(t *testing.T) {
+	ja3, hash := sni.JA3(&tls.ClientHelloInfo{
+		SupportedVersions: []uint16{0x3a3a, tls.VersionTLS13, tls.VersionTLS12},
+		CipherSuites:      []uint16{0x0a0a, 4865, 4866, 49195},
+		Extensions:        []uint16{0x1a1a, 0, 23, 65281, 10, 11},
+		SupportedCurves:   []tls.CurveID{0x2a2a, tls.X25519, tls.CurveP256},
+		SupportedPoints:   []uint8{0},
+	})
+	assert.Equal(t, "771,4865-4866-49195,0-23-65281-10-11,29-23,0", ja3)
+	assert.Len(t, hash, 32)
+}
+
+// TestRouter_Fingerprint проверяет, что отпечаток TLS-клиента доступен обработчику запроса
+// и одинаков для соединений одного клиента.
+func TestRouter_Fingerprint(t *testing.T) {
+	router := sni.NewRouter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, sni.Fingerprint(r))
+	}))
+	router.Add([]string{"api.example.com"}, newCertificate(t, "api.example.com"), nil)
+	server := httptest.NewUnstartedServer(router)
+	server.TLS = router.TLSConfig()
+	server.Config.ConnContext = router.ConnContext
+	server.Config.ConnState = router.ConnState
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(clientConfig *tls.Config) string {
+		clientConfig.InsecureSkipVerify = true
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig, DisableKeepAlives: true}}
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	first := get(&tls.Config{})
+	assert.Len(t, first, 32)
+	assert.Equal(t, first, get(&tls.Config{}), "Новое соединение того же клиента дает тот же отпечаток")
+	other := get(&tls.Config{MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}})
+	assert.NotEqual(t, first, other, "Другой набор шифров - другой отпечаток")
+
+	plain := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, sni.Fingerprint(plain), "Запрос не через HTTPS-листенер")
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"time"
+
+	"load-balancer/internal/i18n"
+)
+
+// accessListTable хранит записи списков запрета и разрешения, добавленные через API.
+// expires_at - время истечения в наносекундах Unix, 0 - запись бессрочная.
+const accessListTable = `
+	CREATE TABLE IF NOT EXISTS access_list (
+		value TEXT PRIMARY KEY,
+		list TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		created_at TEXT NOT NULL DEFAULT '',
+		expires_at INTEGER NOT NULL DEFAULT 0
+	);
+	`
+
+// AccessEntry - запись списка доступа.
+type AccessEntry struct {
+	Value     string // IP-адрес, подсеть CIDR или ID клиента.
+	List      string // config.AccessListDeny или config.AccessListAllow.
+	Reason    string // Причина добавления (для оператора).
+	CreatedAt time.Time
+	ExpiresAt time.Time // Нулевое значение - запись бессрочная.
+}
+
+// Expired сообщает, истек ли срок действия записи к моменту now.
+func (e AccessEntry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// PutAccessEntry добавляет запись в список доступа или заменяет запись с тем же значением.
+func (db *DB) PutAccessEntry(entry AccessEntry) error {
+	var expiresAt int64
+	if !entry.ExpiresAt.IsZero() {
+		expiresAt = entry.ExpiresAt.UnixNano()
+	}
+	query := `INSERT INTO access_list (value, list, reason, created_at, expires_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(value) DO UPDATE SET list = excluded.list, reason = excluded.reason,
+		created_at = excluded.created_at, expires_at = excluded.expires_at`
+	_, err := db.Conn.Exec(query, entry.Value, entry.List, entry.Reason, entry.CreatedAt.Format(time.RFC3339Nano), expiresAt)
+	if err != nil {
+		return i18n.Errorf(i18n.StoragePutAccessEntryFailed, entry.Value, err)
+	}
+	i18n.Logf(i18n.StorageAccessEntryPut, entry.Value, entry.List)
+	return nil
+}
+
+// DeleteAccessEntry удаляет запись из списка доступа. Возвращает ErrAccessEntryNotFound, если записи нет.
+func (db *DB) DeleteAccessEntry(value string) error {
+	res, err := db.Conn.Exec(`DELETE FROM access_list WHERE value = ?`, value)
+	if err != nil {
+		return i18n.Errorf(i18n.StorageDeleteAccessEntryFailed, value, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return i18n.Errorf(i18n.StorageDeletedRowsFailed, value, err)
+	}
+	if rowsAffected == 0 {
+		return i18n.Errorf(i18n.StorageDeleteAccessEntryFailed, value, ErrAccessEntryNotFound)
+	}
+	i18n.Logf(i18n.StorageAccessEntryDeleted, value)
+	return nil
+}
+
+// ListAccessEntries возвращает все записи списков доступа, включая истекшие, в порядке значений.
+func (db *DB) ListAccessEntries() ([]AccessEntry, error) {
+	rows, err := db.Conn.Query(`SELECT value, list, reason, created_at, expires_at FROM access_list ORDER BY value`)
+	if err != nil {
+		return nil, i18n.Errorf(i18n.StorageListAccessFailed, err)
+	}
+	defer rows.Close()
+
+	var entries []AccessEntry
+	for rows.Next() {
+		var entry AccessEntry
+		var createdAt string
+		var expiresAt int64
+		if err := rows.Scan(&entry.Value, &entry.List, &entry.Reason, &createdAt, &expiresAt); err != nil {
+			return nil, i18n.Errorf(i18n.StorageListAccessFailed, err)
+		}
+		// Некорректное время создания не делает запись недействительной
+		entry.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+		if expiresAt != 0 {
+			entry.ExpiresAt = time.Unix(0, expiresAt)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, i18n.Errorf(i18n.StorageListAccessFailed, err)
+	}
+	return entries, nil
+}
+
+// DeleteExpiredAccessEntries удаляет записи, срок действия которых истек к моменту now.
+// Возвращает количество удаленных записей.
+func (db *DB) DeleteExpiredAccessEntries(now time.Time) (int64, error) {
+	res, err := db.Conn.Exec(`DELETE FROM access_list WHERE expires_at != 0 AND expires_at <= ?`, now.UnixNano())
+	if err != nil {
+		return 0, i18n.Errorf(i18n.StoragePurgeAccessFailed, err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, i18n.Errorf(i18n.StoragePurgeAccessFailed, err)
+	}
+	if deleted > 0 {
+		i18n.Logf(i18n.StorageAccessEntriesExpired, deleted)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"load-balancer/internal/i18n"
+)
+
+// apiKeysTable хранит API-ключи клиентов (rate_limiter.api_keys). Ключ хранится только в виде хэша SHA-256:
+// утечка базы не раскрывает ключи. plan - шаблон лимитов, назначенный клиенту при выдаче ключа.
+const apiKeysTable = `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		key_hash TEXT PRIMARY KEY,
+		client_id TEXT NOT NULL,
+		plan TEXT NOT NULL DEFAULT '',
+		created_at TEXT NOT NULL DEFAULT ''
+	);
+	`
+
+// APIKey - API-ключ клиента.
+type APIKey struct {
+	Hash      string // HashAPIKey от ключа.
+	ClientID  string // ID клиента, под которым учитываются запросы с ключом.
+	Plan      string // Шаблон rate_limiter.templates, пусто - лимиты клиента заданы отдельно.
+	CreatedAt time.Time
+}
+
+// HashAPIKey возвращает хэш ключа, под которым он хранится и ищется (SHA-256 в шестнадцатеричном виде).
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// PutAPIKey сохраняет API-ключ или заменяет ключ с тем же хэшем.
+func (db *DB) PutAPIKey(key APIKey) error {
+	query := `INSERT INTO api_keys (key_hash, client_id, plan, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(key_hash) DO UPDATE SET client_id = excluded.client_id, plan = excluded.plan,
+		created_at = excluded.created_at`
+	if _, err := db.Conn.Exec(query, key.Hash, key.ClientID, key.Plan, formatTime(key.CreatedAt)); err != nil {
+		return i18n.Errorf(i18n.StoragePutAPIKeyFailed, key.ClientID, err)
+	}
+	i18n.Logf(i18n.StorageAPIKeyPut, key.ClientID)
+	return nil
+}
+
+// LookupAPIKey находит ключ по хэшу. found = false - ключа нет.
+func (db *DB) LookupAPIKey(hash string) (key APIKey, found bool, err error) {
+	var createdAt string
+	err = db.Conn.QueryRow(`SELECT key_hash, client_id, plan, created_at FROM api_keys WHERE key_hash = ?`, hash).
+		Scan(&key.Hash, &key.ClientID, &key.Plan, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, false, nil
+	}
+	if err != nil {
+		return APIKey{}, false, i18n.Errorf(i18n.StorageLookupAPIKeyFailed, err)
+	}
+	key.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	return key, true, nil
+}
+
+// DeleteAPIKey отзывает ключ. Возвращает ErrAPIKeyNotFound, если ключа нет.
+func (db *DB) DeleteAPIKey(hash string) error {
+	res, err := db.Conn.Exec(`DELETE FROM api_keys WHERE key_hash = ?`, hash)
+	if err != nil {
+		return i18n.Errorf(i18n.StorageDeleteAPIKeyFailed, hash, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return i18n.Errorf(i18n.StorageDeletedRowsFailed, hash, err)
+	}
+	if rowsAffected == 0 {
+		return i18n.Errorf(i18n.StorageDeleteAPIKeyFailed, hash, ErrAPIKeyNotFound)
+	}
+	i18n.Logf(i18n.StorageAPIKeyDeleted, hash)
+	return nil
+}
+
+// ListAPIKeys возвращает все ключи в порядке ID клиентов и времени выдачи.
+func (db *DB) ListAPIKeys() ([]APIKey, error) {
+	rows, err := db.Conn.Query(`SELECT key_hash, client_id, plan, created_at FROM api_keys ORDER BY client_id, created_at, key_hash`)
+	if err != nil {
+		return nil, i18n.Errorf(i18n.StorageListAPIKeysFailed, err)
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		var key APIKey
+		var createdAt string
+		if err := rows.Scan(&key.Hash, &key.ClientID, &key.Plan, &createdAt); err != nil {
+			return nil, i18n.Errorf(i18n.StorageListAPIKeysFailed, err)
+		}
+		key.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, i18n.Errorf(i18n.StorageListAPIKeysFailed, err)
+	}
+	return keys, nil
+}
@@ -1,8 +1,10 @@
 package storage
 
-import "errors"
+import "load-balancer/internal/i18n"
 
 var (
-	ErrClientNotFound      = errors.New("клиент не найден")
-	ErrClientAlreadyExists = errors.New("клиент уже существует")
+	ErrClientNotFound      = i18n.NewError(i18n.StorageClientNotFound)
+	ErrClientAlreadyExists = i18n.NewError(i18n.StorageClientExists)
+	ErrAccessEntryNotFound = i18n.NewError(i18n.StorageAccessEntryNotFound)
+	ErrAPIKeyNotFound      = i18n.NewError(i18n.StorageAPIKeyNotFound)
 )
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"time"
+
+	"load-balancer/internal/i18n"
+)
+
+// backendHealthTable хранит статус бэкендов на момент остановки (см. SaveBackendHealth).
+const backendHealthTable = `
+	CREATE TABLE IF NOT EXISTS backend_health (
+		url TEXT PRIMARY KEY,
+		alive INTEGER NOT NULL,
+		saved_at TEXT NOT NULL DEFAULT ''
+	);
+	`
+
+// SaveBackendHealth сохраняет статус бэкендов (URL -> доступен) в одной транзакции.
+// Записи бэкендов, которых нет в states, удаляются: они убраны из конфигурации.
+func (db *DB) SaveBackendHealth(states map[string]bool, now time.Time) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return i18n.Errorf(i18n.StorageBeginTxFailed, err)
+	}
+	defer tx.Rollback() // Откат по умолчанию, если Commit не будет вызван
+
+	if _, err := tx.Exec(`DELETE FROM backend_health`); err != nil {
+		return i18n.Errorf(i18n.StorageSaveHealthFailed, err)
+	}
+	stmt, err := tx.Prepare(`INSERT INTO backend_health (url, alive, saved_at) VALUES (?, ?, ?)`)
+	if err != nil {
+		return i18n.Errorf(i18n.StoragePrepareFailed, err)
+	}
+	defer stmt.Close()
+
+	savedAt := now.Format(time.RFC3339Nano)
+	for url, alive := range states {
+		if _, err := stmt.Exec(url, alive, savedAt); err != nil {
+			return i18n.Errorf(i18n.StorageSaveHealthFailed, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return i18n.Errorf(i18n.StorageCommitFailed, err)
+	}
+	i18n.Logf(i18n.StorageHealthSaved, len(states))
+	return nil
+}
+
+// LoadBackendHealth возвращает статус бэкендов, сохраненный SaveBackendHealth (URL -> доступен).
+func (db *DB) LoadBackendHealth() (map[string]bool, error) {
+	rows, err := db.Conn.Query(`SELECT url, alive FROM backend_health`)
+	if err != nil {
+		return nil, i18n.Errorf(i18n.StorageLoadHealthFailed, err)
+	}
+	defer rows.Close()
+
+	states := make(map[string]bool)
+	for rows.Next() {
+		var url string
+		var alive bool
+		if err := rows.Scan(&url, &alive); err != nil {
+			return nil, i18n.Errorf(i18n.StorageLoadHealthFailed, err)
+		}
+		states[url] = alive
+	}
+	if err := rows.Err(); err != nil {
+		return nil, i18n.Errorf(i18n.StorageLoadHealthFailed, err)
+	}
+	return states, nil
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+)
+
+// incrementalVacuum - значение PRAGMA auto_vacuum, при котором свободные страницы возвращаются
+// файловой системе командой incremental_vacuum, без полного VACUUM.
+const incrementalVacuum = 2
+
+// MaintenanceReport - результат обслуживания базы (см. Maintain).
+type MaintenanceReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	// ExpiredAccessEntries - удаленные записи списков доступа с истекшим сроком действия.
+	ExpiredAccessEntries int64 `json:"expired_access_entries"`
+	// StaleHealthRows - удаленные записи статуса бэкендов старше storage_maintenance.health_retention.
+	StaleHealthRows int64 `json:"stale_health_rows"`
+	// FreedPages - страницы, возвращенные файловой системе; SizeBefore и SizeAfter - размер базы в байтах.
+	FreedPages int64 `json:"freed_pages"`
+	SizeBefore int64 `json:"size_before_bytes"`
+	SizeAfter  int64 `json:"size_after_bytes"`
+}
+
+// enableIncrementalVacuum переводит базу в режим auto_vacuum = INCREMENTAL. Для базы, созданной
+// без него, режим применяется полным VACUUM: это выполняется один раз, при первом открытии такой базы.
+func enableIncrementalVacuum(conn *sql.DB) error {
+	var mode int
+	if err := conn.QueryRow(`PRAGMA auto_vacuum`).Scan(&mode); err != nil {
+		return err
+	}
+	if mode == incrementalVacuum {
+		return nil
+	}
+	// Оба оператора выполняются в одном соединении: auto_vacuum до VACUUM действует только в нем
+	if _, err := conn.Exec(`PRAGMA auto_vacuum = INCREMENTAL; VACUUM;`); err != nil {
+		return err
+	}
+	i18n.Logf(i18n.StorageIncrementalVacuumEnabled)
+	return nil
+}
+
+// Maintain удаляет устаревшие записи (истекшие записи списков доступа и статус бэкендов старше
+// cfg.HealthRetention) и возвращает файловой системе до cfg.VacuumPages свободных страниц (0 - все).
+// Одновременные вызовы выполняются по очереди.
+func (db *DB) Maintain(now time.Time, cfg config.StorageMaintenanceConfig) (MaintenanceReport, error) {
+	db.maintenance.Lock()
+	defer db.maintenance.Unlock()
+
+	report := MaintenanceReport{StartedAt: now}
+	start := time.Now()
+	var err error
+	if report.SizeBefore, err = db.size(); err != nil {
+		return report, i18n.Errorf(i18n.StorageMaintenanceFailed, err)
+	}
+	if report.ExpiredAccessEntries, err = db.DeleteExpiredAccessEntries(now); err != nil {
+		return report, err
+	}
+	if report.StaleHealthRows, err = db.deleteStaleBackendHealth(now.Add(-cfg.HealthRetention)); err != nil {
+		return report, i18n.Errorf(i18n.StorageMaintenanceFailed, err)
+	}
+
+	freeBefore, err := db.pragmaInt(`PRAGMA freelist_count`)
+	if err != nil {
+		return report, i18n.Errorf(i18n.StorageMaintenanceFailed, err)
+	}
+	if _, err := db.Conn.Exec(`PRAGMA incremental_vacuum(` + strconv.Itoa(cfg.VacuumPages) + `)`); err != nil {
+		return report, i18n.Errorf(i18n.StorageMaintenanceFailed, err)
+	}
+	freeAfter, err := db.pragmaInt(`PRAGMA freelist_count`)
+	if err != nil {
+		return report, i18n.Errorf(i18n.StorageMaintenanceFailed, err)
+	}
+	report.FreedPages = max(freeBefore-freeAfter, 0)
+	if report.SizeAfter, err = db.size(); err != nil {
+		return report, i18n.Errorf(i18n.StorageMaintenanceFailed, err)
+	}
+	report.DurationMs = time.Since(start).Milliseconds()
+
+	i18n.Logf(i18n.StorageMaintenanceDone, report.ExpiredAccessEntries, report.StaleHealthRows,
+		report.FreedPages, report.SizeBefore, report.SizeAfter, time.Since(start))
+	return report, nil
+}
+
+// deleteStaleBackendHealth удаляет статус бэкендов, сохраненный раньше cutoff. saved_at хранится
+// в RFC 3339 с переменной длиной дробной части, поэтому время сравнивается после разбора.
+func (db *DB) deleteStaleBackendHealth(cutoff time.Time) (int64, error) {
+	rows, err := db.Conn.Query(`SELECT DISTINCT saved_at FROM backend_health`)
+	if err != nil {
+		return 0, err
+	}
+	var stale []string
+	for rows.Next() {
+		var savedAt string
+		if err := rows.Scan(&savedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if saved, err := time.Parse(time.RFC3339Nano, savedAt); err != nil || saved.Before(cutoff) {
+			stale = append(stale, savedAt)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, savedAt := range stale {
+		res, err := db.Conn.Exec(`DELETE FROM backend_health WHERE saved_at = ?`, savedAt)
+		if err != nil {
+			return deleted, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// size возвращает размер базы в байтах.
+func (db *DB) size() (int64, error) {
+	pages, err := db.pragmaInt(`PRAGMA page_count`)
+	if err != nil {
+		return 0, err
+	}
+	pageSize, err := db.pragmaInt(`PRAGMA page_size`)
+	if err != nil {
+		return 0, err
+	}
+	return pages * pageSize, nil
+}
+
+func (db *DB) pragmaInt(query string) (int64, error) {
+	var value int64
+	err := db.Conn.QueryRow(query).Scan(&value)
+	return value, err
+}
+
+// Maintainer периодически обслуживает базу (storage_maintenance.interval).
+type Maintainer struct {
+	db       *DB
+	cfg      config.StorageMaintenanceConfig
+	quit     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup // Горутина планового обслуживания (см. Wait)
+}
+
+// NewMaintainer создает обслуживание базы с параметрами cfg (разобранными LoadConfig).
+func NewMaintainer(db *DB, cfg config.StorageMaintenanceConfig) *Maintainer {
+	return &Maintainer{db: db, cfg: cfg, quit: make(chan struct{})}
+}
+
+// Start запускает плановое обслуживание. Первое обслуживание выполняется через interval после запуска:
+// при старте база только что открыта, и записи списков доступа уже очищены (см. access.New).
+func (m *Maintainer) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := m.Run(); err != nil {
+					i18n.Logf(i18n.StorageMaintenanceRunFailed, err)
+				}
+			case <-m.quit:
+				return
+			}
+		}
+	}()
+	i18n.Logf(i18n.StorageMaintenanceStarted, m.cfg.Interval, m.cfg.HealthRetention, m.cfg.VacuumPages)
+}
+
+// Run выполняет обслуживание сразу (например, по POST /admin/storage/maintenance).
+func (m *Maintainer) Run() (MaintenanceReport, error) {
+	return m.db.Maintain(time.Now(), m.cfg)
+}
+
+// Stop останавливает плановое обслуживание.
+func (m *Maintainer) Stop() {
+	m.stopOnce.Do(func() { close(m.quit) })
+}
+
+// Wait ожидает завершения планового обслуживания после Stop.
+// Возвращает ошибку контекста, если обслуживание не завершилось до его отмены.
+func (m *Maintainer) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,434 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+)
+
+// Store - методы хранилища, общие для *DB и *MemoryStore: через него балансировщик работает с любым из них.
+type Store interface {
+	MigrationStore
+	Ping(ctx context.Context) error
+	SupportsStatePersistence() bool
+
+	GetClientLimitAndState(clientID string) (rate, capacity, tokens float64, lastRefill time.Time, found bool, err error)
+	GetClientLimitConfig(clientID string) (rate, capacity float64, found bool, err error)
+	GetClientSavedState(clientID string) (tokens float64, lastRefill time.Time, found bool, err error)
+	CreateClientLimit(clientID string, limit config.ClientRateConfig) error
+	UpdateClientLimit(clientID string, limit config.ClientRateConfig) error
+	DeleteClientLimit(clientID string) error
+	ListClientLimits(prefix string, limit, offset int) (clients []ClientLimit, total int, err error)
+	BatchUpdateClientState(states map[string]ClientState) error
+
+	SetClientTrial(trial ClientTrial) error
+	GetClientTrial(clientID string) (trial ClientTrial, found bool, err error)
+	ExpiredClientTrials(now time.Time) ([]ClientTrial, error)
+	DowngradeClientTrial(trial ClientTrial, limit config.ClientRateConfig) (bool, error)
+
+	PutAccessEntry(entry AccessEntry) error
+	DeleteAccessEntry(value string) error
+	ListAccessEntries() ([]AccessEntry, error)
+	DeleteExpiredAccessEntries(now time.Time) (int64, error)
+
+	SaveBackendHealth(states map[string]bool, now time.Time) error
+	LoadBackendHealth() (map[string]bool, error)
+
+	PutAPIKey(key APIKey) error
+	LookupAPIKey(hash string) (key APIKey, found bool, err error)
+	DeleteAPIKey(hash string) error
+	ListAPIKeys() ([]APIKey, error)
+}
+
+var (
+	_ Store = (*DB)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// MemoryStore - хранилище в памяти процесса с теми же методами, что и *DB (кроме обслуживания базы SQLite).
+// Используется, когда database_path не задан: API управления работает, но данные теряются при перезапуске,
+// поэтому SupportsStatePersistence возвращает false.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	clients map[string]ClientRecord
+	access  map[string]AccessEntry
+	health  map[string]BackendHealthRecord
+	apiKeys map[string]APIKey
+}
+
+// NewMemoryStore создает пустое хранилище в памяти.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		clients: make(map[string]ClientRecord),
+		access:  make(map[string]AccessEntry),
+		health:  make(map[string]BackendHealthRecord),
+		apiKeys: make(map[string]APIKey),
+	}
+}
+
+// Ping всегда успешен: хранилище в памяти доступно, пока работает процесс.
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close ничего не делает: данные хранилища живут до завершения процесса.
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// SupportsStatePersistence возвращает false: состояние корзин не переживает перезапуск.
+func (m *MemoryStore) SupportsStatePersistence() bool {
+	return false
+}
+
+// GetClientLimitAndState возвращает лимиты и сохраненное состояние корзины клиента.
+func (m *MemoryStore) GetClientLimitAndState(clientID string) (rate, capacity, tokens float64, lastRefill time.Time, found bool, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	client, ok := m.clients[clientID]
+	if !ok {
+		return 0, 0, 0, time.Time{}, false, nil
+	}
+	return client.Rate, client.Capacity, client.State.Tokens, client.State.LastRefill, true, nil
+}
+
+// GetClientLimitConfig возвращает лимиты (rate, capacity) клиента.
+func (m *MemoryStore) GetClientLimitConfig(clientID string) (rate, capacity float64, found bool, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	client, ok := m.clients[clientID]
+	return client.Rate, client.Capacity, ok, nil
+}
+
+// GetClientSavedState возвращает сохраненное состояние (tokens, lastRefill) корзины клиента.
+func (m *MemoryStore) GetClientSavedState(clientID string) (tokens float64, lastRefill time.Time, found bool, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	client, ok := m.clients[clientID]
+	return client.State.Tokens, client.State.LastRefill, ok, nil
+}
+
+// CreateClientLimit добавляет клиента с полной корзиной. Возвращает ErrClientAlreadyExists, если клиент есть.
+func (m *MemoryStore) CreateClientLimit(clientID string, limit config.ClientRateConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[clientID]; ok {
+		return i18n.Errorf(i18n.StorageAddClientFailed, clientID, ErrClientAlreadyExists)
+	}
+	m.clients[clientID] = ClientRecord{
+		ClientID: clientID,
+		Rate:     limit.Rate,
+		Capacity: limit.Capacity,
+		State:    ClientState{Tokens: limit.Capacity, LastRefill: time.Now()},
+	}
+	i18n.Logf(i18n.StorageLimitAdded, clientID, limit.Rate, limit.Capacity, limit.Capacity)
+	return nil
+}
+
+// UpdateClientLimit меняет лимиты существующего клиента, не трогая состояние корзины.
+// Возвращает ErrClientNotFound, если клиента нет.
+func (m *MemoryStore) UpdateClientLimit(clientID string, limit config.ClientRateConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	client, ok := m.clients[clientID]
+	if !ok {
+		return i18n.Errorf(i18n.StorageUpdateClientFailed, clientID, ErrClientNotFound)
+	}
+	client.Rate, client.Capacity = limit.Rate, limit.Capacity
+	m.clients[clientID] = client
+	i18n.Logf(i18n.StorageLimitUpdated, clientID, limit.Rate, limit.Capacity)
+	return nil
+}
+
+// DeleteClientLimit удаляет клиента. Возвращает ErrClientNotFound, если клиента нет.
+func (m *MemoryStore) DeleteClientLimit(clientID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[clientID]; !ok {
+		return i18n.Errorf(i18n.StorageDeleteClientFailed, clientID, ErrClientNotFound)
+	}
+	delete(m.clients, clientID)
+	i18n.Logf(i18n.StorageLimitDeleted, clientID)
+	return nil
+}
+
+// ListClientLimits возвращает страницу клиентов в порядке ID, как (*DB).ListClientLimits.
+func (m *MemoryStore) ListClientLimits(prefix string, limit, offset int) (clients []ClientLimit, total int, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]string, 0, len(m.clients))
+	for clientID := range m.clients {
+		if strings.HasPrefix(clientID, prefix) {
+			ids = append(ids, clientID)
+		}
+	}
+	sort.Strings(ids)
+	total = len(ids)
+	for _, clientID := range ids[min(offset, total):min(offset+limit, total)] {
+		client := m.clients[clientID]
+		clients = append(clients, ClientLimit{
+			ClientID: clientID,
+			Rate:     client.Rate,
+			Capacity: client.Capacity,
+			Trial:    client.trial(),
+		})
+	}
+	return clients, total, nil
+}
+
+// BatchUpdateClientState сохраняет состояние корзин существующих клиентов; остальные пропускаются.
+func (m *MemoryStore) BatchUpdateClientState(states map[string]ClientState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	updated := 0
+	for clientID, state := range states {
+		if client, ok := m.clients[clientID]; ok {
+			client.State = state
+			m.clients[clientID] = client
+			updated++
+		}
+	}
+	if len(states) > 0 {
+		i18n.Logf(i18n.StorageBatchUpdated, updated, len(states))
+	}
+	return nil
+}
+
+// trial возвращает пробный период записи; нулевое Until - лимит постоянный.
+func (c ClientRecord) trial() ClientTrial {
+	if c.TrialUntil.IsZero() {
+		return ClientTrial{ClientID: c.ClientID}
+	}
+	return ClientTrial{ClientID: c.ClientID, Until: c.TrialUntil, Plan: c.TrialPlan}
+}
+
+// SetClientTrial задает или снимает (нулевое Until) пробный период клиента. Возвращает ErrClientNotFound,
+// если клиента нет.
+func (m *MemoryStore) SetClientTrial(trial ClientTrial) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	client, ok := m.clients[trial.ClientID]
+	if !ok {
+		return i18n.Errorf(i18n.StorageSetTrialFailed, trial.ClientID, ErrClientNotFound)
+	}
+	client.TrialUntil, client.TrialPlan = time.Time{}, ""
+	if !trial.Until.IsZero() {
+		client.TrialUntil, client.TrialPlan = trial.Until, trial.Plan
+		i18n.Logf(i18n.StorageTrialSet, trial.ClientID, trial.Until.Format(time.RFC3339), trial.Plan)
+	}
+	m.clients[trial.ClientID] = client
+	return nil
+}
+
+// GetClientTrial возвращает пробный период клиента; found = false, если клиента нет или его лимит постоянный.
+func (m *MemoryStore) GetClientTrial(clientID string) (trial ClientTrial, found bool, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	client, ok := m.clients[clientID]
+	if !ok || client.TrialUntil.IsZero() {
+		return ClientTrial{}, false, nil
+	}
+	return client.trial(), true, nil
+}
+
+// ExpiredClientTrials возвращает пробные периоды, закончившиеся к моменту now, в порядке окончания.
+func (m *MemoryStore) ExpiredClientTrials(now time.Time) ([]ClientTrial, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var trials []ClientTrial
+	for _, client := range m.clients {
+		if !client.TrialUntil.IsZero() && !client.TrialUntil.After(now) {
+			trials = append(trials, client.trial())
+		}
+	}
+	sort.Slice(trials, func(i, j int) bool {
+		if !trials[i].Until.Equal(trials[j].Until) {
+			return trials[i].Until.Before(trials[j].Until)
+		}
+		return trials[i].ClientID < trials[j].ClientID
+	})
+	return trials, nil
+}
+
+// DowngradeClientTrial переводит клиента на лимиты плана, если его пробный период не изменился
+// (см. (*DB).DowngradeClientTrial).
+func (m *MemoryStore) DowngradeClientTrial(trial ClientTrial, limit config.ClientRateConfig) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	client, ok := m.clients[trial.ClientID]
+	if !ok || !client.TrialUntil.Equal(trial.Until) || client.TrialPlan != trial.Plan {
+		return false, nil
+	}
+	client.Rate, client.Capacity = limit.Rate, limit.Capacity
+	client.TrialUntil, client.TrialPlan = time.Time{}, ""
+	m.clients[trial.ClientID] = client
+	return true, nil
+}
+
+// PutAccessEntry добавляет запись в список доступа или заменяет запись с тем же значением.
+func (m *MemoryStore) PutAccessEntry(entry AccessEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.access[entry.Value] = entry
+	i18n.Logf(i18n.StorageAccessEntryPut, entry.Value, entry.List)
+	return nil
+}
+
+// DeleteAccessEntry удаляет запись из списка доступа. Возвращает ErrAccessEntryNotFound, если записи нет.
+func (m *MemoryStore) DeleteAccessEntry(value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.access[value]; !ok {
+		return i18n.Errorf(i18n.StorageDeleteAccessEntryFailed, value, ErrAccessEntryNotFound)
+	}
+	delete(m.access, value)
+	i18n.Logf(i18n.StorageAccessEntryDeleted, value)
+	return nil
+}
+
+// ListAccessEntries возвращает все записи списков доступа, включая истекшие, в порядке значений.
+func (m *MemoryStore) ListAccessEntries() ([]AccessEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entries := make([]AccessEntry, 0, len(m.access))
+	for _, entry := range m.access {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Value < entries[j].Value })
+	return entries, nil
+}
+
+// DeleteExpiredAccessEntries удаляет записи, срок действия которых истек к моменту now.
+func (m *MemoryStore) DeleteExpiredAccessEntries(now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var deleted int64
+	for value, entry := range m.access {
+		if entry.Expired(now) {
+			delete(m.access, value)
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		i18n.Logf(i18n.StorageAccessEntriesExpired, deleted)
+	}
+	return deleted, nil
+}
+
+// SaveBackendHealth заменяет сохраненный статус бэкендов (URL -> доступен).
+func (m *MemoryStore) SaveBackendHealth(states map[string]bool, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.health = make(map[string]BackendHealthRecord, len(states))
+	for url, alive := range states {
+		m.health[url] = BackendHealthRecord{URL: url, Alive: alive, SavedAt: now}
+	}
+	i18n.Logf(i18n.StorageHealthSaved, len(states))
+	return nil
+}
+
+// LoadBackendHealth возвращает статус бэкендов, сохраненный SaveBackendHealth.
+func (m *MemoryStore) LoadBackendHealth() (map[string]bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	states := make(map[string]bool, len(m.health))
+	for url, record := range m.health {
+		states[url] = record.Alive
+	}
+	return states, nil
+}
+
+// PutAPIKey сохраняет API-ключ или заменяет ключ с тем же хэшем.
+func (m *MemoryStore) PutAPIKey(key APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apiKeys[key.Hash] = key
+	i18n.Logf(i18n.StorageAPIKeyPut, key.ClientID)
+	return nil
+}
+
+// LookupAPIKey находит ключ по хэшу. found = false - ключа нет.
+func (m *MemoryStore) LookupAPIKey(hash string) (key APIKey, found bool, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key, found = m.apiKeys[hash]
+	return key, found, nil
+}
+
+// DeleteAPIKey отзывает ключ. Возвращает ErrAPIKeyNotFound, если ключа нет.
+func (m *MemoryStore) DeleteAPIKey(hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.apiKeys[hash]; !ok {
+		return i18n.Errorf(i18n.StorageDeleteAPIKeyFailed, hash, ErrAPIKeyNotFound)
+	}
+	delete(m.apiKeys, hash)
+	i18n.Logf(i18n.StorageAPIKeyDeleted, hash)
+	return nil
+}
+
+// ListAPIKeys возвращает все ключи в порядке ID клиентов и времени выдачи.
+func (m *MemoryStore) ListAPIKeys() ([]APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]APIKey, 0, len(m.apiKeys))
+	for _, key := range m.apiKeys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.ClientID != b.ClientID {
+			return a.ClientID < b.ClientID
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.Hash < b.Hash
+	})
+	return keys, nil
+}
+
+// Export возвращает все данные хранилища в том же порядке, что и (*DB).Export.
+func (m *MemoryStore) Export() (Snapshot, error) {
+	var snapshot Snapshot
+	m.mu.RLock()
+	for _, client := range m.clients {
+		snapshot.Clients = append(snapshot.Clients, client)
+	}
+	for _, record := range m.health {
+		snapshot.BackendHealth = append(snapshot.BackendHealth, record)
+	}
+	m.mu.RUnlock()
+	sort.Slice(snapshot.Clients, func(i, j int) bool { return snapshot.Clients[i].ClientID < snapshot.Clients[j].ClientID })
+	sort.Slice(snapshot.BackendHealth, func(i, j int) bool { return snapshot.BackendHealth[i].URL < snapshot.BackendHealth[j].URL })
+	snapshot.AccessEntries, _ = m.ListAccessEntries()
+	snapshot.APIKeys, _ = m.ListAPIKeys()
+	return snapshot, nil
+}
+
+// Import записывает снимок в пустое хранилище. Возвращает ErrStoreNotEmpty, если данные уже есть.
+func (m *MemoryStore) Import(snapshot Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.clients)+len(m.access)+len(m.health)+len(m.apiKeys) > 0 {
+		return i18n.Errorf(i18n.StorageImportFailed, ErrStoreNotEmpty)
+	}
+	for _, client := range snapshot.Clients {
+		m.clients[client.ClientID] = client
+	}
+	for _, entry := range snapshot.AccessEntries {
+		m.access[entry.Value] = entry
+	}
+	for _, record := range snapshot.BackendHealth {
+		m.health[record.URL] = record
+	}
+	for _, key := range snapshot.APIKeys {
+		m.apiKeys[key.Hash] = key
+	}
+	return nil
+}
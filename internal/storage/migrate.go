@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"load-balancer/internal/i18n"
+)
+
+var (
+	// ErrUnknownDriver возвращается OpenStore для адреса с незарегистрированным драйвером.
+	ErrUnknownDriver = i18n.NewError(i18n.StorageUnknownDriver)
+	// ErrStoreNotEmpty возвращается Import, если в хранилище уже есть данные: перенос не объединяет записи.
+	ErrStoreNotEmpty = i18n.NewError(i18n.StorageNotEmpty)
+	// ErrVerificationFailed возвращается Migrate, если данные в новом хранилище отличаются от исходных.
+	ErrVerificationFailed = i18n.NewError(i18n.StorageVerificationFailed)
+)
+
+// Snapshot - содержимое хранилища, переносимое между драйверами (см. Migrate).
+type Snapshot struct {
+	Clients       []ClientRecord
+	AccessEntries []AccessEntry
+	BackendHealth []BackendHealthRecord
+	APIKeys       []APIKey
+}
+
+// ClientRecord - лимиты, сохраненное состояние корзины и пробный период клиента.
+type ClientRecord struct {
+	ClientID string
+	Rate     float64
+	Capacity float64
+	State    ClientState
+	// TrialUntil и TrialPlan - пробный период (см. ClientTrial); нулевое TrialUntil - лимит постоянный.
+	TrialUntil time.Time
+	TrialPlan  string
+}
+
+// BackendHealthRecord - статус бэкенда, сохраненный при остановке (см. SaveBackendHealth).
+type BackendHealthRecord struct {
+	URL     string
+	Alive   bool
+	SavedAt time.Time
+}
+
+// MigrationStore - хранилище, данные которого можно перенести в другое (реализуется *DB).
+type MigrationStore interface {
+	// Export возвращает все данные хранилища.
+	Export() (Snapshot, error)
+	// Import записывает данные в пустое хранилище одной операцией; ErrStoreNotEmpty - данные уже есть.
+	Import(snapshot Snapshot) error
+	Close() error
+}
+
+// Driver открывает хранилище по строке подключения (часть адреса после "<драйвер>:").
+type Driver func(dsn string) (MigrationStore, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{
+		"sqlite": func(dsn string) (MigrationStore, error) { return NewSQLiteDB(dsn) },
+	}
+)
+
+// RegisterDriver регистрирует драйвер хранилища под именем name для OpenStore.
+func RegisterDriver(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[name] = driver
+}
+
+// Drivers возвращает упорядоченные имена зарегистрированных драйверов.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenStore открывает хранилище по адресу "<драйвер>:<строка подключения>", например "sqlite:./rate_limits.db".
+func OpenStore(address string) (MigrationStore, error) {
+	name, dsn, _ := strings.Cut(address, ":")
+	driversMu.RLock()
+	driver, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok || dsn == "" {
+		return nil, i18n.Errorf(i18n.StorageUnknownDriverWrap, ErrUnknownDriver, address, strings.Join(Drivers(), ", "))
+	}
+	return driver(dsn)
+}
+
+// MigrationReport - результат переноса (см. Migrate).
+type MigrationReport struct {
+	Clients       int  `json:"clients"`
+	AccessEntries int  `json:"access_entries"`
+	BackendHealth int  `json:"backend_health"`
+	APIKeys       int  `json:"api_keys"`
+	Verified      bool `json:"verified"`
+}
+
+// Migrate переносит данные из from в пустое хранилище to и сверяет их: после записи данные to читаются
+// заново и сравниваются с исходными. Исходное хранилище не меняется.
+func Migrate(from, to MigrationStore) (MigrationReport, error) {
+	snapshot, err := from.Export()
+	if err != nil {
+		return MigrationReport{}, err
+	}
+	report := MigrationReport{
+		Clients:       len(snapshot.Clients),
+		AccessEntries: len(snapshot.AccessEntries),
+		BackendHealth: len(snapshot.BackendHealth),
+		APIKeys:       len(snapshot.APIKeys),
+	}
+	if err := to.Import(snapshot); err != nil {
+		return report, err
+	}
+	copied, err := to.Export()
+	if err != nil {
+		return report, err
+	}
+	if table, ok := snapshot.sameAs(copied); !ok {
+		return report, i18n.Errorf(i18n.StorageVerificationFailedWrap, ErrVerificationFailed, table)
+	}
+	report.Verified = true
+	i18n.Logf(i18n.StorageMigrated, report.Clients, report.AccessEntries, report.BackendHealth, report.APIKeys)
+	return report, nil
+}
+
+// sameAs сравнивает снимки; при расхождении возвращает имя таблицы, в которой оно найдено.
+func (s Snapshot) sameAs(other Snapshot) (string, bool) {
+	if len(s.Clients) != len(other.Clients) {
+		return "client_rate_limits", false
+	}
+	for i, a := range s.Clients {
+		b := other.Clients[i]
+		if a.ClientID != b.ClientID || a.Rate != b.Rate || a.Capacity != b.Capacity ||
+			a.State.Tokens != b.State.Tokens || !a.State.LastRefill.Equal(b.State.LastRefill) ||
+			!a.TrialUntil.Equal(b.TrialUntil) || a.TrialPlan != b.TrialPlan {
+			return "client_rate_limits", false
+		}
+	}
+	if len(s.AccessEntries) != len(other.AccessEntries) {
+		return "access_list", false
+	}
+	for i, a := range s.AccessEntries {
+		b := other.AccessEntries[i]
+		if a.Value != b.Value || a.List != b.List || a.Reason != b.Reason ||
+			!a.CreatedAt.Equal(b.CreatedAt) || !a.ExpiresAt.Equal(b.ExpiresAt) {
+			return "access_list", false
+		}
+	}
+	if len(s.BackendHealth) != len(other.BackendHealth) {
+		return "backend_health", false
+	}
+	for i, a := range s.BackendHealth {
+		b := other.BackendHealth[i]
+		if a.URL != b.URL || a.Alive != b.Alive || !a.SavedAt.Equal(b.SavedAt) {
+			return "backend_health", false
+		}
+	}
+	if len(s.APIKeys) != len(other.APIKeys) {
+		return "api_keys", false
+	}
+	for i, a := range s.APIKeys {
+		b := other.APIKeys[i]
+		if a.Hash != b.Hash || a.ClientID != b.ClientID || a.Plan != b.Plan || !a.CreatedAt.Equal(b.CreatedAt) {
+			return "api_keys", false
+		}
+	}
+	return "", true
+}
+
+// Export возвращает все данные базы: клиентов, записи списков доступа (включая истекшие), статус бэкендов
+// и API-ключи.
+func (db *DB) Export() (Snapshot, error) {
+	var snapshot Snapshot
+	rows, err := db.Conn.Query(`SELECT client_id, rate, capacity, current_tokens, last_refill, trial_until, trial_plan
+		FROM client_rate_limits ORDER BY client_id`)
+	if err != nil {
+		return Snapshot{}, i18n.Errorf(i18n.StorageExportFailed, err)
+	}
+	for rows.Next() {
+		var client ClientRecord
+		var lastRefill string
+		var trialUntil int64
+		if err := rows.Scan(&client.ClientID, &client.Rate, &client.Capacity, &client.State.Tokens, &lastRefill,
+			&trialUntil, &client.TrialPlan); err != nil {
+			rows.Close()
+			return Snapshot{}, i18n.Errorf(i18n.StorageExportFailed, err)
+		}
+		// Некорректное время пополнения читается как нулевое, как в GetClientSavedState
+		client.State.LastRefill, _ = time.Parse(time.RFC3339Nano, lastRefill)
+		if trialUntil != 0 {
+			client.TrialUntil = time.Unix(0, trialUntil)
+		}
+		snapshot.Clients = append(snapshot.Clients, client)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Snapshot{}, i18n.Errorf(i18n.StorageExportFailed, err)
+	}
+
+	if snapshot.AccessEntries, err = db.ListAccessEntries(); err != nil {
+		return Snapshot{}, err
+	}
+
+	rows, err = db.Conn.Query(`SELECT url, alive, saved_at FROM backend_health ORDER BY url`)
+	if err != nil {
+		return Snapshot{}, i18n.Errorf(i18n.StorageExportFailed, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var record BackendHealthRecord
+		var savedAt string
+		if err := rows.Scan(&record.URL, &record.Alive, &savedAt); err != nil {
+			return Snapshot{}, i18n.Errorf(i18n.StorageExportFailed, err)
+		}
+		record.SavedAt, _ = time.Parse(time.RFC3339Nano, savedAt)
+		snapshot.BackendHealth = append(snapshot.BackendHealth, record)
+	}
+	if err := rows.Err(); err != nil {
+		return Snapshot{}, i18n.Errorf(i18n.StorageExportFailed, err)
+	}
+
+	if snapshot.APIKeys, err = db.ListAPIKeys(); err != nil {
+		return Snapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// Import записывает снимок в пустую базу в одной транзакции. Возвращает ErrStoreNotEmpty,
+// если в базе уже есть клиенты, записи списков доступа, статус бэкендов или API-ключи.
+func (db *DB) Import(snapshot Snapshot) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return i18n.Errorf(i18n.StorageBeginTxFailed, err)
+	}
+	defer tx.Rollback() // Откат по умолчанию, если Commit не будет вызван
+
+	var existing int
+	if err := tx.QueryRow(`SELECT (SELECT COUNT(*) FROM client_rate_limits) + (SELECT COUNT(*) FROM access_list)
+		+ (SELECT COUNT(*) FROM backend_health) + (SELECT COUNT(*) FROM api_keys)`).Scan(&existing); err != nil {
+		return i18n.Errorf(i18n.StorageImportFailed, err)
+	}
+	if existing > 0 {
+		return i18n.Errorf(i18n.StorageImportFailed, ErrStoreNotEmpty)
+	}
+
+	for _, client := range snapshot.Clients {
+		var trialUntil int64
+		if !client.TrialUntil.IsZero() {
+			trialUntil = client.TrialUntil.UnixNano()
+		}
+		if _, err := tx.Exec(`INSERT INTO client_rate_limits
+			(client_id, rate, capacity, current_tokens, last_refill, trial_until, trial_plan) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			client.ClientID, client.Rate, client.Capacity, client.State.Tokens, formatTime(client.State.LastRefill),
+			trialUntil, client.TrialPlan); err != nil {
+			return i18n.Errorf(i18n.StorageImportFailed, err)
+		}
+	}
+	for _, entry := range snapshot.AccessEntries {
+		var expiresAt int64
+		if !entry.ExpiresAt.IsZero() {
+			expiresAt = entry.ExpiresAt.UnixNano()
+		}
+		if _, err := tx.Exec(`INSERT INTO access_list (value, list, reason, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+			entry.Value, entry.List, entry.Reason, entry.CreatedAt.Format(time.RFC3339Nano), expiresAt); err != nil {
+			return i18n.Errorf(i18n.StorageImportFailed, err)
+		}
+	}
+	for _, record := range snapshot.BackendHealth {
+		if _, err := tx.Exec(`INSERT INTO backend_health (url, alive, saved_at) VALUES (?, ?, ?)`,
+			record.URL, record.Alive, formatTime(record.SavedAt)); err != nil {
+			return i18n.Errorf(i18n.StorageImportFailed, err)
+		}
+	}
+	for _, key := range snapshot.APIKeys {
+		if _, err := tx.Exec(`INSERT INTO api_keys (key_hash, client_id, plan, created_at) VALUES (?, ?, ?, ?)`,
+			key.Hash, key.ClientID, key.Plan, formatTime(key.CreatedAt)); err != nil {
+			return i18n.Errorf(i18n.StorageImportFailed, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return i18n.Errorf(i18n.StorageCommitFailed, err)
+	}
+	return nil
+}
+
+// formatTime форматирует время для текстовых столбцов; нулевое время - пустая строка.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// Проверка, что *DB поддерживает перенос
+var _ MigrationStore = (*DB)(nil)
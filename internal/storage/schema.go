@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"load-balancer/internal/i18n"
+)
+
+// schemaMigrationsTable хранит примененные миграции схемы: версия, имя и время применения.
+const schemaMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TEXT NOT NULL DEFAULT ''
+	);
+	`
+
+// clientRateLimitsTable хранит лимиты клиентов и сохраненное состояние их корзин.
+const clientRateLimitsTable = `
+	CREATE TABLE IF NOT EXISTS client_rate_limits (
+		client_id TEXT PRIMARY KEY,
+		rate REAL NOT NULL,
+		capacity REAL NOT NULL,
+		current_tokens REAL NOT NULL DEFAULT 0.0,
+		last_refill TEXT NOT NULL DEFAULT ''
+	);
+	`
+
+// schemaMigration - шаг миграции схемы. Шаг выполняется один раз, в транзакции вместе с записью
+// в schema_migrations.
+type schemaMigration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// schemaMigrations - миграции схемы по возрастанию версии. Изменение схемы добавляется новым шагом
+// в конец списка; примененные шаги не меняются. Шаги 1-5 повторяют схему, которая создавалась до
+// появления миграций, поэтому не падают на уже существующих таблицах и столбцах: в такой базе они
+// только записываются в schema_migrations.
+var schemaMigrations = []schemaMigration{
+	{1, "client_rate_limits", execMigration(clientRateLimitsTable)},
+	{2, "client_trials", migrateTrialColumns},
+	{3, "access_list", execMigration(accessListTable)},
+	{4, "backend_health", execMigration(backendHealthTable)},
+	{5, "api_keys", execMigration(apiKeysTable)},
+}
+
+// execMigration возвращает шаг миграции, выполняющий query.
+func execMigration(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// migrateSchema применяет к базе миграции из schemaMigrations, которых нет в schema_migrations.
+// База, созданная более новой версией балансировщика (с неизвестными миграциями), не открывается:
+// старый код может испортить данные в схеме, которую не знает.
+func migrateSchema(conn *sql.DB) error {
+	if _, err := conn.Exec(schemaMigrationsTable); err != nil {
+		return i18n.Errorf(i18n.StorageSchemaVersionFailed, err)
+	}
+	version, err := schemaVersion(conn)
+	if err != nil {
+		return i18n.Errorf(i18n.StorageSchemaVersionFailed, err)
+	}
+	if latest := schemaMigrations[len(schemaMigrations)-1].version; version > latest {
+		return i18n.Errorf(i18n.StorageSchemaTooNew, version, latest)
+	}
+
+	for _, migration := range schemaMigrations {
+		if migration.version <= version {
+			continue
+		}
+		if err := applyMigration(conn, migration); err != nil {
+			return i18n.Errorf(i18n.StorageSchemaMigrationFailed, migration.version, migration.name, err)
+		}
+		i18n.Logf(i18n.StorageSchemaMigrated, migration.version, migration.name)
+	}
+	return nil
+}
+
+// applyMigration выполняет шаг миграции и записывает его в schema_migrations в одной транзакции.
+func applyMigration(conn *sql.DB, migration schemaMigration) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Откат по умолчанию, если Commit не будет вызван
+
+	if err := migration.up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		migration.version, migration.name, time.Now().UTC().Format(time.RFC3339Nano)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// schemaVersion возвращает версию последней примененной миграции, 0 - миграций не было.
+func schemaVersion(conn *sql.DB) (int, error) {
+	var version int
+	err := conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// SchemaVersion возвращает версию схемы базы (последней примененной миграции).
+func (db *DB) SchemaVersion() (int, error) {
+	version, err := schemaVersion(db.Conn)
+	if err != nil {
+		return 0, i18n.Errorf(i18n.StorageSchemaVersionFailed, err)
+	}
+	return version, nil
+}
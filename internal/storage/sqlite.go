@@ -1,13 +1,16 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
-	"log"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
 
 	_ "modernc.org/sqlite"
 )
@@ -20,42 +23,77 @@ type ClientState struct {
 
 // DB представляет обертку над соединением с базой данных.
 type DB struct {
-	Conn *sql.DB
+	Conn        *sql.DB
+	maintenance sync.Mutex // Упорядочивает Maintain
 }
 
-// NewSQLiteDB инициализирует соединение с базой данных SQLite и создает таблицу, если она не существует.
+// NewSQLiteDB открывает базу SQLite с параметрами соединений по умолчанию (см. OpenSQLiteDB).
 func NewSQLiteDB(dataSourceName string) (*DB, error) {
-	conn, err := sql.Open("sqlite", dataSourceName)
+	return OpenSQLiteDB(dataSourceName, config.DefaultSQLiteConfig())
+}
+
+// OpenSQLiteDB инициализирует соединение с базой данных SQLite с параметрами cfg (секция sqlite)
+// и применяет к ней недостающие миграции схемы.
+func OpenSQLiteDB(dataSourceName string, cfg config.SQLiteConfig) (*DB, error) {
+	conn, err := sql.Open("sqlite", sqliteDSN(dataSourceName, cfg))
 	if err != nil {
-		return nil, fmt.Errorf("ошибка открытия БД SQLite '%s': %w", dataSourceName, err)
+		return nil, i18n.Errorf(i18n.StorageOpenFailed, dataSourceName, err)
 	}
+	// Простаивающие соединения не закрываются: PRAGMA выполняются заново в каждом новом соединении
+	conn.SetMaxOpenConns(cfg.MaxOpenConns)
+	conn.SetMaxIdleConns(max(cfg.MaxOpenConns, 2))
 
 	// Проверяем соединение.
 	if err = conn.Ping(); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("ошибка подключения к БД SQLite '%s': %w", dataSourceName, err)
-	}
-
-	// Создаем таблицу для хранения лимитов, если она еще не существует.
-	query := `
-	CREATE TABLE IF NOT EXISTS client_rate_limits (
-		client_id TEXT PRIMARY KEY,
-		rate REAL NOT NULL,
-		capacity REAL NOT NULL,
-		current_tokens REAL NOT NULL DEFAULT 0.0,  
-		last_refill TEXT NOT NULL DEFAULT ''     
-	);
-	`
-	_, err = conn.Exec(query)
-	if err != nil {
+		return nil, i18n.Errorf(i18n.StoragePingFailed, dataSourceName, err)
+	}
+
+	// Свободные страницы возвращаются файловой системе при обслуживании (см. maintenance.go)
+	if err = enableIncrementalVacuum(conn); err != nil {
+		conn.Close()
+		return nil, i18n.Errorf(i18n.StorageEnableVacuumFailed, err)
+	}
+
+	// Таблицы создаются и обновляются миграциями схемы (см. schema.go)
+	if err = migrateSchema(conn); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("ошибка создания таблицы client_rate_limits: %w", err)
+		return nil, err
+	}
+
+	// WAL недоступен, например, для базы в памяти: SQLite оставляет прежний режим журнала
+	var journalMode string
+	if err = conn.QueryRow(`PRAGMA journal_mode`).Scan(&journalMode); err == nil && journalMode != cfg.JournalMode {
+		i18n.Logf(i18n.StorageJournalModeMismatch, cfg.JournalMode, journalMode)
 	}
 
-	log.Printf("[Storage] Успешно подключено к SQLite DB (pure-go): %s", dataSourceName)
+	i18n.Logf(i18n.StorageConnected, dataSourceName)
 	return &DB{Conn: conn}, nil
 }
 
+// sqliteDSN добавляет к пути базы PRAGMA из cfg. Драйвер выполняет их в каждом новом соединении пула,
+// busy_timeout - первой.
+func sqliteDSN(dataSourceName string, cfg config.SQLiteConfig) string {
+	params := url.Values{}
+	pragma := func(name, value string) { params.Add("_pragma", name+"("+value+")") }
+	pragma("busy_timeout", strconv.FormatInt(cfg.BusyTimeout.Milliseconds(), 10))
+	pragma("journal_mode", cfg.JournalMode)
+	pragma("synchronous", cfg.Synchronous)
+	for name, value := range cfg.Pragmas {
+		pragma(name, value)
+	}
+	separator := "?"
+	if strings.Contains(dataSourceName, "?") {
+		separator = "&"
+	}
+	return dataSourceName + separator + params.Encode()
+}
+
+// Ping проверяет, что база данных доступна.
+func (db *DB) Ping(ctx context.Context) error {
+	return db.Conn.PingContext(ctx)
+}
+
 // Close закрывает соединение с базой данных.
 func (db *DB) Close() error {
 	if db.Conn != nil {
@@ -76,13 +114,13 @@ func (db *DB) GetClientLimitAndState(clientID string) (rate, capacity, tokens fl
 		if errScan == sql.ErrNoRows {
 			return 0, 0, 0, time.Time{}, false, nil
 		}
-		log.Printf("[Storage] Ошибка получения состояния для клиента '%s': %v", clientID, errScan)
-		return 0, 0, 0, time.Time{}, false, fmt.Errorf("ошибка запроса состояния клиента '%s': %w", clientID, errScan)
+		i18n.Logf(i18n.StorageGetStateFailed, clientID, errScan)
+		return 0, 0, 0, time.Time{}, false, i18n.Errorf(i18n.StorageQueryStateFailed, clientID, errScan)
 	}
 
 	lastRefillTime, errParse := time.Parse(time.RFC3339Nano, lastRefillStr)
 	if errParse != nil && lastRefillStr != "" {
-		log.Printf("[Storage] Ошибка парсинга last_refill ('%s') для клиента '%s': %v. Используется нулевое время.", lastRefillStr, clientID, errParse)
+		i18n.Logf(i18n.StorageBadLastRefill, lastRefillStr, clientID, errParse)
 		lastRefillTime = time.Time{}
 	}
 
@@ -99,8 +137,8 @@ func (db *DB) GetClientLimitConfig(clientID string) (rate, capacity float64, fou
 		if errScan == sql.ErrNoRows {
 			return 0, 0, false, nil // Не найдено
 		}
-		log.Printf("[Storage] Ошибка получения конфига лимита для клиента '%s': %v", clientID, errScan)
-		return 0, 0, false, fmt.Errorf("ошибка запроса конфига лимита клиента '%s': %w", clientID, errScan)
+		i18n.Logf(i18n.StorageGetConfigFailed, clientID, errScan)
+		return 0, 0, false, i18n.Errorf(i18n.StorageQueryConfigFailed, clientID, errScan)
 	}
 	return rateDB, capacityDB, true, nil
 }
@@ -116,14 +154,14 @@ func (db *DB) GetClientSavedState(clientID string) (tokens float64, lastRefill t
 		if errScan == sql.ErrNoRows {
 			return 0, time.Time{}, false, nil // Не найдено
 		}
-		log.Printf("[Storage] Ошибка получения сохраненного состояния для клиента '%s': %v", clientID, errScan)
-		return 0, time.Time{}, false, fmt.Errorf("ошибка запроса сохраненного состояния клиента '%s': %w", clientID, errScan)
+		i18n.Logf(i18n.StorageGetSavedStateFailed, clientID, errScan)
+		return 0, time.Time{}, false, i18n.Errorf(i18n.StorageQuerySavedStateFailed, clientID, errScan)
 	}
 
 	// Парсим время
 	lastRefillTime, errParse := time.Parse(time.RFC3339Nano, lastRefillStr)
 	if errParse != nil && lastRefillStr != "" { // Игнорируем ошибку парсинга для пустой строки (дефолт)
-		log.Printf("[Storage] Ошибка парсинга last_refill ('%s') для клиента '%s': %v. Используется нулевое время.", lastRefillStr, clientID, errParse)
+		i18n.Logf(i18n.StorageBadLastRefill, lastRefillStr, clientID, errParse)
 		lastRefillTime = time.Time{} // Используем нулевое время при ошибке парсинга
 	}
 
@@ -140,11 +178,11 @@ func (db *DB) CreateClientLimit(clientID string, limit config.ClientRateConfig)
 	_, err := db.Conn.Exec(query, clientID, limit.Rate, limit.Capacity, initialTokens, initialTimeStr)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") || strings.Contains(err.Error(), "constraint failed: client_rate_limits.client_id") {
-			return fmt.Errorf("ошибка добавления клиента '%s': %w", clientID, ErrClientAlreadyExists)
+			return i18n.Errorf(i18n.StorageAddClientFailed, clientID, ErrClientAlreadyExists)
 		}
-		return fmt.Errorf("ошибка добавления лимита для '%s': %w", clientID, err)
+		return i18n.Errorf(i18n.StorageAddLimitFailed, clientID, err)
 	}
-	log.Printf("[Storage] Добавлен лимит для клиента '%s': Rate=%.2f, Capacity=%.2f, Tokens=%.2f", clientID, limit.Rate, limit.Capacity, initialTokens)
+	i18n.Logf(i18n.StorageLimitAdded, clientID, limit.Rate, limit.Capacity, initialTokens)
 	return nil
 }
 
@@ -154,18 +192,18 @@ func (db *DB) UpdateClientLimit(clientID string, limit config.ClientRateConfig)
 	query := `UPDATE client_rate_limits SET rate = ?, capacity = ? WHERE client_id = ?`
 	res, err := db.Conn.Exec(query, limit.Rate, limit.Capacity, clientID)
 	if err != nil {
-		return fmt.Errorf("ошибка обновления лимита для '%s': %w", clientID, err)
+		return i18n.Errorf(i18n.StorageUpdateLimitFailed, clientID, err)
 	}
 
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("ошибка получения количества обновленных строк для '%s': %w", clientID, err)
+		return i18n.Errorf(i18n.StorageUpdatedRowsFailed, clientID, err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("ошибка обновления клиента '%s': %w", clientID, ErrClientNotFound)
+		return i18n.Errorf(i18n.StorageUpdateClientFailed, clientID, ErrClientNotFound)
 	}
 
-	log.Printf("[Storage] Обновлен лимит (rate/capacity) для клиента '%s': Rate=%.2f, Capacity=%.2f", clientID, limit.Rate, limit.Capacity)
+	i18n.Logf(i18n.StorageLimitUpdated, clientID, limit.Rate, limit.Capacity)
 	return nil
 }
 
@@ -175,21 +213,65 @@ func (db *DB) DeleteClientLimit(clientID string) error {
 	query := `DELETE FROM client_rate_limits WHERE client_id = ?`
 	res, err := db.Conn.Exec(query, clientID)
 	if err != nil {
-		return fmt.Errorf("ошибка удаления лимита для '%s': %w", clientID, err)
+		return i18n.Errorf(i18n.StorageDeleteLimitFailed, clientID, err)
 	}
 
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("ошибка получения количества удаленных строк для '%s': %w", clientID, err)
+		return i18n.Errorf(i18n.StorageDeletedRowsFailed, clientID, err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("ошибка удаления клиента '%s': %w", clientID, ErrClientNotFound)
+		return i18n.Errorf(i18n.StorageDeleteClientFailed, clientID, ErrClientNotFound)
 	}
 
-	log.Printf("[Storage] Удален лимит для клиента '%s'", clientID)
+	i18n.Logf(i18n.StorageLimitDeleted, clientID)
 	return nil
 }
 
+// ClientLimit - лимиты клиента в списке ListClientLimits.
+type ClientLimit struct {
+	ClientID string
+	Rate     float64
+	Capacity float64
+	Trial    ClientTrial // Нулевое Trial.Until - пробного периода нет.
+}
+
+// ListClientLimits возвращает страницу лимитов клиентов в порядке ID: не больше limit записей начиная
+// с offset-й, только клиентов с ID, начинающимся с prefix (пусто - всех). total - сколько всего клиентов
+// с таким префиксом, без учета limit и offset.
+func (db *DB) ListClientLimits(prefix string, limit, offset int) (clients []ClientLimit, total int, err error) {
+	// instr(client_id, '') = 1, поэтому пустой префикс выбирает всех клиентов
+	if err := db.Conn.QueryRow(`SELECT COUNT(*) FROM client_rate_limits WHERE instr(client_id, ?) = 1`, prefix).
+		Scan(&total); err != nil {
+		return nil, 0, i18n.Errorf(i18n.StorageListLimitsFailed, err)
+	}
+	rows, err := db.Conn.Query(`SELECT client_id, rate, capacity, trial_until, trial_plan FROM client_rate_limits
+		WHERE instr(client_id, ?) = 1 ORDER BY client_id LIMIT ? OFFSET ?`, prefix, limit, offset)
+	if err != nil {
+		return nil, 0, i18n.Errorf(i18n.StorageListLimitsFailed, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var client ClientLimit
+		var trialUntil int64
+		if err := rows.Scan(&client.ClientID, &client.Rate, &client.Capacity, &trialUntil, &client.Trial.Plan); err != nil {
+			return nil, 0, i18n.Errorf(i18n.StorageListLimitsFailed, err)
+		}
+		client.Trial.ClientID = client.ClientID
+		if trialUntil != 0 {
+			client.Trial.Until = time.Unix(0, trialUntil)
+		} else {
+			client.Trial.Plan = ""
+		}
+		clients = append(clients, client)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, i18n.Errorf(i18n.StorageListLimitsFailed, err)
+	}
+	return clients, total, nil
+}
+
 // BatchUpdateClientState обновляет состояние (tokens, last_refill) для нескольких клиентов в одной транзакции.
 func (db *DB) BatchUpdateClientState(states map[string]ClientState) error {
 	if len(states) == 0 {
@@ -198,13 +280,13 @@ func (db *DB) BatchUpdateClientState(states map[string]ClientState) error {
 
 	tx, err := db.Conn.Begin()
 	if err != nil {
-		return fmt.Errorf("ошибка начала транзакции для batch update: %w", err)
+		return i18n.Errorf(i18n.StorageBeginTxFailed, err)
 	}
 	defer tx.Rollback() // Откат по умолчанию, если Commit не будет вызван
 
 	stmt, err := tx.Prepare("UPDATE client_rate_limits SET current_tokens = ?, last_refill = ? WHERE client_id = ?")
 	if err != nil {
-		return fmt.Errorf("ошибка подготовки запроса для batch update: %w", err)
+		return i18n.Errorf(i18n.StoragePrepareFailed, err)
 	}
 	defer stmt.Close()
 
@@ -214,8 +296,8 @@ func (db *DB) BatchUpdateClientState(states map[string]ClientState) error {
 		res, err := stmt.Exec(state.Tokens, lastRefillStr, clientID)
 		if err != nil {
 			// Можно добавить логирование конкретной ошибки, но пока просто возвращаем общую
-			log.Printf("[Storage] Ошибка обновления состояния для клиента '%s' в batch: %v", clientID, err)
-			return fmt.Errorf("ошибка выполнения batch update для клиента '%s': %w", clientID, err)
+			i18n.Logf(i18n.StorageBatchClientFailed, clientID, err)
+			return i18n.Errorf(i18n.StorageBatchExecFailed, clientID, err)
 		}
 		// Проверяем, была ли строка действительно обновлена (на случай, если клиент был удален)
 		rowsAffected, _ := res.RowsAffected()
@@ -226,10 +308,10 @@ func (db *DB) BatchUpdateClientState(states map[string]ClientState) error {
 
 	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("ошибка commit транзакции для batch update: %w", err)
+		return i18n.Errorf(i18n.StorageCommitFailed, err)
 	}
 
-	log.Printf("[Storage] BatchUpdateClientState: Успешно обновлено состояние для %d из %d клиентов.", updatedCount, len(states))
+	i18n.Logf(i18n.StorageBatchUpdated, updatedCount, len(states))
 	return nil
 }
 
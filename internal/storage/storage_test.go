@@ -1,7 +1,11 @@
 package storage_test
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -182,6 +186,40 @@ func TestGetClientLimitAndState(t *testing.T) {
 }
 
 // TestBatchUpdateClientState проверяет массовое обновление состояния.
+// TestListClientLimits проверяет постраничный список клиентов с фильтром по префиксу ID.
+func TestListClientLimits(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for i, clientID := range []string{"team-b", "team-a", "solo", "team_c"} {
+		require.NoError(t, db.CreateClientLimit(clientID, config.ClientRateConfig{Rate: float64(i + 1), Capacity: 10}))
+	}
+	until := time.Now().Add(time.Hour)
+	require.NoError(t, db.SetClientTrial(storage.ClientTrial{ClientID: "team-a", Until: until, Plan: "free"}))
+
+	clients, total, err := db.ListClientLimits("", 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 4, total)
+	require.Len(t, clients, 2)
+	assert.Equal(t, "solo", clients[0].ClientID, "Клиенты отсортированы по ID")
+	assert.Equal(t, 3.0, clients[0].Rate)
+	assert.True(t, clients[0].Trial.Until.IsZero())
+	assert.Equal(t, "team-a", clients[1].ClientID)
+	assert.True(t, clients[1].Trial.Until.Equal(until))
+	assert.Equal(t, "free", clients[1].Trial.Plan)
+
+	clients, total, err = db.ListClientLimits("team-", 10, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total, "Префикс сравнивается буквально, без шаблонов LIKE")
+	require.Len(t, clients, 1)
+	assert.Equal(t, "team-b", clients[0].ClientID)
+
+	clients, total, err = db.ListClientLimits("none", 10, 0)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, clients)
+}
+
 func TestBatchUpdateClientState(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -239,3 +277,443 @@ func TestBatchUpdateClientState(t *testing.T) {
 	assert.Equal(t, limit1.Rate, rate1)
 	assert.Equal(t, limit1.Capacity, capacity1)
 }
+
+// TestDBAccessEntries проверяет сохранение, замену, удаление и истечение записей списков доступа.
+func TestDBAccessEntries(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	require.NoError(t, db.PutAccessEntry(storage.AccessEntry{Value: "203.0.113.7", List: config.AccessListDeny, Reason: "scan", CreatedAt: now}))
+	require.NoError(t, db.PutAccessEntry(storage.AccessEntry{Value: "10.0.0.0/8", List: config.AccessListAllow, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, db.PutAccessEntry(storage.AccessEntry{Value: "old", List: config.AccessListDeny, CreatedAt: now, ExpiresAt: now.Add(-time.Second)}))
+
+	// Запись с тем же значением заменяется
+	require.NoError(t, db.PutAccessEntry(storage.AccessEntry{Value: "203.0.113.7", List: config.AccessListDeny, Reason: "abuse", CreatedAt: now}))
+
+	entries, err := db.ListAccessEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "10.0.0.0/8", entries[0].Value, "Записи отсортированы по значению")
+	assert.True(t, entries[0].ExpiresAt.Equal(now.Add(time.Hour)))
+	assert.Equal(t, "abuse", entries[1].Reason)
+	assert.True(t, entries[1].ExpiresAt.IsZero(), "Бессрочная запись")
+	assert.True(t, entries[1].CreatedAt.Equal(now))
+	assert.True(t, entries[2].Expired(now))
+
+	deleted, err := db.DeleteExpiredAccessEntries(now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	require.NoError(t, db.DeleteAccessEntry("203.0.113.7"))
+	err = db.DeleteAccessEntry("203.0.113.7")
+	require.ErrorIs(t, err, storage.ErrAccessEntryNotFound)
+
+	entries, err = db.ListAccessEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, config.AccessListAllow, entries[0].List)
+}
+
+func TestDBBackendHealth(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	states, err := db.LoadBackendHealth()
+	require.NoError(t, err)
+	assert.Empty(t, states)
+
+	require.NoError(t, db.SaveBackendHealth(map[string]bool{"http://a": true, "http://b": false}, time.Now()))
+	states, err = db.LoadBackendHealth()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"http://a": true, "http://b": false}, states)
+
+	// Бэкенды, убранные из конфигурации, не остаются в таблице
+	require.NoError(t, db.SaveBackendHealth(map[string]bool{"http://b": true}, time.Now()))
+	states, err = db.LoadBackendHealth()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"http://b": true}, states)
+}
+
+// TestDBAPIKeys проверяет хранение API-ключей: поиск по хэшу, список и отзыв.
+func TestDBAPIKeys(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	hash := storage.HashAPIKey("secret")
+	assert.NotContains(t, hash, "secret")
+	assert.Len(t, hash, 64)
+
+	_, found, err := db.LookupAPIKey(hash)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	now := time.Now()
+	require.NoError(t, db.PutAPIKey(storage.APIKey{Hash: hash, ClientID: "acme", Plan: "pro", CreatedAt: now}))
+	require.NoError(t, db.PutAPIKey(storage.APIKey{Hash: storage.HashAPIKey("other"), ClientID: "acme", CreatedAt: now.Add(time.Second)}))
+
+	key, found, err := db.LookupAPIKey(hash)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "acme", key.ClientID)
+	assert.Equal(t, "pro", key.Plan)
+	assert.True(t, key.CreatedAt.Equal(now))
+
+	keys, err := db.ListAPIKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, hash, keys[0].Hash, "Ключи клиента отсортированы по времени выдачи")
+
+	require.NoError(t, db.DeleteAPIKey(hash))
+	require.ErrorIs(t, db.DeleteAPIKey(hash), storage.ErrAPIKeyNotFound)
+	_, found, err = db.LookupAPIKey(hash)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+// TestDBClientTrials проверяет пробные периоды клиентов и добавление их столбцов в таблицу,
+// созданную до их появления.
+func TestDBClientTrials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	conn, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = conn.Exec(`CREATE TABLE client_rate_limits (client_id TEXT PRIMARY KEY, rate REAL NOT NULL,
+		capacity REAL NOT NULL, current_tokens REAL NOT NULL DEFAULT 0.0, last_refill TEXT NOT NULL DEFAULT '');
+		INSERT INTO client_rate_limits (client_id, rate, capacity) VALUES ('old-client', 1, 10)`)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	db, err := storage.NewSQLiteDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+	_, found, err := db.GetClientTrial("old-client")
+	require.NoError(t, err)
+	assert.False(t, found, "Существующие клиенты получают постоянный лимит")
+
+	now := time.Now()
+	require.NoError(t, db.CreateClientLimit("trial", config.ClientRateConfig{Rate: 50, Capacity: 500}))
+	trial := storage.ClientTrial{ClientID: "trial", Until: now.Add(time.Hour), Plan: "free"}
+	require.NoError(t, db.SetClientTrial(trial))
+	got, found, err := db.GetClientTrial("trial")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.True(t, trial.Until.Equal(got.Until))
+	assert.Equal(t, "free", got.Plan)
+	assert.ErrorIs(t, db.SetClientTrial(storage.ClientTrial{ClientID: "missing", Until: now}), storage.ErrClientNotFound)
+
+	expired, err := db.ExpiredClientTrials(now)
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+	expired, err = db.ExpiredClientTrials(now.Add(2 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+
+	// Продленный после выборки пробный период не снимается
+	require.NoError(t, db.SetClientTrial(storage.ClientTrial{ClientID: "trial", Until: now.Add(3 * time.Hour), Plan: "free"}))
+	changed, err := db.DowngradeClientTrial(expired[0], config.ClientRateConfig{Rate: 1, Capacity: 5})
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	expired, err = db.ExpiredClientTrials(now.Add(4 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	changed, err = db.DowngradeClientTrial(expired[0], config.ClientRateConfig{Rate: 1, Capacity: 5})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	rate, capacity, _, err := db.GetClientLimitConfig("trial")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 5}, []float64{rate, capacity})
+	_, found, err = db.GetClientTrial("trial")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+// TestStoreImplementations проверяет одинаковое поведение *DB и *MemoryStore через storage.Store.
+func TestStoreImplementations(t *testing.T) {
+	for name, open := range map[string]func(t *testing.T) storage.Store{
+		"sqlite": func(t *testing.T) storage.Store {
+			db, cleanup := setupTestDB(t)
+			t.Cleanup(cleanup)
+			return db
+		},
+		"memory": func(t *testing.T) storage.Store { return storage.NewMemoryStore() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			require.NoError(t, store.Ping(context.Background()))
+
+			// Лимиты клиентов
+			require.NoError(t, store.CreateClientLimit("b", config.ClientRateConfig{Rate: 1, Capacity: 10}))
+			require.NoError(t, store.CreateClientLimit("a", config.ClientRateConfig{Rate: 2, Capacity: 20}))
+			assert.ErrorIs(t, store.CreateClientLimit("a", config.ClientRateConfig{Rate: 1, Capacity: 1}), storage.ErrClientAlreadyExists)
+			require.NoError(t, store.UpdateClientLimit("a", config.ClientRateConfig{Rate: 3, Capacity: 30}))
+			assert.ErrorIs(t, store.UpdateClientLimit("missing", config.ClientRateConfig{Rate: 1, Capacity: 1}), storage.ErrClientNotFound)
+			rate, capacity, found, err := store.GetClientLimitConfig("a")
+			require.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, []float64{3, 30}, []float64{rate, capacity})
+			_, _, found, err = store.GetClientLimitConfig("missing")
+			require.NoError(t, err)
+			assert.False(t, found)
+
+			refill := time.Now().Add(-time.Minute).Round(0)
+			require.NoError(t, store.BatchUpdateClientState(map[string]storage.ClientState{"b": {Tokens: 4, LastRefill: refill}, "missing": {Tokens: 1}}))
+			tokens, lastRefill, found, err := store.GetClientSavedState("b")
+			require.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, 4.0, tokens)
+			assert.True(t, refill.Equal(lastRefill))
+
+			clients, total, err := store.ListClientLimits("", 1, 1)
+			require.NoError(t, err)
+			assert.Equal(t, 2, total)
+			require.Len(t, clients, 1)
+			assert.Equal(t, "b", clients[0].ClientID)
+
+			// Пробные периоды
+			until := time.Now().Add(time.Hour).Round(0)
+			require.NoError(t, store.SetClientTrial(storage.ClientTrial{ClientID: "a", Until: until, Plan: "free"}))
+			assert.ErrorIs(t, store.SetClientTrial(storage.ClientTrial{ClientID: "missing", Until: until}), storage.ErrClientNotFound)
+			expired, err := store.ExpiredClientTrials(until)
+			require.NoError(t, err)
+			require.Len(t, expired, 1)
+			changed, err := store.DowngradeClientTrial(expired[0], config.ClientRateConfig{Rate: 1, Capacity: 5})
+			require.NoError(t, err)
+			assert.True(t, changed)
+			_, found, err = store.GetClientTrial("a")
+			require.NoError(t, err)
+			assert.False(t, found)
+
+			require.NoError(t, store.DeleteClientLimit("b"))
+			assert.ErrorIs(t, store.DeleteClientLimit("b"), storage.ErrClientNotFound)
+
+			// Списки доступа и API-ключи
+			now := time.Now()
+			require.NoError(t, store.PutAccessEntry(storage.AccessEntry{Value: "10.0.0.1", List: config.AccessListDeny, ExpiresAt: now}))
+			require.NoError(t, store.PutAccessEntry(storage.AccessEntry{Value: "10.0.0.2", List: config.AccessListAllow}))
+			deleted, err := store.DeleteExpiredAccessEntries(now)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), deleted)
+			entries, err := store.ListAccessEntries()
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			assert.Equal(t, "10.0.0.2", entries[0].Value)
+			assert.ErrorIs(t, store.DeleteAccessEntry("10.0.0.1"), storage.ErrAccessEntryNotFound)
+
+			require.NoError(t, store.PutAPIKey(storage.APIKey{Hash: storage.HashAPIKey("secret"), ClientID: "a"}))
+			key, found, err := store.LookupAPIKey(storage.HashAPIKey("secret"))
+			require.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, "a", key.ClientID)
+			require.NoError(t, store.DeleteAPIKey(key.Hash))
+			assert.ErrorIs(t, store.DeleteAPIKey(key.Hash), storage.ErrAPIKeyNotFound)
+
+			// Данные переносятся в базу SQLite без потерь
+			db, cleanup := setupTestDB(t)
+			defer cleanup()
+			report, err := storage.Migrate(store, db)
+			require.NoError(t, err)
+			assert.True(t, report.Verified)
+			assert.Equal(t, 1, report.Clients)
+			assert.ErrorIs(t, store.Import(storage.Snapshot{}), storage.ErrStoreNotEmpty)
+		})
+	}
+}
+
+// TestDBMaintain проверяет обслуживание базы: удаление устаревших записей и возврат свободных страниц.
+func TestDBMaintain(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	var mode int
+	require.NoError(t, db.Conn.QueryRow(`PRAGMA auto_vacuum`).Scan(&mode))
+	assert.Equal(t, 2, mode, "Новая база создается с auto_vacuum = INCREMENTAL")
+
+	// Частое создание и удаление клиентов оставляет в файле свободные страницы
+	for i := 0; i < 2000; i++ {
+		require.NoError(t, db.CreateClientLimit(fmt.Sprintf("churn-client-%04d", i), config.ClientRateConfig{Rate: 1, Capacity: 1}))
+	}
+	_, err := db.Conn.Exec(`DELETE FROM client_rate_limits`)
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, db.PutAccessEntry(storage.AccessEntry{Value: "expired", List: config.AccessListDeny, CreatedAt: now, ExpiresAt: now.Add(-time.Second)}))
+	require.NoError(t, db.PutAccessEntry(storage.AccessEntry{Value: "active", List: config.AccessListDeny, CreatedAt: now}))
+	require.NoError(t, db.SaveBackendHealth(map[string]bool{"http://a:80": true, "http://b:80": false}, now.Add(-48*time.Hour)))
+
+	cfg := config.StorageMaintenanceConfig{HealthRetention: 24 * time.Hour}
+	report, err := db.Maintain(now, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.ExpiredAccessEntries)
+	assert.Equal(t, int64(2), report.StaleHealthRows)
+	assert.Positive(t, report.FreedPages)
+	assert.Less(t, report.SizeAfter, report.SizeBefore)
+
+	entries, err := db.ListAccessEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "active", entries[0].Value)
+	health, err := db.LoadBackendHealth()
+	require.NoError(t, err)
+	assert.Empty(t, health)
+
+	// Свежий статус бэкендов не удаляется
+	require.NoError(t, db.SaveBackendHealth(map[string]bool{"http://a:80": true}, now))
+	report, err = db.Maintain(now, cfg)
+	require.NoError(t, err)
+	assert.Zero(t, report.StaleHealthRows)
+}
+
+// TestNewSQLiteDBEnablesIncrementalVacuum проверяет перевод базы, созданной без auto_vacuum, в режим INCREMENTAL.
+func TestNewSQLiteDBEnablesIncrementalVacuum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE client_rate_limits (client_id TEXT PRIMARY KEY, rate REAL NOT NULL, capacity REAL NOT NULL,
+		current_tokens REAL NOT NULL DEFAULT 0.0, last_refill TEXT NOT NULL DEFAULT '');
+		INSERT INTO client_rate_limits (client_id, rate, capacity) VALUES ('kept', 1, 2)`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := storage.NewSQLiteDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var mode int
+	require.NoError(t, db.Conn.QueryRow(`PRAGMA auto_vacuum`).Scan(&mode))
+	assert.Equal(t, 2, mode)
+	rate, capacity, found, err := db.GetClientLimitConfig("kept")
+	require.NoError(t, err)
+	assert.True(t, found, "Данные сохраняются после VACUUM")
+	assert.Equal(t, 1.0, rate)
+	assert.Equal(t, 2.0, capacity)
+}
+
+// TestOpenSQLiteDBPragmas проверяет PRAGMA секции sqlite в соединениях пула и чтение во время незавершенной записи в режиме WAL.
+func TestOpenSQLiteDBPragmas(t *testing.T) {
+	cfg := config.DefaultSQLiteConfig()
+	cfg.BusyTimeout = 250 * time.Millisecond
+	cfg.Pragmas = map[string]string{"cache_size": "-4096"}
+	db, err := storage.OpenSQLiteDB(filepath.Join(t.TempDir(), "wal.db"), cfg)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.CreateClientLimit("reader", config.ClientRateConfig{Rate: 1, Capacity: 2}))
+
+	var journalMode string
+	var busyTimeout, cacheSize int
+	require.NoError(t, db.Conn.QueryRow(`PRAGMA journal_mode`).Scan(&journalMode))
+	require.NoError(t, db.Conn.QueryRow(`PRAGMA busy_timeout`).Scan(&busyTimeout))
+	require.NoError(t, db.Conn.QueryRow(`PRAGMA cache_size`).Scan(&cacheSize))
+	assert.Equal(t, "wal", journalMode)
+	assert.Equal(t, 250, busyTimeout)
+	assert.Equal(t, -4096, cacheSize)
+
+	tx, err := db.Conn.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+	_, err = tx.Exec(`UPDATE client_rate_limits SET current_tokens = 1 WHERE client_id = 'reader'`)
+	require.NoError(t, err)
+	_, _, found, err := db.GetClientLimitConfig("reader")
+	require.NoError(t, err, "Чтение не ждет завершения записи")
+	assert.True(t, found)
+}
+
+// TestNewSQLiteDBSchemaMigrations проверяет миграции схемы: база, созданная до их появления (уже со столбцами
+// пробных лимитов), получает версию без потери данных, повторное открытие ничего не применяет,
+// а база с неизвестной миграцией не открывается.
+func TestNewSQLiteDBSchemaMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE client_rate_limits (client_id TEXT PRIMARY KEY, rate REAL NOT NULL, capacity REAL NOT NULL,
+		current_tokens REAL NOT NULL DEFAULT 0.0, last_refill TEXT NOT NULL DEFAULT '',
+		trial_until INTEGER NOT NULL DEFAULT 0, trial_plan TEXT NOT NULL DEFAULT '');
+		INSERT INTO client_rate_limits (client_id, rate, capacity, trial_until, trial_plan) VALUES ('kept', 1, 2, 1, 'free')`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := storage.NewSQLiteDB(path)
+	require.NoError(t, err)
+	version, err := db.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, 5, version)
+	trial, found, err := db.GetClientTrial("kept")
+	require.NoError(t, err)
+	require.True(t, found, "Данные сохраняются после миграций")
+	assert.Equal(t, "free", trial.Plan)
+	require.NoError(t, db.Close())
+
+	db, err = storage.NewSQLiteDB(path)
+	require.NoError(t, err)
+	var applied int
+	require.NoError(t, db.Conn.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, 5, applied, "Примененные миграции не повторяются")
+
+	_, err = db.Conn.Exec(`INSERT INTO schema_migrations (version, name) VALUES (100, 'future')`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	_, err = storage.NewSQLiteDB(path)
+	assert.Error(t, err, "База более новой версии не открывается")
+}
+
+// TestMigrate проверяет перенос клиентов, пробных периодов, списков доступа, статуса бэкендов и API-ключей
+// между базами SQLite и отказ переноса в непустое хранилище.
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	source, err := storage.OpenStore("sqlite:" + filepath.Join(dir, "source.db"))
+	require.NoError(t, err)
+	defer source.Close()
+	db := source.(*storage.DB)
+
+	now := time.Now()
+	require.NoError(t, db.CreateClientLimit("alice", config.ClientRateConfig{Rate: 5, Capacity: 10}))
+	require.NoError(t, db.CreateClientLimit("bob", config.ClientRateConfig{Rate: 1, Capacity: 2}))
+	require.NoError(t, db.BatchUpdateClientState(map[string]storage.ClientState{"alice": {Tokens: 3.5, LastRefill: now}}))
+	require.NoError(t, db.SetClientTrial(storage.ClientTrial{ClientID: "bob", Until: now.Add(time.Hour), Plan: "free"}))
+	require.NoError(t, db.PutAccessEntry(storage.AccessEntry{Value: "10.0.0.0/8", List: config.AccessListDeny, Reason: "abuse",
+		CreatedAt: now, ExpiresAt: now.Add(time.Minute)}))
+	require.NoError(t, db.SaveBackendHealth(map[string]bool{"http://a": true, "http://b": false}, now))
+	require.NoError(t, db.PutAPIKey(storage.APIKey{Hash: storage.HashAPIKey("secret"), ClientID: "alice", Plan: "pro", CreatedAt: now}))
+
+	destination, err := storage.OpenStore("sqlite:" + filepath.Join(dir, "destination.db"))
+	require.NoError(t, err)
+	defer destination.Close()
+
+	report, err := storage.Migrate(source, destination)
+	require.NoError(t, err)
+	assert.Equal(t, storage.MigrationReport{Clients: 2, AccessEntries: 1, BackendHealth: 2, APIKeys: 1, Verified: true}, report)
+
+	copied := destination.(*storage.DB)
+	tokens, lastRefill, found, err := copied.GetClientSavedState("alice")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 3.5, tokens)
+	assert.True(t, lastRefill.Equal(now))
+	trial, found, err := copied.GetClientTrial("bob")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "free", trial.Plan)
+	entries, err := copied.ListAccessEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "abuse", entries[0].Reason)
+	states, err := copied.LoadBackendHealth()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"http://a": true, "http://b": false}, states)
+	key, found, err := copied.LookupAPIKey(storage.HashAPIKey("secret"))
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "alice", key.ClientID)
+
+	// Повторный перенос не объединяет данные с уже перенесенными
+	_, err = storage.Migrate(source, destination)
+	assert.ErrorIs(t, err, storage.ErrStoreNotEmpty)
+}
+
+func TestOpenStoreUnknownDriver(t *testing.T) {
+	for _, address := range []string{"postgres://localhost/limits", "sqlite:", "rate_limits.db"} {
+		_, err := storage.OpenStore(address)
+		assert.ErrorIs(t, err, storage.ErrUnknownDriver, address)
+	}
+	assert.Equal(t, []string{"sqlite"}, storage.Drivers())
+}
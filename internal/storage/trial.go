@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+)
+
+// trialColumns - столбцы пробного лимита в client_rate_limits: trial_until - окончание пробного периода
+// в наносекундах Unix (0 - лимит постоянный), trial_plan - шаблон rate_limiter.templates, на который
+// клиент переводится после окончания. Добавляются миграцией client_trials (см. schema.go).
+var trialColumns = []struct{ name, definition string }{
+	{"trial_until", "INTEGER NOT NULL DEFAULT 0"},
+	{"trial_plan", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// ClientTrial - пробный лимит клиента: до Until действуют rate и capacity клиента,
+// после - лимиты плана Plan.
+type ClientTrial struct {
+	ClientID string
+	Until    time.Time
+	Plan     string
+}
+
+// migrateTrialColumns добавляет в client_rate_limits недостающие столбцы пробного лимита: в базах,
+// созданных до появления миграций схемы, они могут уже быть.
+func migrateTrialColumns(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT name FROM pragma_table_info('client_rate_limits')`)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range trialColumns {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := tx.Exec(`ALTER TABLE client_rate_limits ADD COLUMN ` + column.name + ` ` + column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetClientTrial задает пробный период существующего клиента; нулевое trial.Until снимает его,
+// и текущие лимиты клиента становятся постоянными. Возвращает ErrClientNotFound, если клиента нет.
+func (db *DB) SetClientTrial(trial ClientTrial) error {
+	var until int64
+	plan := ""
+	if !trial.Until.IsZero() {
+		until, plan = trial.Until.UnixNano(), trial.Plan
+	}
+	res, err := db.Conn.Exec(`UPDATE client_rate_limits SET trial_until = ?, trial_plan = ? WHERE client_id = ?`,
+		until, plan, trial.ClientID)
+	if err != nil {
+		return i18n.Errorf(i18n.StorageSetTrialFailed, trial.ClientID, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return i18n.Errorf(i18n.StorageUpdatedRowsFailed, trial.ClientID, err)
+	}
+	if rowsAffected == 0 {
+		return i18n.Errorf(i18n.StorageSetTrialFailed, trial.ClientID, ErrClientNotFound)
+	}
+	if until != 0 {
+		i18n.Logf(i18n.StorageTrialSet, trial.ClientID, trial.Until.Format(time.RFC3339), plan)
+	}
+	return nil
+}
+
+// GetClientTrial возвращает пробный период клиента; found = false, если клиента нет или его лимит постоянный.
+func (db *DB) GetClientTrial(clientID string) (trial ClientTrial, found bool, err error) {
+	var until int64
+	var plan string
+	row := db.Conn.QueryRow(`SELECT trial_until, trial_plan FROM client_rate_limits WHERE client_id = ?`, clientID)
+	if err := row.Scan(&until, &plan); err != nil {
+		if err == sql.ErrNoRows {
+			return ClientTrial{}, false, nil
+		}
+		return ClientTrial{}, false, i18n.Errorf(i18n.StorageGetTrialFailed, clientID, err)
+	}
+	if until == 0 {
+		return ClientTrial{}, false, nil
+	}
+	return ClientTrial{ClientID: clientID, Until: time.Unix(0, until), Plan: plan}, true, nil
+}
+
+// ExpiredClientTrials возвращает пробные периоды, закончившиеся к моменту now, в порядке окончания.
+func (db *DB) ExpiredClientTrials(now time.Time) ([]ClientTrial, error) {
+	rows, err := db.Conn.Query(`SELECT client_id, trial_until, trial_plan FROM client_rate_limits
+		WHERE trial_until != 0 AND trial_until <= ? ORDER BY trial_until, client_id`, now.UnixNano())
+	if err != nil {
+		return nil, i18n.Errorf(i18n.StorageListTrialsFailed, err)
+	}
+	defer rows.Close()
+
+	var trials []ClientTrial
+	for rows.Next() {
+		var trial ClientTrial
+		var until int64
+		if err := rows.Scan(&trial.ClientID, &until, &trial.Plan); err != nil {
+			return nil, i18n.Errorf(i18n.StorageListTrialsFailed, err)
+		}
+		trial.Until = time.Unix(0, until)
+		trials = append(trials, trial)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, i18n.Errorf(i18n.StorageListTrialsFailed, err)
+	}
+	return trials, nil
+}
+
+// DowngradeClientTrial переводит клиента с закончившимся пробным периодом на лимиты плана и снимает
+// пробный период. Если пробный период клиента за это время изменили (например, продлили через API),
+// запись не меняется и возвращается false.
+func (db *DB) DowngradeClientTrial(trial ClientTrial, limit config.ClientRateConfig) (bool, error) {
+	res, err := db.Conn.Exec(`UPDATE client_rate_limits SET rate = ?, capacity = ?, trial_until = 0, trial_plan = ''
+		WHERE client_id = ? AND trial_until = ? AND trial_plan = ?`,
+		limit.Rate, limit.Capacity, trial.ClientID, trial.Until.UnixNano(), trial.Plan)
+	if err != nil {
+		return false, i18n.Errorf(i18n.StorageDowngradeTrialFailed, trial.ClientID, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, i18n.Errorf(i18n.StorageUpdatedRowsFailed, trial.ClientID, err)
+	}
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,173 @@
+// Package tracing реализует распределенную трассировку в модели OpenTelemetry без внешних зависимостей:
+// span запросов, распространение контекста по W3C Trace Context (заголовок traceparent) и отправку span
+// в коллектор по OTLP/HTTP в кодировке JSON.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentHeader - заголовок W3C Trace Context с контекстом трассы.
+const TraceparentHeader = "traceparent"
+
+// SpanKind - роль span в обмене (значения - как в OTLP).
+type SpanKind int
+
+const (
+	// SpanKindServer - обработка входящего запроса.
+	SpanKindServer SpanKind = 2
+	// SpanKindClient - исходящий запрос к другому сервису (бэкенду).
+	SpanKindClient SpanKind = 3
+)
+
+// TraceID и SpanID - идентификаторы трассы и span.
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+// SpanContext - контекст span, передаваемый между сервисами.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool // Трасса записывается (флаг 01 в traceparent).
+}
+
+// IsValid проверяет, что идентификаторы не нулевые.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent возвращает значение заголовка traceparent версии 00.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceparent разбирает заголовок traceparent. Заголовки будущих версий разбираются по полям
+// версии 00, как требует спецификация; версия ff и нулевые идентификаторы недопустимы.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, false
+	}
+	var sc SpanContext
+	var version, flags [1]byte
+	if !decodeHex(version[:], parts[0]) || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) ||
+		!decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) || !decodeHex(flags[:], parts[3]) {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.IsValid()
+}
+
+// decodeHex декодирует строку из строчных шестнадцатеричных цифр ровно в dst.
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// Extract возвращает контекст вызывающего сервиса из заголовка traceparent.
+func Extract(h http.Header) (SpanContext, bool) {
+	return ParseTraceparent(h.Get(TraceparentHeader))
+}
+
+// Inject записывает в h заголовок traceparent span из ctx; без span заголовок не меняется.
+func Inject(ctx context.Context, h http.Header) {
+	if span := SpanFromContext(ctx); span != nil {
+		h.Set(TraceparentHeader, span.sc.Traceparent())
+	}
+}
+
+type spanKey struct{}
+
+// SpanFromContext возвращает текущий span запроса или nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// attribute - атрибут span; значение - string, int, int64, float64 или bool.
+type attribute struct {
+	key   string
+	value any
+}
+
+// Span - операция в трассе. Методы допускают nil: без трассировки span не создаются и ничего не записывают.
+type Span struct {
+	tracer *Tracer
+	name   string
+	kind   SpanKind
+	sc     SpanContext
+	parent SpanID // Нулевой - корневой span.
+	start  time.Time
+
+	mu       sync.Mutex
+	end      time.Time
+	attrs    []attribute
+	errorMsg string // Непусто - span завершился ошибкой.
+	ended    bool
+}
+
+// Context возвращает контекст span (нулевой для nil).
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttribute добавляет атрибут; value - string, int, int64, float64 или bool.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attribute{key: key, value: value})
+	s.mu.Unlock()
+}
+
+// SetError отмечает span как завершившийся ошибкой.
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errorMsg = message
+	s.mu.Unlock()
+}
+
+// End завершает span и ставит его в очередь на отправку, если трасса записывается. Повторный вызов ничего не делает.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	if s.sc.Sampled {
+		s.tracer.enqueue(s)
+	}
+}
+
+// newID заполняет id случайными байтами.
+func newID(id []byte) {
+	_, _ = rand.Read(id)
+}
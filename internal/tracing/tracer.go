@@ -0,0 +1,307 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+	"load-balancer/internal/metrics"
+)
+
+// scopeName - имя инструментирующей библиотеки в OTLP.
+const scopeName = "load-balancer/internal/tracing"
+
+var (
+	exportedTotal = metrics.NewCounter("tracing_spans_exported_total",
+		"Количество span, доставленных в коллектор OTLP.")
+	droppedTotal = metrics.NewCounter("tracing_spans_dropped_total",
+		"Количество span, отброшенных из-за переполнения очереди или ошибки отправки.")
+)
+
+// Tracer создает span и отправляет записанные span в коллектор пачками из фоновой горутины.
+// Завершение span никогда не блокирует обработку запроса: при переполнении очереди span отбрасываются.
+// Методы допускают nil: без трассировки span не создаются.
+type Tracer struct {
+	cfg    config.TracingConfig
+	client *http.Client
+	queue  chan *Span
+
+	dropped  atomic.Uint64 // Отброшено с момента последнего предупреждения в лог.
+	quit     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup // Горутина отправки (см. Wait)
+}
+
+// New создает Tracer по секции tracing конфигурации. Отправка начинается после Start.
+func New(cfg config.TracingConfig) *Tracer {
+	return &Tracer{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		queue:  make(chan *Span, cfg.QueueSize),
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start запускает фоновую отправку.
+func (t *Tracer) Start() {
+	i18n.Logf(i18n.TracingStarted, t.cfg.Endpoint, t.cfg.ServiceName, t.cfg.SampleRatio)
+	t.wg.Add(1)
+	go t.run()
+}
+
+// Stop останавливает отправку: span, уже стоящие в очереди, отправляются.
+func (t *Tracer) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.quit)
+	})
+}
+
+// Wait дожидается отправки оставшихся span после Stop или истечения ctx.
+func (t *Tracer) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// StartSpan начинает span. Родитель - span из ctx, а если его нет - remote (контекст вызывающего сервиса
+// из traceparent, см. Extract); без родителя начинается новая трасса, которая записывается с долей
+// sample_ratio. Возвращает ctx с новым span.
+func (t *Tracer) StartSpan(ctx context.Context, name string, kind SpanKind, remote SpanContext) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	parent := remote
+	if span := SpanFromContext(ctx); span != nil {
+		parent = span.sc
+	}
+	span := &Span{tracer: t, name: name, kind: kind, start: time.Now()}
+	if parent.IsValid() {
+		span.sc.TraceID = parent.TraceID
+		span.sc.Sampled = parent.Sampled
+		span.parent = parent.SpanID
+	} else {
+		newID(span.sc.TraceID[:])
+		span.sc.Sampled = t.sample(span.sc.TraceID)
+	}
+	newID(span.sc.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// sample решает, записывать ли новую трассу: по младшим байтам ID трассы, как TraceIdRatioBased в OpenTelemetry.
+func (t *Tracer) sample(id TraceID) bool {
+	switch {
+	case t.cfg.SampleRatio >= 1:
+		return true
+	case t.cfg.SampleRatio <= 0:
+		return false
+	}
+	return binary.BigEndian.Uint64(id[8:])>>1 < uint64(t.cfg.SampleRatio*(1<<63))
+}
+
+// enqueue ставит завершенный span в очередь на отправку.
+func (t *Tracer) enqueue(span *Span) {
+	select {
+	case t.queue <- span:
+	default:
+		droppedTotal.Inc()
+		t.dropped.Add(1)
+	}
+}
+
+func (t *Tracer) run() {
+	defer t.wg.Done()
+	defer t.client.CloseIdleConnections()
+
+	ticker := time.NewTicker(t.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, t.cfg.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			t.export(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) == t.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			if dropped := t.dropped.Swap(0); dropped > 0 {
+				i18n.Logf(i18n.TracingDropped, dropped, t.cfg.QueueSize)
+			}
+		case <-t.quit:
+			for {
+				select {
+				case span := <-t.queue:
+					batch = append(batch, span)
+					if len(batch) == t.cfg.BatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export отправляет пачку одним запросом. Неудачная пачка отбрасывается: трассировка не должна
+// нагружать балансировщик повторами, пока коллектор недоступен.
+func (t *Tracer) export(batch []*Span) {
+	body, err := json.Marshal(t.encode(batch))
+	if err == nil {
+		err = t.post(body)
+	}
+	if err != nil {
+		droppedTotal.Add(uint64(len(batch)))
+		i18n.Logf(i18n.TracingExportFailed, len(batch), err)
+		return
+	}
+	exportedTotal.Add(uint64(len(batch)))
+}
+
+func (t *Tracer) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return i18n.Errorf(i18n.TracingBadStatus, resp.Status)
+	}
+	return nil
+}
+
+// Структуры запроса ExportTraceServiceRequest в JSON-кодировке OTLP: идентификаторы - hex-строки,
+// время и целые значения атрибутов - десятичные строки.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              SpanKind        `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"` // 2 - STATUS_CODE_ERROR.
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+)
+
+// encode формирует запрос OTLP с пачкой span.
+func (t *Tracer) encode(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, span := range batch {
+		span.mu.Lock()
+		encoded := otlpSpan{
+			TraceID:           hex.EncodeToString(span.sc.TraceID[:]),
+			SpanID:            hex.EncodeToString(span.sc.SpanID[:]),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+		}
+		if span.parent != (SpanID{}) {
+			encoded.ParentSpanID = hex.EncodeToString(span.parent[:])
+		}
+		for _, attr := range span.attrs {
+			encoded.Attributes = append(encoded.Attributes, encodeAttribute(attr.key, attr.value))
+		}
+		if span.errorMsg != "" {
+			encoded.Status = &otlpStatus{Code: 2, Message: span.errorMsg}
+		}
+		span.mu.Unlock()
+		spans = append(spans, encoded)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttribute{encodeAttribute("service.name", t.cfg.ServiceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: scopeName}, Spans: spans}},
+	}}}
+}
+
+func encodeAttribute(key string, value any) otlpAttribute {
+	attr := otlpAttribute{Key: key}
+	switch v := value.(type) {
+	case string:
+		attr.Value.StringValue = &v
+	case int:
+		s := strconv.Itoa(v)
+		attr.Value.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		attr.Value.IntValue = &s
+	case float64:
+		attr.Value.DoubleValue = &v
+	case bool:
+		attr.Value.BoolValue = &v
+	default:
+		s := ""
+		attr.Value.StringValue = &s
+	}
+	return attr
+}
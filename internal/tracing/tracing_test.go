@@ -0,0 +1,156 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/tracing"
+)
+
+// TestParseTraceparent проверяет разбор заголовка traceparent по W3C Trace Context.
+func TestParseTraceparent(t *testing.T) {
+	const value = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := tracing.ParseTraceparent(value)
+	require.True(t, ok)
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, value, sc.Traceparent())
+
+	sc, ok = tracing.ParseTraceparent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+	require.True(t, ok, "Поля будущих версий игнорируются")
+	assert.False(t, sc.Sampled)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+	} {
+		_, ok := tracing.ParseTraceparent(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+// TestTracer проверяет связи span, распространение контекста, выборку и отправку в коллектор OTLP.
+func TestTracer(t *testing.T) {
+	bodies := make(chan []byte, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer collector.Close()
+
+	tracer := tracing.New(config.TracingConfig{Endpoint: collector.URL, ServiceName: "lb", SampleRatio: 1,
+		Headers: map[string]string{"Authorization": "Bearer token"}, BatchSize: 2, QueueSize: 10,
+		FlushInterval: time.Hour, Timeout: time.Second})
+	tracer.Start()
+
+	ctx, server := tracer.StartSpan(context.Background(), "GET", tracing.SpanKindServer, tracing.SpanContext{})
+	_, client := tracer.StartSpan(ctx, "GET", tracing.SpanKindClient, tracing.SpanContext{})
+	assert.Equal(t, server.Context().TraceID, client.Context().TraceID)
+
+	header := http.Header{}
+	tracing.Inject(ctx, header)
+	propagated, ok := tracing.Extract(header)
+	require.True(t, ok)
+	assert.Equal(t, server.Context(), propagated)
+
+	client.SetAttribute("http.response.status_code", 503)
+	client.SetError("Service Unavailable")
+	client.End()
+	client.End() // Повторное завершение не отправляет span еще раз
+	server.SetAttribute("balancer.client_id", "user1")
+	server.End()
+
+	// Пачка из двух span отправляется, не дожидаясь flush_interval
+	var export struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string `json:"key"`
+					Value struct {
+						StringValue string `json:"stringValue"`
+					} `json:"value"`
+				} `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []struct {
+					Name         string `json:"name"`
+					ParentSpanID string `json:"parentSpanId"`
+					Attributes   []struct {
+						Key   string `json:"key"`
+						Value struct {
+							IntValue string `json:"intValue"`
+						} `json:"value"`
+					} `json:"attributes"`
+					Status *struct {
+						Code    int    `json:"code"`
+						Message string `json:"message"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	select {
+	case body := <-bodies:
+		require.NoError(t, json.Unmarshal(body, &export))
+	case <-time.After(5 * time.Second):
+		t.Fatal("span не отправлены")
+	}
+	resource := export.ResourceSpans[0]
+	assert.Equal(t, "service.name", resource.Resource.Attributes[0].Key)
+	assert.Equal(t, "lb", resource.Resource.Attributes[0].Value.StringValue)
+	spans := resource.ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	assert.NotEmpty(t, spans[0].ParentSpanID)
+	assert.Equal(t, "503", spans[0].Attributes[0].Value.IntValue)
+	require.NotNil(t, spans[0].Status)
+	assert.Equal(t, 2, spans[0].Status.Code)
+	assert.Empty(t, spans[1].ParentSpanID)
+	assert.Nil(t, spans[1].Status)
+
+	// Решение о записи берется у вызывающего сервиса: span незаписываемой трассы не отправляются
+	remote, _ := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, unsampled := tracer.StartSpan(context.Background(), "GET", tracing.SpanKindServer, remote)
+	assert.False(t, unsampled.Context().Sampled)
+	unsampled.End()
+
+	tracer.Stop()
+	require.NoError(t, tracer.Wait(context.Background()))
+	assert.Empty(t, bodies)
+
+	// Без Tracer span не создаются, а методы span безопасны
+	var disabled *tracing.Tracer
+	ctx, span := disabled.StartSpan(context.Background(), "GET", tracing.SpanKindServer, tracing.SpanContext{})
+	assert.Nil(t, span)
+	span.SetAttribute("k", "v")
+	span.End()
+	assert.Nil(t, tracing.SpanFromContext(ctx))
+}
+
+// TestTracerSampleRatio проверяет долю записываемых новых трасс.
+func TestTracerSampleRatio(t *testing.T) {
+	tracer := tracing.New(config.TracingConfig{SampleRatio: 0.25, BatchSize: 1, QueueSize: 1})
+	sampled := 0
+	for range 4000 {
+		_, span := tracer.StartSpan(context.Background(), "GET", tracing.SpanKindServer, tracing.SpanContext{})
+		if span.Context().Sampled {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 1000, sampled, 150)
+}
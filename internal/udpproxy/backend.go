@@ -0,0 +1,76 @@
+package udpproxy
+
+import (
+	"hash/fnv"
+	"net"
+	"sync"
+	"time"
+
+	"load-balancer/internal/i18n"
+)
+
+// Backend - UDP-бэкенд с пассивной проверкой состояния: активных проб нет,
+// сбоем считается ICMP port unreachable или отсутствие ответа за response_timeout.
+type Backend struct {
+	Addr *net.UDPAddr
+
+	mu        sync.Mutex
+	fails     int       // Сбоев подряд с последнего успешного ответа.
+	downUntil time.Time // До этого момента бэкенд исключен из балансировки.
+}
+
+// IsAlive сообщает, участвует ли бэкенд в балансировке. После fail_timeout бэкенд
+// снова получает трафик и окончательно возвращается в строй после первого ответа.
+func (b *Backend) IsAlive(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !now.Before(b.downUntil)
+}
+
+// recordSuccess сбрасывает счетчик сбоев после ответа бэкенда.
+func (b *Backend) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.downUntil.IsZero() {
+		i18n.Logf(i18n.UDPBackendUp, b.Addr)
+	}
+	b.fails = 0
+	b.downUntil = time.Time{}
+}
+
+// recordFailure учитывает сбой; после maxFails сбоев подряд бэкенд исключается на failTimeout.
+func (b *Backend) recordFailure(reason string, maxFails int, failTimeout time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fails++
+	if b.fails >= maxFails {
+		b.fails = 0
+		b.downUntil = time.Now().Add(failTimeout)
+		i18n.Logf(i18n.UDPBackendDown, b.Addr, reason, failTimeout)
+	}
+}
+
+// score - вес бэкенда для клиента при rendezvous-хешировании.
+func (b *Backend) score(clientIP net.IP) uint64 {
+	h := fnv.New64a()
+	h.Write(clientIP.To16())
+	h.Write([]byte(b.Addr.String()))
+	return h.Sum64()
+}
+
+// pickBackend выбирает бэкенд для клиента по хешу его IP-адреса среди доступных.
+// Используется rendezvous-хеширование: при выходе бэкенда из строя на другие
+// переезжают только его клиенты, остальные сохраняют привязку.
+func pickBackend(backends []*Backend, clientIP net.IP, now time.Time) *Backend {
+	var best *Backend
+	var bestScore uint64
+	for _, b := range backends {
+		if !b.IsAlive(now) {
+			continue
+		}
+		if s := b.score(clientIP); best == nil || s > bestScore {
+			best, bestScore = b, s
+		}
+	}
+	return best
+}
@@ -0,0 +1,282 @@
+package udpproxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/i18n"
+)
+
+// maxDatagramSize - максимальный размер UDP-датаграммы.
+const maxDatagramSize = 64 * 1024
+
+// session - поток датаграмм одного клиента (IP:порт) к выбранному бэкенду.
+// Для каждой сессии открывается отдельный подключенный сокет: так ответы бэкенда
+// однозначно возвращаются клиенту, а ICMP port unreachable приходит как ECONNREFUSED.
+type session struct {
+	client   *net.UDPAddr
+	backend  *Backend
+	upstream *net.UDPConn
+
+	mu            sync.Mutex
+	lastActivity  time.Time
+	awaitingSince time.Time // Момент первого запроса, оставшегося без ответа (нулевое - ответов не ждем).
+	closeOnce     sync.Once
+}
+
+// Proxy принимает UDP-датаграммы и распределяет их по бэкендам с привязкой по IP клиента.
+type Proxy struct {
+	cfg      config.UDPConfig
+	backends []*Backend
+	conn     *net.UDPConn
+
+	mu       sync.Mutex
+	sessions map[string]*session // Ключ - адрес клиента (IP:порт).
+
+	quit     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup // Цикл приема и читатели ответов сессий (см. Wait)
+}
+
+// New создает UDP-прокси по секции udp конфигурации.
+func New(cfg config.UDPConfig) (*Proxy, error) {
+	backends := make([]*Backend, 0, len(cfg.Backends))
+	for i, rawAddr := range cfg.Backends {
+		addr, err := net.ResolveUDPAddr("udp", rawAddr)
+		if err != nil {
+			return nil, i18n.Errorf(i18n.UDPBadBackend, i, rawAddr, err)
+		}
+		backends = append(backends, &Backend{Addr: addr})
+	}
+	return &Proxy{
+		cfg:      cfg,
+		backends: backends,
+		sessions: make(map[string]*session),
+		quit:     make(chan struct{}),
+	}, nil
+}
+
+// Start открывает сокет и запускает прием датаграмм в фоне.
+func (p *Proxy) Start() error {
+	addr, err := net.ResolveUDPAddr("udp", p.cfg.Listen)
+	if err != nil {
+		return err
+	}
+	p.conn, err = net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	p.wg.Add(1)
+	go p.serve()
+	i18n.Logf(i18n.UDPListening, p.conn.LocalAddr(), len(p.backends), p.cfg.SessionTimeout, p.cfg.ResponseTimeout)
+	return nil
+}
+
+// Addr возвращает адрес, на котором прокси принимает датаграммы.
+func (p *Proxy) Addr() net.Addr {
+	return p.conn.LocalAddr()
+}
+
+// Stop закрывает сокет и все сессии.
+func (p *Proxy) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.quit)
+		if p.conn != nil {
+			p.conn.Close()
+		}
+		p.mu.Lock()
+		for _, s := range p.sessions {
+			s.close()
+		}
+		p.mu.Unlock()
+		i18n.Logf(i18n.UDPStopped)
+	})
+}
+
+// Wait ожидает завершения фоновых горутин после Stop.
+// Возвращает ошибку контекста, если горутины не завершились до его отмены.
+func (p *Proxy) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HealthyBackends возвращает количество доступных и общее количество бэкендов.
+func (p *Proxy) HealthyBackends() (healthy, total int) {
+	now := time.Now()
+	for _, b := range p.backends {
+		if b.IsAlive(now) {
+			healthy++
+		}
+	}
+	return healthy, len(p.backends)
+}
+
+// GetBackends возвращает бэкенды прокси.
+func (p *Proxy) GetBackends() []*Backend {
+	return p.backends
+}
+
+// serve - цикл приема датаграмм от клиентов.
+func (p *Proxy) serve() {
+	defer p.wg.Done()
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, client, err := p.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-p.quit:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			i18n.Logf(i18n.UDPReadFailed, err)
+			continue
+		}
+
+		s := p.session(client)
+		if s == nil {
+			continue
+		}
+		s.forward(p, buf[:n])
+	}
+}
+
+// session возвращает сессию клиента, открывая новую при необходимости.
+func (p *Proxy) session(client *net.UDPAddr) *session {
+	key := client.String()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s, ok := p.sessions[key]; ok {
+		return s
+	}
+
+	backend := pickBackend(p.backends, client.IP, time.Now())
+	if backend == nil {
+		i18n.Logf(i18n.UDPNoHealthyBackends, client)
+		return nil
+	}
+	upstream, err := net.DialUDP("udp", nil, backend.Addr)
+	if err != nil {
+		i18n.Logf(i18n.UDPDialFailed, backend.Addr, err)
+		backend.recordFailure(err.Error(), p.cfg.MaxFails, p.cfg.FailTimeout)
+		return nil
+	}
+
+	s := &session{
+		client:       client,
+		backend:      backend,
+		upstream:     upstream,
+		lastActivity: time.Now(),
+	}
+	p.sessions[key] = s
+	p.wg.Add(1)
+	go p.relayReplies(s)
+	return s
+}
+
+// forward отправляет датаграмму клиента бэкенду сессии.
+func (s *session) forward(p *Proxy, data []byte) {
+	now := time.Now()
+	s.mu.Lock()
+	s.lastActivity = now
+	if s.awaitingSince.IsZero() {
+		s.awaitingSince = now
+	}
+	s.mu.Unlock()
+
+	if _, err := s.upstream.Write(data); err != nil {
+		i18n.Logf(i18n.UDPWriteFailed, s.backend.Addr, err)
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			s.backend.recordFailure(i18n.T(i18n.UDPReasonUnreachable), p.cfg.MaxFails, p.cfg.FailTimeout)
+		}
+		p.closeSession(s)
+	}
+}
+
+// relayReplies возвращает клиенту ответы бэкенда и отслеживает пассивное состояние бэкенда.
+func (p *Proxy) relayReplies(s *session) {
+	defer p.wg.Done()
+	defer p.closeSession(s)
+
+	// Дедлайн чтения - шаг проверки таймаутов ответа и простоя сессии
+	tick := p.cfg.SessionTimeout
+	if p.cfg.ResponseTimeout > 0 && p.cfg.ResponseTimeout < tick {
+		tick = p.cfg.ResponseTimeout
+	}
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		s.upstream.SetReadDeadline(time.Now().Add(tick))
+		n, err := s.upstream.Read(buf)
+		if err == nil {
+			s.mu.Lock()
+			s.lastActivity = time.Now()
+			s.awaitingSince = time.Time{}
+			s.mu.Unlock()
+			s.backend.recordSuccess()
+			if _, err := p.conn.WriteToUDP(buf[:n], s.client); err != nil {
+				i18n.Logf(i18n.UDPReplyFailed, s.client, err)
+			}
+			continue
+		}
+
+		var netErr net.Error
+		switch {
+		case errors.As(err, &netErr) && netErr.Timeout():
+			now := time.Now()
+			s.mu.Lock()
+			noReply := p.cfg.ResponseTimeout > 0 && !s.awaitingSince.IsZero() &&
+				now.Sub(s.awaitingSince) >= p.cfg.ResponseTimeout
+			idle := now.Sub(s.lastActivity) >= p.cfg.SessionTimeout
+			s.mu.Unlock()
+			if noReply {
+				// Закрываем сессию, чтобы следующая датаграмма клиента заново выбрала бэкенд
+				s.backend.recordFailure(i18n.T(i18n.UDPReasonTimeout, p.cfg.ResponseTimeout), p.cfg.MaxFails, p.cfg.FailTimeout)
+				return
+			}
+			if idle {
+				return
+			}
+		case errors.Is(err, syscall.ECONNREFUSED):
+			// ICMP port unreachable: на бэкенде никто не слушает порт
+			s.backend.recordFailure(i18n.T(i18n.UDPReasonUnreachable), p.cfg.MaxFails, p.cfg.FailTimeout)
+			return
+		default:
+			// Сокет закрыт (Stop или закрытие сессии)
+			return
+		}
+	}
+}
+
+// closeSession удаляет сессию из таблицы и закрывает ее сокет.
+func (p *Proxy) closeSession(s *session) {
+	p.mu.Lock()
+	if p.sessions[s.client.String()] == s {
+		delete(p.sessions, s.client.String())
+	}
+	p.mu.Unlock()
+	s.close()
+}
+
+// close закрывает сокет сессии; повторные вызовы безопасны.
+func (s *session) close() {
+	s.closeOnce.Do(func() { s.upstream.Close() })
+}
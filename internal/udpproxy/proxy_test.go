@@ -0,0 +1,161 @@
+package udpproxy_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"load-balancer/internal/config"
+	"load-balancer/internal/udpproxy"
+)
+
+// echoBackend отвечает на датаграммы строкой "<id>:<данные>"; в режиме silent не отвечает.
+type echoBackend struct {
+	id     string
+	conn   *net.UDPConn
+	silent atomic.Bool
+}
+
+func newEchoBackend(t *testing.T, id string) *echoBackend {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	b := &echoBackend{id: id, conn: conn}
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if !b.silent.Load() {
+				_, _ = conn.WriteToUDP([]byte(id+":"+string(buf[:n])), addr)
+			}
+		}
+	}()
+	t.Cleanup(func() { conn.Close() })
+	return b
+}
+
+func (b *echoBackend) addr() string {
+	return b.conn.LocalAddr().String()
+}
+
+func newTestProxy(t *testing.T, responseTimeout time.Duration, backends ...*echoBackend) *udpproxy.Proxy {
+	t.Helper()
+	cfg := config.UDPConfig{
+		Enabled:         true,
+		Listen:          "127.0.0.1:0",
+		SessionTimeout:  time.Second,
+		ResponseTimeout: responseTimeout,
+		MaxFails:        1,
+		FailTimeout:     time.Minute,
+	}
+	for _, b := range backends {
+		cfg.Backends = append(cfg.Backends, b.addr())
+	}
+	p, err := udpproxy.New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	t.Cleanup(func() {
+		p.Stop()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		assert.NoError(t, p.Wait(ctx), "Горутины прокси должны завершиться после Stop")
+	})
+	return p
+}
+
+// exchange отправляет датаграмму и ждет ответ; при отсутствии ответа повторяет отправку, как DNS-клиент.
+func exchange(t *testing.T, client *net.UDPConn, proxy net.Addr, payload string) string {
+	t.Helper()
+	buf := make([]byte, 1500)
+	for attempt := 0; attempt < 20; attempt++ {
+		_, err := client.WriteTo([]byte(payload), proxy)
+		require.NoError(t, err)
+		client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, err := client.Read(buf)
+		if err == nil {
+			return string(buf[:n])
+		}
+	}
+	t.Fatalf("Нет ответа на %q", payload)
+	return ""
+}
+
+func newClient(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// pinnedBackend возвращает бэкенд, ответивший клиенту.
+func pinnedBackend(reply string, backends ...*echoBackend) *echoBackend {
+	for _, b := range backends {
+		if strings.HasPrefix(reply, b.id+":") {
+			return b
+		}
+	}
+	return nil
+}
+
+// TestProxy_SourceHashAffinity проверяет, что все клиенты с одного IP попадают на один бэкенд.
+func TestProxy_SourceHashAffinity(t *testing.T) {
+	a, b := newEchoBackend(t, "a"), newEchoBackend(t, "b")
+	p := newTestProxy(t, 0, a, b)
+
+	first := exchange(t, newClient(t), p.Addr(), "ping")
+	pinned := pinnedBackend(first, a, b)
+	require.NotNil(t, pinned, "Неожиданный ответ %q", first)
+	assert.Equal(t, pinned.id+":ping", first)
+
+	// Другой порт того же IP и повторные запросы - тот же бэкенд
+	for i := 0; i < 5; i++ {
+		reply := exchange(t, newClient(t), p.Addr(), "ping")
+		assert.Equal(t, pinned.id+":ping", reply)
+	}
+}
+
+// TestProxy_PassiveHealth_Unreachable проверяет исключение бэкенда по ICMP port unreachable.
+func TestProxy_PassiveHealth_Unreachable(t *testing.T) {
+	a, b := newEchoBackend(t, "a"), newEchoBackend(t, "b")
+	p := newTestProxy(t, 0, a, b)
+	client := newClient(t)
+
+	pinned := pinnedBackend(exchange(t, client, p.Addr(), "ping"), a, b)
+	require.NotNil(t, pinned)
+
+	// Порт закрыт: бэкенд отвечает ICMP port unreachable
+	pinned.conn.Close()
+
+	reply := exchange(t, client, p.Addr(), "ping")
+	assert.NotEqual(t, pinned, pinnedBackend(reply, a, b), "Клиент должен переехать на другой бэкенд")
+	healthy, total := p.HealthyBackends()
+	assert.Equal(t, 1, healthy)
+	assert.Equal(t, 2, total)
+}
+
+// TestProxy_PassiveHealth_Timeout проверяет исключение бэкенда, переставшего отвечать.
+func TestProxy_PassiveHealth_Timeout(t *testing.T) {
+	a, b := newEchoBackend(t, "a"), newEchoBackend(t, "b")
+	p := newTestProxy(t, 50*time.Millisecond, a, b)
+	client := newClient(t)
+
+	pinned := pinnedBackend(exchange(t, client, p.Addr(), "ping"), a, b)
+	require.NotNil(t, pinned)
+
+	pinned.silent.Store(true)
+
+	reply := exchange(t, client, p.Addr(), "ping")
+	assert.NotEqual(t, pinned, pinnedBackend(reply, a, b), "Клиент должен переехать на другой бэкенд")
+	healthy, _ := p.HealthyBackends()
+	assert.Equal(t, 1, healthy)
+}